@@ -0,0 +1,355 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+)
+
+// CacheAdapter defines the interface for a cache of string values shared by all service instances
+type CacheAdapter interface {
+	// Get returns the value of key, and whether it was found
+	Get(ctx context.Context, key string) (string, bool, error)
+
+	// Set stores the value of key for ttl
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+
+	// Delete removes key
+	Delete(ctx context.Context, key string) error
+
+	// CompareAndSwap atomically replaces the value of key when it is still old, or when key
+	// is absent with a nil old, and reports whether it did. A nil value removes key; others
+	// are stored for ttl.
+	CompareAndSwap(ctx context.Context, key string, old, value *string, ttl time.Duration) (bool, error)
+}
+
+// memoryCacheAdapter implements CacheAdapter in the memory of the instance, for a single
+// instance or values that need not be shared
+type memoryCacheAdapter struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+	sets    int // Sets since expired entries were last removed
+}
+
+// memoryCacheEntry is a value cached in memory with its expiry
+type memoryCacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// memoryCacheSweep is the number of sets between the removals of expired entries
+const memoryCacheSweep = 1000
+
+// NewMemoryCacheAdapter creates a new CacheAdapter keeping values in memory
+func NewMemoryCacheAdapter() CacheAdapter {
+	return &memoryCacheAdapter{entries: map[string]memoryCacheEntry{}}
+}
+
+// Get returns the value of key, and whether it was found
+func (a *memoryCacheAdapter) Get(ctx context.Context, key string) (string, bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return "", false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Set stores the value of key for ttl, removing the expired entries every memoryCacheSweep sets
+func (a *memoryCacheAdapter) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if a.sets++; a.sets >= memoryCacheSweep {
+		a.sets = 0
+		for k, entry := range a.entries {
+			if now.After(entry.expiresAt) {
+				delete(a.entries, k)
+			}
+		}
+	}
+	a.entries[key] = memoryCacheEntry{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// Delete removes key
+func (a *memoryCacheAdapter) Delete(ctx context.Context, key string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.entries, key)
+	return nil
+}
+
+// CompareAndSwap atomically replaces the value of key when it is still old
+func (a *memoryCacheAdapter) CompareAndSwap(ctx context.Context, key string, old, value *string, ttl time.Duration) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := a.entries[key]
+	ok = ok && !now.After(entry.expiresAt)
+	if ok != (old != nil) || (ok && entry.value != *old) {
+		return false, nil
+	}
+	if value == nil {
+		delete(a.entries, key)
+		return true, nil
+	}
+	a.entries[key] = memoryCacheEntry{value: *value, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// redisCompareAndSwap is the script replacing the value of KEYS[1] when it is still ARGV[2],
+// or when it is absent with ARGV[1] set to 0. ARGV[3] tells whether to set ARGV[4] for ARGV[5]
+// milliseconds, or to delete the key.
+const redisCompareAndSwap = `local current = redis.call('GET', KEYS[1])
+if ARGV[1] == '1' then
+	if current ~= ARGV[2] then return 0 end
+elseif current then
+	return 0
+end
+if ARGV[3] == '1' then
+	redis.call('SET', KEYS[1], ARGV[4], 'PX', ARGV[5])
+else
+	redis.call('DEL', KEYS[1])
+end
+return 1`
+
+// redisCacheAdapter implements CacheAdapter with the commands of the Redis protocol (RESP)
+// over a pool of connections
+type redisCacheAdapter struct {
+	config configs.ChatOwnersRedis
+	idle   chan *redisConn
+}
+
+// redisConn is a connection to Redis with its reply reader
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error reply of Redis
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedisCacheAdapter creates a new CacheAdapter backed by Redis. Connections are opened
+// on demand, and up to config.PoolSize idle ones are kept.
+func NewRedisCacheAdapter(config configs.ChatOwnersRedis) CacheAdapter {
+	if config.PoolSize < 1 {
+		config.PoolSize = 1
+	}
+	return &redisCacheAdapter{config: config, idle: make(chan *redisConn, config.PoolSize)}
+}
+
+// Get returns the value of key, and whether it was found
+func (a *redisCacheAdapter) Get(ctx context.Context, key string) (string, bool, error) {
+	reply, err := a.do(ctx, "GET", key)
+	if err != nil {
+		return "", false, err
+	}
+	if reply == nil {
+		return "", false, nil
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return "", false, fmt.Errorf("redis: unexpected reply to GET: %v", reply)
+	}
+	return value, true, nil
+}
+
+// Set stores the value of key for ttl, rounded to milliseconds
+func (a *redisCacheAdapter) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	_, err := a.do(ctx, "SET", key, value, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+// Delete removes key
+func (a *redisCacheAdapter) Delete(ctx context.Context, key string) error {
+	_, err := a.do(ctx, "DEL", key)
+	return err
+}
+
+// CompareAndSwap atomically replaces the value of key when it is still old, with a script
+// run by Redis
+func (a *redisCacheAdapter) CompareAndSwap(ctx context.Context, key string, old, value *string, ttl time.Duration) (bool, error) {
+	args := []string{"EVAL", redisCompareAndSwap, "1", key, "0", "", "0", "", "0"}
+	if old != nil {
+		args[4], args[5] = "1", *old
+	}
+	if value != nil {
+		args[6], args[7], args[8] = "1", *value, strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)
+	}
+	reply, err := a.do(ctx, args...)
+	if err != nil {
+		return false, err
+	}
+	swapped, ok := reply.(int64)
+	if !ok {
+		return false, fmt.Errorf("redis: unexpected reply to EVAL: %v", reply)
+	}
+	return swapped == 1, nil
+}
+
+// do sends a command and reads its reply, within the configured timeout. Connections
+// failing at the protocol level are closed rather than pooled again.
+func (a *redisCacheAdapter) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := a.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(a.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.conn.SetDeadline(deadline); err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+
+	reply, err := conn.command(args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.conn.Close()
+		return nil, err
+	}
+	a.put(conn)
+	return reply, err
+}
+
+// get returns an idle connection, or opens a new one
+func (a *redisCacheAdapter) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-a.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: a.config.Timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", a.config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if err := netConn.SetDeadline(time.Now().Add(a.config.Timeout)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if a.config.Password != "" {
+		if _, err := conn.command("AUTH", a.config.Password); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("failed to authenticate to redis: %w", err)
+		}
+	}
+	if a.config.DB != 0 {
+		if _, err := conn.command("SELECT", strconv.Itoa(a.config.DB)); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("failed to select redis database %d: %w", a.config.DB, err)
+		}
+	}
+	return conn, nil
+}
+
+// put returns a connection to the pool, or closes it when the pool is full
+func (a *redisCacheAdapter) put(conn *redisConn) {
+	select {
+	case a.idle <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+// command writes a command and reads its reply
+func (c *redisConn) command(args ...string) (interface{}, error) {
+	if err := c.write(args...); err != nil {
+		return nil, err
+	}
+	return c.reply()
+}
+
+// write writes a command as an array of bulk strings
+func (c *redisConn) write(args ...string) error {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// reply reads a reply: a string for simple and bulk strings, an int64 for integers, a slice
+// for arrays, nil for null bulk strings and arrays, or a redisError
+func (c *redisConn) reply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, value := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return value, nil
+	case '-':
+		return nil, redisError(value)
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", value)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", value)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			// Error replies within arrays are values, leaving the rest of the array to read
+			item, err := c.reply()
+			var replyErr redisError
+			if errors.As(err, &replyErr) {
+				item, err = replyErr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,317 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replyConn returns a connection reading the replies of raw
+func replyConn(raw string) *redisConn {
+	return &redisConn{reader: bufio.NewReader(strings.NewReader(raw))}
+}
+
+func TestRedisReply(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want interface{}
+	}{
+		{"simple string", "+OK\r\n", "OK"},
+		{"integer", ":42\r\n", int64(42)},
+		{"bulk string", "$5\r\nhello\r\n", "hello"},
+		{"empty bulk string", "$0\r\n\r\n", ""},
+		{"bulk string with CRLF", "$4\r\na\r\nb\r\n", "a\r\nb"},
+		{"nil bulk string", "$-1\r\n", nil},
+		{"nil array", "*-1\r\n", nil},
+		{"array", "*3\r\n+a\r\n:1\r\n$-1\r\n", []interface{}{"a", int64(1), nil}},
+		{"nested array", "*1\r\n*1\r\n$1\r\nx\r\n", []interface{}{[]interface{}{"x"}}},
+		{"error in array", "*2\r\n-ERR bad\r\n+OK\r\n", []interface{}{redisError("ERR bad"), "OK"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := replyConn(tt.raw)
+			reply, err := conn.reply()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, reply)
+
+			// The whole reply was read
+			_, err = conn.reader.ReadByte()
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestRedisReplyError(t *testing.T) {
+	reply, err := replyConn("-WRONGTYPE Operation against a key holding the wrong kind of value\r\n").reply()
+	assert.Nil(t, reply)
+
+	var replyErr redisError
+	require.ErrorAs(t, err, &replyErr)
+	assert.Equal(t, "WRONGTYPE Operation against a key holding the wrong kind of value", string(replyErr))
+	assert.Equal(t, "redis: WRONGTYPE Operation against a key holding the wrong kind of value", err.Error())
+}
+
+func TestRedisReplyMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"missing CR", "+OK\n"},
+		{"too short", "\r\n"},
+		{"unknown kind", "?x\r\n"},
+		{"bad bulk length", "$x\r\n"},
+		{"bad array length", "*x\r\n"},
+		{"bad integer", ":x\r\n"},
+		{"truncated bulk string", "$5\r\nhel"},
+		{"truncated array", "*2\r\n+a\r\n"},
+		{"no reply", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := replyConn(tt.raw).reply()
+			require.Error(t, err)
+
+			// Protocol errors are not error replies, so the connection is not pooled again
+			var replyErr redisError
+			assert.False(t, errors.As(err, &replyErr))
+		})
+	}
+}
+
+// fakeRedis serves the commands of one connection at a time with reply, recording them
+type fakeRedis struct {
+	listener net.Listener
+	commands chan []string
+	accepted chan struct{}
+}
+
+// newFakeRedis starts a server answering each command with the reply returns
+func newFakeRedis(t *testing.T, reply func(args []string) string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeRedis{listener: listener, commands: make(chan []string, 16), accepted: make(chan struct{}, 16)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.accepted <- struct{}{}
+			go server.serve(conn, reply)
+		}
+	}()
+	return server
+}
+
+// serve reads commands sent as arrays of bulk strings and writes their replies
+func (s *fakeRedis) serve(conn net.Conn, reply func(args []string) string) {
+	defer conn.Close()
+	client := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	for {
+		command, err := client.reply()
+		if err != nil {
+			return
+		}
+		items := command.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = item.(string)
+		}
+		s.commands <- args
+		if _, err := conn.Write([]byte(reply(args))); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeRedis) adapter() CacheAdapter {
+	return NewRedisCacheAdapter(configs.ChatOwnersRedis{Addr: s.listener.Addr().String(), Timeout: time.Second, PoolSize: 1})
+}
+
+func TestRedisCacheAdapterCommands(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		switch args[0] {
+		case "GET":
+			if args[1] == "missing" {
+				return "$-1\r\n"
+			}
+			return "$3\r\nbob\r\n"
+		case "SET":
+			return "+OK\r\n"
+		default:
+			return ":1\r\n"
+		}
+	})
+	cache := server.adapter()
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "chat-owner::1", "bob", 1500*time.Millisecond))
+	assert.Equal(t, []string{"SET", "chat-owner::1", "bob", "PX", "1500"}, <-server.commands)
+
+	value, ok, err := cache.Get(ctx, "chat-owner::1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "bob", value)
+	assert.Equal(t, []string{"GET", "chat-owner::1"}, <-server.commands)
+
+	// A nil bulk reply is a miss, not an error
+	value, ok, err = cache.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, value)
+	<-server.commands
+
+	require.NoError(t, cache.Delete(ctx, "chat-owner::1"))
+	assert.Equal(t, []string{"DEL", "chat-owner::1"}, <-server.commands)
+
+	// Every command went through the pooled connection
+	assert.Len(t, server.accepted, 1)
+}
+
+func TestRedisCacheAdapterErrorReply(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		if args[0] == "GET" {
+			return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+		}
+		return ":1\r\n"
+	})
+	cache := server.adapter()
+	ctx := context.Background()
+
+	_, ok, err := cache.Get(ctx, "chat-owner::1")
+	var replyErr redisError
+	require.ErrorAs(t, err, &replyErr)
+	assert.False(t, ok)
+
+	// The connection stays usable after an error reply, so it is pooled again
+	require.NoError(t, cache.Delete(ctx, "chat-owner::1"))
+	assert.Len(t, server.accepted, 1)
+}
+
+func TestRedisCacheAdapterUnexpectedReply(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		return ":1\r\n"
+	})
+
+	_, ok, err := server.adapter().Get(context.Background(), "chat-owner::1")
+	require.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestRedisCacheAdapterAuthAndSelect(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		return "+OK\r\n"
+	})
+	cache := NewRedisCacheAdapter(configs.ChatOwnersRedis{
+		Addr:     server.listener.Addr().String(),
+		Password: "secret",
+		DB:       2,
+		Timeout:  time.Second,
+	})
+
+	require.NoError(t, cache.Delete(context.Background(), "chat-owner::1"))
+	assert.Equal(t, []string{"AUTH", "secret"}, <-server.commands)
+	assert.Equal(t, []string{"SELECT", "2"}, <-server.commands)
+	assert.Equal(t, []string{"DEL", "chat-owner::1"}, <-server.commands)
+}
+
+func TestMemoryCacheAdapter(t *testing.T) {
+	cache := NewMemoryCacheAdapter()
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "a", "1", time.Minute))
+	require.NoError(t, cache.Set(ctx, "b", "2", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	value, ok, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+
+	// Expired entries are misses
+	_, ok, err = cache.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Delete(ctx, "a"))
+	_, ok, _ = cache.Get(ctx, "a")
+	assert.False(t, ok)
+}
+
+// ptr returns a pointer to a value
+func ptr(value string) *string {
+	return &value
+}
+
+func TestMemoryCacheAdapterCompareAndSwap(t *testing.T) {
+	cache := NewMemoryCacheAdapter()
+	ctx := context.Background()
+
+	// A nil old value only matches an absent key
+	swapped, err := cache.CompareAndSwap(ctx, "a", nil, ptr("1"), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	swapped, err = cache.CompareAndSwap(ctx, "a", nil, ptr("2"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = cache.CompareAndSwap(ctx, "a", ptr("2"), ptr("3"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+	swapped, err = cache.CompareAndSwap(ctx, "a", ptr("1"), ptr("2"), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	value, _, _ := cache.Get(ctx, "a")
+	assert.Equal(t, "2", value)
+
+	// A nil value deletes the key
+	swapped, err = cache.CompareAndSwap(ctx, "a", ptr("2"), nil, 0)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	_, ok, _ := cache.Get(ctx, "a")
+	assert.False(t, ok)
+
+	// Expired keys are absent
+	require.NoError(t, cache.Set(ctx, "b", "1", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	swapped, err = cache.CompareAndSwap(ctx, "b", nil, ptr("2"), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+}
+
+func TestRedisCacheAdapterCompareAndSwap(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		if args[4] == "1" && args[5] == "stale" {
+			return ":0\r\n"
+		}
+		return ":1\r\n"
+	})
+	cache := server.adapter()
+	ctx := context.Background()
+
+	swapped, err := cache.CompareAndSwap(ctx, "k", ptr("old"), ptr("new"), 1500*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	assert.Equal(t, []string{"EVAL", redisCompareAndSwap, "1", "k", "1", "old", "1", "new", "1500"}, <-server.commands)
+
+	swapped, err = cache.CompareAndSwap(ctx, "k", nil, nil, 0)
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	assert.Equal(t, []string{"EVAL", redisCompareAndSwap, "1", "k", "0", "", "0", "", "0"}, <-server.commands)
+
+	swapped, err = cache.CompareAndSwap(ctx, "k", ptr("stale"), ptr("new"), time.Second)
+	require.NoError(t, err)
+	assert.False(t, swapped)
+}
@@ -0,0 +1,225 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// CrawlerAdapter defines the interface for crawling websites
+type CrawlerAdapter interface {
+	// Robots fetches the robots.txt rules of the site of a URL
+	Robots(ctx context.Context, siteURL string) (*RobotsRules, error)
+
+	// Sitemap fetches a sitemap or a sitemap index
+	Sitemap(ctx context.Context, sitemapURL string) (*dtos.Sitemap, error)
+
+	// Page fetches a page with its readable text and links
+	Page(ctx context.Context, pageURL string) (*dtos.CrawledPage, error)
+}
+
+// crawlerAdapter crawls public websites over HTTP, with the same connection checks as the
+// web adapter
+type crawlerAdapter struct {
+	client    *http.Client
+	userAgent string
+	allowlist []string
+	maxBytes  int64
+}
+
+// NewCrawlerAdapter creates a crawler adapter
+func NewCrawlerAdapter(config configs.Crawler) CrawlerAdapter {
+	a := &crawlerAdapter{
+		userAgent: config.UserAgent,
+		allowlist: config.Allowlist,
+		maxBytes:  config.MaxBytes,
+	}
+	a.client = newPublicHTTPClient(config.Timeout, a.checkURL)
+	return a
+}
+
+// Robots fetches the robots.txt rules of the site of a URL. Sites without robots.txt allow
+// everything, while unreachable ones fail so they are not crawled against their rules.
+func (a *crawlerAdapter) Robots(ctx context.Context, siteURL string) (*RobotsRules, error) {
+	site, err := url.Parse(siteURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrURLNotAllowed, err)
+	}
+	robotsURL := &url.URL{Scheme: site.Scheme, Host: site.Host, Path: "/robots.txt"}
+
+	resp, err := a.get(ctx, robotsURL.String(), "text/plain")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return ParseRobots(io.LimitReader(resp.Body, a.maxBytes), a.userAgent), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return &RobotsRules{}, nil
+	default:
+		return nil, fmt.Errorf("robots.txt returned status %d", resp.StatusCode)
+	}
+}
+
+// sitemapXML is a sitemap or a sitemap index
+type sitemapXML struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// Sitemap fetches a sitemap or a sitemap index
+func (a *crawlerAdapter) Sitemap(ctx context.Context, sitemapURL string) (*dtos.Sitemap, error) {
+	resp, err := a.get(ctx, sitemapURL, "application/xml, text/xml")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("sitemap returned status %d", resp.StatusCode)
+	}
+
+	var content sitemapXML
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, a.maxBytes)).Decode(&content); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+
+	sitemap := &dtos.Sitemap{}
+	for _, u := range content.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			sitemap.Pages = append(sitemap.Pages, loc)
+		}
+	}
+	for _, s := range content.Sitemaps {
+		if loc := strings.TrimSpace(s.Loc); loc != "" {
+			sitemap.Sitemaps = append(sitemap.Sitemaps, loc)
+		}
+	}
+	return sitemap, nil
+}
+
+// Page fetches a page with its readable text and links. Pages over the size limit are cut off.
+func (a *crawlerAdapter) Page(ctx context.Context, pageURL string) (*dtos.CrawledPage, error) {
+	resp, err := a.get(ctx, pageURL, "text/html, text/plain;q=0.9")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("web page returned status %d", resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, a.maxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read web page: %w", err)
+	}
+
+	page := &dtos.CrawledPage{WebPage: dtos.WebPage{URL: resp.Request.URL.String()}}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		page.Title, page.Text, err = extractReadableText(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse web page: %w", err)
+		}
+		page.Links = extractLinks(bytes.NewReader(content), resp.Request.URL)
+	case "text/plain":
+		page.Text = strings.TrimSpace(strings.ToValidUTF8(string(content), ""))
+	default:
+		return nil, fmt.Errorf("unsupported web page content type %q", mediaType)
+	}
+
+	return page, nil
+}
+
+// get sends a GET request to a URL allowed for crawling
+func (a *crawlerAdapter) get(ctx context.Context, rawURL, accept string) (*http.Response, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrURLNotAllowed, err)
+	}
+	if err := a.checkURL(target); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", a.userAgent)
+
+	return a.client.Do(req)
+}
+
+// checkURL checks a URL is an HTTP(S) URL on a default port of an allowed host
+func (a *crawlerAdapter) checkURL(u *url.URL) error {
+	return checkPublicURL(u, a.allowlist)
+}
+
+// extractLinks returns the absolute HTTP(S) URLs of the links of an HTML page, without
+// fragments and in order of appearance. Links marked nofollow are left out.
+func extractLinks(r io.Reader, base *url.URL) []string {
+	tokenizer := html.NewTokenizer(r)
+	seen := make(map[string]bool)
+	var links []string
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if atom.Lookup(name) != atom.A || !hasAttr {
+				continue
+			}
+
+			var href string
+			nofollow := false
+			for {
+				key, value, more := tokenizer.TagAttr()
+				switch string(key) {
+				case "href":
+					href = string(value)
+				case "rel":
+					nofollow = strings.Contains(strings.ToLower(string(value)), "nofollow")
+				}
+				if !more {
+					break
+				}
+			}
+			if href == "" || nofollow {
+				continue
+			}
+
+			link, err := base.Parse(strings.TrimSpace(href))
+			if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+				continue
+			}
+			link.Fragment, link.RawFragment = "", ""
+			if s := link.String(); !seen[s] {
+				seen[s] = true
+				links = append(links, s)
+			}
+		}
+	}
+}
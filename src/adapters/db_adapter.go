@@ -2,7 +2,10 @@ package adapters
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/nvnamsss/chat/src/configs"
 	"github.com/nvnamsss/chat/src/logger"
@@ -16,18 +19,70 @@ type DBAdapter interface {
 	Close() error
 	Ping(ctx context.Context) error
 	AutoMigrate(models ...interface{}) error
+	// Regions returns the data residency regions served, the main database first
+	Regions() []string
 }
 
 // dbAdapter implements the DBAdapter interface
 type dbAdapter struct {
-	db *gorm.DB
+	db         *gorm.DB
+	mainRegion string
+	regions    map[string]*gorm.DB // Databases of the other regions
 }
 
-// NewDBAdapter creates a new database adapter
+// NewDBAdapter creates a new database adapter. With regions configured, every statement runs
+// on the database of the region of its context.
 func NewDBAdapter(config configs.Database) (DBAdapter, error) {
+	db, err := openDB(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := registerQueryMetrics(db, config.SlowQueryThreshold); err != nil {
+		return nil, fmt.Errorf("failed to register query metrics: %w", err)
+	}
+	if err := registerQueryTimeouts(db, config); err != nil {
+		return nil, fmt.Errorf("failed to register query timeouts: %w", err)
+	}
+
+	adapter := &dbAdapter{db: db, mainRegion: config.Region, regions: map[string]*gorm.DB{}}
+	if len(config.Regions) == 0 {
+		return adapter, nil
+	}
+
+	main, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying *sql.DB: %w", err)
+	}
+	pool := &regionPool{main: main, mainRegion: config.Region, regions: map[string]*sql.DB{}}
+	for name, region := range config.Regions {
+		if name == config.Region {
+			return nil, fmt.Errorf("region %s is both the main region and another one", name)
+		}
+		regionDB, err := openDB(config.ForRegion(name, region))
+		if err != nil {
+			adapter.Close()
+			return nil, fmt.Errorf("region %s: %w", name, err)
+		}
+		adapter.regions[name] = regionDB
+		if pool.regions[name], err = regionDB.DB(); err != nil {
+			adapter.Close()
+			return nil, fmt.Errorf("failed to get underlying *sql.DB: %w", err)
+		}
+	}
+
+	// Statements run through the main GORM instance, with its metrics and timeouts, on the
+	// connections of their region
+	db.Config.ConnPool = pool
+	db.Statement.ConnPool = pool
+	return adapter, nil
+}
+
+// openDB connects to a database and configures its connection pool
+func openDB(config configs.Database) (*gorm.DB, error) {
 	// Configure GORM
 	gormConfig := &gorm.Config{
-		Logger: logger.NewGormLogger(),
+		Logger: logger.NewGormLogger(config.SlowQueryThreshold),
 	}
 
 	// Connect to database
@@ -46,9 +101,10 @@ func NewDBAdapter(config configs.Database) (DBAdapter, error) {
 
 	logger.Info("Connected to database",
 		logger.Field("host", config.Host),
-		logger.Field("database", config.Name))
+		logger.Field("database", config.Name),
+		logger.Field("region", config.Region))
 
-	return &dbAdapter{db: db}, nil
+	return db, nil
 }
 
 // GetDB returns the database connection
@@ -56,26 +112,63 @@ func (a *dbAdapter) GetDB() *gorm.DB {
 	return a.db
 }
 
-// Close closes the database connection
+// Close closes the database connections
 func (a *dbAdapter) Close() error {
 	logger.Info("Closing database connection")
-	sqlDB, err := a.db.DB()
-	if err != nil {
-		return fmt.Errorf("failed to get underlying *sql.DB: %w", err)
+	var errs []error
+	for _, db := range a.all() {
+		sqlDB, err := db.DB()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get underlying *sql.DB: %w", err))
+			continue
+		}
+		errs = append(errs, sqlDB.Close())
 	}
-	return sqlDB.Close()
+	return errors.Join(errs...)
 }
 
-// Ping checks the database connection
+// Ping checks the connection to the database of every region
 func (a *dbAdapter) Ping(ctx context.Context) error {
-	sqlDB, err := a.db.DB()
-	if err != nil {
-		return fmt.Errorf("failed to get underlying *sql.DB: %w", err)
+	for _, db := range a.all() {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return fmt.Errorf("failed to get underlying *sql.DB: %w", err)
+		}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			return err
+		}
 	}
-	return sqlDB.PingContext(ctx)
+	return nil
 }
 
-// AutoMigrate runs GORM auto-migration for the given models
+// AutoMigrate runs GORM auto-migration for the given models in every region
 func (a *dbAdapter) AutoMigrate(models ...interface{}) error {
-	return a.db.AutoMigrate(models...)
+	if err := a.db.AutoMigrate(models...); err != nil {
+		return err
+	}
+	for name, db := range a.regions {
+		if err := db.AutoMigrate(models...); err != nil {
+			return fmt.Errorf("region %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Regions returns the data residency regions served, the main database first
+func (a *dbAdapter) Regions() []string {
+	regions := []string{a.mainRegion}
+	for name := range a.regions {
+		regions = append(regions, name)
+	}
+	sort.Strings(regions[1:])
+	return regions
+}
+
+// all returns the GORM instances of the main database and of the other regions
+func (a *dbAdapter) all() []*gorm.DB {
+	all := []*gorm.DB{a.db}
+	for _, db := range a.regions {
+		all = append(all, db)
+	}
+	return all
 }
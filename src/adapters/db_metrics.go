@@ -0,0 +1,56 @@
+package adapters
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nvnamsss/chat/src/metrics"
+	"gorm.io/gorm"
+)
+
+// queryStartKey holds the start time of a statement in the GORM instance
+const queryStartKey = "metrics:query_start"
+
+// registerQueryMetrics records the latency of every statement, and counts the ones
+// slower than slowThreshold, by table and operation
+func registerQueryMetrics(db *gorm.DB, slowThreshold time.Duration) error {
+	before := func(tx *gorm.DB) {
+		tx.InstanceSet(queryStartKey, time.Now())
+	}
+	after := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			value, ok := tx.InstanceGet(queryStartKey)
+			if !ok {
+				return
+			}
+			elapsed := time.Since(value.(time.Time))
+
+			// Raw statements have no table
+			table := tx.Statement.Table
+			if table == "" {
+				table = "raw"
+			}
+
+			metrics.DBQueryLatency.WithLabelValues(table, operation).Observe(elapsed.Seconds())
+			if slowThreshold > 0 && elapsed > slowThreshold {
+				metrics.DBSlowQueries.WithLabelValues(table, operation).Inc()
+			}
+		}
+	}
+
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("*").Register("metrics:before_create", before),
+		callback.Create().After("*").Register("metrics:after_create", after("create")),
+		callback.Query().Before("*").Register("metrics:before_query", before),
+		callback.Query().After("*").Register("metrics:after_query", after("query")),
+		callback.Update().Before("*").Register("metrics:before_update", before),
+		callback.Update().After("*").Register("metrics:after_update", after("update")),
+		callback.Delete().Before("*").Register("metrics:before_delete", before),
+		callback.Delete().After("*").Register("metrics:after_delete", after("delete")),
+		callback.Row().Before("*").Register("metrics:before_row", before),
+		callback.Row().After("*").Register("metrics:after_row", after("row")),
+		callback.Raw().Before("*").Register("metrics:before_raw", before),
+		callback.Raw().After("*").Register("metrics:after_raw", after("raw")),
+	)
+}
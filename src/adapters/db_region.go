@@ -0,0 +1,103 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RegionHeader is the event header carrying the data residency region of the request that
+// published the event, so consumers read and write the same region
+const RegionHeader = "X-Data-Region"
+
+// regionKey is the context key of the data residency region of a request
+type regionKey struct{}
+
+// WithRegion routes the queries run with the returned context to the database of region.
+// An empty region is that of the main database.
+func WithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, region)
+}
+
+// RegionOf returns the data residency region of the queries run with ctx, empty for the
+// region of the main database
+func RegionOf(ctx context.Context) string {
+	region, _ := ctx.Value(regionKey{}).(string)
+	return region
+}
+
+// regionPool is the GORM connection pool routing every statement to the database of the
+// region of its context. Transactions begin on the database of their context too, so all
+// their statements stay in the same region.
+type regionPool struct {
+	main       *sql.DB
+	mainRegion string
+	regions    map[string]*sql.DB
+}
+
+// route returns the database of the region of ctx
+func (p *regionPool) route(ctx context.Context) (*sql.DB, error) {
+	region := RegionOf(ctx)
+	if region == "" || region == p.mainRegion {
+		return p.main, nil
+	}
+	if db, ok := p.regions[region]; ok {
+		return db, nil
+	}
+	// Never fall back to another region: the data would leave its region
+	return nil, fmt.Errorf("unknown data residency region %q", region)
+}
+
+// PrepareContext prepares a statement on the database of the region of ctx
+func (p *regionPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	db, err := p.route(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.PrepareContext(ctx, query)
+}
+
+// ExecContext runs a statement on the database of the region of ctx
+func (p *regionPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	db, err := p.route(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs a query on the database of the region of ctx
+func (p *regionPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	db, err := p.route(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a single row query on the database of the region of ctx. A row cannot
+// be created with an error, so the query of an unknown region is canceled before it gets a
+// connection.
+func (p *regionPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	db, err := p.route(ctx)
+	if err != nil {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		return p.main.QueryRowContext(canceled, query, args...)
+	}
+	return db.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx begins a transaction on the database of the region of ctx
+func (p *regionPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	db, err := p.route(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.BeginTx(ctx, opts)
+}
+
+// GetDBConn returns the main database, used for its connection pool settings and locks
+func (p *regionPool) GetDBConn() (*sql.DB, error) {
+	return p.main, nil
+}
@@ -0,0 +1,93 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"gorm.io/gorm"
+)
+
+// GORM instance keys of the statement context replaced by its deadline
+const (
+	queryContextKey = "timeout:query_context"
+	queryCancelKey  = "timeout:query_cancel"
+)
+
+// queryCanceledState is the SQLSTATE of statements canceled by the server, which
+// Postgres reports when a canceled context had the driver cancel the statement
+const queryCanceledState = "57014"
+
+// searchQueryKey marks the contexts of search queries
+type searchQueryKey struct{}
+
+// WithSearchTimeout marks the queries run with the returned context as searches, which
+// get the search timeout instead of the query timeout
+func WithSearchTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, searchQueryKey{}, true)
+}
+
+// IsQueryTimeout reports whether a database error is a statement running out of time
+func IsQueryTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == queryCanceledState
+}
+
+// registerQueryTimeouts bounds every statement with the configured timeout, so a slow
+// query releases its connection instead of holding it until it completes. Row queries
+// are left out: their rows are read after the statement callbacks return.
+func registerQueryTimeouts(db *gorm.DB, config configs.Database) error {
+	if config.QueryTimeout <= 0 && config.SearchTimeout <= 0 {
+		return nil
+	}
+
+	before := func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		timeout := queryTimeoutOf(ctx, config)
+		if timeout <= 0 {
+			return
+		}
+
+		// The statement may be reused by the caller, so its own context is restored after
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		tx.InstanceSet(queryContextKey, ctx)
+		tx.InstanceSet(queryCancelKey, cancel)
+		tx.Statement.Context = timeoutCtx
+	}
+	after := func(tx *gorm.DB) {
+		cancel, ok := tx.InstanceGet(queryCancelKey)
+		if !ok {
+			return
+		}
+		cancel.(context.CancelFunc)()
+		if ctx, ok := tx.InstanceGet(queryContextKey); ok {
+			tx.Statement.Context = ctx.(context.Context)
+		}
+	}
+
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("*").Register("timeout:before_create", before),
+		callback.Create().After("*").Register("timeout:after_create", after),
+		callback.Query().Before("*").Register("timeout:before_query", before),
+		callback.Query().After("*").Register("timeout:after_query", after),
+		callback.Update().Before("*").Register("timeout:before_update", before),
+		callback.Update().After("*").Register("timeout:after_update", after),
+		callback.Delete().Before("*").Register("timeout:before_delete", before),
+		callback.Delete().After("*").Register("timeout:after_delete", after),
+		callback.Raw().Before("*").Register("timeout:before_raw", before),
+		callback.Raw().After("*").Register("timeout:after_raw", after),
+	)
+}
+
+// queryTimeoutOf returns the timeout applied to the statements of a context
+func queryTimeoutOf(ctx context.Context, config configs.Database) time.Duration {
+	if search, _ := ctx.Value(searchQueryKey{}).(bool); search && config.SearchTimeout > 0 {
+		return config.SearchTimeout
+	}
+	return config.QueryTimeout
+}
@@ -0,0 +1,161 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// DiscordInteractionsPath is the interactions endpoint URL of the Discord application
+const DiscordInteractionsPath = "/integrations/discord/interactions"
+
+// Headers of the signature of Discord interactions
+const (
+	DiscordSignatureHeader = "X-Signature-Ed25519"
+	DiscordTimestampHeader = "X-Signature-Timestamp"
+)
+
+// discordMaxSkew is how old the timestamp of an interaction may be, against replays
+const discordMaxSkew = 5 * time.Minute
+
+// ErrDiscordUnauthorized is returned for interactions without a valid signature
+var ErrDiscordUnauthorized = errors.New("invalid Discord interaction signature")
+
+// DiscordAdapter defines the interface for answering Discord interactions
+type DiscordAdapter interface {
+	// Verify verifies the signature of an interaction request by the application public key
+	Verify(signature, timestamp string, body []byte) error
+
+	// EditResponse replaces the original response to an interaction
+	EditResponse(ctx context.Context, token string, message *dtos.DiscordMessage) error
+
+	// SendFollowup sends a follow-up message to an interaction
+	SendFollowup(ctx context.Context, token string, message *dtos.DiscordMessage) error
+}
+
+// discordAdapter implements DiscordAdapter with the webhooks of interactions, which their
+// token authorizes without a bot token
+type discordAdapter struct {
+	client    *http.Client
+	config    configs.Discord
+	publicKey ed25519.PublicKey
+}
+
+// NewDiscordAdapter creates a Discord adapter for the configured application
+func NewDiscordAdapter(config configs.Discord) (DiscordAdapter, error) {
+	if config.ApplicationID == "" {
+		return nil, fmt.Errorf("the Discord application ID is required")
+	}
+	publicKey, err := hex.DecodeString(config.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("the Discord public key must be a hex-encoded Ed25519 key")
+	}
+
+	return &discordAdapter{
+		client:    &http.Client{Timeout: config.Timeout},
+		config:    config,
+		publicKey: publicKey,
+	}, nil
+}
+
+// Verify verifies the Ed25519 signature of the timestamp and body of an interaction request
+func (a *discordAdapter) Verify(signature, timestamp string, body []byte) error {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return ErrDiscordUnauthorized
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrDiscordUnauthorized
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > discordMaxSkew || skew < -discordMaxSkew {
+		return fmt.Errorf("%w: stale timestamp", ErrDiscordUnauthorized)
+	}
+
+	if !ed25519.Verify(a.publicKey, append([]byte(timestamp), body...), sig) {
+		return ErrDiscordUnauthorized
+	}
+	return nil
+}
+
+// EditResponse replaces the original response to an interaction
+func (a *discordAdapter) EditResponse(ctx context.Context, token string, message *dtos.DiscordMessage) error {
+	return a.send(ctx, http.MethodPatch, a.webhookURL(token)+"/messages/@original", message)
+}
+
+// SendFollowup sends a follow-up message to an interaction
+func (a *discordAdapter) SendFollowup(ctx context.Context, token string, message *dtos.DiscordMessage) error {
+	return a.send(ctx, http.MethodPost, a.webhookURL(token), message)
+}
+
+// webhookURL returns the URL of the webhook of an interaction
+func (a *discordAdapter) webhookURL(token string) string {
+	return strings.TrimSuffix(a.config.APIURL, "/") + "/webhooks/" + url.PathEscape(a.config.ApplicationID) + "/" + url.PathEscape(token)
+}
+
+// send sends a message to the webhook of an interaction, retrying once when rate limited
+func (a *discordAdapter) send(ctx context.Context, method, endpoint string, message *dtos.DiscordMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := a.do(ctx, method, endpoint, body)
+		if retryAfter == 0 || attempt > 0 {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryAfter):
+		}
+	}
+}
+
+// do sends a request to Discord. When it is rate limited, it returns how long to wait
+// before retrying.
+func (a *discordAdapter) do(ctx context.Context, method, endpoint string, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to Discord: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return 0, nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("Discord returned error: %d %s", resp.StatusCode, detail)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var limited struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		if json.Unmarshal(detail, &limited) == nil && limited.RetryAfter > 0 {
+			return time.Duration(limited.RetryAfter * float64(time.Second)), err
+		}
+	}
+	return 0, err
+}
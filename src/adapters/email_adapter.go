@@ -0,0 +1,147 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// EmailAdapter defines the interface for sending emails
+type EmailAdapter interface {
+	// Send sends an email
+	Send(ctx context.Context, email *dtos.Email) error
+}
+
+// smtpEmailAdapter sends emails through an SMTP server
+type smtpEmailAdapter struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPEmailAdapter creates an email adapter sending through the configured SMTP server.
+// STARTTLS is used when the server supports it.
+func NewSMTPEmailAdapter(config configs.Email) (EmailAdapter, error) {
+	return newSMTPEmailAdapter(config, config.Host)
+}
+
+// NewSESEmailAdapter creates an email adapter sending through the SMTP interface of
+// Amazon SES in the configured region, authenticated with SES SMTP credentials
+func NewSESEmailAdapter(config configs.Email) (EmailAdapter, error) {
+	return newSMTPEmailAdapter(config, fmt.Sprintf("email-smtp.%s.amazonaws.com", config.Region))
+}
+
+// newSMTPEmailAdapter creates an email adapter sending through host
+func newSMTPEmailAdapter(config configs.Email, host string) (EmailAdapter, error) {
+	if host == "" {
+		return nil, fmt.Errorf("email host is required")
+	}
+	if _, err := mail.ParseAddress(config.From); err != nil {
+		return nil, fmt.Errorf("invalid email sender %q: %w", config.From, err)
+	}
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, host)
+	}
+
+	return &smtpEmailAdapter{
+		addr: fmt.Sprintf("%s:%d", host, config.Port),
+		from: config.From,
+		auth: auth,
+	}, nil
+}
+
+// Send sends an email as a multipart message with text and HTML alternatives
+func (a *smtpEmailAdapter) Send(ctx context.Context, email *dtos.Email) error {
+	from, err := mail.ParseAddress(a.from)
+	if err != nil {
+		return err
+	}
+
+	message, err := buildMessage(a.from, email)
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+
+	if err := smtp.SendMail(a.addr, a.auth, from.Address, []string{email.To}, message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// buildMessage renders an email as a MIME message
+func buildMessage(from string, email *dtos.Email) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	alternatives := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	}
+	for _, alternative := range alternatives {
+		if alternative.content == "" {
+			continue
+		}
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(part)
+		if _, err := encoder.Write([]byte(alternative.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", email.To)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if email.ListUnsubscribe != "" {
+		fmt.Fprintf(&message, "List-Unsubscribe: <%s>\r\n", email.ListUnsubscribe)
+		fmt.Fprintf(&message, "List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	}
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// logEmailAdapter only logs emails, for development
+type logEmailAdapter struct{}
+
+// NewLogEmailAdapter creates an email adapter that logs emails instead of sending them
+func NewLogEmailAdapter() EmailAdapter {
+	return &logEmailAdapter{}
+}
+
+// Send logs the email
+func (a *logEmailAdapter) Send(ctx context.Context, email *dtos.Email) error {
+	logger.Context(ctx).Infow("Email not sent, logging only", "to", email.To, "subject", email.Subject)
+	return nil
+}
@@ -0,0 +1,329 @@
+package adapters
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+)
+
+// spoolSegmentExt is the extension of the files of the spool
+const spoolSegmentExt = ".jsonl"
+
+// spoolProducer publishes to a broker, spilling the messages to files on disk while the
+// broker is unavailable and replaying them in order once it recovers. Publishing waits for
+// the broker at most PublishTimeout, and not at all while messages are spooled, so an outage
+// does not slow down requests. Messages are spooled after the first failure until the spool
+// is replayed, keeping them in order, and are published at least once: a message that timed
+// out may still reach the broker, and a replay interrupted by a restart starts its file over.
+type spoolProducer struct {
+	producer queue.Producer
+	config   configs.Spool
+
+	mu          sync.Mutex
+	segments    []string // Closed files, oldest first
+	current     *os.File // File messages are appended to, if any
+	currentSize int64
+	next        int64 // Number of the next file
+	replayed    int   // Lines of the oldest file already replayed
+	size        int64 // Bytes of every file
+	down        bool  // Whether the broker failed since the spool was last replayed
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// spooledMessage is a message written to the spool, one per line
+type spooledMessage struct {
+	Topic   string            `json:"topic"`
+	Key     []byte            `json:"key,omitempty"`
+	Value   []byte            `json:"value"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// NewSpoolProducer wraps a producer with a spool in the configured directory. Messages left
+// in the spool by a previous run are replayed first.
+func NewSpoolProducer(producer queue.Producer, config configs.Spool) (queue.Producer, error) {
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create the spool directory: %w", err)
+	}
+	if config.ReplayBatch < 1 {
+		config.ReplayBatch = 1
+	}
+
+	p := &spoolProducer{
+		producer: producer,
+		config:   config,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	if len(p.segments) > 0 {
+		logger.Info("Found spooled events to replay", logger.Field("files", len(p.segments)), logger.Field("bytes", p.size))
+	}
+
+	go p.replayLoop()
+	return p, nil
+}
+
+// load lists the files left in the spool directory
+func (p *spoolProducer) load() error {
+	entries, err := os.ReadDir(p.config.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read the spool directory: %w", err)
+	}
+
+	var numbers []int64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, spoolSegmentExt) {
+			continue
+		}
+		number, err := strconv.ParseInt(strings.TrimSuffix(name, spoolSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to read the spool directory: %w", err)
+		}
+		numbers = append(numbers, number)
+		p.size += info.Size()
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	for _, number := range numbers {
+		p.segments = append(p.segments, p.segmentPath(number))
+		p.next = number + 1
+	}
+	metrics.EventSpoolBytes.Set(float64(p.size))
+	return nil
+}
+
+// segmentPath returns the path of the file of a number, named so that files sort in order
+func (p *spoolProducer) segmentPath(number int64) string {
+	return filepath.Join(p.config.Dir, fmt.Sprintf("%020d%s", number, spoolSegmentExt))
+}
+
+// Produce publishes messages to the broker, or spools them when the broker is down, fails
+// within PublishTimeout, or older messages are still spooled. It only fails when the spool
+// is full, dropping the messages over its size.
+func (p *spoolProducer) Produce(ctx context.Context, messages ...queue.Message) error {
+	p.mu.Lock()
+	spooling := p.down || p.size > 0
+	p.mu.Unlock()
+
+	if !spooling {
+		// Requests cancelled by their client must not count as broker failures
+		produceCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.config.PublishTimeout)
+		err := p.producer.Produce(produceCtx, messages...)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		logger.Context(ctx).Warnw("Broker unavailable, spooling events", "error", err)
+	}
+
+	return p.spool(messages)
+}
+
+// spool appends messages to the current file, starting a new one when it is full
+func (p *spoolProducer) spool(messages []queue.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = true
+
+	dropped := 0
+	for _, message := range messages {
+		line, err := json.Marshal(spooledMessage{Topic: message.Topic, Key: message.Key, Value: message.Value, Headers: message.Headers})
+		if err != nil {
+			return fmt.Errorf("failed to encode spooled event: %w", err)
+		}
+		line = append(line, '\n')
+		if p.size+int64(len(line)) > p.config.MaxBytes {
+			dropped++
+			continue
+		}
+
+		if p.current == nil {
+			file, err := os.OpenFile(p.segmentPath(p.next), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return fmt.Errorf("failed to create spool file: %w", err)
+			}
+			p.current, p.currentSize = file, 0
+			p.next++
+		}
+		if _, err := p.current.Write(line); err != nil {
+			return fmt.Errorf("failed to write spooled event: %w", err)
+		}
+		p.currentSize += int64(len(line))
+		p.size += int64(len(line))
+		metrics.EventsSpooled.Inc()
+
+		if p.currentSize >= p.config.SegmentBytes {
+			p.rotate()
+		}
+	}
+	metrics.EventSpoolBytes.Set(float64(p.size))
+
+	if dropped > 0 {
+		metrics.EventsDropped.Add(float64(dropped))
+		return fmt.Errorf("event spool is full, dropped %d events", dropped)
+	}
+	return nil
+}
+
+// rotate closes the current file, queueing it for replay. p.mu must be held.
+func (p *spoolProducer) rotate() {
+	if p.current == nil {
+		return
+	}
+	if err := p.current.Close(); err != nil {
+		logger.Warn("Failed to close spool file", logger.Field("error", err))
+	}
+	p.segments = append(p.segments, p.current.Name())
+	p.current, p.currentSize = nil, 0
+}
+
+// replayLoop replays the spool every ReplayInterval until the producer is closed
+func (p *spoolProducer) replayLoop() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.config.ReplayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.replay()
+		}
+	}
+}
+
+// replay publishes the spooled messages in order, deleting each file once it is published,
+// until the spool is empty or the broker fails again
+func (p *spoolProducer) replay() {
+	for {
+		p.mu.Lock()
+		if len(p.segments) == 0 {
+			p.rotate()
+		}
+		if len(p.segments) == 0 {
+			if p.down {
+				logger.Info("Event spool replayed, publishing to the broker again")
+			}
+			p.down = false
+			p.mu.Unlock()
+			return
+		}
+		path, replayed := p.segments[0], p.replayed
+		p.mu.Unlock()
+
+		size, err := p.replaySegment(path, replayed)
+		if err != nil {
+			logger.Debug("Failed to replay spooled events", logger.Field("file", path), logger.Field("error", err))
+			return
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Warn("Failed to delete replayed spool file", logger.Field("file", path), logger.Field("error", err))
+		}
+
+		p.mu.Lock()
+		p.segments = p.segments[1:]
+		p.replayed = 0
+		p.size -= size
+		metrics.EventSpoolBytes.Set(float64(p.size))
+		p.mu.Unlock()
+	}
+}
+
+// replaySegment publishes the messages of a file after its first skip lines, in batches,
+// and returns the size of the file. The lines published are counted in p.replayed, so a
+// failed replay resumes after them.
+func (p *spoolProducer) replaySegment(path string, skip int) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var size int64
+	reader := bufio.NewReader(file)
+	batch := make([]queue.Message, 0, p.config.ReplayBatch)
+	flush := func(lines int) error {
+		if len(batch) == 0 {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.PublishTimeout)
+		defer cancel()
+		if err := p.producer.Produce(ctx, batch...); err != nil {
+			return err
+		}
+		metrics.EventsReplayed.Add(float64(len(batch)))
+		p.mu.Lock()
+		p.replayed = lines
+		p.mu.Unlock()
+		batch = batch[:0]
+		return nil
+	}
+
+	index := 0
+	for ; ; index++ {
+		line, err := reader.ReadBytes('\n')
+		size += int64(len(line))
+		if err == io.EOF {
+			// A line cut by a crash is left out
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if index < skip {
+			continue
+		}
+
+		var message spooledMessage
+		if err := json.Unmarshal(line, &message); err != nil {
+			logger.Warn("Skipping unreadable spooled event", logger.Field("file", path), logger.Field("error", err))
+			continue
+		}
+		batch = append(batch, queue.Message{Topic: message.Topic, Key: message.Key, Value: message.Value, Headers: message.Headers})
+		if len(batch) == p.config.ReplayBatch {
+			if err := flush(index + 1); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := flush(index); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// Close stops replaying and closes the broker producer. Messages still spooled are kept on
+// disk and replayed by the next run.
+func (p *spoolProducer) Close() error {
+	close(p.stop)
+	<-p.done
+
+	p.mu.Lock()
+	p.rotate()
+	p.mu.Unlock()
+	return p.producer.Close()
+}
@@ -0,0 +1,218 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// Image generation providers
+const (
+	ImageProviderDALLE     = "dalle"
+	ImageProviderStability = "stability"
+)
+
+// Default base URLs of the image generation providers
+const (
+	dalleBaseURL     = "https://api.openai.com/v1"
+	stabilityBaseURL = "https://api.stability.ai"
+)
+
+// GeneratedImage is an image generated from a prompt
+type GeneratedImage struct {
+	Data        []byte
+	ContentType string
+	// RevisedPrompt is the prompt the provider rewrote the request into, when it does
+	RevisedPrompt string
+}
+
+// ImageAdapter defines the interface for generating images from text prompts
+type ImageAdapter interface {
+	// Generate generates count images of the prompt
+	Generate(ctx context.Context, prompt string, count int) ([]*GeneratedImage, error)
+}
+
+// NewImageAdapter creates an image generation adapter for the configured provider:
+// DALL·E through the OpenAI images API, or Stability AI
+func NewImageAdapter(config configs.Images) (ImageAdapter, error) {
+	client := &http.Client{Timeout: config.Timeout}
+
+	switch config.Provider {
+	case ImageProviderDALLE:
+		baseURL := config.BaseURL
+		if baseURL == "" {
+			baseURL = dalleBaseURL
+		}
+		return &dalleAdapter{
+			client:  client,
+			baseURL: strings.TrimSuffix(baseURL, "/"),
+			apiKey:  config.APIKey,
+			model:   config.Model,
+			size:    config.Size,
+		}, nil
+	case ImageProviderStability:
+		baseURL := config.BaseURL
+		if baseURL == "" {
+			baseURL = stabilityBaseURL
+		}
+		return &stabilityAdapter{
+			client:  client,
+			baseURL: strings.TrimSuffix(baseURL, "/"),
+			apiKey:  config.APIKey,
+			model:   config.Model,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown image generation provider %q", config.Provider)
+	}
+}
+
+// dalleAdapter generates images with the OpenAI images API
+type dalleAdapter struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+	size    string
+}
+
+// Generate requests the images and decodes them from their base64 encoding
+func (a *dalleAdapter) Generate(ctx context.Context, prompt string, count int) ([]*GeneratedImage, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model":           a.model,
+		"prompt":          prompt,
+		"n":               count,
+		"size":            a.size,
+		"response_format": "b64_json",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/images/generations", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to image generation service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("image generation service returned error: %d %s", resp.StatusCode, detail)
+	}
+
+	var result struct {
+		Data []struct {
+			B64JSON       string `json:"b64_json"`
+			RevisedPrompt string `json:"revised_prompt"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse generated images: %w", err)
+	}
+
+	images := make([]*GeneratedImage, 0, len(result.Data))
+	for _, item := range result.Data {
+		data, err := base64.StdEncoding.DecodeString(item.B64JSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode generated image: %w", err)
+		}
+		images = append(images, &GeneratedImage{Data: data, ContentType: "image/png", RevisedPrompt: item.RevisedPrompt})
+	}
+
+	logger.Context(ctx).Debugw("Images generated", "provider", ImageProviderDALLE, "count", len(images))
+	return images, nil
+}
+
+// stabilityAdapter generates images with the Stability AI stable image API, whose model
+// selects the endpoint: core, ultra or sd3
+type stabilityAdapter struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+// Generate requests the images one at a time, as the API generates one per request
+func (a *stabilityAdapter) Generate(ctx context.Context, prompt string, count int) ([]*GeneratedImage, error) {
+	images := make([]*GeneratedImage, 0, count)
+	for range count {
+		image, err := a.generate(ctx, prompt)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+
+	logger.Context(ctx).Debugw("Images generated", "provider", ImageProviderStability, "count", len(images))
+	return images, nil
+}
+
+// generate requests one image
+func (a *stabilityAdapter) generate(ctx context.Context, prompt string) (*GeneratedImage, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("prompt", prompt); err != nil {
+		return nil, err
+	}
+	if err := form.WriteField("output_format", "png"); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/v2beta/stable-image/generate/"+a.model, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	if a.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to image generation service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("image generation service returned error: %d %s", resp.StatusCode, detail)
+	}
+
+	var result struct {
+		Image        string `json:"image"`
+		FinishReason string `json:"finish_reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse generated image: %w", err)
+	}
+	if result.FinishReason != "" && result.FinishReason != "SUCCESS" {
+		return nil, fmt.Errorf("image generation did not complete: %s", result.FinishReason)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(result.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode generated image: %w", err)
+	}
+	return &GeneratedImage{Data: data, ContentType: "image/png"}, nil
+}
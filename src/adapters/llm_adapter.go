@@ -6,12 +6,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/nvnamsss/chat/src/configs"
 	"github.com/nvnamsss/chat/src/dtos"
 	"github.com/nvnamsss/chat/src/errors"
 	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
 )
 
 // LLMAdapter defines the interface for LLM service communication
@@ -19,27 +22,50 @@ type LLMAdapter interface {
 	GenerateResponse(ctx context.Context, request *dtos.LLMRequest) (*dtos.LLMResponse, error)
 }
 
+// LLMStreamer is implemented by LLM adapters that can deliver a response incrementally.
+// onChunk is invoked for every chunk; returning an error from it aborts the stream.
+type LLMStreamer interface {
+	StreamResponse(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error)
+}
+
 // llmAdapter implements the LLMAdapter interface
 type llmAdapter struct {
-	client  *http.Client
-	baseURL string
-	apiKey  string
-	model   string
+	client      *http.Client
+	baseURL     string
+	keys        *LLMKeyPool
+	model       string
+	connections configs.LLMConnections
+	passthrough configs.LLMPassthrough
+	lastUsed    atomic.Int64 // Unix nanoseconds of the last request sent
 }
 
-// NewLLMAdapter creates a new LLMAdapter
-func NewLLMAdapter(config configs.LLM) LLMAdapter {
+// NewLLMAdapter creates a new LLMAdapter sending requests with the keys of keys in turn.
+// When the pool has several keys, requests answered with 429 are sent again with another
+// key, until every key cools down. The vendor headers and parameters of config.Passthrough,
+// checked with CheckLLMPassthrough, are passed through. The adapter implements LLMWarmer.
+func NewLLMAdapter(config configs.LLM, keys *LLMKeyPool) LLMAdapter {
+	// Per-request deadlines are carried by the context; the client timeout only
+	// guards against requests that have none, so it must allow the largest override
+	timeout := config.Timeout
+	if config.MaxTimeout > timeout {
+		timeout = config.MaxTimeout
+	}
+
 	return &llmAdapter{
 		client: &http.Client{
-			Timeout: config.Timeout,
+			Timeout:   timeout,
+			Transport: newLLMTransport(config.Connections),
 		},
-		baseURL: config.BaseURL,
-		apiKey:  config.APIKey,
-		model:   config.Model,
+		baseURL:     config.BaseURL,
+		keys:        keys,
+		model:       config.Model,
+		connections: config.Connections,
+		passthrough: config.Passthrough,
 	}
 }
 
-// GenerateResponse sends a request to the LLM vendor service and returns the response
+// GenerateResponse sends a request to the LLM vendor service and returns the response,
+// hedging it when configured
 func (a *llmAdapter) GenerateResponse(ctx context.Context, request *dtos.LLMRequest) (*dtos.LLMResponse, error) {
 	log := logger.Context(ctx)
 	startTime := time.Now()
@@ -54,42 +80,121 @@ func (a *llmAdapter) GenerateResponse(ctx context.Context, request *dtos.LLMRequ
 	if err != nil {
 		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to marshal LLM request")
 	}
+	jsonData, err = passthroughBody(ctx, a.passthrough, jsonData)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create HTTP request
 	url := fmt.Sprintf("%s/generate", a.baseURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	llmResponse, err := a.generateHedged(ctx, func(ctx context.Context) (*dtos.LLMResponse, error) {
+		return a.generate(ctx, url, jsonData)
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to create LLM request")
+		return nil, err
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.apiKey))
+	elapsed := time.Since(startTime)
+	log.Infof("LLM request completed in %v with %d tokens used", elapsed, llmResponse.Usage.TotalTokens)
 
-	log.Debugf("Sending request to LLM service: %s", url)
+	return llmResponse, nil
+}
 
-	// Send request
-	resp, err := a.client.Do(req)
+// generate sends a request body and parses the response
+func (a *llmAdapter) generate(ctx context.Context, url string, body []byte) (*dtos.LLMResponse, error) {
+	resp, release, err := a.send(ctx, url, body)
 	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrLLMService, "Failed to connect to LLM service")
+		return nil, err
 	}
+	defer release()
 	defer resp.Body.Close()
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(errors.ErrLLMService, fmt.Sprintf("LLM service returned error: %d", resp.StatusCode))
-	}
-
 	// Parse response
 	var llmResponse dtos.LLMResponse
 	if err := json.NewDecoder(resp.Body).Decode(&llmResponse); err != nil {
 		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to parse LLM response")
 	}
+	return &llmResponse, nil
+}
 
-	elapsed := time.Since(startTime)
-	log.Infof("LLM request completed in %v with %d tokens used", elapsed, llmResponse.Usage.TotalTokens)
+// send posts a request with a key of the pool, and with the next ones while the keys are
+// rate limited. Responses other than 200 are returned as errors. The key counts as in use
+// until release is called, once the response is read.
+func (a *llmAdapter) send(ctx context.Context, url string, body []byte) (*http.Response, func(), error) {
+	log := logger.Context(ctx)
+	a.lastUsed.Store(time.Now().UnixNano())
+
+	for attempt := 0; attempt < a.keys.Len(); attempt++ {
+		key, release, err := a.keys.acquire(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Create HTTP request
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			release()
+			return nil, nil, errors.Wrap(err, errors.ErrInternal, "Failed to create LLM request")
+		}
+
+		// Set headers, the vendor ones first so they cannot replace those of the adapter
+		setPassthroughHeaders(ctx, a.passthrough, req.Header)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key.value))
+		for name, value := range logger.Headers(ctx) {
+			req.Header.Set(name, value)
+		}
+
+		log.Debugf("Sending request to LLM service: %s", url)
+
+		// Send request
+		resp, err := a.client.Do(req)
+		if err != nil {
+			release()
+			metrics.LLMKeyRequests.WithLabelValues(key.fingerprint, "error").Inc()
+			return nil, nil, errors.Wrap(err, errors.ErrLLMService, "Failed to connect to LLM service")
+		}
+
+		// Check response status
+		switch resp.StatusCode {
+		case http.StatusOK:
+			metrics.LLMKeyRequests.WithLabelValues(key.fingerprint, "success").Inc()
+			return resp, release, nil
+		case http.StatusTooManyRequests:
+			metrics.LLMKeyRequests.WithLabelValues(key.fingerprint, "rate_limited").Inc()
+			resp.Body.Close()
+			// A single key is not cooled down, leaving retries to the retry middleware
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			if a.keys.Len() == 1 {
+				release()
+				return nil, nil, errors.New(errors.ErrLLMService, fmt.Sprintf("LLM service returned error: %d", resp.StatusCode)).WithRetryAfter(retryAfter)
+			}
+			release()
+			a.keys.coolDown(key, retryAfter)
+			log.Warnw("LLM API key rate limited, cooling it down", "key", key.fingerprint, "retryAfter", retryAfter)
+		default:
+			metrics.LLMKeyRequests.WithLabelValues(key.fingerprint, "error").Inc()
+			resp.Body.Close()
+			release()
+			return nil, nil, errors.New(errors.ErrLLMService, fmt.Sprintf("LLM service returned error: %d", resp.StatusCode))
+		}
+	}
 
-	return &llmResponse, nil
+	return nil, nil, errors.New(errors.ErrRateLimited, "Every LLM API key is rate limited").WithRetryAfter(a.keys.cooldownLeft())
+}
+
+// parseRetryAfter returns the delay of a Retry-After header given in seconds or as a date,
+// or 0 when it is missing or invalid
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
 }
 
 type nothingLLMAdapter struct {
@@ -0,0 +1,90 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// LLMCapture is an LLM call captured for support: what was sent to the provider, what it
+// returned and how long it took
+type LLMCapture struct {
+	UserID    string
+	Route     string
+	Request   *dtos.LLMRequest
+	Response  *dtos.LLMResponse // Nil when the call failed
+	Error     string
+	Streaming bool
+	Chunks    int
+	// FirstChunk is the time to the first streamed chunk, 0 without chunks
+	FirstChunk time.Duration
+	Duration   time.Duration
+	StartedAt  time.Time
+}
+
+// captureKey is the context key of the capture of requests
+type captureKey struct{}
+
+// captureInfo describes the request whose LLM calls are captured
+type captureInfo struct {
+	userID string
+	route  string
+}
+
+// WithCapture captures the LLM calls made with the returned context, on behalf of a user for
+// a route, such as "POST /api/v1/messages"
+func WithCapture(ctx context.Context, userID, route string) context.Context {
+	return context.WithValue(ctx, captureKey{}, captureInfo{userID: userID, route: route})
+}
+
+// WithoutCapture keeps the LLM calls made with the returned context from being captured, even
+// when ctx is from WithCapture
+func WithoutCapture(ctx context.Context) context.Context {
+	return context.WithValue(ctx, captureKey{}, nil)
+}
+
+// CaptureLLMMiddleware passes the LLM calls made with a context from WithCapture to record,
+// along with the outcome and timings of each. Other calls are not captured.
+func CaptureLLMMiddleware(record func(ctx context.Context, capture *LLMCapture)) LLMMiddleware {
+	return func(next LLMGenerateFunc) LLMGenerateFunc {
+		return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			info, ok := ctx.Value(captureKey{}).(captureInfo)
+			if !ok {
+				return next(ctx, request, onChunk)
+			}
+
+			// The request is copied, since later middlewares and callers may change it
+			sent := *request
+			sent.Messages = append([]dtos.LLMMessage(nil), request.Messages...)
+			capture := &LLMCapture{
+				UserID:    info.userID,
+				Route:     info.route,
+				Request:   &sent,
+				Streaming: onChunk != nil,
+				StartedAt: time.Now(),
+			}
+
+			counted := onChunk
+			if onChunk != nil {
+				counted = func(chunk *dtos.LLMChunk) error {
+					if capture.Chunks == 0 {
+						capture.FirstChunk = time.Since(capture.StartedAt)
+					}
+					capture.Chunks++
+					return onChunk(chunk)
+				}
+			}
+
+			response, err := next(ctx, request, counted)
+			capture.Duration = time.Since(capture.StartedAt)
+			capture.Response = response
+			if err != nil {
+				capture.Error = err.Error()
+			}
+			// A capture is recorded even when the caller has given up on the call
+			record(context.WithoutCancel(ctx), capture)
+			return response, err
+		}
+	}
+}
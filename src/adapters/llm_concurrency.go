@@ -0,0 +1,164 @@
+package adapters
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
+)
+
+// Priority is the class of an LLM request waiting for a concurrency slot. Lower values go
+// first.
+type Priority int
+
+// Priority classes
+const (
+	// PriorityInteractive is that of replies users wait for, the default
+	PriorityInteractive Priority = iota
+	// PriorityRegeneration is that of replies generated again, such as with other models
+	// for comparisons, or once voice messages are transcribed
+	PriorityRegeneration
+	// PriorityBackground is that of batches and scheduled jobs
+	PriorityBackground
+
+	priorityClasses = iota
+)
+
+// String returns the name of a priority class, as used in metrics
+func (p Priority) String() string {
+	switch p {
+	case PriorityRegeneration:
+		return "regeneration"
+	case PriorityBackground:
+		return "background"
+	default:
+		return "interactive"
+	}
+}
+
+// priorityKey is the context key of the priority of requests
+type priorityKey struct{}
+
+// WithPriority sets the priority class of the LLM requests sent with the returned context
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityOf returns the priority class of the LLM requests sent with ctx
+func PriorityOf(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok && priority >= 0 && priority < priorityClasses {
+		return priority
+	}
+	return PriorityInteractive
+}
+
+// ConcurrencyLLMMiddleware bounds the requests in flight to the slots of limiter. Requests
+// over the bound queue for a slot and fail once they waited maxWait. Released slots go to
+// the queued requests of the highest priority class first, in the order they queued, so that
+// background work never holds up replies users wait for. Streamed requests hold their slot
+// until the stream ends.
+func ConcurrencyLLMMiddleware(limiter *LLMLimiter, maxWait time.Duration) LLMMiddleware {
+	return func(next LLMGenerateFunc) LLMGenerateFunc {
+		return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			if err := limiter.acquire(ctx, PriorityOf(ctx), maxWait); err != nil {
+				return nil, err
+			}
+			metrics.LLMInFlight.Inc()
+			defer func() {
+				metrics.LLMInFlight.Dec()
+				limiter.release()
+			}()
+
+			return next(ctx, request, onChunk)
+		}
+	}
+}
+
+// LLMLimiter hands out a bounded number of slots, queuing requests per priority class
+type LLMLimiter struct {
+	mu   sync.Mutex
+	free int
+	// queues hold the channels of the queued requests, closed once they are handed a slot
+	queues [priorityClasses]list.List
+}
+
+// NewLLMLimiter creates a limiter of size slots
+func NewLLMLimiter(size int) *LLMLimiter {
+	return &LLMLimiter{free: size}
+}
+
+// Queued returns the number of requests of a priority class waiting for a slot
+func (l *LLMLimiter) Queued(priority Priority) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.queues[priority].Len()
+}
+
+// acquire takes a slot, waiting up to maxWait for one to be handed over
+func (l *LLMLimiter) acquire(ctx context.Context, priority Priority, maxWait time.Duration) error {
+	l.mu.Lock()
+	// Slots are handed straight to queued requests, so a free slot means none is queued
+	if l.free > 0 {
+		l.free--
+		l.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	element := l.queues[priority].PushBack(ready)
+	l.mu.Unlock()
+
+	queued := metrics.LLMQueued.WithLabelValues(priority.String())
+	queued.Inc()
+	start := time.Now()
+	defer func() {
+		queued.Dec()
+		metrics.LLMQueueWait.WithLabelValues(priority.String()).Observe(time.Since(start).Seconds())
+	}()
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-timer.C:
+		err = errors.New(errors.ErrLLMService, "Too many requests to the LLM provider, try again later")
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	select {
+	case <-ready:
+		// The slot was handed over while giving up; it is passed on
+		l.mu.Unlock()
+		l.release()
+	default:
+		l.queues[priority].Remove(element)
+		l.mu.Unlock()
+	}
+	if ctx.Err() == nil {
+		logger.Context(ctx).Warnw("LLM request queued too long", "priority", priority.String(), "maxWait", maxWait)
+	}
+	return err
+}
+
+// release hands a slot to the first queued request of the highest priority class, or frees it
+func (l *LLMLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.queues {
+		if front := l.queues[i].Front(); front != nil {
+			l.queues[i].Remove(front)
+			close(front.Value.(chan struct{}))
+			return
+		}
+	}
+	l.free++
+}
@@ -0,0 +1,142 @@
+package adapters
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
+)
+
+// LLMWarmer is implemented by LLM adapters keeping their connections to the vendor warm
+type LLMWarmer interface {
+	// KeepWarm opens connections to the vendor, and again after idle periods, until ctx is done
+	KeepWarm(ctx context.Context)
+}
+
+// newLLMTransport returns the transport of the connections to the LLM vendor. HTTP/2 is
+// attempted, and its connections are pinged while idle so that dead ones are replaced
+// before a request needs them.
+func newLLMTransport(config configs.LLMConnections) *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: config.KeepAlive}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.ForceAttemptHTTP2 = true
+	transport.MaxIdleConns = config.MaxIdleConns
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = config.IdleConnTimeout
+	if config.PingInterval > 0 {
+		transport.HTTP2 = &http.HTTP2Config{
+			SendPingTimeout: config.PingInterval,
+			PingTimeout:     config.PingTimeout,
+		}
+	}
+	return transport
+}
+
+// KeepWarm warms the connections to the vendor at once, then whenever no request was sent
+// for WarmInterval, until ctx is done
+func (a *llmAdapter) KeepWarm(ctx context.Context) {
+	a.warm(ctx)
+	if a.connections.WarmInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(a.connections.WarmInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, a.lastUsed.Load())) >= a.connections.WarmInterval {
+				a.warm(ctx)
+			}
+		}
+	}
+}
+
+// warm sends WarmConnections HEAD requests to the vendor at once, each opening a connection
+// or keeping an idle one alive. Only the connections matter, not the responses.
+func (a *llmAdapter) warm(ctx context.Context) {
+	log := logger.Context(ctx)
+
+	var wg sync.WaitGroup
+	for range a.connections.WarmConnections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, a.baseURL, nil)
+			if err != nil {
+				log.Warnw("Failed to create LLM warming request", "error", err)
+				return
+			}
+			resp, err := a.client.Do(req)
+			if err != nil {
+				metrics.LLMConnectionWarms.WithLabelValues("error").Inc()
+				log.Warnw("Failed to warm LLM connection", "error", err)
+				return
+			}
+			// The body is read so that the connection goes back to the idle pool
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			metrics.LLMConnectionWarms.WithLabelValues("success").Inc()
+		}()
+	}
+	wg.Wait()
+	log.Debugw("Warmed LLM connections", "count", a.connections.WarmConnections)
+}
+
+// generateHedged runs generate, and runs it a second time when it did not return within
+// HedgeAfter; the first response wins and the other request is cancelled. No hedge is sent
+// once the first request failed, whose error is then returned.
+func (a *llmAdapter) generateHedged(ctx context.Context, generate func(ctx context.Context) (*dtos.LLMResponse, error)) (*dtos.LLMResponse, error) {
+	if a.connections.HedgeAfter <= 0 {
+		return generate(ctx)
+	}
+
+	done := make(chan struct{})
+	hedged := false
+	calls := []FanOutCall[*dtos.LLMResponse]{
+		func(ctx context.Context) (*dtos.LLMResponse, error) {
+			defer close(done)
+			return generate(ctx)
+		},
+		func(ctx context.Context) (*dtos.LLMResponse, error) {
+			timer := time.NewTimer(a.connections.HedgeAfter)
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-done:
+				return nil, errors.New(errors.ErrInternal, "Hedged LLM request not sent")
+			case <-timer.C:
+			}
+			hedged = true
+			logger.Context(ctx).Infow("LLM request not answered in time, hedging it", "hedgeAfter", a.connections.HedgeAfter)
+			return generate(ctx)
+		},
+	}
+
+	response, winner, err := FanOutFirst(ctx, calls)
+	// FanOutFirst waits for both calls, so hedged is settled
+	if hedged {
+		switch {
+		case err != nil:
+			metrics.LLMHedges.WithLabelValues("failed").Inc()
+		case winner == 1:
+			metrics.LLMHedges.WithLabelValues("won").Inc()
+		default:
+			metrics.LLMHedges.WithLabelValues("lost").Inc()
+		}
+	}
+	return response, err
+}
@@ -0,0 +1,79 @@
+package adapters
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nvnamsss/chat/src/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// FanOutCall is one of the calls FanOutAll and FanOutFirst run concurrently. It must return
+// once its context is cancelled.
+type FanOutCall[T any] func(ctx context.Context) (T, error)
+
+// FanOutResult is the outcome of a call run by FanOutAll
+type FanOutResult[T any] struct {
+	Value T
+	Err   error
+}
+
+// FanOutAll runs calls concurrently and waits for all of them, returning their results in
+// the order of calls. A call failing does not cancel the others; cancelling ctx cancels them all.
+func FanOutAll[T any](ctx context.Context, calls []FanOutCall[T]) []FanOutResult[T] {
+	results := make([]FanOutResult[T], len(calls))
+
+	var group errgroup.Group
+	for i, call := range calls {
+		group.Go(func() error {
+			results[i].Value, results[i].Err = call(ctx)
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	return results
+}
+
+// FanOutFirst runs calls concurrently and returns the first value a call returns without
+// error, along with the index of that call. The contexts of the other calls are then
+// cancelled, and FanOutFirst waits for them to return, so that no call outlives it. When
+// every call fails, the error of the first call is returned.
+func FanOutFirst[T any](ctx context.Context, calls []FanOutCall[T]) (T, int, error) {
+	var (
+		once   sync.Once
+		value  T
+		winner = -1
+		errs   = make([]error, len(calls))
+	)
+
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var group errgroup.Group
+	for i, call := range calls {
+		group.Go(func() error {
+			result, err := call(raceCtx)
+			if err != nil {
+				errs[i] = err
+				return nil
+			}
+			once.Do(func() {
+				value, winner = result, i
+				// The losers are not needed anymore
+				cancel()
+			})
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	if winner < 0 {
+		var zero T
+		if len(errs) == 0 {
+			return zero, -1, errors.New(errors.ErrInternal, "No calls to fan out")
+		}
+		return zero, -1, errs[0]
+	}
+	return value, winner, nil
+}
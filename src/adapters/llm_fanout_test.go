@@ -0,0 +1,202 @@
+package adapters
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	logger.Init("error", "test")
+}
+
+// slowCall returns value after delay, or the context error once ctx is cancelled. returned
+// counts the calls that returned and cancelled those that saw their context cancelled.
+func slowCall(value string, delay time.Duration, returned, cancelled *atomic.Int32) FanOutCall[string] {
+	return func(ctx context.Context) (string, error) {
+		defer returned.Add(1)
+		select {
+		case <-time.After(delay):
+			return value, nil
+		case <-ctx.Done():
+			cancelled.Add(1)
+			return "", ctx.Err()
+		}
+	}
+}
+
+// failingCall fails with err after delay
+func failingCall(err error, delay time.Duration, returned *atomic.Int32) FanOutCall[string] {
+	return func(ctx context.Context) (string, error) {
+		defer returned.Add(1)
+		time.Sleep(delay)
+		return "", err
+	}
+}
+
+func TestFanOutFirstCancelsLosers(t *testing.T) {
+	var returned, cancelled atomic.Int32
+	calls := []FanOutCall[string]{
+		slowCall("slow", time.Minute, &returned, &cancelled),
+		slowCall("fast", 10*time.Millisecond, &returned, &cancelled),
+		slowCall("slower", time.Minute, &returned, &cancelled),
+	}
+
+	start := time.Now()
+	value, winner, err := FanOutFirst(context.Background(), calls)
+	require.NoError(t, err)
+	assert.Equal(t, "fast", value)
+	assert.Equal(t, 1, winner)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// No call outlives FanOutFirst
+	assert.Equal(t, int32(3), returned.Load())
+	assert.Equal(t, int32(2), cancelled.Load())
+}
+
+func TestFanOutFirstSkipsFailures(t *testing.T) {
+	var returned, cancelled atomic.Int32
+	calls := []FanOutCall[string]{
+		failingCall(errors.New(errors.ErrLLMService, "primary failed"), 0, &returned),
+		slowCall("fallback", 20*time.Millisecond, &returned, &cancelled),
+	}
+
+	value, winner, err := FanOutFirst(context.Background(), calls)
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", value)
+	assert.Equal(t, 1, winner)
+	assert.Equal(t, int32(2), returned.Load())
+}
+
+func TestFanOutFirstReturnsFirstErrorWhenAllFail(t *testing.T) {
+	var returned atomic.Int32
+	first := errors.New(errors.ErrLLMService, "primary failed")
+	calls := []FanOutCall[string]{
+		failingCall(first, 20*time.Millisecond, &returned),
+		failingCall(errors.New(errors.ErrLLMService, "fallback failed"), 0, &returned),
+	}
+
+	_, winner, err := FanOutFirst(context.Background(), calls)
+	assert.Equal(t, first, err)
+	assert.Equal(t, -1, winner)
+	assert.Equal(t, int32(2), returned.Load())
+}
+
+func TestFanOutFirstStopsWhenCancelled(t *testing.T) {
+	var returned, cancelled atomic.Int32
+	calls := []FanOutCall[string]{
+		slowCall("a", time.Minute, &returned, &cancelled),
+		slowCall("b", time.Minute, &returned, &cancelled),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err := FanOutFirst(ctx, calls)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(2), returned.Load())
+	assert.Equal(t, int32(2), cancelled.Load())
+}
+
+func TestFanOutAllWaitsForEveryCall(t *testing.T) {
+	var returned, cancelled atomic.Int32
+	failure := errors.New(errors.ErrLLMService, "failed")
+	calls := []FanOutCall[string]{
+		slowCall("slow", 30*time.Millisecond, &returned, &cancelled),
+		failingCall(failure, 0, &returned),
+		slowCall("fast", 0, &returned, &cancelled),
+	}
+
+	results := FanOutAll(context.Background(), calls)
+	require.Len(t, results, 3)
+	assert.Equal(t, "slow", results[0].Value)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, failure, results[1].Err)
+	assert.Equal(t, "fast", results[2].Value)
+
+	// A failure does not cancel the other calls
+	assert.Equal(t, int32(3), returned.Load())
+	assert.Equal(t, int32(0), cancelled.Load())
+}
+
+func TestFanOutAllStopsWhenCancelled(t *testing.T) {
+	var returned, cancelled atomic.Int32
+	calls := []FanOutCall[string]{
+		slowCall("a", time.Minute, &returned, &cancelled),
+		slowCall("b", time.Minute, &returned, &cancelled),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	results := FanOutAll(ctx, calls)
+	for _, result := range results {
+		assert.ErrorIs(t, result.Err, context.DeadlineExceeded)
+	}
+	assert.Equal(t, int32(2), returned.Load())
+	assert.Equal(t, int32(2), cancelled.Load())
+}
+
+// modelGenerate answers requests after the delay of their model, or fails for the models
+// without one, counting the requests that returned and were cancelled
+func modelGenerate(delays map[string]time.Duration, returned, cancelled *atomic.Int32) LLMGenerateFunc {
+	return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+		defer returned.Add(1)
+		delay, ok := delays[request.Model]
+		if !ok {
+			return nil, errors.New(errors.ErrLLMService, "model "+request.Model+" failed")
+		}
+		select {
+		case <-time.After(delay):
+			return &dtos.LLMResponse{Model: request.Model, Message: dtos.LLMMessage{Role: "assistant", Content: request.Model}}, nil
+		case <-ctx.Done():
+			cancelled.Add(1)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestFallbackLLMMiddlewareFallsBackInOrder(t *testing.T) {
+	var returned, cancelled atomic.Int32
+	generate := FallbackLLMMiddleware("primary", []string{"broken", "backup"}, false)(
+		modelGenerate(map[string]time.Duration{"backup": 0}, &returned, &cancelled))
+
+	response, err := generate(context.Background(), &dtos.LLMRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "backup", response.Model)
+	assert.Equal(t, int32(3), returned.Load())
+}
+
+func TestFallbackLLMMiddlewareSpeculativeCancelsLosers(t *testing.T) {
+	var returned, cancelled atomic.Int32
+	generate := FallbackLLMMiddleware("primary", []string{"backup"}, true)(
+		modelGenerate(map[string]time.Duration{"primary": time.Minute, "backup": 10 * time.Millisecond}, &returned, &cancelled))
+
+	response, err := generate(context.Background(), &dtos.LLMRequest{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "backup", response.Model)
+	assert.Equal(t, int32(2), returned.Load())
+	assert.Equal(t, int32(1), cancelled.Load())
+}
+
+func TestFallbackLLMMiddlewareKeepsDeliveredStreams(t *testing.T) {
+	var calls atomic.Int32
+	failure := errors.New(errors.ErrLLMService, "stream broke")
+	generate := FallbackLLMMiddleware("primary", []string{"backup"}, true)(
+		func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			calls.Add(1)
+			if err := onChunk(&dtos.LLMChunk{Content: "partial"}); err != nil {
+				return nil, err
+			}
+			return nil, failure
+		})
+
+	_, err := generate(context.Background(), &dtos.LLMRequest{}, func(chunk *dtos.LLMChunk) error { return nil })
+	assert.Equal(t, failure, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
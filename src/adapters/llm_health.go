@@ -0,0 +1,229 @@
+package adapters
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+	CircuitDisabled = "disabled"
+)
+
+// Provider health statuses
+const (
+	ProviderHealthy     = "healthy"
+	ProviderDegraded    = "degraded"
+	ProviderUnavailable = "unavailable"
+)
+
+// maxHealthSamples bounds the request outcomes kept per provider
+const maxHealthSamples = 1000
+
+// healthSample is the outcome of a request to an LLM provider
+type healthSample struct {
+	at      time.Time
+	success bool
+	latency time.Duration
+}
+
+// LLMHealth tracks the outcomes of the recent requests to an LLM provider. With the
+// circuit breaker enabled, consecutive failures open the circuit: requests then fail
+// fast until, after the cooldown, a trial request succeeds.
+type LLMHealth struct {
+	name   string
+	model  string
+	config configs.LLMHealth
+
+	mu       sync.Mutex
+	samples  []healthSample
+	failures int // Consecutive failures
+	state    string
+	openedAt time.Time
+	trial    bool // A half-open trial request is in flight
+}
+
+// NewLLMHealth creates the health tracker of an LLM provider
+func NewLLMHealth(name, model string, config configs.LLMHealth) *LLMHealth {
+	if config.Window <= 0 {
+		config.Window = 5 * time.Minute
+	}
+	if config.CircuitBreaker.FailureThreshold <= 0 {
+		config.CircuitBreaker.FailureThreshold = 5
+	}
+
+	state := CircuitDisabled
+	if config.CircuitBreaker.Enabled {
+		state = CircuitClosed
+	}
+
+	return &LLMHealth{
+		name:   name,
+		model:  model,
+		config: config,
+		state:  state,
+	}
+}
+
+// Middleware records the outcome of every request, and rejects requests while the circuit
+// is open. Requests cancelled by their caller or rejected as invalid are not counted.
+func (h *LLMHealth) Middleware() LLMMiddleware {
+	return func(next LLMGenerateFunc) LLMGenerateFunc {
+		return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			if ok, retryAfter := h.allow(); !ok {
+				return nil, errors.New(errors.ErrLLMService, "LLM provider is unavailable, try again later").WithRetryAfter(retryAfter)
+			}
+
+			startTime := time.Now()
+			response, err := next(ctx, request, onChunk)
+			latency := time.Since(startTime)
+
+			// Rejected and abandoned requests say nothing about the provider
+			if err != nil && !retryable(ctx, err) && ctx.Err() != context.DeadlineExceeded {
+				h.release()
+				return nil, err
+			}
+
+			h.record(ctx, err == nil, latency)
+			return response, err
+		}
+	}
+}
+
+// allow reports whether a request may be sent to the provider and, when it may not, how
+// long until a trial request may be, if known
+func (h *LLMHealth) allow() (bool, time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch h.state {
+	case CircuitOpen:
+		if elapsed := time.Since(h.openedAt); elapsed < h.config.CircuitBreaker.Cooldown {
+			return false, h.config.CircuitBreaker.Cooldown - elapsed
+		}
+		h.state = CircuitHalfOpen
+		h.trial = true
+		return true, 0
+	case CircuitHalfOpen:
+		if h.trial {
+			return false, 0
+		}
+		h.trial = true
+		return true, 0
+	default:
+		return true, 0
+	}
+}
+
+// release lets another half-open trial through after a request that was not counted
+func (h *LLMHealth) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.trial = false
+}
+
+// record records the outcome of a request and moves the circuit accordingly
+func (h *LLMHealth) record(ctx context.Context, success bool, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.samples = append(h.prune(now), healthSample{at: now, success: success, latency: latency})
+	h.trial = false
+
+	if success {
+		h.failures = 0
+		if h.state == CircuitHalfOpen || h.state == CircuitOpen {
+			logger.Context(ctx).Infow("LLM circuit closed", "provider", h.name)
+			h.state = CircuitClosed
+		}
+		return
+	}
+
+	h.failures++
+	if h.state == CircuitHalfOpen || (h.state == CircuitClosed && h.failures >= h.config.CircuitBreaker.FailureThreshold) {
+		logger.Context(ctx).Warnw("LLM circuit opened", "provider", h.name, "failures", h.failures, "cooldown", h.config.CircuitBreaker.Cooldown)
+		h.state = CircuitOpen
+		h.openedAt = now
+	}
+}
+
+// prune drops the samples older than the window, keeping room for a new one
+func (h *LLMHealth) prune(now time.Time) []healthSample {
+	cutoff := now.Add(-h.config.Window)
+	first := 0
+	for first < len(h.samples) && h.samples[first].at.Before(cutoff) {
+		first++
+	}
+	if len(h.samples)-first >= maxHealthSamples {
+		first = len(h.samples) - maxHealthSamples + 1
+	}
+	return append(h.samples[:0], h.samples[first:]...)
+}
+
+// Status returns the success rate and median latency of the provider within the window,
+// and the state of its circuit breaker. The median latency is that of successful requests.
+func (h *LLMHealth) Status() dtos.ProviderStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.samples = h.prune(time.Now())
+
+	status := dtos.ProviderStatus{
+		Name:           h.name,
+		Model:          h.model,
+		Status:         ProviderHealthy,
+		Requests:       len(h.samples),
+		CircuitBreaker: h.state,
+	}
+
+	var latencies []time.Duration
+	for _, sample := range h.samples {
+		if sample.success {
+			latencies = append(latencies, sample.latency)
+		}
+	}
+	if len(h.samples) > 0 {
+		successRate := float64(len(latencies)) / float64(len(h.samples))
+		status.SuccessRate = &successRate
+		if successRate < h.config.DegradedSuccessRate {
+			status.Status = ProviderDegraded
+		}
+	}
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		median := latencies[len(latencies)/2]
+		if len(latencies)%2 == 0 {
+			median = (latencies[len(latencies)/2-1] + median) / 2
+		}
+		medianMs := median.Milliseconds()
+		status.MedianLatencyMs = &medianMs
+	}
+
+	switch h.state {
+	case CircuitOpen:
+		status.Status = ProviderUnavailable
+		retryAt := h.openedAt.Add(h.config.CircuitBreaker.Cooldown)
+		status.RetryAt = &retryAt
+	case CircuitHalfOpen:
+		status.Status = ProviderDegraded
+	}
+
+	return status
+}
+
+// Window returns how far back the request outcomes of the status go
+func (h *LLMHealth) Window() time.Duration {
+	return h.config.Window
+}
@@ -0,0 +1,233 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// Strategies picking the API key of each request
+const (
+	KeyStrategyRoundRobin  = "round_robin"
+	KeyStrategyLeastLoaded = "least_loaded"
+)
+
+// LLMKeyPool spreads LLM requests over several API keys of a provider. Keys answered with
+// 429 cool down, and are skipped until then; when every key cools down, requests fail fast.
+// Keys read from a file are replaced when the file changes, and the state of the keys kept
+// is carried over.
+type LLMKeyPool struct {
+	config configs.LLMKeyPool
+
+	mu        sync.Mutex
+	keys      []*llmKey
+	next      int       // Index of the next key tried by round robin
+	modTime   time.Time // Modification time of the file when its keys were read
+	checkedAt time.Time // When the file was last checked for changes
+}
+
+// llmKey is an API key of the pool with its state
+type llmKey struct {
+	value       string
+	fingerprint string // Identifies the key in logs and metrics without revealing it
+	inFlight    int
+	coolUntil   time.Time
+}
+
+// NewLLMKeyPool creates a pool of the configured keys, or of apiKey alone when none are. The
+// file of keys, when configured, must be readable and hold at least one key.
+func NewLLMKeyPool(config configs.LLMKeyPool, apiKey string) (*LLMKeyPool, error) {
+	switch config.Strategy {
+	case "":
+		config.Strategy = KeyStrategyRoundRobin
+	case KeyStrategyRoundRobin, KeyStrategyLeastLoaded:
+	default:
+		return nil, fmt.Errorf("unknown key pool strategy %q", config.Strategy)
+	}
+
+	pool := &LLMKeyPool{config: config}
+	if config.File != "" {
+		info, err := os.Stat(config.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the key pool file: %w", err)
+		}
+		if err := pool.load(info.ModTime()); err != nil {
+			return nil, err
+		}
+		return pool, nil
+	}
+
+	values := config.Keys
+	if len(values) == 0 {
+		values = []string{apiKey}
+	}
+	pool.keys = pool.merge(values)
+	return pool, nil
+}
+
+// Len returns the number of keys in the pool
+func (p *LLMKeyPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.keys)
+}
+
+// acquire picks a key that is not cooling down and counts a request in flight on it, until
+// release is called. It fails with ErrRateLimited when every key cools down, telling when
+// the first of them is available again.
+func (p *LLMKeyPool) acquire(ctx context.Context) (*llmKey, func(), error) {
+	p.refresh(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	// Ties of least loaded keys are broken in round robin order
+	var picked *llmKey
+	var available time.Time // When the first key cooling down is available again
+	start := p.next
+	for i := range p.keys {
+		index := (start + i) % len(p.keys)
+		key := p.keys[index]
+		if now.Before(key.coolUntil) {
+			if available.IsZero() || key.coolUntil.Before(available) {
+				available = key.coolUntil
+			}
+			continue
+		}
+		if picked == nil || (p.config.Strategy == KeyStrategyLeastLoaded && key.inFlight < picked.inFlight) {
+			picked = key
+			p.next = index + 1
+		}
+		if p.config.Strategy == KeyStrategyRoundRobin {
+			break
+		}
+	}
+	if picked == nil {
+		return nil, nil, errors.New(errors.ErrRateLimited, "Every LLM API key is rate limited").WithRetryAfter(available.Sub(now))
+	}
+
+	picked.inFlight++
+	return picked, func() {
+		p.mu.Lock()
+		picked.inFlight--
+		p.mu.Unlock()
+	}, nil
+}
+
+// cooldownLeft returns how long until a key of the pool is available again, 0 when one is
+func (p *LLMKeyPool) cooldownLeft() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var left time.Duration
+	for _, key := range p.keys {
+		if !now.Before(key.coolUntil) {
+			return 0
+		}
+		if wait := key.coolUntil.Sub(now); left == 0 || wait < left {
+			left = wait
+		}
+	}
+	return left
+}
+
+// coolDown leaves a key out for d, or for the configured cooldown when d is not positive
+func (p *LLMKeyPool) coolDown(key *llmKey, d time.Duration) {
+	if d <= 0 {
+		d = p.config.Cooldown
+	}
+
+	p.mu.Lock()
+	key.coolUntil = time.Now().Add(d)
+	p.mu.Unlock()
+}
+
+// refresh reads the file of keys again when it changed since it was read, at most once per
+// RefreshInterval. A file that cannot be read or holds no key is logged, and the keys in
+// use are kept.
+func (p *LLMKeyPool) refresh(ctx context.Context) {
+	if p.config.File == "" {
+		return
+	}
+
+	p.mu.Lock()
+	if time.Since(p.checkedAt) < p.config.RefreshInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.checkedAt = time.Now()
+	modTime := p.modTime
+	p.mu.Unlock()
+
+	info, err := os.Stat(p.config.File)
+	if err != nil {
+		logger.Context(ctx).Warnw("Failed to check the key pool file", "error", err)
+		return
+	}
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+	if err := p.load(info.ModTime()); err != nil {
+		logger.Context(ctx).Warnw("Failed to reload the key pool file, keeping the current keys", "error", err)
+	}
+}
+
+// load replaces the keys with those of the file, modified at modTime
+func (p *LLMKeyPool) load(modTime time.Time) error {
+	data, err := os.ReadFile(p.config.File)
+	if err != nil {
+		return fmt.Errorf("failed to read the key pool file: %w", err)
+	}
+	var values []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			values = append(values, line)
+		}
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("the key pool file %s holds no key", p.config.File)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = p.merge(values)
+	p.modTime = modTime
+	logger.Info("Loaded LLM API keys", logger.Field("count", len(p.keys)))
+	return nil
+}
+
+// merge returns the keys of values without duplicates, reusing the state of the keys of
+// the pool among them
+func (p *LLMKeyPool) merge(values []string) []*llmKey {
+	current := make(map[string]*llmKey, len(p.keys))
+	for _, key := range p.keys {
+		current[key.value] = key
+	}
+
+	keys := make([]*llmKey, 0, len(values))
+	seen := map[string]bool{}
+	for _, value := range values {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		if key, ok := current[value]; ok {
+			keys = append(keys, key)
+			continue
+		}
+		sum := sha256.Sum256([]byte(value))
+		keys = append(keys, &llmKey{value: value, fingerprint: hex.EncodeToString(sum[:4])})
+	}
+	return keys
+}
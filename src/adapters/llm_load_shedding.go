@@ -0,0 +1,99 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
+	"github.com/nvnamsss/chat/src/pkg/warnings"
+)
+
+// Reasons requests are downgraded, as used in metrics
+const (
+	ShedReasonQueue   = "queue"
+	ShedReasonLatency = "latency"
+)
+
+// loadCheckInterval is how long the latency of the provider is reused between requests
+const loadCheckInterval = time.Second
+
+// LLMLoadShedder downgrades the replies users wait for to a cheaper, faster model while the
+// provider is overloaded: while more of them queue for a concurrency slot than allowed, or the
+// median latency of the provider is over the bound. Other priority classes keep their model,
+// as nobody waits for them.
+type LLMLoadShedder struct {
+	config  configs.LLMLoadShedding
+	health  *LLMHealth
+	limiter *LLMLimiter // Nil when requests are not bounded
+
+	mu        sync.Mutex
+	slow      bool // Whether the latency was over the bound when last checked
+	checkedAt time.Time
+}
+
+// NewLLMLoadShedder creates a load shedder reading the latency of health and the queue of
+// limiter, which may be nil
+func NewLLMLoadShedder(config configs.LLMLoadShedding, health *LLMHealth, limiter *LLMLimiter) (*LLMLoadShedder, error) {
+	if config.Model == "" {
+		return nil, fmt.Errorf("a model is required to shed load")
+	}
+	return &LLMLoadShedder{config: config, health: health, limiter: limiter}, nil
+}
+
+// Middleware sends interactive requests to the configured model while the provider is
+// overloaded, warning the client that the reply was generated by it
+func (s *LLMLoadShedder) Middleware(defaultModel string) LLMMiddleware {
+	return func(next LLMGenerateFunc) LLMGenerateFunc {
+		return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			requested := request.Model
+			if requested == "" {
+				requested = defaultModel
+			}
+			if PriorityOf(ctx) != PriorityInteractive || requested == s.config.Model {
+				return next(ctx, request, onChunk)
+			}
+			reason := s.overloaded()
+			if reason == "" {
+				return next(ctx, request, onChunk)
+			}
+
+			metrics.LLMDowngrades.WithLabelValues(reason).Inc()
+			logger.Context(ctx).Infow("Downgrading LLM request under load", "model", s.config.Model, "requested", requested, "reason", reason)
+			downgraded := *request
+			downgraded.Model = s.config.Model
+			response, err := next(ctx, &downgraded, onChunk)
+			if err == nil {
+				warnings.Add(ctx, warnings.ModelDowngraded, fmt.Sprintf("Generated by %s instead of %s because the service is under heavy load", s.config.Model, requested))
+			}
+			return response, err
+		}
+	}
+}
+
+// overloaded returns why the provider is overloaded, or an empty string when it is not
+func (s *LLMLoadShedder) overloaded() string {
+	if s.limiter != nil && s.config.MaxQueued > 0 && s.limiter.Queued(PriorityInteractive) > s.config.MaxQueued {
+		return ShedReasonQueue
+	}
+	if s.config.MaxLatency <= 0 {
+		return ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.checkedAt) >= loadCheckInterval {
+		status := s.health.Status()
+		s.slow = status.Requests >= s.config.MinRequests && status.MedianLatencyMs != nil &&
+			time.Duration(*status.MedianLatencyMs)*time.Millisecond > s.config.MaxLatency
+		s.checkedAt = time.Now()
+	}
+	if s.slow {
+		return ShedReasonLatency
+	}
+	return ""
+}
@@ -0,0 +1,432 @@
+package adapters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
+	"github.com/nvnamsss/chat/src/pkg/warnings"
+)
+
+// LLMGenerateFunc generates a response to an LLM request. onChunk is nil for
+// non-streaming calls; otherwise it receives the response incrementally.
+type LLMGenerateFunc func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error)
+
+// LLMMiddleware wraps LLM generation with a cross-cutting concern
+type LLMMiddleware func(next LLMGenerateFunc) LLMGenerateFunc
+
+// LLMAdapterBuilder composes middlewares around an LLM adapter
+type LLMAdapterBuilder struct {
+	adapter     LLMAdapter
+	middlewares []LLMMiddleware
+}
+
+// NewLLMAdapterBuilder creates a builder wrapping the given adapter
+func NewLLMAdapterBuilder(adapter LLMAdapter) *LLMAdapterBuilder {
+	return &LLMAdapterBuilder{adapter: adapter}
+}
+
+// Use adds middlewares. The first middleware added is the outermost.
+func (b *LLMAdapterBuilder) Use(middlewares ...LLMMiddleware) *LLMAdapterBuilder {
+	b.middlewares = append(b.middlewares, middlewares...)
+	return b
+}
+
+// Build returns the adapter wrapped in the middlewares. The result streams
+// only if the wrapped adapter does.
+func (b *LLMAdapterBuilder) Build() LLMAdapter {
+	streamer, streaming := b.adapter.(LLMStreamer)
+
+	generate := func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+		if onChunk != nil && streaming {
+			return streamer.StreamResponse(ctx, request, onChunk)
+		}
+		return b.adapter.GenerateResponse(ctx, request)
+	}
+
+	for i := len(b.middlewares) - 1; i >= 0; i-- {
+		generate = b.middlewares[i](generate)
+	}
+
+	if streaming {
+		return &chainedLLMStreamer{chainedLLMAdapter{generate: generate}}
+	}
+	return &chainedLLMAdapter{generate: generate}
+}
+
+// chainedLLMAdapter is an LLMAdapter running requests through a middleware chain
+type chainedLLMAdapter struct {
+	generate LLMGenerateFunc
+}
+
+// GenerateResponse runs the request through the middleware chain
+func (a *chainedLLMAdapter) GenerateResponse(ctx context.Context, request *dtos.LLMRequest) (*dtos.LLMResponse, error) {
+	return a.generate(ctx, request, nil)
+}
+
+// chainedLLMStreamer is a chainedLLMAdapter over a streaming adapter
+type chainedLLMStreamer struct {
+	chainedLLMAdapter
+}
+
+// StreamResponse runs the streamed request through the middleware chain
+func (a *chainedLLMStreamer) StreamResponse(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+	return a.generate(ctx, request, onChunk)
+}
+
+// LoggingLLMMiddleware logs every LLM request with its latency and token usage
+func LoggingLLMMiddleware() LLMMiddleware {
+	return func(next LLMGenerateFunc) LLMGenerateFunc {
+		return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			log := logger.Context(ctx)
+			startTime := time.Now()
+
+			response, err := next(ctx, request, onChunk)
+			if err != nil {
+				log.Warnw("LLM request failed", "model", request.Model, "messages", len(request.Messages), "streaming", onChunk != nil, "duration", time.Since(startTime), "error", err)
+				return nil, err
+			}
+
+			log.Infow("LLM request completed",
+				"model", response.Model,
+				"messages", len(request.Messages),
+				"streaming", onChunk != nil,
+				"duration", time.Since(startTime),
+				"totalTokens", response.Usage.TotalTokens)
+			return response, nil
+		}
+	}
+}
+
+// MetricsLLMMiddleware records request counts, latency and token usage
+func MetricsLLMMiddleware(defaultModel string) LLMMiddleware {
+	return func(next LLMGenerateFunc) LLMGenerateFunc {
+		return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			model := request.Model
+			if model == "" {
+				model = defaultModel
+			}
+			startTime := time.Now()
+
+			response, err := next(ctx, request, onChunk)
+			metrics.LLMLatency.WithLabelValues(model).Observe(time.Since(startTime).Seconds())
+			if err != nil {
+				metrics.LLMRequests.WithLabelValues(model, "error").Inc()
+				return nil, err
+			}
+
+			metrics.LLMRequests.WithLabelValues(model, "success").Inc()
+			metrics.LLMTokens.WithLabelValues(model, "prompt").Add(float64(response.Usage.PromptTokens))
+			metrics.LLMTokens.WithLabelValues(model, "completion").Add(float64(response.Usage.CompletionTokens))
+			return response, nil
+		}
+	}
+}
+
+// RetryLLMMiddleware retries failed requests with linear backoff. Streamed requests
+// are only retried if no chunk was delivered, and rejected requests are never retried.
+func RetryLLMMiddleware(maxAttempts int, backoff time.Duration) LLMMiddleware {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return func(next LLMGenerateFunc) LLMGenerateFunc {
+		return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			log := logger.Context(ctx)
+
+			delivered := false
+			callback := onChunk
+			if onChunk != nil {
+				callback = func(chunk *dtos.LLMChunk) error {
+					delivered = true
+					return onChunk(chunk)
+				}
+			}
+
+			var err error
+			for attempt := 1; attempt <= maxAttempts; attempt++ {
+				var response *dtos.LLMResponse
+				if response, err = next(ctx, request, callback); err == nil {
+					return response, nil
+				}
+
+				if delivered || !retryable(ctx, err) || attempt == maxAttempts {
+					break
+				}
+
+				log.Warnw("Retrying LLM request", "attempt", attempt, "error", err)
+				if sleepContext(ctx, backoff*time.Duration(attempt)) != nil {
+					break
+				}
+			}
+			return nil, err
+		}
+	}
+}
+
+// FallbackLLMMiddleware answers requests with the fallback models, in order, when the
+// requested model fails. With speculative, requests that are not streamed are sent to every
+// model at once instead: the first response wins and the other requests are cancelled.
+// Streamed requests only fall back while no chunk was delivered, and rejected requests
+// never do.
+func FallbackLLMMiddleware(defaultModel string, models []string, speculative bool) LLMMiddleware {
+	return func(next LLMGenerateFunc) LLMGenerateFunc {
+		return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			log := logger.Context(ctx)
+
+			requested := request.Model
+			if requested == "" {
+				requested = defaultModel
+			}
+			candidates := []string{requested}
+			for _, model := range models {
+				if !slices.Contains(candidates, model) {
+					candidates = append(candidates, model)
+				}
+			}
+			if len(candidates) == 1 {
+				return next(ctx, request, onChunk)
+			}
+
+			if speculative && onChunk == nil {
+				calls := make([]FanOutCall[*dtos.LLMResponse], len(candidates))
+				for i, model := range candidates {
+					modelRequest := *request
+					modelRequest.Model = model
+					calls[i] = func(ctx context.Context) (*dtos.LLMResponse, error) {
+						return next(ctx, &modelRequest, nil)
+					}
+				}
+				response, winner, err := FanOutFirst(ctx, calls)
+				if err == nil && winner > 0 {
+					log.Infow("LLM request answered by fallback model", "model", candidates[winner], "requested", requested)
+					warnFallback(ctx, candidates[winner], requested)
+				}
+				return response, err
+			}
+
+			delivered := false
+			callback := onChunk
+			if onChunk != nil {
+				callback = func(chunk *dtos.LLMChunk) error {
+					delivered = true
+					return onChunk(chunk)
+				}
+			}
+
+			var err error
+			for i, model := range candidates {
+				modelRequest := *request
+				modelRequest.Model = model
+				var response *dtos.LLMResponse
+				if response, err = next(ctx, &modelRequest, callback); err == nil {
+					if i > 0 {
+						warnFallback(ctx, model, requested)
+					}
+					return response, nil
+				}
+				if delivered || !retryable(ctx, err) || i == len(candidates)-1 {
+					break
+				}
+				log.Warnw("Falling back to another LLM model", "model", model, "fallback", candidates[i+1], "error", err)
+			}
+			return nil, err
+		}
+	}
+}
+
+// warnFallback tells the client that a fallback model answered instead of the requested one
+func warnFallback(ctx context.Context, model, requested string) {
+	warnings.Add(ctx, warnings.FallbackModel, fmt.Sprintf("Generated by %s because %s failed", model, requested))
+}
+
+// retryable reports whether a failed LLM request may succeed when retried
+func retryable(ctx context.Context, err error) bool {
+	// Every API key cooling down is not worth retrying before the cooldown ends
+	if appErr, ok := err.(*errors.AppError); ok && (appErr.Code == errors.ErrInvalidRequest || appErr.Code == errors.ErrRateLimited) {
+		return false
+	}
+	return ctx.Err() == nil
+}
+
+// cacheEntry is a cached LLM response
+type cacheEntry struct {
+	response  dtos.LLMResponse
+	expiresAt time.Time
+}
+
+// CacheLLMMiddleware serves identical requests from an in-memory cache for ttl.
+// When the cache is full, expired entries are evicted, then the oldest one.
+func CacheLLMMiddleware(ttl time.Duration, maxEntries int) LLMMiddleware {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+
+	var mu sync.Mutex
+	entries := map[string]*cacheEntry{}
+
+	evict := func(now time.Time) {
+		var oldestKey string
+		var oldest time.Time
+		for key, entry := range entries {
+			if now.After(entry.expiresAt) {
+				delete(entries, key)
+				continue
+			}
+			if oldestKey == "" || entry.expiresAt.Before(oldest) {
+				oldestKey, oldest = key, entry.expiresAt
+			}
+		}
+		if len(entries) >= maxEntries && oldestKey != "" {
+			delete(entries, oldestKey)
+		}
+	}
+
+	return func(next LLMGenerateFunc) LLMGenerateFunc {
+		return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			// Vendor parameters of the request may change the response
+			if vendorOptionsOf(ctx) != nil {
+				return next(ctx, request, onChunk)
+			}
+			key, err := cacheKey(request)
+			if err != nil {
+				return next(ctx, request, onChunk)
+			}
+
+			mu.Lock()
+			entry, ok := entries[key]
+			if ok && time.Now().After(entry.expiresAt) {
+				delete(entries, key)
+				ok = false
+			}
+			mu.Unlock()
+
+			if ok {
+				metrics.LLMCacheHits.Inc()
+				logger.Context(ctx).Debugw("LLM response served from cache")
+				response := entry.response
+				if onChunk != nil {
+					if err := onChunk(&dtos.LLMChunk{Content: response.Message.Content, Done: true}); err != nil {
+						return nil, err
+					}
+				}
+				return &response, nil
+			}
+
+			response, err := next(ctx, request, onChunk)
+			if err != nil {
+				return nil, err
+			}
+
+			mu.Lock()
+			now := time.Now()
+			if len(entries) >= maxEntries {
+				evict(now)
+			}
+			entries[key] = &cacheEntry{response: *response, expiresAt: now.Add(ttl)}
+			mu.Unlock()
+
+			return response, nil
+		}
+	}
+}
+
+// cacheKey hashes the JSON encoding of a request
+func cacheKey(request *dtos.LLMRequest) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// redaction replaces blocked terms in responses
+const redaction = "***"
+
+// Moderation levels of the requests of a context
+const (
+	// ModerationOff neither blocks requests nor redacts responses
+	ModerationOff = "off"
+	// ModerationRedact only redacts blocked terms from responses
+	ModerationRedact = "redact"
+	// ModerationBlock blocks requests with blocked terms and redacts responses, the default
+	ModerationBlock = "block"
+)
+
+// moderationKey is the context key of the moderation level of requests
+type moderationKey struct{}
+
+// WithModerationLevel sets the moderation level of the requests sent with the returned
+// context. An empty level keeps the default, ModerationBlock.
+func WithModerationLevel(ctx context.Context, level string) context.Context {
+	return context.WithValue(ctx, moderationKey{}, level)
+}
+
+// moderationLevel returns the moderation level of the requests sent with ctx
+func moderationLevel(ctx context.Context) string {
+	if level, _ := ctx.Value(moderationKey{}).(string); level != "" {
+		return level
+	}
+	return ModerationBlock
+}
+
+// ModerationLLMMiddleware rejects requests whose latest message contains a blocked
+// term and redacts blocked terms from responses, as far as the moderation level of the
+// request context allows. Matching is case-insensitive.
+func ModerationLLMMiddleware(blockedTerms []string) LLMMiddleware {
+	patterns := make([]string, 0, len(blockedTerms))
+	for _, term := range blockedTerms {
+		if term = strings.TrimSpace(term); term != "" {
+			patterns = append(patterns, regexp.QuoteMeta(term))
+		}
+	}
+
+	return func(next LLMGenerateFunc) LLMGenerateFunc {
+		if len(patterns) == 0 {
+			return next
+		}
+		blocked := regexp.MustCompile("(?i)" + strings.Join(patterns, "|"))
+
+		return func(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+			level := moderationLevel(ctx)
+			if level == ModerationOff {
+				return next(ctx, request, onChunk)
+			}
+			if n := len(request.Messages); level == ModerationBlock && n > 0 && blocked.MatchString(request.Messages[n-1].Content) {
+				logger.Context(ctx).Warnw("LLM request blocked by moderation")
+				return nil, errors.New(errors.ErrInvalidRequest, "Message violates the content policy")
+			}
+
+			callback := onChunk
+			if onChunk != nil {
+				callback = func(chunk *dtos.LLMChunk) error {
+					redacted := *chunk
+					redacted.Content = blocked.ReplaceAllLiteralString(chunk.Content, redaction)
+					return onChunk(&redacted)
+				}
+			}
+
+			response, err := next(ctx, request, callback)
+			if err != nil {
+				return nil, err
+			}
+			if blocked.MatchString(response.Message.Content) {
+				response.Message.Content = blocked.ReplaceAllLiteralString(response.Message.Content, redaction)
+				warnings.Add(ctx, warnings.ModerationRedacted, "Blocked terms were redacted from the reply")
+			}
+			return response, nil
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// reservedHeaders are set by the adapter or the HTTP client, and cannot be passed through
+var reservedHeaders = map[string]bool{
+	"Authorization":     true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Cookie":            true,
+	"Host":              true,
+	"Transfer-Encoding": true,
+	http.CanonicalHeaderKey(logger.RequestIDHeader): true,
+}
+
+// reservedParams are the fields of LLM requests, which cannot be passed through
+var reservedParams = map[string]bool{
+	"messages":   true,
+	"model":      true,
+	"max_tokens": true,
+	"stream":     true,
+}
+
+// vendorOptionsKey is the context key of the vendor options of requests
+type vendorOptionsKey struct{}
+
+// WithVendorOptions passes the vendor-specific headers and parameters of options through with
+// the LLM requests sent with the returned context. Options must have been checked with
+// CheckVendorOptions.
+func WithVendorOptions(ctx context.Context, options *dtos.VendorOptions) context.Context {
+	if options == nil || (len(options.Headers) == 0 && len(options.Params) == 0) {
+		return ctx
+	}
+	return context.WithValue(ctx, vendorOptionsKey{}, options)
+}
+
+// vendorOptionsOf returns the vendor options of the requests sent with ctx, or nil
+func vendorOptionsOf(ctx context.Context) *dtos.VendorOptions {
+	options, _ := ctx.Value(vendorOptionsKey{}).(*dtos.VendorOptions)
+	return options
+}
+
+// CheckLLMPassthrough returns an error when the configured headers or parameters, or those
+// allowed per request, are set by the adapter, or when a parameter is not valid JSON
+func CheckLLMPassthrough(config configs.LLMPassthrough) error {
+	for name := range config.Headers {
+		if reservedHeaders[http.CanonicalHeaderKey(name)] {
+			return fmt.Errorf("the LLM header %s cannot be passed through", name)
+		}
+	}
+	for _, name := range config.AllowedHeaders {
+		if reservedHeaders[http.CanonicalHeaderKey(name)] {
+			return fmt.Errorf("the LLM header %s cannot be passed through", name)
+		}
+	}
+	for name, value := range config.Params {
+		if reservedParams[name] {
+			return fmt.Errorf("the LLM parameter %s cannot be passed through", name)
+		}
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("the LLM parameter %s is not valid JSON", name)
+		}
+	}
+	for _, name := range config.AllowedParams {
+		if reservedParams[name] {
+			return fmt.Errorf("the LLM parameter %s cannot be passed through", name)
+		}
+	}
+	return nil
+}
+
+// CheckVendorOptions returns an ErrInvalidRequest error when options set a header or a
+// parameter outside the allowlists of config, or a parameter that is not valid JSON.
+// Header names are case-insensitive.
+func CheckVendorOptions(config configs.LLMPassthrough, options *dtos.VendorOptions) error {
+	if options == nil {
+		return nil
+	}
+	for name := range options.Headers {
+		if !allowed(config.AllowedHeaders, name, http.CanonicalHeaderKey) {
+			return errors.New(errors.ErrInvalidRequest, fmt.Sprintf("The LLM header %s cannot be passed through", name))
+		}
+	}
+	for name, value := range options.Params {
+		if !allowed(config.AllowedParams, name, func(name string) string { return name }) {
+			return errors.New(errors.ErrInvalidRequest, fmt.Sprintf("The LLM parameter %s cannot be passed through", name))
+		}
+		if !json.Valid(value) {
+			return errors.New(errors.ErrInvalidRequest, fmt.Sprintf("The LLM parameter %s is not valid JSON", name))
+		}
+	}
+	return nil
+}
+
+// allowed reports whether name is in the allowlist, comparing the names normalized by normalize
+func allowed(allowlist []string, name string, normalize func(string) string) bool {
+	for _, entry := range allowlist {
+		if normalize(entry) == normalize(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// passthroughBody adds the configured vendor parameters, then those of the context, to the
+// JSON body of a request. The fields of the request are kept over them.
+func passthroughBody(ctx context.Context, config configs.LLMPassthrough, body []byte) ([]byte, error) {
+	options := vendorOptionsOf(ctx)
+	if len(config.Params) == 0 && options == nil {
+		return body, nil
+	}
+	if err := CheckVendorOptions(config, options); err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to marshal LLM request")
+	}
+	merged := make(map[string]json.RawMessage, len(config.Params)+len(fields))
+	for name, value := range config.Params {
+		merged[name] = json.RawMessage(value)
+	}
+	if options != nil {
+		for name, value := range options.Params {
+			merged[name] = value
+		}
+	}
+	for name, value := range fields {
+		merged[name] = value
+	}
+
+	body, err := json.Marshal(merged)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to marshal LLM request")
+	}
+	return body, nil
+}
+
+// setPassthroughHeaders sets the configured vendor headers, then those of the context, on a
+// request; the adapter sets its own headers afterwards
+func setPassthroughHeaders(ctx context.Context, config configs.LLMPassthrough, header http.Header) {
+	for name, value := range config.Headers {
+		header.Set(name, value)
+	}
+	if options := vendorOptionsOf(ctx); options != nil {
+		for name, value := range options.Headers {
+			header.Set(name, value)
+		}
+	}
+}
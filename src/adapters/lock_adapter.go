@@ -0,0 +1,121 @@
+package adapters
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// lockMinBackoff and lockMaxBackoff bound the waits between the attempts of Lock
+const (
+	lockMinBackoff = 10 * time.Millisecond
+	lockMaxBackoff = 500 * time.Millisecond
+)
+
+// LockAdapter defines the interface for distributed locks shared by all service instances
+type LockAdapter interface {
+	// TryLock attempts to acquire the named lock without blocking. When acquired is true,
+	// the returned unlock function must be called to release it.
+	TryLock(ctx context.Context, name string) (unlock func(), acquired bool, err error)
+
+	// Lock acquires the named lock, waiting until its holder releases it or ctx is done.
+	// The returned unlock function must be called to release it.
+	Lock(ctx context.Context, name string) (unlock func(), err error)
+}
+
+// pgLockAdapter implements LockAdapter with Postgres session-level advisory locks
+type pgLockAdapter struct {
+	db DBAdapter
+}
+
+// NewPostgresLockAdapter creates a new LockAdapter backed by Postgres advisory locks
+func NewPostgresLockAdapter(db DBAdapter) LockAdapter {
+	return &pgLockAdapter{db: db}
+}
+
+// TryLock attempts to acquire the advisory lock for name. Advisory locks belong to a
+// database session, so a dedicated connection is held until the lock is released.
+func (a *pgLockAdapter) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	return a.acquire(ctx, name, "SELECT pg_try_advisory_lock($1)")
+}
+
+// Lock acquires the advisory lock for name, waiting for it until ctx is done. Waiters poll
+// with TryLock, backing off up to lockMaxBackoff, rather than block in pg_advisory_lock, so
+// they hold no connection while waiting and cannot starve the holder of the pool.
+func (a *pgLockAdapter) Lock(ctx context.Context, name string) (func(), error) {
+	backoff := lockMinBackoff
+	for {
+		unlock, acquired, err := a.TryLock(ctx, name)
+		if err != nil || acquired {
+			return unlock, err
+		}
+
+		// Jitter spreads the attempts of the waiters of a lock
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to acquire lock %s: %w", name, ctx.Err())
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, lockMaxBackoff)
+	}
+}
+
+// acquire runs the locking query on a dedicated connection; the query reports whether the
+// lock was acquired. Locks are held on the main database, with names scoped to the data
+// residency region of ctx since the IDs of different regions overlap.
+func (a *pgLockAdapter) acquire(ctx context.Context, name, query string) (func(), bool, error) {
+	log := logger.Context(ctx)
+
+	if region := RegionOf(ctx); region != "" {
+		name = region + ":" + name
+	}
+
+	sqlDB, err := a.db.GetDB().DB()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get underlying *sql.DB: %w", err)
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection for lock %s: %w", name, err)
+	}
+
+	key := lockKey(name)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, query, key).Scan(&acquired); err != nil {
+		// A cancelled wait may leave the session in any state, so it is discarded
+		conn.Raw(func(driverConn interface{}) error { return driver.ErrBadConn })
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		defer conn.Close()
+		// Use a fresh context so the lock is released even if ctx was cancelled
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log.Errorw("Failed to release lock", "lock", name, "error", err)
+			// Ending the session releases the lock, so discard the connection instead of pooling it
+			conn.Raw(func(driverConn interface{}) error { return driver.ErrBadConn })
+		}
+	}
+
+	return unlock, true, nil
+}
+
+// lockKey maps a lock name to the int64 key space of advisory locks
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
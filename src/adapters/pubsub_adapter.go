@@ -0,0 +1,174 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+)
+
+// PubSubAdapter defines the interface for broadcasting messages to the subscribers of a
+// channel on every service instance
+type PubSubAdapter interface {
+	// Publish sends message to the current subscribers of channel
+	Publish(ctx context.Context, channel, message string) error
+
+	// Subscribe returns the messages published on channel once the subscription is in place.
+	// They are delivered until ctx is done or the subscription is lost, which closes the
+	// returned channel; messages published meanwhile are not delivered.
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+}
+
+// pubSubBuffer is the number of messages a subscriber may leave unread; slower subscribers
+// lose their subscription, as Redis disconnects them
+const pubSubBuffer = 256
+
+// memoryPubSubAdapter implements PubSubAdapter in the memory of the instance, for a single
+// instance
+type memoryPubSubAdapter struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan string]struct{}
+}
+
+// NewMemoryPubSubAdapter creates a new PubSubAdapter delivering the messages published by
+// the instance
+func NewMemoryPubSubAdapter() PubSubAdapter {
+	return &memoryPubSubAdapter{subscribers: map[string]map[chan string]struct{}{}}
+}
+
+// Publish sends message to the current subscribers of channel
+func (a *memoryPubSubAdapter) Publish(ctx context.Context, channel, message string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for messages := range a.subscribers[channel] {
+		select {
+		case messages <- message:
+		default:
+			delete(a.subscribers[channel], messages)
+			close(messages)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the messages published on channel until ctx is done
+func (a *memoryPubSubAdapter) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	messages := make(chan string, pubSubBuffer)
+
+	a.mu.Lock()
+	if a.subscribers[channel] == nil {
+		a.subscribers[channel] = map[chan string]struct{}{}
+	}
+	a.subscribers[channel][messages] = struct{}{}
+	a.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if _, ok := a.subscribers[channel][messages]; ok {
+			delete(a.subscribers[channel], messages)
+			close(messages)
+		}
+	}()
+	return messages, nil
+}
+
+// redisPubSubPing is how often subscriptions to Redis are checked, so that a lost connection
+// ends them within about that long
+const redisPubSubPing = 15 * time.Second
+
+// redisPubSubAdapter implements PubSubAdapter with the PUBLISH and SUBSCRIBE commands of Redis
+type redisPubSubAdapter struct {
+	redis *redisCacheAdapter
+}
+
+// NewRedisPubSubAdapter creates a new PubSubAdapter backed by Redis. Messages are published
+// over the pooled connections, and each subscription has a connection of its own.
+func NewRedisPubSubAdapter(config configs.ChatOwnersRedis) PubSubAdapter {
+	return &redisPubSubAdapter{redis: NewRedisCacheAdapter(config).(*redisCacheAdapter)}
+}
+
+// Publish sends message to the current subscribers of channel
+func (a *redisPubSubAdapter) Publish(ctx context.Context, channel, message string) error {
+	_, err := a.redis.do(ctx, "PUBLISH", channel, message)
+	return err
+}
+
+// Subscribe returns the messages published on channel until ctx is done or the connection
+// is lost. The connection is pinged every redisPubSubPing, and lost once a reply is late.
+func (a *redisPubSubAdapter) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	conn, err := a.redis.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.conn.SetDeadline(time.Now().Add(a.redis.config.Timeout)); err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+	reply, err := conn.command("SUBSCRIBE", channel)
+	if err != nil {
+		conn.conn.Close()
+		return nil, fmt.Errorf("failed to subscribe to redis channel %s: %w", channel, err)
+	}
+	if items, ok := reply.([]interface{}); !ok || len(items) != 3 || items[0] != "subscribe" {
+		conn.conn.Close()
+		return nil, fmt.Errorf("redis: unexpected reply to SUBSCRIBE: %v", reply)
+	}
+
+	messages := make(chan string, pubSubBuffer)
+	done := make(chan struct{})
+	go func() {
+		// Closing the connection ends the reads below
+		defer conn.conn.Close()
+		ticker := time.NewTicker(redisPubSubPing)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := conn.conn.SetWriteDeadline(time.Now().Add(a.redis.config.Timeout)); err != nil {
+					return
+				}
+				if err := conn.write("PING"); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	go func() {
+		defer close(messages)
+		defer close(done)
+		for {
+			if err := conn.conn.SetReadDeadline(time.Now().Add(redisPubSubPing + a.redis.config.Timeout)); err != nil {
+				return
+			}
+			reply, err := conn.reply()
+			if err != nil {
+				return
+			}
+			// Pushed messages are [message, channel, payload]; pongs are [pong, ""]
+			items, ok := reply.([]interface{})
+			if !ok || len(items) != 3 || items[0] != "message" {
+				continue
+			}
+			message, ok := items[2].(string)
+			if !ok {
+				continue
+			}
+			select {
+			case messages <- message:
+			default:
+				// Unread messages would be lost, so the subscription is
+				return
+			}
+		}
+	}()
+	return messages, nil
+}
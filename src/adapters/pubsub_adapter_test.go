@@ -0,0 +1,105 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receive returns the next message of a subscription, failing the test after a second
+func receive(t *testing.T, messages <-chan string) (string, bool) {
+	select {
+	case message, ok := <-messages:
+		return message, ok
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return "", false
+	}
+}
+
+func TestMemoryPubSubAdapter(t *testing.T) {
+	pubsub := NewMemoryPubSubAdapter()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	first, err := pubsub.Subscribe(ctx, "c")
+	require.NoError(t, err)
+	second, err := pubsub.Subscribe(context.Background(), "c")
+	require.NoError(t, err)
+	other, err := pubsub.Subscribe(context.Background(), "other")
+	require.NoError(t, err)
+
+	require.NoError(t, pubsub.Publish(ctx, "c", "hello"))
+	for _, messages := range []<-chan string{first, second} {
+		message, ok := receive(t, messages)
+		require.True(t, ok)
+		assert.Equal(t, "hello", message)
+	}
+	assert.Empty(t, other)
+
+	// Ending a subscription closes its messages
+	cancel()
+	_, ok := receive(t, first)
+	assert.False(t, ok)
+}
+
+func TestMemoryPubSubAdapterSlowSubscriber(t *testing.T) {
+	pubsub := NewMemoryPubSubAdapter()
+	ctx := context.Background()
+
+	messages, err := pubsub.Subscribe(ctx, "c")
+	require.NoError(t, err)
+	for i := 0; i <= pubSubBuffer; i++ {
+		require.NoError(t, pubsub.Publish(ctx, "c", "hello"))
+	}
+
+	// A subscriber missing messages loses its subscription, after those it had
+	for i := 0; i < pubSubBuffer; i++ {
+		_, ok := receive(t, messages)
+		require.True(t, ok)
+	}
+	_, ok := receive(t, messages)
+	assert.False(t, ok)
+}
+
+func TestRedisPubSubAdapter(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		switch args[0] {
+		case "SUBSCRIBE":
+			// The confirmation, followed by a message pushed to the subscriber
+			return "*3\r\n$9\r\nsubscribe\r\n$1\r\nc\r\n:1\r\n*3\r\n$7\r\nmessage\r\n$1\r\nc\r\n$5\r\nhello\r\n"
+		default:
+			return ":1\r\n"
+		}
+	})
+	pubsub := &redisPubSubAdapter{redis: server.adapter().(*redisCacheAdapter)}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	require.NoError(t, pubsub.Publish(ctx, "c", "hello"))
+	assert.Equal(t, []string{"PUBLISH", "c", "hello"}, <-server.commands)
+
+	messages, err := pubsub.Subscribe(ctx, "c")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SUBSCRIBE", "c"}, <-server.commands)
+	message, ok := receive(t, messages)
+	require.True(t, ok)
+	assert.Equal(t, "hello", message)
+
+	// Ending the subscription closes its connection and messages
+	cancel()
+	_, ok = receive(t, messages)
+	assert.False(t, ok)
+}
+
+func TestRedisPubSubAdapterSubscribeError(t *testing.T) {
+	server := newFakeRedis(t, func(args []string) string {
+		return "-ERR unknown command\r\n"
+	})
+	pubsub := &redisPubSubAdapter{redis: server.adapter().(*redisCacheAdapter)}
+
+	_, err := pubsub.Subscribe(context.Background(), "c")
+	var replyErr redisError
+	require.ErrorAs(t, err, &replyErr)
+}
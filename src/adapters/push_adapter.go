@@ -0,0 +1,367 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// Push platforms
+const (
+	PushPlatformFCM  = "fcm"
+	PushPlatformAPNs = "apns"
+)
+
+// ErrInvalidDeviceToken is returned when the push service no longer accepts a device
+// token, typically because the app was uninstalled; the token should be forgotten
+var ErrInvalidDeviceToken = errors.New("invalid device token")
+
+// PushAdapter defines the interface for pushing notifications to mobile devices
+type PushAdapter interface {
+	// Send pushes a notification to the device identified by token
+	Send(ctx context.Context, token string, notification *dtos.PushNotification) error
+}
+
+// pushTimeout bounds the requests to push services
+const pushTimeout = 10 * time.Second
+
+// fcmScope is the OAuth scope required to send messages with FCM
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// fcmCredentials is the part of a service account JSON key used to obtain access tokens
+type fcmCredentials struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+	ProjectID   string `json:"project_id"`
+}
+
+// fcmPushAdapter sends notifications with the FCM HTTP v1 API
+type fcmPushAdapter struct {
+	client      *http.Client
+	credentials fcmCredentials
+	key         *rsa.PrivateKey
+	sendURL     string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMPushAdapter creates a push adapter for Android devices, authenticated with
+// the configured service account
+func NewFCMPushAdapter(config configs.FCM) (PushAdapter, error) {
+	data, err := os.ReadFile(config.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+	}
+
+	var credentials fcmCredentials
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to parse FCM credentials: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(credentials.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FCM private key: %w", err)
+	}
+
+	projectID := config.ProjectID
+	if projectID == "" {
+		projectID = credentials.ProjectID
+	}
+
+	return &fcmPushAdapter{
+		client:      &http.Client{Timeout: pushTimeout},
+		credentials: credentials,
+		key:         key,
+		sendURL:     fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", projectID),
+	}, nil
+}
+
+// Send pushes a notification to an Android device
+func (a *fcmPushAdapter) Send(ctx context.Context, token string, notification *dtos.PushNotification) error {
+	accessToken, err := a.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": token,
+			"notification": map[string]string{
+				"title": notification.Title,
+				"body":  notification.Body,
+			},
+			"data": notification.Data,
+			"android": map[string]interface{}{
+				"notification": map[string]string{"tag": notification.ThreadID},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.sendURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to FCM: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	// Unregistered tokens are reported as not found
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrInvalidDeviceToken
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode == http.StatusBadRequest && strings.Contains(string(detail), "registration token") {
+		return ErrInvalidDeviceToken
+	}
+	return fmt.Errorf("FCM returned error: %d %s", resp.StatusCode, detail)
+}
+
+// token returns an OAuth access token, exchanging a signed service account assertion
+// for a new one when the cached token is about to expire
+func (a *fcmPushAdapter) token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.accessToken != "" && time.Until(a.expiresAt) > time.Minute {
+		return a.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   a.credentials.ClientEmail,
+		"scope": fcmScope,
+		"aud":   a.credentials.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign FCM assertion: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.credentials.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get FCM access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("FCM token endpoint returned error: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse FCM access token: %w", err)
+	}
+
+	a.accessToken = result.AccessToken
+	a.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return a.accessToken, nil
+}
+
+// apnsTokenLifetime is how long an APNs provider token is reused; Apple rejects
+// tokens older than an hour and refreshing them more often than every 20 minutes
+const apnsTokenLifetime = 30 * time.Minute
+
+// apnsPushAdapter sends notifications with the APNs HTTP/2 API and token-based authentication
+type apnsPushAdapter struct {
+	client  *http.Client
+	baseURL string
+	key     *ecdsa.PrivateKey
+	keyID   string
+	teamID  string
+	topic   string
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsPushAdapter creates a push adapter for iOS devices, authenticated with the
+// configured token signing key
+func NewAPNsPushAdapter(config configs.APNs) (PushAdapter, error) {
+	data, err := os.ReadFile(config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read APNs key: %w", err)
+	}
+
+	key, err := jwt.ParseECPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse APNs key: %w", err)
+	}
+
+	baseURL := "https://api.sandbox.push.apple.com"
+	if config.Production {
+		baseURL = "https://api.push.apple.com"
+	}
+
+	return &apnsPushAdapter{
+		client:  &http.Client{Timeout: pushTimeout},
+		baseURL: baseURL,
+		key:     key,
+		keyID:   config.KeyID,
+		teamID:  config.TeamID,
+		topic:   config.Topic,
+	}, nil
+}
+
+// Send pushes a notification to an iOS device
+func (a *apnsPushAdapter) Send(ctx context.Context, token string, notification *dtos.PushNotification) error {
+	providerToken, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{
+				"title": notification.Title,
+				"body":  notification.Body,
+			},
+			"thread-id": notification.ThreadID,
+			"sound":     "default",
+		},
+	}
+	for key, value := range notification.Data {
+		payload[key] = value
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to APNs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode == http.StatusGone || result.Reason == "BadDeviceToken" || result.Reason == "Unregistered" {
+		return ErrInvalidDeviceToken
+	}
+	return fmt.Errorf("APNs returned error: %d %s", resp.StatusCode, result.Reason)
+}
+
+// providerToken returns the signed provider token, renewing it once it is apnsTokenLifetime old
+func (a *apnsPushAdapter) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Since(a.issuedAt) < apnsTokenLifetime {
+		return a.token, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = a.keyID
+
+	signed, err := token.SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign APNs token: %w", err)
+	}
+
+	a.token = signed
+	a.issuedAt = now
+	return a.token, nil
+}
+
+// logPushAdapter only logs notifications, for development
+type logPushAdapter struct{}
+
+// NewLogPushAdapter creates a push adapter that logs notifications instead of sending them
+func NewLogPushAdapter() PushAdapter {
+	return &logPushAdapter{}
+}
+
+// Send logs the notification
+func (a *logPushAdapter) Send(ctx context.Context, token string, notification *dtos.PushNotification) error {
+	logger.Context(ctx).Infow("Push notification not sent, logging only", "title", notification.Title, "threadID", notification.ThreadID)
+	return nil
+}
+
+// NewPushAdapters creates the push adapters of the enabled platforms, keyed by platform.
+// Platforms that are not enabled only log their notifications.
+func NewPushAdapters(config configs.Push) (map[string]PushAdapter, error) {
+	pushAdapters := map[string]PushAdapter{
+		PushPlatformFCM:  NewLogPushAdapter(),
+		PushPlatformAPNs: NewLogPushAdapter(),
+	}
+
+	if config.FCM.Enabled {
+		adapter, err := NewFCMPushAdapter(config.FCM)
+		if err != nil {
+			return nil, err
+		}
+		pushAdapters[PushPlatformFCM] = adapter
+	}
+	if config.APNs.Enabled {
+		adapter, err := NewAPNsPushAdapter(config.APNs)
+		if err != nil {
+			return nil, err
+		}
+		pushAdapters[PushPlatformAPNs] = adapter
+	}
+
+	return pushAdapters, nil
+}
@@ -0,0 +1,146 @@
+package adapters
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotsRule is an allow or disallow rule of robots.txt
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// RobotsRules are the robots.txt rules of a site applying to the crawler. Nil rules allow
+// every path.
+type RobotsRules struct {
+	rules []robotsRule
+	// CrawlDelay is the delay the site asks for between requests
+	CrawlDelay time.Duration
+	// Sitemaps are the sitemap URLs the site lists
+	Sitemaps []string
+}
+
+// Allowed reports whether the rules allow crawling the path, with its query. As in RFC 9309,
+// the most specific matching rule wins and allow rules win ties.
+func (r *RobotsRules) Allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// robotsGroup is a group of robots.txt rules with the user agents it applies to
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// ParseRobots parses a robots.txt file, keeping the rules of the groups of the product token
+// of agent, or those of the * groups when no group names it
+func ParseRobots(r io.Reader, agent string) *RobotsRules {
+	token := strings.ToLower(agent)
+	if i := strings.IndexByte(token, '/'); i >= 0 {
+		token = token[:i]
+	}
+
+	var groups []*robotsGroup
+	var group *robotsGroup
+	inRules := false
+	rules := &RobotsRules{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user agent following rules starts a new group
+			if group == nil || inRules {
+				group = &robotsGroup{}
+				groups = append(groups, group)
+				inRules = false
+			}
+			group.agents = append(group.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if group == nil {
+				continue
+			}
+			inRules = true
+			// An empty disallow rule allows everything, as no rule does
+			if value != "" {
+				group.rules = append(group.rules, robotsRule{pattern: value, allow: key == "allow"})
+			}
+		case "crawl-delay":
+			if group == nil {
+				continue
+			}
+			inRules = true
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				group.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			rules.Sitemaps = append(rules.Sitemaps, value)
+		}
+	}
+
+	for _, name := range []string{token, "*"} {
+		matched := false
+		for _, group := range groups {
+			if slices.Contains(group.agents, name) {
+				matched = true
+				rules.rules = append(rules.rules, group.rules...)
+				rules.CrawlDelay = max(rules.CrawlDelay, group.crawlDelay)
+			}
+		}
+		if matched {
+			break
+		}
+	}
+
+	return rules
+}
+
+// robotsMatch reports whether a robots.txt path pattern matches the path. Patterns match
+// path prefixes, * matches any characters and a trailing $ anchors the end of the path.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		// The last part of an anchored pattern has to end the path
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+	return !anchored || rest == ""
+}
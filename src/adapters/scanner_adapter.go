@@ -0,0 +1,169 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+)
+
+// Scanning providers
+const (
+	ScannerClamAV = "clamav"
+	ScannerHTTP   = "http"
+)
+
+// clamdChunkSize is the size of the chunks a file is streamed to clamd in
+const clamdChunkSize = 64 * 1024
+
+// ScanResult is the verdict of a malware scan
+type ScanResult struct {
+	Infected bool
+	// Threat names the malware found in an infected file
+	Threat string
+}
+
+// ScannerAdapter defines the interface for scanning files for malware
+type ScannerAdapter interface {
+	// Scan scans the content read from r
+	Scan(ctx context.Context, r io.Reader, filename string) (*ScanResult, error)
+}
+
+// NewScannerAdapter creates a scanning adapter for the configured provider: a clamd daemon
+// or a cloud scanning API
+func NewScannerAdapter(config configs.AttachmentScanning) (ScannerAdapter, error) {
+	switch config.Provider {
+	case ScannerClamAV:
+		if config.Address == "" {
+			return nil, fmt.Errorf("clamd address is required for the clamav provider")
+		}
+		return &clamdAdapter{address: config.Address, timeout: config.Timeout}, nil
+	case ScannerHTTP:
+		if config.URL == "" {
+			return nil, fmt.Errorf("scanning URL is required for the http provider")
+		}
+		return &httpScannerAdapter{
+			client: &http.Client{Timeout: config.Timeout},
+			url:    config.URL,
+			apiKey: config.APIKey,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown scanning provider %q", config.Provider)
+	}
+}
+
+// clamdAdapter scans files with a clamd daemon through its INSTREAM command
+type clamdAdapter struct {
+	address string
+	timeout time.Duration
+}
+
+// Scan streams the content to clamd in length-prefixed chunks and parses its reply
+func (a *clamdAdapter) Scan(ctx context.Context, r io.Reader, filename string) (*ScanResult, error) {
+	dialer := net.Dialer{Timeout: a.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", a.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	if a.timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(a.timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return nil, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+	chunk := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := r.Read(chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(append(size, chunk[:n]...)); err != nil {
+				return nil, fmt.Errorf("failed to send to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read file: %w", readErr)
+		}
+	}
+	// A zero-length chunk ends the stream
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return nil, fmt.Errorf("failed to send to clamd: %w", err)
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamdReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamdReply parses a reply such as "stream: OK" or "stream: Eicar-Signature FOUND"
+func parseClamdReply(reply string) (*ScanResult, error) {
+	verdict := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case verdict == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &ScanResult{Infected: true, Threat: strings.TrimSuffix(verdict, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamd returned error: %s", reply)
+	}
+}
+
+// httpScannerAdapter scans files with a cloud scanning API. The file is the body of a POST
+// request, and the API responds with {"infected": bool, "threat": "..."}.
+type httpScannerAdapter struct {
+	client *http.Client
+	url    string
+	apiKey string
+}
+
+// Scan uploads the content to the scanning API and returns its verdict
+func (a *httpScannerAdapter) Scan(ctx context.Context, r io.Reader, filename string) (*ScanResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Filename", filename)
+	if a.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to scanning service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("scanning service returned error: %d %s", resp.StatusCode, detail)
+	}
+
+	var result struct {
+		Infected bool   `json:"infected"`
+		Threat   string `json:"threat"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse scan result: %w", err)
+	}
+	return &ScanResult{Infected: result.Infected, Threat: result.Threat}, nil
+}
@@ -0,0 +1,91 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// SchemaRegistryAdapter registers event schemas with a schema registry
+type SchemaRegistryAdapter interface {
+	// Register registers a JSON schema under a subject and returns its schema ID
+	Register(ctx context.Context, subject string, schema []byte) (int, error)
+}
+
+// schemaRegistryAdapter implements SchemaRegistryAdapter against the
+// Confluent-compatible schema registry REST API
+type schemaRegistryAdapter struct {
+	client  *http.Client
+	baseURL string
+}
+
+// registerSchemaRequest is the body of a schema registration
+type registerSchemaRequest struct {
+	SchemaType string `json:"schemaType"`
+	Schema     string `json:"schema"`
+}
+
+// registerSchemaResponse is the response of a schema registration
+type registerSchemaResponse struct {
+	ID int `json:"id"`
+}
+
+// NewSchemaRegistryAdapter creates a new SchemaRegistryAdapter
+func NewSchemaRegistryAdapter(config configs.SchemaRegistry) SchemaRegistryAdapter {
+	return &schemaRegistryAdapter{
+		client: &http.Client{
+			Timeout: config.Timeout,
+		},
+		baseURL: config.URL,
+	}
+}
+
+// Register registers a JSON schema under a subject. Registering an identical
+// schema again returns the existing ID.
+func (a *schemaRegistryAdapter) Register(ctx context.Context, subject string, schema []byte) (int, error) {
+	log := logger.Context(ctx)
+
+	jsonData, err := json.Marshal(registerSchemaRequest{
+		SchemaType: "JSON",
+		Schema:     string(schema),
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, errors.ErrInternal, "Failed to marshal schema registration")
+	}
+
+	endpoint := fmt.Sprintf("%s/subjects/%s/versions", a.baseURL, url.PathEscape(subject))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, errors.Wrap(err, errors.ErrInternal, "Failed to create schema registration request")
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		log.Errorw("Failed to register schema", "error", err, "subject", subject)
+		return 0, errors.Wrap(err, errors.ErrInternal, "Failed to register schema")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Errorw("Schema registry returned error", "status", resp.StatusCode, "body", string(body), "subject", subject)
+		return 0, errors.New(errors.ErrInternal, fmt.Sprintf("Schema registry returned status %d", resp.StatusCode))
+	}
+
+	var result registerSchemaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, errors.Wrap(err, errors.ErrInternal, "Failed to decode schema registration response")
+	}
+
+	log.Infow("Registered event schema", "subject", subject, "id", result.ID)
+	return result.ID, nil
+}
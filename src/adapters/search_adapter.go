@@ -0,0 +1,303 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// SearchAdapter defines the interface for indexing and searching chats and messages
+type SearchAdapter interface {
+	// EnsureIndices creates the chat and message indices when they are missing
+	EnsureIndices(ctx context.Context) error
+
+	// IndexChat adds or replaces a chat in the index
+	IndexChat(ctx context.Context, doc *dtos.ChatDocument) error
+
+	// DeleteChat removes a chat and its messages from the index
+	DeleteChat(ctx context.Context, chatID int64) error
+
+	// SetChatOwner changes the owner of a chat and its messages in the index
+	SetChatOwner(ctx context.Context, chatID int64, userID string) error
+
+	// IndexMessage adds or replaces a message in the index
+	IndexMessage(ctx context.Context, doc *dtos.MessageDocument) error
+
+	// DeleteMessage removes a message from the index
+	DeleteMessage(ctx context.Context, messageID int64) error
+
+	// SearchChats returns the IDs of the chats of a user matching a query by title, best
+	// matches first, with the total number of matches. An empty query matches every chat.
+	SearchChats(ctx context.Context, userID, query string, limit, offset int) ([]int64, int64, error)
+
+	// SearchMessages returns the IDs of the messages of the chats of a user matching a query,
+	// optionally within a chat, best matches first, with the total number of matches
+	SearchMessages(ctx context.Context, userID, query string, chatID int64, limit, offset int) ([]int64, int64, error)
+
+	// ResetChats and ResetMessages recreate the chat and message indices empty
+	ResetChats(ctx context.Context) error
+	ResetMessages(ctx context.Context) error
+}
+
+// Mappings of the indices, created when an index is missing or reset
+const (
+	chatIndexMapping = `{"mappings":{"properties":{
+		"chat_id":{"type":"long"},
+		"user_id":{"type":"keyword"},
+		"title":{"type":"text"},
+		"updated_at":{"type":"date"}}}}`
+	messageIndexMapping = `{"mappings":{"properties":{
+		"message_id":{"type":"long"},
+		"chat_id":{"type":"long"},
+		"user_id":{"type":"keyword"},
+		"role":{"type":"keyword"},
+		"content":{"type":"text"},
+		"created_at":{"type":"date"}}}}`
+)
+
+// elasticsearchAdapter indexes and searches with the REST API shared by Elasticsearch and
+// OpenSearch
+type elasticsearchAdapter struct {
+	client       *http.Client
+	baseURL      string
+	username     string
+	password     string
+	chatIndex    string
+	messageIndex string
+}
+
+// NewSearchAdapter creates a search adapter for the configured service
+func NewSearchAdapter(config configs.Search) (SearchAdapter, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("search URL is required")
+	}
+
+	return &elasticsearchAdapter{
+		client:       &http.Client{Timeout: config.Timeout},
+		baseURL:      strings.TrimSuffix(config.URL, "/"),
+		username:     config.Username,
+		password:     config.Password,
+		chatIndex:    config.Index + "-chats",
+		messageIndex: config.Index + "-messages",
+	}, nil
+}
+
+// EnsureIndices creates the chat and message indices when they are missing, so documents are
+// never indexed with dynamic mappings
+func (a *elasticsearchAdapter) EnsureIndices(ctx context.Context) error {
+	for index, mapping := range map[string]string{a.chatIndex: chatIndexMapping, a.messageIndex: messageIndexMapping} {
+		err := a.do(ctx, http.MethodHead, "/"+index, nil, nil)
+		if isStatus(err, http.StatusNotFound) {
+			err = a.do(ctx, http.MethodPut, "/"+index, json.RawMessage(mapping), nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexChat adds or replaces a chat in the index
+func (a *elasticsearchAdapter) IndexChat(ctx context.Context, doc *dtos.ChatDocument) error {
+	return a.do(ctx, http.MethodPut, a.docPath(a.chatIndex, doc.ChatID), doc, nil)
+}
+
+// DeleteChat removes a chat and its messages from the index
+func (a *elasticsearchAdapter) DeleteChat(ctx context.Context, chatID int64) error {
+	if err := a.do(ctx, http.MethodPost, "/"+a.messageIndex+"/_delete_by_query?conflicts=proceed", map[string]interface{}{
+		"query": map[string]interface{}{"term": map[string]interface{}{"chat_id": chatID}},
+	}, nil); err != nil {
+		return err
+	}
+	return a.ignoreNotFound(a.do(ctx, http.MethodDelete, a.docPath(a.chatIndex, chatID), nil, nil))
+}
+
+// SetChatOwner changes the owner of a chat and its messages in the index
+func (a *elasticsearchAdapter) SetChatOwner(ctx context.Context, chatID int64, userID string) error {
+	update := map[string]interface{}{
+		"query": map[string]interface{}{"term": map[string]interface{}{"chat_id": chatID}},
+		"script": map[string]interface{}{
+			"source": "ctx._source.user_id = params.userId",
+			"params": map[string]string{"userId": userID},
+		},
+	}
+	if err := a.do(ctx, http.MethodPost, "/"+a.chatIndex+"/_update_by_query?conflicts=proceed", update, nil); err != nil {
+		return err
+	}
+	return a.do(ctx, http.MethodPost, "/"+a.messageIndex+"/_update_by_query?conflicts=proceed", update, nil)
+}
+
+// IndexMessage adds or replaces a message in the index
+func (a *elasticsearchAdapter) IndexMessage(ctx context.Context, doc *dtos.MessageDocument) error {
+	return a.do(ctx, http.MethodPut, a.docPath(a.messageIndex, doc.MessageID), doc, nil)
+}
+
+// DeleteMessage removes a message from the index
+func (a *elasticsearchAdapter) DeleteMessage(ctx context.Context, messageID int64) error {
+	return a.ignoreNotFound(a.do(ctx, http.MethodDelete, a.docPath(a.messageIndex, messageID), nil, nil))
+}
+
+// SearchChats returns the IDs of the chats of a user matching a query by title
+func (a *elasticsearchAdapter) SearchChats(ctx context.Context, userID, query string, limit, offset int) ([]int64, int64, error) {
+	filters := []interface{}{map[string]interface{}{"term": map[string]interface{}{"user_id": userID}}}
+	return a.search(ctx, a.chatIndex, "chat_id", "title", "updated_at", query, filters, limit, offset)
+}
+
+// SearchMessages returns the IDs of the messages of the chats of a user matching a query
+func (a *elasticsearchAdapter) SearchMessages(ctx context.Context, userID, query string, chatID int64, limit, offset int) ([]int64, int64, error) {
+	filters := []interface{}{map[string]interface{}{"term": map[string]interface{}{"user_id": userID}}}
+	if chatID != 0 {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"chat_id": chatID}})
+	}
+	return a.search(ctx, a.messageIndex, "message_id", "content", "created_at", query, filters, limit, offset)
+}
+
+// ResetChats recreates the chat index empty
+func (a *elasticsearchAdapter) ResetChats(ctx context.Context) error {
+	return a.resetIndex(ctx, a.chatIndex, chatIndexMapping)
+}
+
+// ResetMessages recreates the message index empty
+func (a *elasticsearchAdapter) ResetMessages(ctx context.Context) error {
+	return a.resetIndex(ctx, a.messageIndex, messageIndexMapping)
+}
+
+// search runs a query on a field of an index within filters and returns the IDs of the
+// matching documents. Matches are ranked by relevance, then by date; without a query, by date.
+func (a *elasticsearchAdapter) search(ctx context.Context, index, idField, field, dateField, query string, filters []interface{}, limit, offset int) ([]int64, int64, error) {
+	boolQuery := map[string]interface{}{"filter": filters}
+	sort := []interface{}{map[string]string{dateField: "desc"}}
+	if query != "" {
+		boolQuery["must"] = map[string]interface{}{
+			"match": map[string]interface{}{
+				field: map[string]interface{}{"query": query, "operator": "and", "fuzziness": "AUTO"},
+			},
+		}
+		sort = append([]interface{}{"_score"}, sort...)
+	}
+
+	body := map[string]interface{}{
+		"query":            map[string]interface{}{"bool": boolQuery},
+		"sort":             sort,
+		"from":             offset,
+		"size":             limit,
+		"_source":          []string{idField},
+		"track_total_hits": true,
+	}
+
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source map[string]json.Number `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	err := a.do(ctx, http.MethodPost, "/"+index+"/_search", body, &result)
+	if isStatus(err, http.StatusNotFound) {
+		// Nothing was indexed yet
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]int64, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		id, err := hit.Source[idField].Int64()
+		if err != nil {
+			return nil, 0, fmt.Errorf("search service returned an invalid %s: %w", idField, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, result.Hits.Total.Value, nil
+}
+
+// resetIndex deletes an index and creates it again with its mapping
+func (a *elasticsearchAdapter) resetIndex(ctx context.Context, index, mapping string) error {
+	if err := a.ignoreNotFound(a.do(ctx, http.MethodDelete, "/"+index, nil, nil)); err != nil {
+		return err
+	}
+	return a.do(ctx, http.MethodPut, "/"+index, json.RawMessage(mapping), nil)
+}
+
+// docPath returns the path of a document of an index
+func (a *elasticsearchAdapter) docPath(index string, id int64) string {
+	return "/" + index + "/_doc/" + strconv.FormatInt(id, 10)
+}
+
+// searchStatusError is a response of the search service with an error status
+type searchStatusError struct {
+	status int
+	detail string
+}
+
+func (e *searchStatusError) Error() string {
+	return fmt.Sprintf("search service returned error: %d %s", e.status, e.detail)
+}
+
+// isStatus reports whether err is a response of the search service with the status
+func isStatus(err error, status int) bool {
+	statusErr, ok := err.(*searchStatusError)
+	return ok && statusErr.status == status
+}
+
+// ignoreNotFound drops the error of deleting something already missing
+func (a *elasticsearchAdapter) ignoreNotFound(err error) error {
+	if isStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+// do sends a JSON request to the service and decodes its response into out, when given
+func (a *elasticsearchAdapter) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.username != "" {
+		req.SetBasicAuth(a.username, a.password)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to search service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &searchStatusError{status: resp.StatusCode, detail: string(detail)}
+	}
+
+	if out == nil {
+		return nil
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(out); err != nil {
+		return fmt.Errorf("failed to parse search service response: %w", err)
+	}
+	return nil
+}
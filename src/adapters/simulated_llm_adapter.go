@@ -0,0 +1,198 @@
+package adapters
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// simulatedWords is the vocabulary used to build synthetic completions
+var simulatedWords = []string{
+	"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit",
+	"sed", "do", "eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore",
+	"magna", "aliqua", "enim", "ad", "minim", "veniam", "quis", "nostrud",
+}
+
+// simulatedLLMAdapter implements LLMAdapter and LLMStreamer with synthetic latency,
+// token counts and failures, so load tests can exercise the full pipeline without vendor cost
+type simulatedLLMAdapter struct {
+	config configs.Simulated
+	model  string
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewSimulatedLLMAdapter creates a new simulated LLMAdapter
+func NewSimulatedLLMAdapter(config configs.Simulated, model string) LLMAdapter {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	if config.MaxTokens < config.MinTokens {
+		config.MaxTokens = config.MinTokens
+	}
+	if config.ChunkTokens <= 0 {
+		config.ChunkTokens = 1
+	}
+
+	return &simulatedLLMAdapter{
+		config: config,
+		model:  model,
+		rnd:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+// GenerateResponse waits for a sampled latency and returns a synthetic completion
+func (a *simulatedLLMAdapter) GenerateResponse(ctx context.Context, request *dtos.LLMRequest) (*dtos.LLMResponse, error) {
+	return a.StreamResponse(ctx, request, nil)
+}
+
+// StreamResponse emits a synthetic completion in chunks at the configured cadence.
+// The sampled latency is spent before the first chunk, like a real time-to-first-token.
+func (a *simulatedLLMAdapter) StreamResponse(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, error) {
+	log := logger.Context(ctx)
+
+	latency, tokens, failAt := a.sample()
+	log.Debugw("Simulating LLM response", "latency", latency, "tokens", tokens, "failAt", failAt)
+
+	if err := sleepContext(ctx, latency); err != nil {
+		return nil, errors.Wrap(err, errors.ErrLLMService, "Simulated LLM request cancelled")
+	}
+
+	words := a.words(tokens)
+	var content strings.Builder
+	index := 0
+	for start := 0; start < len(words); start += a.config.ChunkTokens {
+		if failAt >= 0 && start >= failAt {
+			return nil, errors.New(errors.ErrLLMService, "Simulated LLM failure")
+		}
+
+		end := start + a.config.ChunkTokens
+		if end > len(words) {
+			end = len(words)
+		}
+
+		piece := strings.Join(words[start:end], " ")
+		if start > 0 {
+			piece = " " + piece
+		}
+		content.WriteString(piece)
+
+		if onChunk != nil {
+			if err := onChunk(&dtos.LLMChunk{Index: index, Content: piece}); err != nil {
+				return nil, err
+			}
+			if err := sleepContext(ctx, a.config.ChunkInterval); err != nil {
+				return nil, errors.Wrap(err, errors.ErrLLMService, "Simulated LLM request cancelled")
+			}
+		}
+		index++
+	}
+
+	if failAt >= 0 {
+		return nil, errors.New(errors.ErrLLMService, "Simulated LLM failure")
+	}
+
+	if onChunk != nil {
+		if err := onChunk(&dtos.LLMChunk{Index: index, Done: true}); err != nil {
+			return nil, err
+		}
+	}
+
+	promptTokens := 0
+	for _, msg := range request.Messages {
+		promptTokens += len(strings.Fields(msg.Content))
+	}
+
+	model := request.Model
+	if model == "" {
+		model = a.model
+	}
+
+	return &dtos.LLMResponse{
+		Message: dtos.LLMMessage{
+			Role:    "assistant",
+			Content: content.String(),
+		},
+		Usage: dtos.LLMUsage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: tokens,
+			TotalTokens:      promptTokens + tokens,
+		},
+		Model:    model,
+		Finished: true,
+	}, nil
+}
+
+// sample draws the latency, completion length and failure point for one request.
+// failAt is the token offset at which the request fails, or -1 when it succeeds.
+func (a *simulatedLLMAdapter) sample() (latency time.Duration, tokens int, failAt int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	mean := float64(a.config.LatencyMean)
+	stdDev := float64(a.config.LatencyStdDev)
+
+	var value float64
+	switch a.config.LatencyDistribution {
+	case "uniform":
+		value = mean - stdDev + a.rnd.Float64()*2*stdDev
+	case "normal":
+		value = mean + a.rnd.NormFloat64()*stdDev
+	case "exponential":
+		value = a.rnd.ExpFloat64() * mean
+	default:
+		value = mean
+	}
+	latency = time.Duration(math.Max(value, 0))
+
+	tokens = a.config.MinTokens
+	if span := a.config.MaxTokens - a.config.MinTokens; span > 0 {
+		tokens += a.rnd.Intn(span + 1)
+	}
+
+	failAt = -1
+	if a.config.ErrorRate > 0 && a.rnd.Float64() < a.config.ErrorRate {
+		failAt = a.rnd.Intn(tokens + 1)
+	}
+
+	return latency, tokens, failAt
+}
+
+// words builds a synthetic completion of n words
+func (a *simulatedLLMAdapter) words(n int) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	words := make([]string, n)
+	for i := range words {
+		words[i] = simulatedWords[a.rnd.Intn(len(simulatedWords))]
+	}
+	return words
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
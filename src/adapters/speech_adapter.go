@@ -0,0 +1,110 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// whisperBaseURL is the base URL of the OpenAI API hosting Whisper
+const whisperBaseURL = "https://api.openai.com/v1"
+
+// SpeechToTextAdapter defines the interface for transcribing audio
+type SpeechToTextAdapter interface {
+	// Transcribe returns the text spoken in the audio read from r
+	Transcribe(ctx context.Context, r io.Reader, filename, contentType string) (string, error)
+}
+
+// whisperAdapter transcribes audio with the OpenAI transcription API, served by
+// Whisper or by self-hosted servers implementing the same API
+type whisperAdapter struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+// NewSpeechToTextAdapter creates a speech-to-text adapter for the configured provider.
+// The local provider requires a base URL; whisper defaults to the OpenAI API.
+func NewSpeechToTextAdapter(config configs.Transcription) (SpeechToTextAdapter, error) {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		if config.Provider == "local" {
+			return nil, fmt.Errorf("transcription base URL is required for the local provider")
+		}
+		baseURL = whisperBaseURL
+	}
+
+	return &whisperAdapter{
+		client:  &http.Client{Timeout: config.Timeout},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  config.APIKey,
+		model:   config.Model,
+	}, nil
+}
+
+// Transcribe uploads the audio to the transcription endpoint and returns its text
+func (a *whisperAdapter) Transcribe(ctx context.Context, r io.Reader, filename, contentType string) (string, error) {
+	log := logger.Context(ctx)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("model", a.model); err != nil {
+		return "", err
+	}
+	if err := form.WriteField("response_format", "json"); err != nil {
+		return "", err
+	}
+	part, err := form.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {fmt.Sprintf(`form-data; name="file"; filename=%q`, filename)},
+		"Content-Type":        {contentType},
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return "", fmt.Errorf("failed to read audio: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if a.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to transcription service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("transcription service returned error: %d %s", resp.StatusCode, detail)
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse transcription: %w", err)
+	}
+
+	log.Debugw("Audio transcribed", "filename", filename, "length", len(result.Text))
+	return strings.TrimSpace(result.Text), nil
+}
@@ -0,0 +1,47 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// WaitFor calls connect until it succeeds, backing off exponentially between attempts, so
+// the service can start before its dependencies. Once the configured wait timeout has
+// passed, or ctx is done, the last connection error is returned.
+func WaitFor(ctx context.Context, name string, config configs.Startup, connect func(ctx context.Context) error) error {
+	deadline := time.Now().Add(config.WaitTimeout)
+	backoff := config.RetryBackoff
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := connect(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("Dependency is ready", logger.Field("dependency", name), logger.Field("attempts", attempt))
+			}
+			return nil
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+		logger.Warn("Dependency is not ready, retrying",
+			logger.Field("dependency", name),
+			logger.Field("attempt", attempt),
+			logger.Field("backoff", backoff),
+			logger.Field("error", err))
+		if sleepContext(ctx, backoff) != nil {
+			return err
+		}
+
+		backoff *= 2
+		if config.MaxBackoff > 0 && backoff > config.MaxBackoff {
+			backoff = config.MaxBackoff
+		}
+	}
+}
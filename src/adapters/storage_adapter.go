@@ -0,0 +1,203 @@
+package adapters
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+)
+
+// DownloadPath is the route serving files of the local storage through signed URLs
+const DownloadPath = "/downloads"
+
+// UploadPath is the route storing files in the local storage through signed URLs
+const UploadPath = "/uploads"
+
+// StorageAdapter defines the interface for storing generated files
+type StorageAdapter interface {
+	// Put stores the content read from r under key and returns its size
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+
+	// Open opens the file stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the file stored under key
+	Delete(ctx context.Context, key string) error
+
+	// SignedURL returns a URL downloading the file stored under key until ttl elapses
+	SignedURL(key string, ttl time.Duration) (string, error)
+
+	// Verify checks the expiry and signature of a URL returned by SignedURL
+	Verify(key string, expires int64, signature string) bool
+
+	// SignedUploadURL returns a URL storing the body of a PUT request under key until ttl elapses
+	SignedUploadURL(key string, ttl time.Duration) (string, error)
+
+	// VerifyUpload checks the expiry and signature of a URL returned by SignedUploadURL
+	VerifyUpload(key string, expires int64, signature string) bool
+
+	// Size returns the size of the file stored under key
+	Size(ctx context.Context, key string) (int64, error)
+}
+
+// localStorageAdapter stores files in a local directory
+type localStorageAdapter struct {
+	dir     string
+	baseURL string
+	secret  []byte
+}
+
+// NewLocalStorageAdapter creates a storage adapter writing files under the configured directory.
+// Its signed URLs point to DownloadPath and UploadPath on the configured base URL.
+func NewLocalStorageAdapter(config configs.Storage) (StorageAdapter, error) {
+	if config.Secret == "" {
+		return nil, fmt.Errorf("storage secret is required to sign download URLs")
+	}
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &localStorageAdapter{
+		dir:     config.Dir,
+		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+		secret:  []byte(config.Secret),
+	}, nil
+}
+
+// Put stores the content read from r under key and returns its size
+func (a *localStorageAdapter) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	path, err := a.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	// Write to a temporary file first so a partial file is never served
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("failed to store file: %w", err)
+	}
+	return size, nil
+}
+
+// Open opens the file stored under key
+func (a *localStorageAdapter) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := a.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes the file stored under key
+func (a *localStorageAdapter) Delete(ctx context.Context, key string) error {
+	path, err := a.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Size returns the size of the file stored under key
+func (a *localStorageAdapter) Size(ctx context.Context, key string) (int64, error) {
+	path, err := a.path(key)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// SignedURL returns a URL downloading the file stored under key until ttl elapses
+func (a *localStorageAdapter) SignedURL(key string, ttl time.Duration) (string, error) {
+	return a.signedURL(DownloadPath, "", key, ttl)
+}
+
+// Verify checks the expiry and signature of a URL returned by SignedURL
+func (a *localStorageAdapter) Verify(key string, expires int64, signature string) bool {
+	return a.verify("", key, expires, signature)
+}
+
+// SignedUploadURL returns a URL storing the body of a PUT request under key until ttl elapses
+func (a *localStorageAdapter) SignedUploadURL(key string, ttl time.Duration) (string, error) {
+	return a.signedURL(UploadPath, http.MethodPut, key, ttl)
+}
+
+// VerifyUpload checks the expiry and signature of a URL returned by SignedUploadURL
+func (a *localStorageAdapter) VerifyUpload(key string, expires int64, signature string) bool {
+	return a.verify(http.MethodPut, key, expires, signature)
+}
+
+// signedURL returns a URL of route for key signed for method until ttl elapses
+func (a *localStorageAdapter) signedURL(route, method, key string, ttl time.Duration) (string, error) {
+	if _, err := a.path(key); err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(ttl).Unix()
+	query := url.Values{}
+	query.Set("key", key)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", a.sign(method, key, expires))
+
+	return a.baseURL + route + "?" + query.Encode(), nil
+}
+
+// verify checks the expiry and signature of a URL signed for method
+func (a *localStorageAdapter) verify(method, key string, expires int64, signature string) bool {
+	if time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(a.sign(method, key, expires)))
+}
+
+// sign returns the HMAC of a key and its expiry. Upload signatures also cover their method,
+// so a download URL never grants writing the file.
+func (a *localStorageAdapter) sign(method, key string, expires int64) string {
+	mac := hmac.New(sha256.New, a.secret)
+	if method != "" {
+		fmt.Fprintf(mac, "%s\n", method)
+	}
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path returns the file path of a key, rejecting keys escaping the storage directory
+func (a *localStorageAdapter) path(key string) (string, error) {
+	if key == "" || !filepath.IsLocal(key) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(a.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,281 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// TeamsMessagesPath is the route the Azure Bot Service posts Teams activities to
+const TeamsMessagesPath = "/integrations/teams/messages"
+
+// Bot Framework authentication
+const (
+	// teamsTokenIssuer issues the tokens of the requests the Bot Framework sends to bots
+	teamsTokenIssuer = "https://api.botframework.com"
+	// teamsScope is the OAuth scope of the tokens bots send activities with
+	teamsScope = "https://api.botframework.com/.default"
+	// teamsDefaultTenant issues the tokens of multi-tenant bots
+	teamsDefaultTenant = "botframework.com"
+	// teamsKeysLifetime is how long the signing keys of the Bot Framework are cached
+	teamsKeysLifetime = 24 * time.Hour
+	// teamsKeysMinRefresh limits refreshing the keys on tokens signed with an unknown key
+	teamsKeysMinRefresh = 5 * time.Minute
+)
+
+// ErrTeamsUnauthorized is returned for activity requests without a valid Bot Framework token
+var ErrTeamsUnauthorized = errors.New("invalid Bot Framework token")
+
+// TeamsAdapter defines the interface for exchanging activities with Microsoft Teams through
+// the Bot Framework
+type TeamsAdapter interface {
+	// Authenticate verifies the Bot Framework token in the Authorization header of an activity
+	// request, issued for the bot and the service URL of the activity
+	Authenticate(ctx context.Context, authorization, serviceURL string) error
+
+	// SendActivity sends an activity to a conversation, in reply to replyToID when not empty
+	SendActivity(ctx context.Context, serviceURL, conversationID, replyToID string, activity *dtos.TeamsActivity) error
+}
+
+// teamsAdapter implements TeamsAdapter with the Bot Framework connector API
+type teamsAdapter struct {
+	client   *http.Client
+	config   configs.Teams
+	tokenURL string
+
+	keysMu        sync.Mutex
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+
+	tokenMu     sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewTeamsAdapter creates a Teams adapter authenticated as the configured bot
+func NewTeamsAdapter(config configs.Teams) (TeamsAdapter, error) {
+	if config.AppID == "" || config.AppPassword == "" {
+		return nil, fmt.Errorf("the Teams app ID and password are required")
+	}
+
+	tenant := config.TenantID
+	if tenant == "" {
+		tenant = teamsDefaultTenant
+	}
+
+	return &teamsAdapter{
+		client:   &http.Client{Timeout: config.Timeout},
+		config:   config,
+		tokenURL: fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", url.PathEscape(tenant)),
+	}, nil
+}
+
+// Authenticate verifies the Bot Framework token of an activity request
+func (a *teamsAdapter) Authenticate(ctx context.Context, authorization, serviceURL string) error {
+	tokenStr, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || tokenStr == "" {
+		return ErrTeamsUnauthorized
+	}
+
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return a.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(teamsTokenIssuer),
+		jwt.WithAudience(a.config.AppID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(5*time.Minute),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTeamsUnauthorized, err)
+	}
+
+	// The token is only valid for the connector it was issued for, which replies are sent to
+	claims, _ := token.Claims.(jwt.MapClaims)
+	if claimed, _ := claims["serviceurl"].(string); claimed == "" || !strings.EqualFold(strings.TrimSuffix(claimed, "/"), strings.TrimSuffix(serviceURL, "/")) {
+		return fmt.Errorf("%w: token issued for another service URL", ErrTeamsUnauthorized)
+	}
+	return nil
+}
+
+// key returns the Bot Framework signing key with an ID, fetching the keys when they are
+// stale or the ID is unknown
+func (a *teamsAdapter) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+
+	if key, ok := a.keys[kid]; ok && time.Since(a.keysFetchedAt) < teamsKeysLifetime {
+		return key, nil
+	}
+	if a.keys != nil && time.Since(a.keysFetchedAt) < teamsKeysMinRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := a.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.keys = keys
+	a.keysFetchedAt = time.Now()
+
+	key, ok := a.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// fetchKeys fetches the signing keys of the Bot Framework from its OpenID metadata
+func (a *teamsAdapter) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var metadata struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := a.getJSON(ctx, a.config.OpenIDMetadataURL, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to get Bot Framework OpenID metadata: %w", err)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := a.getJSON(ctx, metadata.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to get Bot Framework signing keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
+
+// getJSON gets and decodes a JSON document
+func (a *teamsAdapter) getJSON(ctx context.Context, documentURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, documentURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// SendActivity sends an activity to a conversation through the connector of the activity
+func (a *teamsAdapter) SendActivity(ctx context.Context, serviceURL, conversationID, replyToID string, activity *dtos.TeamsActivity) error {
+	accessToken, err := a.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimSuffix(serviceURL, "/") + "/v3/conversations/" + url.PathEscape(conversationID) + "/activities"
+	if replyToID != "" {
+		endpoint += "/" + url.PathEscape(replyToID)
+	}
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the Bot Framework: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("Bot Framework returned error: %d %s", resp.StatusCode, detail)
+}
+
+// token returns an access token of the bot, requesting a new one with its client
+// credentials when the cached token is about to expire
+func (a *teamsAdapter) token(ctx context.Context) (string, error) {
+	a.tokenMu.Lock()
+	defer a.tokenMu.Unlock()
+
+	if a.accessToken != "" && time.Until(a.expiresAt) > time.Minute {
+		return a.accessToken, nil
+	}
+
+	now := time.Now()
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", a.config.AppID)
+	form.Set("client_secret", a.config.AppPassword)
+	form.Set("scope", teamsScope)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get Bot Framework access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Bot Framework token endpoint returned error: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse Bot Framework access token: %w", err)
+	}
+
+	a.accessToken = result.AccessToken
+	a.expiresAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return a.accessToken, nil
+}
@@ -0,0 +1,119 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// TranslationAdapter defines the interface for detecting the language of text and translating it
+type TranslationAdapter interface {
+	// Detect returns the ISO 639-1 code of the language the text is most likely written in
+	Detect(ctx context.Context, text string) (string, error)
+	// Translate translates the text from the source language into the target language
+	Translate(ctx context.Context, text, source, target string) (string, error)
+}
+
+// libreTranslateAdapter detects and translates with the LibreTranslate API, served by
+// LibreTranslate or by services implementing the same API
+type libreTranslateAdapter struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+// NewTranslationAdapter creates a translation adapter for the configured service
+func NewTranslationAdapter(config configs.Translation) (TranslationAdapter, error) {
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("translation base URL is required")
+	}
+
+	return &libreTranslateAdapter{
+		client:  &http.Client{Timeout: config.Timeout},
+		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+		apiKey:  config.APIKey,
+	}, nil
+}
+
+// Detect returns the language the service detects with the highest confidence
+func (a *libreTranslateAdapter) Detect(ctx context.Context, text string) (string, error) {
+	var detections []struct {
+		Language   string  `json:"language"`
+		Confidence float64 `json:"confidence"`
+	}
+	if err := a.post(ctx, "/detect", map[string]string{"q": text}, &detections); err != nil {
+		return "", err
+	}
+
+	language := ""
+	best := -1.0
+	for _, detection := range detections {
+		if detection.Confidence > best {
+			language, best = detection.Language, detection.Confidence
+		}
+	}
+	if language == "" {
+		return "", fmt.Errorf("translation service detected no language")
+	}
+
+	logger.Context(ctx).Debugw("Language detected", "language", language, "confidence", best)
+	return language, nil
+}
+
+// Translate translates plain text from the source language into the target language
+func (a *libreTranslateAdapter) Translate(ctx context.Context, text, source, target string) (string, error) {
+	var result struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	body := map[string]string{
+		"q":      text,
+		"source": source,
+		"target": target,
+		"format": "text",
+	}
+	if err := a.post(ctx, "/translate", body, &result); err != nil {
+		return "", err
+	}
+
+	return result.TranslatedText, nil
+}
+
+// post sends a JSON request to an endpoint of the service and decodes its response into out
+func (a *libreTranslateAdapter) post(ctx context.Context, path string, body map[string]string, out interface{}) error {
+	if a.apiKey != "" {
+		body["api_key"] = a.apiKey
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to translation service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("translation service returned error: %d %s", resp.StatusCode, detail)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse translation service response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package adapters
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// UsageExportAdapter defines the interface for reading the usage and costs reported by the
+// LLM vendor
+type UsageExportAdapter interface {
+	// DailyUsage returns the usage of each model and UTC day from a day, inclusive, to
+	// another, exclusive, restricted to a project of the vendor unless project is empty
+	DailyUsage(ctx context.Context, from, to time.Time, project string) ([]dtos.VendorUsage, error)
+}
+
+// NewUsageExportAdapter creates a usage export adapter for the configured source
+func NewUsageExportAdapter(config configs.BillingReconciliation) (UsageExportAdapter, error) {
+	switch config.Source {
+	case configs.ReconciliationSourceOpenAI:
+		if config.AdminKey == "" {
+			return nil, fmt.Errorf("an admin key is required to read the usage of the OpenAI organization")
+		}
+		return &openAIUsageAdapter{
+			client:   &http.Client{Timeout: config.Timeout},
+			baseURL:  strings.TrimSuffix(config.BaseURL, "/"),
+			adminKey: config.AdminKey,
+		}, nil
+	case configs.ReconciliationSourceCSV:
+		if config.File == "" {
+			return nil, fmt.Errorf("a usage export file is required by the csv source")
+		}
+		return &csvUsageAdapter{file: config.File}, nil
+	default:
+		return nil, fmt.Errorf("unknown usage reconciliation source %q", config.Source)
+	}
+}
+
+// openAIUsageAdapter reads the completions usage and the costs of an OpenAI organization
+// with its usage and costs APIs
+type openAIUsageAdapter struct {
+	client   *http.Client
+	baseURL  string
+	adminKey string
+}
+
+// openAIPage is a page of buckets of the usage and costs APIs
+type openAIPage[T any] struct {
+	Data []struct {
+		StartTime int64 `json:"start_time"`
+		Results   []T   `json:"results"`
+	} `json:"data"`
+	HasMore  bool   `json:"has_more"`
+	NextPage string `json:"next_page"`
+}
+
+// openAICompletionsUsage is the usage of a model in a bucket of the completions usage API
+type openAICompletionsUsage struct {
+	Model            string `json:"model"`
+	InputTokens      int64  `json:"input_tokens"`
+	OutputTokens     int64  `json:"output_tokens"`
+	NumModelRequests int64  `json:"num_model_requests"`
+}
+
+// openAICost is the cost of a line item in a bucket of the costs API
+type openAICost struct {
+	Amount struct {
+		Value float64 `json:"value"`
+	} `json:"amount"`
+	// LineItem names the model and what was billed, as "gpt-4o-2024-08-06, input"
+	LineItem string `json:"line_item"`
+}
+
+// DailyUsage returns the completions usage of each model and day, with the costs of their
+// line items. Costs of line items that are not of a model with usage are left out.
+func (a *openAIUsageAdapter) DailyUsage(ctx context.Context, from, to time.Time, project string) ([]dtos.VendorUsage, error) {
+	query := url.Values{}
+	query.Set("start_time", strconv.FormatInt(from.Unix(), 10))
+	query.Set("end_time", strconv.FormatInt(to.Unix(), 10))
+	query.Set("bucket_width", "1d")
+	if project != "" {
+		query.Set("project_ids", project)
+	}
+
+	type key struct {
+		day   int64
+		model string
+	}
+	usage := map[key]*dtos.VendorUsage{}
+	var order []key
+
+	usageQuery := cloneValues(query)
+	usageQuery.Set("group_by", "model")
+	err := readOpenAIPages(ctx, a, "/organization/usage/completions", usageQuery, func(start int64, result openAICompletionsUsage) {
+		k := key{start, result.Model}
+		entry, ok := usage[k]
+		if !ok {
+			entry = &dtos.VendorUsage{Day: time.Unix(start, 0).UTC(), Model: result.Model}
+			usage[k] = entry
+			order = append(order, k)
+		}
+		entry.Requests += result.NumModelRequests
+		entry.PromptTokens += result.InputTokens
+		entry.CompletionTokens += result.OutputTokens
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	costQuery := cloneValues(query)
+	costQuery.Set("group_by", "line_item")
+	err = readOpenAIPages(ctx, a, "/organization/costs", costQuery, func(start int64, result openAICost) {
+		model, _, _ := strings.Cut(result.LineItem, ", ")
+		if entry, ok := usage[key{start, model}]; ok {
+			cost := result.Amount.Value
+			if entry.Cost != nil {
+				cost += *entry.Cost
+			}
+			entry.Cost = &cost
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]dtos.VendorUsage, 0, len(order))
+	for _, k := range order {
+		list = append(list, *usage[k])
+	}
+	return list, nil
+}
+
+// readOpenAIPages calls fn with the start time of the bucket of each result of every page of
+// an API of the organization
+func readOpenAIPages[T any](ctx context.Context, a *openAIUsageAdapter, path string, query url.Values, fn func(start int64, result T)) error {
+	for {
+		var page openAIPage[T]
+		if err := a.get(ctx, path, query, &page); err != nil {
+			return err
+		}
+		for _, bucket := range page.Data {
+			for _, result := range bucket.Results {
+				fn(bucket.StartTime, result)
+			}
+		}
+		if !page.HasMore || page.NextPage == "" {
+			return nil
+		}
+		query.Set("page", page.NextPage)
+	}
+}
+
+// get sends a GET request to an API of the organization and decodes its JSON response
+func (a *openAIUsageAdapter) get(ctx context.Context, path string, query url.Values, response interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create usage request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.adminKey)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("usage request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("usage API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode usage response: %w", err)
+	}
+	return nil
+}
+
+// cloneValues returns a copy of query values
+func cloneValues(values url.Values) url.Values {
+	clone := make(url.Values, len(values))
+	for name, list := range values {
+		clone[name] = append([]string(nil), list...)
+	}
+	return clone
+}
+
+// csvUsageAdapter reads a usage export dropped at a path, replaced as the vendor exports it
+type csvUsageAdapter struct {
+	file string
+}
+
+// DailyUsage returns the usage of the export within the period
+func (a *csvUsageAdapter) DailyUsage(ctx context.Context, from, to time.Time, project string) ([]dtos.VendorUsage, error) {
+	f, err := os.Open(a.file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the usage export: %w", err)
+	}
+	defer f.Close()
+
+	usage, err := ParseUsageCSV(f, project)
+	if err != nil {
+		return nil, err
+	}
+	list := usage[:0]
+	for _, entry := range usage {
+		if !entry.Day.Before(from) && entry.Day.Before(to) {
+			list = append(list, entry)
+		}
+	}
+	return list, nil
+}
+
+// usageCSVColumns are the accepted headers of each column of usage exports, lower-cased
+var usageCSVColumns = map[string][]string{
+	"day":               {"date", "day", "start_time"},
+	"model":             {"model", "snapshot_id"},
+	"requests":          {"requests", "num_model_requests", "n_requests"},
+	"prompt_tokens":     {"input_tokens", "prompt_tokens", "n_context_tokens_total"},
+	"completion_tokens": {"output_tokens", "completion_tokens", "n_generated_tokens_total"},
+	"cost":              {"cost", "amount", "cost_usd"},
+	"project":           {"project_id", "project"},
+}
+
+// ParseUsageCSV parses a usage export of the vendor, aggregated by UTC day and model. Its
+// header names the columns: a day as 2006-01-02, RFC 3339 or Unix seconds, a model and
+// token counts are required; requests, a cost in USD and a project are optional. Rows of
+// other projects than project are left out when both are set.
+func ParseUsageCSV(r io.Reader, project string) ([]dtos.VendorUsage, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the usage export header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		for column, names := range usageCSVColumns {
+			for _, accepted := range names {
+				if _, ok := columns[column]; !ok && name == accepted {
+					columns[column] = i
+				}
+			}
+		}
+	}
+	for _, column := range []string{"day", "model", "prompt_tokens", "completion_tokens"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("the usage export has no %s column", column)
+		}
+	}
+
+	type key struct {
+		day   time.Time
+		model string
+	}
+	usage := map[key]*dtos.VendorUsage{}
+	var order []key
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the usage export: %w", err)
+		}
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if project != "" && field("project") != "" && field("project") != project {
+			continue
+		}
+
+		day, err := parseUsageDay(field("day"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d of the usage export: %w", line, err)
+		}
+		model := field("model")
+		if model == "" {
+			return nil, fmt.Errorf("line %d of the usage export has no model", line)
+		}
+		var counts [3]int64
+		for i, column := range []string{"requests", "prompt_tokens", "completion_tokens"} {
+			if value := field(column); value != "" {
+				if counts[i], err = strconv.ParseInt(value, 10, 64); err != nil {
+					return nil, fmt.Errorf("line %d of the usage export has invalid %s %q", line, column, value)
+				}
+			}
+		}
+
+		k := key{day, model}
+		entry, ok := usage[k]
+		if !ok {
+			entry = &dtos.VendorUsage{Day: day, Model: model}
+			usage[k] = entry
+			order = append(order, k)
+		}
+		entry.Requests += counts[0]
+		entry.PromptTokens += counts[1]
+		entry.CompletionTokens += counts[2]
+		if value := field("cost"); value != "" {
+			cost, err := strconv.ParseFloat(strings.TrimPrefix(value, "$"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d of the usage export has invalid cost %q", line, value)
+			}
+			if entry.Cost != nil {
+				cost += *entry.Cost
+			}
+			entry.Cost = &cost
+		}
+	}
+
+	list := make([]dtos.VendorUsage, 0, len(order))
+	for _, k := range order {
+		list = append(list, *usage[k])
+	}
+	return list, nil
+}
+
+// parseUsageDay parses the day of a row of a usage export, truncated to its UTC day
+func parseUsageDay(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC().Truncate(24 * time.Hour), nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if day, err := time.Parse(layout, value); err == nil {
+			return day.UTC().Truncate(24 * time.Hour), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid day %q", value)
+}
@@ -0,0 +1,176 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+)
+
+// WarehouseSinkAdapter defines the interface for the sink of the objects exported to a data
+// warehouse, from which they are loaded
+type WarehouseSinkAdapter interface {
+	// Write stores data under key, replacing any object stored under it
+	Write(ctx context.Context, key string, data []byte, contentType string) error
+}
+
+// NewWarehouseSinkAdapter creates a sink adapter for the configured sink
+func NewWarehouseSinkAdapter(config configs.Warehouse) (WarehouseSinkAdapter, error) {
+	switch config.Sink {
+	case configs.WarehouseSinkFile:
+		if config.Dir == "" {
+			return nil, fmt.Errorf("a directory is required by the file warehouse sink")
+		}
+		return &fileWarehouseSink{dir: config.Dir}, nil
+	case configs.WarehouseSinkS3:
+		s3 := config.S3
+		if s3.Bucket == "" || s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+			return nil, fmt.Errorf("a bucket and credentials are required by the s3 warehouse sink")
+		}
+		endpoint := s3.Endpoint
+		if endpoint == "" {
+			endpoint = "https://s3." + s3.Region + ".amazonaws.com"
+		}
+		base, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+		if err != nil || base.Host == "" {
+			return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+		}
+		return &s3WarehouseSink{
+			client: &http.Client{Timeout: s3.Timeout},
+			base:   base,
+			config: s3,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown warehouse sink %q", config.Sink)
+	}
+}
+
+// fileWarehouseSink writes objects as files under a directory, such as a mounted bucket
+type fileWarehouseSink struct {
+	dir string
+}
+
+// Write writes the object to a temporary file renamed to its key, so that loaders never read
+// partial objects
+func (s *fileWarehouseSink) Write(ctx context.Context, key string, data []byte, contentType string) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create warehouse directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write warehouse object: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write warehouse object: %w", err)
+	}
+	return nil
+}
+
+// s3WarehouseSink puts objects in a bucket of an S3-compatible service, addressed in path
+// style and signed with AWS Signature Version 4
+type s3WarehouseSink struct {
+	client *http.Client
+	base   *url.URL
+	config configs.WarehouseS3
+}
+
+// Write puts the object in the bucket
+func (s *s3WarehouseSink) Write(ctx context.Context, key string, data []byte, contentType string) error {
+	path := s.base.EscapedPath() + "/" + awsURIEncode(s.config.Bucket) + "/" + awsURIEncode(key)
+	target, err := url.Parse(s.base.Scheme + "://" + s.base.Host + path)
+	if err != nil {
+		return fmt.Errorf("invalid warehouse object key %q: %w", key, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create warehouse request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, path, data, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("warehouse request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// sign adds the headers authenticating a request without query parameters to the escaped
+// path, with the signature of its host, payload and time
+func (s *s3WarehouseSink) sign(req *http.Request, path string, payload []byte, now time.Time) {
+	sum := sha256.Sum256(payload)
+	payloadHash := hex.EncodeToString(sum[:])
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		"",
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	canonicalSum := sha256.Sum256([]byte(canonical))
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalSum[:])
+
+	key := []byte("AWS4" + s.config.SecretAccessKey)
+	for _, part := range []string{date, s.config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.config.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncode percent-encodes a path as AWS signatures expect: every byte but unreserved
+// characters and slashes
+func awsURIEncode(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,305 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// maxWebRedirects is the most redirects followed when fetching a page
+const maxWebRedirects = 3
+
+// ErrURLNotAllowed is returned for URLs that may not be fetched
+var ErrURLNotAllowed = errors.New("url not allowed")
+
+// blockedPrefixes are the non-public ranges not covered by the net/netip classifications
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, which maps to IPv4 addresses
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+}
+
+// WebAdapter defines the interface for fetching web pages
+type WebAdapter interface {
+	// Fetch fetches a web page and extracts its readable text
+	Fetch(ctx context.Context, rawURL string) (*dtos.WebPage, error)
+}
+
+// webAdapter fetches public web pages over HTTP. Every connection, including those of
+// redirects, is checked against the allowlist and to not reach private addresses.
+type webAdapter struct {
+	client    *http.Client
+	allowlist []string
+	maxBytes  int64
+	maxChars  int
+}
+
+// NewWebAdapter creates a web adapter
+func NewWebAdapter(config configs.URLContext) WebAdapter {
+	a := &webAdapter{
+		allowlist: config.Allowlist,
+		maxBytes:  config.MaxBytes,
+		maxChars:  config.MaxChars,
+	}
+	a.client = newPublicHTTPClient(config.Timeout, a.checkURL)
+	return a
+}
+
+// newPublicHTTPClient creates an HTTP client that only connects to public addresses, and
+// only follows redirects to URLs passing checkURL
+func newPublicHTTPClient(timeout time.Duration, checkURL func(u *url.URL) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		// Control runs after name resolution, so hosts resolving to private addresses
+		// are refused even when the name itself looks public
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s is not a public address", ErrURLNotAllowed, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No proxy: it would make the connection checks apply to the proxy instead
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxWebRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxWebRedirects)
+			}
+			return checkURL(req.URL)
+		},
+	}
+}
+
+// Fetch fetches a web page and extracts its readable text
+func (a *webAdapter) Fetch(ctx context.Context, rawURL string) (*dtos.WebPage, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrURLNotAllowed, err)
+	}
+	if err := a.checkURL(target); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html, text/plain;q=0.9")
+	req.Header.Set("User-Agent", "chat-service-link-preview/1.0")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("web page returned status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	// Larger pages are cut off; the extracted text is capped far below maxBytes anyway
+	body := io.LimitReader(resp.Body, a.maxBytes)
+
+	page := &dtos.WebPage{URL: resp.Request.URL.String()}
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		page.Title, page.Text, err = extractReadableText(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse web page: %w", err)
+		}
+	case "text/plain":
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read web page: %w", err)
+		}
+		page.Text = strings.TrimSpace(strings.ToValidUTF8(string(content), ""))
+	default:
+		return nil, fmt.Errorf("unsupported web page content type %q", mediaType)
+	}
+
+	page.Text = truncateText(page.Text, a.maxChars)
+	return page, nil
+}
+
+// checkURL checks a URL is an HTTP(S) URL on a default port of an allowed host
+func (a *webAdapter) checkURL(u *url.URL) error {
+	return checkPublicURL(u, a.allowlist)
+}
+
+// checkPublicURL checks a URL is an HTTP(S) URL on a default port of a host of the
+// allowlist, any public host when it is empty
+func checkPublicURL(u *url.URL, allowlist []string) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", ErrURLNotAllowed, u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials in URL", ErrURLNotAllowed)
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		return fmt.Errorf("%w: port %s", ErrURLNotAllowed, port)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrURLNotAllowed)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublicAddr(addr) {
+		return fmt.Errorf("%w: %s is not a public address", ErrURLNotAllowed, host)
+	}
+
+	if len(allowlist) == 0 {
+		return nil
+	}
+	for _, allowed := range allowlist {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: host %s is not in the allowlist", ErrURLNotAllowed, host)
+}
+
+// isPublicAddr reports whether an address is publicly routable
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// skippedElements hold no readable text of a page
+var skippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Button:   true,
+}
+
+// blockElements start a new line of text
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Article: true, atom.Section: true, atom.Main: true, atom.Pre: true,
+	atom.Blockquote: true, atom.Table: true, atom.Ul: true, atom.Ol: true, atom.Dd: true, atom.Dt: true,
+}
+
+// extractReadableText returns the title and the visible text of an HTML page, one
+// line per block and without navigation, scripts and styles
+func extractReadableText(r io.Reader) (string, string, error) {
+	tokenizer := html.NewTokenizer(r)
+
+	var title, text strings.Builder
+	var inTitle bool
+	skipped := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return "", "", err
+			}
+			return collapseSpaces(title.String()), collapseLines(text.String()), nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			switch {
+			case tag == atom.Title:
+				inTitle = true
+			case skippedElements[tag]:
+				skipped++
+			case blockElements[tag]:
+				text.WriteByte('\n')
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			switch {
+			case tag == atom.Title:
+				inTitle = false
+			case skippedElements[tag] && skipped > 0:
+				skipped--
+			case blockElements[tag]:
+				text.WriteByte('\n')
+			}
+		case html.TextToken:
+			if inTitle {
+				title.Write(tokenizer.Text())
+			} else if skipped == 0 {
+				text.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+// collapseSpaces replaces runs of whitespace with a single space
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// collapseLines collapses the whitespace of every line and drops empty lines
+func collapseLines(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = collapseSpaces(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateText cuts text to at most maxChars characters
+func truncateText(text string, maxChars int) string {
+	if maxChars <= 0 || utf8.RuneCountInString(text) <= maxChars {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:maxChars]) + "…"
+}
@@ -0,0 +1,110 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+)
+
+// Headers of webhook deliveries
+const (
+	// WebhookSignatureHeader carries the HMAC-SHA256 of "<timestamp>.<body>" keyed with the
+	// secret of the webhook, as sha256=<hex>
+	WebhookSignatureHeader = "X-Chat-Signature"
+	// WebhookTimestampHeader carries the Unix time of the delivery, for receivers to reject replays
+	WebhookTimestampHeader = "X-Chat-Timestamp"
+)
+
+// WebhookAdapter defines the interface for posting to the webhooks of users
+type WebhookAdapter interface {
+	// Post posts a JSON body to a webhook, signed when secret is not empty
+	Post(ctx context.Context, webhookURL, secret string, body []byte) error
+
+	// CheckURL checks a webhook URL can be posted to
+	CheckURL(webhookURL string) error
+}
+
+// webhookAdapter posts to public webhooks over HTTP, with the same connection checks as the
+// web adapter, unless the webhooks are trusted
+type webhookAdapter struct {
+	client    *http.Client
+	allowlist []string
+	trusted   bool
+}
+
+// NewWebhookAdapter creates a webhook adapter
+func NewWebhookAdapter(config configs.NotificationsWebhook) WebhookAdapter {
+	a := &webhookAdapter{allowlist: config.Allowlist}
+	a.client = newPublicHTTPClient(config.Timeout, a.checkURL)
+	return a
+}
+
+// NewTrustedWebhookAdapter creates a webhook adapter for the webhooks operators configure,
+// which may be on private hosts and ports
+func NewTrustedWebhookAdapter(timeout time.Duration) WebhookAdapter {
+	return &webhookAdapter{client: &http.Client{Timeout: timeout}, trusted: true}
+}
+
+// Post posts a JSON body to a webhook, signed when secret is not empty
+func (a *webhookAdapter) Post(ctx context.Context, webhookURL, secret string, body []byte) error {
+	if err := a.CheckURL(webhookURL); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// CheckURL checks a webhook URL is an HTTP(S) URL on a default port of an allowed host
+func (a *webhookAdapter) CheckURL(webhookURL string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrURLNotAllowed, err)
+	}
+	return a.checkURL(u)
+}
+
+// checkURL checks a parsed webhook URL, also used for redirects. Trusted webhooks only need
+// to be HTTP(S).
+func (a *webhookAdapter) checkURL(u *url.URL) error {
+	if a.trusted {
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("%w: scheme %q", ErrURLNotAllowed, u.Scheme)
+		}
+		return nil
+	}
+	return checkPublicURL(u, a.allowlist)
+}
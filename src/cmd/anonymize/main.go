@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/migrations"
+	"github.com/nvnamsss/chat/src/repositories"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// Copies the chats of every region into a separate schema, with user IDs hashed and
+// personal information scrubbed, for staging and analytics environments
+func main() {
+	// Parse command-line flags
+	configPath := flag.String("config", "", "comma-separated config files, each overlaying the previous ones")
+	profile := flag.String("profile", "", "config profile overlaying <profile>.yaml next to the first config file; defaults to CONFIG_PROFILE")
+	schema := flag.String("schema", "", "schema receiving the anonymized copy; defaults to anonymization.schema")
+	flag.Parse()
+
+	// Load configuration
+	if err := configs.Load(*configPath, *profile); err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	cfg := configs.AppConfig
+	if *schema != "" {
+		cfg.Anonymization.Schema = *schema
+	}
+
+	// Initialize logger
+	logger.Init(cfg.App.LogLevel, cfg.App.Environment)
+	defer logger.Sync()
+
+	// Stop on interrupt, leaving the copy partial until the next run
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	dbAdapter, err := adapters.NewDBAdapter(cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", logger.Field("error", err))
+	}
+	defer dbAdapter.Close()
+
+	// The copy takes the shape of the source tables, which must match the models
+	if err := migrations.CheckVersion(ctx, dbAdapter); err != nil {
+		logger.Fatal("Database schema is newer than the binary", logger.Field("error", err))
+	}
+
+	// Large message content is read from the storage, as the server does
+	if cfg.Storage.Secret == "" {
+		cfg.Storage.Secret = cfg.JWT.Secret
+	}
+	storageAdapter, err := adapters.NewLocalStorageAdapter(cfg.Storage)
+	if err != nil {
+		logger.Fatal("Failed to initialize storage", logger.Field("error", err))
+	}
+	messageContent := repositories.NewMessageContentStore(storageAdapter, dbAdapter, cfg.MessageContent)
+
+	anonymizationService := services.NewAnonymizationService(cfg.Anonymization,
+		repositories.NewAnonymizationRepository(dbAdapter, messageContent))
+
+	for _, region := range dbAdapter.Regions() {
+		start := time.Now()
+		report, err := anonymizationService.Anonymize(adapters.WithRegion(ctx, region))
+		if err != nil {
+			logger.Fatal("Failed to anonymize chats", logger.Field("region", region), logger.Field("error", err))
+		}
+		logger.Info("Chats anonymized",
+			logger.Field("region", region),
+			logger.Field("schema", report.Schema),
+			logger.Field("chats", report.Chats),
+			logger.Field("messages", report.Messages),
+			logger.Field("duration", time.Since(start)))
+	}
+}
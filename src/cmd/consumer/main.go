@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/handlers"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/migrations"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+	"github.com/nvnamsss/chat/src/repositories"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+func main() {
+	// Parse command-line flags
+	configPath := flag.String("config", "", "comma-separated config files, each overlaying the previous ones")
+	profile := flag.String("profile", "", "config profile overlaying <profile>.yaml next to the first config file; defaults to CONFIG_PROFILE")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted, then exit")
+	waitForDeps := flag.Bool("wait-for-deps", false, "wait until the database and Kafka are reachable, then exit")
+	flag.Parse()
+
+	// Load configuration
+	if err := configs.Load(*configPath, *profile); err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	cfg := configs.AppConfig
+	if *printConfig {
+		if err := configs.Print(os.Stdout, cfg); err != nil {
+			fmt.Printf("Failed to print config: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Initialize logger
+	logger.Init(cfg.App.LogLevel, cfg.App.Environment)
+	defer logger.Sync()
+
+	// Stop consuming on interrupt; the message in flight is finished and committed.
+	// Until then, interrupts stop waiting for the dependencies.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Connect to database, retrying until it is reachable
+	var dbAdapter adapters.DBAdapter
+	err := adapters.WaitFor(ctx, "database", cfg.Startup, func(ctx context.Context) error {
+		var err error
+		dbAdapter, err = adapters.NewDBAdapter(cfg.Database)
+		return err
+	})
+	if err != nil {
+		logger.Fatal("Failed to connect to database", logger.Field("error", err))
+	}
+	defer dbAdapter.Close()
+
+	err = adapters.WaitFor(ctx, "Kafka", cfg.Startup, func(ctx context.Context) error {
+		return queue.PingKafka(ctx, cfg.Kafka.Brokers)
+	})
+	if err != nil {
+		logger.Fatal("Failed to connect to Kafka", logger.Field("error", err))
+	}
+	if *waitForDeps {
+		logger.Info("Dependencies are ready")
+		return
+	}
+
+	// Refuse to run against a schema migrated past this binary, as during a botched rollback
+	if err := migrations.CheckVersion(ctx, dbAdapter); err != nil {
+		logger.Fatal("Database schema is newer than the binary", logger.Field("error", err))
+	}
+
+	if err := dbAdapter.AutoMigrate(&models.DeadLetter{}, &models.ChatStats{}, &models.Device{}, &models.ChatMute{}); err != nil {
+		logger.Fatal("Failed to run auto-migrations", logger.Field("error", err))
+	}
+
+	// Initialize Kafka producer used for dead-letter routing
+	producer := queue.NewKafkaProducer(cfg.Kafka.Brokers)
+	defer producer.Close()
+
+	// Initialize repositories and services
+	deadLetterRepo := repositories.NewDeadLetterRepository(dbAdapter)
+	deadLetterService := services.NewDeadLetterService(deadLetterRepo, producer)
+	chatStatsRepo := repositories.NewChatStatsRepository(dbAdapter)
+
+	// Initialize consumers
+	newConsumer := func(topic, groupID string) queue.Consumer {
+		return queue.NewKafkaConsumer(queue.ConsumerConfig{
+			Brokers:          cfg.Kafka.Brokers,
+			GroupID:          groupID,
+			Topic:            topic,
+			Balancer:         cfg.Kafka.Consumer.Balancer,
+			SessionTimeout:   cfg.Kafka.Consumer.SessionTimeout,
+			RebalanceTimeout: cfg.Kafka.Consumer.RebalanceTimeout,
+		})
+	}
+	runner := handlers.NewRunner(cfg.Kafka, newConsumer, producer, deadLetterService)
+	runner.Register(handlers.NewChatStatsHandler(cfg.Kafka, chatStatsRepo))
+	if cfg.Push.Enabled {
+		pushAdapters, err := adapters.NewPushAdapters(cfg.Push)
+		if err != nil {
+			logger.Fatal("Failed to initialize push adapters", logger.Field("error", err))
+		}
+		pushService := services.NewPushService(repositories.NewDeviceRepository(dbAdapter), repositories.NewChatRepository(dbAdapter, nil), pushAdapters)
+		runner.Register(handlers.NewPushHandler(cfg.Kafka, pushService))
+	}
+	if cfg.Billing.Enabled && cfg.Billing.WebhookURL != "" {
+		billingWebhook := adapters.NewTrustedWebhookAdapter(cfg.Billing.WebhookTimeout)
+		runner.Register(handlers.NewBillingHandler(cfg.Kafka, cfg.Billing, billingWebhook))
+	}
+	if cfg.Search.Enabled {
+		chatSearch, messageSearch := setupSearch(ctx, cfg, dbAdapter)
+		runner.Register(chatSearch)
+		runner.Register(messageSearch)
+	}
+
+	logger.Info("Starting consumer", logger.Field("brokers", cfg.Kafka.Brokers), logger.Field("group", cfg.Kafka.ConsumerGroup))
+	runner.Run(ctx)
+	logger.Info("Consumer exited")
+}
+
+// setupSearch creates the handlers indexing chats and messages in the search service, creating
+// its indices when they are missing
+func setupSearch(ctx context.Context, cfg configs.Config, dbAdapter adapters.DBAdapter) (handlers.Projection, handlers.Projection) {
+	searchAdapter, err := adapters.NewSearchAdapter(cfg.Search)
+	if err != nil {
+		logger.Fatal("Failed to initialize search adapter", logger.Field("error", err))
+	}
+	if err := searchAdapter.EnsureIndices(ctx); err != nil {
+		logger.Fatal("Failed to create search indices", logger.Field("error", err))
+	}
+
+	// Content kept in the storage is read back, so large messages are indexed whole
+	if cfg.Storage.Secret == "" {
+		cfg.Storage.Secret = cfg.JWT.Secret
+	}
+	storageAdapter, err := adapters.NewLocalStorageAdapter(cfg.Storage)
+	if err != nil {
+		logger.Fatal("Failed to initialize storage", logger.Field("error", err))
+	}
+	messageContent := repositories.NewMessageContentStore(storageAdapter, dbAdapter, cfg.MessageContent)
+
+	return handlers.NewChatSearchHandler(cfg.Kafka, searchAdapter),
+		handlers.NewMessageSearchHandler(cfg.Kafka, searchAdapter,
+			repositories.NewChatRepository(dbAdapter, messageContent),
+			repositories.NewMessageRepository(dbAdapter, messageContent))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"syscall"
+	"time"
+
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// handleDumpSignal writes goroutine and heap dumps to dir every time the process
+// receives SIGUSR1, for inspecting a live instance without restarting it
+func handleDumpSignal(dir string) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			prefix := filepath.Join(dir, fmt.Sprintf("chat-%d-%s", os.Getpid(), time.Now().UTC().Format("20060102T150405")))
+			goroutines, err := writeProfile(prefix+"-goroutine.txt", "goroutine", 2)
+			if err != nil {
+				logger.Warn("Failed to write goroutine dump", logger.Field("error", err))
+				continue
+			}
+			// Collect first, so the heap profile is up to date
+			runtime.GC()
+			heap, err := writeProfile(prefix+"-heap.pprof", "heap", 0)
+			if err != nil {
+				logger.Warn("Failed to write heap dump", logger.Field("error", err))
+				continue
+			}
+			logger.Info("Wrote goroutine and heap dumps", logger.Field("goroutine", goroutines), logger.Field("heap", heap))
+		}
+	}()
+}
+
+// writeProfile writes the named runtime profile to path; debug 2 writes goroutines
+// as stack traces, 0 writes the pprof format
+func writeProfile(path, name string, debug int) (string, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := pprof.Lookup(name).WriteTo(file, debug); err != nil {
+		return "", err
+	}
+	return path, file.Close()
+}
@@ -9,7 +9,6 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -17,84 +16,635 @@ import (
 	"github.com/nvnamsss/chat/src/configs"
 	"github.com/nvnamsss/chat/src/controllers"
 	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/jobs"
 	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
 	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/migrations"
 	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+	"github.com/nvnamsss/chat/src/pkg/schema"
+	"github.com/nvnamsss/chat/src/pkg/tokenizer"
+	"github.com/nvnamsss/chat/src/plugins"
 	"github.com/nvnamsss/chat/src/repositories"
 	"github.com/nvnamsss/chat/src/services"
 )
 
+const usage = `Usage: chat-service [flags] [command]
+
+Without a command, the service is started.
+
+Commands:
+  selftest  check the configuration, the database, the message broker and the LLM provider,
+            and run an exchange against the simulated provider, printing a JSON report;
+            exits with 1 when a check failed
+
+Flags:
+`
+
 func main() {
 	// Parse command-line flags
-	configPath := flag.String("config", "", "path to config file")
+	configPath := flag.String("config", "", "comma-separated config files, each overlaying the previous ones")
+	profile := flag.String("profile", "", "config profile overlaying <profile>.yaml next to the first config file; defaults to CONFIG_PROFILE")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted, then exit")
+	waitForDeps := flag.Bool("wait-for-deps", false, "wait until the database and message broker are reachable, then exit")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
+	switch {
+	case flag.NArg() == 0:
+	case flag.Arg(0) == "selftest" && flag.NArg() == 1:
+		os.Exit(runSelfTest(*configPath, *profile))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	// Load configuration
-	if err := configs.Load(*configPath); err != nil {
+	if err := configs.Load(*configPath, *profile); err != nil {
 		fmt.Printf("Failed to load config: %v\n", err)
 		os.Exit(1)
 	}
 	cfg := configs.AppConfig
-	log.Printf("Loaded config: %+v", cfg)
+	if *printConfig {
+		if err := configs.Print(os.Stdout, cfg); err != nil {
+			fmt.Printf("Failed to print config: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	log.Printf("Loaded config: %+v", configs.Redacted(cfg))
 
 	// Initialize logger
 	logger.Init(cfg.App.LogLevel, cfg.App.Environment)
+	logger.ConfigureSampling(cfg.App.LogSampling.Tick, cfg.App.LogSampling.Initial, cfg.App.LogSampling.Thereafter)
 	defer logger.Sync()
 
 	// Set Gin mode
 	if cfg.App.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
+		if cfg.AccessLog.LogBodies {
+			logger.Warn("Access log body capture is enabled in production")
+		}
+	}
+
+	// Sends cannot be gated on terms users have no way to accept
+	if cfg.Consent.Required && cfg.Consent.TermsVersion == "" {
+		logger.Fatal("Consent is required but no terms version is configured")
 	}
 
+	// Dependencies may start after the service; connections are retried until they are
+	// reachable, unless interrupted
+	waitCtx, stopWaiting := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopWaiting()
+
 	// Connect to database
-	dbAdapter, err := adapters.NewDBAdapter(cfg.Database)
+	var dbAdapter adapters.DBAdapter
+	err := adapters.WaitFor(waitCtx, "database", cfg.Startup, func(ctx context.Context) error {
+		var err error
+		dbAdapter, err = adapters.NewDBAdapter(cfg.Database)
+		return err
+	})
 	if err != nil {
 		logger.Fatal("Failed to connect to database", logger.Field("error", err))
 	}
 	defer dbAdapter.Close()
 
+	if *waitForDeps {
+		setupQueue(waitCtx, cfg).Close()
+		logger.Info("Dependencies are ready")
+		return
+	}
+
+	// Refuse to run against a schema migrated past this binary, as during a botched rollback
+	if err := migrations.CheckVersion(waitCtx, dbAdapter); err != nil {
+		logger.Fatal("Database schema is newer than the binary", logger.Field("error", err))
+	}
+
 	// Run GORM auto-migrations
-	if err := dbAdapter.AutoMigrate(&models.Chat{}, &models.Message{}); err != nil {
+	if err := dbAdapter.AutoMigrate(&models.Chat{}, &models.Message{}, &models.MessageRevision{}, &models.MessageContent{}, &models.DeadLetter{}, &models.RetentionPolicy{}, &models.ChatStats{}, &models.Export{}, &models.NotificationPreference{}, &models.NotificationDigestItem{}, &models.Device{}, &models.ChatMute{}, &models.Attachment{}, &models.Artifact{}, &models.Persona{}, &models.Experiment{}, &models.ExperimentVariant{}, &models.Budget{}, &models.Usage{}, &models.UsageReconciliation{}, &models.Batch{}, &models.BatchItem{}, &models.Consent{}, &models.ChatTransfer{}, &models.Collection{}, &models.Document{}, &models.DocumentChunk{}, &models.ChatCollection{}, &models.Crawl{}, &models.AuditLog{}, &models.Evaluation{}, &models.TeamsConversation{}, &models.DiscordChannel{}, &models.Widget{}, &models.WorkspaceSettings{}, &models.UserPreference{}, &models.SavedSearch{}, &models.MessageReaction{}, &models.SupportCapture{}, &models.Checkpoint{}, &models.ChatWebhook{}, &models.ChatWebhookDelivery{}, &models.Task{}, &models.WarehouseCursor{}, &models.TrainingExport{}, &models.WorkspaceStat{}, &models.WorkspaceStatUser{}, &models.CustomCommand{}); err != nil {
 		logger.Fatal("Failed to run auto-migrations", logger.Field("error", err))
 	}
 
-	// Initialize Kafka producer
-	kafkaProducer := setupKafka(cfg)
+	// Initialize distributed lock adapter
+	lockAdapter := adapters.NewPostgresLockAdapter(dbAdapter)
 
-	// Initialize LLM adapter
-	// llmAdapter := adapters.NewLLMAdapter(cfg.LLM)
-	llmAdapter := adapters.NewNothingLLMAdapter()
+	// Initialize storage adapter; download and upload URLs are signed with the JWT secret unless configured
+	if cfg.Storage.Secret == "" {
+		cfg.Storage.Secret = cfg.JWT.Secret
+	}
+	storageAdapter, err := adapters.NewLocalStorageAdapter(cfg.Storage)
+	if err != nil {
+		logger.Fatal("Failed to initialize storage", logger.Field("error", err))
+	}
+
+	// Initialize speech-to-text adapter
+	speechToTextAdapter, err := adapters.NewSpeechToTextAdapter(cfg.Transcription)
+	if err != nil {
+		logger.Fatal("Failed to initialize speech-to-text adapter", logger.Field("error", err))
+	}
+
+	// Initialize malware scanning adapter; uploads are not scanned unless enabled
+	var scannerAdapter adapters.ScannerAdapter
+	if cfg.Attachments.Scanning.Enabled {
+		scannerAdapter, err = adapters.NewScannerAdapter(cfg.Attachments.Scanning)
+		if err != nil {
+			logger.Fatal("Failed to initialize scanning adapter", logger.Field("error", err))
+		}
+	}
+
+	// Initialize image generation adapter
+	var imageAdapter adapters.ImageAdapter
+	if cfg.Images.Enabled {
+		imageAdapter, err = adapters.NewImageAdapter(cfg.Images)
+		if err != nil {
+			logger.Fatal("Failed to initialize image generation adapter", logger.Field("error", err))
+		}
+	}
+
+	// Initialize search adapter; searches use the database unless enabled
+	var searchAdapter adapters.SearchAdapter
+	if cfg.Search.Enabled {
+		searchAdapter, err = adapters.NewSearchAdapter(cfg.Search)
+		if err != nil {
+			logger.Fatal("Failed to initialize search adapter", logger.Field("error", err))
+		}
+	}
+
+	// Initialize email adapter
+	emailAdapter := setupEmail(cfg)
+
+	// Initialize message broker producer
+	producer := setupQueue(waitCtx, cfg)
+	if cfg.Broker.Spool.Enabled {
+		var err error
+		if producer, err = adapters.NewSpoolProducer(producer, cfg.Broker.Spool); err != nil {
+			logger.Fatal("Failed to initialize event spool", logger.Field("error", err))
+		}
+	}
+	defer producer.Close()
+	stopWaiting()
+	// Services publish to the event bus, which passes events on to the broker and to
+	// in-process subscribers
+	eventBus := services.NewEventBus()
+	eventBus.Subscribe("broker", setupEvents(cfg, producer))
+	registerEventSchemas(cfg)
+
+	// Initialize LLM adapter; the calls of captured requests are saved by the support service
+	supportService := services.NewSupportService(cfg.Capture, repositories.NewSupportCaptureRepository(dbAdapter))
+	llmHealth := adapters.NewLLMHealth(cfg.LLM.Provider, cfg.LLM.Model, cfg.LLM.Middleware.Health)
+	llmProvider := setupLLM(cfg)
+	if warmer, ok := llmProvider.(adapters.LLMWarmer); ok && cfg.LLM.Connections.Prewarm {
+		go warmer.KeepWarm(context.Background())
+	}
+	llmAdapter := setupLLMMiddleware(cfg, llmProvider, llmHealth, supportService)
+
+	// Load lifecycle plugins
+	hooks, err := plugins.Load(cfg.Plugins.Enabled)
+	if err != nil {
+		logger.Fatal("Failed to load plugins", logger.Field("error", err), logger.Field("available", plugins.Available()))
+	}
+	logger.Info("Plugins loaded", logger.Field("plugins", hooks.Names()))
 
 	// Initialize repositories
-	chatRepo := repositories.NewChatRepository(dbAdapter)
-	messageRepo := repositories.NewMessageRepository(dbAdapter)
+	messageContent := repositories.NewMessageContentStore(storageAdapter, dbAdapter, cfg.MessageContent)
+	chatRepo := repositories.NewChatRepository(dbAdapter, messageContent)
+	messageRepo := repositories.NewMessageRepository(dbAdapter, messageContent)
+	if cfg.Database.RepositoryMetrics {
+		chatRepo = repositories.NewChatRepositoryMetrics(chatRepo)
+		messageRepo = repositories.NewMessageRepositoryMetrics(messageRepo)
+	}
+	// Messages of privacy mode chats are kept in memory or Redis instead of the database
+	var ephemeralMessages *repositories.EphemeralMessageStore
+	if cfg.Privacy.Enabled {
+		var ephemeralCache adapters.CacheAdapter
+		switch cfg.Privacy.Store {
+		case configs.PrivacyStoreMemory:
+			ephemeralCache = adapters.NewMemoryCacheAdapter()
+		case configs.PrivacyStoreRedis:
+			ephemeralCache = adapters.NewRedisCacheAdapter(cfg.ChatOwners.Redis)
+		default:
+			logger.Fatal("Unknown privacy mode store", logger.Field("store", cfg.Privacy.Store))
+		}
+		ephemeralMessages = repositories.NewEphemeralMessageStore(ephemeralCache, cfg.Privacy)
+		messageRepo = repositories.NewEphemeralMessageRepository(messageRepo, chatRepo, ephemeralMessages)
+	}
+	deadLetterRepo := repositories.NewDeadLetterRepository(dbAdapter)
+	retentionRepo := repositories.NewRetentionRepository(dbAdapter)
+	chatStatsRepo := repositories.NewChatStatsRepository(dbAdapter)
+	exportRepo := repositories.NewExportRepository(dbAdapter)
+	notificationRepo := repositories.NewNotificationRepository(dbAdapter)
+	deviceRepo := repositories.NewDeviceRepository(dbAdapter)
+	attachmentRepo := repositories.NewAttachmentRepository(dbAdapter)
+	artifactRepo := repositories.NewArtifactRepository(dbAdapter)
+	personaRepo := repositories.NewPersonaRepository(dbAdapter)
+	checkpointRepo := repositories.NewCheckpointRepository(dbAdapter)
+	chatWebhookRepo := repositories.NewChatWebhookRepository(dbAdapter)
+	taskRepo := repositories.NewTaskRepository(dbAdapter)
+	experimentRepo := repositories.NewExperimentRepository(dbAdapter)
+	widgetRepo := repositories.NewWidgetRepository(dbAdapter)
+	workspaceRepo := repositories.NewWorkspaceRepository(dbAdapter)
+	userPreferenceRepo := repositories.NewUserPreferenceRepository(dbAdapter)
+	budgetRepo := repositories.NewBudgetRepository(dbAdapter)
+	workspaceStatRepo := repositories.NewWorkspaceStatRepository(dbAdapter)
+	usageReconciliationRepo := repositories.NewUsageReconciliationRepository(dbAdapter)
+	warehouseRepo := repositories.NewWarehouseRepository(dbAdapter, messageContent)
+	trainingExportRepo := repositories.NewTrainingExportRepository(dbAdapter)
+	batchRepo := repositories.NewBatchRepository(dbAdapter)
+	consentRepo := repositories.NewConsentRepository(dbAdapter)
+	collectionRepo := repositories.NewCollectionRepository(dbAdapter)
+	savedSearchRepo := repositories.NewSavedSearchRepository(dbAdapter)
+	customCommandRepo := repositories.NewCustomCommandRepository(dbAdapter)
+	documentRepo := repositories.NewDocumentRepository(dbAdapter)
+	crawlRepo := repositories.NewCrawlRepository(dbAdapter)
+	auditLogRepo := repositories.NewAuditLogRepository(dbAdapter)
+	evaluationRepo := repositories.NewEvaluationRepository(dbAdapter)
+	teamsRepo := repositories.NewTeamsRepository(dbAdapter)
+	discordRepo := repositories.NewDiscordRepository(dbAdapter)
 
 	// Initialize services
-	chatService := services.NewChatService(chatRepo, kafkaProducer)
-	messageService := services.NewMessageService(messageRepo, chatRepo, llmAdapter, kafkaProducer)
+	// Without a BPE file for an encoding, tokens are estimated from the length of text
+	tokens := tokenizer.New(cfg.LLM.Tokenizer.EncodingsDir)
+	if err := tokens.Err(cfg.LLM.Model); err != nil {
+		logger.Warn("Estimating tokens of the LLM model", logger.Field("model", cfg.LLM.Model), logger.Field("error", err))
+	}
+	promptBuilder := services.NewPromptBuilder(cfg.LLM.Prompt, cfg.LLM.Model, messageRepo, workspaceRepo, userPreferenceRepo, tokens)
+	summaryService := services.NewSummaryService(cfg.LLM.Prompt, chatRepo, messageRepo, llmAdapter)
+	// Chat owners are cached in memory, then in Redis when enabled, and invalidated by chat
+	// events, broadcast to the instances through Redis
+	var chatOwners *services.ChatOwners
+	if cfg.ChatOwners.Enabled {
+		var sharedOwners adapters.CacheAdapter
+		var ownerInvalidations adapters.PubSubAdapter
+		if cfg.ChatOwners.Redis.Enabled {
+			sharedOwners = adapters.NewRedisCacheAdapter(cfg.ChatOwners.Redis)
+			ownerInvalidations = adapters.NewRedisPubSubAdapter(cfg.ChatOwners.Redis)
+		}
+		chatOwners = services.NewChatOwners(cfg.ChatOwners, sharedOwners, ownerInvalidations)
+		eventBus.Subscribe("chat-owners", chatOwners.Events())
+		go chatOwners.Watch(context.Background())
+	}
+	// The first pages of chat lists are served from a view of the recent chats of each user,
+	// kept with the chat owners and invalidated by chat events
+	var recentChats *services.RecentChats
+	if cfg.RecentChats.Enabled {
+		var recentCache adapters.CacheAdapter
+		if cfg.ChatOwners.Redis.Enabled {
+			recentCache = adapters.NewRedisCacheAdapter(cfg.ChatOwners.Redis)
+		} else {
+			recentCache = adapters.NewMemoryCacheAdapter()
+		}
+		recentChats = services.NewRecentChats(cfg.RecentChats, recentCache)
+		eventBus.Subscribe("recent-chats", recentChats.Events())
+	}
+	chatService := services.NewChatService(cfg.ChatDeletion, chatRepo, chatStatsRepo, eventBus, hooks, searchAdapter, chatOwners, recentChats, ephemeralMessages)
+	// Chats can only turn on fetching linked pages when it is enabled for the service
+	var urlContext services.URLContextEnricher
+	if cfg.URLContext.Enabled {
+		urlContext = services.NewURLContextEnricher(cfg.URLContext, adapters.NewWebAdapter(cfg.URLContext))
+	}
+	var translator services.Translator
+	if cfg.Translation.Enabled {
+		translationAdapter, err := adapters.NewTranslationAdapter(cfg.Translation)
+		if err != nil {
+			logger.Fatal("Failed to initialize translation adapter", logger.Field("error", err))
+		}
+		translator = services.NewTranslator(cfg.Translation, translationAdapter)
+	}
+	// Likewise, chats can only turn on follow-up suggestions when they are enabled
+	var suggester services.Suggester
+	if cfg.Suggestions.Enabled {
+		suggester = services.NewSuggester(cfg.Suggestions, llmAdapter)
+	}
+	postProcessors, err := services.NewPostProcessorChain(cfg.PostProcessing)
+	if err != nil {
+		logger.Fatal("Failed to initialize post-processors", logger.Field("error", err))
+	}
+	var guardrails services.Guardrails
+	if cfg.Guardrails.Enabled {
+		guardrails, err = services.NewGuardrails(cfg.Guardrails, llmAdapter, auditLogRepo)
+		if err != nil {
+			logger.Fatal("Failed to initialize guardrails", logger.Field("error", err))
+		}
+	}
+	analyticsPolicy, err := services.NewAnalyticsPolicy(cfg.Analytics)
+	if err != nil {
+		logger.Fatal("Failed to initialize analytics", logger.Field("error", err))
+	}
+	messageContentService := services.NewMessageContentService(messageContent)
+	workspaceStatsService := services.NewWorkspaceStatsService(cfg.WorkspaceStats, analyticsPolicy, workspaceStatRepo)
+	go workspaceStatsService.Run(context.Background())
+	budgetService := services.NewBudgetService(cfg.Budgets, cfg.Billing, budgetRepo, eventBus, workspaceStatsService)
+	var usageExportAdapter adapters.UsageExportAdapter
+	if cfg.Billing.Reconciliation.Enabled {
+		usageExportAdapter, err = adapters.NewUsageExportAdapter(cfg.Billing.Reconciliation)
+		if err != nil {
+			logger.Fatal("Failed to initialize usage export adapter", logger.Field("error", err))
+		}
+	}
+	billingService := services.NewBillingService(cfg.Billing, budgetRepo, usageReconciliationRepo, usageExportAdapter)
+	consentService := services.NewConsentService(cfg.Consent, consentRepo)
+	var streamBuffer *services.StreamBuffer
+	if cfg.Streams.Resumable {
+		streamBuffer = services.NewStreamBuffer(cfg.Streams)
+	}
+	messageService := services.NewMessageService(messageRepo, chatRepo, artifactRepo, personaRepo, experimentRepo, widgetRepo, workspaceRepo, llmAdapter, lockAdapter, promptBuilder, tokens, postProcessors, urlContext, translator, suggester, guardrails, budgetService, consentService, eventBus, hooks, streamBuffer, cfg.Guest, cfg.Dedup, cfg.ChatLock)
+	deadLetterService := services.NewDeadLetterService(deadLetterRepo, producer)
+	retentionService := services.NewRetentionService(cfg.Retention, retentionRepo, messageRepo, workspaceRepo, eventBus)
+	guestService := services.NewGuestService(cfg.Guest, cfg.JWT.Secret, chatRepo, chatService, eventBus)
+	widgetService := services.NewWidgetService(cfg.Widget, cfg.JWT.Secret, widgetRepo)
+	workspaceService := services.NewWorkspaceService(cfg.LLM, cfg.Retention, cfg.Guardrails, workspaceRepo)
+	instructionService := services.NewInstructionService(userPreferenceRepo, chatRepo, promptBuilder)
+	auditService := services.NewAuditService(cfg.Impersonation, cfg.JWT.Secret, auditLogRepo)
+	// Chat messages are pushed by the consumer; the server pushes the notifications of other triggers
+	var pushAdapters map[string]adapters.PushAdapter
+	var notificationPush services.PushService
+	if cfg.Push.Enabled {
+		pushAdapters, err = adapters.NewPushAdapters(cfg.Push)
+		if err != nil {
+			logger.Fatal("Failed to initialize push adapters", logger.Field("error", err))
+		}
+	}
+	pushService := services.NewPushService(deviceRepo, chatRepo, pushAdapters)
+	if cfg.Push.Enabled {
+		notificationPush = pushService
+	}
+	var webhookAdapter adapters.WebhookAdapter
+	if cfg.Notifications.Webhook.Enabled {
+		webhookAdapter = adapters.NewWebhookAdapter(cfg.Notifications.Webhook)
+	}
+	notificationService, err := services.NewNotificationService(cfg.Notifications, notificationRepo, emailAdapter, notificationPush, webhookAdapter, budgetService)
+	if err != nil {
+		logger.Fatal("Failed to initialize notifications", logger.Field("error", err))
+	}
+	eventBus.Subscribe("notifications", services.NotificationEvents(notificationService, chatRepo))
+	voiceService := services.NewVoiceService(cfg.Transcription, chatRepo, messageRepo, attachmentRepo, storageAdapter, speechToTextAdapter, messageService, budgetService, consentService)
+	personaService := services.NewPersonaService(personaRepo, chatRepo)
+	checkpointService := services.NewCheckpointService(checkpointRepo, chatRepo, messageRepo, chatService, eventBus)
+	chatWebhookAdapter := adapters.NewWebhookAdapter(configs.NotificationsWebhook{Timeout: cfg.ChatWebhooks.Timeout, Allowlist: cfg.ChatWebhooks.Allowlist})
+	chatWebhookService := services.NewChatWebhookService(cfg.ChatWebhooks, chatWebhookRepo, chatRepo, messageRepo, chatWebhookAdapter)
+	if cfg.ChatWebhooks.Enabled {
+		eventBus.Subscribe("chat-webhooks", chatWebhookService.Events())
+	}
+	taskService := services.NewTaskService(cfg.Tasks, taskRepo, chatRepo, messageRepo, llmAdapter, adapters.NewTrustedWebhookAdapter(cfg.Tasks.Timeout), budgetService, consentService)
+	experimentService := services.NewExperimentService(experimentRepo)
+	attachmentService := services.NewAttachmentService(cfg.Attachments, chatRepo, messageRepo, attachmentRepo, storageAdapter, scannerAdapter, eventBus)
+	imageService := services.NewImageService(cfg.Images, chatRepo, messageRepo, attachmentRepo, storageAdapter, imageAdapter, attachmentService, budgetService, consentService, eventBus)
+	exportService := services.NewExportService(cfg.Export, exportRepo, chatRepo, messageRepo, attachmentRepo, storageAdapter, notificationService, consentService)
+	providerService := services.NewProviderService(llmHealth)
+	batchService := services.NewBatchService(cfg.Batch, batchRepo, chatRepo, messageRepo, messageService, llmAdapter, budgetService, consentService, notificationService)
+	evaluationService := services.NewEvaluationService(cfg.Evaluation, evaluationRepo, messageRepo, llmAdapter)
+	collectionService := services.NewCollectionService(cfg.Collections, collectionRepo, documentRepo, chatRepo)
+	savedSearchService := services.NewSavedSearchService(savedSearchRepo, chatService)
+	commandService := services.NewCommandService(customCommandRepo, chatService, messageService)
+	limitsService := services.NewLimitsService(cfg, messageRepo, workspaceRepo, budgetService)
+	var searchService services.SearchService
+	if searchAdapter != nil {
+		searchService = services.NewSearchService(searchAdapter, messageRepo)
+	}
+	var warehouseService services.WarehouseService
+	if cfg.Warehouse.Enabled {
+		warehouseSink, err := adapters.NewWarehouseSinkAdapter(cfg.Warehouse)
+		if err != nil {
+			logger.Fatal("Failed to initialize warehouse sink", logger.Field("error", err))
+		}
+		warehouseService = services.NewWarehouseService(cfg.Warehouse, warehouseRepo, workspaceRepo, workspaceStatRepo, warehouseSink, analyticsPolicy)
+	}
+	trainingExportService := services.NewTrainingExportService(cfg.FineTuning, trainingExportRepo, messageRepo, storageAdapter)
+	crawlService := services.NewCrawlService(cfg.Collections, crawlRepo, collectionRepo, documentRepo, adapters.NewCrawlerAdapter(cfg.Collections.Crawler))
+
+	// Initialize background jobs
+	scheduler := jobs.NewScheduler(lockAdapter, dbAdapter.Regions())
+	if cfg.LLM.Prompt.Strategy == services.PromptStrategySummary {
+		scheduler.Register(jobs.NewSummarizerJob(summaryService), cfg.LLM.Prompt.SummaryInterval)
+	}
+	if cfg.Retention.Enabled {
+		scheduler.Register(jobs.NewRetentionJob(retentionService, cfg.Retention.DryRun), cfg.Retention.Interval)
+	}
+	// Widget visitors are guests, whose chats are purged the same way
+	if cfg.Guest.Enabled || cfg.Widget.Enabled {
+		scheduler.Register(jobs.NewGuestCleanupJob(guestService), cfg.Guest.CleanupInterval)
+	}
+	// Runs even without a grace period, to remove the chats deleted before it was turned off
+	scheduler.Register(jobs.NewChatDeletionJob(chatService), cfg.ChatDeletion.Interval)
+	if cfg.Privacy.Enabled {
+		scheduler.Register(jobs.NewEphemeralCleanupJob(chatService), cfg.Privacy.CleanupInterval)
+	}
+	// Runs even with sharing turned off, to remove the content shared before
+	scheduler.Register(jobs.NewMessageContentCleanupJob(messageContentService), cfg.MessageContent.CleanupInterval)
+	// Runs even with capturing turned off, to remove the captures taken before
+	scheduler.Register(jobs.NewCaptureCleanupJob(supportService), cfg.Capture.CleanupInterval)
+	scheduler.Register(jobs.NewExportJob(exportService), cfg.Export.Interval)
+	scheduler.Register(jobs.NewBatchJob(batchService), cfg.Batch.Interval)
+	scheduler.Register(jobs.NewTrainingExportJob(trainingExportService), cfg.FineTuning.Interval)
+	if cfg.Notifications.Enabled && cfg.Notifications.Digest.Enabled {
+		scheduler.Register(jobs.NewDigestJob(notificationService), cfg.Notifications.Digest.Interval)
+	}
+	if cfg.Billing.Reconciliation.Enabled {
+		scheduler.Register(jobs.NewReconciliationJob(billingService), cfg.Billing.Reconciliation.Interval)
+	}
+	if cfg.Warehouse.Enabled {
+		scheduler.Register(jobs.NewWarehouseJob(warehouseService), cfg.Warehouse.Interval)
+	}
+	if cfg.Evaluation.Enabled {
+		scheduler.Register(jobs.NewEvaluationJob(evaluationService), cfg.Evaluation.Interval)
+	}
+	if cfg.Transcription.Enabled {
+		scheduler.Register(jobs.NewTranscriptionJob(voiceService), cfg.Transcription.Interval)
+	}
+	if cfg.ChatWebhooks.Enabled {
+		scheduler.Register(jobs.NewChatWebhooksJob(chatWebhookService), cfg.ChatWebhooks.Interval)
+	}
+	if cfg.Watchdog.Enabled {
+		scheduler.Register(jobs.NewWatchdogJob(messageService), cfg.Watchdog.Interval)
+	}
+	if cfg.Attachments.Scanning.Enabled {
+		scheduler.Register(jobs.NewScanJob(attachmentService), cfg.Attachments.Scanning.Interval)
+	}
+	scheduler.Register(jobs.NewDocumentIndexingJob(collectionService), cfg.Collections.Interval)
+	if cfg.Collections.Crawler.Enabled {
+		scheduler.Register(jobs.NewCrawlJob(crawlService), cfg.Collections.Crawler.Interval)
+	}
 
 	// Initialize controllers
 	chatController := controllers.NewChatController(chatService)
-	messageController := controllers.NewMessageController(messageService, chatService)
+	messageController := controllers.NewMessageController(messageService, chatService, budgetService, cfg.Streams)
+	maintenance := middlewares.NewMaintenanceState(cfg.Maintenance)
+	drain := middlewares.NewDrainState(cfg.Server.Shutdown)
+	adminController := controllers.NewAdminController(maintenance, drain, deadLetterService, messageService, retentionService, auditService, evaluationService)
+	retentionController := controllers.NewRetentionController(retentionService)
+	authController := controllers.NewAuthController(cfg.JWT)
+	guestController := controllers.NewGuestController(cfg.Guest, cfg.JWT.Secret, guestService)
+	widgetController := controllers.NewWidgetController(cfg.Widget, widgetService)
+	workspaceController := controllers.NewWorkspaceController(workspaceService, workspaceStatsService)
+	instructionController := controllers.NewInstructionController(instructionService)
+	exportController := controllers.NewExportController(exportService)
+	notificationController := controllers.NewNotificationController(notificationService)
+	pushController := controllers.NewPushController(pushService)
+	personaController := controllers.NewPersonaController(personaService)
+	checkpointController := controllers.NewCheckpointController(checkpointService)
+	chatWebhookController := controllers.NewChatWebhookController(chatWebhookService)
+	errorController := controllers.NewErrorController()
+	taskController := controllers.NewTaskController(taskService)
+	experimentController := controllers.NewExperimentController(experimentService)
+	budgetController := controllers.NewBudgetController(budgetService)
+	billingController := controllers.NewBillingController(billingService)
+	trainingExportController := controllers.NewTrainingExportController(trainingExportService)
+	supportController := controllers.NewSupportController(supportService)
+	consentController := controllers.NewConsentController(consentService)
+	batchController := controllers.NewBatchController(batchService)
+	providerController := controllers.NewProviderController(providerService)
+	openAIController := controllers.NewOpenAIController(messageService, chatService, cfg.LLM, cfg.Streams)
+	voiceController := controllers.NewVoiceController(voiceService)
+	attachmentController := controllers.NewAttachmentController(attachmentService)
+	imageController := controllers.NewImageController(imageService)
+	collectionController := controllers.NewCollectionController(collectionService)
+	crawlController := controllers.NewCrawlController(crawlService)
+	searchController := controllers.NewSearchController(searchService)
+	savedSearchController := controllers.NewSavedSearchController(savedSearchService)
+	commandController := controllers.NewCommandController(commandService)
+	limitsController := controllers.NewLimitsController(limitsService)
+	var teamsController *controllers.TeamsController
+	if cfg.Integrations.Teams.Enabled {
+		teamsAdapter, err := adapters.NewTeamsAdapter(cfg.Integrations.Teams)
+		if err != nil {
+			logger.Fatal("Failed to initialize Teams adapter", logger.Field("error", err))
+		}
+		teamsService := services.NewTeamsService(cfg.Integrations.Teams, teamsRepo, chatService, messageService, teamsAdapter)
+		teamsController = controllers.NewTeamsController(teamsService, teamsAdapter)
+	}
+	var discordController *controllers.DiscordController
+	if cfg.Integrations.Discord.Enabled {
+		discordAdapter, err := adapters.NewDiscordAdapter(cfg.Integrations.Discord)
+		if err != nil {
+			logger.Fatal("Failed to initialize Discord adapter", logger.Field("error", err))
+		}
+		discordService := services.NewDiscordService(cfg.Integrations.Discord, discordRepo, chatService, messageService, discordAdapter)
+		discordController = controllers.NewDiscordController(discordService, discordAdapter)
+	}
+
+	apiVersions := []string{middlewares.APIVersion1, middlewares.APIVersion2}
+	publicPaths := []string{adapters.DownloadPath, adapters.UploadPath, adapters.TeamsMessagesPath, adapters.DiscordInteractionsPath, services.DigestUnsubscribePath}
+	for _, version := range apiVersions {
+		publicPaths = append(publicPaths, authController.PublicPaths("/api/"+version)...)
+		publicPaths = append(publicPaths, guestController.PublicPaths("/api/"+version)...)
+		publicPaths = append(publicPaths, widgetController.PublicPaths("/api/"+version)...)
+		publicPaths = append(publicPaths, errorController.PublicPaths("/api/"+version)...)
+	}
+
+	// Streamed replies and uploads take as long as they need
+	streamingRoutes := []string{"POST /v1/chat/completions", "PUT " + adapters.UploadPath}
+	for _, version := range apiVersions {
+		streamingRoutes = append(streamingRoutes, "GET /api/"+version+"/messages/:id/stream")
+	}
+
+	// Writes that can be checked with ?dryRun=true without being performed
+	var dryRunRoutes []string
+	for _, version := range apiVersions {
+		base := "/api/" + version
+		dryRunRoutes = append(dryRunRoutes, "POST "+base+"/chats", "PUT "+base+"/chats/:id", "POST "+base+"/messages", "PUT "+base+"/messages/:id")
+	}
 
 	// Create router
 	router := gin.New()
 	router.Use(gin.Recovery())
-	router.Use(middlewares.Logger())
+	router.Use(middlewares.Logger(cfg.AccessLog))
 	router.Use(middlewares.RequestID())
-	router.Use(middlewares.CORS())
-	router.Use(middlewares.Auth(cfg.JWT.Secret))
+	router.Use(middlewares.Timeout(cfg.Server.Timeouts, streamingRoutes...))
+	router.Use(middlewares.CORS(cfg.JWT.Cookie.AllowedOrigins))
+	router.Use(middlewares.Timing())
+	router.Use(middlewares.Auth(cfg.JWT, publicPaths...))
+	router.Use(middlewares.Impersonation(auditService))
+	router.Use(middlewares.Region(cfg.Database))
+	router.Use(middlewares.Workspace(cfg.WorkspaceStats, workspaceStatsService))
+	router.Use(middlewares.PublicIDs(chatRepo, messageRepo))
+	if cfg.Capture.Enabled {
+		router.Use(middlewares.Capture(cfg.Capture.All))
+	}
+	router.Use(middlewares.GuestRateLimit(cfg.Guest.RequestsPerMinute))
+	router.Use(middlewares.Maintenance(maintenance))
+	router.Use(middlewares.Drain(drain))
+	router.Use(middlewares.DryRun(dryRunRoutes...))
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
-	// API routes
-	api := router.Group("/api/v1")
-	{
+	// Readiness endpoint, failing while the server drains
+	router.GET("/ready", func(c *gin.Context) {
+		if drain.Draining() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	// Prometheus metrics endpoint
+	router.GET("/metrics", metrics.Handler())
+
+	// Signed download and upload URLs of stored files
+	exportController.RegisterDownloadRoute(router)
+	attachmentController.RegisterUploadRoute(router)
+
+	// Unsubscribe links of activity digests
+	notificationController.RegisterUnsubscribeRoute(router)
+
+	// OpenAI-compatible endpoints, at the paths OpenAI SDK clients expect
+	openAIController.RegisterRoutes(router)
+
+	// Messaging platform integrations, authenticated by the platforms
+	if teamsController != nil {
+		teamsController.RegisterRoutes(router)
+	}
+	if discordController != nil {
+		discordController.RegisterRoutes(router)
+	}
+
+	// API routes, registered once per version. v1 is frozen; v2 wraps responses in an envelope.
+	for _, version := range apiVersions {
+		api := router.Group("/api/"+version,
+			middlewares.APIVersion(version),
+			middlewares.Deprecation(version, cfg.API),
+			middlewares.Warnings(),
+		)
 		chatController.RegisterRoutes(api)
 		messageController.RegisterRoutes(api)
+		retentionController.RegisterRoutes(api)
+		adminController.RegisterRoutes(api)
+		authController.RegisterRoutes(api)
+		guestController.RegisterRoutes(api)
+		widgetController.RegisterRoutes(api)
+		workspaceController.RegisterRoutes(api)
+		instructionController.RegisterRoutes(api)
+		exportController.RegisterRoutes(api)
+		notificationController.RegisterRoutes(api)
+		pushController.RegisterRoutes(api)
+		personaController.RegisterRoutes(api)
+		checkpointController.RegisterRoutes(api)
+		chatWebhookController.RegisterRoutes(api)
+		errorController.RegisterRoutes(api)
+		taskController.RegisterRoutes(api)
+		experimentController.RegisterRoutes(api)
+		budgetController.RegisterRoutes(api)
+		billingController.RegisterRoutes(api)
+		trainingExportController.RegisterRoutes(api)
+		supportController.RegisterRoutes(api)
+		consentController.RegisterRoutes(api)
+		batchController.RegisterRoutes(api)
+		providerController.RegisterRoutes(api)
+		voiceController.RegisterRoutes(api)
+		attachmentController.RegisterRoutes(api)
+		imageController.RegisterRoutes(api)
+		collectionController.RegisterRoutes(api)
+		crawlController.RegisterRoutes(api)
+		searchController.RegisterRoutes(api)
+		savedSearchController.RegisterRoutes(api)
+		commandController.RegisterRoutes(api)
+		limitsController.RegisterRoutes(api)
 	}
 
 	// Start the server
@@ -103,33 +653,59 @@ func main() {
 		Addr:    addr,
 		Handler: router,
 	}
+	if err := configureServer(srv, cfg.Server); err != nil {
+		logger.Fatal("Failed to configure server", logger.Field("error", err))
+	}
+
+	listener, err := newListener(addr, cfg.Server)
+	if err != nil {
+		logger.Fatal("Failed to listen", logger.Field("error", err))
+	}
 
 	// Run the server in a goroutine
 	go func() {
 		logger.Info("Starting server",
-			logger.Field("host", cfg.App.Host),
-			logger.Field("port", cfg.App.Port),
+			logger.Field("address", listener.Addr().String()),
+			logger.Field("tls", cfg.Server.TLS.Enabled),
+			logger.Field("http2", cfg.Server.HTTP2),
 			logger.Field("env", cfg.App.Environment))
 
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := serve(srv, listener, cfg.Server.TLS); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", logger.Field("error", err))
 		}
 	}()
 
+	// Start background jobs
+	if cfg.Jobs.Enabled {
+		scheduler.Start(context.Background())
+		defer scheduler.Stop()
+	}
+
+	// Dump goroutines and the heap on SIGUSR1
+	handleDumpSignal(cfg.Server.DumpDir)
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
+	// Report not ready until load balancers stop routing new requests; a second signal
+	// skips the wait
+	logger.Info("Draining server...", logger.Field("readinessDelay", cfg.Server.Shutdown.ReadinessDelay))
+	drainCtx, stopDraining := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	drain.Drain(drainCtx)
+	stopDraining()
+
 	logger.Info("Shutting down server...")
 
 	// Create a deadline to wait for current operations to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.Shutdown.Grace)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatal("Server forced to shutdown", logger.Field("error", err))
 	}
+	workspaceStatsService.Flush(ctx)
 
 	logger.Info("Server exited")
 }
@@ -157,27 +733,222 @@ func main() {
 // 	return nil
 // }
 
-// setupKafka initializes the Kafka producer
-func setupKafka(cfg configs.Config) services.KafkaProducer {
-	// In a real application, this would initialize a Kafka client
-	// For simplicity, we'll use a mock implementation
-	return &mockKafkaProducer{}
+// setupLLMMiddleware wraps the LLM adapter in the configured middlewares. Moderation
+// runs before the cache so blocked requests are never served, and retries run
+// closest to the provider so each attempt is not counted as a separate request.
+// Load shedding runs after the cache, so cached replies of the requested model are still
+// served under load, and outside fallbacks, so the model downgraded to may fall back too.
+// Fallbacks run outside retries, so a model is only given up once its retries failed.
+// Each attempt takes a concurrency slot, and time spent queued for one is neither
+// captured nor counted towards the health of the provider.
+// Health tracking wraps the provider itself, so cache hits do not count and each
+// retry attempt does. Captures are taken next to it, recording what each attempt sent to
+// and got from the provider.
+func setupLLMMiddleware(cfg configs.Config, adapter adapters.LLMAdapter, health *adapters.LLMHealth, support services.SupportService) adapters.LLMAdapter {
+	middleware := cfg.LLM.Middleware
+	builder := adapters.NewLLMAdapterBuilder(adapter)
+
+	if middleware.Logging {
+		builder.Use(adapters.LoggingLLMMiddleware())
+	}
+	if middleware.Metrics {
+		builder.Use(adapters.MetricsLLMMiddleware(cfg.LLM.Model))
+	}
+	if middleware.Moderation.Enabled {
+		builder.Use(adapters.ModerationLLMMiddleware(middleware.Moderation.BlockedTerms))
+	}
+	if middleware.Cache.Enabled {
+		builder.Use(adapters.CacheLLMMiddleware(middleware.Cache.TTL, middleware.Cache.MaxEntries))
+	}
+	var limiter *adapters.LLMLimiter
+	if limit := middleware.Concurrency.Limit(cfg.LLM.Provider); limit > 0 {
+		limiter = adapters.NewLLMLimiter(limit)
+	}
+	if middleware.LoadShedding.Enabled {
+		shedder, err := adapters.NewLLMLoadShedder(middleware.LoadShedding, health, limiter)
+		if err != nil {
+			logger.Fatal("Failed to initialize LLM load shedding", logger.Field("error", err))
+		}
+		builder.Use(shedder.Middleware(cfg.LLM.Model))
+	}
+	if len(middleware.Fallback.Models) > 0 {
+		builder.Use(adapters.FallbackLLMMiddleware(cfg.LLM.Model, middleware.Fallback.Models, middleware.Fallback.Speculative))
+	}
+	if middleware.Retry.MaxAttempts > 1 {
+		builder.Use(adapters.RetryLLMMiddleware(middleware.Retry.MaxAttempts, middleware.Retry.Backoff))
+	}
+	if limiter != nil {
+		builder.Use(adapters.ConcurrencyLLMMiddleware(limiter, middleware.Concurrency.MaxWait))
+	}
+	if cfg.Capture.Enabled {
+		builder.Use(adapters.CaptureLLMMiddleware(support.Record))
+	}
+	builder.Use(health.Middleware())
+
+	return builder.Build()
+}
+
+// setupLLM initializes the LLM adapter for the configured provider
+func setupLLM(cfg configs.Config) adapters.LLMAdapter {
+	switch cfg.LLM.Provider {
+	case "http":
+		keys, err := adapters.NewLLMKeyPool(cfg.LLM.KeyPool, cfg.LLM.APIKey)
+		if err != nil {
+			logger.Fatal("Failed to initialize LLM API keys", logger.Field("error", err))
+		}
+		if err := adapters.CheckLLMPassthrough(cfg.LLM.Passthrough); err != nil {
+			logger.Fatal("Invalid LLM passthrough configuration", logger.Field("error", err))
+		}
+		logger.Info("Using http LLM provider", logger.Field("keys", keys.Len()), logger.Field("keyStrategy", cfg.LLM.KeyPool.Strategy))
+		return adapters.NewLLMAdapter(cfg.LLM, keys)
+	case "simulated":
+		logger.Info("Using simulated LLM provider",
+			logger.Field("latencyDistribution", cfg.LLM.Simulated.LatencyDistribution),
+			logger.Field("latencyMean", cfg.LLM.Simulated.LatencyMean),
+			logger.Field("errorRate", cfg.LLM.Simulated.ErrorRate))
+		return adapters.NewSimulatedLLMAdapter(cfg.LLM.Simulated, cfg.LLM.Model)
+	default:
+		return adapters.NewNothingLLMAdapter()
+	}
+}
+
+// setupEmail initializes the email adapter for the configured provider
+func setupEmail(cfg configs.Config) adapters.EmailAdapter {
+	var adapter adapters.EmailAdapter
+	var err error
+	switch cfg.Email.Provider {
+	case "smtp":
+		adapter, err = adapters.NewSMTPEmailAdapter(cfg.Email)
+	case "ses":
+		adapter, err = adapters.NewSESEmailAdapter(cfg.Email)
+	default:
+		return adapters.NewLogEmailAdapter()
+	}
+	if err != nil {
+		logger.Fatal("Failed to initialize email adapter", logger.Field("provider", cfg.Email.Provider), logger.Field("error", err))
+	}
+	return adapter
+}
+
+// setupQueue initializes the message broker producer once the broker is reachable
+func setupQueue(ctx context.Context, cfg configs.Config) queue.Producer {
+	var producer queue.Producer
+	switch cfg.Broker.Type {
+	case "nats":
+		err := adapters.WaitFor(ctx, "NATS", cfg.Startup, func(ctx context.Context) error {
+			var err error
+			producer, err = queue.NewNATSProducer(cfg.Broker.NATS.URL, cfg.Broker.NATS.Stream, cfg.Broker.NATS.SubjectPrefix)
+			return err
+		})
+		if err != nil {
+			logger.Fatal("Failed to initialize NATS producer", logger.Field("error", err))
+		}
+		return producer
+	case "rabbitmq":
+		err := adapters.WaitFor(ctx, "RabbitMQ", cfg.Startup, func(ctx context.Context) error {
+			var err error
+			producer, err = queue.NewRabbitMQProducer(cfg.Broker.RabbitMQ.URL, cfg.Broker.RabbitMQ.Exchange)
+			return err
+		})
+		if err != nil {
+			logger.Fatal("Failed to initialize RabbitMQ producer", logger.Field("error", err))
+		}
+		return producer
+	default:
+		if !cfg.Kafka.Enabled {
+			return queue.NewMockProducer()
+		}
+		err := adapters.WaitFor(ctx, "Kafka", cfg.Startup, func(ctx context.Context) error {
+			return queue.PingKafka(ctx, cfg.Kafka.Brokers)
+		})
+		if err != nil {
+			logger.Fatal("Failed to connect to Kafka", logger.Field("error", err))
+		}
+		return queue.NewKafkaProducer(cfg.Kafka.Brokers)
+	}
+}
+
+// setupEvents initializes the event publisher
+func setupEvents(cfg configs.Config, producer queue.Producer) services.EventPublisher {
+	if cfg.Broker.Type == "kafka" && !cfg.Kafka.Enabled {
+		// Without a broker, events are only logged
+		return &mockEventPublisher{}
+	}
+	return services.NewEventPublisher(producer, cfg.Kafka)
+}
+
+// registerEventSchemas registers the JSON schemas of published events with the schema registry
+func registerEventSchemas(cfg configs.Config) {
+	if !cfg.Kafka.SchemaRegistry.Enabled {
+		return
+	}
+
+	registry := adapters.NewSchemaRegistryAdapter(cfg.Kafka.SchemaRegistry)
+	events := map[string]interface{}{
+		cfg.Kafka.Topics.Chat:       dtos.KafkaMessage[dtos.ChatPayload]{},
+		cfg.Kafka.Topics.Message:    dtos.KafkaMessage[dtos.MessagePayload]{},
+		cfg.Kafka.Topics.Budget:     dtos.KafkaMessage[dtos.BudgetPayload]{},
+		cfg.Kafka.Topics.Attachment: dtos.KafkaMessage[dtos.AttachmentPayload]{},
+		cfg.Kafka.Topics.Billing:    dtos.KafkaMessage[dtos.BillingPayload]{},
+	}
+
+	for topic, event := range events {
+		// Subjects follow the registry's topic name strategy
+		subject := topic + "-value"
+		document, err := schema.Generate(subject, event)
+		if err != nil {
+			logger.Fatal("Failed to generate event schema", logger.Field("subject", subject), logger.Field("error", err))
+		}
+		if _, err := registry.Register(context.Background(), subject, document); err != nil {
+			// Publishing does not depend on the registry
+			logger.Error("Failed to register event schema", logger.Field("subject", subject), logger.Field("error", err))
+		}
+	}
 }
 
-// mockKafkaProducer is a simple mock implementation of the KafkaProducer interface
-type mockKafkaProducer struct{}
+// mockEventPublisher is a simple mock implementation of the EventPublisher interface
+type mockEventPublisher struct{}
 
-func (m *mockKafkaProducer) PublishChatEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.ChatPayload]) error {
+func (m *mockEventPublisher) PublishChatEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.ChatPayload]) error {
 	logger.Context(ctx).Infow("Mock: Publishing chat event",
 		"event", message.Event,
-		"chatID", message.Payload.ChatID)
+		"chatID", message.Payload.ChatID,
+		"headers", message.Headers)
 	return nil
 }
 
-func (m *mockKafkaProducer) PublishMessageEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.MessagePayload]) error {
+func (m *mockEventPublisher) PublishMessageEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.MessagePayload]) error {
 	logger.Context(ctx).Infow("Mock: Publishing message event",
 		"event", message.Event,
 		"messageID", message.Payload.MessageID,
-		"chatID", message.Payload.ChatID)
+		"chatID", message.Payload.ChatID,
+		"headers", message.Headers)
+	return nil
+}
+
+func (m *mockEventPublisher) PublishBudgetEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.BudgetPayload]) error {
+	logger.Context(ctx).Infow("Mock: Publishing budget event",
+		"event", message.Event,
+		"scope", message.Payload.Scope,
+		"userID", message.Payload.UserID,
+		"headers", message.Headers)
+	return nil
+}
+
+func (m *mockEventPublisher) PublishAttachmentEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.AttachmentPayload]) error {
+	logger.Context(ctx).Infow("Mock: Publishing attachment event",
+		"event", message.Event,
+		"attachmentID", message.Payload.AttachmentID,
+		"chatID", message.Payload.ChatID,
+		"headers", message.Headers)
+	return nil
+}
+
+func (m *mockEventPublisher) PublishBillingEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.BillingPayload]) error {
+	logger.Context(ctx).Infow("Mock: Publishing billing event",
+		"event", message.Event,
+		"exchangeID", message.Payload.ExchangeID,
+		"userID", message.Payload.UserID,
+		"headers", message.Headers)
 	return nil
 }
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/migrations"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+)
+
+// selfTestTimeout bounds each check of the self-test, so an unreachable dependency fails
+// the check instead of hanging the deployment
+const selfTestTimeout = 5 * time.Second
+
+// Outcomes of self-test checks
+const (
+	selfTestOK      = "ok"
+	selfTestFailed  = "failed"
+	selfTestSkipped = "skipped"
+)
+
+// selfTestReport is the report of the self-test, printed as JSON
+type selfTestReport struct {
+	Status string          `json:"status"`
+	Checks []selfTestCheck `json:"checks"`
+}
+
+// selfTestCheck is the outcome of a check of the self-test
+type selfTestCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	DurationMs int64  `json:"durationMs"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// runSelfTest checks that the service can run with its configuration: it validates the
+// configuration, connects to the database, the message broker and the LLM provider, then
+// runs an exchange through the LLM middleware against the simulated provider. The report is
+// printed to stdout, and the exit code is 1 when a check failed.
+func runSelfTest(configPath, profile string) int {
+	report := selfTestReport{Status: selfTestOK}
+	add := func(check selfTestCheck) {
+		if check.Status == selfTestFailed {
+			report.Status = selfTestFailed
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	configCheck := selfTestCheck{Name: "config", Status: selfTestOK}
+	if err := configs.Load(configPath, profile); err != nil {
+		configCheck.Status, configCheck.Error = selfTestFailed, err.Error()
+		add(configCheck)
+		for _, name := range []string{"database", "broker", "llm", "exchange"} {
+			add(selfTestCheck{Name: name, Status: selfTestSkipped, Detail: "the configuration failed to load"})
+		}
+		return printSelfTest(report)
+	}
+	cfg := configs.AppConfig
+	// Logs go to stderr, leaving stdout to the report
+	logger.Init(cfg.App.LogLevel, cfg.App.Environment)
+	defer logger.Sync()
+
+	if err := validateConfig(cfg); err != nil {
+		configCheck.Status, configCheck.Error = selfTestFailed, err.Error()
+	}
+	add(configCheck)
+
+	add(runCheck("database", func(ctx context.Context) (string, error) {
+		dbAdapter, err := adapters.NewDBAdapter(cfg.Database)
+		if err != nil {
+			return "", err
+		}
+		defer dbAdapter.Close()
+		if err := dbAdapter.Ping(ctx); err != nil {
+			return "", err
+		}
+		if err := migrations.CheckVersion(ctx, dbAdapter); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d region(s) reachable", len(dbAdapter.Regions())), nil
+	}))
+	add(checkBroker(cfg))
+	add(checkLLM(cfg))
+	add(runCheck("exchange", func(ctx context.Context) (string, error) {
+		return runSelfTestExchange(ctx, cfg)
+	}))
+
+	return printSelfTest(report)
+}
+
+// validateConfig checks the settings the service refuses to start with
+func validateConfig(cfg configs.Config) error {
+	var problems []string
+	if cfg.Consent.Required && cfg.Consent.TermsVersion == "" {
+		problems = append(problems, "consent is required but no terms version is configured")
+	}
+	if cfg.LLM.Provider == "http" {
+		if _, err := adapters.NewLLMKeyPool(cfg.LLM.KeyPool, cfg.LLM.APIKey); err != nil {
+			problems = append(problems, fmt.Sprintf("LLM API keys: %v", err))
+		}
+		if err := adapters.CheckLLMPassthrough(cfg.LLM.Passthrough); err != nil {
+			problems = append(problems, fmt.Sprintf("LLM passthrough: %v", err))
+		}
+	}
+	if cfg.LLM.Middleware.LoadShedding.Enabled && cfg.LLM.Middleware.LoadShedding.Model == "" {
+		problems = append(problems, "LLM load shedding is enabled without a model")
+	}
+	if cfg.Privacy.Enabled && cfg.Privacy.Store != configs.PrivacyStoreMemory && cfg.Privacy.Store != configs.PrivacyStoreRedis {
+		problems = append(problems, fmt.Sprintf("unknown privacy mode store %q", cfg.Privacy.Store))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkBroker connects to the configured message broker
+func checkBroker(cfg configs.Config) selfTestCheck {
+	switch cfg.Broker.Type {
+	case "nats":
+		return runCheck("broker", func(ctx context.Context) (string, error) {
+			producer, err := queue.NewNATSProducer(cfg.Broker.NATS.URL, cfg.Broker.NATS.Stream, cfg.Broker.NATS.SubjectPrefix)
+			if err != nil {
+				return "", err
+			}
+			return "nats reachable", producer.Close()
+		})
+	case "rabbitmq":
+		return runCheck("broker", func(ctx context.Context) (string, error) {
+			producer, err := queue.NewRabbitMQProducer(cfg.Broker.RabbitMQ.URL, cfg.Broker.RabbitMQ.Exchange)
+			if err != nil {
+				return "", err
+			}
+			return "rabbitmq reachable", producer.Close()
+		})
+	default:
+		if !cfg.Kafka.Enabled {
+			return selfTestCheck{Name: "broker", Status: selfTestSkipped, Detail: "kafka is disabled, events are only logged"}
+		}
+		return runCheck("broker", func(ctx context.Context) (string, error) {
+			return "kafka reachable", queue.PingKafka(ctx, cfg.Kafka.Brokers)
+		})
+	}
+}
+
+// checkLLM connects to the vendor of the http LLM provider. Any HTTP response counts, as it
+// shows the vendor is reachable without spending tokens.
+func checkLLM(cfg configs.Config) selfTestCheck {
+	if cfg.LLM.Provider != "http" {
+		return selfTestCheck{Name: "llm", Status: selfTestSkipped, Detail: fmt.Sprintf("the %s provider makes no connections", cfg.LLM.Provider)}
+	}
+	return runCheck("llm", func(ctx context.Context) (string, error) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodHead, cfg.LLM.BaseURL, nil)
+		if err != nil {
+			return "", err
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return "", err
+		}
+		response.Body.Close()
+		return fmt.Sprintf("%s answered with HTTP %d", cfg.LLM.BaseURL, response.StatusCode), nil
+	})
+}
+
+// runSelfTestExchange streams a reply from the simulated provider through the configured LLM
+// middleware, checking that the chunks add up to the reply
+func runSelfTestExchange(ctx context.Context, cfg configs.Config) (string, error) {
+	simulated := cfg.LLM.Simulated
+	simulated.LatencyDistribution = "fixed"
+	simulated.LatencyMean = 10 * time.Millisecond
+	simulated.ChunkInterval = 0
+	simulated.ErrorRate = 0
+	// Captures are saved by the support service, which needs the database
+	cfg.Capture.Enabled = false
+	// Load shedding without a model fails the config check already
+	if cfg.LLM.Middleware.LoadShedding.Model == "" {
+		cfg.LLM.Middleware.LoadShedding.Enabled = false
+	}
+
+	health := adapters.NewLLMHealth("simulated", cfg.LLM.Model, cfg.LLM.Middleware.Health)
+	llm := setupLLMMiddleware(cfg, adapters.NewSimulatedLLMAdapter(simulated, cfg.LLM.Model), health, nil)
+	streamer, ok := llm.(adapters.LLMStreamer)
+	if !ok {
+		return "", fmt.Errorf("the LLM middleware does not stream")
+	}
+
+	var streamed strings.Builder
+	chunks := 0
+	response, err := streamer.StreamResponse(ctx, &dtos.LLMRequest{
+		Model:    cfg.LLM.Model,
+		Messages: []dtos.LLMMessage{{Role: "user", Content: "Self-test: reply with a few words."}},
+	}, func(chunk *dtos.LLMChunk) error {
+		chunks++
+		streamed.WriteString(chunk.Content)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if chunks == 0 || response.Message.Content == "" {
+		return "", fmt.Errorf("the reply is empty")
+	}
+	if streamed.String() != response.Message.Content {
+		return "", fmt.Errorf("the streamed chunks do not add up to the reply")
+	}
+	return fmt.Sprintf("%d chunks, %d tokens", chunks, response.Usage.TotalTokens), nil
+}
+
+// runCheck runs a check with selfTestTimeout. Checks that do not return in time are reported
+// as failed and left running, as some connections cannot be cancelled.
+func runCheck(name string, check func(ctx context.Context) (string, error)) selfTestCheck {
+	ctx, cancel := context.WithTimeout(context.Background(), selfTestTimeout)
+	defer cancel()
+
+	type outcome struct {
+		detail string
+		err    error
+	}
+	start := time.Now()
+	done := make(chan outcome, 1)
+	go func() {
+		detail, err := check(ctx)
+		done <- outcome{detail, err}
+	}()
+
+	result := selfTestCheck{Name: name, Status: selfTestOK}
+	select {
+	case out := <-done:
+		result.Detail = out.detail
+		if out.err != nil {
+			result.Status, result.Detail, result.Error = selfTestFailed, "", out.err.Error()
+		}
+	case <-ctx.Done():
+		result.Status, result.Error = selfTestFailed, fmt.Sprintf("timed out after %s", selfTestTimeout)
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result
+}
+
+// printSelfTest prints the report to stdout and returns the exit code of the self-test
+func printSelfTest(report selfTestReport) int {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to print the self-test report: %v\n", err)
+		return 1
+	}
+	if report.Status != selfTestOK {
+		return 1
+	}
+	return 0
+}
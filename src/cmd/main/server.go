@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// newListener returns the server listener: a Unix socket when configured, TCP on addr otherwise
+func newListener(addr string, cfg configs.Server) (net.Listener, error) {
+	if cfg.Socket == "" {
+		return net.Listen("tcp", addr)
+	}
+
+	// Remove the socket left over by a previous run that did not shut down cleanly
+	if err := os.Remove(cfg.Socket); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return net.Listen("unix", cfg.Socket)
+}
+
+// configureServer sets the protocols and timeouts of the server and, when TLS is enabled
+// without a certificate file, the autocert TLS configuration
+func configureServer(srv *http.Server, cfg configs.Server) error {
+	// Request bodies and handlers are bounded per route by the timeout middleware
+	srv.ReadHeaderTimeout = cfg.Timeouts.ReadHeader
+	srv.IdleTimeout = cfg.Timeouts.Idle
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if cfg.HTTP2 {
+		if cfg.TLS.Enabled {
+			protocols.SetHTTP2(true)
+		} else {
+			// Cleartext HTTP/2 with prior knowledge, for proxies speaking h2c
+			protocols.SetUnencryptedHTTP2(true)
+		}
+	}
+	srv.Protocols = protocols
+
+	if !cfg.TLS.Enabled || cfg.TLS.CertFile != "" {
+		return nil
+	}
+	if len(cfg.TLS.Autocert.Domains) == 0 {
+		return fmt.Errorf("tls is enabled without a certificate file or autocert domains")
+	}
+
+	// Certificates are obtained with the TLS-ALPN-01 challenge, which requires
+	// the server to be reachable on port 443
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.TLS.Autocert.Domains...),
+		Cache:      autocert.DirCache(cfg.TLS.Autocert.CacheDir),
+		Email:      cfg.TLS.Autocert.Email,
+	}
+	srv.TLSConfig = manager.TLSConfig()
+	return nil
+}
+
+// serve accepts connections on the listener, terminating TLS when enabled
+func serve(srv *http.Server, listener net.Listener, cfg configs.TLS) error {
+	if !cfg.Enabled {
+		return srv.Serve(listener)
+	}
+
+	// Cert and key files are empty with autocert, which provides certificates through the TLS config
+	return srv.ServeTLS(listener, cfg.CertFile, cfg.KeyFile)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/migrations"
+)
+
+const usage = `Usage: migrate [flags] <command>
+
+Commands:
+  up          apply the pending migrations
+  down N      roll back the last N applied migrations
+  baseline N  record the migrations up to N as applied without running them, adopting a
+              schema migrated before, such as by golang-migrate
+  status      list the migrations and when they were applied
+  check       list the unacknowledged destructive statements of the migrations, without a database
+
+Flags:
+`
+
+// Applies and rolls back the SQL migrations of the database schema
+func main() {
+	// Parse command-line flags
+	configPath := flag.String("config", "", "comma-separated config files, each overlaying the previous ones")
+	profile := flag.String("profile", "", "config profile overlaying <profile>.yaml next to the first config file; defaults to CONFIG_PROFILE")
+	allowDestructive := flag.Bool("allow-destructive", false, "apply migrations with destructive statements not acknowledged in the migration")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	all, err := migrations.Load()
+	if err != nil {
+		fmt.Printf("Failed to load migrations: %v\n", err)
+		os.Exit(1)
+	}
+
+	command := flag.Arg(0)
+	switch {
+	case command == "check" && flag.NArg() == 1:
+		os.Exit(check(all))
+	case (command == "down" || command == "baseline") && flag.NArg() == 2:
+	case (command == "up" || command == "status") && flag.NArg() == 1:
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load configuration
+	if err := configs.Load(*configPath, *profile); err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	cfg := configs.AppConfig
+
+	// Initialize logger
+	logger.Init(cfg.App.LogLevel, cfg.App.Environment)
+	defer logger.Sync()
+
+	// Stop between migrations on interrupt; the running one completes or is rolled back
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	dbAdapter, err := adapters.NewDBAdapter(cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", logger.Field("error", err))
+	}
+	defer dbAdapter.Close()
+
+	runner := migrations.NewRunner(dbAdapter, adapters.NewPostgresLockAdapter(dbAdapter), all)
+	switch command {
+	case "up":
+		applied, err := runner.Up(ctx, *allowDestructive)
+		for region, list := range applied {
+			logger.Info("Migrations applied", logger.Field("region", region), logger.Field("count", len(list)))
+		}
+		if err != nil {
+			logger.Fatal("Failed to apply migrations", logger.Field("error", err))
+		}
+	case "down":
+		n, err := strconv.Atoi(flag.Arg(1))
+		if err != nil {
+			logger.Fatal("Invalid number of migrations to roll back", logger.Field("error", err))
+		}
+		rolledBack, err := runner.Down(ctx, n)
+		for region, list := range rolledBack {
+			logger.Info("Migrations rolled back", logger.Field("region", region), logger.Field("count", len(list)))
+		}
+		if err != nil {
+			logger.Fatal("Failed to roll back migrations", logger.Field("error", err))
+		}
+	case "baseline":
+		version, err := strconv.Atoi(flag.Arg(1))
+		if err != nil {
+			logger.Fatal("Invalid version to baseline at", logger.Field("error", err))
+		}
+		adopted, err := runner.Baseline(ctx, version)
+		for region, list := range adopted {
+			logger.Info("Migrations recorded as applied", logger.Field("region", region), logger.Field("count", len(list)))
+		}
+		if err != nil {
+			logger.Fatal("Failed to record the baseline", logger.Field("error", err))
+		}
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			logger.Fatal("Failed to read the migration status", logger.Field("error", err))
+		}
+		for _, region := range dbAdapter.Regions() {
+			printStatus(region, statuses[region])
+		}
+	}
+}
+
+// check prints the migrations with unacknowledged destructive statements, and returns the
+// exit code: 1 when there are some
+func check(all []migrations.Migration) int {
+	code := 0
+	for _, migration := range all {
+		if err := migration.Check(); err != nil {
+			fmt.Println(err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// printStatus prints the state of the migrations of a region, one per line
+func printStatus(region string, statuses []migrations.Status) {
+	if region == "" {
+		region = "main"
+	}
+	fmt.Printf("Region %s:\n", region)
+	for _, status := range statuses {
+		state := "pending"
+		if status.AppliedAt != nil {
+			state = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+		switch {
+		case status.Unknown:
+			state += " (unknown to this binary)"
+		case status.Drifted:
+			state += " (changed since applied)"
+		}
+		fmt.Printf("  %03d_%s\t%s\n", status.Version, status.Name, state)
+	}
+}
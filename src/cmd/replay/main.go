@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/handlers"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/migrations"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// Replays the retained events of Kafka topics through projections to rebuild them
+func main() {
+	// Parse command-line flags
+	configPath := flag.String("config", "", "comma-separated config files, each overlaying the previous ones")
+	profile := flag.String("profile", "", "config profile overlaying <profile>.yaml next to the first config file; defaults to CONFIG_PROFILE")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted, then exit")
+	names := flag.String("projections", "", "comma-separated projections to rebuild; all when empty")
+	since := flag.String("since", "", "replay the events published since this RFC 3339 time")
+	offsets := flag.String("offsets", "", "replay partitions from these offsets, as partition:offset pairs separated by commas")
+	reset := flag.Bool("reset", false, "clear the projections before a full replay")
+	flag.Parse()
+
+	// Load configuration
+	if err := configs.Load(*configPath, *profile); err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	cfg := configs.AppConfig
+	if *printConfig {
+		if err := configs.Print(os.Stdout, cfg); err != nil {
+			fmt.Printf("Failed to print config: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Initialize logger
+	logger.Init(cfg.App.LogLevel, cfg.App.Environment)
+	defer logger.Sync()
+
+	opts, err := parseOptions(*since, *offsets, *reset)
+	if err != nil {
+		logger.Fatal("Invalid replay options", logger.Field("error", err))
+	}
+
+	// Stop replaying on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	dbAdapter, err := adapters.NewDBAdapter(cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", logger.Field("error", err))
+	}
+	defer dbAdapter.Close()
+
+	// Refuse to run against a schema migrated past this binary, as during a botched rollback
+	if err := migrations.CheckVersion(ctx, dbAdapter); err != nil {
+		logger.Fatal("Database schema is newer than the binary", logger.Field("error", err))
+	}
+
+	if err := dbAdapter.AutoMigrate(&models.ChatStats{}); err != nil {
+		logger.Fatal("Failed to run auto-migrations", logger.Field("error", err))
+	}
+
+	// The projections maintained by the consumer
+	all := []handlers.Projection{
+		handlers.NewChatStatsHandler(cfg.Kafka, repositories.NewChatStatsRepository(dbAdapter)),
+	}
+	if cfg.Search.Enabled {
+		chatSearch, messageSearch := setupSearch(ctx, cfg, dbAdapter)
+		all = append(all, chatSearch, messageSearch)
+	}
+	projections := map[string]handlers.Projection{}
+	for _, projection := range all {
+		projections[projection.Name()] = projection
+	}
+
+	selected, err := selectProjections(projections, *names)
+	if err != nil {
+		logger.Fatal("Invalid projections", logger.Field("error", err))
+	}
+
+	replayer := handlers.NewReplayer(cfg.Kafka, dbAdapter.Regions())
+	failed := false
+	for _, projection := range selected {
+		logger.Info("Replaying events", logger.Field("handler", projection.Name()), logger.Field("topic", projection.Topic()))
+		start := time.Now()
+
+		result, err := replayer.Replay(ctx, projection, opts)
+		if err != nil {
+			logger.Error("Failed to replay events", logger.Field("handler", projection.Name()), logger.Field("error", err))
+			failed = true
+			continue
+		}
+		logger.Info("Events replayed",
+			logger.Field("handler", projection.Name()),
+			logger.Field("handled", result.Handled),
+			logger.Field("failed", result.Failed),
+			logger.Field("duration", time.Since(start)))
+		failed = failed || result.Failed > 0
+	}
+
+	if failed {
+		logger.Sync()
+		os.Exit(1)
+	}
+}
+
+// parseOptions parses the replay start flags. Only full replays may reset projections.
+func parseOptions(since, offsets string, reset bool) (handlers.ReplayOptions, error) {
+	opts := handlers.ReplayOptions{Reset: reset}
+
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return opts, fmt.Errorf("invalid since time: %w", err)
+		}
+		opts.Since = t
+	}
+
+	if offsets != "" {
+		opts.Offsets = map[int]int64{}
+		for _, pair := range strings.Split(offsets, ",") {
+			partition, offset, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			p, err := strconv.Atoi(partition)
+			if !ok || err != nil {
+				return opts, fmt.Errorf("invalid partition offset %q", pair)
+			}
+			o, err := strconv.ParseInt(offset, 10, 64)
+			if err != nil || o < 0 {
+				return opts, fmt.Errorf("invalid partition offset %q", pair)
+			}
+			opts.Offsets[p] = o
+		}
+	}
+
+	if reset && (!opts.Since.IsZero() || len(opts.Offsets) > 0) {
+		return opts, fmt.Errorf("reset requires a full replay, without since or offsets")
+	}
+	return opts, nil
+}
+
+// selectProjections returns the named projections, or all of them in name order
+func selectProjections(projections map[string]handlers.Projection, names string) ([]handlers.Projection, error) {
+	if names == "" {
+		all := make([]string, 0, len(projections))
+		for name := range projections {
+			all = append(all, name)
+		}
+		sort.Strings(all)
+		names = strings.Join(all, ",")
+	}
+
+	var selected []handlers.Projection
+	for _, name := range strings.Split(names, ",") {
+		projection, ok := projections[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown projection %q", name)
+		}
+		selected = append(selected, projection)
+	}
+	return selected, nil
+}
+
+// setupSearch creates the projections of the search service, as the consumer does
+func setupSearch(ctx context.Context, cfg configs.Config, dbAdapter adapters.DBAdapter) (handlers.Projection, handlers.Projection) {
+	searchAdapter, err := adapters.NewSearchAdapter(cfg.Search)
+	if err != nil {
+		logger.Fatal("Failed to initialize search adapter", logger.Field("error", err))
+	}
+	if err := searchAdapter.EnsureIndices(ctx); err != nil {
+		logger.Fatal("Failed to create search indices", logger.Field("error", err))
+	}
+
+	if cfg.Storage.Secret == "" {
+		cfg.Storage.Secret = cfg.JWT.Secret
+	}
+	storageAdapter, err := adapters.NewLocalStorageAdapter(cfg.Storage)
+	if err != nil {
+		logger.Fatal("Failed to initialize storage", logger.Field("error", err))
+	}
+	messageContent := repositories.NewMessageContentStore(storageAdapter, dbAdapter, cfg.MessageContent)
+
+	return handlers.NewChatSearchHandler(cfg.Kafka, searchAdapter),
+		handlers.NewMessageSearchHandler(cfg.Kafka, searchAdapter,
+			repositories.NewChatRepository(dbAdapter, messageContent),
+			repositories.NewMessageRepository(dbAdapter, messageContent))
+}
@@ -0,0 +1,132 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationType is the type of duration settings, parsed as Go durations
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyDefaults sets the settings of a configuration struct to the values of their default tags
+func applyDefaults(v reflect.Value) error {
+	return walkSettings(v, func(field reflect.Value, tag reflect.StructTag) error {
+		if value, ok := tag.Lookup("default"); ok {
+			return setSetting(field, tag, value)
+		}
+		return nil
+	})
+}
+
+// applyEnv sets the settings of a configuration struct whose environment variables, named by
+// their envconfig tags, are set. Other settings keep their values.
+func applyEnv(v reflect.Value) error {
+	return walkSettings(v, func(field reflect.Value, tag reflect.StructTag) error {
+		if key := tag.Get("envconfig"); key != "" {
+			if value, ok := os.LookupEnv(key); ok {
+				return setSetting(field, tag, value)
+			}
+		}
+		return nil
+	})
+}
+
+// checkRequired checks the settings with a required tag are set
+func checkRequired(v reflect.Value) error {
+	return walkSettings(v, func(field reflect.Value, tag reflect.StructTag) error {
+		missing := field.IsZero() || (field.Kind() == reflect.Slice && field.Len() == 0)
+		if tag.Get("required") == "true" && missing {
+			return fmt.Errorf("required setting %s is missing", settingName(tag))
+		}
+		return nil
+	})
+}
+
+// walkSettings calls fn with every setting of a configuration struct and its nested structs.
+// Settings tagged ignored are only read from files.
+func walkSettings(v reflect.Value, fn func(field reflect.Value, tag reflect.StructTag) error) error {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("ignored") == "true" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			if err := walkSettings(v.Field(i), fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(v.Field(i), field.Tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setSetting parses a value into a setting. Lists are comma-separated.
+func setSetting(field reflect.Value, tag reflect.StructTag, value string) error {
+	if err := parseSetting(field, value); err != nil {
+		return fmt.Errorf("invalid value %q of %s: %w", value, settingName(tag), err)
+	}
+	return nil
+}
+
+// parseSetting parses a value into a setting of a supported type
+func parseSetting(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+		var items []string
+		if strings.TrimSpace(value) != "" {
+			items = strings.Split(value, ",")
+		}
+		field.Set(reflect.ValueOf(items).Convert(field.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// settingName names a setting by its environment variable, or its YAML key
+func settingName(tag reflect.StructTag) string {
+	if key := tag.Get("envconfig"); key != "" {
+		return key
+	}
+	return tag.Get("yaml")
+}
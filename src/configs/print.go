@@ -0,0 +1,94 @@
+package configs
+
+import (
+	"io"
+	"net/url"
+	"reflect"
+
+	"gopkg.in/yaml.v2"
+)
+
+// redactedValue replaces the values of secret settings
+const redactedValue = "[REDACTED]"
+
+// Print writes the configuration as YAML with its secrets redacted
+func Print(w io.Writer, config Config) error {
+	data, err := yaml.Marshal(Redacted(config))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Redacted returns a copy of the configuration with the settings tagged secret, and the
+// passwords of URLs, redacted
+func Redacted(config Config) Config {
+	redact(reflect.ValueOf(&config).Elem())
+	return config
+}
+
+// redact redacts the secrets of a value in place. Maps and slices are replaced by redacted
+// copies, as they are shared with the configuration being copied.
+func redact(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("secret") == "true" && v.Field(i).Kind() == reflect.String {
+				if v.Field(i).String() != "" {
+					v.Field(i).SetString(redactedValue)
+				}
+				continue
+			}
+			if field.Tag.Get("secret") == "true" && v.Field(i).Type() == reflect.TypeOf([]string(nil)) {
+				if values := v.Field(i).Interface().([]string); values != nil {
+					redactedValues := make([]string, len(values))
+					for j, value := range values {
+						if value != "" {
+							redactedValues[j] = redactedValue
+						}
+					}
+					v.Field(i).Set(reflect.ValueOf(redactedValues))
+				}
+				continue
+			}
+			redact(v.Field(i))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		// Map values are not addressable, so they are redacted as copies
+		redactedMap := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(iter.Value())
+			redact(value)
+			redactedMap.SetMapIndex(iter.Key(), value)
+		}
+		v.Set(redactedMap)
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		redactedSlice := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(redactedSlice, v)
+		for i := 0; i < redactedSlice.Len(); i++ {
+			redact(redactedSlice.Index(i))
+		}
+		v.Set(redactedSlice)
+	case reflect.String:
+		// URLs such as those of brokers may carry credentials
+		if u, err := url.Parse(v.String()); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				v.SetString(u.Redacted())
+			}
+		}
+	}
+}
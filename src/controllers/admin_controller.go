@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// AdminController handles HTTP requests for operational administration
+type AdminController struct {
+	maintenance       *middlewares.MaintenanceState
+	drain             *middlewares.DrainState
+	deadLetterService services.DeadLetterService
+	messageService    services.MessageService
+	retentionService  services.RetentionService
+	auditService      services.AuditService
+	evaluationService services.EvaluationService
+}
+
+// NewAdminController creates a new admin controller
+func NewAdminController(
+	maintenance *middlewares.MaintenanceState,
+	drain *middlewares.DrainState,
+	deadLetterService services.DeadLetterService,
+	messageService services.MessageService,
+	retentionService services.RetentionService,
+	auditService services.AuditService,
+	evaluationService services.EvaluationService,
+) *AdminController {
+	return &AdminController{
+		maintenance:       maintenance,
+		drain:             drain,
+		deadLetterService: deadLetterService,
+		messageService:    messageService,
+		retentionService:  retentionService,
+		auditService:      auditService,
+		evaluationService: evaluationService,
+	}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *AdminController) RegisterRoutes(router *gin.RouterGroup) {
+	admin := router.Group("/admin")
+	admin.Use(middlewares.RequireRole(models.RoleAdmin), middlewares.RequireScope(middlewares.ScopeAdmin))
+	{
+		admin.GET("/loglevel", c.GetLogLevel)
+		admin.PUT("/loglevel", c.SetLogLevel)
+		admin.GET("/maintenance", c.GetMaintenance)
+		admin.PUT("/maintenance", c.SetMaintenance)
+		admin.POST("/drain", c.Drain)
+		admin.GET("/dlq", c.ListDeadLetters)
+		admin.POST("/dlq/:id/replay", c.ReplayDeadLetter)
+		admin.POST("/announcements", c.BroadcastAnnouncement)
+		admin.POST("/retention/purge", c.PurgeRetention)
+		admin.POST("/impersonations", c.StartImpersonation)
+		admin.GET("/audit", c.ListAuditLog)
+		admin.GET("/stats", c.GetStats)
+	}
+}
+
+// GetLogLevel handles getting the current log level
+func (c *AdminController) GetLogLevel(ctx *gin.Context) {
+	respond(ctx, http.StatusOK, dtos.LogLevelResponse{Level: logger.GetLevel()})
+}
+
+// SetLogLevel handles changing the log level at runtime
+func (c *AdminController) SetLogLevel(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.LogLevelRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse log level request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	previous := logger.GetLevel()
+	if err := logger.SetLevel(req.Level); err != nil {
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid log level"))
+		return
+	}
+
+	log.Warnw("Log level changed", "from", previous, "to", req.Level, "userID", getUserIDFromContext(ctx))
+	respond(ctx, http.StatusOK, dtos.LogLevelResponse{Level: logger.GetLevel()})
+}
+
+// GetMaintenance handles getting the maintenance mode state
+func (c *AdminController) GetMaintenance(ctx *gin.Context) {
+	respond(ctx, http.StatusOK, c.maintenanceResponse())
+}
+
+// SetMaintenance handles toggling maintenance mode
+func (c *AdminController) SetMaintenance(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.MaintenanceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse maintenance request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	c.maintenance.Set(*req.Enabled, time.Duration(req.RetryAfterSeconds)*time.Second, req.Message)
+
+	log.Warnw("Maintenance mode changed", "enabled", *req.Enabled, "userID", getUserIDFromContext(ctx))
+	respond(ctx, http.StatusOK, c.maintenanceResponse())
+}
+
+// maintenanceResponse converts the maintenance state to a response DTO
+func (c *AdminController) maintenanceResponse() dtos.MaintenanceResponse {
+	enabled, retryAfter, message := c.maintenance.Get()
+	return dtos.MaintenanceResponse{
+		Enabled:           enabled,
+		RetryAfterSeconds: int(retryAfter.Seconds()),
+		Message:           message,
+	}
+}
+
+// Drain handles taking the server out of rotation before it stops. The response is sent once
+// readiness has been reported as false for the readiness delay, so a preStop hook calling it
+// returns after load balancers have stopped routing new requests to the server.
+func (c *AdminController) Drain(ctx *gin.Context) {
+	logger.Context(ctx.Request.Context()).Warnw("Server draining", "userID", getUserIDFromContext(ctx))
+
+	c.drain.Drain(ctx.Request.Context())
+	respond(ctx, http.StatusOK, dtos.DrainResponse{Draining: c.drain.Draining()})
+}
+
+// ListDeadLetters handles listing messages routed to dead-letter topics
+func (c *AdminController) ListDeadLetters(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request parameters
+	var req dtos.ListDeadLettersRequest
+	if err := bindListQuery(ctx, &req); err != nil {
+		log.Errorw("Failed to parse list dead letters request", "error", err)
+		respondError(ctx, err)
+		return
+	}
+
+	response, err := c.deadLetterService.List(ctx.Request.Context(), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, response)
+}
+
+// ReplayDeadLetter handles republishing a dead letter to its original topic
+func (c *AdminController) ReplayDeadLetter(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse dead letter ID from path
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Errorw("Invalid dead letter ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid dead letter ID"))
+		return
+	}
+
+	response, err := c.deadLetterService.Replay(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, response)
+}
+
+// BroadcastAnnouncement handles posting a system message into user chats
+func (c *AdminController) BroadcastAnnouncement(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.AnnouncementRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse announcement request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	response, err := c.messageService.BroadcastAnnouncement(ctx.Request.Context(), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	log.Warnw("Announcement broadcast", "delivered", response.Delivered, "userID", getUserIDFromContext(ctx))
+	respond(ctx, http.StatusCreated, response)
+}
+
+// PurgeRetention handles running the retention purge, or reporting what it would delete on a dry run
+func (c *AdminController) PurgeRetention(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request parameters
+	var req dtos.PurgeRetentionRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse retention purge request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	report, err := c.retentionService.Purge(ctx.Request.Context(), req.DryRun)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	log.Warnw("Retention purge triggered", "dryRun", req.DryRun, "messages", report.Messages, "userID", getUserIDFromContext(ctx))
+	respond(ctx, http.StatusOK, report)
+}
+
+// StartImpersonation handles issuing a token acting as a user on behalf of the admin
+func (c *AdminController) StartImpersonation(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.ImpersonationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse impersonation request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	response, err := c.auditService.StartImpersonation(ctx.Request.Context(), getUserIDFromContext(ctx), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, response)
+}
+
+// ListAuditLog handles listing the actions admins took on behalf of users
+func (c *AdminController) ListAuditLog(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request parameters
+	var req dtos.ListAuditLogsRequest
+	if err := bindListQuery(ctx, &req); err != nil {
+		log.Errorw("Failed to parse list audit log request", "error", err)
+		respondError(ctx, err)
+		return
+	}
+
+	response, err := c.auditService.List(ctx.Request.Context(), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, response)
+}
+
+// GetStats handles getting the statistics of the service, the conversation quality trends
+func (c *AdminController) GetStats(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request parameters
+	var req dtos.AdminStatsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse stats request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	response, err := c.evaluationService.GetStats(ctx.Request.Context(), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, response)
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// AttachmentController handles HTTP requests for message attachments
+type AttachmentController struct {
+	attachmentService services.AttachmentService
+}
+
+// NewAttachmentController creates a new message attachment controller
+func NewAttachmentController(attachmentService services.AttachmentService) *AttachmentController {
+	return &AttachmentController{attachmentService: attachmentService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *AttachmentController) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/messages/:id/attachments", requireRead, c.ListAttachments)
+	router.POST("/messages/:id/attachments", requireWrite, c.CreateUpload)
+	router.POST("/messages/:id/attachments/:attachmentId/complete", requireWrite, c.CompleteUpload)
+}
+
+// RegisterUploadRoute registers the route storing files through signed upload URLs. It is
+// not versioned and is served without authentication, the URL signature granting access.
+func (c *AttachmentController) RegisterUploadRoute(router gin.IRouter) {
+	router.PUT(adapters.UploadPath, c.Upload)
+}
+
+// ListAttachments handles listing the attachments of a message with their download URLs
+func (c *AttachmentController) ListAttachments(ctx *gin.Context) {
+	userID, messageID, ok := parseMessageRequest(ctx)
+	if !ok {
+		return
+	}
+
+	attachments, err := c.attachmentService.ListAttachments(ctx.Request.Context(), userID, messageID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, attachments)
+}
+
+// CreateUpload handles creating a pending attachment on a message, responding with the
+// signed URL its file is uploaded to
+func (c *AttachmentController) CreateUpload(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	userID, messageID, ok := parseMessageRequest(ctx)
+	if !ok {
+		return
+	}
+
+	var req dtos.CreateUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse upload request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	upload, err := c.attachmentService.CreateUpload(ctx.Request.Context(), userID, messageID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, upload)
+}
+
+// CompleteUpload handles marking an attachment as uploaded once its file is in the storage
+func (c *AttachmentController) CompleteUpload(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	userID, messageID, ok := parseMessageRequest(ctx)
+	if !ok {
+		return
+	}
+
+	// Parse attachment ID from path
+	attachmentIDStr := ctx.Param("attachmentId")
+	attachmentID, err := strconv.ParseInt(attachmentIDStr, 10, 64)
+	if err != nil {
+		log.Errorw("Invalid attachment ID", "id", attachmentIDStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid attachment ID"))
+		return
+	}
+
+	attachment, err := c.attachmentService.CompleteUpload(ctx.Request.Context(), userID, messageID, attachmentID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, attachment)
+}
+
+// Upload handles storing a file through a signed upload URL. The file is the body of the
+// request.
+func (c *AttachmentController) Upload(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	var req dtos.UploadRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse upload request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	if _, err := c.attachmentService.Upload(ctx.Request.Context(), &req, ctx.Request.Body); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// parseMessageRequest returns the current user and the message ID from the path,
+// responding with an error when either is missing
+func parseMessageRequest(ctx *gin.Context) (string, int64, bool) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return "", 0, false
+	}
+
+	// Parse message ID from path
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Errorw("Invalid message ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid message ID"))
+		return "", 0, false
+	}
+
+	return userID, id, true
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+)
+
+// AuthController handles HTTP requests for cookie-based sessions
+type AuthController struct {
+	config configs.JWT
+}
+
+// NewAuthController creates a new auth controller
+func NewAuthController(config configs.JWT) *AuthController {
+	return &AuthController{config: config}
+}
+
+// RegisterRoutes registers the controller routes with the router. Sessions are
+// only available when tokens may be read from the cookie.
+func (c *AuthController) RegisterRoutes(router *gin.RouterGroup) {
+	if c.config.Mode == middlewares.AuthModeHeader {
+		return
+	}
+
+	auth := router.Group("/auth")
+	{
+		auth.POST("/login", c.Login)
+		auth.POST("/logout", c.Logout)
+	}
+}
+
+// PublicPaths returns the paths of the controller served without authentication
+// under the given route prefix
+func (c *AuthController) PublicPaths(prefix string) []string {
+	return []string{prefix + "/auth/login", prefix + "/auth/logout"}
+}
+
+// Login handles exchanging a JWT for a secure, HttpOnly session cookie
+func (c *AuthController) Login(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Other sites could otherwise log the browser into an account of theirs
+	if !middlewares.SameOrigin(ctx, c.config.Cookie.AllowedOrigins) {
+		log.Warnw("Cross-site login request", "origin", ctx.GetHeader("Origin"))
+		respondError(ctx, errors.New(errors.ErrForbidden, "Cross-site request rejected"))
+		return
+	}
+
+	// Parse request
+	var req dtos.LoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse login request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	claims, err := middlewares.ParseToken(req.Token, c.config.Secret)
+	if err != nil {
+		log.Warnw("Invalid login token", "error", err)
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "Invalid or expired authentication token"))
+		return
+	}
+
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "Invalid user identification"))
+		return
+	}
+
+	// The cookie lives as long as the token, or ExpiresIn for tokens without expiry
+	expiresAt := time.Now().Add(c.config.ExpiresIn)
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		expiresAt = exp.Time
+	}
+
+	c.setCookie(ctx, req.Token, int(time.Until(expiresAt).Seconds()))
+
+	log.Infow("Session started", "userID", userID)
+	respond(ctx, http.StatusOK, dtos.SessionResponse{UserID: userID, ExpiresAt: expiresAt})
+}
+
+// Logout handles clearing the session cookie
+func (c *AuthController) Logout(ctx *gin.Context) {
+	if !middlewares.SameOrigin(ctx, c.config.Cookie.AllowedOrigins) {
+		respondError(ctx, errors.New(errors.ErrForbidden, "Cross-site request rejected"))
+		return
+	}
+	c.setCookie(ctx, "", -1)
+	ctx.Status(http.StatusNoContent)
+}
+
+// setCookie sets the session cookie; a negative maxAge deletes it
+func (c *AuthController) setCookie(ctx *gin.Context, value string, maxAge int) {
+	cookie := c.config.Cookie
+	ctx.SetSameSite(sameSite(cookie.SameSite))
+	ctx.SetCookie(cookie.Name, value, maxAge, cookie.Path, cookie.Domain, cookie.Secure, true)
+}
+
+// sameSite converts a configured SameSite value to its cookie attribute
+func sameSite(value string) http.SameSite {
+	switch value {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
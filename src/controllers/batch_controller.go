@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// BatchController handles HTTP requests for batch prompts
+type BatchController struct {
+	batchService services.BatchService
+}
+
+// NewBatchController creates a new batch controller
+func NewBatchController(batchService services.BatchService) *BatchController {
+	return &BatchController{batchService: batchService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *BatchController) RegisterRoutes(router *gin.RouterGroup) {
+	batches := router.Group("/batch")
+	{
+		batches.POST("", requireSend, c.CreateBatch)
+		batches.GET("/:id", requireRead, c.GetBatch)
+		batches.GET("/:id/items", requireRead, c.ListItems)
+	}
+}
+
+// CreateBatch handles queueing a list of prompts of the current user
+func (c *BatchController) CreateBatch(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.BatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse batch request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	batch, err := c.batchService.CreateBatch(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, batch)
+}
+
+// GetBatch handles getting the progress of a batch
+func (c *BatchController) GetBatch(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	id, ok := parseBatchID(ctx)
+	if !ok {
+		return
+	}
+
+	batch, err := c.batchService.GetBatch(ctx.Request.Context(), userID, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, batch)
+}
+
+// ListItems handles listing the results of the prompts of a batch
+func (c *BatchController) ListItems(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	id, ok := parseBatchID(ctx)
+	if !ok {
+		return
+	}
+
+	var req dtos.ListBatchItemsRequest
+	if err := bindListQuery(ctx, &req); err != nil {
+		log.Errorw("Failed to parse batch items request", "error", err)
+		respondError(ctx, err)
+		return
+	}
+
+	items, err := c.batchService.ListItems(ctx.Request.Context(), userID, id, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, items)
+}
+
+// parseBatchID parses the batch ID path parameter, responding with an error when invalid
+func parseBatchID(ctx *gin.Context) (int64, bool) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		logger.Context(ctx.Request.Context()).Errorw("Invalid batch ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid batch ID"))
+		return 0, false
+	}
+	return id, true
+}
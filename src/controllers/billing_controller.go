@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// BillingController handles HTTP requests for the reconciliation of billable exchanges, and
+// of the usage recorded with the usage of the LLM vendor
+type BillingController struct {
+	billingService services.BillingService
+}
+
+// NewBillingController creates a new billing controller
+func NewBillingController(billingService services.BillingService) *BillingController {
+	return &BillingController{billingService: billingService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *BillingController) RegisterRoutes(router *gin.RouterGroup) {
+	billing := router.Group("/admin/billing")
+	billing.Use(middlewares.RequireRole(models.RoleAdmin), middlewares.RequireScope(middlewares.ScopeAdmin))
+	{
+		billing.GET("/exchanges", c.ListExchanges)
+		billing.GET("/reconciliation", c.GetReconciliation)
+		billing.POST("/reconciliation/import", c.ImportUsage)
+	}
+}
+
+// ListExchanges handles listing the billable exchanges of a period
+func (c *BillingController) ListExchanges(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.ListBillingExchangesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse billing exchanges request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	exchanges, err := c.billingService.ListExchanges(ctx.Request.Context(), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, exchanges)
+}
+
+// GetReconciliation handles reporting the usage reconciliations of a period
+func (c *BillingController) GetReconciliation(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.UsageReconciliationRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse usage reconciliation request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	report, err := c.billingService.GetReconciliation(ctx.Request.Context(), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, report)
+}
+
+// ImportUsage handles reconciling a CSV usage export of the vendor, uploaded as the "file"
+// field of a multipart form
+func (c *BillingController) ImportUsage(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse the export upload
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		log.Errorw("Failed to parse usage export upload", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Errorw("Failed to open usage export upload", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+	defer file.Close()
+
+	result, err := c.billingService.ImportUsage(ctx.Request.Context(), file)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, result)
+}
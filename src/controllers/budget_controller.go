@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// BudgetController handles HTTP requests for monthly cost budgets
+type BudgetController struct {
+	budgetService services.BudgetService
+}
+
+// NewBudgetController creates a new budget controller
+func NewBudgetController(budgetService services.BudgetService) *BudgetController {
+	return &BudgetController{budgetService: budgetService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *BudgetController) RegisterRoutes(router *gin.RouterGroup) {
+	budget := router.Group("/budget")
+	{
+		budget.GET("", requireRead, c.GetBudget)
+		budget.PUT("", requireWrite, c.SetBudget)
+		budget.DELETE("", requireWrite, c.DeleteBudget)
+	}
+
+	// The service-wide budget caps the spending of all users together
+	global := router.Group("/admin/budget")
+	global.Use(middlewares.RequireRole(models.RoleAdmin), middlewares.RequireScope(middlewares.ScopeAdmin))
+	{
+		global.GET("", c.GetGlobalBudget)
+		global.PUT("", c.SetGlobalBudget)
+		global.DELETE("", c.DeleteGlobalBudget)
+	}
+}
+
+// GetBudget handles getting the spending of the current user against their budget
+func (c *BudgetController) GetBudget(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	c.getBudget(ctx, userID)
+}
+
+// SetBudget handles setting the budget of the current user
+func (c *BudgetController) SetBudget(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+	if models.IsGuest(userID) {
+		respondError(ctx, errors.New(errors.ErrForbidden, "Guest sessions cannot set a budget"))
+		return
+	}
+
+	c.setBudget(ctx, userID)
+}
+
+// DeleteBudget handles removing the budget of the current user
+func (c *BudgetController) DeleteBudget(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	c.deleteBudget(ctx, userID)
+}
+
+// GetGlobalBudget handles getting the spending of the service against its budget
+func (c *BudgetController) GetGlobalBudget(ctx *gin.Context) {
+	c.getBudget(ctx, "")
+}
+
+// SetGlobalBudget handles setting the budget of the service
+func (c *BudgetController) SetGlobalBudget(ctx *gin.Context) {
+	c.setBudget(ctx, "")
+}
+
+// DeleteGlobalBudget handles removing the budget of the service
+func (c *BudgetController) DeleteGlobalBudget(ctx *gin.Context) {
+	c.deleteBudget(ctx, "")
+}
+
+// getBudget responds with the spending against the budget of a user, or of the service when userID is empty
+func (c *BudgetController) getBudget(ctx *gin.Context, userID string) {
+	budget, err := c.budgetService.GetBudget(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, budget)
+}
+
+// setBudget sets the budget of a user, or of the service when userID is empty
+func (c *BudgetController) setBudget(ctx *gin.Context, userID string) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.BudgetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse budget request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	budget, err := c.budgetService.SetBudget(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, budget)
+}
+
+// deleteBudget removes the budget of a user, or of the service when userID is empty
+func (c *BudgetController) deleteBudget(ctx *gin.Context, userID string) {
+	if err := c.budgetService.DeleteBudget(ctx.Request.Context(), userID); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
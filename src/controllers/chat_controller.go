@@ -8,6 +8,8 @@ import (
 	"github.com/nvnamsss/chat/src/dtos"
 	"github.com/nvnamsss/chat/src/errors"
 	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
 	"github.com/nvnamsss/chat/src/services"
 )
 
@@ -25,14 +27,23 @@ func NewChatController(chatService services.ChatService) *ChatController {
 
 // RegisterRoutes registers the controller routes with the router
 func (c *ChatController) RegisterRoutes(router *gin.RouterGroup) {
+	// Routes of a chat load it once, checking that it belongs to the user
+	loadChat := middlewares.LoadChat(c.chatService, respondError)
+	loadChatOrAdmin := middlewares.LoadChatOrAdmin(c.chatService, respondError)
+
 	chats := router.Group("/chats")
 	{
-		chats.POST("", c.CreateChat)
-		chats.GET("", c.ListChats)
-		chats.GET("/search", c.SearchChats)
-		chats.GET("/:id", c.GetChat)
-		chats.PUT("/:id", c.UpdateChat)
-		chats.DELETE("/:id", c.DeleteChat)
+		chats.POST("", requireWrite, c.CreateChat)
+		chats.GET("", requireRead, c.ListChats)
+		chats.GET("/search", requireRead, c.SearchChats)
+		chats.GET("/stats", requireRead, c.GetStats)
+		chats.GET("/:id", requireRead, loadChat, c.GetChat)
+		chats.PUT("/:id", requireWrite, loadChat, c.UpdateChat)
+		chats.DELETE("/:id", requireWrite, loadChat, c.DeleteChat)
+		chats.POST("/:id/undo-delete", requireWrite, loadChat, c.UndoDeleteChat)
+		chats.PUT("/:id/lock", requireWrite, loadChatOrAdmin, c.LockChat)
+		chats.DELETE("/:id/lock", requireWrite, loadChatOrAdmin, c.UnlockChat)
+		chats.POST("/:id/transfer", requireWrite, loadChatOrAdmin, c.TransferChat)
 	}
 }
 
@@ -62,43 +73,12 @@ func (c *ChatController) CreateChat(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, chat)
+	respond(ctx, createdStatus(ctx), chat)
 }
 
 // GetChat handles getting a single chat by ID
 func (c *ChatController) GetChat(ctx *gin.Context) {
-	log := logger.Context(ctx.Request.Context())
-
-	// Get user ID from JWT token
-	userID := getUserIDFromContext(ctx)
-	if userID == "" {
-		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
-		return
-	}
-
-	// Parse chat ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		log.Errorw("Invalid chat ID", "id", idStr, "error", err)
-		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid chat ID"))
-		return
-	}
-
-	// Get chat
-	chat, err := c.chatService.GetChat(ctx.Request.Context(), id)
-	if err != nil {
-		respondError(ctx, err)
-		return
-	}
-
-	// Verify the user owns the chat
-	if chat.UserID != userID {
-		respondError(ctx, errors.New(errors.ErrForbidden, "User does not have access to this chat"))
-		return
-	}
-
-	ctx.JSON(http.StatusOK, chat)
+	respond(ctx, http.StatusOK, middlewares.LoadedChat(ctx))
 }
 
 // ListChats handles listing chats for the authenticated user
@@ -111,24 +91,26 @@ func (c *ChatController) ListChats(ctx *gin.Context) {
 	}
 
 	// Parse pagination parameters
-	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
-	if err != nil || limit < 1 {
-		limit = 10
+	var page dtos.PageRequest
+	if err := bindListQuery(ctx, &page); err != nil {
+		respondError(ctx, err)
+		return
 	}
 
-	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
-	if err != nil || offset < 0 {
-		offset = 0
+	// Counting is skipped only when explicitly disabled
+	count, err := strconv.ParseBool(ctx.DefaultQuery("count", "true"))
+	if err != nil {
+		count = true
 	}
 
 	// Get chats
-	response, err := c.chatService.ListChats(ctx.Request.Context(), userID, limit, offset)
+	response, err := c.chatService.ListChats(ctx.Request.Context(), userID, page.Limit, page.Offset, count)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	respond(ctx, http.StatusOK, response)
 }
 
 // SearchChats handles searching chats by title
@@ -144,9 +126,9 @@ func (c *ChatController) SearchChats(ctx *gin.Context) {
 
 	// Parse search parameters
 	var req dtos.SearchChatsRequest
-	if err := ctx.ShouldBindQuery(&req); err != nil {
+	if err := bindListQuery(ctx, &req); err != nil {
 		log.Errorw("Failed to parse search request", "error", err)
-		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid search parameters"))
+		respondError(ctx, err)
 		return
 	}
 
@@ -157,41 +139,13 @@ func (c *ChatController) SearchChats(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	respond(ctx, http.StatusOK, response)
 }
 
 // UpdateChat handles updating a chat
 func (c *ChatController) UpdateChat(ctx *gin.Context) {
 	log := logger.Context(ctx.Request.Context())
-
-	// Get user ID from JWT token
-	userID := getUserIDFromContext(ctx)
-	if userID == "" {
-		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
-		return
-	}
-
-	// Parse chat ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		log.Errorw("Invalid chat ID", "id", idStr, "error", err)
-		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid chat ID"))
-		return
-	}
-
-	// Get existing chat to verify ownership
-	existingChat, err := c.chatService.GetChat(ctx.Request.Context(), id)
-	if err != nil {
-		respondError(ctx, err)
-		return
-	}
-
-	// Verify the user owns the chat
-	if existingChat.UserID != userID {
-		respondError(ctx, errors.New(errors.ErrForbidden, "User does not have access to this chat"))
-		return
-	}
+	id := middlewares.LoadedChat(ctx).ID
 
 	// Parse request
 	var req dtos.ChatRequest
@@ -208,49 +162,79 @@ func (c *ChatController) UpdateChat(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, chat)
+	respond(ctx, http.StatusOK, chat)
 }
 
-// DeleteChat handles deleting a chat
+// DeleteChat handles deleting a chat, which stays restorable for the grace period
 func (c *ChatController) DeleteChat(ctx *gin.Context) {
-	log := logger.Context(ctx.Request.Context())
+	// Delete chat
+	chat, err := c.chatService.RequestDeleteChat(ctx.Request.Context(), middlewares.LoadedChat(ctx).ID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
 
-	// Get user ID from JWT token
-	userID := getUserIDFromContext(ctx)
-	if userID == "" {
-		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+	// Without a grace period the chat is already gone
+	if chat == nil {
+		ctx.Status(http.StatusNoContent)
 		return
 	}
+	respond(ctx, http.StatusAccepted, chat)
+}
 
-	// Parse chat ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+// UndoDeleteChat handles restoring a deleted chat before its grace period ends
+func (c *ChatController) UndoDeleteChat(ctx *gin.Context) {
+	chat, err := c.chatService.UndoDeleteChat(ctx.Request.Context(), middlewares.LoadedChat(ctx).ID)
 	if err != nil {
-		log.Errorw("Invalid chat ID", "id", idStr, "error", err)
-		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid chat ID"))
+		respondError(ctx, err)
 		return
 	}
 
-	// Get existing chat to verify ownership
-	existingChat, err := c.chatService.GetChat(ctx.Request.Context(), id)
+	respond(ctx, http.StatusOK, chat)
+}
+
+// LockChat handles making a chat read-only
+func (c *ChatController) LockChat(ctx *gin.Context) {
+	c.setLocked(ctx, true)
+}
+
+// UnlockChat handles making a locked chat writable again
+func (c *ChatController) UnlockChat(ctx *gin.Context) {
+	c.setLocked(ctx, false)
+}
+
+// setLocked locks or unlocks a chat of the user, or of any user for admins
+func (c *ChatController) setLocked(ctx *gin.Context, locked bool) {
+	admin := ctx.GetString("role") == models.RoleAdmin
+	chat, err := c.chatService.SetLocked(ctx.Request.Context(), middlewares.LoadedChat(ctx).ID, locked, admin)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	// Verify the user owns the chat
-	if existingChat.UserID != userID {
-		respondError(ctx, errors.New(errors.ErrForbidden, "User does not have access to this chat"))
+	respond(ctx, http.StatusOK, chat)
+}
+
+// TransferChat handles moving a chat to another user, by its owner or an admin
+func (c *ChatController) TransferChat(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+	userID := getUserIDFromContext(ctx)
+
+	// Parse request
+	var req dtos.TransferChatRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse transfer chat request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
 		return
 	}
 
-	// Delete chat
-	if err := c.chatService.DeleteChat(ctx.Request.Context(), id); err != nil {
+	chat, err := c.chatService.TransferChat(ctx.Request.Context(), middlewares.LoadedChat(ctx).ID, userID, &req)
+	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	ctx.Status(http.StatusNoContent)
+	respond(ctx, http.StatusOK, chat)
 }
 
 // getUserIDFromContext extracts the user ID from the JWT token in the context
@@ -269,3 +253,21 @@ func getUserIDFromContext(ctx *gin.Context) string {
 
 	return userID
 }
+
+// GetStats handles getting the activity across the current user's chats
+func (c *ChatController) GetStats(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	response, err := c.chatService.GetStats(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, response)
+}
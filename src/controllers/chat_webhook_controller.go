@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// ChatWebhookController handles HTTP requests for the webhooks of chats
+type ChatWebhookController struct {
+	webhookService services.ChatWebhookService
+}
+
+// NewChatWebhookController creates a new chat webhook controller
+func NewChatWebhookController(webhookService services.ChatWebhookService) *ChatWebhookController {
+	return &ChatWebhookController{webhookService: webhookService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *ChatWebhookController) RegisterRoutes(router *gin.RouterGroup) {
+	webhooks := router.Group("/chats/:id/webhooks")
+	{
+		webhooks.POST("", requireWrite, c.CreateWebhook)
+		webhooks.GET("", requireRead, c.ListWebhooks)
+		webhooks.DELETE("/:webhookId", requireWrite, c.DeleteWebhook)
+	}
+}
+
+// CreateWebhook handles attaching a webhook to a chat
+func (c *ChatWebhookController) CreateWebhook(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req dtos.ChatWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse chat webhook request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	webhook, err := c.webhookService.CreateWebhook(ctx.Request.Context(), userID, chatID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, webhook)
+}
+
+// ListWebhooks handles listing the webhooks of a chat
+func (c *ChatWebhookController) ListWebhooks(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	webhooks, err := c.webhookService.ListWebhooks(ctx.Request.Context(), userID, chatID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, webhooks)
+}
+
+// DeleteWebhook handles removing a webhook of a chat
+func (c *ChatWebhookController) DeleteWebhook(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+	webhookID, ok := parseIDParam(ctx, "webhookId", "webhook")
+	if !ok {
+		return
+	}
+
+	if err := c.webhookService.DeleteWebhook(ctx.Request.Context(), userID, chatID, webhookID); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// CheckpointController handles HTTP requests for the checkpoints of chats
+type CheckpointController struct {
+	checkpointService services.CheckpointService
+}
+
+// NewCheckpointController creates a new checkpoint controller
+func NewCheckpointController(checkpointService services.CheckpointService) *CheckpointController {
+	return &CheckpointController{checkpointService: checkpointService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *CheckpointController) RegisterRoutes(router *gin.RouterGroup) {
+	checkpoints := router.Group("/chats/:id/checkpoints")
+	{
+		checkpoints.POST("", requireWrite, c.CreateCheckpoint)
+		checkpoints.GET("", requireRead, c.ListCheckpoints)
+		checkpoints.POST("/:checkpointId/restore", requireWrite, c.RestoreCheckpoint)
+		checkpoints.DELETE("/:checkpointId", requireWrite, c.DeleteCheckpoint)
+	}
+}
+
+// CreateCheckpoint handles creating a checkpoint of a chat
+func (c *CheckpointController) CreateCheckpoint(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req dtos.CheckpointRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse checkpoint request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	checkpoint, err := c.checkpointService.CreateCheckpoint(ctx.Request.Context(), userID, chatID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, checkpoint)
+}
+
+// ListCheckpoints handles listing the checkpoints of a chat
+func (c *CheckpointController) ListCheckpoints(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	checkpoints, err := c.checkpointService.ListCheckpoints(ctx.Request.Context(), userID, chatID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, checkpoints)
+}
+
+// RestoreCheckpoint handles restoring a checkpoint of a chat into a new chat
+func (c *CheckpointController) RestoreCheckpoint(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+	checkpointID, ok := parseCheckpointID(ctx)
+	if !ok {
+		return
+	}
+
+	// The request body is optional
+	var req dtos.RestoreCheckpointRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Errorw("Failed to parse restore checkpoint request", "error", err)
+			respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+			return
+		}
+	}
+
+	restored, err := c.checkpointService.RestoreCheckpoint(ctx.Request.Context(), userID, chatID, checkpointID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, restored)
+}
+
+// DeleteCheckpoint handles removing a checkpoint of a chat
+func (c *CheckpointController) DeleteCheckpoint(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+	checkpointID, ok := parseCheckpointID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.checkpointService.DeleteCheckpoint(ctx.Request.Context(), userID, chatID, checkpointID); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// parseCheckpointID parses the checkpoint ID path parameter, responding with an error when it is invalid
+func parseCheckpointID(ctx *gin.Context) (int64, bool) {
+	idStr := ctx.Param("checkpointId")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		logger.Context(ctx.Request.Context()).Errorw("Invalid checkpoint ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid checkpoint ID"))
+		return 0, false
+	}
+	return id, true
+}
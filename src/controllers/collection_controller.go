@@ -0,0 +1,391 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// CollectionController handles HTTP requests for document collections and their attachment
+// to chats
+type CollectionController struct {
+	collectionService services.CollectionService
+}
+
+// NewCollectionController creates a new collection controller
+func NewCollectionController(collectionService services.CollectionService) *CollectionController {
+	return &CollectionController{collectionService: collectionService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *CollectionController) RegisterRoutes(router *gin.RouterGroup) {
+	collections := router.Group("/collections")
+	{
+		collections.POST("", requireWrite, c.CreateCollection)
+		collections.GET("", requireRead, c.ListCollections)
+		collections.GET("/:id", requireRead, c.GetCollection)
+		collections.PUT("/:id", requireWrite, c.UpdateCollection)
+		collections.DELETE("/:id", requireWrite, c.DeleteCollection)
+		collections.POST("/:id/reindex", requireWrite, c.Reindex)
+		collections.POST("/:id/documents", requireWrite, c.AddDocument)
+		collections.GET("/:id/documents", requireRead, c.ListDocuments)
+		collections.GET("/:id/documents/:documentId", requireRead, c.GetDocument)
+		collections.DELETE("/:id/documents/:documentId", requireWrite, c.DeleteDocument)
+	}
+
+	chatCollections := router.Group("/chats/:id/collections")
+	{
+		chatCollections.GET("", requireRead, c.ListChatCollections)
+		chatCollections.PUT("/:collectionId", requireWrite, c.AttachCollection)
+		chatCollections.DELETE("/:collectionId", requireWrite, c.DetachCollection)
+	}
+}
+
+// CreateCollection handles creating a document collection
+func (c *CollectionController) CreateCollection(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.CollectionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse collection request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	collection, err := c.collectionService.CreateCollection(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, collection)
+}
+
+// ListCollections handles listing the collections of the user
+func (c *CollectionController) ListCollections(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collections, err := c.collectionService.ListCollections(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, collections)
+}
+
+// GetCollection handles retrieving a collection
+func (c *CollectionController) GetCollection(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+
+	collection, err := c.collectionService.GetCollection(ctx.Request.Context(), userID, collectionID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, collection)
+}
+
+// UpdateCollection handles updating a collection and its chunking
+func (c *CollectionController) UpdateCollection(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req dtos.CollectionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse collection request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	collection, err := c.collectionService.UpdateCollection(ctx.Request.Context(), userID, collectionID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, collection)
+}
+
+// DeleteCollection handles deleting a collection with its documents
+func (c *CollectionController) DeleteCollection(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+
+	if err := c.collectionService.DeleteCollection(ctx.Request.Context(), userID, collectionID); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// Reindex handles queuing the documents of a collection to be chunked again
+func (c *CollectionController) Reindex(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+
+	reindex, err := c.collectionService.Reindex(ctx.Request.Context(), userID, collectionID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, reindex)
+}
+
+// AddDocument handles adding a document to a collection
+func (c *CollectionController) AddDocument(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req dtos.DocumentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse document request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	document, err := c.collectionService.AddDocument(ctx.Request.Context(), userID, collectionID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, document)
+}
+
+// ListDocuments handles listing the documents of a collection
+func (c *CollectionController) ListDocuments(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+
+	documents, err := c.collectionService.ListDocuments(ctx.Request.Context(), userID, collectionID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, documents)
+}
+
+// GetDocument handles retrieving a document of a collection
+func (c *CollectionController) GetDocument(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+	documentID, ok := parseIDParam(ctx, "documentId", "document")
+	if !ok {
+		return
+	}
+
+	document, err := c.collectionService.GetDocument(ctx.Request.Context(), userID, collectionID, documentID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, document)
+}
+
+// DeleteDocument handles deleting a document of a collection
+func (c *CollectionController) DeleteDocument(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+	documentID, ok := parseIDParam(ctx, "documentId", "document")
+	if !ok {
+		return
+	}
+
+	if err := c.collectionService.DeleteDocument(ctx.Request.Context(), userID, collectionID, documentID); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListChatCollections handles listing the collections attached to a chat
+func (c *CollectionController) ListChatCollections(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	collections, err := c.collectionService.ListChatCollections(ctx.Request.Context(), userID, chatID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, collections)
+}
+
+// AttachCollection handles attaching a collection to a chat
+func (c *CollectionController) AttachCollection(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+	collectionID, ok := parseIDParam(ctx, "collectionId", "collection")
+	if !ok {
+		return
+	}
+
+	if err := c.collectionService.AttachCollection(ctx.Request.Context(), userID, chatID, collectionID); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DetachCollection handles detaching a collection from a chat
+func (c *CollectionController) DetachCollection(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+	collectionID, ok := parseIDParam(ctx, "collectionId", "collection")
+	if !ok {
+		return
+	}
+
+	if err := c.collectionService.DetachCollection(ctx.Request.Context(), userID, chatID, collectionID); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// parseIDParam parses an ID path parameter of the named resource, responding with an error
+// when it is invalid
+func parseIDParam(ctx *gin.Context, param, name string) (int64, bool) {
+	idStr := ctx.Param(param)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		logger.Context(ctx.Request.Context()).Errorw("Invalid "+name+" ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid "+name+" ID"))
+		return 0, false
+	}
+	return id, true
+}
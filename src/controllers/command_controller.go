@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// CommandController handles HTTP requests for the slash commands users run, and for the
+// custom commands admins define for the workspace
+type CommandController struct {
+	commandService services.CommandService
+}
+
+// NewCommandController creates a new command controller
+func NewCommandController(commandService services.CommandService) *CommandController {
+	return &CommandController{commandService: commandService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *CommandController) RegisterRoutes(router *gin.RouterGroup) {
+	commands := router.Group("/commands")
+	{
+		commands.GET("", requireRead, c.ListCommands)
+		commands.POST("", requireWrite, c.RunCommand)
+	}
+
+	admin := router.Group("/admin/commands")
+	admin.Use(middlewares.RequireRole(models.RoleAdmin), middlewares.RequireScope(middlewares.ScopeAdmin))
+	{
+		admin.GET("", c.ListCustomCommands)
+		admin.POST("", c.CreateCustomCommand)
+		admin.PUT("/:name", c.UpdateCustomCommand)
+		admin.DELETE("/:name", c.DeleteCustomCommand)
+	}
+}
+
+// ListCommands handles listing the commands available to users
+func (c *CommandController) ListCommands(ctx *gin.Context) {
+	commands, err := c.commandService.ListCommands(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, commands)
+}
+
+// RunCommand handles running a slash command
+func (c *CommandController) RunCommand(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.CommandRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse command request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	result, err := c.commandService.RunCommand(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, result)
+}
+
+// ListCustomCommands handles listing the commands defined for the workspace
+func (c *CommandController) ListCustomCommands(ctx *gin.Context) {
+	commands, err := c.commandService.ListCustomCommands(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, commands)
+}
+
+// CreateCustomCommand handles defining a command for the workspace
+func (c *CommandController) CreateCustomCommand(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	adminID := getUserIDFromContext(ctx)
+	if adminID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.CustomCommandRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse custom command request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	command, err := c.commandService.CreateCustomCommand(ctx.Request.Context(), adminID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, command)
+}
+
+// UpdateCustomCommand handles updating a command of the workspace
+func (c *CommandController) UpdateCustomCommand(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	adminID := getUserIDFromContext(ctx)
+	if adminID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.UpdateCustomCommandRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse custom command request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	command, err := c.commandService.UpdateCustomCommand(ctx.Request.Context(), adminID, ctx.Param("name"), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, command)
+}
+
+// DeleteCustomCommand handles deleting a command of the workspace
+func (c *CommandController) DeleteCustomCommand(ctx *gin.Context) {
+	if err := c.commandService.DeleteCustomCommand(ctx.Request.Context(), ctx.Param("name")); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// ConsentController handles HTTP requests for the data-usage terms users accept
+type ConsentController struct {
+	consentService services.ConsentService
+}
+
+// NewConsentController creates a new consent controller
+func NewConsentController(consentService services.ConsentService) *ConsentController {
+	return &ConsentController{consentService: consentService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *ConsentController) RegisterRoutes(router *gin.RouterGroup) {
+	me := router.Group("/me")
+	{
+		me.GET("/consent", requireRead, c.GetConsent)
+		me.POST("/consent", requireWrite, c.Accept)
+	}
+}
+
+// GetConsent handles getting the current terms and the terms the current user accepted
+func (c *ConsentController) GetConsent(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	consent, err := c.consentService.GetConsent(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, consent)
+}
+
+// Accept handles the current user accepting the current terms
+func (c *ConsentController) Accept(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.ConsentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse consent request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	consent, err := c.consentService.Accept(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, consent)
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// CrawlController handles HTTP requests for crawling websites into collections
+type CrawlController struct {
+	crawlService services.CrawlService
+}
+
+// NewCrawlController creates a new crawl controller
+func NewCrawlController(crawlService services.CrawlService) *CrawlController {
+	return &CrawlController{crawlService: crawlService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *CrawlController) RegisterRoutes(router *gin.RouterGroup) {
+	crawls := router.Group("/collections/:id/crawls")
+	{
+		crawls.POST("", requireWrite, c.StartCrawl)
+		crawls.GET("", requireRead, c.ListCrawls)
+		crawls.GET("/:crawlId", requireRead, c.GetCrawl)
+	}
+}
+
+// StartCrawl handles queuing a crawl of a website into a collection
+func (c *CrawlController) StartCrawl(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req dtos.CrawlRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse crawl request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	crawl, err := c.crawlService.StartCrawl(ctx.Request.Context(), userID, collectionID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, crawl)
+}
+
+// ListCrawls handles listing the crawls of a collection
+func (c *CrawlController) ListCrawls(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+
+	crawls, err := c.crawlService.ListCrawls(ctx.Request.Context(), userID, collectionID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, crawls)
+}
+
+// GetCrawl handles retrieving a crawl and its progress
+func (c *CrawlController) GetCrawl(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	collectionID, ok := parseIDParam(ctx, "id", "collection")
+	if !ok {
+		return
+	}
+	crawlID, ok := parseIDParam(ctx, "crawlId", "crawl")
+	if !ok {
+		return
+	}
+
+	crawl, err := c.crawlService.GetCrawl(ctx.Request.Context(), userID, collectionID, crawlID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, crawl)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// discordMaxBodySize caps the size of interaction requests
+const discordMaxBodySize = 1 << 20
+
+// DiscordController handles the interactions Discord posts to the interactions endpoint of
+// the application
+type DiscordController struct {
+	discordService services.DiscordService
+	discordAdapter adapters.DiscordAdapter
+}
+
+// NewDiscordController creates a new Discord controller
+func NewDiscordController(discordService services.DiscordService, discordAdapter adapters.DiscordAdapter) *DiscordController {
+	return &DiscordController{discordService: discordService, discordAdapter: discordAdapter}
+}
+
+// RegisterRoutes registers the interactions endpoint. It is not versioned and is served
+// without JWT authentication, requests being signed by Discord instead.
+func (c *DiscordController) RegisterRoutes(router gin.IRouter) {
+	router.POST(adapters.DiscordInteractionsPath, c.HandleInteraction)
+}
+
+// HandleInteraction handles an interaction from Discord, verifying its signature over the
+// raw body before parsing it
+func (c *DiscordController) HandleInteraction(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, discordMaxBodySize))
+	if err != nil {
+		log.Errorw("Failed to read Discord interaction", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	if err := c.discordAdapter.Verify(ctx.GetHeader(adapters.DiscordSignatureHeader), ctx.GetHeader(adapters.DiscordTimestampHeader), body); err != nil {
+		log.Warnw("Invalid Discord interaction signature", "error", err)
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "Invalid interaction signature"))
+		return
+	}
+
+	// Parse request
+	var interaction dtos.DiscordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		log.Errorw("Failed to parse Discord interaction", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	response, err := c.discordService.HandleInteraction(ctx.Request.Context(), &interaction)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
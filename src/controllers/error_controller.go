@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+)
+
+// ErrorController handles HTTP requests for the catalog of the error codes of the API
+type ErrorController struct{}
+
+// NewErrorController creates a new error controller
+func NewErrorController() *ErrorController {
+	return &ErrorController{}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *ErrorController) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/errors", c.ListErrors)
+}
+
+// PublicPaths returns the paths of the controller served without authentication
+// under the given route prefix
+func (c *ErrorController) PublicPaths(prefix string) []string {
+	return []string{prefix + "/errors"}
+}
+
+// ListErrors handles listing every error code with its HTTP status and remediation, so
+// clients can handle each of them
+func (c *ErrorController) ListErrors(ctx *gin.Context) {
+	catalog := errors.Catalog()
+	response := dtos.ErrorCatalogResponse{Errors: make([]dtos.ErrorCodeResponse, 0, len(catalog))}
+	for _, definition := range catalog {
+		response.Errors = append(response.Errors, dtos.ErrorCodeResponse{
+			Code:        definition.Code,
+			Status:      definition.Status,
+			Message:     definition.Message,
+			Remediation: definition.Remediation,
+		})
+	}
+
+	respond(ctx, http.StatusOK, response)
+}
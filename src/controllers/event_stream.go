@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/metrics"
+)
+
+// Headers of streamed replies
+const (
+	// HeaderStreamToken carries the token resuming the stream of a reply
+	HeaderStreamToken = "X-Stream-Token"
+	// HeaderLastEventID is sent by reconnecting EventSource clients with the last event they received
+	HeaderLastEventID = "Last-Event-ID"
+)
+
+// eventStream writes server-sent events to a client. Once started, a heartbeat comment is
+// written whenever nothing else was for the heartbeat interval, so that proxies and clients do
+// not take a slow reply for a dead connection.
+type eventStream struct {
+	ctx       *gin.Context
+	heartbeat time.Duration
+	mu        sync.Mutex
+	closed    bool
+	lastWrite time.Time
+	stop      chan struct{}
+}
+
+// newEventStream creates an event stream to the client of a request
+func newEventStream(ctx *gin.Context, heartbeat time.Duration) *eventStream {
+	return &eventStream{ctx: ctx, heartbeat: heartbeat, stop: make(chan struct{})}
+}
+
+// start responds with the event stream headers and starts the heartbeat
+func (s *eventStream) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ctx.Header("Content-Type", "text/event-stream")
+	s.ctx.Header("Cache-Control", "no-cache")
+	s.ctx.Header("X-Accel-Buffering", "no")
+	s.ctx.Status(http.StatusOK)
+	s.ctx.Writer.WriteHeaderNow()
+	s.ctx.Writer.Flush()
+	s.lastWrite = time.Now()
+
+	if s.heartbeat > 0 {
+		go s.beat()
+	}
+}
+
+// send writes an event with a JSON payload, with an ID when id is not empty
+func (s *eventStream) send(id string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		return s.write(fmt.Sprintf("id: %s\ndata: %s\n\n", id, data))
+	}
+	return s.write(fmt.Sprintf("data: %s\n\n", data))
+}
+
+// sendError writes an error event with a JSON payload
+func (s *eventStream) sendError(payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return s.write(fmt.Sprintf("event: error\ndata: %s\n\n", data))
+}
+
+// toStreamAbort converts the error a reply stream failed with after it started to the event
+// ending it, counting the reason, with the failed reply and the token of its stream when
+// known
+func toStreamAbort(ctx *gin.Context, err error, messageID int64, token string) dtos.StreamAbort {
+	response := toErrorResponse(ctx, err)
+	abort := dtos.StreamAbort{
+		Code:        response.Code,
+		Message:     response.Message,
+		Reason:      streamAbortReason(err),
+		Retryable:   true,
+		MessageID:   messageID,
+		StreamToken: token,
+	}
+	if abort.Reason == dtos.StreamAbortContentFiltered {
+		abort.Retryable = false
+	}
+	if retryAfter := errors.RetryAfter(err); retryAfter > 0 {
+		abort.RetryAfterMs = retryAfter.Milliseconds()
+	}
+
+	metrics.StreamsAborted.WithLabelValues(abort.Reason).Inc()
+	return abort
+}
+
+// streamAbortReason classifies the error a reply stream failed with
+func streamAbortReason(err error) string {
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		return dtos.StreamAbortInternal
+	}
+	switch appErr.Code {
+	case errors.ErrTimeout:
+		return dtos.StreamAbortTimeout
+	case errors.ErrInvalidRequest:
+		return dtos.StreamAbortContentFiltered
+	}
+	// Rate limits of the provider are wrapped in the error of the reply
+	for cause := error(appErr); cause != nil; {
+		causeErr, ok := cause.(*errors.AppError)
+		if !ok {
+			break
+		}
+		if causeErr.Code == errors.ErrRateLimited {
+			return dtos.StreamAbortRateLimited
+		}
+		cause = causeErr.Err
+	}
+	if appErr.Code == errors.ErrLLMService {
+		return dtos.StreamAbortProviderError
+	}
+	return dtos.StreamAbortInternal
+}
+
+// done writes the [DONE] event ending the stream
+func (s *eventStream) done() error {
+	return s.write("data: [DONE]\n\n")
+}
+
+// close stops the heartbeat; nothing is written to the client afterwards
+func (s *eventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+}
+
+// write writes raw event text and flushes it to the client
+func (s *eventStream) write(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprint(s.ctx.Writer, text); err != nil {
+		return err
+	}
+	s.ctx.Writer.Flush()
+	s.lastWrite = time.Now()
+	return nil
+}
+
+// beat writes a heartbeat comment whenever nothing was written for the heartbeat interval,
+// until the stream is closed
+func (s *eventStream) beat() {
+	ticker := time.NewTicker(s.heartbeat / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			// The response may be finished once the stream is closed
+			if !s.closed && time.Since(s.lastWrite) >= s.heartbeat {
+				if _, err := fmt.Fprint(s.ctx.Writer, ": heartbeat\n\n"); err == nil {
+					s.ctx.Writer.Flush()
+				}
+				s.lastWrite = time.Now()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
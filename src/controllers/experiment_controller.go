@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// ExperimentController handles HTTP requests for managing prompt and model experiments
+type ExperimentController struct {
+	experimentService services.ExperimentService
+}
+
+// NewExperimentController creates a new experiment controller
+func NewExperimentController(experimentService services.ExperimentService) *ExperimentController {
+	return &ExperimentController{experimentService: experimentService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *ExperimentController) RegisterRoutes(router *gin.RouterGroup) {
+	experiments := router.Group("/admin/experiments")
+	experiments.Use(middlewares.RequireRole(models.RoleAdmin), middlewares.RequireScope(middlewares.ScopeAdmin))
+	{
+		experiments.POST("", c.CreateExperiment)
+		experiments.GET("", c.ListExperiments)
+		experiments.GET("/:id", c.GetExperiment)
+		experiments.POST("/:id/start", c.StartExperiment)
+		experiments.POST("/:id/stop", c.StopExperiment)
+		experiments.GET("/:id/results", c.GetResults)
+	}
+}
+
+// CreateExperiment handles creating a draft experiment
+func (c *ExperimentController) CreateExperiment(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.ExperimentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse experiment request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	experiment, err := c.experimentService.CreateExperiment(ctx.Request.Context(), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, experiment)
+}
+
+// ListExperiments handles listing all experiments
+func (c *ExperimentController) ListExperiments(ctx *gin.Context) {
+	experiments, err := c.experimentService.ListExperiments(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, experiments)
+}
+
+// GetExperiment handles getting an experiment
+func (c *ExperimentController) GetExperiment(ctx *gin.Context) {
+	id, ok := parseExperimentID(ctx)
+	if !ok {
+		return
+	}
+
+	experiment, err := c.experimentService.GetExperiment(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, experiment)
+}
+
+// StartExperiment handles starting a draft experiment
+func (c *ExperimentController) StartExperiment(ctx *gin.Context) {
+	id, ok := parseExperimentID(ctx)
+	if !ok {
+		return
+	}
+
+	experiment, err := c.experimentService.StartExperiment(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	logger.Context(ctx.Request.Context()).Warnw("Experiment started", "id", id, "userID", getUserIDFromContext(ctx))
+	respond(ctx, http.StatusOK, experiment)
+}
+
+// StopExperiment handles stopping a running experiment
+func (c *ExperimentController) StopExperiment(ctx *gin.Context) {
+	id, ok := parseExperimentID(ctx)
+	if !ok {
+		return
+	}
+
+	experiment, err := c.experimentService.StopExperiment(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	logger.Context(ctx.Request.Context()).Warnw("Experiment stopped", "id", id, "userID", getUserIDFromContext(ctx))
+	respond(ctx, http.StatusOK, experiment)
+}
+
+// GetResults handles reporting the metrics of each variant of an experiment
+func (c *ExperimentController) GetResults(ctx *gin.Context) {
+	id, ok := parseExperimentID(ctx)
+	if !ok {
+		return
+	}
+
+	results, err := c.experimentService.GetResults(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, results)
+}
+
+// parseExperimentID parses the experiment ID path parameter, responding with an error when it is invalid
+func parseExperimentID(ctx *gin.Context) (int64, bool) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		logger.Context(ctx.Request.Context()).Errorw("Invalid experiment ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid experiment ID"))
+		return 0, false
+	}
+	return id, true
+}
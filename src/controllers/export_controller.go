@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// ExportController handles HTTP requests for bulk chat exports
+type ExportController struct {
+	exportService services.ExportService
+}
+
+// NewExportController creates a new export controller
+func NewExportController(exportService services.ExportService) *ExportController {
+	return &ExportController{exportService: exportService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *ExportController) RegisterRoutes(router *gin.RouterGroup) {
+	exports := router.Group("/exports")
+	{
+		exports.POST("", requireRead, c.CreateExport)
+		exports.GET("/:id", requireRead, c.GetExport)
+	}
+}
+
+// RegisterDownloadRoute registers the route serving signed download URLs. It is not
+// versioned and is served without authentication, the URL signature granting access.
+func (c *ExportController) RegisterDownloadRoute(router gin.IRouter) {
+	router.GET(adapters.DownloadPath, c.Download)
+}
+
+// CreateExport handles queueing an export of all the current user's chats
+func (c *ExportController) CreateExport(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	export, err := c.exportService.CreateExport(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, export)
+}
+
+// GetExport handles getting the status of an export
+func (c *ExportController) GetExport(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse export ID from path
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Errorw("Invalid export ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid export ID"))
+		return
+	}
+
+	export, err := c.exportService.GetExport(ctx.Request.Context(), userID, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, export)
+}
+
+// Download handles downloading a file through a signed URL
+func (c *ExportController) Download(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	var req dtos.DownloadRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse download request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	file, err := c.exportService.OpenDownload(ctx.Request.Context(), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(req.Key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(req.Key)))
+	ctx.Status(http.StatusOK)
+
+	if _, err := io.Copy(ctx.Writer, file); err != nil {
+		log.Errorw("Failed to send download", "error", err, "key", req.Key)
+	}
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// GuestController handles HTTP requests for anonymous guest sessions
+type GuestController struct {
+	config       configs.Guest
+	secret       string
+	guestService services.GuestService
+}
+
+// NewGuestController creates a new guest controller verifying guest tokens with secret
+func NewGuestController(config configs.Guest, secret string, guestService services.GuestService) *GuestController {
+	return &GuestController{
+		config:       config,
+		secret:       secret,
+		guestService: guestService,
+	}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *GuestController) RegisterRoutes(router *gin.RouterGroup) {
+	if !c.config.Enabled {
+		return
+	}
+
+	guest := router.Group("/guest")
+	{
+		// Sessions are started without authentication, so they are limited per client IP
+		guest.POST("/sessions", middlewares.IPRateLimit(c.config.RequestsPerMinute), c.StartSession)
+		guest.POST("/claim", requireWrite, c.ClaimChats)
+	}
+}
+
+// PublicPaths returns the paths of the controller served without authentication
+// under the given route prefix
+func (c *GuestController) PublicPaths(prefix string) []string {
+	return []string{prefix + "/guest/sessions"}
+}
+
+// StartSession handles starting an anonymous guest session
+func (c *GuestController) StartSession(ctx *gin.Context) {
+	session, err := c.guestService.StartSession(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, session)
+}
+
+// ClaimChats handles moving the chats of a guest session to the authenticated user
+func (c *GuestController) ClaimChats(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+	if ctx.GetString("role") == models.RoleGuest {
+		respondError(ctx, errors.New(errors.ErrForbidden, "Guests cannot claim chats"))
+		return
+	}
+
+	// Parse request
+	var req dtos.ClaimGuestChatsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse claim request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	claims, err := middlewares.ParseToken(req.Token, c.secret)
+	if err != nil {
+		log.Warnw("Invalid guest token", "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid guest token"))
+		return
+	}
+
+	guestID, _ := claims["sub"].(string)
+	response, err := c.guestService.ClaimChats(ctx.Request.Context(), guestID, userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, response)
+}
@@ -1,11 +1,18 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
 	"github.com/nvnamsss/chat/src/errors"
 	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/pkg/dryrun"
+	"github.com/nvnamsss/chat/src/pkg/timing"
 )
 
 // ErrorResponse represents the structure of error responses
@@ -14,30 +21,137 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
+// Envelope is the v2 response body wrapping every payload
+type Envelope struct {
+	Data  interface{}    `json:"data,omitempty"`
+	Error *ErrorResponse `json:"error,omitempty"`
+	Meta  EnvelopeMeta   `json:"meta"`
+}
+
+// EnvelopeMeta holds request metadata returned in the v2 envelope
+type EnvelopeMeta struct {
+	RequestID string `json:"requestId,omitempty"`
+	Version   string `json:"version"`
+	// Warnings tell clients a limit is close, before it rejects their requests
+	Warnings []middlewares.Warning `json:"warnings,omitempty"`
+	// DryRun is set when the request was only checked, without being performed
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// respond sends a response in the format of the request's API version
+func respond(c *gin.Context, statusCode int, data interface{}) {
+	if middlewares.GetAPIVersion(c) == middlewares.APIVersion1 {
+		c.JSON(statusCode, data)
+		return
+	}
+
+	c.JSON(statusCode, Envelope{Data: data, Meta: envelopeMeta(c)})
+}
+
+// envelopeMeta returns the envelope metadata of a request
+func envelopeMeta(c *gin.Context) EnvelopeMeta {
+	return EnvelopeMeta{
+		RequestID: logger.GetRequestID(c.Request.Context()),
+		Version:   middlewares.GetAPIVersion(c),
+		Warnings:  middlewares.GetWarnings(c),
+		DryRun:    dryrun.Enabled(c.Request.Context()),
+	}
+}
+
+// createdStatus returns the status of a response creating a resource: 201, or 200 for a dry
+// run, which creates nothing
+func createdStatus(c *gin.Context) int {
+	if dryrun.Enabled(c.Request.Context()) {
+		return http.StatusOK
+	}
+	return http.StatusCreated
+}
+
+// setServerTiming sets the Server-Timing header of the stages of a request when the client
+// asked for it
+func setServerTiming(c *gin.Context) {
+	if !middlewares.TimingRequested(c) {
+		return
+	}
+	if header := timing.Header(c.Request.Context()); header != "" {
+		c.Header("Server-Timing", header)
+	}
+}
+
 // respondError sends an error response to the client
 func respondError(c *gin.Context, err error) {
-	log := logger.Context(c.Request.Context())
+	statusCode := http.StatusInternalServerError
+	if appErr, ok := err.(*errors.AppError); ok {
+		statusCode = appErr.StatusCode()
+	}
+	errorResponse := toErrorResponse(c, err)
 
-	var statusCode int
-	var errorResponse ErrorResponse
+	if middlewares.GetAPIVersion(c) == middlewares.APIVersion1 {
+		c.JSON(statusCode, errorResponse)
+		return
+	}
+
+	c.JSON(statusCode, Envelope{Error: &errorResponse, Meta: envelopeMeta(c)})
+}
+
+// toErrorResponse converts an error to its response, logging it
+func toErrorResponse(c *gin.Context, err error) ErrorResponse {
+	log := logger.Context(c.Request.Context())
 
 	// Check if this is an application error
 	if appErr, ok := err.(*errors.AppError); ok {
-		statusCode = appErr.StatusCode()
-		errorResponse = ErrorResponse{
+		log.Warnw("Application error", "code", appErr.Code, "message", appErr.Message, "error", appErr.Err)
+		return ErrorResponse{
 			Code:    appErr.Code,
 			Message: appErr.Message,
 		}
-		log.Warnw("Application error", "code", appErr.Code, "message", appErr.Message, "error", appErr.Err)
-	} else {
-		// Unknown error
-		statusCode = http.StatusInternalServerError
-		errorResponse = ErrorResponse{
-			Code:    errors.ErrInternal,
-			Message: "Internal server error",
-		}
-		log.Errorw("Unknown error", "error", err)
 	}
 
-	c.JSON(statusCode, errorResponse)
+	// Unknown error
+	log.Errorw("Unknown error", "error", err)
+	return ErrorResponse{
+		Code:    errors.ErrInternal,
+		Message: "Internal server error",
+	}
+}
+
+// parseChatID parses the chat ID path parameter, responding with an error when it is invalid
+func parseChatID(ctx *gin.Context) (int64, bool) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		logger.Context(ctx.Request.Context()).Errorw("Invalid chat ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid chat ID"))
+		return 0, false
+	}
+	return id, true
+}
+
+// pagedRequest is a list request embedding dtos.PageRequest
+type pagedRequest interface {
+	Page() *dtos.PageRequest
+}
+
+// bindListQuery binds the query parameters of a list request. The limit defaults to
+// api.defaultLimit; limits over api.maxLimit and negative ones or offsets are rejected.
+func bindListQuery(ctx *gin.Context, req pagedRequest) error {
+	if err := ctx.ShouldBindQuery(req); err != nil {
+		return errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format")
+	}
+
+	config := configs.AppConfig.API
+	page := req.Page()
+	switch {
+	case page.Limit < 0:
+		return errors.New(errors.ErrInvalidRequest, "limit must be positive")
+	case config.MaxLimit > 0 && page.Limit > config.MaxLimit:
+		return errors.New(errors.ErrInvalidRequest, fmt.Sprintf("limit must be at most %d", config.MaxLimit))
+	case page.Offset < 0:
+		return errors.New(errors.ErrInvalidRequest, "offset must not be negative")
+	}
+	if page.Limit == 0 {
+		page.Limit = config.DefaultLimit
+	}
+
+	return nil
 }
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// ImageController handles HTTP requests for image generation in chats
+type ImageController struct {
+	imageService services.ImageService
+}
+
+// NewImageController creates a new image generation controller
+func NewImageController(imageService services.ImageService) *ImageController {
+	return &ImageController{imageService: imageService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *ImageController) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/messages/image", requireSend, c.GenerateImages)
+}
+
+// GenerateImages handles generating images of a prompt in a chat. The images are
+// attachments of the assistant message answering the prompt.
+func (c *ImageController) GenerateImages(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse chat ID from query parameter
+	chatIDStr := ctx.Query("chatId")
+	if chatIDStr == "" {
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Missing chat ID"))
+		return
+	}
+
+	chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+	if err != nil {
+		log.Errorw("Invalid chat ID", "chatID", chatIDStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid chat ID"))
+		return
+	}
+
+	var req dtos.ImageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse image request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	exchange, err := c.imageService.GenerateImages(ctx.Request.Context(), chatID, userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, exchange)
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// InstructionController handles HTTP requests for the custom instructions layered into prompts
+type InstructionController struct {
+	instructionService services.InstructionService
+}
+
+// NewInstructionController creates a new instruction controller
+func NewInstructionController(instructionService services.InstructionService) *InstructionController {
+	return &InstructionController{instructionService: instructionService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *InstructionController) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/me/instructions", requireRead, c.GetUserInstructions)
+	router.PUT("/me/instructions", requireWrite, c.SetUserInstructions)
+	router.GET("/chats/:id/debug/instructions", requireDebug, c.GetChatInstructions)
+}
+
+// GetUserInstructions handles getting the current user's custom instructions
+func (c *InstructionController) GetUserInstructions(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	instructions, err := c.instructionService.GetUserInstructions(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, instructions)
+}
+
+// SetUserInstructions handles setting the current user's custom instructions
+func (c *InstructionController) SetUserInstructions(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	var req dtos.UserInstructionsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse user instructions request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	instructions, err := c.instructionService.SetUserInstructions(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, instructions)
+}
+
+// GetChatInstructions handles showing the instruction layers of a chat, by the owner of the
+// chat or an admin
+func (c *InstructionController) GetChatInstructions(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	admin := ctx.GetString("role") == models.RoleAdmin
+	instructions, err := c.instructionService.GetChatInstructions(ctx.Request.Context(), chatID, userID, admin)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, instructions)
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// LimitsController handles HTTP requests for the limits in effect for users
+type LimitsController struct {
+	limitsService services.LimitsService
+}
+
+// NewLimitsController creates a new limits controller
+func NewLimitsController(limitsService services.LimitsService) *LimitsController {
+	return &LimitsController{limitsService: limitsService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *LimitsController) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/limits", requireRead, c.GetLimits)
+}
+
+// GetLimits handles getting the limits in effect for the current user
+func (c *LimitsController) GetLimits(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	limits, err := c.limitsService.GetLimits(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, limits)
+}
@@ -1,13 +1,19 @@
 package controllers
 
 import (
+	"fmt"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/configs"
 	"github.com/nvnamsss/chat/src/dtos"
 	"github.com/nvnamsss/chat/src/errors"
 	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
 	"github.com/nvnamsss/chat/src/services"
 )
 
@@ -15,53 +21,133 @@ import (
 type MessageController struct {
 	messageService services.MessageService
 	chatService    services.ChatService
+	budgetService  services.BudgetService
+	streams        configs.Streams
 }
 
 // NewMessageController creates a new message controller
-func NewMessageController(messageService services.MessageService, chatService services.ChatService) *MessageController {
+func NewMessageController(messageService services.MessageService, chatService services.ChatService, budgetService services.BudgetService, streams configs.Streams) *MessageController {
 	return &MessageController{
 		messageService: messageService,
 		chatService:    chatService,
+		budgetService:  budgetService,
+		streams:        streams,
 	}
 }
 
 // RegisterRoutes registers the controller routes with the router
 func (c *MessageController) RegisterRoutes(router *gin.RouterGroup) {
+	// Routes of a message or chat load it once, checking that the chat belongs to the user
+	loadMessage := middlewares.LoadMessage(c.messageService, c.chatService, respondError)
+	loadChat := middlewares.LoadChat(c.chatService, respondError)
+	loadChatOrAdmin := middlewares.LoadChatOrAdmin(c.chatService, respondError)
+	loadQueryChat := middlewares.LoadQueryChat(c.chatService, respondError)
+
 	messages := router.Group("/messages")
 	{
-		messages.POST("", c.SendMessage)
-		messages.GET("", c.ListMessages)
-		messages.GET("/:id", c.GetMessage)
-		messages.PUT("/:id", c.UpdateMessage)
-		messages.DELETE("/:id", c.DeleteMessage)
+		messages.POST("", requireSend, loadQueryChat, c.SendMessage)
+		messages.GET("", requireRead, loadQueryChat, c.ListMessages)
+		messages.POST("/lookup", requireRead, c.LookupMessages)
+		messages.GET("/:id", requireRead, loadMessage, c.GetMessage)
+		messages.GET("/:id/stream", requireRead, loadMessage, c.ResumeStream)
+		messages.GET("/:id/wait", requireRead, loadMessage, c.WaitForReply)
+		messages.POST("/:id/retry", requireSend, loadMessage, c.RetryReply)
+		messages.GET("/:id/revisions", requireRead, loadMessage, c.ListRevisions)
+		messages.GET("/:id/artifacts", requireRead, loadMessage, c.ListArtifacts)
+		messages.PUT("/:id/feedback", requireWrite, loadMessage, c.SetFeedback)
+		messages.PUT("/:id/reactions", requireWrite, loadMessage, c.React)
+		messages.GET("/:id/artifacts/:artifactId/raw", requireRead, loadMessage, c.DownloadArtifact)
+		messages.PUT("/:id", requireWrite, loadMessage, c.UpdateMessage)
+		messages.DELETE("/:id", requireWrite, loadMessage, c.DeleteMessage)
 	}
+
+	router.POST("/chats/:id/compare", requireSend, loadChat, c.CompareMessage)
+	router.DELETE("/chats/:id/messages", requireWrite, loadChat, c.DeleteMessages)
+	router.GET("/chats/:id/models", requireRead, loadChat, c.ListModelHistory)
+	router.POST("/chats/:id/summarize", requireSend, loadChat, c.SummarizeChat)
+	router.POST("/estimate", requireRead, middlewares.LoadBodyChat(c.chatService, respondError), c.EstimateMessage)
+	router.POST("/chats/:id/debug/prompt", requireDebug, loadChatOrAdmin, c.PreviewPrompt)
 }
 
-// SendMessage handles sending a new message to a chat and getting a response from the LLM
-func (c *MessageController) SendMessage(ctx *gin.Context) {
+// PreviewPrompt handles previewing the LLM request a reply in a chat would be generated with,
+// by the owner of the chat or an admin
+func (c *MessageController) PreviewPrompt(ctx *gin.Context) {
 	log := logger.Context(ctx.Request.Context())
 
-	// Get user ID from JWT token
-	userID := getUserIDFromContext(ctx)
-	if userID == "" {
-		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+	// The body is optional; without it the prompt answers the latest messages
+	var req dtos.PromptPreviewRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Errorw("Failed to parse prompt preview request", "error", err)
+			respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+			return
+		}
+	}
+
+	chatID := middlewares.LoadedChat(ctx).ID
+	preview, err := c.messageService.PreviewPrompt(ctx.Request.Context(), chatID, &req)
+	if err != nil {
+		respondError(ctx, err)
 		return
 	}
+	// Admins may preview the prompts of other users
+	log.Infow("Previewed prompt", "chatID", chatID, "userID", getUserIDFromContext(ctx), "admin", ctx.GetString("role") == models.RoleAdmin)
+
+	respond(ctx, http.StatusOK, preview)
+}
+
+// SummarizeChat handles summarizing a conversation for its user
+func (c *MessageController) SummarizeChat(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// The body is optional; without it the summary is not pinned
+	var req dtos.SummarizeChatRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Errorw("Failed to parse summarize request", "error", err)
+			respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+			return
+		}
+	}
+
+	summary, err := c.messageService.SummarizeChat(ctx.Request.Context(), middlewares.LoadedChat(ctx).ID, getUserIDFromContext(ctx), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, summary)
+}
 
-	// Parse chat ID from query parameter
-	chatIDStr := ctx.Query("chatId")
-	if chatIDStr == "" {
-		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Missing chat ID"))
+// EstimateMessage handles estimating the prompt tokens and cost of a message before it is sent
+func (c *MessageController) EstimateMessage(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	userID := getUserIDFromContext(ctx)
+
+	// Parse request
+	var req dtos.EstimateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse estimate request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
 		return
 	}
 
-	chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+	estimate, err := c.messageService.EstimateMessage(ctx.Request.Context(), userID, &req)
 	if err != nil {
-		log.Errorw("Invalid chat ID", "chatID", chatIDStr, "error", err)
-		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid chat ID"))
+		respondError(ctx, err)
 		return
 	}
 
+	respond(ctx, http.StatusOK, estimate)
+}
+
+// SendMessage handles sending a new message to a chat and getting a response from the LLM
+func (c *MessageController) SendMessage(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	userID := getUserIDFromContext(ctx)
+
 	// Parse request
 	var req dtos.MessageRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -71,157 +157,321 @@ func (c *MessageController) SendMessage(ctx *gin.Context) {
 	}
 
 	// Send message
-	message, err := c.messageService.SendMessage(ctx.Request.Context(), chatID, userID, &req)
+	exchange, err := c.messageService.SendMessage(ctx.Request.Context(), middlewares.LoadedChat(ctx).ID, userID, &req)
+	setServerTiming(ctx)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, message)
+	// A duplicate returns the previous exchange without creating messages, as dry runs do
+	status := createdStatus(ctx)
+	if exchange.Duplicate {
+		status = http.StatusOK
+	}
+	c.reportQuota(ctx, userID)
+
+	// v1 is frozen and only returns the user message
+	if middlewares.GetAPIVersion(ctx) == middlewares.APIVersion1 {
+		respond(ctx, status, exchange.UserMessage)
+		return
+	}
+	respond(ctx, status, exchange)
 }
 
 // GetMessage handles getting a single message by ID
 func (c *MessageController) GetMessage(ctx *gin.Context) {
+	respond(ctx, http.StatusOK, middlewares.LoadedMessage(ctx))
+}
+
+// ResumeStream handles resuming the stream of a reply dropped by the client, as server-sent
+// events of the chunks from the requested one, numbered by their event ID, ending with [DONE].
+// Errors before the first chunk are sent as regular responses; later ones as an error event
+// telling why the reply failed and how to recover.
+func (c *MessageController) ResumeStream(ctx *gin.Context) {
 	log := logger.Context(ctx.Request.Context())
 
-	// Get user ID from JWT token
 	userID := getUserIDFromContext(ctx)
-	if userID == "" {
-		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+
+	id := middlewares.LoadedMessage(ctx).ID
+
+	var req dtos.ResumeStreamRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse resume stream request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
 		return
 	}
+	from := 0
+	if req.From != nil {
+		from = *req.From
+	} else if lastEventID := ctx.GetHeader(HeaderLastEventID); lastEventID != "" {
+		last, err := strconv.Atoi(lastEventID)
+		if err != nil || last < 0 {
+			respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid Last-Event-ID header"))
+			return
+		}
+		from = last + 1
+	}
 
-	// Parse message ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	// Streams only resume for the owner of the chat they were opened for
+	stream := newEventStream(ctx, c.streams.HeartbeatInterval)
+	defer stream.close()
+
+	started := false
+	err := c.messageService.ResumeStream(ctx.Request.Context(), id, userID, req.Token, from, func(chunk *dtos.LLMChunk) error {
+		if !started {
+			started = true
+			stream.start()
+		}
+		return stream.send(strconv.Itoa(chunk.Index), dtos.StreamChunk{Index: chunk.Index, Content: chunk.Content})
+	})
 	if err != nil {
-		log.Errorw("Invalid message ID", "id", idStr, "error", err)
-		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid message ID"))
+		if !started {
+			respondError(ctx, err)
+			return
+		}
+		// Nothing can be sent to clients that left
+		if ctx.Request.Context().Err() == nil {
+			stream.sendError(toStreamAbort(ctx, err, id, req.Token))
+		}
 		return
 	}
 
-	// Get message
-	message, err := c.messageService.GetMessage(ctx.Request.Context(), id)
-	if err != nil {
-		respondError(ctx, err)
+	if !started {
+		stream.start()
+	}
+	stream.done()
+}
+
+// defaultReplyWait is how long WaitForReply waits without a timeout
+const defaultReplyWait = 30 * time.Second
+
+// WaitForReply handles long-polling an assistant reply being generated, for clients that
+// cannot use streams: it responds once the reply completed or failed, or with the reply as
+// it is when the timeout elapsed, its status still partial
+func (c *MessageController) WaitForReply(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	id := middlewares.LoadedMessage(ctx).ID
+
+	var req dtos.WaitMessageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse wait request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
 		return
 	}
+	timeout := defaultReplyWait
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
 
-	// Verify the user has access to this chat
-	chat, err := c.chatService.GetChat(ctx.Request.Context(), message.ChatID)
+	message, err := c.messageService.WaitForReply(ctx.Request.Context(), id, timeout)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	if chat.UserID != userID {
-		respondError(ctx, errors.New(errors.ErrForbidden, "User does not have access to this message"))
+	respond(ctx, http.StatusOK, message)
+}
+
+// RetryReply handles generating again a failed reply, the latest message of its chat
+func (c *MessageController) RetryReply(ctx *gin.Context) {
+	userID := getUserIDFromContext(ctx)
+
+	reply, err := c.messageService.RetryReply(ctx.Request.Context(), middlewares.LoadedMessage(ctx).ID, userID)
+	if err != nil {
+		respondError(ctx, err)
 		return
 	}
+	c.reportQuota(ctx, userID)
 
-	ctx.JSON(http.StatusOK, message)
+	respond(ctx, http.StatusCreated, reply)
 }
 
-// ListMessages handles listing all messages for a chat
-func (c *MessageController) ListMessages(ctx *gin.Context) {
+// CompareMessage handles sending a message to a chat and getting the replies of several
+// models to it side by side
+func (c *MessageController) CompareMessage(ctx *gin.Context) {
 	log := logger.Context(ctx.Request.Context())
 
-	// Get user ID from JWT token
 	userID := getUserIDFromContext(ctx)
-	if userID == "" {
-		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
-		return
-	}
 
-	// Parse request parameters
-	var req dtos.ListMessagesRequest
-	if err := ctx.ShouldBindQuery(&req); err != nil {
-		log.Errorw("Failed to parse list messages request", "error", err)
+	// Parse request
+	var req dtos.CompareRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse compare request", "error", err)
 		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
 		return
 	}
 
-	// Verify the user has access to this chat
-	chat, err := c.chatService.GetChat(ctx.Request.Context(), req.ChatID)
+	comparison, err := c.messageService.CompareMessage(ctx.Request.Context(), middlewares.LoadedChat(ctx).ID, userID, &req)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	if chat.UserID != userID {
-		respondError(ctx, errors.New(errors.ErrForbidden, "User does not have access to this chat"))
+	c.reportQuota(ctx, userID)
+	respond(ctx, http.StatusCreated, comparison)
+}
+
+// reportQuota sets the X-Quota-Remaining header to the amount left of the tightest budget
+// covering the user this month, with a warning once it has crossed its threshold. The
+// request does not fail when the budgets cannot be read.
+func (c *MessageController) reportQuota(ctx *gin.Context, userID string) {
+	quota, err := c.budgetService.Quota(ctx.Request.Context(), userID)
+	if err != nil {
+		logger.Context(ctx.Request.Context()).Warnw("Failed to report quota", "error", err, "userID", userID)
+		return
+	}
+	if quota == nil {
 		return
 	}
 
-	// Get messages
-	messages, err := c.messageService.ListMessages(ctx.Request.Context(), &req)
+	ctx.Header("X-Quota-Remaining", strconv.FormatFloat(*quota.Remaining, 'f', 4, 64))
+	budget := "your monthly budget"
+	if quota.Scope == dtos.BudgetScopeGlobal {
+		budget = "the monthly budget of the service"
+	}
+	switch quota.Status {
+	case dtos.BudgetStatusExceeded:
+		middlewares.AddWarning(ctx, middlewares.WarningQuota, "New messages are rejected until "+budget+" resets")
+	case dtos.BudgetStatusWarning:
+		middlewares.AddWarning(ctx, middlewares.WarningQuota, fmt.Sprintf("%.2f USD remain of %s", *quota.Remaining, budget))
+	}
+}
+
+// ListRevisions handles listing the edit history of a message
+func (c *MessageController) ListRevisions(ctx *gin.Context) {
+	revisions, err := c.messageService.ListRevisions(ctx.Request.Context(), middlewares.LoadedMessage(ctx).ID)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, messages)
+	respond(ctx, http.StatusOK, revisions)
 }
 
-// UpdateMessage handles updating a message
-func (c *MessageController) UpdateMessage(ctx *gin.Context) {
+// ListModelHistory handles listing the models that generated the replies of a chat
+func (c *MessageController) ListModelHistory(ctx *gin.Context) {
+	history, err := c.messageService.ListModelHistory(ctx.Request.Context(), middlewares.LoadedChat(ctx).ID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, history)
+}
+
+// SetFeedback handles rating an assistant message
+func (c *MessageController) SetFeedback(ctx *gin.Context) {
 	log := logger.Context(ctx.Request.Context())
 
-	// Get user ID from JWT token
-	userID := getUserIDFromContext(ctx)
-	if userID == "" {
-		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+	id := middlewares.LoadedMessage(ctx).ID
+
+	// Parse request
+	var req dtos.FeedbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse feedback request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
 		return
 	}
 
-	// Parse message ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	message, err := c.messageService.SetFeedback(ctx.Request.Context(), id, &req)
 	if err != nil {
-		log.Errorw("Invalid message ID", "id", idStr, "error", err)
-		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid message ID"))
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, message)
+}
+
+// React handles adding or taking back an emoji reaction to a message
+func (c *MessageController) React(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	id := middlewares.LoadedMessage(ctx).ID
+
+	// Parse request
+	var req dtos.ReactionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse reaction request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
 		return
 	}
 
-	// Get the message first to check ownership
-	existingMessage, err := c.messageService.GetMessage(ctx.Request.Context(), id)
+	reactions, err := c.messageService.React(ctx.Request.Context(), id, getUserIDFromContext(ctx), &req)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	// Verify the user has access to this chat
-	chat, err := c.chatService.GetChat(ctx.Request.Context(), existingMessage.ChatID)
+	respond(ctx, http.StatusOK, reactions)
+}
+
+// ListArtifacts handles listing the code blocks extracted from a message
+func (c *MessageController) ListArtifacts(ctx *gin.Context) {
+	id := middlewares.LoadedMessage(ctx).ID
+
+	artifacts, err := c.messageService.ListArtifacts(ctx.Request.Context(), id)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	if chat.UserID != userID {
-		respondError(ctx, errors.New(errors.ErrForbidden, "User does not have access to this message"))
+	respond(ctx, http.StatusOK, artifacts)
+}
+
+// DownloadArtifact handles downloading a code block of a message as a raw file
+func (c *MessageController) DownloadArtifact(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	id := middlewares.LoadedMessage(ctx).ID
+
+	// Parse artifact ID from path
+	artifactIDStr := ctx.Param("artifactId")
+	artifactID, err := strconv.ParseInt(artifactIDStr, 10, 64)
+	if err != nil {
+		log.Errorw("Invalid artifact ID", "id", artifactIDStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid artifact ID"))
 		return
 	}
 
-	// Parse request
-	var req dtos.MessageRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		log.Errorw("Failed to parse update message request", "error", err)
-		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+	artifact, err := c.messageService.GetArtifact(ctx.Request.Context(), id, artifactID)
+	if err != nil {
+		respondError(ctx, err)
 		return
 	}
 
-	// Update message
-	message, err := c.messageService.UpdateMessage(ctx.Request.Context(), id, &req)
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": artifact.Filename}))
+	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(artifact.Content))
+}
+
+// ListMessages handles listing all messages for a chat
+func (c *MessageController) ListMessages(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	userID := getUserIDFromContext(ctx)
+
+	// Parse request parameters
+	var req dtos.ListMessagesRequest
+	if err := bindListQuery(ctx, &req); err != nil {
+		log.Errorw("Failed to parse list messages request", "error", err)
+		respondError(ctx, err)
+		return
+	}
+
+	// Get messages
+	messages, err := c.messageService.ListMessages(ctx.Request.Context(), userID, &req)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, message)
+	respond(ctx, http.StatusOK, messages)
 }
 
-// DeleteMessage handles deleting a message
-func (c *MessageController) DeleteMessage(ctx *gin.Context) {
+// LookupMessages handles getting messages by ID in one request. Messages of chats of other
+// users are reported missing, like those that do not exist.
+func (c *MessageController) LookupMessages(ctx *gin.Context) {
 	log := logger.Context(ctx.Request.Context())
 
 	// Get user ID from JWT token
@@ -231,39 +481,76 @@ func (c *MessageController) DeleteMessage(ctx *gin.Context) {
 		return
 	}
 
-	// Parse message ID from path
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
-	if err != nil {
-		log.Errorw("Invalid message ID", "id", idStr, "error", err)
-		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid message ID"))
+	// Parse request
+	var req dtos.LookupMessagesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse lookup messages request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+	if maxIDs := configs.AppConfig.API.MaxLimit; maxIDs > 0 && len(req.IDs) > maxIDs {
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("at most %d IDs can be looked up", maxIDs)))
 		return
 	}
 
-	// Get the message first to check ownership
-	existingMessage, err := c.messageService.GetMessage(ctx.Request.Context(), id)
+	messages, err := c.messageService.LookupMessages(ctx.Request.Context(), userID, req.IDs)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	// Verify the user has access to this chat
-	chat, err := c.chatService.GetChat(ctx.Request.Context(), existingMessage.ChatID)
+	respond(ctx, http.StatusOK, messages)
+}
+
+// UpdateMessage handles updating a message
+func (c *MessageController) UpdateMessage(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.MessageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse update message request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	// Update message
+	message, err := c.messageService.UpdateMessage(ctx.Request.Context(), middlewares.LoadedMessage(ctx).ID, &req)
 	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	if chat.UserID != userID {
-		respondError(ctx, errors.New(errors.ErrForbidden, "User does not have access to this message"))
+	respond(ctx, http.StatusOK, message)
+}
+
+// DeleteMessage handles deleting a message
+func (c *MessageController) DeleteMessage(ctx *gin.Context) {
+	if err := c.messageService.DeleteMessage(ctx.Request.Context(), middlewares.LoadedMessage(ctx).ID); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DeleteMessages handles deleting the messages of a chat matching filters
+func (c *MessageController) DeleteMessages(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse filters
+	var req dtos.DeleteMessagesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse delete messages request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
 		return
 	}
 
-	// Delete message
-	if err := c.messageService.DeleteMessage(ctx.Request.Context(), id); err != nil {
+	response, err := c.messageService.DeleteMessages(ctx.Request.Context(), middlewares.LoadedChat(ctx).ID, &req)
+	if err != nil {
 		respondError(ctx, err)
 		return
 	}
 
-	ctx.Status(http.StatusNoContent)
+	respond(ctx, http.StatusOK, response)
 }
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// NotificationController handles HTTP requests for users' notification preferences
+type NotificationController struct {
+	notificationService services.NotificationService
+}
+
+// NewNotificationController creates a new notification controller
+func NewNotificationController(notificationService services.NotificationService) *NotificationController {
+	return &NotificationController{notificationService: notificationService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *NotificationController) RegisterRoutes(router *gin.RouterGroup) {
+	notifications := router.Group("/notifications")
+	{
+		notifications.GET("/preferences", requireRead, c.GetPreferences)
+		notifications.PUT("/preferences", requireWrite, c.SetPreferences)
+	}
+
+	me := router.Group("/me")
+	{
+		me.GET("/notifications", requireRead, c.GetSettings)
+		me.PUT("/notifications", requireWrite, c.SetSettings)
+	}
+}
+
+// RegisterUnsubscribeRoute registers the route of the unsubscribe links of activity digests.
+// It is not versioned and is served without authentication, the link signature granting
+// access. GET only asks for a confirmation, as mail scanners and link prefetchers follow
+// links; POST unsubscribes, from the confirmation page or the one-click unsubscribes of
+// mail clients.
+func (c *NotificationController) RegisterUnsubscribeRoute(router gin.IRouter) {
+	router.GET(services.DigestUnsubscribePath, c.ConfirmUnsubscribe)
+	router.POST(services.DigestUnsubscribePath, c.Unsubscribe)
+}
+
+// GetPreferences handles getting the current user's notification preferences
+func (c *NotificationController) GetPreferences(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	preferences, err := c.notificationService.GetPreferences(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, preferences)
+}
+
+// SetPreferences handles setting the current user's notification preferences
+func (c *NotificationController) SetPreferences(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.NotificationPreferencesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse notification preferences request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	preferences, err := c.notificationService.SetPreferences(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, preferences)
+}
+
+// GetSettings handles getting the current user's notification settings for every channel
+func (c *NotificationController) GetSettings(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	settings, err := c.notificationService.GetSettings(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, settings)
+}
+
+// SetSettings handles setting the current user's notification settings
+func (c *NotificationController) SetSettings(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.NotificationSettingsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse notification settings request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	settings, err := c.notificationService.SetSettings(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, settings)
+}
+
+// unsubscribePage is the confirmation page of unsubscribe links, posting the link back
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Unsubscribe</title></head>
+<body>
+<p>Stop receiving the activity digest?</p>
+<form method="post" action="{{.}}"><button type="submit">Unsubscribe</button></form>
+</body>
+</html>
+`))
+
+// ConfirmUnsubscribe handles showing the confirmation page of an unsubscribe link, which
+// changes nothing
+func (c *NotificationController) ConfirmUnsubscribe(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	var req dtos.DigestUnsubscribeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse unsubscribe request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	ctx.Status(http.StatusOK)
+	if err := unsubscribePage.Execute(ctx.Writer, ctx.Request.URL.RequestURI()); err != nil {
+		log.Errorw("Failed to render unsubscribe page", "error", err)
+	}
+}
+
+// Unsubscribe handles turning off the activity digest of the user of an unsubscribe link
+func (c *NotificationController) Unsubscribe(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	var req dtos.DigestUnsubscribeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse unsubscribe request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	if err := c.notificationService.Unsubscribe(ctx.Request.Context(), &req); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.String(http.StatusOK, "You are unsubscribed from the activity digest.")
+}
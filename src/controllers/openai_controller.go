@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// OpenAI proxy headers
+const (
+	// HeaderChatID selects the chat a completion is appended to; a new chat is created without it
+	HeaderChatID    = "X-Chat-ID"
+	HeaderMessageID = "X-Message-ID"
+)
+
+// completionTitleLength is the most characters of the first prompt used as the title of a new chat
+const completionTitleLength = 50
+
+// finishReasonStop is the finish reason of completed replies
+var finishReasonStop = "stop"
+
+// OpenAIController serves OpenAI-compatible endpoints, so that OpenAI SDK clients can use
+// the service with the JWT as API key. Completions are persisted as chats.
+type OpenAIController struct {
+	messageService services.MessageService
+	chatService    services.ChatService
+	config         configs.LLM
+	streams        configs.Streams
+}
+
+// NewOpenAIController creates a new OpenAI-compatible controller
+func NewOpenAIController(messageService services.MessageService, chatService services.ChatService, config configs.LLM, streams configs.Streams) *OpenAIController {
+	return &OpenAIController{
+		messageService: messageService,
+		chatService:    chatService,
+		config:         config,
+		streams:        streams,
+	}
+}
+
+// RegisterRoutes registers the controller routes with the router. They are not under the
+// API versions, but at the paths of the OpenAI API.
+func (c *OpenAIController) RegisterRoutes(router gin.IRouter) {
+	openai := router.Group("/v1")
+	{
+		openai.POST("/chat/completions", requireSend, c.CreateCompletion)
+		openai.GET("/models", requireRead, c.ListModels)
+	}
+}
+
+// CreateCompletion handles an OpenAI chat completion request, streamed as server-sent
+// events when requested
+func (c *OpenAIController) CreateCompletion(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondOpenAIError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.ChatCompletionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse chat completion request", "error", err)
+		respondOpenAIError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+	// Checked before a chat is created for the request
+	if err := services.CheckModel(c.config, req.Model); err != nil {
+		respondOpenAIError(ctx, err)
+		return
+	}
+
+	chatID, err := c.resolveChat(ctx, userID, &req)
+	if err != nil {
+		respondOpenAIError(ctx, err)
+		return
+	}
+	ctx.Header(HeaderChatID, strconv.FormatInt(chatID, 10))
+
+	if req.Stream {
+		c.streamCompletion(ctx, chatID, userID, &req)
+		return
+	}
+
+	result, err := c.messageService.Complete(ctx.Request.Context(), chatID, userID, &req, nil)
+	if err != nil {
+		respondOpenAIError(ctx, err)
+		return
+	}
+
+	ctx.Header(HeaderMessageID, strconv.FormatInt(result.Message.ID, 10))
+	ctx.JSON(http.StatusOK, dtos.ChatCompletionResponse{
+		ID:      fmt.Sprintf("chatcmpl-%d", result.Message.ID),
+		Object:  dtos.OpenAIObjectCompletion,
+		Created: result.Message.CreatedAt.Unix(),
+		Model:   result.Model,
+		Choices: []dtos.ChatCompletionChoice{{
+			Message:      &dtos.ChatCompletionReply{Role: "assistant", Content: result.Message.Content},
+			FinishReason: &finishReasonStop,
+		}},
+		Usage: toCompletionUsage(result.Usage),
+	})
+}
+
+// streamCompletion streams the reply to a chat completion request as server-sent events of
+// completion chunks, ending with [DONE]. Errors before the first chunk are sent as regular
+// responses; later ones as an error event, whose abort tells why the reply failed and how to
+// recover. When the stream is resumable, the response
+// carries the message and stream token headers, and content chunks are numbered by their
+// event ID.
+func (c *OpenAIController) streamCompletion(ctx *gin.Context, chatID int64, userID string, req *dtos.ChatCompletionRequest) {
+	id := "chatcmpl-" + uuid.NewString()
+	created := time.Now().Unix()
+	model := req.Model
+	if model == "" {
+		model = c.config.Model
+	}
+	chunk := func(delta *dtos.ChatCompletionReply, finishReason *string) dtos.ChatCompletionResponse {
+		return dtos.ChatCompletionResponse{
+			ID:      id,
+			Object:  dtos.OpenAIObjectCompletionChunk,
+			Created: created,
+			Model:   model,
+			Choices: []dtos.ChatCompletionChoice{{Delta: delta, FinishReason: finishReason}},
+		}
+	}
+
+	stream := newEventStream(ctx, c.streams.HeartbeatInterval)
+	defer stream.close()
+
+	started := false
+	var messageID int64
+	var token string
+	result, err := c.messageService.Complete(ctx.Request.Context(), chatID, userID, req, func(llmChunk *dtos.LLMChunk) error {
+		if !started {
+			started = true
+			messageID, token = llmChunk.MessageID, llmChunk.StreamToken
+			if llmChunk.StreamToken != "" {
+				ctx.Header(HeaderMessageID, strconv.FormatInt(llmChunk.MessageID, 10))
+				ctx.Header(HeaderStreamToken, llmChunk.StreamToken)
+			}
+			stream.start()
+			if err := stream.send("", chunk(&dtos.ChatCompletionReply{Role: "assistant"}, nil)); err != nil {
+				return err
+			}
+		}
+		if llmChunk.Content == "" {
+			return nil
+		}
+		id := ""
+		if llmChunk.StreamToken != "" {
+			id = strconv.Itoa(llmChunk.Index)
+		}
+		return stream.send(id, chunk(&dtos.ChatCompletionReply{Content: llmChunk.Content}, nil))
+	})
+	if err != nil {
+		if !started {
+			respondOpenAIError(ctx, err)
+			return
+		}
+		if ctx.Request.Context().Err() == nil {
+			response := toOpenAIError(ctx, err)
+			abort := toStreamAbort(ctx, err, messageID, token)
+			response.Error.Abort = &abort
+			stream.send("", response)
+		}
+		return
+	}
+
+	if err := stream.send("", chunk(&dtos.ChatCompletionReply{}, &finishReasonStop)); err != nil {
+		return
+	}
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		usage := chunk(nil, nil)
+		usage.Choices = []dtos.ChatCompletionChoice{}
+		usage.Usage = toCompletionUsage(result.Usage)
+		if err := stream.send("", usage); err != nil {
+			return
+		}
+	}
+	stream.done()
+}
+
+// resolveChat returns the chat selected by the X-Chat-ID header, or creates one titled
+// after the latest message of the request
+func (c *OpenAIController) resolveChat(ctx *gin.Context, userID string, req *dtos.ChatCompletionRequest) (int64, error) {
+	if header := ctx.GetHeader(HeaderChatID); header != "" {
+		chatID, err := strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			return 0, errors.New(errors.ErrInvalidRequest, "Invalid chat ID")
+		}
+		owner, err := c.chatService.GetOwner(ctx.Request.Context(), chatID)
+		if err != nil {
+			return 0, err
+		}
+		if owner.UserID != userID {
+			return 0, errors.New(errors.ErrForbidden, "User does not have access to this chat")
+		}
+		return chatID, nil
+	}
+
+	title := strings.Join(strings.Fields(string(req.Messages[len(req.Messages)-1].Content)), " ")
+	if runes := []rune(title); len(runes) > completionTitleLength {
+		title = string(runes[:completionTitleLength])
+	}
+	if title == "" {
+		title = "New chat"
+	}
+
+	chat, err := c.chatService.CreateChat(ctx.Request.Context(), userID, &dtos.ChatRequest{Title: title})
+	if err != nil {
+		return 0, err
+	}
+	return chat.ID, nil
+}
+
+// ListModels handles listing the models completions can be requested with: the default
+// model and those configured for comparison
+func (c *OpenAIController) ListModels(ctx *gin.Context) {
+	names := services.AvailableModels(c.config)
+	list := dtos.OpenAIModelList{Object: dtos.OpenAIObjectList, Data: make([]dtos.OpenAIModel, len(names))}
+	for i, name := range names {
+		list.Data[i] = dtos.OpenAIModel{ID: name, Object: dtos.OpenAIObjectModel, OwnedBy: "system"}
+	}
+
+	ctx.JSON(http.StatusOK, list)
+}
+
+// respondOpenAIError sends an error response in the OpenAI format
+func respondOpenAIError(ctx *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	if appErr, ok := err.(*errors.AppError); ok {
+		statusCode = appErr.StatusCode()
+	}
+
+	ctx.JSON(statusCode, toOpenAIError(ctx, err))
+}
+
+// toOpenAIError converts an error to the OpenAI format, logging it like respondError
+func toOpenAIError(ctx *gin.Context, err error) dtos.OpenAIErrorResponse {
+	log := logger.Context(ctx.Request.Context())
+
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		log.Errorw("Unknown error", "error", err)
+		return dtos.OpenAIErrorResponse{Error: dtos.OpenAIError{
+			Message: "Internal server error",
+			Type:    "server_error",
+			Code:    strings.ToLower(errors.ErrInternal),
+		}}
+	}
+
+	log.Warnw("Application error", "code", appErr.Code, "message", appErr.Message, "error", appErr.Err)
+	errorType := "invalid_request_error"
+	if appErr.StatusCode() >= http.StatusInternalServerError {
+		errorType = "server_error"
+	}
+	return dtos.OpenAIErrorResponse{Error: dtos.OpenAIError{
+		Message: appErr.Message,
+		Type:    errorType,
+		Code:    strings.ToLower(appErr.Code),
+	}}
+}
+
+// toCompletionUsage converts LLM token usage to the OpenAI format
+func toCompletionUsage(usage dtos.LLMUsage) *dtos.ChatCompletionUsage {
+	return &dtos.ChatCompletionUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// PersonaController handles HTTP requests for the assistant personas of group chats
+type PersonaController struct {
+	personaService services.PersonaService
+}
+
+// NewPersonaController creates a new persona controller
+func NewPersonaController(personaService services.PersonaService) *PersonaController {
+	return &PersonaController{personaService: personaService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *PersonaController) RegisterRoutes(router *gin.RouterGroup) {
+	personas := router.Group("/chats/:id/personas")
+	{
+		personas.POST("", requireWrite, c.CreatePersona)
+		personas.GET("", requireRead, c.ListPersonas)
+		personas.PUT("/:personaId", requireWrite, c.UpdatePersona)
+		personas.DELETE("/:personaId", requireWrite, c.DeletePersona)
+	}
+}
+
+// CreatePersona handles adding an assistant persona to a chat
+func (c *PersonaController) CreatePersona(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req dtos.PersonaRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse persona request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	persona, err := c.personaService.CreatePersona(ctx.Request.Context(), userID, chatID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, persona)
+}
+
+// ListPersonas handles listing the personas of a chat
+func (c *PersonaController) ListPersonas(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	personas, err := c.personaService.ListPersonas(ctx.Request.Context(), userID, chatID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, personas)
+}
+
+// UpdatePersona handles updating a persona of a chat
+func (c *PersonaController) UpdatePersona(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+	personaID, ok := parsePersonaID(ctx)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req dtos.UpdatePersonaRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse update persona request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	persona, err := c.personaService.UpdatePersona(ctx.Request.Context(), userID, chatID, personaID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, persona)
+}
+
+// DeletePersona handles removing a persona from a chat
+func (c *PersonaController) DeletePersona(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+	personaID, ok := parsePersonaID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.personaService.DeletePersona(ctx.Request.Context(), userID, chatID, personaID); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// parsePersonaID parses the persona ID path parameter, responding with an error when it is invalid
+func parsePersonaID(ctx *gin.Context) (int64, bool) {
+	idStr := ctx.Param("personaId")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		logger.Context(ctx.Request.Context()).Errorw("Invalid persona ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid persona ID"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// ProviderController handles HTTP requests for the health of the LLM providers
+type ProviderController struct {
+	providerService services.ProviderService
+}
+
+// NewProviderController creates a new provider controller
+func NewProviderController(providerService services.ProviderService) *ProviderController {
+	return &ProviderController{providerService: providerService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *ProviderController) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/providers/status", requireRead, c.GetStatus)
+}
+
+// GetStatus handles reporting the health of the LLM providers, so clients can warn users
+// of degraded generation before they send a prompt
+func (c *ProviderController) GetStatus(ctx *gin.Context) {
+	respond(ctx, http.StatusOK, c.providerService.GetStatus(ctx.Request.Context()))
+}
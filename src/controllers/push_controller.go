@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// PushController handles HTTP requests for push notification devices and chat mutes
+type PushController struct {
+	pushService services.PushService
+}
+
+// NewPushController creates a new push controller
+func NewPushController(pushService services.PushService) *PushController {
+	return &PushController{pushService: pushService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *PushController) RegisterRoutes(router *gin.RouterGroup) {
+	devices := router.Group("/devices")
+	{
+		devices.POST("", requireWrite, c.RegisterDevice)
+		devices.DELETE("/:token", requireWrite, c.UnregisterDevice)
+	}
+
+	router.PUT("/chats/:id/mute", requireWrite, c.MuteChat)
+	router.DELETE("/chats/:id/mute", requireWrite, c.UnmuteChat)
+}
+
+// RegisterDevice handles registering a device of the current user for push notifications
+func (c *PushController) RegisterDevice(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.RegisterDeviceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse register device request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	device, err := c.pushService.RegisterDevice(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, device)
+}
+
+// UnregisterDevice handles stopping push notifications to a device of the current user
+func (c *PushController) UnregisterDevice(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	if err := c.pushService.UnregisterDevice(ctx.Request.Context(), userID, ctx.Param("token")); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// MuteChat handles muting the push notifications of a chat
+func (c *PushController) MuteChat(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	// The body is optional; without it the chat stays muted until unmuted
+	var req dtos.MuteChatRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Errorw("Failed to parse mute chat request", "error", err)
+			respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+			return
+		}
+	}
+
+	mute, err := c.pushService.MuteChat(ctx.Request.Context(), userID, chatID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, mute)
+}
+
+// UnmuteChat handles restoring the push notifications of a chat
+func (c *PushController) UnmuteChat(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	mute, err := c.pushService.UnmuteChat(ctx.Request.Context(), userID, chatID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, mute)
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// RetentionController handles HTTP requests for users' message retention preferences
+type RetentionController struct {
+	retentionService services.RetentionService
+}
+
+// NewRetentionController creates a new retention controller
+func NewRetentionController(retentionService services.RetentionService) *RetentionController {
+	return &RetentionController{retentionService: retentionService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *RetentionController) RegisterRoutes(router *gin.RouterGroup) {
+	retention := router.Group("/retention")
+	{
+		retention.GET("", requireRead, c.GetPolicy)
+		retention.PUT("", requireWrite, c.SetPolicy)
+	}
+}
+
+// GetPolicy handles getting the retention of the current user's messages
+func (c *RetentionController) GetPolicy(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	policy, err := c.retentionService.GetPolicy(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, policy)
+}
+
+// SetPolicy handles setting the retention of the current user's messages
+func (c *RetentionController) SetPolicy(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.RetentionPolicyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse retention policy request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	policy, err := c.retentionService.SetPolicy(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, policy)
+}
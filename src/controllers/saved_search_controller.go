@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// SavedSearchController handles HTTP requests for saved chat searches, the smart folders of users
+type SavedSearchController struct {
+	savedSearchService services.SavedSearchService
+}
+
+// NewSavedSearchController creates a new saved search controller
+func NewSavedSearchController(savedSearchService services.SavedSearchService) *SavedSearchController {
+	return &SavedSearchController{savedSearchService: savedSearchService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *SavedSearchController) RegisterRoutes(router *gin.RouterGroup) {
+	searches := router.Group("/saved-searches")
+	{
+		searches.POST("", requireWrite, c.CreateSavedSearch)
+		searches.GET("", requireRead, c.ListSavedSearches)
+		searches.GET("/:id", requireRead, c.GetSavedSearch)
+		searches.GET("/:id/chats", requireRead, c.RunSavedSearch)
+		searches.PUT("/:id", requireWrite, c.UpdateSavedSearch)
+		searches.DELETE("/:id", requireWrite, c.DeleteSavedSearch)
+	}
+}
+
+// CreateSavedSearch handles saving a chat search
+func (c *SavedSearchController) CreateSavedSearch(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.SavedSearchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse saved search request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	search, err := c.savedSearchService.CreateSavedSearch(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, search)
+}
+
+// ListSavedSearches handles listing the saved searches of the user
+func (c *SavedSearchController) ListSavedSearches(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	searches, err := c.savedSearchService.ListSavedSearches(ctx.Request.Context(), userID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, searches)
+}
+
+// GetSavedSearch handles retrieving a saved search
+func (c *SavedSearchController) GetSavedSearch(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	id, ok := parseIDParam(ctx, "id", "saved search")
+	if !ok {
+		return
+	}
+
+	search, err := c.savedSearchService.GetSavedSearch(ctx.Request.Context(), userID, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, search)
+}
+
+// RunSavedSearch handles listing a page of the chats of a saved search
+func (c *SavedSearchController) RunSavedSearch(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	id, ok := parseIDParam(ctx, "id", "saved search")
+	if !ok {
+		return
+	}
+
+	// Parse pagination parameters
+	var req dtos.RunSavedSearchRequest
+	if err := bindListQuery(ctx, &req); err != nil {
+		log.Errorw("Failed to parse saved search chats request", "error", err)
+		respondError(ctx, err)
+		return
+	}
+
+	chats, err := c.savedSearchService.RunSavedSearch(ctx.Request.Context(), userID, id, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, chats)
+}
+
+// UpdateSavedSearch handles replacing the name and filters of a saved search
+func (c *SavedSearchController) UpdateSavedSearch(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	id, ok := parseIDParam(ctx, "id", "saved search")
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req dtos.SavedSearchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse saved search request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	search, err := c.savedSearchService.UpdateSavedSearch(ctx.Request.Context(), userID, id, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, search)
+}
+
+// DeleteSavedSearch handles deleting a saved search
+func (c *SavedSearchController) DeleteSavedSearch(ctx *gin.Context) {
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	id, ok := parseIDParam(ctx, "id", "saved search")
+	if !ok {
+		return
+	}
+
+	if err := c.savedSearchService.DeleteSavedSearch(ctx.Request.Context(), userID, id); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,11 @@
+package controllers
+
+import "github.com/nvnamsss/chat/src/middlewares"
+
+// Route middlewares requiring the token scopes of user routes
+var (
+	requireRead  = middlewares.RequireScope(middlewares.ScopeChatsRead)
+	requireWrite = middlewares.RequireScope(middlewares.ScopeChatsWrite)
+	requireSend  = middlewares.RequireScope(middlewares.ScopeMessagesSend)
+	requireDebug = middlewares.RequireScope(middlewares.ScopeChatsDebug)
+)
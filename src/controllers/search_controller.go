@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// SearchController handles HTTP requests for searching messages in the search index
+type SearchController struct {
+	searchService services.SearchService
+}
+
+// NewSearchController creates a new search controller; without a search service, searching
+// messages is not available
+func NewSearchController(searchService services.SearchService) *SearchController {
+	return &SearchController{searchService: searchService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *SearchController) RegisterRoutes(router *gin.RouterGroup) {
+	if c.searchService == nil {
+		return
+	}
+
+	router.GET("/messages/search", requireRead, c.SearchMessages)
+}
+
+// SearchMessages handles searching the messages of the user's chats
+func (c *SearchController) SearchMessages(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse search parameters
+	var req dtos.SearchMessagesRequest
+	if err := bindListQuery(ctx, &req); err != nil {
+		log.Errorw("Failed to parse search request", "error", err)
+		respondError(ctx, err)
+		return
+	}
+
+	response, err := c.searchService.SearchMessages(ctx.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, response)
+}
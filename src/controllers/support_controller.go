@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// SupportController handles HTTP requests for the support bundles of captured requests
+type SupportController struct {
+	supportService services.SupportService
+}
+
+// NewSupportController creates a new support controller
+func NewSupportController(supportService services.SupportService) *SupportController {
+	return &SupportController{supportService: supportService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *SupportController) RegisterRoutes(router *gin.RouterGroup) {
+	support := router.Group("/admin/support")
+	support.Use(middlewares.RequireRole(models.RoleAdmin), middlewares.RequireScope(middlewares.ScopeAdmin))
+	{
+		support.GET("/bundles/:requestId", c.GetBundle)
+	}
+}
+
+// GetBundle handles getting the support bundle of a request
+func (c *SupportController) GetBundle(ctx *gin.Context) {
+	requestID := ctx.Param("requestId")
+	logger.Context(ctx.Request.Context()).Infow("Getting support bundle", "requestID", requestID, "adminID", getUserIDFromContext(ctx))
+
+	bundle, err := c.supportService.GetBundle(ctx.Request.Context(), requestID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, bundle)
+}
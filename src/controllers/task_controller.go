@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// TaskController handles HTTP requests for the tasks extracted from chats
+type TaskController struct {
+	taskService services.TaskService
+}
+
+// NewTaskController creates a new task controller
+func NewTaskController(taskService services.TaskService) *TaskController {
+	return &TaskController{taskService: taskService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *TaskController) RegisterRoutes(router *gin.RouterGroup) {
+	tasks := router.Group("/chats/:id/tasks")
+	{
+		tasks.POST("/extract", requireSend, c.ExtractTasks)
+		tasks.GET("", requireRead, c.ListTasks)
+		tasks.PUT("/:taskId", requireWrite, c.UpdateTask)
+		tasks.POST("/export", requireWrite, c.ExportTasks)
+	}
+}
+
+// ExtractTasks handles extracting the action items of a chat into tasks
+func (c *TaskController) ExtractTasks(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	// The request body is optional
+	var req dtos.ExtractTasksRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Errorw("Failed to parse extract tasks request", "error", err)
+			respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+			return
+		}
+	}
+
+	tasks, err := c.taskService.ExtractTasks(ctx.Request.Context(), userID, chatID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, tasks)
+}
+
+// ListTasks handles listing the tasks of a chat
+func (c *TaskController) ListTasks(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	var req dtos.ListTasksRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse list tasks request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	tasks, err := c.taskService.ListTasks(ctx.Request.Context(), userID, chatID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, tasks)
+}
+
+// UpdateTask handles changing the status of a task of a chat
+func (c *TaskController) UpdateTask(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+	taskID, ok := parseTaskID(ctx)
+	if !ok {
+		return
+	}
+
+	var req dtos.UpdateTaskRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse update task request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	task, err := c.taskService.UpdateTask(ctx.Request.Context(), userID, chatID, taskID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, task)
+}
+
+// ExportTasks handles exporting tasks of a chat to a tracker
+func (c *TaskController) ExportTasks(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	chatID, ok := parseChatID(ctx)
+	if !ok {
+		return
+	}
+
+	var req dtos.ExportTasksRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse export tasks request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	tasks, err := c.taskService.ExportTasks(ctx.Request.Context(), userID, chatID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, tasks)
+}
+
+// parseTaskID parses the task ID path parameter, responding with an error when it is invalid
+func parseTaskID(ctx *gin.Context) (int64, bool) {
+	idStr := ctx.Param("taskId")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		logger.Context(ctx.Request.Context()).Errorw("Invalid task ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid task ID"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// TeamsController handles the activities the Azure Bot Service posts from Microsoft Teams
+type TeamsController struct {
+	teamsService services.TeamsService
+	teamsAdapter adapters.TeamsAdapter
+}
+
+// NewTeamsController creates a new Teams controller
+func NewTeamsController(teamsService services.TeamsService, teamsAdapter adapters.TeamsAdapter) *TeamsController {
+	return &TeamsController{teamsService: teamsService, teamsAdapter: teamsAdapter}
+}
+
+// RegisterRoutes registers the messaging endpoint of the bot. It is not versioned and is
+// served without JWT authentication, requests carrying a Bot Framework token instead.
+func (c *TeamsController) RegisterRoutes(router gin.IRouter) {
+	router.POST(adapters.TeamsMessagesPath, c.HandleActivity)
+}
+
+// HandleActivity handles an activity from Teams, acknowledged before it is answered
+func (c *TeamsController) HandleActivity(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var activity dtos.TeamsActivity
+	if err := ctx.ShouldBindJSON(&activity); err != nil {
+		log.Errorw("Failed to parse Teams activity", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	if err := c.teamsAdapter.Authenticate(ctx.Request.Context(), ctx.GetHeader("Authorization"), activity.ServiceURL); err != nil {
+		log.Warnw("Invalid Teams activity token", "error", err)
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "Invalid Bot Framework token"))
+		return
+	}
+
+	if err := c.teamsService.HandleActivity(ctx.Request.Context(), &activity); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
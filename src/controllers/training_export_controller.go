@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// TrainingExportController handles HTTP requests for the exports of conversations into
+// fine-tuning datasets
+type TrainingExportController struct {
+	trainingExportService services.TrainingExportService
+}
+
+// NewTrainingExportController creates a new fine-tuning dataset export controller
+func NewTrainingExportController(trainingExportService services.TrainingExportService) *TrainingExportController {
+	return &TrainingExportController{trainingExportService: trainingExportService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *TrainingExportController) RegisterRoutes(router *gin.RouterGroup) {
+	exports := router.Group("/admin/fine-tuning/exports")
+	exports.Use(middlewares.RequireRole(models.RoleAdmin), middlewares.RequireScope(middlewares.ScopeAdmin))
+	{
+		exports.POST("", c.CreateExport)
+		exports.GET("", c.ListExports)
+		exports.GET("/:id", c.GetExport)
+	}
+}
+
+// CreateExport handles queueing an export of the conversations matching filters
+func (c *TrainingExportController) CreateExport(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	var req dtos.TrainingExportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse training export request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	export, err := c.trainingExportService.CreateExport(ctx.Request.Context(), getUserIDFromContext(ctx), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, export)
+}
+
+// ListExports handles listing the latest dataset exports
+func (c *TrainingExportController) ListExports(ctx *gin.Context) {
+	exports, err := c.trainingExportService.ListExports(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, exports)
+}
+
+// GetExport handles getting the status of a dataset export
+func (c *TrainingExportController) GetExport(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse export ID from path
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		log.Errorw("Invalid training export ID", "id", idStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid export ID"))
+		return
+	}
+
+	export, err := c.trainingExportService.GetExport(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, export)
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// VoiceController handles HTTP requests for voice messages
+type VoiceController struct {
+	voiceService services.VoiceService
+}
+
+// NewVoiceController creates a new voice message controller
+func NewVoiceController(voiceService services.VoiceService) *VoiceController {
+	return &VoiceController{voiceService: voiceService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *VoiceController) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/messages/voice", requireSend, c.SendVoiceMessage)
+}
+
+// SendVoiceMessage handles uploading a voice message to a chat. The audio is sent as
+// the "audio" field of a multipart form; the message is transcribed asynchronously.
+func (c *VoiceController) SendVoiceMessage(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Get user ID from JWT token
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse chat ID from query parameter
+	chatIDStr := ctx.Query("chatId")
+	if chatIDStr == "" {
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Missing chat ID"))
+		return
+	}
+
+	chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+	if err != nil {
+		log.Errorw("Invalid chat ID", "chatID", chatIDStr, "error", err)
+		respondError(ctx, errors.New(errors.ErrInvalidRequest, "Invalid chat ID"))
+		return
+	}
+
+	// Parse the audio upload
+	header, err := ctx.FormFile("audio")
+	if err != nil {
+		log.Errorw("Failed to parse voice message upload", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Errorw("Failed to open voice message upload", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+	defer file.Close()
+
+	message, err := c.voiceService.SendVoiceMessage(ctx.Request.Context(), chatID, userID, &dtos.AudioUpload{
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        header.Size,
+		Reader:      file,
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, message)
+}
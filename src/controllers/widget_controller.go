@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// WidgetController handles HTTP requests for embeddable chat widgets: their sessions and
+// configuration, public to the pages embedding them, and their management by admins
+type WidgetController struct {
+	config        configs.Widget
+	widgetService services.WidgetService
+}
+
+// NewWidgetController creates a new widget controller
+func NewWidgetController(config configs.Widget, widgetService services.WidgetService) *WidgetController {
+	return &WidgetController{
+		config:        config,
+		widgetService: widgetService,
+	}
+}
+
+// RegisterRoutes registers the controller routes with the router. Widgets can be managed
+// while widget sessions are disabled.
+func (c *WidgetController) RegisterRoutes(router *gin.RouterGroup) {
+	if c.config.Enabled {
+		widget := router.Group("/widget")
+		// Served without authentication, so they are limited per client IP
+		widget.Use(middlewares.IPRateLimit(c.config.RequestsPerMinute))
+		{
+			widget.POST("/sessions", c.StartSession)
+			widget.GET("/config", c.GetConfig)
+		}
+	}
+
+	widgets := router.Group("/admin/widgets")
+	widgets.Use(middlewares.RequireRole(models.RoleAdmin), middlewares.RequireScope(middlewares.ScopeAdmin))
+	{
+		widgets.POST("", c.CreateWidget)
+		widgets.GET("", c.ListWidgets)
+		widgets.GET("/:id", c.GetWidget)
+		widgets.PUT("/:id", c.UpdateWidget)
+		widgets.DELETE("/:id", c.DeleteWidget)
+	}
+}
+
+// PublicPaths returns the paths of the controller served without authentication
+// under the given route prefix
+func (c *WidgetController) PublicPaths(prefix string) []string {
+	if !c.config.Enabled {
+		return nil
+	}
+	return []string{prefix + "/widget/sessions", prefix + "/widget/config"}
+}
+
+// StartSession handles starting a session of a widget from the page embedding it
+func (c *WidgetController) StartSession(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.WidgetSessionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse widget session request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	session, err := c.widgetService.StartSession(ctx.Request.Context(), req.WidgetID, ctx.GetHeader("Origin"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, session)
+}
+
+// GetConfig handles getting the configuration a widget is rendered with
+func (c *WidgetController) GetConfig(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.WidgetConfigRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse widget config request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	config, err := c.widgetService.GetConfig(ctx.Request.Context(), req.WidgetID, ctx.GetHeader("Origin"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, config)
+}
+
+// CreateWidget handles creating a widget
+func (c *WidgetController) CreateWidget(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request
+	var req dtos.WidgetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse widget request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	widget, err := c.widgetService.CreateWidget(ctx.Request.Context(), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, widget)
+}
+
+// ListWidgets handles listing the widgets
+func (c *WidgetController) ListWidgets(ctx *gin.Context) {
+	widgets, err := c.widgetService.ListWidgets(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, widgets)
+}
+
+// GetWidget handles getting a widget
+func (c *WidgetController) GetWidget(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "widget")
+	if !ok {
+		return
+	}
+
+	widget, err := c.widgetService.GetWidget(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, widget)
+}
+
+// UpdateWidget handles replacing the settings of a widget
+func (c *WidgetController) UpdateWidget(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	id, ok := parseIDParam(ctx, "id", "widget")
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req dtos.WidgetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse widget request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	widget, err := c.widgetService.UpdateWidget(ctx.Request.Context(), id, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, widget)
+}
+
+// DeleteWidget handles deleting a widget
+func (c *WidgetController) DeleteWidget(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx, "id", "widget")
+	if !ok {
+		return
+	}
+
+	if err := c.widgetService.DeleteWidget(ctx.Request.Context(), id); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
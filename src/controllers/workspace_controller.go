@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// WorkspaceController handles HTTP requests for the workspace defaults admins manage, and
+// for the statistics of workspaces
+type WorkspaceController struct {
+	workspaceService      services.WorkspaceService
+	workspaceStatsService services.WorkspaceStatsService
+}
+
+// NewWorkspaceController creates a new workspace controller
+func NewWorkspaceController(workspaceService services.WorkspaceService, workspaceStatsService services.WorkspaceStatsService) *WorkspaceController {
+	return &WorkspaceController{workspaceService: workspaceService, workspaceStatsService: workspaceStatsService}
+}
+
+// RegisterRoutes registers the controller routes with the router
+func (c *WorkspaceController) RegisterRoutes(router *gin.RouterGroup) {
+	workspace := router.Group("/admin/workspace")
+	workspace.Use(middlewares.RequireRole(models.RoleAdmin), middlewares.RequireScope(middlewares.ScopeAdmin))
+	{
+		workspace.GET("/settings", c.GetSettings)
+		workspace.PUT("/settings", c.UpdateSettings)
+	}
+
+	workspaces := router.Group("/admin/workspaces")
+	workspaces.Use(middlewares.RequireRole(models.RoleAdmin), middlewares.RequireScope(middlewares.ScopeAdmin))
+	{
+		workspaces.GET("/:id/stats", c.GetStats)
+	}
+}
+
+// GetSettings handles getting the workspace defaults
+func (c *WorkspaceController) GetSettings(ctx *gin.Context) {
+	settings, err := c.workspaceService.GetSettings(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, settings)
+}
+
+// UpdateSettings handles replacing the workspace defaults
+func (c *WorkspaceController) UpdateSettings(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	adminID := getUserIDFromContext(ctx)
+	if adminID == "" {
+		respondError(ctx, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+		return
+	}
+
+	// Parse request
+	var req dtos.WorkspaceSettingsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Errorw("Failed to parse workspace settings request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	settings, err := c.workspaceService.UpdateSettings(ctx.Request.Context(), adminID, &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, settings)
+}
+
+// GetStats handles getting the request rates, token spend and error rates of a workspace,
+// so that noisy workspaces can be found
+func (c *WorkspaceController) GetStats(ctx *gin.Context) {
+	log := logger.Context(ctx.Request.Context())
+
+	// Parse request parameters
+	var req dtos.WorkspaceStatsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		log.Errorw("Failed to parse workspace stats request", "error", err)
+		respondError(ctx, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid request format"))
+		return
+	}
+
+	stats, err := c.workspaceStatsService.GetStats(ctx.Request.Context(), ctx.Param("id"), &req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, stats)
+}
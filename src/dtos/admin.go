@@ -0,0 +1,79 @@
+package dtos
+
+import (
+	"time"
+)
+
+// LogLevelRequest represents a request to change the log level at runtime
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required,oneof=debug info warn error"`
+}
+
+// LogLevelResponse represents the current log level
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// MaintenanceRequest represents a request to toggle maintenance mode
+type MaintenanceRequest struct {
+	Enabled           *bool  `json:"enabled" binding:"required"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty" binding:"omitempty,min=1"`
+	Message           string `json:"message,omitempty"`
+}
+
+// MaintenanceResponse represents the current maintenance mode state
+type MaintenanceResponse struct {
+	Enabled           bool   `json:"enabled"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds"`
+	Message           string `json:"message"`
+}
+
+// DrainResponse represents the drain state of the server
+type DrainResponse struct {
+	Draining bool `json:"draining"`
+}
+
+// AnnouncementRequest represents a request to broadcast a system message into chats.
+// Without chat or user IDs the announcement is posted to every chat.
+type AnnouncementRequest struct {
+	Content string   `json:"content" binding:"required"`
+	ChatIDs []int64  `json:"chatIds,omitempty"`
+	UserIDs []string `json:"userIds,omitempty"`
+}
+
+// AnnouncementResponse represents the result of broadcasting an announcement
+type AnnouncementResponse struct {
+	Delivered int `json:"delivered"`
+}
+
+// AdminStatsRequest represents a request for the statistics of the service
+type AdminStatsRequest struct {
+	// Days is the number of days covered, up to today; 30 when unset
+	Days int `form:"days" binding:"omitempty,min=1,max=365"`
+}
+
+// AdminStatsResponse represents the statistics of the service since a time
+type AdminStatsResponse struct {
+	Since       time.Time       `json:"since"`
+	Evaluations EvaluationStats `json:"evaluations"`
+}
+
+// EvaluationStats aggregates the conversation quality scores of a period, overall and by day
+type EvaluationStats struct {
+	Evaluations int64 `json:"evaluations"`
+	Replies     int64 `json:"replies"`
+	// RefusalRate is the share of the replies refusing the request
+	RefusalRate float64 `json:"refusalRate"`
+	// AvgHelpfulness is from 1 (unhelpful) to 5 (very helpful), or 0 without evaluations
+	AvgHelpfulness float64              `json:"avgHelpfulness"`
+	Days           []EvaluationDayStats `json:"days"`
+}
+
+// EvaluationDayStats aggregates the conversation quality scores of a day
+type EvaluationDayStats struct {
+	Date           string  `json:"date"` // In UTC, as 2006-01-02
+	Evaluations    int64   `json:"evaluations"`
+	Replies        int64   `json:"replies"`
+	RefusalRate    float64 `json:"refusalRate"`
+	AvgHelpfulness float64 `json:"avgHelpfulness"`
+}
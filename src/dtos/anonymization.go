@@ -0,0 +1,8 @@
+package dtos
+
+// AnonymizationReport represents the result of copying the chats of a region anonymized
+type AnonymizationReport struct {
+	Schema   string `json:"schema"`
+	Chats    int    `json:"chats"`
+	Messages int    `json:"messages"`
+}
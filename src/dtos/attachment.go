@@ -0,0 +1,63 @@
+package dtos
+
+import (
+	"time"
+)
+
+// AttachmentResponse represents a message attachment in API responses
+type AttachmentResponse struct {
+	ID          int64  `json:"id"`
+	MessageID   int64  `json:"messageId"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Status      string `json:"status"`
+	// Threat names the malware found in a quarantined attachment
+	Threat string `json:"threat,omitempty"`
+	// DownloadURL is set once the file is uploaded and found clean, and is valid until DownloadExpiresAt
+	DownloadURL       string     `json:"downloadUrl,omitempty"`
+	DownloadExpiresAt *time.Time `json:"downloadExpiresAt,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// ListAttachmentsResponse represents the attachments of a message in API responses
+type ListAttachmentsResponse struct {
+	Attachments []AttachmentResponse `json:"attachments"`
+}
+
+// CreateUploadRequest represents a request to attach a file to a message through an upload URL
+type CreateUploadRequest struct {
+	Filename    string `json:"filename" binding:"required,max=255"`
+	ContentType string `json:"contentType" binding:"required,max=255"`
+	// Size is the size in bytes of the file to upload
+	Size int64 `json:"size" binding:"required,min=1"`
+}
+
+// UploadURLResponse represents a pending attachment and the URL its file is uploaded to
+type UploadURLResponse struct {
+	Attachment AttachmentResponse `json:"attachment"`
+	// UploadURL receives the file as the body of a PUT request until UploadExpiresAt
+	UploadURL       string    `json:"uploadUrl"`
+	UploadExpiresAt time.Time `json:"uploadExpiresAt"`
+}
+
+// UploadRequest represents a request to store a file through a signed upload URL
+type UploadRequest struct {
+	Key       string `form:"key" binding:"required"`
+	Expires   int64  `form:"expires" binding:"required"`
+	Signature string `form:"signature" binding:"required"`
+}
+
+// AttachmentPayload represents the payload for attachment Kafka messages
+type AttachmentPayload struct {
+	AttachmentID int64  `json:"attachmentId"`
+	MessageID    int64  `json:"messageId"`
+	ChatID       int64  `json:"chatId"`
+	UserID       string `json:"userId"`
+	Filename     string `json:"filename"`
+	ContentType  string `json:"contentType"`
+	Size         int64  `json:"size"`
+	Status       string `json:"status"`
+	// Threat names the malware found on attachment.quarantined
+	Threat string `json:"threat,omitempty"`
+}
@@ -0,0 +1,52 @@
+package dtos
+
+import (
+	"time"
+)
+
+// ImpersonationRequest represents a request of an admin to act as a user. Without scopes the
+// token grants every scope of regular users.
+type ImpersonationRequest struct {
+	UserID string   `json:"userId" binding:"required"`
+	Reason string   `json:"reason" binding:"required,max=1000"`
+	Scopes []string `json:"scopes,omitempty" binding:"omitempty,dive,oneof=chats:read chats:write messages:send"`
+}
+
+// ImpersonationResponse represents a short-lived token acting as a user on behalf of an admin
+type ImpersonationResponse struct {
+	Token     string    `json:"token"`
+	UserID    string    `json:"userId"`
+	AdminID   string    `json:"adminId"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// AuditLogResponse represents an audit log entry in API responses
+type AuditLogResponse struct {
+	ID        int64     `json:"id"`
+	AdminID   string    `json:"adminId"`
+	UserID    string    `json:"userId"`
+	Action    string    `json:"action"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+	ChatID    *int64    `json:"chatId,omitempty"`
+	MessageID *int64    `json:"messageId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListAuditLogsRequest represents a request to list audit log entries
+type ListAuditLogsRequest struct {
+	AdminID string `form:"adminId"`
+	UserID  string `form:"userId"`
+	Action  string `form:"action"`
+	PageRequest
+}
+
+// ListAuditLogsResponse represents a list of audit log entries in API responses
+type ListAuditLogsResponse struct {
+	Entries []AuditLogResponse `json:"entries"`
+	Total   int64              `json:"total"`
+}
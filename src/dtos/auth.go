@@ -0,0 +1,33 @@
+package dtos
+
+import (
+	"time"
+)
+
+// LoginRequest represents a request to exchange a JWT for a session cookie
+type LoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// SessionResponse represents the session started by a login
+type SessionResponse struct {
+	UserID    string    `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// GuestSessionResponse represents a new anonymous guest session
+type GuestSessionResponse struct {
+	Token     string    `json:"token"`
+	UserID    string    `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ClaimGuestChatsRequest represents a request to move the chats of a guest session to the current user
+type ClaimGuestChatsRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ClaimGuestChatsResponse represents the result of claiming guest chats
+type ClaimGuestChatsResponse struct {
+	Claimed int `json:"claimed"`
+}
@@ -0,0 +1,59 @@
+package dtos
+
+import (
+	"time"
+)
+
+// BatchRequest represents a request to process a list of prompts asynchronously
+type BatchRequest struct {
+	// Template is a prompt with {{name}} placeholders filled with the variables of each item
+	Template string             `json:"template"`
+	Items    []BatchItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// BatchItemRequest represents a prompt of a batch
+type BatchItemRequest struct {
+	// ChatID sends the prompt as a message of the chat; without it the prompt is answered on its own
+	ChatID *int64 `json:"chatId"`
+	// Prompt is required unless the batch has a template, which Variables fill instead
+	Prompt    string            `json:"prompt"`
+	Variables map[string]string `json:"variables"`
+}
+
+// BatchResponse represents a batch and its progress in API responses
+type BatchResponse struct {
+	ID          int64      `json:"id"`
+	Status      string     `json:"status"`
+	Total       int        `json:"total"`
+	Completed   int        `json:"completed"`
+	Failed      int        `json:"failed"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// BatchItemResponse represents the result of a prompt of a batch in API responses
+type BatchItemResponse struct {
+	ID          int64      `json:"id"`
+	Position    int        `json:"position"`
+	ChatID      *int64     `json:"chatId,omitempty"`
+	Prompt      string     `json:"prompt"`
+	Status      string     `json:"status"`
+	Response    string     `json:"response,omitempty"`
+	MessageID   *int64     `json:"messageId,omitempty"` // Assistant message of items sent to a chat
+	Model       string     `json:"model,omitempty"`
+	TotalTokens int        `json:"totalTokens,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// ListBatchItemsRequest represents a request to list the results of a batch
+type ListBatchItemsRequest struct {
+	PageRequest
+}
+
+// ListBatchItemsResponse represents a page of the results of a batch
+type ListBatchItemsResponse struct {
+	Items   []BatchItemResponse `json:"items"`
+	Total   int64               `json:"total"`
+	HasMore bool                `json:"hasMore"`
+}
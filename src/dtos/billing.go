@@ -0,0 +1,127 @@
+package dtos
+
+import (
+	"time"
+)
+
+// BillingCurrency is the currency of billed costs, that of the price sheet
+const BillingCurrency = "USD"
+
+// BillingExchange represents a billable exchange: a reply, batch prompt or image generation
+// and its usage
+type BillingExchange struct {
+	// ExchangeID identifies the exchange in billing events and reconciliation, for finance
+	// systems to count each exchange once
+	ExchangeID int64  `json:"exchangeId"`
+	Workspace  string `json:"workspace"`
+	UserID     string `json:"userId"`
+	// ChatID and MessageID are unset for batch prompts answered outside chats
+	ChatID           int64     `json:"chatId,omitempty"`
+	MessageID        int64     `json:"messageId,omitempty"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"promptTokens"`
+	CompletionTokens int       `json:"completionTokens"`
+	TotalTokens      int       `json:"totalTokens"`
+	Images           int       `json:"images,omitempty"`
+	Cost             float64   `json:"cost"`
+	Currency         string    `json:"currency"`
+	CompletedAt      time.Time `json:"completedAt"`
+}
+
+// BillingPayload represents the payload for billing Kafka messages
+type BillingPayload = BillingExchange
+
+// ListBillingExchangesRequest represents a request for the billable exchanges of a period,
+// from inclusive to exclusive, in pages following AfterID
+type ListBillingExchangesRequest struct {
+	From time.Time `form:"from" binding:"required"`
+	To   time.Time `form:"to" binding:"required,gtfield=From"`
+	// AfterID is the nextAfterId of the previous page
+	AfterID int64 `form:"afterId" binding:"omitempty,min=0"`
+	Limit   int   `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// ListBillingExchangesResponse represents a page of the billable exchanges of a period, with
+// the totals of the whole period
+type ListBillingExchangesResponse struct {
+	Exchanges []BillingExchange `json:"exchanges"`
+	Totals    BillingTotals     `json:"totals"`
+	// NextAfterID is set when more exchanges follow
+	NextAfterID *int64 `json:"nextAfterId,omitempty"`
+}
+
+// BillingTotals represents the usage of the billable exchanges of a period
+type BillingTotals struct {
+	Exchanges        int64   `json:"exchanges"`
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	Images           int64   `json:"images"`
+	Cost             float64 `json:"cost"`
+	Currency         string  `json:"currency"`
+}
+
+// VendorUsage represents the usage of a model on a UTC day reported by the LLM vendor
+type VendorUsage struct {
+	Day              time.Time
+	Model            string
+	Requests         int64
+	PromptTokens     int64
+	CompletionTokens int64
+	// Cost is nil when the source reports no cost, in USD otherwise
+	Cost *float64
+}
+
+// UsageReconciliationRequest represents a request for the usage reconciliations of the days
+// from inclusive to exclusive, as 2006-01-02
+type UsageReconciliationRequest struct {
+	From   time.Time `form:"from" time_format:"2006-01-02" time_utc:"1" binding:"required"`
+	To     time.Time `form:"to" time_format:"2006-01-02" time_utc:"1" binding:"required,gtfield=From"`
+	Status string    `form:"status" binding:"omitempty,oneof=matched discrepancy missing_local missing_vendor"`
+}
+
+// UsageReconciliationResponse represents the usage reconciliations of a period with their totals
+type UsageReconciliationResponse struct {
+	Reconciliations []UsageReconciliation `json:"reconciliations"`
+	// Discrepancies counts the reconciliations that are not matched
+	Discrepancies int                       `json:"discrepancies"`
+	Totals        UsageReconciliationTotals `json:"totals"`
+}
+
+// UsageReconciliation represents the usage of a model on a UTC day reported by the LLM vendor
+// next to the usage recorded
+type UsageReconciliation struct {
+	Day    string          `json:"day"`
+	Model  string          `json:"model"`
+	Source string          `json:"source"`
+	Status string          `json:"status"`
+	Vendor ReconciledUsage `json:"vendor"`
+	Local  ReconciledUsage `json:"local"`
+	// TokenDiff and CostDiff are the vendor's figures minus the local ones
+	TokenDiff  int64     `json:"tokenDiff"`
+	CostDiff   *float64  `json:"costDiff,omitempty"`
+	ImportedAt time.Time `json:"importedAt"`
+}
+
+// ReconciledUsage represents usage on one side of a reconciliation
+type ReconciledUsage struct {
+	Requests         int64 `json:"requests"`
+	PromptTokens     int64 `json:"promptTokens"`
+	CompletionTokens int64 `json:"completionTokens"`
+	// Cost is unset when the vendor reports no cost
+	Cost *float64 `json:"cost,omitempty"`
+}
+
+// UsageReconciliationTotals represents the usage of the reconciliations of a period on both
+// sides. The vendor cost only sums the reconciliations with a vendor cost.
+type UsageReconciliationTotals struct {
+	Vendor   ReconciledUsage `json:"vendor"`
+	Local    ReconciledUsage `json:"local"`
+	Currency string          `json:"currency"`
+}
+
+// UsageImportResponse represents the result of importing a usage export of the vendor
+type UsageImportResponse struct {
+	Days            int `json:"days"`
+	Reconciliations int `json:"reconciliations"`
+	Discrepancies   int `json:"discrepancies"`
+}
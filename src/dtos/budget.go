@@ -0,0 +1,56 @@
+package dtos
+
+import (
+	"time"
+)
+
+// Budget scopes
+const (
+	BudgetScopeUser   = "user"
+	BudgetScopeGlobal = "global"
+)
+
+// Budget statuses
+const (
+	BudgetStatusOK       = "ok"
+	BudgetStatusWarning  = "warning"
+	BudgetStatusExceeded = "exceeded"
+)
+
+// BudgetRequest represents a request to set a monthly cost budget
+type BudgetRequest struct {
+	// MonthlyLimit is the most that may be spent in a calendar month, in USD
+	MonthlyLimit float64 `json:"monthlyLimit" binding:"required,gt=0"`
+	// WarnThreshold is the share of the limit whose spending triggers a warning; defaults to the configured one
+	WarnThreshold *float64 `json:"warnThreshold" binding:"omitempty,gt=0,lte=1"`
+}
+
+// BudgetResponse represents the spending of the current month against a budget.
+// Amounts are in USD; the month runs in UTC.
+type BudgetResponse struct {
+	Scope  string `json:"scope"`
+	UserID string `json:"userId,omitempty"`
+	// MonthlyLimit and WarnThreshold are unset when no budget is set
+	MonthlyLimit  *float64 `json:"monthlyLimit,omitempty"`
+	WarnThreshold *float64 `json:"warnThreshold,omitempty"`
+	Spent         float64  `json:"spent"`
+	Remaining     *float64 `json:"remaining,omitempty"`
+	// BurnRate is the average spending per day so far this month
+	BurnRate float64 `json:"burnRate"`
+	// Projected is the spending at the end of the month at the current burn rate
+	Projected   float64   `json:"projected"`
+	Status      string    `json:"status"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+}
+
+// BudgetPayload represents the payload for budget Kafka messages
+type BudgetPayload struct {
+	Scope string `json:"scope"`
+	// UserID is empty for the service-wide budget
+	UserID        string  `json:"userId,omitempty"`
+	Period        string  `json:"period"` // Month, as 2006-01
+	MonthlyLimit  float64 `json:"monthlyLimit"`
+	WarnThreshold float64 `json:"warnThreshold"`
+	Spent         float64 `json:"spent"`
+}
@@ -7,36 +7,171 @@ import (
 // ChatRequest represents a request to create a new chat
 type ChatRequest struct {
 	Title string `json:"title" binding:"required"`
+	// URLContext turns fetching the pages linked in messages into the LLM context on or off;
+	// it is left unchanged when omitted
+	URLContext *bool `json:"urlContext"`
+	// Suggestions turns suggesting follow-up questions after replies on or off; it is left
+	// unchanged when omitted
+	Suggestions *bool `json:"suggestions"`
+	// Language is the language replies are translated into, e.g. fr; an empty string
+	// turns translation off. It is left unchanged when omitted.
+	Language *string `json:"language" binding:"omitempty,max=16"`
+	// Budget caps the usage of the chat; it is left unchanged when omitted, and removed
+	// when both limits are 0
+	Budget *ChatBudgetRequest `json:"budget"`
+	// Tags replace the tags of the chat; they are left unchanged when omitted
+	Tags *[]string `json:"tags" binding:"omitempty,max=20,dive,required,max=64,excludesall=0x2C"`
+	// Archived archives or restores the chat; it is left unchanged when omitted
+	Archived *bool `json:"archived"`
+	// SystemPrompt is the instructions of the chat, layered over those of the workspace and
+	// the user; an empty string removes them. It is left unchanged when omitted.
+	SystemPrompt *string `json:"systemPrompt"`
+	// Ephemeral creates the chat in privacy mode: its messages are kept for a while instead of
+	// being stored, and published without their content. It is ignored on updates.
+	Ephemeral bool `json:"ephemeral"`
+}
+
+// ChatBudgetRequest represents the token and cost budget of a chat
+type ChatBudgetRequest struct {
+	// MaxTokens and MaxCost, in USD, limit the cumulative usage of the replies; 0 is no limit
+	MaxTokens int64   `json:"maxTokens" binding:"min=0"`
+	MaxCost   float64 `json:"maxCost" binding:"min=0"`
+	// Mode is reject to refuse messages once the budget is used, the default, or warn to
+	// only flag them
+	Mode string `json:"mode" binding:"omitempty,oneof=reject warn"`
+}
+
+// TransferChatRequest represents a request to transfer a chat to another user
+type TransferChatRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+// ChatOwner is the user owning a chat and the workspace it was created in
+type ChatOwner struct {
+	UserID      string `json:"userId"`
+	WorkspaceID string `json:"workspaceId,omitempty"`
 }
 
 // ChatResponse represents a chat in API responses
 type ChatResponse struct {
-	ID        int64     `json:"id"`
-	UserID    string    `json:"userId"`
-	Title     string    `json:"title"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID          int64    `json:"id"`
+	PublicID    string   `json:"publicId"` // Accepted wherever the ID is, in paths and the chatId query parameter
+	UserID      string   `json:"userId"`
+	Title       string   `json:"title"`
+	URLContext  bool     `json:"urlContext"`
+	Suggestions bool     `json:"suggestions"`
+	Language    string   `json:"language,omitempty"`
+	Locked      bool     `json:"locked"`             // Locked chats are read-only
+	LockedBy    string   `json:"lockedBy,omitempty"` // owner or admin
+	Tags        []string `json:"tags"`
+	Archived    bool     `json:"archived"`
+	// SystemPrompt is the instructions of the chat
+	SystemPrompt string    `json:"systemPrompt,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	// PinnedMessageID is the message pinned to the top of the chat, such as a summary
+	PinnedMessageID *int64 `json:"pinnedMessageId,omitempty"`
+	// Stats is included in chat lists once the chat has activity
+	Stats *ChatStatsResponse `json:"stats,omitempty"`
+	// Budget is included when the chat has a budget
+	Budget *ChatBudgetResponse `json:"budget,omitempty"`
+	// DeleteAt is when a chat pending deletion is removed, unless the deletion is undone
+	DeleteAt *time.Time `json:"deleteAt,omitempty"`
+	// Ephemeral flags privacy mode chats, whose messages are not retained
+	Ephemeral bool `json:"ephemeral"`
+}
+
+// ChatBudgetResponse represents the budget of a chat and its usage in API responses.
+// Costs are in USD.
+type ChatBudgetResponse struct {
+	MaxTokens  int64   `json:"maxTokens,omitempty"`
+	MaxCost    float64 `json:"maxCost,omitempty"`
+	Mode       string  `json:"mode"`
+	UsedTokens int64   `json:"usedTokens"`
+	UsedCost   float64 `json:"usedCost"`
+	// RemainingTokens and RemainingCost are set for the limits of the budget, and never negative
+	RemainingTokens *int64   `json:"remainingTokens,omitempty"`
+	RemainingCost   *float64 `json:"remainingCost,omitempty"`
+	Exceeded        bool     `json:"exceeded"`
+}
+
+// ChatStatsResponse represents the activity of a chat in API responses
+type ChatStatsResponse struct {
+	MessageCount   int64     `json:"messageCount"`
+	TotalTokens    int64     `json:"totalTokens"`
+	LastActivityAt time.Time `json:"lastActivityAt"`
+}
+
+// UserStatsResponse represents the activity across a user's chats in API responses
+type UserStatsResponse struct {
+	Chats          int64      `json:"chats"`
+	Messages       int64      `json:"messages"`
+	TotalTokens    int64      `json:"totalTokens"`
+	LastActivityAt *time.Time `json:"lastActivityAt,omitempty"`
 }
 
 // ListChatsResponse represents a list of chats in API responses
 type ListChatsResponse struct {
-	Chats []ChatResponse `json:"chats"`
-	Total int64          `json:"total"`
+	Chats          []ChatResponse `json:"chats"`
+	Total          int64          `json:"total"`
+	HasMore        bool           `json:"hasMore"`
+	TotalEstimated bool           `json:"totalEstimated,omitempty"` // Total is a lower bound when counting was skipped
+	// Facets is included in searches asking for it
+	Facets *ChatFacets `json:"facets,omitempty"`
 }
 
-// SearchChatsRequest represents a request to search chats
+// SearchChatsRequest represents a request to search chats by title, narrowed by filters
 type SearchChatsRequest struct {
-	Query  string `form:"query"`
-	Limit  int    `form:"limit,default=10"`
-	Offset int    `form:"offset,default=0"`
+	Query string `form:"query"`
+	// From and To bound when the chats were created
+	From *time.Time `form:"from"`
+	To   *time.Time `form:"to"`
+	Tag  string     `form:"tag"`
+	// Archived keeps only archived chats when true, and leaves them out when false
+	Archived       *bool `form:"archived"`
+	HasAttachments *bool `form:"hasAttachments"`
+	// Model keeps the chats a model replied in
+	Model string `form:"model"`
+	// Facets counts the matching chats per value of each filter
+	Facets bool `form:"facets"`
+	PageRequest
+}
+
+// Filtered reports whether the search is narrowed by filters or asks for facets, which
+// only the database answers
+func (r *SearchChatsRequest) Filtered() bool {
+	return r.From != nil || r.To != nil || r.Tag != "" || r.Archived != nil || r.HasAttachments != nil || r.Model != "" || r.Facets
+}
+
+// ChatFacets counts the chats matching a search per value of each filter. Each facet
+// applies every filter of the search but its own, so it lists the alternatives to it.
+type ChatFacets struct {
+	Tags   []FacetCount `json:"tags"`
+	Models []FacetCount `json:"models"`
+	// Months counts the chats created each month, as 2006-01 in UTC
+	Months []FacetCount `json:"months"`
+	// Archived and HasAttachments count the chats per value, true or false
+	Archived       []FacetCount `json:"archived"`
+	HasAttachments []FacetCount `json:"hasAttachments"`
+}
+
+// FacetCount represents the number of chats with a value of a filter
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
 }
 
 // KafkaMessage is a generic structure for Kafka messages with a typed payload
 type KafkaMessage[T any] struct {
-	ID        string `json:"id"`
-	Event     string `json:"event"`
-	Timestamp int64  `json:"timestamp"`
-	Payload   T      `json:"payload"`
+	ID            string `json:"id"`
+	Event         string `json:"event"`
+	Timestamp     int64  `json:"timestamp"`
+	SchemaVersion int    `json:"schemaVersion"`
+	Producer      string `json:"producer"`
+	TraceID       string `json:"traceId,omitempty"`
+	Payload       T      `json:"payload"`
+	// Headers are sent as Kafka record headers rather than in the message body
+	Headers map[string]string `json:"-"`
 }
 
 // ChatPayload represents the payload for chat-related Kafka messages
@@ -44,4 +179,10 @@ type ChatPayload struct {
 	ChatID int64  `json:"chatId"`
 	UserID string `json:"userId"`
 	Title  string `json:"title"`
+	// MessageIDs lists the messages deleted along with the chat on chat.deleted
+	MessageIDs []int64 `json:"messageIds,omitempty"`
+	// PreviousUserID is the previous owner of the chat on chat.transferred
+	PreviousUserID string `json:"previousUserId,omitempty"`
+	// DeleteAt is when the chat is deleted on chat.delete_requested
+	DeleteAt *time.Time `json:"deleteAt,omitempty"`
 }
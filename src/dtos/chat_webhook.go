@@ -0,0 +1,50 @@
+package dtos
+
+import (
+	"time"
+)
+
+// ChatWebhookRequest represents a request to attach a webhook to a chat
+type ChatWebhookRequest struct {
+	URL string `json:"url" binding:"required,url,max=2048"`
+	// Secret signs the deliveries; a random one is generated when empty
+	Secret string `json:"secret" binding:"omitempty,min=16,max=255"`
+}
+
+// ChatWebhookResponse represents a chat webhook in API responses
+type ChatWebhookResponse struct {
+	ID     int64  `json:"id"`
+	ChatID int64  `json:"chatId"`
+	URL    string `json:"url"`
+	// Secret is only returned when the webhook is created
+	Secret          string     `json:"secret,omitempty"`
+	LastDeliveredAt *time.Time `json:"lastDeliveredAt,omitempty"`
+	// LastError is why the latest exchange given up could not be delivered
+	LastError string    `json:"lastError,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListChatWebhooksResponse represents the webhooks of a chat in API responses
+type ListChatWebhooksResponse struct {
+	Webhooks []ChatWebhookResponse `json:"webhooks"`
+}
+
+// ChatWebhookExchange is the body posted to the webhooks of a chat for each completed exchange
+type ChatWebhookExchange struct {
+	ID     string `json:"id"`    // Identifies the delivery, the same for each attempt
+	Event  string `json:"event"` // exchange.completed
+	ChatID int64  `json:"chatId"`
+	// UserMessage is the latest user message before the reply, which it answers; unset when
+	// there is none
+	UserMessage *MessageResponse `json:"userMessage,omitempty"`
+	Reply       MessageResponse  `json:"reply"`
+	Usage       ChatWebhookUsage `json:"usage"`
+	CompletedAt time.Time        `json:"completedAt"`
+}
+
+// ChatWebhookUsage is the LLM usage of the reply of an exchange
+type ChatWebhookUsage struct {
+	Model       string `json:"model"`
+	TotalTokens int    `json:"totalTokens"`
+	LatencyMs   int64  `json:"latencyMs"`
+}
@@ -0,0 +1,38 @@
+package dtos
+
+import (
+	"time"
+)
+
+// CheckpointRequest represents a request to create a checkpoint of a chat
+type CheckpointRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// RestoreCheckpointRequest represents a request to restore a checkpoint into a new chat
+type RestoreCheckpointRequest struct {
+	// Title of the new chat; defaults to the title of the chat followed by the checkpoint name
+	Title string `json:"title" binding:"omitempty,max=255"`
+}
+
+// CheckpointResponse represents a chat checkpoint in API responses
+type CheckpointResponse struct {
+	ID           int64     `json:"id"`
+	ChatID       int64     `json:"chatId"`
+	Name         string    `json:"name"`
+	SeqNo        int64     `json:"seqNo"`
+	MessageCount int64     `json:"messageCount"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ListCheckpointsResponse represents the checkpoints of a chat in API responses
+type ListCheckpointsResponse struct {
+	Checkpoints []CheckpointResponse `json:"checkpoints"`
+}
+
+// RestoreCheckpointResponse represents the chat a checkpoint was restored into
+type RestoreCheckpointResponse struct {
+	Chat         ChatResponse `json:"chat"`
+	CheckpointID int64        `json:"checkpointId"`
+	MessageCount int          `json:"messageCount"`
+}
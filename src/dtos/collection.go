@@ -0,0 +1,98 @@
+package dtos
+
+import (
+	"time"
+)
+
+// CollectionRequest represents a request to create or update a document collection
+type CollectionRequest struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=4000"`
+	// ChunkSize and ChunkOverlap are in characters; the configured defaults apply to new
+	// collections and the current values are kept on update when omitted
+	ChunkSize    *int `json:"chunkSize" binding:"omitempty,min=1"`
+	ChunkOverlap *int `json:"chunkOverlap" binding:"omitempty,min=0"`
+}
+
+// CollectionResponse represents a document collection in API responses
+type CollectionResponse struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description,omitempty"`
+	ChunkSize    int       `json:"chunkSize"`
+	ChunkOverlap int       `json:"chunkOverlap"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// ListCollectionsResponse represents a list of collections in API responses
+type ListCollectionsResponse struct {
+	Collections []CollectionResponse `json:"collections"`
+}
+
+// DocumentRequest represents a request to add a text document to a collection
+type DocumentRequest struct {
+	Title string `json:"title" binding:"required,max=255"`
+	// Source is where the document comes from, such as a filename or a URL
+	Source  string `json:"source" binding:"max=2048"`
+	Content string `json:"content" binding:"required"`
+}
+
+// DocumentResponse represents a collection document and its processing status in API responses
+type DocumentResponse struct {
+	ID           int64  `json:"id"`
+	CollectionID int64  `json:"collectionId"`
+	Title        string `json:"title"`
+	Source       string `json:"source,omitempty"`
+	// Content is only returned when a single document is read
+	Content string `json:"content,omitempty"`
+	// Status is pending until the document is chunked, then ready, or failed with Error
+	Status    string     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	Chunks    int        `json:"chunks"`
+	IndexedAt *time.Time `json:"indexedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// ListDocumentsResponse represents the documents of a collection in API responses
+type ListDocumentsResponse struct {
+	Documents []DocumentResponse `json:"documents"`
+}
+
+// ReindexResponse represents the documents queued by a re-index
+type ReindexResponse struct {
+	Documents int64 `json:"documents"`
+}
+
+// CrawlRequest represents a request to crawl a website into a collection
+type CrawlRequest struct {
+	URL string `json:"url" binding:"required,url,max=2048"`
+	// Mode is sitemap to crawl the pages of the sitemaps of the site, or of the sitemap at URL,
+	// or links to follow the links of the page at URL
+	Mode string `json:"mode" binding:"required,oneof=sitemap links"`
+	// MaxDepth bounds the links followed from the start page in links mode
+	MaxDepth *int `json:"maxDepth" binding:"omitempty,min=0"`
+	MaxPages int  `json:"maxPages" binding:"omitempty,min=1"`
+}
+
+// CrawlResponse represents a website crawl and its progress in API responses
+type CrawlResponse struct {
+	ID           int64      `json:"id"`
+	CollectionID int64      `json:"collectionId"`
+	URL          string     `json:"url"`
+	Mode         string     `json:"mode"`
+	MaxDepth     int        `json:"maxDepth"`
+	MaxPages     int        `json:"maxPages"`
+	Status       string     `json:"status"`
+	Pages        int        `json:"pages"`
+	Skipped      int        `json:"skipped"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	CompletedAt  *time.Time `json:"completedAt,omitempty"`
+}
+
+// ListCrawlsResponse represents the crawls of a collection in API responses
+type ListCrawlsResponse struct {
+	Crawls []CrawlResponse `json:"crawls"`
+}
@@ -0,0 +1,81 @@
+package dtos
+
+import (
+	"time"
+)
+
+// Actions performed by commands
+const (
+	CommandActionCreateChat  = "create_chat"
+	CommandActionSummarize   = "summarize_chat"
+	CommandActionSetLanguage = "set_chat_language"
+	CommandActionSendMessage = "send_message"
+)
+
+// CommandRequest represents a slash command typed by a user, such as /translate fr
+type CommandRequest struct {
+	Input string `json:"input" binding:"required,max=10000"`
+	// ChatID is the chat the command applies to, required by every command but /new
+	ChatID int64 `json:"chatId" binding:"omitempty,min=1"`
+}
+
+// CommandResponse represents the outcome of a command, with the result of its action
+type CommandResponse struct {
+	// Command is the name of the command run, without its slash
+	Command string `json:"command"`
+	Action  string `json:"action"`
+	// Chat is set by create_chat and set_chat_language
+	Chat *ChatResponse `json:"chat,omitempty"`
+	// Summary is set by summarize_chat
+	Summary *ChatSummaryResponse `json:"summary,omitempty"`
+	// Exchange is set by send_message
+	Exchange *MessageExchangeResponse `json:"exchange,omitempty"`
+}
+
+// CommandInfo describes a command available to users
+type CommandInfo struct {
+	Name        string `json:"name"`
+	Usage       string `json:"usage"`
+	Description string `json:"description,omitempty"`
+	// Custom is set for the commands defined for the workspace
+	Custom bool `json:"custom"`
+	// NeedsChat tells whether the command applies to a chat, given as chatId
+	NeedsChat bool `json:"needsChat"`
+}
+
+// ListCommandsResponse represents the commands available to users, built-in ones first
+type ListCommandsResponse struct {
+	Commands []CommandInfo `json:"commands"`
+}
+
+// CustomCommandRequest represents a request to define a slash command for the workspace
+type CustomCommandRequest struct {
+	// Name is the command without its slash; it cannot change once created
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description" binding:"max=200"`
+	// Template is the prompt sent, with {{input}} replaced by the input of the command; the
+	// input is appended to templates without the placeholder
+	Template string `json:"template" binding:"required,max=10000"`
+}
+
+// UpdateCustomCommandRequest represents a request to update a slash command of the workspace
+type UpdateCustomCommandRequest struct {
+	Description string `json:"description" binding:"max=200"`
+	Template    string `json:"template" binding:"required,max=10000"`
+}
+
+// CustomCommandResponse represents a slash command defined for the workspace
+type CustomCommandResponse struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Template    string    `json:"template"`
+	UpdatedBy   string    `json:"updatedBy,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// ListCustomCommandsResponse represents the slash commands defined for the workspace
+type ListCustomCommandsResponse struct {
+	Commands []CustomCommandResponse `json:"commands"`
+}
@@ -0,0 +1,28 @@
+package dtos
+
+import "time"
+
+// ConsentRequest represents the acceptance of the data-usage terms by the current user
+type ConsentRequest struct {
+	// TermsVersion is the version of the terms accepted; it must be the current version
+	TermsVersion string `json:"termsVersion" binding:"required"`
+}
+
+// ConsentResponse represents the consent state of a user
+type ConsentResponse struct {
+	// TermsVersion and TermsURL identify the current terms
+	TermsVersion string `json:"termsVersion"`
+	TermsURL     string `json:"termsUrl,omitempty"`
+	// Required is set when messages cannot be sent before the current terms are accepted
+	Required bool `json:"required"`
+	// Accepted is set when the user accepted the current terms
+	Accepted bool `json:"accepted"`
+	// History lists the terms versions the user accepted, oldest first
+	History []ConsentRecord `json:"history"`
+}
+
+// ConsentRecord represents the acceptance of a terms version
+type ConsentRecord struct {
+	TermsVersion string    `json:"termsVersion"`
+	AcceptedAt   time.Time `json:"acceptedAt"`
+}
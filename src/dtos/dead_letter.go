@@ -0,0 +1,32 @@
+package dtos
+
+import (
+	"time"
+)
+
+// DeadLetterResponse represents a dead letter in API responses
+type DeadLetterResponse struct {
+	ID         int64             `json:"id"`
+	Topic      string            `json:"topic"`
+	Partition  int               `json:"partition"`
+	Offset     int64             `json:"offset"`
+	Key        string            `json:"key,omitempty"`
+	Payload    string            `json:"payload"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Error      string            `json:"error"`
+	Attempts   int               `json:"attempts"`
+	ReplayedAt *time.Time        `json:"replayedAt,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+}
+
+// ListDeadLettersRequest represents a request to list dead letters
+type ListDeadLettersRequest struct {
+	Topic string `form:"topic"`
+	PageRequest
+}
+
+// ListDeadLettersResponse represents a list of dead letters in API responses
+type ListDeadLettersResponse struct {
+	DeadLetters []DeadLetterResponse `json:"deadLetters"`
+	Total       int64                `json:"total"`
+}
@@ -0,0 +1,86 @@
+package dtos
+
+// Types of the Discord interactions handled
+const (
+	DiscordInteractionPing    = 1
+	DiscordInteractionCommand = 2
+)
+
+// Types of the responses to Discord interactions
+const (
+	DiscordResponsePong = 1
+	// DiscordResponseMessage replies to an interaction with a message
+	DiscordResponseMessage = 4
+	// DiscordResponseDeferred acknowledges an interaction whose reply is sent by editing the
+	// original response
+	DiscordResponseDeferred = 5
+)
+
+// DiscordMessageEphemeral flags messages only the user of the interaction sees
+const DiscordMessageEphemeral = 1 << 6
+
+// DiscordInteraction is an interaction Discord posts to the interactions endpoint of the bot
+type DiscordInteraction struct {
+	ID            string                  `json:"id"`
+	ApplicationID string                  `json:"application_id"`
+	Type          int                     `json:"type"`
+	Data          *DiscordInteractionData `json:"data,omitempty"`
+	// GuildID is empty for interactions in direct messages
+	GuildID   string          `json:"guild_id,omitempty"`
+	ChannelID string          `json:"channel_id,omitempty"`
+	Channel   *DiscordChannel `json:"channel,omitempty"`
+	// Member is the user of interactions in guilds, and User that of direct messages
+	Member *DiscordMember `json:"member,omitempty"`
+	User   *DiscordUser   `json:"user,omitempty"`
+	// Token authorizes editing the response and sending follow-up messages, for 15 minutes
+	Token string `json:"token"`
+}
+
+// DiscordInteractionData is the data of a slash command interaction
+type DiscordInteractionData struct {
+	Name    string                 `json:"name"`
+	Options []DiscordCommandOption `json:"options,omitempty"`
+}
+
+// DiscordCommandOption is an option of a slash command, with the value the user set
+type DiscordCommandOption struct {
+	Name  string      `json:"name"`
+	Type  int         `json:"type"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// DiscordChannel is the channel of an interaction; ParentID is the channel of threads
+type DiscordChannel struct {
+	ID       string `json:"id"`
+	Type     int    `json:"type"`
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+// DiscordMember is a guild member
+type DiscordMember struct {
+	User *DiscordUser `json:"user,omitempty"`
+}
+
+// DiscordUser is a Discord user
+type DiscordUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+// DiscordInteractionResponse is the response to a Discord interaction
+type DiscordInteractionResponse struct {
+	Type int             `json:"type"`
+	Data *DiscordMessage `json:"data,omitempty"`
+}
+
+// DiscordMessage is a message sent to Discord
+type DiscordMessage struct {
+	Content         string                  `json:"content"`
+	Flags           int                     `json:"flags,omitempty"`
+	AllowedMentions *DiscordAllowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+// DiscordAllowedMentions lists the mentions of a message that notify; empty Parse notifies none
+type DiscordAllowedMentions struct {
+	Parse []string `json:"parse"`
+}
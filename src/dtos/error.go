@@ -0,0 +1,16 @@
+package dtos
+
+// ErrorCodeResponse describes an error code of the API in the error catalog
+type ErrorCodeResponse struct {
+	Code string `json:"code"`
+	// Status is the HTTP status of the responses with the code
+	Status int `json:"status"`
+	// Message is the default message of the code; responses may carry a more specific one
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+}
+
+// ErrorCatalogResponse represents the error codes of the API
+type ErrorCatalogResponse struct {
+	Errors []ErrorCodeResponse `json:"errors"`
+}
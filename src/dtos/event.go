@@ -0,0 +1,125 @@
+package dtos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event envelope schema versions
+const (
+	// SchemaVersionV1 is the original envelope without schemaVersion, producer and traceId
+	SchemaVersionV1 = 1
+	// SchemaVersionV2 adds schemaVersion, producer and traceId
+	SchemaVersionV2 = 2
+	// CurrentSchemaVersion is the envelope version produced by this service
+	CurrentSchemaVersion = SchemaVersionV2
+)
+
+// UnknownProducer is the producer of events published before the producer field existed
+const UnknownProducer = "unknown"
+
+// envelopeUpgraders upgrade a raw envelope from the keyed version to the next one
+var envelopeUpgraders = map[int]func(envelope map[string]interface{}){
+	SchemaVersionV1: func(envelope map[string]interface{}) {
+		envelope["producer"] = UnknownProducer
+	},
+}
+
+// DecodeKafkaMessage decodes an event envelope of any supported schema version,
+// upgrading older versions to the current one
+func DecodeKafkaMessage[T any](data []byte) (*KafkaMessage[T], error) {
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode event envelope: %w", err)
+	}
+
+	// Envelopes without schemaVersion predate versioning
+	version := SchemaVersionV1
+	if raw, ok := envelope["schemaVersion"].(float64); ok {
+		version = int(raw)
+	}
+
+	if version < SchemaVersionV1 || version > CurrentSchemaVersion {
+		return nil, fmt.Errorf("unsupported event schema version %d", version)
+	}
+
+	if version < CurrentSchemaVersion {
+		for v := version; v < CurrentSchemaVersion; v++ {
+			if upgrade, ok := envelopeUpgraders[v]; ok {
+				upgrade(envelope)
+			}
+		}
+		envelope["schemaVersion"] = CurrentSchemaVersion
+
+		var err error
+		if data, err = json.Marshal(envelope); err != nil {
+			return nil, fmt.Errorf("failed to upgrade event envelope: %w", err)
+		}
+	}
+
+	var message KafkaMessage[T]
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	return &message, nil
+}
+
+// CloudEvents structured mode constants
+const (
+	CloudEventsSpecVersion = "1.0"
+	CloudEventsContentType = "application/cloudevents+json"
+)
+
+// CloudEvent is an event in CloudEvents 1.0 structured JSON format
+type CloudEvent[T any] struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Subject         string `json:"subject,omitempty"`
+	Time            string `json:"time"`
+	DataContentType string `json:"datacontenttype"`
+	// Extension attributes carrying the envelope fields
+	SchemaVersion int    `json:"schemaversion"`
+	Producer      string `json:"producer,omitempty"`
+	TraceID       string `json:"traceid,omitempty"`
+	Data          T      `json:"data"`
+}
+
+// ToCloudEvent converts the event envelope to a CloudEvent about the given subject, such as chats/42
+func (m *KafkaMessage[T]) ToCloudEvent(source, typePrefix, subject string) *CloudEvent[T] {
+	return &CloudEvent[T]{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              m.ID,
+		Source:          source,
+		Type:            typePrefix + m.Event,
+		Subject:         subject,
+		Time:            time.Unix(m.Timestamp, 0).UTC().Format(time.RFC3339),
+		DataContentType: "application/json",
+		SchemaVersion:   m.SchemaVersion,
+		Producer:        m.Producer,
+		TraceID:         m.TraceID,
+		Data:            m.Payload,
+	}
+}
+
+// ToKafkaMessage converts a CloudEvent back to the event envelope, removing the type prefix
+func (e *CloudEvent[T]) ToKafkaMessage(typePrefix string) *KafkaMessage[T] {
+	timestamp := int64(0)
+	if t, err := time.Parse(time.RFC3339, e.Time); err == nil {
+		timestamp = t.Unix()
+	}
+
+	return &KafkaMessage[T]{
+		ID:            e.ID,
+		Event:         strings.TrimPrefix(e.Type, typePrefix),
+		Timestamp:     timestamp,
+		SchemaVersion: e.SchemaVersion,
+		Producer:      e.Producer,
+		TraceID:       e.TraceID,
+		Payload:       e.Data,
+	}
+}
@@ -0,0 +1,69 @@
+package dtos
+
+import (
+	"time"
+)
+
+// ExperimentRequest represents a request to create an experiment
+type ExperimentRequest struct {
+	Name        string                     `json:"name" binding:"required"`
+	Description string                     `json:"description"`
+	Variants    []ExperimentVariantRequest `json:"variants" binding:"required,min=2,dive"`
+}
+
+// ExperimentVariantRequest represents a variant of an experiment to create
+type ExperimentVariantRequest struct {
+	Name string `json:"name" binding:"required"`
+	// Weight is the share of chats assigned to the variant, relative to the other variants
+	Weight int `json:"weight" binding:"required,min=1"`
+	// Model and SystemPrompt override the defaults when set
+	Model        string `json:"model"`
+	SystemPrompt string `json:"systemPrompt"`
+}
+
+// ExperimentResponse represents an experiment in API responses
+type ExperimentResponse struct {
+	ID          int64                       `json:"id"`
+	Name        string                      `json:"name"`
+	Description string                      `json:"description,omitempty"`
+	Status      string                      `json:"status"`
+	Variants    []ExperimentVariantResponse `json:"variants"`
+	StartedAt   *time.Time                  `json:"startedAt,omitempty"`
+	StoppedAt   *time.Time                  `json:"stoppedAt,omitempty"`
+	CreatedAt   time.Time                   `json:"createdAt"`
+}
+
+// ExperimentVariantResponse represents a variant of an experiment in API responses
+type ExperimentVariantResponse struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Weight       int    `json:"weight"`
+	Model        string `json:"model,omitempty"`
+	SystemPrompt string `json:"systemPrompt,omitempty"`
+}
+
+// ListExperimentsResponse represents a list of experiments in API responses
+type ListExperimentsResponse struct {
+	Experiments []ExperimentResponse `json:"experiments"`
+}
+
+// ExperimentResultsResponse represents the metrics of the variants of an experiment
+type ExperimentResultsResponse struct {
+	Experiment ExperimentResponse `json:"experiment"`
+	Variants   []VariantResults   `json:"variants"`
+}
+
+// VariantResults represents the metrics of the replies generated with a variant
+type VariantResults struct {
+	VariantID        int64   `json:"variantId"`
+	Name             string  `json:"name"`
+	Chats            int64   `json:"chats"`
+	Replies          int64   `json:"replies"`
+	AvgLatencyMs     float64 `json:"avgLatencyMs"`
+	AvgTokens        float64 `json:"avgTokens"`
+	TotalTokens      int64   `json:"totalTokens"`
+	PositiveFeedback int64   `json:"positiveFeedback"`
+	NegativeFeedback int64   `json:"negativeFeedback"`
+	// Satisfaction is the share of positive ratings among the rated replies, when any is rated
+	Satisfaction *float64 `json:"satisfaction,omitempty"`
+}
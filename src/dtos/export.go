@@ -0,0 +1,43 @@
+package dtos
+
+import (
+	"time"
+)
+
+// ExportResponse represents a chat export in API responses
+type ExportResponse struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+	Chats  int    `json:"chats"`
+	Size   int64  `json:"size"`
+	Error  string `json:"error,omitempty"`
+	// DownloadURL is set once the export is completed and is valid until DownloadExpiresAt
+	DownloadURL       string     `json:"downloadUrl,omitempty"`
+	DownloadExpiresAt *time.Time `json:"downloadExpiresAt,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	CompletedAt       *time.Time `json:"completedAt,omitempty"`
+}
+
+// DownloadRequest represents a request to download a file through a signed URL
+type DownloadRequest struct {
+	Key       string `form:"key" binding:"required"`
+	Expires   int64  `form:"expires" binding:"required"`
+	Signature string `form:"signature" binding:"required"`
+}
+
+// ChatExport represents a chat with its messages in an export archive
+type ChatExport struct {
+	Chat        ChatResponse       `json:"chat"`
+	Messages    []MessageResponse  `json:"messages"`
+	Attachments []ExportAttachment `json:"attachments,omitempty"`
+}
+
+// ExportAttachment represents a message attachment in an export archive
+type ExportAttachment struct {
+	MessageID   int64  `json:"messageId"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	// Path locates the file in the archive
+	Path string `json:"path"`
+}
@@ -0,0 +1,16 @@
+package dtos
+
+// ImageRequest represents a request to generate images in a chat
+type ImageRequest struct {
+	Prompt string `json:"prompt" binding:"required,max=4000"`
+	// Count is the number of images to generate, 1 when omitted
+	Count int `json:"count" binding:"omitempty,min=1"`
+}
+
+// ImageExchangeResponse represents the prompt of an image generation and the assistant
+// message holding the generated images
+type ImageExchangeResponse struct {
+	UserMessage      MessageResponse      `json:"userMessage"`
+	AssistantMessage MessageResponse      `json:"assistantMessage"`
+	Images           []AttachmentResponse `json:"images"`
+}
@@ -0,0 +1,31 @@
+package dtos
+
+import (
+	"time"
+)
+
+// UserInstructionsRequest represents a request to set the custom instructions of the current user
+type UserInstructionsRequest struct {
+	// Instructions apply to all the chats of the user; empty removes them
+	Instructions string `json:"instructions"`
+}
+
+// UserInstructionsResponse represents the custom instructions of a user in API responses
+type UserInstructionsResponse struct {
+	Instructions string     `json:"instructions"`
+	UpdatedAt    *time.Time `json:"updatedAt,omitempty"`
+}
+
+// InstructionLayer is a level of the instructions sent to the model
+type InstructionLayer struct {
+	// Source is workspace, user or chat
+	Source  string `json:"source"`
+	Content string `json:"content"`
+}
+
+// ChatInstructionsResponse represents the effective instructions of a chat in API responses
+type ChatInstructionsResponse struct {
+	ChatID int64 `json:"chatId"`
+	// Layers are the instructions set at each level, in the order they are sent to the model
+	Layers []InstructionLayer `json:"layers"`
+}
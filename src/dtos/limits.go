@@ -0,0 +1,33 @@
+package dtos
+
+// LimitsResponse represents the limits in effect for the current user, for clients to adapt
+// to instead of hard-coding them. Sizes are in bytes; a limit of 0 is unbounded.
+type LimitsResponse struct {
+	// MaxMessageSize is the largest content of the messages the user sends or edits
+	MaxMessageSize int `json:"maxMessageSize"`
+	// RequestsPerMinute is the rate limit of the user's requests, unset when not limited
+	RequestsPerMinute *int `json:"requestsPerMinute,omitempty"`
+	// MaxMessages and RemainingMessages are the allowance of messages of guest sessions,
+	// unset for other users
+	MaxMessages       *int `json:"maxMessages,omitempty"`
+	RemainingMessages *int `json:"remainingMessages,omitempty"`
+	// Models are the models replies can be generated with, the default one first
+	Models []string `json:"models"`
+	// ContextWindow is the most tokens of chat history sent to the model, 0 when the history
+	// is only bounded by HistoryMessages
+	ContextWindow   int `json:"contextWindow"`
+	HistoryMessages int `json:"historyMessages"`
+	// MaxReplyTokens is the longest reply generated for the user
+	MaxReplyTokens int `json:"maxReplyTokens"`
+	// MaxTimeoutMs is the longest timeoutMs a message request may set
+	MaxTimeoutMs int64 `json:"maxTimeoutMs"`
+	// MaxAttachmentSize is the largest attachment uploaded, and MaxAudioSize the largest
+	// voice message, unset when transcription is disabled
+	MaxAttachmentSize int64  `json:"maxAttachmentSize"`
+	MaxAudioSize      *int64 `json:"maxAudioSize,omitempty"`
+	// MaxPageSize is the largest limit list endpoints accept
+	MaxPageSize int `json:"maxPageSize"`
+	// Budget is the budget covering the user with the least remaining this month, unset
+	// when budgets are disabled or none is set
+	Budget *BudgetResponse `json:"budget,omitempty"`
+}
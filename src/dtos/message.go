@@ -1,45 +1,385 @@
 package dtos
 
 import (
+	"encoding/json"
+	"io"
 	"time"
 )
 
 // MessageRequest represents a request to create a new message
 type MessageRequest struct {
 	Content string `json:"content" binding:"required"`
+	// ContentType tells how to render the content; text/markdown when omitted, or unchanged
+	// when a message is updated. application/json content must be valid JSON.
+	ContentType string `json:"contentType,omitempty" binding:"omitempty,oneof=text/markdown text/plain application/json"`
+	// TimeoutMs optionally overrides the LLM timeout, bounded by llm.maxTimeout
+	TimeoutMs int `json:"timeoutMs,omitempty" binding:"omitempty,min=1"`
+	// AllowDuplicate sends the message even when it repeats the previous one
+	AllowDuplicate bool `json:"allowDuplicate,omitempty"`
+	// Vendor passes vendor-specific headers and parameters through to the LLM vendor
+	Vendor *VendorOptions `json:"vendor,omitempty"`
+}
+
+// VendorOptions are vendor-specific HTTP headers and body parameters passed through to the
+// LLM vendor, within the allowlists of llm.passthrough
+type VendorOptions struct {
+	Headers map[string]string          `json:"headers,omitempty"`
+	Params  map[string]json.RawMessage `json:"params,omitempty"`
 }
 
 // MessageResponse represents a message in API responses
 type MessageResponse struct {
+	ID       int64   `json:"id"`
+	PublicID string  `json:"publicId"` // Accepted in paths wherever the ID is
+	ChatID   int64   `json:"chatId"`
+	SeqNo    int64   `json:"seqNo"` // Orders the messages of a chat deterministically
+	UserID   *string `json:"userId,omitempty"`
+	Role     string  `json:"role"`
+	Content  string  `json:"content"`
+	// Name is the function or tool a tool or function result came from
+	Name string `json:"name,omitempty"`
+	// ToolCallID is the call of the assistant a tool result answers
+	ToolCallID string `json:"toolCallId,omitempty"`
+	// ExcludeFromPrompt is set on messages never sent to the LLM, such as announcements
+	ExcludeFromPrompt bool `json:"excludeFromPrompt,omitempty"`
+	// ContentType tells how to render the content: text/markdown, text/plain or application/json
+	ContentType string `json:"contentType"`
+	// Kind is summary for conversation summaries, and empty for the other messages
+	Kind string `json:"kind,omitempty"`
+	// Status is set while a voice message is transcribed, or when its transcription failed,
+	// to partial while an assistant reply is generated, and to failed when its generation was
+	// cut off
+	Status string `json:"status,omitempty"`
+	// PersonaID is the group chat persona that wrote an assistant message
+	PersonaID *int64 `json:"personaId,omitempty"`
+	// Model is the LLM model that generated an assistant message, Provider the LLM provider
+	// serving it and ModelVersion the version of the model it reported
+	Model        string `json:"model,omitempty"`
+	Provider     string `json:"provider,omitempty"`
+	ModelVersion string `json:"modelVersion,omitempty"`
+	// Feedback is the user rating of an assistant message: 1, -1, or 0 when not rated
+	Feedback int `json:"feedback,omitempty"`
+	// Suggestions are follow-up questions suggested after an assistant reply, for quick replies
+	Suggestions []string `json:"suggestions,omitempty"`
+	// Reactions counts the emoji reactions to the message, set when messages are listed
+	Reactions []ReactionCount `json:"reactions,omitempty"`
+	// Language is the detected language of a user message, or the language of an assistant
+	// message, when translation is enabled
+	Language string `json:"language,omitempty"`
+	// EditedAt is set once the message content was edited
+	EditedAt  *time.Time `json:"editedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// AudioUpload represents the audio of a voice message
+type AudioUpload struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Reader      io.Reader
+}
+
+// MessageExchangeResponse represents a user message with the assistant reply to it
+type MessageExchangeResponse struct {
+	UserMessage      MessageResponse  `json:"userMessage"`
+	AssistantMessage *MessageResponse `json:"assistantMessage"`
+	// Duplicate is set when the message repeated the previous one, whose exchange is returned;
+	// its reply is null while it is still being generated
+	Duplicate bool `json:"duplicate,omitempty"`
+	// BudgetExceeded is set when the chat had used its budget and only warns about it
+	BudgetExceeded bool `json:"budgetExceeded,omitempty"`
+	// DryRun is set when the message was only checked: the user message was not saved, and
+	// Estimate holds the cost of the reply instead of the reply itself
+	DryRun   bool              `json:"dryRun,omitempty"`
+	Estimate *EstimateResponse `json:"estimate,omitempty"`
+}
+
+// FeedbackRequest represents a user rating of an assistant message; 0 clears the rating
+type FeedbackRequest struct {
+	Rating int `json:"rating" binding:"oneof=-1 0 1"`
+}
+
+// ReactionRequest represents a user adding or taking back an emoji reaction to a message.
+// It sets whether the user reacted with the emoji, so repeating it changes nothing.
+type ReactionRequest struct {
+	Emoji   string `json:"emoji" binding:"required,max=32"`
+	Reacted *bool  `json:"reacted" binding:"required"`
+}
+
+// ReactionCount counts the users who reacted to a message with an emoji
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int64  `json:"count"`
+	// Reacted is set when the requesting user is among them
+	Reacted bool `json:"reacted,omitempty"`
+}
+
+// ReactionsResponse represents the reactions to a message after a reaction request
+type ReactionsResponse struct {
+	MessageID int64           `json:"messageId"`
+	Changed   bool            `json:"changed"` // Unset when the user had already reacted, or not, as requested
+	Reactions []ReactionCount `json:"reactions"`
+}
+
+// CompareRequest represents a request to answer a message with several models side by side
+type CompareRequest struct {
+	Content string `json:"content" binding:"required"`
+	// Models selects some of the models configured for comparison; all of them when omitted
+	Models []string `json:"models"`
+	// TimeoutMs optionally overrides the LLM timeout of every model, bounded by llm.maxTimeout
+	TimeoutMs int `json:"timeoutMs,omitempty" binding:"omitempty,min=1"`
+}
+
+// EstimateRequest represents a request to estimate the cost of sending a message to a chat
+type EstimateRequest struct {
+	ChatID  int64  `json:"chatId" binding:"required"`
+	Content string `json:"content" binding:"required"`
+	// Models selects some of the available models; all of them when omitted
+	Models []string `json:"models"`
+}
+
+// PromptPreviewRequest represents a request to preview the prompt of a chat
+type PromptPreviewRequest struct {
+	// Content previews the prompt once a message with it is sent; when omitted, the prompt
+	// answering the latest messages of the chat
+	Content string `json:"content"`
+}
+
+// PromptPreviewResponse represents the LLM request a reply in a chat would be generated with
+type PromptPreviewResponse struct {
+	ChatID    int64        `json:"chatId"`
+	Model     string       `json:"model"`
+	MaxTokens int          `json:"maxTokens,omitempty"`
+	Messages  []LLMMessage `json:"messages"`
+	// PromptTokens is the size of the messages for the model
+	PromptTokens int `json:"promptTokens"`
+	// PersonaID and VariantID are the persona and experiment variant the reply is routed to
+	PersonaID       *int64 `json:"personaId,omitempty"`
+	VariantID       *int64 `json:"variantId,omitempty"`
+	ModerationLevel string `json:"moderationLevel,omitempty"`
+}
+
+// ModelEstimate represents the estimated cost of a message with one model, in USD
+type ModelEstimate struct {
+	Model string `json:"model"`
+	// PromptTokens is the size of the prompt for the model, whose encoding may differ
+	PromptTokens int     `json:"promptTokens"`
+	PromptCost   float64 `json:"promptCost"`
+	// MaxCost adds the cost of a reply of the most completion tokens allowed
+	MaxCost float64 `json:"maxCost"`
+}
+
+// EstimateResponse represents the estimated prompt tokens and cost of sending a message
+type EstimateResponse struct {
+	// PromptTokens is the estimated size of the prompt: the message with the chat context
+	PromptTokens        int             `json:"promptTokens"`
+	MaxCompletionTokens int             `json:"maxCompletionTokens"`
+	Estimates           []ModelEstimate `json:"estimates"`
+}
+
+// CompareReply represents the reply of one model to a compared message
+type CompareReply struct {
+	Model string `json:"model"`
+	// Message is the assistant reply, or nil when the model failed to answer
+	Message *MessageResponse `json:"message,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// CompareResponse represents a user message with the replies of the compared models, in the
+// order the models were requested
+type CompareResponse struct {
+	UserMessage MessageResponse `json:"userMessage"`
+	Replies     []CompareReply  `json:"replies"`
+}
+
+// MessageRevisionResponse represents a previous version of a message in API responses
+type MessageRevisionResponse struct {
 	ID        int64     `json:"id"`
-	ChatID    int64     `json:"chatId"`
-	UserID    *string   `json:"userId,omitempty"`
-	Role      string    `json:"role"`
+	MessageID int64     `json:"messageId"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ListMessageRevisionsResponse represents the edit history of a message in API responses
+type ListMessageRevisionsResponse struct {
+	Revisions []MessageRevisionResponse `json:"revisions"`
+}
+
+// SummarizeChatRequest represents a request to summarize a conversation
+type SummarizeChatRequest struct {
+	// Pin pins the summary to the top of the chat, in place of the message pinned before
+	Pin bool `json:"pin,omitempty"`
+}
+
+// ChatSummaryResponse represents the summary of a conversation, saved as a message of the chat
+type ChatSummaryResponse struct {
+	Message MessageResponse `json:"message"`
+	Pinned  bool            `json:"pinned"`
+}
+
+// ModelRunResponse represents consecutive assistant messages of a chat generated by the same
+// provider, model and model version
+type ModelRunResponse struct {
+	Provider       string    `json:"provider,omitempty"`
+	Model          string    `json:"model"`
+	ModelVersion   string    `json:"modelVersion,omitempty"`
+	FirstMessageID int64     `json:"firstMessageId"`
+	LastMessageID  int64     `json:"lastMessageId"`
+	Messages       int       `json:"messages"`
+	FirstAt        time.Time `json:"firstAt"`
+	LastAt         time.Time `json:"lastAt"`
+}
+
+// ChatModelHistoryResponse represents the models that generated the replies of a chat in
+// API responses, oldest first; each run starts where the model or its version changed
+type ChatModelHistoryResponse struct {
+	ChatID int64              `json:"chatId"`
+	Runs   []ModelRunResponse `json:"runs"`
+}
+
+// ArtifactResponse represents a code block extracted from a message in API responses
+type ArtifactResponse struct {
+	ID        int64     `json:"id"`
+	MessageID int64     `json:"messageId"`
+	Index     int       `json:"index"`
+	Language  string    `json:"language,omitempty"`
+	Filename  string    `json:"filename"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListArtifactsResponse represents the artifacts of a message in API responses
+type ListArtifactsResponse struct {
+	Artifacts []ArtifactResponse `json:"artifacts"`
 }
 
 // ListMessagesResponse represents a list of messages in API responses
 type ListMessagesResponse struct {
+	Messages       []MessageResponse `json:"messages"`
+	Total          int64             `json:"total"`
+	HasMore        bool              `json:"hasMore"`
+	TotalEstimated bool              `json:"totalEstimated,omitempty"` // Total is a lower bound when counting was skipped
+}
+
+// LookupMessagesRequest represents a request to get messages by ID, up to api.maxLimit
+type LookupMessagesRequest struct {
+	IDs []int64 `json:"ids" binding:"required,min=1"`
+}
+
+// LookupMessagesResponse represents the messages found by ID, in the order requested.
+// Missing lists the IDs of messages that do not exist or are not in the chats of the user.
+type LookupMessagesResponse struct {
 	Messages []MessageResponse `json:"messages"`
-	Total    int64             `json:"total"`
+	Missing  []int64           `json:"missing"`
 }
 
 // ListMessagesRequest represents a request to list messages in a chat
 type ListMessagesRequest struct {
 	ChatID int64 `form:"chatId" binding:"required"`
-	Limit  int   `form:"limit,default=50"`
-	Offset int   `form:"offset,default=0"`
+	Count  bool  `form:"count,default=true"` // false skips the exact total count
+	PageRequest
+}
+
+// DeleteMessagesRequest represents a request to delete the messages of a chat matching
+// filters; messages must match every filter given, and at least one is required
+type DeleteMessagesRequest struct {
+	// Before deletes the messages created before a time
+	Before *time.Time `form:"before"`
+	Role   string     `form:"role" binding:"omitempty,oneof=user assistant system tool function"`
+	// IDs deletes the listed messages, given as repeated ids parameters
+	IDs []int64 `form:"ids" binding:"max=1000,dive,min=1"`
+}
+
+// DeleteMessagesResponse represents the messages deleted from a chat
+type DeleteMessagesResponse struct {
+	Deleted    int     `json:"deleted"`
+	MessageIDs []int64 `json:"messageIds"`
+}
+
+// ResumeStreamRequest represents a request to resume the stream of a reply
+type ResumeStreamRequest struct {
+	Token string `form:"token" binding:"required"`
+	// From is the number of the first chunk to send; without it, the stream resumes after the
+	// Last-Event-ID header, or from the start
+	From *int `form:"from" binding:"omitempty,min=0"`
+}
+
+// WaitMessageRequest represents a request to wait for an assistant reply to complete
+type WaitMessageRequest struct {
+	// TimeoutMs is how long to wait; 30000 when unset
+	TimeoutMs int `form:"timeoutMs" binding:"omitempty,min=0,max=60000"`
+}
+
+// StreamChunk represents a numbered chunk of a resumed reply stream
+type StreamChunk struct {
+	Index   int    `json:"index"`
+	Content string `json:"content"`
+}
+
+// Reasons streamed replies abort for
+const (
+	StreamAbortProviderError   = "provider_error"
+	StreamAbortTimeout         = "timeout"
+	StreamAbortRateLimited     = "rate_limited"
+	StreamAbortContentFiltered = "content_filtered"
+	StreamAbortInternal        = "internal"
+)
+
+// StreamAbort represents the terminal error event of a streamed reply that failed after it
+// started, telling clients whether and how to recover
+type StreamAbort struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	// Retryable tells whether the reply may succeed when generated again
+	Retryable bool `json:"retryable"`
+	// RetryAfterMs is how long to wait before retrying, when known
+	RetryAfterMs int64 `json:"retryAfterMs,omitempty"`
+	// MessageID is the failed reply, kept with the content streamed so far, which
+	// POST /messages/:id/retry generates again; it is set when the stream is resumable
+	MessageID int64 `json:"messageId,omitempty"`
+	// StreamToken reads the content streamed so far again from GET /messages/:id/stream
+	StreamToken string `json:"streamToken,omitempty"`
 }
 
 // MessagePayload represents the payload for message-related Kafka messages
 type MessagePayload struct {
 	MessageID int64   `json:"messageId"`
 	ChatID    int64   `json:"chatId"`
+	SeqNo     int64   `json:"seqNo"` // Lets consumers detect gaps and duplicates within a chat
 	UserID    *string `json:"userId,omitempty"`
 	Role      string  `json:"role"`
 	Content   string  `json:"content"`
+	// ContentType tells how to render the content, set along with it
+	ContentType string `json:"contentType,omitempty"`
+	// Kind is summary for conversation summaries, and empty for the other messages
+	Kind string `json:"kind,omitempty"`
+	// ContentTruncated is set when Content is a preview of content too large to be published,
+	// or empty; the full content is read through the API
+	ContentTruncated bool `json:"contentTruncated,omitempty"`
+	// ContentHash is the hex SHA-256 of the full content, set when it is truncated
+	ContentHash string `json:"contentHash,omitempty"`
+	PersonaID   *int64 `json:"personaId,omitempty"` // Group chat persona of assistant messages
+	Model       string `json:"model,omitempty"`     // LLM model of assistant messages
+	// TotalTokens is the LLM token usage of generated assistant messages
+	TotalTokens int `json:"totalTokens,omitempty"`
+	// Partial is set on message.updated for the content saved while a reply is generated, or
+	// when its generation was cut off; the completed reply is published as message.created
+	Partial bool `json:"partial,omitempty"`
+	// Reaction is set on message.reaction_added and message.reaction_removed
+	Reaction *ReactionPayload `json:"reaction,omitempty"`
+	// Ephemeral is set for the messages of privacy mode chats, published without their
+	// content, which cannot be read back once they expire
+	Ephemeral bool `json:"ephemeral,omitempty"`
+}
+
+// ReactionPayload is the reaction of a message event, with the reactions to the message
+// once it was added or removed
+type ReactionPayload struct {
+	UserID    string          `json:"userId"`
+	Emoji     string          `json:"emoji"`
+	Reactions []ReactionCount `json:"reactions"`
 }
 
 // LLMRequest represents a request to the LLM vendor service
@@ -53,14 +393,31 @@ type LLMRequest struct {
 type LLMMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// Name is the function or tool of tool and function results
+	Name string `json:"name,omitempty"`
+	// ToolCallID is the call of the assistant a tool result answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 // LLMResponse represents a response from the LLM vendor service
 type LLMResponse struct {
-	Message  LLMMessage `json:"message"`
-	Usage    LLMUsage   `json:"usage"`
-	Model    string     `json:"model"`
-	Finished bool       `json:"finished"`
+	Message LLMMessage `json:"message"`
+	Usage   LLMUsage   `json:"usage"`
+	Model   string     `json:"model"`
+	// ModelVersion is the version of the model reported by the provider, when it reports one
+	ModelVersion string `json:"model_version,omitempty"`
+	Finished     bool   `json:"finished"`
+}
+
+// LLMChunk represents a partial piece of a streamed LLM response
+type LLMChunk struct {
+	Index   int    `json:"index"`
+	Content string `json:"content"`
+	Done    bool   `json:"done"`
+	// MessageID and StreamToken identify the buffered stream of a reply, which can be resumed;
+	// they are set on the chunks passed on to clients, whose Index is then the chunk number
+	MessageID   int64  `json:"messageId,omitempty"`
+	StreamToken string `json:"streamToken,omitempty"`
 }
 
 // LLMUsage represents token usage information from the LLM vendor
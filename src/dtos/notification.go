@@ -0,0 +1,180 @@
+package dtos
+
+import (
+	"fmt"
+	"time"
+)
+
+// Email represents an email to send
+type Email struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+	// ListUnsubscribe is a URL unsubscribing the recipient with a POST, offered to mail
+	// clients as a one-click unsubscribe (RFC 8058)
+	ListUnsubscribe string
+}
+
+// NotificationPreferencesRequest represents a request to set the current user's notification preferences
+type NotificationPreferencesRequest struct {
+	// Email is the address notifications are sent to; empty disables all notifications
+	Email string `json:"email" binding:"omitempty,email"`
+	// Triggers maps notification triggers to whether they are enabled
+	Triggers map[string]bool `json:"triggers"`
+}
+
+// NotificationPreferencesResponse represents a user's notification preferences
+type NotificationPreferencesResponse struct {
+	Email    string          `json:"email"`
+	Triggers map[string]bool `json:"triggers"`
+}
+
+// NotificationSettingsRequest represents a request to set the current user's notification
+// settings. Settings left out keep their current value.
+type NotificationSettingsRequest struct {
+	// Email is the address notifications are emailed to; empty disables email notifications
+	Email *string `json:"email" binding:"omitempty,len=0|email"`
+	// WebhookURL is the URL notifications are posted to; empty disables webhook notifications
+	WebhookURL *string `json:"webhookUrl" binding:"omitempty,len=0|url"`
+	// WebhookSecret signs webhook deliveries with HMAC-SHA256; empty leaves them unsigned
+	WebhookSecret *string `json:"webhookSecret" binding:"omitempty,max=255"`
+	// Digest is daily or weekly to receive a digest of the activity by email in place of
+	// the individual emails of the triggers it covers, or off
+	Digest *string `json:"digest" binding:"omitempty,oneof=off daily weekly"`
+	// Triggers maps notification triggers to whether each of their channels is enabled
+	Triggers map[string]map[string]bool `json:"triggers"`
+}
+
+// NotificationSettingsResponse represents a user's notification settings
+type NotificationSettingsResponse struct {
+	Email      string `json:"email"`
+	WebhookURL string `json:"webhookUrl"`
+	// WebhookSigned reports whether webhook deliveries are signed
+	WebhookSigned bool `json:"webhookSigned"`
+	// Digest is the frequency of the activity digest, or off
+	Digest string `json:"digest"`
+	// Channels lists the channels notifications can be delivered through
+	Channels []string                   `json:"channels"`
+	Triggers map[string]map[string]bool `json:"triggers"`
+}
+
+// DigestUnsubscribeRequest represents the query of the unsubscribe link of an activity digest
+type DigestUnsubscribeRequest struct {
+	UserID string `form:"user" binding:"required"`
+	// Region is the data residency region of the user, empty for the main one
+	Region    string `form:"region"`
+	Signature string `form:"signature" binding:"required"`
+}
+
+// DigestNotification is the data of the email of an activity digest
+type DigestNotification struct {
+	Frequency string       `json:"frequency"`
+	Items     []DigestItem `json:"items"`
+	// More counts the notifications left for the next digest
+	More int64 `json:"more"`
+	// Budget reports the spending of the month, when the user is covered by a budget
+	Budget         string `json:"budget,omitempty"`
+	UnsubscribeURL string `json:"unsubscribeUrl"`
+}
+
+// DigestItem is a notification listed in an activity digest
+type DigestItem struct {
+	Subject   string    `json:"subject"`
+	Summary   string    `json:"summary"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookNotification is the body of a notification posted to the webhook of a user
+type WebhookNotification struct {
+	ID        string      `json:"id"`
+	Trigger   string      `json:"trigger"`
+	Subject   string      `json:"subject"`
+	Summary   string      `json:"summary"`
+	Data      interface{} `json:"data"`
+	Timestamp int64       `json:"timestamp"`
+}
+
+// ExportReadyNotification is the data of the notification sent when an export is completed
+type ExportReadyNotification struct {
+	Chats       int       `json:"chats"`
+	DownloadURL string    `json:"downloadUrl"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// Summary summarizes the notification in a sentence, for push notifications
+func (n *ExportReadyNotification) Summary() string {
+	return fmt.Sprintf("The export of your %d chats is ready to download.", n.Chats)
+}
+
+// ScheduledPromptNotification is the data of the notification sent with the result of a scheduled prompt
+type ScheduledPromptNotification struct {
+	ChatID    int64  `json:"chatId"`
+	ChatTitle string `json:"chatTitle"`
+	Prompt    string `json:"prompt"`
+	Response  string `json:"response"`
+}
+
+// Summary summarizes the notification in a sentence, for push notifications
+func (n *ScheduledPromptNotification) Summary() string {
+	return fmt.Sprintf("Your scheduled prompt in %s has been answered.", n.ChatTitle)
+}
+
+// ShareInvitationNotification is the data of the notification sent when a chat is shared with a user
+type ShareInvitationNotification struct {
+	InviterID string `json:"inviterId"`
+	ChatTitle string `json:"chatTitle"`
+	URL       string `json:"url"`
+}
+
+// Summary summarizes the notification in a sentence, for push notifications
+func (n *ShareInvitationNotification) Summary() string {
+	return fmt.Sprintf("%s invited you to the chat %s.", n.InviterID, n.ChatTitle)
+}
+
+// ReplyDoneNotification is the data of the notification sent when the replies to a batch of
+// prompts are done
+type ReplyDoneNotification struct {
+	BatchID   int64 `json:"batchId"`
+	Completed int   `json:"completed"`
+	Failed    int   `json:"failed"`
+}
+
+// Summary summarizes the notification in a sentence, for push notifications
+func (n *ReplyDoneNotification) Summary() string {
+	if n.Failed > 0 {
+		return fmt.Sprintf("%d prompts of your batch were answered and %d failed.", n.Completed, n.Failed)
+	}
+	return fmt.Sprintf("The %d prompts of your batch were answered.", n.Completed)
+}
+
+// SharedChatActivityNotification is the data of the notification sent when another user posts
+// in a chat of the user
+type SharedChatActivityNotification struct {
+	ChatID    int64  `json:"chatId"`
+	ChatTitle string `json:"chatTitle"`
+	AuthorID  string `json:"authorId"`
+	Content   string `json:"content"`
+}
+
+// Summary summarizes the notification in a sentence, for push notifications
+func (n *SharedChatActivityNotification) Summary() string {
+	return fmt.Sprintf("%s posted in %s.", n.AuthorID, n.ChatTitle)
+}
+
+// BudgetAlertNotification is the data of the notification sent when the budget of the user
+// reaches its warning threshold or is exceeded
+type BudgetAlertNotification struct {
+	Exceeded     bool    `json:"exceeded"`
+	Period       string  `json:"period"`
+	Spent        float64 `json:"spent"`
+	MonthlyLimit float64 `json:"monthlyLimit"`
+}
+
+// Summary summarizes the notification in a sentence, for push notifications
+func (n *BudgetAlertNotification) Summary() string {
+	if n.Exceeded {
+		return fmt.Sprintf("You spent your budget of $%.2f for %s.", n.MonthlyLimit, n.Period)
+	}
+	return fmt.Sprintf("You spent $%.2f of your budget of $%.2f for %s.", n.Spent, n.MonthlyLimit, n.Period)
+}
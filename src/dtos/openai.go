@@ -0,0 +1,143 @@
+package dtos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// OpenAI object types
+const (
+	OpenAIObjectCompletion      = "chat.completion"
+	OpenAIObjectCompletionChunk = "chat.completion.chunk"
+	OpenAIObjectList            = "list"
+	OpenAIObjectModel           = "model"
+)
+
+// ChatCompletionRequest represents an OpenAI chat completion request
+type ChatCompletionRequest struct {
+	Model    string                  `json:"model"`
+	Messages []ChatCompletionMessage `json:"messages" binding:"required,min=1,dive"`
+	Stream   bool                    `json:"stream"`
+	// StreamOptions.IncludeUsage adds a last chunk with the token usage to streams
+	StreamOptions *ChatCompletionStreamOptions `json:"stream_options"`
+	// MaxTokens is the deprecated name of MaxCompletionTokens, which wins when both are set
+	MaxTokens           int `json:"max_tokens" binding:"omitempty,min=1"`
+	MaxCompletionTokens int `json:"max_completion_tokens" binding:"omitempty,min=1"`
+	// Vendor passes vendor-specific headers and parameters through to the LLM vendor
+	Vendor *VendorOptions `json:"vendor,omitempty"`
+}
+
+// ChatCompletionStreamOptions represents the options of a streamed OpenAI chat completion
+type ChatCompletionStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ChatCompletionMessage represents a message of an OpenAI chat completion request
+type ChatCompletionMessage struct {
+	Role    string                `json:"role" binding:"required,oneof=system developer user assistant tool function"`
+	Content ChatCompletionContent `json:"content"`
+	// Name is the function of function results
+	Name string `json:"name,omitempty" binding:"required_if=Role function"`
+	// ToolCallID is the call of the assistant a tool result answers
+	ToolCallID string `json:"tool_call_id,omitempty" binding:"required_if=Role tool"`
+}
+
+// ChatCompletionContent is the content of an OpenAI message, sent either as a string or
+// as a list of parts of which only the text ones are supported
+type ChatCompletionContent string
+
+// UnmarshalJSON decodes a string content, or joins the text of a list of content parts
+func (c *ChatCompletionContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = ChatCompletionContent(text)
+		return nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("content must be a string or a list of content parts")
+	}
+
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != "text" {
+			return fmt.Errorf("content parts of type %q are not supported", part.Type)
+		}
+		texts = append(texts, part.Text)
+	}
+	*c = ChatCompletionContent(strings.Join(texts, "\n"))
+	return nil
+}
+
+// ChatCompletionResponse represents an OpenAI chat completion
+type ChatCompletionResponse struct {
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []ChatCompletionChoice `json:"choices"`
+	Usage   *ChatCompletionUsage   `json:"usage,omitempty"`
+}
+
+// ChatCompletionChoice represents a choice of an OpenAI chat completion
+type ChatCompletionChoice struct {
+	Index        int                  `json:"index"`
+	Message      *ChatCompletionReply `json:"message,omitempty"`
+	Delta        *ChatCompletionReply `json:"delta,omitempty"`
+	FinishReason *string              `json:"finish_reason"`
+}
+
+// ChatCompletionReply represents the message, or the delta of a chunk, of an OpenAI chat completion choice
+type ChatCompletionReply struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// ChatCompletionUsage represents the token usage of an OpenAI chat completion
+type ChatCompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// OpenAIModel represents a model in the OpenAI model list
+type OpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// OpenAIModelList represents the OpenAI model list
+type OpenAIModelList struct {
+	Object string        `json:"object"`
+	Data   []OpenAIModel `json:"data"`
+}
+
+// OpenAIErrorResponse represents an error in the OpenAI format
+type OpenAIErrorResponse struct {
+	Error OpenAIError `json:"error"`
+}
+
+// OpenAIError represents the details of an error in the OpenAI format
+type OpenAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	// Abort tells why a stream ended with the error, and how to recover; an extension of
+	// the format, only set in streams
+	Abort *StreamAbort `json:"abort,omitempty"`
+}
+
+// CompletionResult represents the reply to a chat completion request saved to a chat
+type CompletionResult struct {
+	ChatID  int64
+	Message *MessageResponse
+	Model   string
+	Usage   LLMUsage
+}
@@ -0,0 +1,13 @@
+package dtos
+
+// PageRequest holds the pagination query parameters of list requests. A zero limit
+// takes the configured default.
+type PageRequest struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+// Page returns the pagination parameters of a list request embedding them
+func (p *PageRequest) Page() *PageRequest {
+	return p
+}
@@ -0,0 +1,38 @@
+package dtos
+
+import (
+	"time"
+)
+
+// PersonaRequest represents a request to add an assistant persona to a chat
+type PersonaRequest struct {
+	// Handle addresses the persona in messages as @handle
+	Handle       string `json:"handle" binding:"required"`
+	Name         string `json:"name" binding:"required"`
+	Model        string `json:"model"`
+	SystemPrompt string `json:"systemPrompt"`
+}
+
+// UpdatePersonaRequest represents a request to update a persona; its handle cannot change
+type UpdatePersonaRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Model        string `json:"model"`
+	SystemPrompt string `json:"systemPrompt"`
+}
+
+// PersonaResponse represents an assistant persona in API responses
+type PersonaResponse struct {
+	ID           int64     `json:"id"`
+	ChatID       int64     `json:"chatId"`
+	Handle       string    `json:"handle"`
+	Name         string    `json:"name"`
+	Model        string    `json:"model,omitempty"`
+	SystemPrompt string    `json:"systemPrompt,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// ListPersonasResponse represents the personas of a chat in API responses
+type ListPersonasResponse struct {
+	Personas []PersonaResponse `json:"personas"`
+}
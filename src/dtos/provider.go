@@ -0,0 +1,27 @@
+package dtos
+
+import "time"
+
+// ProviderStatusResponse represents the health of the LLM providers in API responses
+type ProviderStatusResponse struct {
+	Providers []ProviderStatus `json:"providers"`
+	// WindowSeconds is how far back the request outcomes of the statistics go
+	WindowSeconds int64 `json:"windowSeconds"`
+}
+
+// ProviderStatus represents the health of an LLM provider
+type ProviderStatus struct {
+	Name  string `json:"name"`
+	Model string `json:"model"`
+	// Status is healthy, degraded or unavailable
+	Status string `json:"status"`
+	// Requests is the number of requests sent to the provider within the window
+	Requests int `json:"requests"`
+	// SuccessRate and MedianLatencyMs are omitted without requests within the window
+	SuccessRate     *float64 `json:"successRate,omitempty"`
+	MedianLatencyMs *int64   `json:"medianLatencyMs,omitempty"`
+	// CircuitBreaker is closed, open, half_open or disabled
+	CircuitBreaker string `json:"circuitBreaker"`
+	// RetryAt is when an open circuit lets a trial request through
+	RetryAt *time.Time `json:"retryAt,omitempty"`
+}
@@ -0,0 +1,42 @@
+package dtos
+
+import (
+	"time"
+)
+
+// PushNotification represents a notification pushed to a mobile device
+type PushNotification struct {
+	Title string
+	Body  string
+	// ThreadID groups the notifications of a chat on the device
+	ThreadID string
+	// Data is delivered to the app along with the notification
+	Data map[string]string
+}
+
+// RegisterDeviceRequest represents a request to register a device for push notifications
+type RegisterDeviceRequest struct {
+	// Platform is fcm for Android devices or apns for iOS devices
+	Platform string `json:"platform" binding:"required,oneof=fcm apns"`
+	Token    string `json:"token" binding:"required"`
+}
+
+// DeviceResponse represents a registered device in API responses
+type DeviceResponse struct {
+	Platform  string    `json:"platform"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// MuteChatRequest represents a request to mute the push notifications of a chat
+type MuteChatRequest struct {
+	// Until is when the chat is unmuted; the chat stays muted when omitted
+	Until *time.Time `json:"until"`
+}
+
+// ChatMuteResponse represents the mute setting of a chat in API responses
+type ChatMuteResponse struct {
+	ChatID int64      `json:"chatId"`
+	Muted  bool       `json:"muted"`
+	Until  *time.Time `json:"until,omitempty"`
+}
@@ -0,0 +1,35 @@
+package dtos
+
+// RetentionPolicyRequest represents a request to set the retention of the current user's messages
+type RetentionPolicyRequest struct {
+	// Days after which messages are deleted; 0 keeps messages forever
+	Days *int `json:"days" binding:"required,min=0"`
+}
+
+// RetentionPolicyResponse represents the retention applied to a user's messages
+type RetentionPolicyResponse struct {
+	Days int `json:"days"`
+	// Default is set when the user has no policy and the service default applies
+	Default bool `json:"default"`
+}
+
+// RetentionReport represents the result of a retention purge
+type RetentionReport struct {
+	DryRun bool `json:"dryRun"`
+	// Messages counts the messages deleted, or that would be deleted on a dry run
+	Messages int64                   `json:"messages"`
+	Policies []RetentionPolicyReport `json:"policies"`
+}
+
+// RetentionPolicyReport represents the messages purged under one retention policy
+type RetentionPolicyReport struct {
+	// UserID is empty for the default policy
+	UserID   string `json:"userId,omitempty"`
+	Days     int    `json:"days"`
+	Messages int64  `json:"messages"`
+}
+
+// PurgeRetentionRequest represents a request to run the retention purge
+type PurgeRetentionRequest struct {
+	DryRun bool `form:"dryRun"`
+}
@@ -0,0 +1,56 @@
+package dtos
+
+import (
+	"time"
+)
+
+// Saved search periods, date ranges relative to when a saved search runs, in UTC
+const (
+	SearchPeriodToday      = "today"
+	SearchPeriodLast7Days  = "last_7_days"
+	SearchPeriodLast30Days = "last_30_days"
+	SearchPeriodThisMonth  = "this_month"
+	SearchPeriodLastMonth  = "last_month"
+)
+
+// SavedSearchRequest represents a request to create or replace a saved search
+type SavedSearchRequest struct {
+	Name    string             `json:"name" binding:"required,max=255"`
+	Filters SavedSearchFilters `json:"filters"`
+}
+
+// SavedSearchFilters represents the chat search of a saved search, with the filters of
+// chat searches
+type SavedSearchFilters struct {
+	Query string `json:"query,omitempty" binding:"max=255"`
+	// Period bounds when the chats were created relative to when the search runs, and
+	// excludes From and To
+	Period         string     `json:"period,omitempty" binding:"omitempty,oneof=today last_7_days last_30_days this_month last_month"`
+	From           *time.Time `json:"from,omitempty"`
+	To             *time.Time `json:"to,omitempty"`
+	Tag            string     `json:"tag,omitempty" binding:"max=64"`
+	Archived       *bool      `json:"archived,omitempty"`
+	HasAttachments *bool      `json:"hasAttachments,omitempty"`
+	Model          string     `json:"model,omitempty" binding:"max=255"`
+}
+
+// SavedSearchResponse represents a saved search in API responses
+type SavedSearchResponse struct {
+	ID        int64              `json:"id"`
+	Name      string             `json:"name"`
+	Filters   SavedSearchFilters `json:"filters"`
+	CreatedAt time.Time          `json:"createdAt"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+}
+
+// ListSavedSearchesResponse represents a list of saved searches in API responses
+type ListSavedSearchesResponse struct {
+	SavedSearches []SavedSearchResponse `json:"savedSearches"`
+}
+
+// RunSavedSearchRequest represents a request for a page of the chats of a saved search
+type RunSavedSearchRequest struct {
+	// Facets counts the matching chats per value of each filter
+	Facets bool `form:"facets"`
+	PageRequest
+}
@@ -0,0 +1,31 @@
+package dtos
+
+import (
+	"time"
+)
+
+// ChatDocument represents a chat in the search index
+type ChatDocument struct {
+	ChatID    int64     `json:"chat_id"`
+	UserID    string    `json:"user_id"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MessageDocument represents a message in the search index. UserID is the owner of its chat.
+type MessageDocument struct {
+	MessageID int64     `json:"message_id"`
+	ChatID    int64     `json:"chat_id"`
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SearchMessagesRequest represents a request to search the messages of the user's chats,
+// optionally within a chat
+type SearchMessagesRequest struct {
+	Query  string `form:"query" binding:"required"`
+	ChatID int64  `form:"chatId"`
+	PageRequest
+}
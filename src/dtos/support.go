@@ -0,0 +1,31 @@
+package dtos
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SupportCaptureResponse represents an LLM call captured for support in API responses
+type SupportCaptureResponse struct {
+	ID     int64  `json:"id"`
+	Model  string `json:"model"`
+	Route  string `json:"route,omitempty"`
+	UserID string `json:"userId,omitempty"`
+	// Request and Response are the LLM request and response as sent and received; Response
+	// is null when the call failed with Error
+	Request      json.RawMessage `json:"request"`
+	Response     json.RawMessage `json:"response"`
+	Error        string          `json:"error,omitempty"`
+	Streaming    bool            `json:"streaming"`
+	Chunks       int             `json:"chunks,omitempty"`
+	FirstChunkMs int64           `json:"firstChunkMs,omitempty"`
+	DurationMs   int64           `json:"durationMs"`
+	StartedAt    time.Time       `json:"startedAt"`
+	ExpiresAt    time.Time       `json:"expiresAt"`
+}
+
+// SupportBundleResponse represents the LLM calls captured for a request
+type SupportBundleResponse struct {
+	RequestID string                   `json:"requestId"`
+	Captures  []SupportCaptureResponse `json:"captures"`
+}
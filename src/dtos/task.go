@@ -0,0 +1,61 @@
+package dtos
+
+import (
+	"time"
+)
+
+// ExtractTasksRequest represents a request to extract the action items of a chat
+type ExtractTasksRequest struct {
+	// Tracker names a configured tracker the extracted tasks are exported to
+	Tracker string `json:"tracker" binding:"omitempty,max=100"`
+}
+
+// ExportTasksRequest represents a request to export tasks of a chat to a tracker
+type ExportTasksRequest struct {
+	Tracker string `json:"tracker" binding:"required,max=100"`
+	// IDs selects the tasks exported; the open tasks not exported yet when omitted
+	IDs []int64 `json:"ids" binding:"omitempty,max=100"`
+}
+
+// UpdateTaskRequest represents a request to change the status of a task
+type UpdateTaskRequest struct {
+	Status string `json:"status" binding:"required,oneof=open done dismissed"`
+}
+
+// ListTasksRequest represents the filters of a list of tasks
+type ListTasksRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=open done dismissed"`
+}
+
+// TaskResponse represents an action item of a chat in API responses
+type TaskResponse struct {
+	ID          int64  `json:"id"`
+	ChatID      int64  `json:"chatId"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Assignee    string `json:"assignee,omitempty"`
+	// DueDate is the date the task is due, as 2006-01-02
+	DueDate         string     `json:"dueDate,omitempty"`
+	Status          string     `json:"status"`
+	SourceMessageID int64      `json:"sourceMessageId"`
+	Tracker         string     `json:"tracker,omitempty"`
+	ExportedAt      *time.Time `json:"exportedAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// ListTasksResponse represents the tasks of a chat in API responses
+type ListTasksResponse struct {
+	Tasks []TaskResponse `json:"tasks"`
+	// Exported is set when the tasks were exported to the tracker of the request
+	Exported bool `json:"exported,omitempty"`
+}
+
+// TaskExport is the body posted to the webhook of a tracker the tasks of a chat are exported to
+type TaskExport struct {
+	Event     string         `json:"event"` // tasks.exported
+	ChatID    int64          `json:"chatId"`
+	ChatTitle string         `json:"chatTitle"`
+	UserID    string         `json:"userId"`
+	Tasks     []TaskResponse `json:"tasks"`
+}
@@ -0,0 +1,50 @@
+package dtos
+
+// Types of the Bot Framework activities handled or sent
+const (
+	TeamsActivityMessage = "message"
+	TeamsActivityTyping  = "typing"
+)
+
+// TeamsActivity is a Bot Framework activity, received from or sent to Microsoft Teams
+type TeamsActivity struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	// ServiceURL is the endpoint of the Bot Framework connector replies are sent to
+	ServiceURL   string                    `json:"serviceUrl,omitempty"`
+	ChannelID    string                    `json:"channelId,omitempty"`
+	From         *TeamsAccount             `json:"from,omitempty"`
+	Conversation *TeamsConversationAccount `json:"conversation,omitempty"`
+	Recipient    *TeamsAccount             `json:"recipient,omitempty"`
+	Text         string                    `json:"text,omitempty"`
+	// TextFormat is plain, markdown or xml
+	TextFormat  string            `json:"textFormat,omitempty"`
+	ReplyToID   string            `json:"replyToId,omitempty"`
+	ChannelData *TeamsChannelData `json:"channelData,omitempty"`
+}
+
+// TeamsAccount is a user or bot of a Teams activity
+type TeamsAccount struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// AADObjectID is the Azure AD object ID of users
+	AADObjectID string `json:"aadObjectId,omitempty"`
+}
+
+// TeamsConversationAccount is the conversation of a Teams activity: a personal chat, a group
+// chat or a channel thread
+type TeamsConversationAccount struct {
+	ID               string `json:"id"`
+	ConversationType string `json:"conversationType,omitempty"`
+	TenantID         string `json:"tenantId,omitempty"`
+}
+
+// TeamsChannelData is the Teams-specific data of an activity
+type TeamsChannelData struct {
+	Tenant *TeamsTenant `json:"tenant,omitempty"`
+}
+
+// TeamsTenant is the Azure AD tenant of a Teams activity
+type TeamsTenant struct {
+	ID string `json:"id"`
+}
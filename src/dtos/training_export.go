@@ -0,0 +1,59 @@
+package dtos
+
+import (
+	"time"
+)
+
+// TrainingExportRequest represents a request to export the conversations matching filters
+// into a fine-tuning dataset
+type TrainingExportRequest struct {
+	// From and To bound the creation time of the chats exported
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+	// Tags are the tags the chats exported must all have
+	Tags []string `json:"tags" binding:"omitempty,max=20,dive,required,max=50"`
+	// MinScore is the least sum of the feedback of the replies of a chat, 1 when omitted
+	MinScore *int `json:"minScore"`
+	// SystemPrompt starts each conversation of the dataset
+	SystemPrompt string `json:"systemPrompt" binding:"max=10000"`
+}
+
+// TrainingExportResponse represents a fine-tuning dataset export in API responses
+type TrainingExportResponse struct {
+	ID           int64      `json:"id"`
+	Status       string     `json:"status"`
+	RequestedBy  string     `json:"requestedBy"`
+	From         *time.Time `json:"from,omitempty"`
+	To           *time.Time `json:"to,omitempty"`
+	Tags         []string   `json:"tags"`
+	MinScore     int        `json:"minScore"`
+	SystemPrompt string     `json:"systemPrompt,omitempty"`
+	// Chats is the number of conversations of the dataset
+	Chats int    `json:"chats"`
+	Size  int64  `json:"size"`
+	Error string `json:"error,omitempty"`
+	// DownloadURL is set once the dataset is built and is valid until DownloadExpiresAt
+	DownloadURL       string     `json:"downloadUrl,omitempty"`
+	DownloadExpiresAt *time.Time `json:"downloadExpiresAt,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	CompletedAt       *time.Time `json:"completedAt,omitempty"`
+}
+
+// ListTrainingExportsResponse represents the latest fine-tuning dataset exports
+type ListTrainingExportsResponse struct {
+	Exports []TrainingExportResponse `json:"exports"`
+}
+
+// TrainingExample represents a conversation of a fine-tuning dataset, a line of its JSONL
+// file in the chat format of fine-tuning APIs
+type TrainingExample struct {
+	Messages []TrainingMessage `json:"messages"`
+}
+
+// TrainingMessage represents a message of a fine-tuning conversation
+type TrainingMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Weight is 0 for the replies rated down, kept as context but not trained on
+	Weight *int `json:"weight,omitempty"`
+}
@@ -0,0 +1,115 @@
+package dtos
+
+import (
+	"time"
+)
+
+// Schema versions of the rows of the warehouse tables. A version is bumped whenever the
+// columns of its rows change, and the table is then exported again from the start under
+// the new version.
+const (
+	WarehouseChatsVersion    = 1
+	WarehouseMessagesVersion = 1
+	WarehouseUsageVersion    = 1
+	// WarehouseWorkspaceStatsVersion is exported only when analytics are enabled
+	WarehouseWorkspaceStatsVersion = 1
+)
+
+// WarehouseChat represents a chat exported to the data warehouse. Its columns are snake_case
+// like those of warehouse tables.
+type WarehouseChat struct {
+	ID       int64  `json:"id"`
+	PublicID string `json:"public_id"`
+	UserID   string `json:"user_id"`
+	// Title is null unless content is exported
+	Title       *string    `json:"title"`
+	Tags        string     `json:"tags"`
+	Language    string     `json:"language"`
+	Archived    bool       `json:"archived"`
+	Locked      bool       `json:"locked"`
+	URLContext  bool       `json:"url_context"`
+	Suggestions bool       `json:"suggestions"`
+	MaxTokens   int64      `json:"max_tokens"`
+	MaxCost     float64    `json:"max_cost"`
+	UsedTokens  int64      `json:"used_tokens"`
+	UsedCost    float64    `json:"used_cost"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeleteAt    *time.Time `json:"delete_at"`
+}
+
+// WarehouseMessage represents a message exported to the data warehouse
+type WarehouseMessage struct {
+	ID       int64   `json:"id"`
+	PublicID string  `json:"public_id"`
+	ChatID   int64   `json:"chat_id"`
+	SeqNo    int64   `json:"seq_no"`
+	UserID   *string `json:"user_id"`
+	Role     string  `json:"role"`
+	Kind     string  `json:"kind"`
+	// Content is null unless content is exported, and a preview when the content is kept in
+	// the storage, as ContentInStorage tells
+	Content          *string    `json:"content"`
+	ContentLength    int        `json:"content_length"`
+	ContentInStorage bool       `json:"content_in_storage"`
+	ContentType      string     `json:"content_type"`
+	Status           string     `json:"status"`
+	Model            string     `json:"model"`
+	Provider         string     `json:"provider"`
+	ModelVersion     string     `json:"model_version"`
+	PersonaID        *int64     `json:"persona_id"`
+	VariantID        *int64     `json:"variant_id"`
+	TotalTokens      int        `json:"total_tokens"`
+	LatencyMs        int64      `json:"latency_ms"`
+	Feedback         int        `json:"feedback"`
+	Language         string     `json:"language"`
+	EditedAt         *time.Time `json:"edited_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// WarehouseUsage represents a usage record exported to the data warehouse
+type WarehouseUsage struct {
+	ID               int64     `json:"id"`
+	UserID           string    `json:"user_id"`
+	ChatID           int64     `json:"chat_id"`
+	MessageID        int64     `json:"message_id"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Images           int       `json:"images"`
+	Cost             float64   `json:"cost"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// WarehouseWorkspaceStat represents the statistics of a workspace in an hour exported to
+// the data warehouse, as released by the analytics policy. The values of suppressed hours,
+// which had too few users, are null.
+type WarehouseWorkspaceStat struct {
+	WorkspaceID  string    `json:"workspace_id"`
+	Hour         time.Time `json:"hour"`
+	Suppressed   bool      `json:"suppressed"`
+	Users        *int64    `json:"users"`
+	Requests     *int64    `json:"requests"`
+	ClientErrors *int64    `json:"client_errors"`
+	ServerErrors *int64    `json:"server_errors"`
+	Tokens       *int64    `json:"tokens"`
+	Cost         *float64  `json:"cost"`
+}
+
+// WarehouseSchema describes the columns of a version of a warehouse table, written next to
+// its objects for loaders
+type WarehouseSchema struct {
+	Table   string                 `json:"table"`
+	Version int                    `json:"version"`
+	Fields  []WarehouseSchemaField `json:"fields"`
+}
+
+// WarehouseSchemaField describes a column of a warehouse table, with a type of BigQuery:
+// STRING, INTEGER, FLOAT, BOOLEAN or TIMESTAMP
+type WarehouseSchemaField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Mode is NULLABLE or REQUIRED
+	Mode string `json:"mode"`
+}
@@ -0,0 +1,22 @@
+package dtos
+
+// WebPage is the readable content of a fetched web page
+type WebPage struct {
+	URL   string
+	Title string
+	Text  string
+}
+
+// CrawledPage is a web page fetched by the crawler, with the links it holds
+type CrawledPage struct {
+	WebPage
+	// Links are the absolute HTTP(S) URLs linked from the page, without fragments
+	Links []string
+}
+
+// Sitemap is the content of a sitemap: the pages it lists, or for a sitemap index the
+// sitemaps it lists
+type Sitemap struct {
+	Pages    []string
+	Sitemaps []string
+}
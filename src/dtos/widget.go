@@ -0,0 +1,78 @@
+package dtos
+
+import (
+	"time"
+)
+
+// WidgetRequest represents a request to create or replace an embeddable chat widget
+type WidgetRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+	// AllowedOrigins are the web origins the widget is embedded on, e.g. https://shop.example.com
+	AllowedOrigins []string        `json:"allowedOrigins" binding:"required,min=1,dive,required"`
+	Branding       WidgetBranding  `json:"branding"`
+	Assistant      WidgetAssistant `json:"assistant"`
+	// Enabled defaults to true; disabled widgets start no sessions
+	Enabled *bool `json:"enabled"`
+}
+
+// WidgetBranding represents the look of a widget
+type WidgetBranding struct {
+	Title        string `json:"title,omitempty" binding:"max=255"`
+	Greeting     string `json:"greeting,omitempty" binding:"max=1000"`
+	PrimaryColor string `json:"primaryColor,omitempty" binding:"omitempty,hexcolor"`
+	AvatarURL    string `json:"avatarUrl,omitempty" binding:"omitempty,url,max=2048"`
+}
+
+// WidgetAssistant represents the default assistant answering the visitors of a widget
+type WidgetAssistant struct {
+	Name string `json:"name,omitempty" binding:"max=255"`
+	// Model and SystemPrompt override the defaults when set
+	Model        string `json:"model,omitempty"`
+	SystemPrompt string `json:"systemPrompt,omitempty"`
+}
+
+// WidgetResponse represents a widget in API responses
+type WidgetResponse struct {
+	ID             int64           `json:"id"`
+	Name           string          `json:"name"`
+	AllowedOrigins []string        `json:"allowedOrigins"`
+	Enabled        bool            `json:"enabled"`
+	Branding       WidgetBranding  `json:"branding"`
+	Assistant      WidgetAssistant `json:"assistant"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
+
+// ListWidgetsResponse represents a list of widgets in API responses
+type ListWidgetsResponse struct {
+	Widgets []WidgetResponse `json:"widgets"`
+}
+
+// WidgetConfigRequest represents a request for the public configuration of a widget
+type WidgetConfigRequest struct {
+	WidgetID int64 `form:"widgetId" binding:"required,min=1"`
+}
+
+// WidgetConfigResponse represents the configuration a widget is rendered with, without
+// the prompt of its assistant
+type WidgetConfigResponse struct {
+	WidgetID      int64          `json:"widgetId"`
+	Branding      WidgetBranding `json:"branding"`
+	AssistantName string         `json:"assistantName,omitempty"`
+}
+
+// WidgetSessionRequest represents a request to start a session of a widget
+type WidgetSessionRequest struct {
+	WidgetID int64 `json:"widgetId" binding:"required,min=1"`
+}
+
+// WidgetSessionResponse represents a new widget session, with the token the widget calls the
+// API with
+type WidgetSessionResponse struct {
+	Token     string    `json:"token"`
+	UserID    string    `json:"userId"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Config is the configuration the widget is rendered with
+	Config WidgetConfigResponse `json:"config"`
+}
@@ -0,0 +1,88 @@
+package dtos
+
+import (
+	"time"
+)
+
+// WorkspaceSettingsRequest represents a request to replace the workspace defaults; empty
+// settings fall back to the configuration
+type WorkspaceSettingsRequest struct {
+	// DefaultModel and SystemPrompt apply to replies without a persona, widget or experiment
+	// variant setting their own
+	DefaultModel string `json:"defaultModel" binding:"max=255"`
+	SystemPrompt string `json:"systemPrompt"`
+	// ModerationLevel is off, redact or block
+	ModerationLevel string `json:"moderationLevel" binding:"omitempty,oneof=off redact block"`
+	// RetentionDays applies to users without a retention policy; 0 keeps messages forever
+	RetentionDays *int `json:"retentionDays" binding:"omitempty,min=0"`
+	// Guardrails are the names of the guardrail rules replies are checked against; omitted
+	// checks every rule, and an empty list none
+	Guardrails []string `json:"guardrails" binding:"omitempty,dive,required"`
+	// WarehouseOptOut keeps the data of the workspace out of the data warehouse export
+	WarehouseOptOut bool `json:"warehouseOptOut"`
+}
+
+// WorkspaceSettingsResponse represents the workspace defaults in API responses
+type WorkspaceSettingsResponse struct {
+	DefaultModel    string `json:"defaultModel,omitempty"`
+	SystemPrompt    string `json:"systemPrompt,omitempty"`
+	ModerationLevel string `json:"moderationLevel,omitempty"`
+	RetentionDays   *int   `json:"retentionDays,omitempty"`
+	// Guardrails is null when every guardrail rule is checked
+	Guardrails      []string `json:"guardrails"`
+	WarehouseOptOut bool     `json:"warehouseOptOut"`
+	// Effective are the defaults applied, the settings or else the configuration
+	Effective WorkspaceEffectiveSettings `json:"effective"`
+	UpdatedBy string                     `json:"updatedBy,omitempty"`
+	UpdatedAt *time.Time                 `json:"updatedAt,omitempty"`
+}
+
+// WorkspaceEffectiveSettings represents the workspace defaults applied
+type WorkspaceEffectiveSettings struct {
+	Model string `json:"model"`
+	// ModerationLevel is off while moderation is not configured
+	ModerationLevel string `json:"moderationLevel"`
+	RetentionDays   int    `json:"retentionDays"`
+	// Guardrails are the guardrail rules checked, none while guardrails are disabled
+	Guardrails []string `json:"guardrails"`
+}
+
+// WorkspaceStatsRequest represents a request for the statistics of a workspace
+type WorkspaceStatsRequest struct {
+	// Hours is the number of hours covered, up to the current one; 24 when unset
+	Hours int `form:"hours" binding:"omitempty,min=1,max=720"`
+}
+
+// WorkspaceStatsResponse represents the requests and usage of a workspace since a time,
+// overall and by hour
+type WorkspaceStatsResponse struct {
+	WorkspaceID  string    `json:"workspaceId"`
+	Since        time.Time `json:"since"`
+	Requests     int64     `json:"requests"`
+	ClientErrors int64     `json:"clientErrors"`
+	ServerErrors int64     `json:"serverErrors"`
+	// ErrorRate is the share of the requests failing with a 5xx status, ClientErrorRate with
+	// a 4xx one, such as rate limited requests
+	ErrorRate       float64 `json:"errorRate"`
+	ClientErrorRate float64 `json:"clientErrorRate"`
+	// RequestsPerMinute is the average over the period
+	RequestsPerMinute float64              `json:"requestsPerMinute"`
+	Tokens            int64                `json:"tokens"`
+	Cost              float64              `json:"cost"` // Estimated, in USD
+	Hours             []WorkspaceHourStats `json:"hours"`
+	// SuppressedHours is the number of hours left out for having too few users to be
+	// released, when analytics are enabled
+	SuppressedHours int `json:"suppressedHours,omitempty"`
+}
+
+// WorkspaceHourStats represents the requests and usage of a workspace in an hour
+type WorkspaceHourStats struct {
+	Hour time.Time `json:"hour"`
+	// Users is the number of distinct users of the hour, counted when analytics are enabled
+	Users        int64   `json:"users,omitempty"`
+	Requests     int64   `json:"requests"`
+	ClientErrors int64   `json:"clientErrors"`
+	ServerErrors int64   `json:"serverErrors"`
+	Tokens       int64   `json:"tokens"`
+	Cost         float64 `json:"cost"`
+}
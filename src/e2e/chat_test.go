@@ -0,0 +1,118 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createChat creates a chat titled title as the user of c
+func createChat(c *client, title string) dtos.ChatResponse {
+	c.t.Helper()
+
+	var chat dtos.ChatResponse
+	c.call(http.MethodPost, "/api/v1/chats", map[string]string{"title": title}, http.StatusCreated, &chat)
+	return chat
+}
+
+func TestChatLifecycle(t *testing.T) {
+	userID := newUserID()
+	c := newClient(t, mintToken(t, userID, nil))
+
+	chat := createChat(c, "First chat")
+	assert.Equal(t, userID, chat.UserID)
+	assert.Equal(t, "First chat", chat.Title)
+	path := fmt.Sprintf("/api/v1/chats/%d", chat.ID)
+
+	var fetched dtos.ChatResponse
+	c.call(http.MethodGet, path, nil, http.StatusOK, &fetched)
+	assert.Equal(t, chat.ID, fetched.ID)
+
+	// Chats are also found by their public ID
+	c.call(http.MethodGet, "/api/v1/chats/"+chat.PublicID, nil, http.StatusOK, &fetched)
+	assert.Equal(t, chat.ID, fetched.ID)
+
+	var updated dtos.ChatResponse
+	c.call(http.MethodPut, path, map[string]string{"title": "Renamed"}, http.StatusOK, &updated)
+	assert.Equal(t, "Renamed", updated.Title)
+
+	var list dtos.ListChatsResponse
+	c.call(http.MethodGet, "/api/v1/chats", nil, http.StatusOK, &list)
+	require.Len(t, list.Chats, 1)
+	assert.Equal(t, chat.ID, list.Chats[0].ID)
+	assert.Equal(t, int64(1), list.Total)
+
+	// Deleted chats are hidden until their grace period ends, and can be restored meanwhile
+	c.call(http.MethodDelete, path, nil, http.StatusAccepted, nil)
+	c.call(http.MethodGet, "/api/v1/chats", nil, http.StatusOK, &list)
+	assert.Empty(t, list.Chats)
+
+	c.call(http.MethodPost, path+"/undo-delete", nil, http.StatusOK, &fetched)
+	assert.Equal(t, "Renamed", fetched.Title)
+	c.call(http.MethodGet, path, nil, http.StatusOK, nil)
+}
+
+func TestChatEnvelope(t *testing.T) {
+	c := newClient(t, mintToken(t, newUserID(), nil))
+	chat := createChat(c, "Enveloped")
+
+	var body envelope[dtos.ChatResponse]
+	response := c.call(http.MethodGet, fmt.Sprintf("/api/v2/chats/%d", chat.ID), nil, http.StatusOK, &body)
+	assert.Nil(t, body.Error)
+	assert.Equal(t, chat.ID, body.Data.ID)
+	assert.Equal(t, "v2", body.Meta.Version)
+	assert.Equal(t, response.Header.Get("X-Request-ID"), body.Meta.RequestID)
+}
+
+func TestChatErrors(t *testing.T) {
+	owner := newClient(t, mintToken(t, newUserID(), nil))
+	chat := createChat(owner, "Private")
+	path := fmt.Sprintf("/api/v1/chats/%d", chat.ID)
+
+	t.Run("missing token", func(t *testing.T) {
+		var body errorBody
+		newClient(t, "").call(http.MethodGet, path, nil, http.StatusUnauthorized, &body)
+		assert.Equal(t, "UNAUTHORIZED", body.Code)
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": newUserID()}).
+			SignedString([]byte("not-the-secret"))
+		require.NoError(t, err)
+		newClient(t, forged).call(http.MethodGet, path, nil, http.StatusUnauthorized, nil)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		var body errorBody
+		newClient(t, mintToken(t, newUserID(), nil)).call(http.MethodGet, path, nil, http.StatusForbidden, &body)
+		assert.Equal(t, "FORBIDDEN", body.Code)
+	})
+
+	t.Run("missing chat", func(t *testing.T) {
+		var body errorBody
+		owner.call(http.MethodGet, "/api/v1/chats/999999999999", nil, http.StatusNotFound, &body)
+		assert.Equal(t, "NOT_FOUND", body.Code)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		var body envelope[*dtos.ChatResponse]
+		response := owner.call(http.MethodPost, "/api/v2/chats", map[string]string{}, http.StatusBadRequest, &body)
+		require.NotNil(t, body.Error)
+		assert.Equal(t, "INVALID_REQUEST", body.Error.Code)
+		assert.Nil(t, body.Data)
+		assert.Equal(t, response.Header.Get("X-Request-ID"), body.Meta.RequestID)
+	})
+
+	t.Run("read-only scope", func(t *testing.T) {
+		reader := newClient(t, mintToken(t, newUserID(), jwt.MapClaims{"scope": "chats:read"}))
+		reader.call(http.MethodGet, "/api/v1/chats", nil, http.StatusOK, nil)
+		reader.call(http.MethodPost, "/api/v1/chats", map[string]string{"title": "Denied"}, http.StatusForbidden, nil)
+	})
+}
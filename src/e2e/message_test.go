@@ -0,0 +1,128 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendMessage(t *testing.T) {
+	c := newClient(t, mintToken(t, newUserID(), nil))
+	chat := createChat(c, "Conversation")
+
+	var body envelope[dtos.MessageExchangeResponse]
+	c.call(http.MethodPost, fmt.Sprintf("/api/v2/messages?chatId=%d", chat.ID),
+		map[string]string{"content": "Hello"}, http.StatusCreated, &body)
+	exchange := body.Data
+	assert.Equal(t, "user", exchange.UserMessage.Role)
+	assert.Equal(t, "Hello", exchange.UserMessage.Content)
+	require.NotNil(t, exchange.AssistantMessage)
+	assert.Equal(t, "assistant", exchange.AssistantMessage.Role)
+	assert.NotEmpty(t, exchange.AssistantMessage.Content)
+
+	var list dtos.ListMessagesResponse
+	c.call(http.MethodGet, fmt.Sprintf("/api/v1/messages?chatId=%d", chat.ID), nil, http.StatusOK, &list)
+	require.Len(t, list.Messages, 2)
+	assert.Equal(t, int64(2), list.Total)
+	assert.Equal(t, exchange.UserMessage.ID, list.Messages[0].ID)
+	assert.Equal(t, exchange.AssistantMessage.ID, list.Messages[1].ID)
+}
+
+func TestSendMessageErrors(t *testing.T) {
+	c := newClient(t, mintToken(t, newUserID(), nil))
+	chat := createChat(c, "Errors")
+	path := fmt.Sprintf("/api/v2/messages?chatId=%d", chat.ID)
+
+	t.Run("empty content", func(t *testing.T) {
+		var body envelope[*dtos.MessageExchangeResponse]
+		c.call(http.MethodPost, path, map[string]string{}, http.StatusBadRequest, &body)
+		require.NotNil(t, body.Error)
+		assert.Equal(t, "INVALID_REQUEST", body.Error.Code)
+	})
+
+	t.Run("missing chat ID", func(t *testing.T) {
+		c.call(http.MethodPost, "/api/v2/messages", map[string]string{"content": "Hello"}, http.StatusBadRequest, nil)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		other := newClient(t, mintToken(t, newUserID(), nil))
+		var body envelope[*dtos.MessageExchangeResponse]
+		other.call(http.MethodPost, path, map[string]string{"content": "Hello"}, http.StatusForbidden, &body)
+		require.NotNil(t, body.Error)
+		assert.Equal(t, "FORBIDDEN", body.Error.Code)
+	})
+
+	t.Run("echoes request ID", func(t *testing.T) {
+		c.header.Set("X-Request-ID", "e2e-request")
+		defer c.header.Del("X-Request-ID")
+
+		var body envelope[*dtos.MessageExchangeResponse]
+		response := c.call(http.MethodPost, path, map[string]string{}, http.StatusBadRequest, &body)
+		assert.Equal(t, "e2e-request", response.Header.Get("X-Request-ID"))
+		assert.Equal(t, "e2e-request", body.Meta.RequestID)
+	})
+}
+
+func TestStreamCompletion(t *testing.T) {
+	c := newClient(t, mintToken(t, newUserID(), nil))
+
+	response := c.do(http.MethodPost, "/v1/chat/completions", map[string]interface{}{
+		"messages": []map[string]string{{"role": "user", "content": "Hi"}},
+		"stream":   true,
+	})
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, response.Header.Get("Content-Type"), "text/event-stream")
+	chatID, err := strconv.ParseInt(response.Header.Get("X-Chat-ID"), 10, 64)
+	require.NoError(t, err)
+
+	// Events are data lines of completion chunks, ending with [DONE]
+	var chunks []dtos.ChatCompletionResponse
+	done := false
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk dtos.ChatCompletionResponse
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk), data)
+		chunks = append(chunks, chunk)
+	}
+	require.NoError(t, scanner.Err())
+	require.True(t, done, "stream ended without [DONE]")
+
+	// The first chunk carries the role, the last one the finish reason, and content is in between
+	require.GreaterOrEqual(t, len(chunks), 3)
+	require.Len(t, chunks[0].Choices, 1)
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	last := chunks[len(chunks)-1]
+	require.Len(t, last.Choices, 1)
+	require.NotNil(t, last.Choices[0].FinishReason)
+	assert.Equal(t, "stop", *last.Choices[0].FinishReason)
+
+	var content strings.Builder
+	for _, chunk := range chunks[1 : len(chunks)-1] {
+		content.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	assert.NotEmpty(t, content.String())
+
+	// The exchange is stored in the chat the completion created
+	var list dtos.ListMessagesResponse
+	c.call(http.MethodGet, fmt.Sprintf("/api/v1/messages?chatId=%d", chatID), nil, http.StatusOK, &list)
+	require.Len(t, list.Messages, 2)
+	assert.Equal(t, content.String(), list.Messages[1].Content)
+}
@@ -0,0 +1,277 @@
+//go:build e2e
+
+// Package e2e boots the chat service as a separate process against a test database, with a
+// simulated LLM and events kept in memory, and exercises it over HTTP. Run it with
+// go test -tags e2e ./e2e/ and the TEST_DB_* variables of the repository tests.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// jwtSecret signs the tokens of the test users
+const jwtSecret = "e2e-secret"
+
+// startTimeout bounds how long the server takes to become ready
+const startTimeout = 60 * time.Second
+
+// baseURL is the address of the server under test
+var baseURL string
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// run builds and starts the server, runs the tests against it, then stops it
+func run(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "chat-e2e-")
+	if err != nil {
+		fmt.Printf("Failed to create a work directory: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "chat")
+	build := exec.Command("go", "build", "-o", binary, "./cmd/main")
+	build.Dir = ".."
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Printf("Failed to build the server: %v\n", err)
+		return 1
+	}
+
+	port, err := freePort()
+	if err != nil {
+		fmt.Printf("Failed to find a free port: %v\n", err)
+		return 1
+	}
+	baseURL = "http://127.0.0.1:" + strconv.Itoa(port)
+
+	config, err := filepath.Abs("../../config.yaml")
+	if err != nil {
+		fmt.Printf("Failed to locate the config: %v\n", err)
+		return 1
+	}
+	logs, err := os.Create(filepath.Join(dir, "server.log"))
+	if err != nil {
+		fmt.Printf("Failed to create the server log: %v\n", err)
+		return 1
+	}
+	defer logs.Close()
+
+	server := exec.Command(binary, "-config", config)
+	server.Dir = dir
+	server.Env = append(os.Environ(), serverEnv(port)...)
+	server.Stdout, server.Stderr = logs, logs
+	if err := server.Start(); err != nil {
+		fmt.Printf("Failed to start the server: %v\n", err)
+		return 1
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- server.Wait() }()
+
+	if err := waitReady(exited); err != nil {
+		fmt.Printf("Server did not become ready: %v\n", err)
+		printLog(logs.Name())
+		return 1
+	}
+
+	code := m.Run()
+
+	_ = server.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-exited:
+	case <-time.After(30 * time.Second):
+		_ = server.Process.Kill()
+	}
+	if code != 0 {
+		printLog(logs.Name())
+	}
+	return code
+}
+
+// serverEnv returns the environment overriding the config for the server under test
+func serverEnv(port int) []string {
+	return []string{
+		"APP_PORT=" + strconv.Itoa(port),
+		"APP_ENV=test",
+		"LOG_LEVEL=info",
+		"DB_HOST=" + getEnvOrDefault("TEST_DB_HOST", "localhost"),
+		"DB_PORT=" + getEnvOrDefault("TEST_DB_PORT", "5432"),
+		"DB_USER=" + getEnvOrDefault("TEST_DB_USER", "postgres"),
+		"DB_PASSWORD=" + getEnvOrDefault("TEST_DB_PASSWORD", "postgres"),
+		"DB_NAME=" + getEnvOrDefault("TEST_DB_NAME", "chat_test"),
+		"DB_SSL_MODE=disable",
+		"JWT_SECRET=" + jwtSecret,
+		"KAFKA_ENABLED=false",
+		"LLM_PROVIDER=simulated",
+		"LLM_BASE_URL=http://127.0.0.1:0",
+		"LLM_API_KEY=e2e",
+		"LLM_SIM_LATENCY_DISTRIBUTION=fixed",
+		"LLM_SIM_LATENCY_MEAN=10ms",
+		"LLM_SIM_CHUNK_INTERVAL=1ms",
+		"LLM_SIM_ERROR_RATE=0",
+	}
+}
+
+// freePort returns a TCP port nothing listens on
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// waitReady waits until the server reports ready, or fails if it exits first
+func waitReady(exited <-chan error) error {
+	deadline := time.After(startTimeout)
+	for {
+		select {
+		case err := <-exited:
+			return fmt.Errorf("server exited: %v", err)
+		case <-deadline:
+			return fmt.Errorf("timed out after %s", startTimeout)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		response, err := http.Get(baseURL + "/ready")
+		if err != nil {
+			continue
+		}
+		response.Body.Close()
+		if response.StatusCode == http.StatusOK {
+			return nil
+		}
+	}
+}
+
+// printLog prints the log of the server, to tell why it failed
+func printLog(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	fmt.Printf("Server log:\n%s\n", data)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// newUserID returns the ID of a user of no other test
+func newUserID() string {
+	return "e2e-" + uuid.NewString()
+}
+
+// mintToken signs a token for a user with extra claims, such as role or scope
+func mintToken(t *testing.T, userID string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	all := jwt.MapClaims{
+		"sub": userID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for key, value := range claims {
+		all[key] = value
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, all).SignedString([]byte(jwtSecret))
+	require.NoError(t, err)
+	return signed
+}
+
+// client sends requests to the server as a user
+type client struct {
+	t      *testing.T
+	token  string
+	header http.Header
+}
+
+// newClient creates a client sending requests with a token; an empty token sends none
+func newClient(t *testing.T, token string) *client {
+	return &client{t: t, token: token, header: http.Header{}}
+}
+
+// do sends a request with a JSON body, when body is not nil, and returns the response,
+// which is closed at the end of the test
+func (c *client) do(method, path string, body interface{}) *http.Response {
+	c.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(c.t, err)
+		reader = bytes.NewReader(data)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	c.t.Cleanup(cancel)
+	request, err := http.NewRequestWithContext(ctx, method, baseURL+path, reader)
+	require.NoError(c.t, err)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	for key, values := range c.header {
+		request.Header[key] = values
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(c.t, err)
+	c.t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+// call sends a request, requires the response to have status and decodes its body into out,
+// when out is not nil
+func (c *client) call(method, path string, body interface{}, status int, out interface{}) *http.Response {
+	c.t.Helper()
+
+	response := c.do(method, path, body)
+	data, err := io.ReadAll(response.Body)
+	require.NoError(c.t, err)
+	require.Equal(c.t, status, response.StatusCode, "%s %s: %s", method, path, data)
+	if out != nil {
+		require.NoError(c.t, json.Unmarshal(data, out), "%s %s: %s", method, path, data)
+	}
+	return response
+}
+
+// errorBody is the body of v1 error responses
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// envelope is the body of v2 responses
+type envelope[T any] struct {
+	Data  T          `json:"data"`
+	Error *errorBody `json:"error"`
+	Meta  struct {
+		RequestID string `json:"requestId"`
+		Version   string `json:"version"`
+	} `json:"meta"`
+}
@@ -3,23 +3,95 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // Error codes
 const (
-	ErrInvalidRequest = "INVALID_REQUEST"
-	ErrNotFound       = "NOT_FOUND"
-	ErrInternal       = "INTERNAL_ERROR"
-	ErrUnauthorized   = "UNAUTHORIZED"
-	ErrForbidden      = "FORBIDDEN"
-	ErrLLMService     = "LLM_SERVICE_ERROR"
+	ErrInvalidRequest  = "INVALID_REQUEST"
+	ErrNotFound        = "NOT_FOUND"
+	ErrInternal        = "INTERNAL_ERROR"
+	ErrUnauthorized    = "UNAUTHORIZED"
+	ErrForbidden       = "FORBIDDEN"
+	ErrLLMService      = "LLM_SERVICE_ERROR"
+	ErrTimeout         = "TIMEOUT"
+	ErrMaintenance     = "MAINTENANCE"
+	ErrRateLimited     = "RATE_LIMITED"
+	ErrQuotaExceeded   = "QUOTA_EXCEEDED"
+	ErrDatabaseTimeout = "DATABASE_TIMEOUT"
+	ErrConflict        = "CONFLICT"
+	ErrConsentRequired = "CONSENT_REQUIRED"
+	ErrChatLocked      = "CHAT_LOCKED"
+	ErrPayloadTooLarge = "PAYLOAD_TOO_LARGE"
+	ErrModelNotFound   = "MODEL_NOT_FOUND"
 )
 
+// Definition describes an error code: the HTTP status of its responses, its default message
+// and how clients can remedy it
+type Definition struct {
+	Code        string `json:"code"`
+	Status      int    `json:"status"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+}
+
+// definitions are the error codes of the service, in the order they are listed to clients
+var definitions = []Definition{
+	{ErrInvalidRequest, http.StatusBadRequest, "Invalid request parameters",
+		"Fix the request as the message tells; retrying it unchanged fails again."},
+	{ErrUnauthorized, http.StatusUnauthorized, "Unauthorized access",
+		"Sign in again or refresh the access token, then retry."},
+	{ErrForbidden, http.StatusForbidden, "Access forbidden",
+		"The token lacks the scope or the rights required; request them instead of retrying."},
+	{ErrNotFound, http.StatusNotFound, "Resource not found",
+		"Check the ID; the resource may have been deleted or belong to another user."},
+	{ErrModelNotFound, http.StatusNotFound, "Model not found",
+		"Use one of the models GET /v1/models lists."},
+	{ErrConflict, http.StatusConflict, "Conflicting request in progress",
+		"Wait for the conflicting request to complete, reload the resource, then retry."},
+	{ErrPayloadTooLarge, http.StatusRequestEntityTooLarge, "Payload too large",
+		"Send a smaller file or body, within the limits the message tells."},
+	{ErrChatLocked, http.StatusLocked, "The chat is locked",
+		"The chat can only be read until it is unlocked; chats locked by an admin only by an admin."},
+	{ErrQuotaExceeded, http.StatusPaymentRequired, "Quota exceeded",
+		"Raise or remove the budget, or wait for the next period, before retrying."},
+	{ErrConsentRequired, http.StatusForbidden, "The data-usage terms must be accepted first",
+		"Have the user accept the current data-usage terms, then retry."},
+	{ErrRateLimited, http.StatusTooManyRequests, "Too many requests",
+		"Retry after the delay of the Retry-After header, backing off exponentially."},
+	{ErrInternal, http.StatusInternalServerError, "Internal server error",
+		"Retry later, and report the request ID of the response if it persists."},
+	{ErrLLMService, http.StatusServiceUnavailable, "LLM service error",
+		"The LLM provider failed; retry later, or check GET /providers/status."},
+	{ErrMaintenance, http.StatusServiceUnavailable, "Service is under maintenance",
+		"Retry after the delay of the Retry-After header."},
+	{ErrDatabaseTimeout, http.StatusServiceUnavailable, "Database query timed out",
+		"Retry later, or narrow the request, such as with a smaller page."},
+	{ErrTimeout, http.StatusGatewayTimeout, "Request timed out",
+		"Retry later; streamed endpoints avoid timeouts of long replies."},
+}
+
+// definitionsByCode indexes definitions by code
+var definitionsByCode = func() map[string]Definition {
+	index := make(map[string]Definition, len(definitions))
+	for _, definition := range definitions {
+		index[definition.Code] = definition
+	}
+	return index
+}()
+
+// Catalog returns the definitions of every error code of the service
+func Catalog() []Definition {
+	return append([]Definition(nil), definitions...)
+}
+
 // AppError represents an application error
 type AppError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Err     error  `json:"-"`
+	// RetryAfter is how long clients should wait before retrying, when it is known
+	RetryAfter time.Duration `json:"-"`
 }
 
 // Error implements the error interface
@@ -32,20 +104,32 @@ func (e *AppError) Error() string {
 
 // StatusCode returns the HTTP status code associated with the error
 func (e *AppError) StatusCode() int {
-	switch e.Code {
-	case ErrInvalidRequest:
-		return http.StatusBadRequest
-	case ErrNotFound:
-		return http.StatusNotFound
-	case ErrUnauthorized:
-		return http.StatusUnauthorized
-	case ErrForbidden:
-		return http.StatusForbidden
-	case ErrLLMService:
-		return http.StatusServiceUnavailable
-	default:
-		return http.StatusInternalServerError
+	if definition, ok := definitionsByCode[e.Code]; ok {
+		return definition.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// WithRetryAfter sets how long clients should wait before retrying, and returns the error
+func (e *AppError) WithRetryAfter(d time.Duration) *AppError {
+	e.RetryAfter = d
+	return e
+}
+
+// RetryAfter returns how long clients should wait before retrying after err, as set on it
+// or on an error it wraps, or 0 when it is not known
+func RetryAfter(err error) time.Duration {
+	for err != nil {
+		appErr, ok := err.(*AppError)
+		if !ok {
+			return 0
+		}
+		if appErr.RetryAfter > 0 {
+			return appErr.RetryAfter
+		}
+		err = appErr.Err
 	}
+	return 0
 }
 
 // New creates a new AppError
@@ -88,20 +172,8 @@ func Wrap(err error, code string, msg ...interface{}) *AppError {
 
 // getDefaultMessage returns a default message for a given error code
 func getDefaultMessage(code string) string {
-	switch code {
-	case ErrInvalidRequest:
-		return "Invalid request parameters"
-	case ErrNotFound:
-		return "Resource not found"
-	case ErrInternal:
-		return "Internal server error"
-	case ErrUnauthorized:
-		return "Unauthorized access"
-	case ErrForbidden:
-		return "Access forbidden"
-	case ErrLLMService:
-		return "LLM service error"
-	default:
-		return "An error occurred"
+	if definition, ok := definitionsByCode[code]; ok {
+		return definition.Message
 	}
+	return "An error occurred"
 }
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+)
+
+// billingHandler delivers billing events to the webhook of the finance system
+type billingHandler struct {
+	cfg     configs.Kafka
+	billing configs.Billing
+	webhook adapters.WebhookAdapter
+}
+
+// NewBillingHandler creates the handler posting billing events to the billing webhook
+func NewBillingHandler(cfg configs.Kafka, billing configs.Billing, webhook adapters.WebhookAdapter) Handler {
+	return &billingHandler{cfg: cfg, billing: billing, webhook: webhook}
+}
+
+// Name returns the handler name
+func (h *billingHandler) Name() string {
+	return "billing-webhook"
+}
+
+// Topic returns the billing events topic
+func (h *billingHandler) Topic() string {
+	return h.cfg.Topics.Billing
+}
+
+// Handle posts a billing event in the native format, whatever format it was published in.
+// Failed deliveries are retried, so the webhook may receive an exchange more than once.
+func (h *billingHandler) Handle(ctx context.Context, msg *queue.Message) error {
+	event, err := decodeEvent[dtos.BillingPayload](msg, h.cfg.CloudEvents.TypePrefix)
+	if err != nil {
+		return err
+	}
+	if event.Event != models.EventBillingExchangeCompleted {
+		return nil
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return h.webhook.Post(ctx, h.billing.WebhookURL, h.billing.WebhookSecret, body)
+}
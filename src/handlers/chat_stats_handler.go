@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// chatStatsHandler maintains the chat_stats projection from message events
+type chatStatsHandler struct {
+	cfg       configs.Kafka
+	statsRepo repositories.ChatStatsRepository
+}
+
+// NewChatStatsHandler creates the handler maintaining chat statistics
+func NewChatStatsHandler(cfg configs.Kafka, statsRepo repositories.ChatStatsRepository) Projection {
+	return &chatStatsHandler{cfg: cfg, statsRepo: statsRepo}
+}
+
+// Name returns the handler name
+func (h *chatStatsHandler) Name() string {
+	return "chat-stats"
+}
+
+// Topic returns the message events topic
+func (h *chatStatsHandler) Topic() string {
+	return h.cfg.Topics.Message
+}
+
+// Handle applies a message event to the stats of its chat
+func (h *chatStatsHandler) Handle(ctx context.Context, msg *queue.Message) error {
+	event, err := decodeMessageEvent(msg, h.cfg.CloudEvents.TypePrefix)
+	if err != nil {
+		return err
+	}
+
+	payload := event.Payload
+	switch event.Event {
+	case models.EventMessageCreated:
+		return h.statsRepo.RecordMessage(ctx, payload.ChatID, payload.MessageID, payload.TotalTokens, time.Unix(event.Timestamp, 0))
+	case models.EventMessageDeleted:
+		return h.statsRepo.RecordDeletion(ctx, payload.ChatID)
+	default:
+		return nil
+	}
+}
+
+// Reset clears the chat statistics
+func (h *chatStatsHandler) Reset(ctx context.Context) error {
+	return h.statsRepo.Reset(ctx)
+}
+
+// decodeMessageEvent decodes a message event published in either event format
+func decodeMessageEvent(msg *queue.Message, typePrefix string) (*dtos.KafkaMessage[dtos.MessagePayload], error) {
+	return decodeEvent[dtos.MessagePayload](msg, typePrefix)
+}
+
+// decodeEvent decodes an event published in either event format
+func decodeEvent[T any](msg *queue.Message, typePrefix string) (*dtos.KafkaMessage[T], error) {
+	if msg.Headers["content-type"] != dtos.CloudEventsContentType {
+		return dtos.DecodeKafkaMessage[T](msg.Value)
+	}
+
+	var event dtos.CloudEvent[T]
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode cloud event: %w", err)
+	}
+	return event.ToKafkaMessage(typePrefix), nil
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/pkg/queue"
+)
+
+// Handler processes messages consumed from a topic
+type Handler interface {
+	// Name identifies the handler; it is appended to the consumer group so every
+	// handler of a topic receives all of its messages
+	Name() string
+
+	// Topic returns the topic the handler consumes
+	Topic() string
+
+	// Handle processes a single message. Returning an error causes the message to be
+	// retried and eventually dead-lettered.
+	Handle(ctx context.Context, msg *queue.Message) error
+}
+
+// Projection is a handler maintaining state derived from the events of its topic, which can
+// be rebuilt by replaying them. Handlers with side effects, such as sending notifications,
+// are not projections.
+type Projection interface {
+	Handler
+
+	// Reset clears the state of the projection, in the region of ctx, before a full replay
+	Reset(ctx context.Context) error
+}
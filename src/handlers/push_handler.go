@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// pushHandler pushes new chat messages to mobile devices
+type pushHandler struct {
+	cfg         configs.Kafka
+	pushService services.PushService
+}
+
+// NewPushHandler creates the handler pushing new messages to the devices of chat owners
+func NewPushHandler(cfg configs.Kafka, pushService services.PushService) Handler {
+	return &pushHandler{cfg: cfg, pushService: pushService}
+}
+
+// Name returns the handler name
+func (h *pushHandler) Name() string {
+	return "push"
+}
+
+// Topic returns the message events topic
+func (h *pushHandler) Topic() string {
+	return h.cfg.Topics.Message
+}
+
+// Handle pushes created messages
+func (h *pushHandler) Handle(ctx context.Context, msg *queue.Message) error {
+	event, err := decodeMessageEvent(msg, h.cfg.CloudEvents.TypePrefix)
+	if err != nil {
+		return err
+	}
+
+	if event.Event != models.EventMessageCreated {
+		return nil
+	}
+	return h.pushService.NotifyMessage(ctx, &event.Payload)
+}
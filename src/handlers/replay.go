@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+)
+
+// ReplayOptions select the events replayed through a projection
+type ReplayOptions struct {
+	// Offsets and Since select where partitions are replayed from, as in queue.ReplayConfig
+	Offsets map[int]int64
+	Since   time.Time
+	// Reset clears the projection in every region first. Only full replays should reset, as
+	// the events before the start would otherwise be lost from the projection.
+	Reset bool
+}
+
+// ReplayResult counts the events replayed through a projection
+type ReplayResult struct {
+	Handled int
+	Failed  int
+}
+
+// Replayer rebuilds projections by replaying the retained events of their topics, after
+// schema changes or bugs left them wrong. Consumer group offsets are left as they are.
+type Replayer struct {
+	cfg     configs.Kafka
+	regions []string
+}
+
+// NewReplayer creates a replayer for projections stored in regions
+func NewReplayer(cfg configs.Kafka, regions []string) *Replayer {
+	if len(regions) == 0 {
+		regions = []string{""}
+	}
+	return &Replayer{cfg: cfg, regions: regions}
+}
+
+// Replay replays the events of the topic of a projection through it. Events failing to be
+// handled are logged and counted rather than retried or dead-lettered, so one bad event does
+// not stop a rebuild.
+func (r *Replayer) Replay(ctx context.Context, projection Projection, opts ReplayOptions) (*ReplayResult, error) {
+	if opts.Reset {
+		for _, region := range r.regions {
+			if err := projection.Reset(adapters.WithRegion(ctx, region)); err != nil {
+				return nil, err
+			}
+		}
+		logger.Info("Projection reset", logger.Field("handler", projection.Name()))
+	}
+
+	result := &ReplayResult{}
+	err := queue.ReplayKafka(ctx, queue.ReplayConfig{
+		Brokers: r.cfg.Brokers,
+		Topic:   projection.Topic(),
+		Offsets: opts.Offsets,
+		Since:   opts.Since,
+	}, func(msg *queue.Message) error {
+		// Events are handled in the region of the request that published them, as when consumed
+		msgCtx := logger.FromHeaders(ctx, msg.Headers)
+		msgCtx = adapters.WithRegion(msgCtx, msg.Headers[adapters.RegionHeader])
+
+		if err := projection.Handle(msgCtx, msg); err != nil {
+			logger.Context(msgCtx).Warnw("Failed to replay message",
+				"handler", projection.Name(),
+				"partition", msg.Partition,
+				"offset", msg.Offset,
+				"error", err)
+			result.Failed++
+			return nil
+		}
+		result.Handled++
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// Headers added to messages routed to a dead-letter topic
+const (
+	HeaderDLQOriginalTopic     = "X-DLQ-Original-Topic"
+	HeaderDLQOriginalPartition = "X-DLQ-Original-Partition"
+	HeaderDLQOriginalOffset    = "X-DLQ-Original-Offset"
+	HeaderDLQError             = "X-DLQ-Error"
+	HeaderDLQAttempts          = "X-DLQ-Attempts"
+)
+
+// ConsumerFactory creates the consumer for a topic and consumer group
+type ConsumerFactory func(topic, groupID string) queue.Consumer
+
+// Runner consumes topics with the registered handlers. Failed messages are retried
+// and, after the configured number of attempts, routed to a dead-letter topic and
+// recorded for replay. Offsets are committed only once a message is handled or
+// dead-lettered, so shutting down mid-message never loses or skips it.
+type Runner struct {
+	cfg         configs.Kafka
+	newConsumer ConsumerFactory
+	producer    queue.Producer
+	deadLetters services.DeadLetterService
+	handlers    []Handler
+}
+
+// NewRunner creates a new consumer runner
+func NewRunner(cfg configs.Kafka, newConsumer ConsumerFactory, producer queue.Producer, deadLetters services.DeadLetterService) *Runner {
+	return &Runner{
+		cfg:         cfg,
+		newConsumer: newConsumer,
+		producer:    producer,
+		deadLetters: deadLetters,
+	}
+}
+
+// Register adds a handler. Handlers must be registered before Run.
+func (r *Runner) Register(handler Handler) {
+	r.handlers = append(r.handlers, handler)
+}
+
+// Run consumes with every registered handler until ctx is done
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, handler := range r.handlers {
+		wg.Add(1)
+		go func(handler Handler) {
+			defer wg.Done()
+			r.consume(ctx, handler)
+		}(handler)
+	}
+
+	logger.Info("Consumers started", logger.Field("handlers", len(r.handlers)))
+	wg.Wait()
+	logger.Info("Consumers stopped")
+}
+
+// consume runs the fetch-handle-commit loop of a handler
+func (r *Runner) consume(ctx context.Context, handler Handler) {
+	groupID := r.cfg.ConsumerGroup + "." + handler.Name()
+	consumer := r.newConsumer(handler.Topic(), groupID)
+	defer func() {
+		// Closing leaves the group so partitions are reassigned immediately
+		if err := consumer.Close(); err != nil {
+			logger.Error("Failed to close consumer", logger.Field("handler", handler.Name()), logger.Field("error", err))
+		}
+	}()
+
+	for {
+		msg, err := consumer.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Error("Failed to fetch message", logger.Field("handler", handler.Name()), logger.Field("error", err))
+			if sleep(ctx, r.cfg.Consumer.RetryBackoff) != nil {
+				return
+			}
+			continue
+		}
+
+		// Processing is not interrupted by shutdown; the message in flight is finished. Its
+		// queries run in the region of the request that published it.
+		msgCtx := logger.FromHeaders(context.WithoutCancel(ctx), msg.Headers)
+		msgCtx = adapters.WithRegion(msgCtx, msg.Headers[adapters.RegionHeader])
+		if !r.process(ctx, msgCtx, handler, &msg) {
+			// Shutting down before the message was settled: leave it uncommitted
+			// so the next owner of the partition picks it up
+			return
+		}
+
+		if err := consumer.Commit(msgCtx, msg); err != nil {
+			logger.Context(msgCtx).Errorw("Failed to commit message", "handler", handler.Name(), "offset", msg.Offset, "error", err)
+		}
+	}
+}
+
+// process handles a message with retries, dead-lettering it when all attempts fail.
+// It returns false if shutdown interrupted it before the message was settled.
+func (r *Runner) process(ctx, msgCtx context.Context, handler Handler, msg *queue.Message) bool {
+	log := logger.Context(msgCtx)
+
+	maxAttempts := r.cfg.Consumer.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = handler.Handle(msgCtx, msg); err == nil {
+			return true
+		}
+
+		log.Warnw("Failed to handle message",
+			"handler", handler.Name(),
+			"topic", msg.Topic,
+			"partition", msg.Partition,
+			"offset", msg.Offset,
+			"attempt", attempt,
+			"error", err)
+
+		if attempt < maxAttempts {
+			if sleep(ctx, r.cfg.Consumer.RetryBackoff*time.Duration(attempt)) != nil {
+				return false
+			}
+		}
+	}
+
+	// Keep trying to dead-letter; committing without it would lose the message
+	for {
+		dlqErr := r.deadLetter(msgCtx, msg, err, maxAttempts)
+		if dlqErr == nil {
+			return true
+		}
+		log.Errorw("Failed to dead-letter message", "handler", handler.Name(), "offset", msg.Offset, "error", dlqErr)
+
+		if sleep(ctx, r.cfg.Consumer.RetryBackoff) != nil {
+			return false
+		}
+	}
+}
+
+// deadLetter routes a message to the dead-letter topic and records it for replay
+func (r *Runner) deadLetter(ctx context.Context, msg *queue.Message, cause error, attempts int) error {
+	headers := make(map[string]string, len(msg.Headers)+5)
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	headers[HeaderDLQOriginalTopic] = msg.Topic
+	headers[HeaderDLQOriginalPartition] = strconv.Itoa(msg.Partition)
+	headers[HeaderDLQOriginalOffset] = strconv.FormatInt(msg.Offset, 10)
+	headers[HeaderDLQError] = cause.Error()
+	headers[HeaderDLQAttempts] = strconv.Itoa(attempts)
+
+	if err := r.producer.Produce(ctx, queue.Message{
+		Topic:   msg.Topic + r.cfg.Consumer.DLQSuffix,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}); err != nil {
+		return err
+	}
+
+	return r.deadLetters.Record(ctx, msg, cause, attempts)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// chatSearchHandler maintains the chat index of the search service from chat events
+type chatSearchHandler struct {
+	cfg    configs.Kafka
+	search adapters.SearchAdapter
+}
+
+// NewChatSearchHandler creates the handler indexing chats in the search service
+func NewChatSearchHandler(cfg configs.Kafka, search adapters.SearchAdapter) Projection {
+	return &chatSearchHandler{cfg: cfg, search: search}
+}
+
+// Name returns the handler name
+func (h *chatSearchHandler) Name() string {
+	return "search-chats"
+}
+
+// Topic returns the chat events topic
+func (h *chatSearchHandler) Topic() string {
+	return h.cfg.Topics.Chat
+}
+
+// Handle applies a chat event to the index
+func (h *chatSearchHandler) Handle(ctx context.Context, msg *queue.Message) error {
+	event, err := decodeEvent[dtos.ChatPayload](msg, h.cfg.CloudEvents.TypePrefix)
+	if err != nil {
+		return err
+	}
+
+	payload := event.Payload
+	switch event.Event {
+	case models.EventChatCreated, models.EventChatUpdated:
+		return h.search.IndexChat(ctx, &dtos.ChatDocument{
+			ChatID:    payload.ChatID,
+			UserID:    payload.UserID,
+			Title:     payload.Title,
+			UpdatedAt: time.Unix(event.Timestamp, 0),
+		})
+	case models.EventChatTransferred:
+		return h.search.SetChatOwner(ctx, payload.ChatID, payload.UserID)
+	case models.EventChatDeleted:
+		return h.search.DeleteChat(ctx, payload.ChatID)
+	default:
+		return nil
+	}
+}
+
+// Reset empties the chat index
+func (h *chatSearchHandler) Reset(ctx context.Context) error {
+	return h.search.ResetChats(ctx)
+}
+
+// messageSearchHandler maintains the message index of the search service from message events
+type messageSearchHandler struct {
+	cfg         configs.Kafka
+	search      adapters.SearchAdapter
+	chatRepo    repositories.ChatRepository
+	messageRepo repositories.MessageRepository
+}
+
+// NewMessageSearchHandler creates the handler indexing messages in the search service. Messages
+// are indexed with the owner of their chat, and their full content when events only carry a
+// preview of it.
+func NewMessageSearchHandler(
+	cfg configs.Kafka,
+	search adapters.SearchAdapter,
+	chatRepo repositories.ChatRepository,
+	messageRepo repositories.MessageRepository,
+) Projection {
+	return &messageSearchHandler{
+		cfg:         cfg,
+		search:      search,
+		chatRepo:    chatRepo,
+		messageRepo: messageRepo,
+	}
+}
+
+// Name returns the handler name
+func (h *messageSearchHandler) Name() string {
+	return "search-messages"
+}
+
+// Topic returns the message events topic
+func (h *messageSearchHandler) Topic() string {
+	return h.cfg.Topics.Message
+}
+
+// Handle applies a message event to the index. System messages, such as announcements, and
+// the messages of privacy mode chats are not indexed.
+func (h *messageSearchHandler) Handle(ctx context.Context, msg *queue.Message) error {
+	event, err := decodeMessageEvent(msg, h.cfg.CloudEvents.TypePrefix)
+	if err != nil {
+		return err
+	}
+
+	payload := event.Payload
+	switch event.Event {
+	case models.EventMessageCreated, models.EventMessageUpdated:
+		if payload.Role == models.MessageRoleSystem || payload.Ephemeral {
+			return nil
+		}
+		return h.index(ctx, &payload, time.Unix(event.Timestamp, 0))
+	case models.EventMessageDeleted:
+		return h.search.DeleteMessage(ctx, payload.MessageID)
+	default:
+		return nil
+	}
+}
+
+// index indexes a created or updated message. Messages of chats deleted since the event are
+// skipped; the deletion removes them from the index.
+func (h *messageSearchHandler) index(ctx context.Context, payload *dtos.MessagePayload, at time.Time) error {
+	chat, err := h.chatRepo.Get(ctx, payload.ChatID)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	content := payload.Content
+	if payload.ContentTruncated {
+		message, err := h.messageRepo.Get(ctx, payload.MessageID)
+		if isNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		content, at = message.Content, message.CreatedAt
+	}
+
+	return h.search.IndexMessage(ctx, &dtos.MessageDocument{
+		MessageID: payload.MessageID,
+		ChatID:    payload.ChatID,
+		UserID:    chat.UserID,
+		Role:      payload.Role,
+		Content:   content,
+		CreatedAt: at,
+	})
+}
+
+// Reset empties the message index
+func (h *messageSearchHandler) Reset(ctx context.Context) error {
+	return h.search.ResetMessages(ctx)
+}
+
+// isNotFound reports whether err is a not found error of a repository
+func isNotFound(err error) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.Code == errors.ErrNotFound
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// batchJob periodically answers the pending batch prompts
+type batchJob struct {
+	batchService services.BatchService
+}
+
+// NewBatchJob creates the batch prompt job
+func NewBatchJob(batchService services.BatchService) Job {
+	return &batchJob{batchService: batchService}
+}
+
+// Name returns the job name
+func (j *batchJob) Name() string {
+	return "batch"
+}
+
+// Run answers the pending batch prompts
+func (j *batchJob) Run(ctx context.Context) error {
+	processed, err := j.batchService.ProcessPending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if processed > 0 {
+		logger.Context(ctx).Infow("Processed batch prompts", "count", processed)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// captureCleanupJob periodically deletes the expired support captures
+type captureCleanupJob struct {
+	supportService services.SupportService
+}
+
+// NewCaptureCleanupJob creates the support capture cleanup job
+func NewCaptureCleanupJob(supportService services.SupportService) Job {
+	return &captureCleanupJob{supportService: supportService}
+}
+
+// Name returns the job name
+func (j *captureCleanupJob) Name() string {
+	return "capture_cleanup"
+}
+
+// Run deletes the expired support captures
+func (j *captureCleanupJob) Run(ctx context.Context) error {
+	purged, err := j.supportService.PurgeExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		logger.Context(ctx).Infow("Purged support captures", "count", purged)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// chatDeletionJob periodically deletes the chats whose undo window has ended
+type chatDeletionJob struct {
+	chatService services.ChatService
+}
+
+// NewChatDeletionJob creates the chat deletion job
+func NewChatDeletionJob(chatService services.ChatService) Job {
+	return &chatDeletionJob{chatService: chatService}
+}
+
+// Name returns the job name
+func (j *chatDeletionJob) Name() string {
+	return "chat_deletion"
+}
+
+// Run deletes the chats pending deletion past their grace period
+func (j *chatDeletionJob) Run(ctx context.Context) error {
+	purged, err := j.chatService.PurgeDeleted(ctx)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		logger.Context(ctx).Infow("Deleted chats pending deletion", "count", purged)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// chatWebhooksJob periodically retries the exchanges not yet delivered to chat webhooks
+type chatWebhooksJob struct {
+	webhookService services.ChatWebhookService
+}
+
+// NewChatWebhooksJob creates the chat webhook delivery job
+func NewChatWebhooksJob(webhookService services.ChatWebhookService) Job {
+	return &chatWebhooksJob{webhookService: webhookService}
+}
+
+// Name returns the job name
+func (j *chatWebhooksJob) Name() string {
+	return "chat_webhooks"
+}
+
+// Run posts the exchanges whose next attempt is due
+func (j *chatWebhooksJob) Run(ctx context.Context) error {
+	delivered, err := j.webhookService.DeliverPending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if delivered > 0 {
+		logger.Context(ctx).Infow("Retried chat webhook deliveries", "delivered", delivered)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// crawlJob periodically runs the pending website crawls
+type crawlJob struct {
+	crawlService services.CrawlService
+}
+
+// NewCrawlJob creates the website crawl job
+func NewCrawlJob(crawlService services.CrawlService) Job {
+	return &crawlJob{crawlService: crawlService}
+}
+
+// Name returns the job name
+func (j *crawlJob) Name() string {
+	return "collection_crawl"
+}
+
+// Run runs the pending crawls
+func (j *crawlJob) Run(ctx context.Context) error {
+	processed, err := j.crawlService.ProcessPending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if processed > 0 {
+		logger.Context(ctx).Infow("Ran crawls", "count", processed)
+	}
+	return nil
+}
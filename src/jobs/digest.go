@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// digestJob periodically emails the activity digests due
+type digestJob struct {
+	notificationService services.NotificationService
+}
+
+// NewDigestJob creates the activity digest job
+func NewDigestJob(notificationService services.NotificationService) Job {
+	return &digestJob{notificationService: notificationService}
+}
+
+// Name returns the job name
+func (j *digestJob) Name() string {
+	return "digest"
+}
+
+// Run emails the digests due
+func (j *digestJob) Run(ctx context.Context) error {
+	sent, err := j.notificationService.SendDigests(ctx)
+	if err != nil {
+		return err
+	}
+
+	if sent > 0 {
+		logger.Context(ctx).Infow("Sent digests", "count", sent)
+	}
+	return nil
+}
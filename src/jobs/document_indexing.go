@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// documentIndexingJob periodically chunks the pending documents of collections
+type documentIndexingJob struct {
+	collectionService services.CollectionService
+}
+
+// NewDocumentIndexingJob creates the document indexing job
+func NewDocumentIndexingJob(collectionService services.CollectionService) Job {
+	return &documentIndexingJob{collectionService: collectionService}
+}
+
+// Name returns the job name
+func (j *documentIndexingJob) Name() string {
+	return "document_indexing"
+}
+
+// Run chunks the pending documents
+func (j *documentIndexingJob) Run(ctx context.Context) error {
+	processed, err := j.collectionService.ProcessPending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if processed > 0 {
+		logger.Context(ctx).Infow("Indexed documents", "count", processed)
+	}
+	return nil
+}
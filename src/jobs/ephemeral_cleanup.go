@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// ephemeralCleanupJob periodically deletes the privacy mode chats whose messages all expired
+type ephemeralCleanupJob struct {
+	chatService services.ChatService
+}
+
+// NewEphemeralCleanupJob creates the privacy mode chat cleanup job
+func NewEphemeralCleanupJob(chatService services.ChatService) Job {
+	return &ephemeralCleanupJob{chatService: chatService}
+}
+
+// Name returns the job name
+func (j *ephemeralCleanupJob) Name() string {
+	return "ephemeral_cleanup"
+}
+
+// Run deletes the privacy mode chats left without messages
+func (j *ephemeralCleanupJob) Run(ctx context.Context) error {
+	purged, err := j.chatService.PurgeEphemeral(ctx)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		logger.Context(ctx).Infow("Deleted expired privacy mode chats", "count", purged)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// evaluationJob periodically scores a sample of the recent conversations with an LLM judge
+type evaluationJob struct {
+	evaluationService services.EvaluationService
+}
+
+// NewEvaluationJob creates the conversation evaluation job
+func NewEvaluationJob(evaluationService services.EvaluationService) Job {
+	return &evaluationJob{evaluationService: evaluationService}
+}
+
+// Name returns the job name
+func (j *evaluationJob) Name() string {
+	return "evaluation"
+}
+
+// Run scores the sampled conversations
+func (j *evaluationJob) Run(ctx context.Context) error {
+	evaluated, err := j.evaluationService.EvaluatePending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if evaluated > 0 {
+		logger.Context(ctx).Infow("Evaluated chats", "count", evaluated)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// exportJob periodically packages the pending chat exports
+type exportJob struct {
+	exportService services.ExportService
+}
+
+// NewExportJob creates the chat export job
+func NewExportJob(exportService services.ExportService) Job {
+	return &exportJob{exportService: exportService}
+}
+
+// Name returns the job name
+func (j *exportJob) Name() string {
+	return "export"
+}
+
+// Run packages the pending exports
+func (j *exportJob) Run(ctx context.Context) error {
+	processed, err := j.exportService.ProcessPending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if processed > 0 {
+		logger.Context(ctx).Infow("Processed exports", "count", processed)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// guestCleanupJob periodically deletes the unclaimed chats of expired guest sessions
+type guestCleanupJob struct {
+	guestService services.GuestService
+}
+
+// NewGuestCleanupJob creates the guest cleanup job
+func NewGuestCleanupJob(guestService services.GuestService) Job {
+	return &guestCleanupJob{guestService: guestService}
+}
+
+// Name returns the job name
+func (j *guestCleanupJob) Name() string {
+	return "guest_cleanup"
+}
+
+// Run deletes the chats of expired guest sessions
+func (j *guestCleanupJob) Run(ctx context.Context) error {
+	purged, err := j.guestService.PurgeExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		logger.Context(ctx).Infow("Purged guest chats", "count", purged)
+	}
+	return nil
+}
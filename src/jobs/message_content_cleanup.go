@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// messageContentCleanupJob periodically deletes the shared message content no message
+// references anymore
+type messageContentCleanupJob struct {
+	messageContentService services.MessageContentService
+}
+
+// NewMessageContentCleanupJob creates the shared message content cleanup job
+func NewMessageContentCleanupJob(messageContentService services.MessageContentService) Job {
+	return &messageContentCleanupJob{messageContentService: messageContentService}
+}
+
+// Name returns the job name
+func (j *messageContentCleanupJob) Name() string {
+	return "message_content_cleanup"
+}
+
+// Run deletes the shared message content no message references anymore
+func (j *messageContentCleanupJob) Run(ctx context.Context) error {
+	purged, err := j.messageContentService.PurgeUnreferenced(ctx)
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		logger.Context(ctx).Infow("Purged unreferenced message content", "count", purged)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// reconciliationJob periodically reconciles the usage recorded with the usage reported by
+// the LLM vendor
+type reconciliationJob struct {
+	billingService services.BillingService
+}
+
+// NewReconciliationJob creates the usage reconciliation job
+func NewReconciliationJob(billingService services.BillingService) Job {
+	return &reconciliationJob{billingService: billingService}
+}
+
+// Name returns the job name
+func (j *reconciliationJob) Name() string {
+	return "usage_reconciliation"
+}
+
+// Run imports the usage of the vendor and reconciles it
+func (j *reconciliationJob) Run(ctx context.Context) error {
+	discrepancies, err := j.billingService.ReconcileUsage(ctx)
+	if err != nil {
+		return err
+	}
+
+	if discrepancies > 0 {
+		logger.Context(ctx).Warnw("Usage discrepancies with the vendor", "count", discrepancies)
+	}
+	return nil
+}
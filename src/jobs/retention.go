@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// retentionJob periodically deletes the messages past their retention
+type retentionJob struct {
+	retentionService services.RetentionService
+	dryRun           bool
+}
+
+// NewRetentionJob creates the retention purge job. On a dry run it only reports
+// the messages it would delete.
+func NewRetentionJob(retentionService services.RetentionService, dryRun bool) Job {
+	return &retentionJob{retentionService: retentionService, dryRun: dryRun}
+}
+
+// Name returns the job name
+func (j *retentionJob) Name() string {
+	return "retention"
+}
+
+// Run purges the expired messages; the purge report is logged by the service
+func (j *retentionJob) Run(ctx context.Context) error {
+	_, err := j.retentionService.Purge(ctx, j.dryRun)
+	return err
+}
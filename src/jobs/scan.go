@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// scanJob periodically scans the uploaded attachments for malware
+type scanJob struct {
+	attachmentService services.AttachmentService
+}
+
+// NewScanJob creates the attachment malware scanning job
+func NewScanJob(attachmentService services.AttachmentService) Job {
+	return &scanJob{attachmentService: attachmentService}
+}
+
+// Name returns the job name
+func (j *scanJob) Name() string {
+	return "attachment_scan"
+}
+
+// Run scans the uploaded attachments
+func (j *scanJob) Run(ctx context.Context) error {
+	processed, err := j.attachmentService.ProcessPending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if processed > 0 {
+		logger.Context(ctx).Infow("Scanned attachments", "count", processed)
+	}
+	return nil
+}
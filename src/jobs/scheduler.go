@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// Job defines a background job run periodically by the scheduler
+type Job interface {
+	// Name returns the unique job name, also used as its distributed lock name
+	Name() string
+
+	// Run executes one iteration of the job
+	Run(ctx context.Context) error
+}
+
+// entry is a job registered with its interval
+type entry struct {
+	job      Job
+	interval time.Duration
+}
+
+// Scheduler runs registered jobs periodically. Every run is guarded by a distributed
+// lock so that only one instance executes a job at a time when horizontally scaled.
+// Jobs run once per data residency region, each run with the queries of its region.
+type Scheduler struct {
+	lock    adapters.LockAdapter
+	regions []string
+	entries []entry
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a new job scheduler running jobs in each of regions
+func NewScheduler(lock adapters.LockAdapter, regions []string) *Scheduler {
+	if len(regions) == 0 {
+		regions = []string{""}
+	}
+	return &Scheduler{lock: lock, regions: regions}
+}
+
+// Register adds a job to run every interval. Jobs must be registered before Start.
+func (s *Scheduler) Register(job Job, interval time.Duration) {
+	s.entries = append(s.entries, entry{job: job, interval: interval})
+}
+
+// Start launches one goroutine per registered job
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go func(e entry) {
+			defer s.wg.Done()
+			s.loop(ctx, e)
+		}(e)
+	}
+
+	logger.Info("Job scheduler started", logger.Field("jobs", len(s.entries)))
+}
+
+// Stop cancels running jobs and waits for them to finish
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	logger.Info("Job scheduler stopped")
+}
+
+// loop runs a job on every tick until ctx is done
+func (s *Scheduler) loop(ctx context.Context, e entry) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, region := range s.regions {
+				s.RunOnce(adapters.WithRegion(ctx, region), e.job)
+			}
+		}
+	}
+}
+
+// RunOnce runs a job immediately, in the region of ctx, if its lock can be acquired
+func (s *Scheduler) RunOnce(ctx context.Context, job Job) {
+	// The LLM requests of jobs queue behind those of users
+	ctx = adapters.WithPriority(logger.WithRequestID(ctx), adapters.PriorityBackground)
+	log := logger.Context(ctx)
+
+	unlock, acquired, err := s.lock.TryLock(ctx, "job:"+job.Name())
+	if err != nil {
+		log.Errorw("Failed to acquire job lock", "job", job.Name(), "error", err)
+		return
+	}
+	if !acquired {
+		log.Debugw("Job is running on another instance, skipping", "job", job.Name())
+		return
+	}
+	defer unlock()
+
+	start := time.Now()
+	log.Infow("Starting job", "job", job.Name(), "region", adapters.RegionOf(ctx))
+	if err := job.Run(ctx); err != nil {
+		log.Errorw("Job failed", "job", job.Name(), "error", err, "elapsed", time.Since(start))
+		return
+	}
+	log.Infow("Completed job", "job", job.Name(), "elapsed", time.Since(start))
+}
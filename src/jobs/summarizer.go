@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// summarizerJob periodically folds old messages of long chats into their summaries
+type summarizerJob struct {
+	summaryService services.SummaryService
+}
+
+// NewSummarizerJob creates the chat summarizer job
+func NewSummarizerJob(summaryService services.SummaryService) Job {
+	return &summarizerJob{summaryService: summaryService}
+}
+
+// Name returns the job name
+func (j *summarizerJob) Name() string {
+	return "summarizer"
+}
+
+// Run summarizes the chats pending a summary update
+func (j *summarizerJob) Run(ctx context.Context) error {
+	summarized, err := j.summaryService.SummarizePending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if summarized > 0 {
+		logger.Context(ctx).Infow("Summarized chats", "count", summarized)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// trainingExportJob periodically builds the pending fine-tuning dataset exports
+type trainingExportJob struct {
+	trainingExportService services.TrainingExportService
+}
+
+// NewTrainingExportJob creates the fine-tuning dataset export job
+func NewTrainingExportJob(trainingExportService services.TrainingExportService) Job {
+	return &trainingExportJob{trainingExportService: trainingExportService}
+}
+
+// Name returns the job name
+func (j *trainingExportJob) Name() string {
+	return "training_export"
+}
+
+// Run builds the pending dataset exports
+func (j *trainingExportJob) Run(ctx context.Context) error {
+	processed, err := j.trainingExportService.ProcessPending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if processed > 0 {
+		logger.Context(ctx).Infow("Processed training exports", "count", processed)
+	}
+	return nil
+}
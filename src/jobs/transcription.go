@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// transcriptionJob periodically transcribes the pending voice messages
+type transcriptionJob struct {
+	voiceService services.VoiceService
+}
+
+// NewTranscriptionJob creates the voice message transcription job
+func NewTranscriptionJob(voiceService services.VoiceService) Job {
+	return &transcriptionJob{voiceService: voiceService}
+}
+
+// Name returns the job name
+func (j *transcriptionJob) Name() string {
+	return "transcription"
+}
+
+// Run transcribes the pending voice messages
+func (j *transcriptionJob) Run(ctx context.Context) error {
+	processed, err := j.voiceService.ProcessPending(ctx)
+	if err != nil {
+		return err
+	}
+
+	if processed > 0 {
+		logger.Context(ctx).Infow("Transcribed voice messages", "count", processed)
+	}
+	return nil
+}
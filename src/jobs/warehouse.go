@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// warehouseJob periodically exports the chats, messages and usage changed to the data warehouse
+type warehouseJob struct {
+	warehouseService services.WarehouseService
+}
+
+// NewWarehouseJob creates the warehouse export job
+func NewWarehouseJob(warehouseService services.WarehouseService) Job {
+	return &warehouseJob{warehouseService: warehouseService}
+}
+
+// Name returns the job name
+func (j *warehouseJob) Name() string {
+	return "warehouse_export"
+}
+
+// Run exports the rows changed since the previous run
+func (j *warehouseJob) Run(ctx context.Context) error {
+	exported, err := j.warehouseService.Export(ctx)
+	if exported > 0 {
+		logger.Context(ctx).Infow("Exported rows to the warehouse", "count", exported)
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/services"
+)
+
+// watchdogJob periodically fails the replies stuck in generation, so that their chats are
+// not left waiting on a generation that ended with its instance
+type watchdogJob struct {
+	messageService services.MessageService
+}
+
+// NewWatchdogJob creates the stuck generation watchdog job
+func NewWatchdogJob(messageService services.MessageService) Job {
+	return &watchdogJob{messageService: messageService}
+}
+
+// Name returns the job name
+func (j *watchdogJob) Name() string {
+	return "generation_watchdog"
+}
+
+// Run fails the replies not saved for longer than the watchdog threshold
+func (j *watchdogJob) Run(ctx context.Context) error {
+	failed, err := j.messageService.FailStalledReplies(ctx)
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		logger.Context(ctx).Warnw("Failed replies stuck in generation", "count", failed)
+	}
+	return nil
+}
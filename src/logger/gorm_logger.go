@@ -17,10 +17,11 @@ type GormLogger struct {
 	SkipErrRecordNotFound bool
 }
 
-// NewGormLogger creates a new GORM logger that integrates with our logging system
-func NewGormLogger() logger.Interface {
+// NewGormLogger creates a new GORM logger that integrates with our logging system,
+// warning about queries slower than slowThreshold
+func NewGormLogger(slowThreshold time.Duration) logger.Interface {
 	return &GormLogger{
-		SlowThreshold:         200 * time.Millisecond,
+		SlowThreshold:         slowThreshold,
 		SkipErrRecordNotFound: true,
 		SourceField:           "source",
 	}
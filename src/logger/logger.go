@@ -2,14 +2,22 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var globalLogger *zap.Logger
+var (
+	globalLogger *zap.Logger
+	// sampledLogger is used for high-volume logs such as HTTP access logs
+	sampledLogger *zap.Logger
+	// atomicLevel allows changing the log level at runtime
+	atomicLevel = zap.NewAtomicLevel()
+)
 
 // RequestIDKey is the context key for request ID
 type ctxKey string
@@ -19,25 +27,27 @@ const (
 	RequestIDKey ctxKey = "request_id"
 )
 
+const (
+	// RequestIDHeader carries the request ID across service boundaries (HTTP and Kafka headers)
+	RequestIDHeader = "X-Request-ID"
+	// CorrelationIDHeader is accepted as an alternative inbound request ID header
+	CorrelationIDHeader = "X-Correlation-ID"
+)
+
 // Init initializes the logger
 func Init(level string, env string) {
 	config := zap.NewProductionConfig()
 
 	// Set log level
-	var logLevel zapcore.Level
-	switch level {
-	case "debug":
-		logLevel = zap.DebugLevel
-	case "info":
-		logLevel = zap.InfoLevel
-	case "warn":
-		logLevel = zap.WarnLevel
-	case "error":
-		logLevel = zap.ErrorLevel
-	default:
+	logLevel, err := parseLevel(level)
+	if err != nil {
 		logLevel = zap.InfoLevel
 	}
-	config.Level = zap.NewAtomicLevelAt(logLevel)
+	atomicLevel.SetLevel(logLevel)
+	config.Level = atomicLevel
+
+	// Sampling is applied explicitly to high-volume logs only (see ConfigureSampling)
+	config.Sampling = nil
 
 	// Configure output format
 	config.EncoderConfig.TimeKey = "timestamp"
@@ -50,7 +60,6 @@ func Init(level string, env string) {
 	}
 
 	// Create logger
-	var err error
 	globalLogger, err = config.Build()
 	if err != nil {
 		// If we can't initialize the logger, use a simple fallback and exit
@@ -58,9 +67,54 @@ func Init(level string, env string) {
 		os.Exit(1)
 	}
 
+	sampledLogger = globalLogger
 	zap.RedirectStdLog(globalLogger)
 }
 
+// ConfigureSampling enables sampling for high-volume logs: within each tick the first
+// `initial` entries with the same message are logged, then every `thereafter`-th one.
+// A non-positive initial disables sampling.
+func ConfigureSampling(tick time.Duration, initial, thereafter int) {
+	if initial <= 0 || tick <= 0 {
+		sampledLogger = globalLogger
+		return
+	}
+	sampledLogger = globalLogger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, tick, initial, thereafter)
+	}))
+}
+
+// SetLevel changes the log level at runtime
+func SetLevel(level string) error {
+	logLevel, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	atomicLevel.SetLevel(logLevel)
+	return nil
+}
+
+// GetLevel returns the current log level
+func GetLevel() string {
+	return atomicLevel.Level().String()
+}
+
+// parseLevel converts a level name into a zap level
+func parseLevel(level string) (zapcore.Level, error) {
+	switch level {
+	case "debug":
+		return zap.DebugLevel, nil
+	case "info":
+		return zap.InfoLevel, nil
+	case "warn":
+		return zap.WarnLevel, nil
+	case "error":
+		return zap.ErrorLevel, nil
+	default:
+		return zap.InfoLevel, fmt.Errorf("unknown log level: %s", level)
+	}
+}
+
 // WithRequestID adds a request ID to the logger
 func WithRequestID(ctx context.Context) context.Context {
 	if reqID, ok := ctx.Value(RequestIDKey).(string); ok && reqID != "" {
@@ -69,6 +123,32 @@ func WithRequestID(ctx context.Context) context.Context {
 	return context.WithValue(ctx, RequestIDKey, uuid.New().String())
 }
 
+// WithCorrelationID stores an inbound correlation ID as the request ID, generating a new one when empty
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		id = uuid.New().String()
+	}
+	return context.WithValue(ctx, RequestIDKey, id)
+}
+
+// FromHeaders restores the request ID carried by the headers of an inbound event
+func FromHeaders(ctx context.Context, headers map[string]string) context.Context {
+	id := headers[RequestIDHeader]
+	if id == "" {
+		id = headers[CorrelationIDHeader]
+	}
+	return WithCorrelationID(ctx, id)
+}
+
+// Headers returns the headers propagating the request ID of ctx to downstream services
+func Headers(ctx context.Context) map[string]string {
+	reqID := GetRequestID(ctx)
+	if reqID == "" {
+		return nil
+	}
+	return map[string]string{RequestIDHeader: reqID}
+}
+
 // GetRequestID gets the request ID from context
 func GetRequestID(ctx context.Context) string {
 	if reqID, ok := ctx.Value(RequestIDKey).(string); ok {
@@ -91,6 +171,15 @@ func Context(ctx context.Context) *zap.SugaredLogger {
 	return globalLogger.With(zap.String("request_id", reqID)).Sugar()
 }
 
+// Sampled returns a sampled logger with context information, for high-volume logs
+func Sampled(ctx context.Context) *zap.SugaredLogger {
+	reqID := GetRequestID(ctx)
+	if reqID == "" {
+		return sampledLogger.Sugar()
+	}
+	return sampledLogger.With(zap.String("request_id", reqID)).Sugar()
+}
+
 // Debug logs a debug message
 func Debug(msg string, fields ...zap.Field) {
 	globalLogger.Debug(msg, fields...)
@@ -0,0 +1,275 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric of the service
+const namespace = "chat"
+
+// Registry holds the metrics of the service
+var Registry = prometheus.NewRegistry()
+
+// LLM metrics
+var (
+	LLMRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "requests_total",
+		Help:      "LLM requests by model and outcome.",
+	}, []string{"model", "status"})
+
+	LLMLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "request_duration_seconds",
+		Help:      "LLM request latency by model.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"model"})
+
+	LLMTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "tokens_total",
+		Help:      "LLM tokens by model and kind (prompt or completion).",
+	}, []string{"model", "kind"})
+
+	LLMCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "cache_hits_total",
+		Help:      "LLM responses served from the cache.",
+	})
+
+	ChatOwnerLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "chat_owners",
+		Name:      "lookups_total",
+		Help:      "Lookups of chat owners by the level answering them (memory, redis or database).",
+	}, []string{"level"})
+
+	RecentChatsLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "recent_chats",
+		Name:      "lookups_total",
+		Help:      "Chat list pages served by the recent chats view (hit) or rebuilding it (miss).",
+	}, []string{"result"})
+
+	LLMKeyRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "key_requests_total",
+		Help:      "LLM requests by API key fingerprint and outcome (success, rate_limited or error).",
+	}, []string{"key", "outcome"})
+
+	LLMHedges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "hedges_total",
+		Help:      "Hedged LLM requests sent, by outcome (won, lost or failed).",
+	}, []string{"outcome"})
+
+	LLMConnectionWarms = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "connection_warms_total",
+		Help:      "Connections to the LLM vendor warmed, by outcome (success or error).",
+	}, []string{"outcome"})
+
+	LLMInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "requests_in_flight",
+		Help:      "LLM requests sent to the provider and not completed, when concurrency is bounded.",
+	})
+
+	LLMQueued = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "requests_queued",
+		Help:      "LLM requests waiting for a concurrency slot by priority class.",
+	}, []string{"priority"})
+
+	LLMStalledGenerations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "stalled_generations_total",
+		Help:      "Replies stuck in generation failed by the watchdog.",
+	})
+
+	LLMQueueWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "queue_wait_seconds",
+		Help:      "Time LLM requests waited for a concurrency slot by priority class, including those that gave up.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"priority"})
+
+	LLMDowngrades = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "downgrades_total",
+		Help:      "Interactive LLM requests sent to the load shedding model by reason (queue or latency).",
+	}, []string{"reason"})
+)
+
+// Database metrics
+var (
+	DBQueryLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Database query latency by table and operation.",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"table", "operation"})
+
+	DBSlowQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "slow_queries_total",
+		Help:      "Database queries slower than the slow query threshold by table and operation.",
+	}, []string{"table", "operation"})
+)
+
+// Repository metrics
+var (
+	RepositoryCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "calls_total",
+		Help:      "Repository method calls by repository, method and outcome (success, not_found or error).",
+	}, []string{"repository", "method", "status"})
+
+	RepositoryLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "call_duration_seconds",
+		Help:      "Repository method latency by repository and method.",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"repository", "method"})
+)
+
+// Message metrics
+var (
+	SendMessageStageLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "messages",
+		Name:      "send_stage_duration_seconds",
+		Help:      "Time spent sending a message by stage: auth, db_read, lock, prompt_build, llm, db_write or publish.",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"stage"})
+
+	StreamsAborted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "messages",
+		Name:      "streams_aborted_total",
+		Help:      "Streamed replies that failed after they started, by reason: provider_error, timeout, rate_limited, content_filtered or internal.",
+	}, []string{"reason"})
+)
+
+// Workspace metrics, labeled with a bounded number of workspaces per instance
+var (
+	WorkspaceRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "workspace",
+		Name:      "requests_total",
+		Help:      "Authenticated requests by workspace and status class (2xx, 3xx, 4xx or 5xx).",
+	}, []string{"workspace", "class"})
+
+	WorkspaceTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "workspace",
+		Name:      "tokens_total",
+		Help:      "LLM tokens spent by workspace.",
+	}, []string{"workspace"})
+
+	WorkspaceCost = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "workspace",
+		Name:      "cost_usd_total",
+		Help:      "Estimated LLM cost in USD by workspace.",
+	}, []string{"workspace"})
+)
+
+// Event metrics
+var (
+	EventDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "deliveries_total",
+		Help:      "Events delivered to the subscribers of the event bus by subscriber and outcome.",
+	}, []string{"subscriber", "status"})
+
+	EventsSpooled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "spooled_total",
+		Help:      "Events spilled to the local spool while the broker was unavailable.",
+	})
+
+	EventsReplayed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "replayed_total",
+		Help:      "Spooled events replayed to the broker once it recovered.",
+	})
+
+	EventsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "spool_dropped_total",
+		Help:      "Events dropped because the spool was full.",
+	})
+
+	EventSpoolBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "spool_bytes",
+		Help:      "Size of the events spooled on disk, waiting to be replayed to the broker.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		LLMRequests,
+		LLMLatency,
+		LLMTokens,
+		LLMCacheHits,
+		ChatOwnerLookups,
+		RecentChatsLookups,
+		LLMKeyRequests,
+		LLMHedges,
+		LLMConnectionWarms,
+		LLMInFlight,
+		LLMQueued,
+		LLMQueueWait,
+		LLMDowngrades,
+		LLMStalledGenerations,
+		DBQueryLatency,
+		DBSlowQueries,
+		RepositoryCalls,
+		RepositoryLatency,
+		SendMessageStageLatency,
+		StreamsAborted,
+		WorkspaceRequests,
+		WorkspaceTokens,
+		WorkspaceCost,
+		EventDeliveries,
+		EventsSpooled,
+		EventsReplayed,
+		EventsDropped,
+		EventSpoolBytes,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() gin.HandlerFunc {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	return func(c *gin.Context) {
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -6,54 +6,62 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/nvnamsss/chat/src/configs"
 	"github.com/nvnamsss/chat/src/errors"
 	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/pkg/timing"
 )
 
-// Auth returns a middleware for JWT authentication
-func Auth(secret string) gin.HandlerFunc {
+// Authentication modes selecting where the JWT is read from
+const (
+	AuthModeHeader = "header"
+	AuthModeCookie = "cookie"
+	AuthModeBoth   = "both"
+)
+
+// Auth returns a middleware for JWT authentication. The token is read from the
+// Authorization header or the session cookie depending on the configured mode.
+// publicPaths are served without authentication.
+func Auth(cfg configs.JWT, publicPaths ...string) gin.HandlerFunc {
+	// Skip auth for health check and metrics scraping
+	public := map[string]bool{"/health": true, "/metrics": true}
+	for _, path := range publicPaths {
+		public[path] = true
+	}
+
 	return func(c *gin.Context) {
 		log := logger.Context(c.Request.Context())
 
-		// Skip auth for health check
-		if c.Request.URL.Path == "/health" {
+		if public[c.Request.URL.Path] {
 			c.Next()
 			return
 		}
 
-		// Get token from Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			log.Warnw("Missing Authorization header")
+		endAuth := timing.Start(c.Request.Context(), timing.StageAuth)
+		tokenStr, fromCookie, message := extractToken(c, cfg)
+		if message != "" {
+			log.Warnw(message)
 			c.AbortWithStatusJSON(401, gin.H{
 				"code":    errors.ErrUnauthorized,
-				"message": "Missing authentication token",
+				"message": message,
 			})
 			return
 		}
 
-		// Check if the header has the expected format
-		authParts := strings.Split(authHeader, " ")
-		if len(authParts) != 2 || authParts[0] != "Bearer" {
-			log.Warnw("Invalid Authorization header format")
-			c.AbortWithStatusJSON(401, gin.H{
-				"code":    errors.ErrUnauthorized,
-				"message": "Invalid authentication token format",
+		// Browsers attach the cookie to requests of any site, so changes must come from a
+		// trusted origin
+		if fromCookie && !SameOrigin(c, cfg.Cookie.AllowedOrigins) {
+			log.Warnw("Cross-site request with the session cookie", "method", c.Request.Method, "origin", c.GetHeader("Origin"))
+			c.AbortWithStatusJSON(403, gin.H{
+				"code":    errors.ErrForbidden,
+				"message": "Cross-site request rejected",
 			})
 			return
 		}
 
 		// Parse and validate token
-		tokenStr := authParts[1]
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := ParseToken(tokenStr, cfg.Secret)
+		if err != nil {
 			log.Warnw("Invalid authentication token", "error", err)
 			c.AbortWithStatusJSON(401, gin.H{
 				"code":    errors.ErrUnauthorized,
@@ -62,17 +70,6 @@ func Auth(secret string) gin.HandlerFunc {
 			return
 		}
 
-		// Extract claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			log.Warnw("Failed to extract token claims")
-			c.AbortWithStatusJSON(401, gin.H{
-				"code":    errors.ErrUnauthorized,
-				"message": "Invalid token claims",
-			})
-			return
-		}
-
 		// Extract user ID from claims
 		userID, ok := claims["sub"].(string)
 		if !ok {
@@ -84,12 +81,109 @@ func Auth(secret string) gin.HandlerFunc {
 			return
 		}
 
+		// Tokens bound to an origin, like those of widget sessions, are rejected from browsers
+		// on other origins
+		if origin, ok := claims["origin"].(string); ok && origin != "" {
+			if requestOrigin := c.GetHeader("Origin"); requestOrigin != "" && !strings.EqualFold(strings.TrimSuffix(requestOrigin, "/"), origin) {
+				log.Warnw("Token used from another origin", "origin", requestOrigin, "userID", userID)
+				c.AbortWithStatusJSON(403, gin.H{
+					"code":    errors.ErrForbidden,
+					"message": "Token is not valid on this origin",
+				})
+				return
+			}
+		}
+
 		// Store user ID in context
 		c.Set("userID", userID)
 
+		// Store role in context; tokens without a role claim are regular users
+		if role, ok := claims["role"].(string); ok {
+			c.Set("role", role)
+		}
+
+		// Store scopes in context; tokens without a scope claim are not restricted
+		if scopes, ok := tokenScopes(claims); ok {
+			c.Set("scopes", scopes)
+		}
+
+		// Store the admin acting as the user of impersonation tokens, named by their act claim
+		if actor, ok := claims["act"].(map[string]interface{}); ok {
+			if adminID, ok := actor["sub"].(string); ok && adminID != "" {
+				c.Set("impersonatorID", adminID)
+			}
+		}
+
 		// Store claims in context if needed
 		c.Set("claims", claims)
 
+		endAuth()
+		c.Next()
+	}
+}
+
+// extractToken returns the raw token of a request and whether it was read from the cookie,
+// or the reason it is missing
+func extractToken(c *gin.Context, cfg configs.JWT) (string, bool, string) {
+	authHeader := c.GetHeader("Authorization")
+
+	// In both mode the Authorization header takes precedence over the cookie
+	if cfg.Mode == AuthModeCookie || (cfg.Mode == AuthModeBoth && authHeader == "") {
+		token, err := c.Cookie(cfg.Cookie.Name)
+		if err != nil || token == "" {
+			return "", false, "Missing authentication token"
+		}
+		return token, true, ""
+	}
+
+	// Get token from Authorization header
+	if authHeader == "" {
+		return "", false, "Missing authentication token"
+	}
+
+	// Check if the header has the expected format
+	authParts := strings.Split(authHeader, " ")
+	if len(authParts) != 2 || authParts[0] != "Bearer" {
+		return "", false, "Invalid authentication token format"
+	}
+	return authParts[1], false, ""
+}
+
+// ParseToken parses and validates a JWT signed with secret and returns its claims
+func ParseToken(tokenStr string, secret string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		// Validate the signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	return claims, nil
+}
+
+// RequireRole returns a middleware that only allows users with the given role
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != role {
+			logger.Context(c.Request.Context()).Warnw("Insufficient role", "required", role, "userID", c.GetString("userID"))
+			c.AbortWithStatusJSON(403, gin.H{
+				"code":    errors.ErrForbidden,
+				"message": "Insufficient permissions",
+			})
+			return
+		}
+
 		c.Next()
 	}
 }
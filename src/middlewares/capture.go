@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/adapters"
+)
+
+// CaptureHeader opts a request in to having its LLM calls captured for support
+const CaptureHeader = "X-Support-Capture"
+
+// Capture returns a middleware capturing the LLM calls of requests into support bundles,
+// retrievable by admins with the request ID. With all, every request is captured; otherwise
+// only those setting CaptureHeader to true. Captured responses carry the header too.
+func Capture(all bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		optedIn, _ := strconv.ParseBool(c.GetHeader(CaptureHeader))
+		if !all && !optedIn {
+			c.Next()
+			return
+		}
+
+		route := c.Request.Method + " " + c.Request.URL.Path
+		c.Request = c.Request.WithContext(adapters.WithCapture(c.Request.Context(), c.GetString("userID"), route))
+		c.Header(CaptureHeader, "true")
+		c.Next()
+	}
+}
@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// ChatKey is the context key of the chat loaded by LoadChat
+const ChatKey = "chat"
+
+// ChatLoader looks up chats by ID
+type ChatLoader interface {
+	GetChat(ctx context.Context, id int64) (*dtos.ChatResponse, error)
+}
+
+// LoadChat returns a middleware loading the chat of the id path parameter and checking that
+// the authenticated user owns it, before the request is handled. The chat is then read with
+// LoadedChat. Errors are sent with respondError and abort the request.
+func LoadChat(chats ChatLoader, respondError func(c *gin.Context, err error)) gin.HandlerFunc {
+	return loadChat(chats, respondError, false, pathChatID)
+}
+
+// LoadChatOrAdmin returns a middleware like LoadChat that also lets admins load the chats of
+// other users
+func LoadChatOrAdmin(chats ChatLoader, respondError func(c *gin.Context, err error)) gin.HandlerFunc {
+	return loadChat(chats, respondError, true, pathChatID)
+}
+
+// LoadQueryChat returns a middleware like LoadChat loading the chat of the chatId query
+// parameter
+func LoadQueryChat(chats ChatLoader, respondError func(c *gin.Context, err error)) gin.HandlerFunc {
+	return loadChat(chats, respondError, false, queryChatID)
+}
+
+// LoadBodyChat returns a middleware like LoadChat loading the chat of the chatId field of the
+// JSON body, which is left for the handler to read
+func LoadBodyChat(chats ChatLoader, respondError func(c *gin.Context, err error)) gin.HandlerFunc {
+	return loadChat(chats, respondError, false, bodyChatID)
+}
+
+// loadChat returns the middleware of LoadChat for the chat ID chatID reads, letting admins
+// through when admins is set
+func loadChat(chats ChatLoader, respondError func(c *gin.Context, err error), admins bool, chatID func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		if userID == "" {
+			respondError(c, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+			c.Abort()
+			return
+		}
+
+		idStr := chatID(c)
+		if idStr == "" {
+			respondError(c, errors.New(errors.ErrInvalidRequest, "Missing chat ID"))
+			c.Abort()
+			return
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			logger.Context(c.Request.Context()).Errorw("Invalid chat ID", "id", idStr, "error", err)
+			respondError(c, errors.New(errors.ErrInvalidRequest, "Invalid chat ID"))
+			c.Abort()
+			return
+		}
+
+		chat, err := chats.GetChat(c.Request.Context(), id)
+		if err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+
+		// Verify the user owns the chat
+		if chat.UserID != userID && !(admins && c.GetString("role") == models.RoleAdmin) {
+			respondError(c, errors.New(errors.ErrForbidden, "User does not have access to this chat"))
+			c.Abort()
+			return
+		}
+
+		c.Set(ChatKey, chat)
+		c.Next()
+	}
+}
+
+// pathChatID returns the id path parameter
+func pathChatID(c *gin.Context) string {
+	return c.Param("id")
+}
+
+// queryChatID returns the chatId query parameter
+func queryChatID(c *gin.Context) string {
+	return c.Query("chatId")
+}
+
+// bodyChatID returns the chatId field of the JSON body, restoring the body for the handler
+func bodyChatID(c *gin.Context) string {
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		ChatID json.RawMessage `json:"chatId"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	return string(req.ChatID)
+}
+
+// LoadedChat returns the chat loaded by LoadChat, or nil when it did not run
+func LoadedChat(c *gin.Context) *dtos.ChatResponse {
+	chat, _ := c.Get(ChatKey)
+	loaded, _ := chat.(*dtos.ChatResponse)
+	return loaded
+}
@@ -1,20 +1,54 @@
 package middlewares
 
 import (
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// CORS returns a middleware for handling Cross-Origin Resource Sharing (CORS)
-func CORS() gin.HandlerFunc {
-	return cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"},
-		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	})
+// CORS returns a middleware for handling Cross-Origin Resource Sharing (CORS). Every origin
+// may call the API with tokens in the Authorization header, as embedded widgets do, but only
+// allowedOrigins may send credentialed requests carrying the session cookie.
+func CORS(allowedOrigins []string) gin.HandlerFunc {
+	public := cors.New(corsConfig(cors.Config{AllowAllOrigins: true}))
+	if len(allowedOrigins) == 0 {
+		return public
+	}
+
+	credentialed := cors.New(corsConfig(cors.Config{AllowOrigins: allowedOrigins, AllowCredentials: true}))
+	allowed := originSet(allowedOrigins)
+	return func(c *gin.Context) {
+		if allowed[normalizeOrigin(c.GetHeader("Origin"))] {
+			credentialed(c)
+			return
+		}
+		// The response differs for the allowed origins, which caches must not be served
+		c.Writer.Header().Add("Vary", "Origin")
+		public(c)
+	}
+}
+
+// corsConfig completes config with the methods and headers of the API
+func corsConfig(config cors.Config) cors.Config {
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID", "X-Correlation-ID", "X-Chat-ID", "X-Support-Capture", "X-Requested-With", TimingHeader}
+	config.ExposeHeaders = []string{"Content-Length", "X-Request-ID", "X-Chat-ID", "X-Message-ID", "X-Support-Capture", "Server-Timing"}
+	config.MaxAge = 12 * time.Hour
+	return config
+}
+
+// originSet returns the normalized origins of a list
+func originSet(origins []string) map[string]bool {
+	set := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		set[normalizeOrigin(origin)] = true
+	}
+	return set
+}
+
+// normalizeOrigin lowercases an origin and drops its trailing slash
+func normalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimSuffix(origin, "/"))
 }
@@ -0,0 +1,64 @@
+package middlewares
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/configs"
+)
+
+// DrainState tracks whether the server is draining before it stops. While draining,
+// readiness is reported as false so load balancers stop routing new requests to it.
+type DrainState struct {
+	delay time.Duration
+
+	mu        sync.RWMutex
+	drainedAt time.Time
+}
+
+// NewDrainState creates the drain state from the shutdown configuration
+func NewDrainState(cfg configs.Shutdown) *DrainState {
+	return &DrainState{delay: cfg.ReadinessDelay}
+}
+
+// Draining reports whether the server is draining
+func (d *DrainState) Draining() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return !d.drainedAt.IsZero()
+}
+
+// Drain starts draining, then waits until readiness has been reported as false for the
+// readiness delay or ctx is done. Draining again only waits for the rest of the delay, so a
+// shutdown following a preStop drain closes the listener right away.
+func (d *DrainState) Drain(ctx context.Context) {
+	d.mu.Lock()
+	if d.drainedAt.IsZero() {
+		d.drainedAt = time.Now()
+	}
+	remaining := time.Until(d.drainedAt.Add(d.delay))
+	d.mu.Unlock()
+
+	if remaining <= 0 {
+		return
+	}
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// Drain returns a middleware that asks clients to close their connections while the
+// server is draining, so keep-alive clients reconnect to another instance
+func Drain(state *DrainState) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if state.Draining() {
+			c.Header("Connection", "close")
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/pkg/dryrun"
+)
+
+// DryRunHeader asks for a dry run as the dryRun query parameter does, and marks the
+// responses to dry runs
+const DryRunHeader = "X-Dry-Run"
+
+// DryRun returns a middleware marking the context of requests asking for a dry run, with
+// the dryRun query parameter or the X-Dry-Run header. Only routes, given as method and path
+// as registered, support dry runs; writes to other routes asking for one are rejected
+// rather than performed, and reads are served as usual.
+func DryRun(routes ...string) gin.HandlerFunc {
+	supported := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		supported[route] = true
+	}
+
+	return func(c *gin.Context) {
+		value := c.Query("dryRun")
+		if value == "" {
+			value = c.GetHeader(DryRunHeader)
+		}
+		if value == "" {
+			c.Next()
+			return
+		}
+
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"code":    errors.ErrInvalidRequest,
+				"message": "Invalid dryRun value, expected true or false",
+			})
+			return
+		}
+		if !enabled || isReadMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if !supported[c.Request.Method+" "+c.FullPath()] {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"code":    errors.ErrInvalidRequest,
+				"message": "This endpoint does not support dry runs",
+			})
+			return
+		}
+
+		c.Header(DryRunHeader, "true")
+		c.Request = c.Request.WithContext(dryrun.With(c.Request.Context()))
+		c.Next()
+	}
+}
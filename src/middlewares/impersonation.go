@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// AuditRecorder writes entries to the audit log
+type AuditRecorder interface {
+	Record(ctx context.Context, entry *models.AuditLog) error
+}
+
+// Impersonation returns a middleware writing every request made with an impersonation token
+// to the audit log, with the admin behind it, once the request has been handled. It has to
+// run after authentication and before the region is selected.
+func Impersonation(audit AuditRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID := c.GetString("impersonatorID")
+		if adminID == "" {
+			c.Next()
+			return
+		}
+
+		// The entry goes to the main database whatever the data residency region of the request,
+		// and is written even when the client went away before the response
+		ctx := context.WithoutCancel(c.Request.Context())
+		c.Next()
+
+		if err := audit.Record(ctx, &models.AuditLog{
+			AdminID:   adminID,
+			UserID:    c.GetString("userID"),
+			Action:    models.AuditActionImpersonatedRequest,
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Status:    c.Writer.Status(),
+			RequestID: logger.GetRequestID(ctx),
+		}); err != nil {
+			logger.Context(ctx).Errorw("Failed to audit impersonated request", "error", err, "adminID", adminID)
+		}
+	}
+}
@@ -1,14 +1,51 @@
 package middlewares
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/configs"
 	"github.com/nvnamsss/chat/src/logger"
 )
 
+// redactedValue replaces the value of sensitive fields in captured bodies
+const redactedValue = "[REDACTED]"
+
+// bodyCaptureWriter tees the response body into a size-capped buffer
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body  *bytes.Buffer
+	limit int
+}
+
+// Write writes the data to the response and captures it up to the limit
+func (w *bodyCaptureWriter) Write(data []byte) (int, error) {
+	if remaining := w.limit - w.body.Len(); remaining > 0 {
+		if len(data) > remaining {
+			w.body.Write(data[:remaining])
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
 // Logger returns a middleware that logs HTTP requests
-func Logger() gin.HandlerFunc {
+func Logger(cfg configs.AccessLog) gin.HandlerFunc {
+	excluded := make(map[string]bool, len(cfg.ExcludePaths))
+	for _, path := range cfg.ExcludePaths {
+		excluded[path] = true
+	}
+
+	redacted := make(map[string]bool, len(cfg.RedactFields))
+	for _, field := range cfg.RedactFields {
+		redacted[strings.ToLower(field)] = true
+	}
+
 	return func(c *gin.Context) {
 		// Get request ID from context
 		ctx := logger.WithRequestID(c.Request.Context())
@@ -19,18 +56,45 @@ func Logger() gin.HandlerFunc {
 		path := c.Request.URL.Path
 		raw := c.Request.URL.RawQuery
 
+		// Capture bodies if enabled
+		var requestBody []byte
+		var responseBody *bodyCaptureWriter
+		if cfg.LogBodies && !excluded[path] {
+			if c.Request.Body != nil {
+				requestBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, int64(cfg.MaxBodySize)))
+				c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(requestBody), c.Request.Body))
+			}
+			responseBody = &bodyCaptureWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}, limit: cfg.MaxBodySize}
+			c.Writer = responseBody
+		}
+
 		// Process request
 		c.Next()
 
 		// Calculate latency
 		latency := time.Since(start)
 
-		// Get request ID for logging
+		// Get request ID for logging; later middlewares may have replaced it
+		// with the inbound X-Request-ID
+		ctx = c.Request.Context()
 		reqID := logger.GetRequestID(ctx)
 
-		// Log request details
-		log := logger.Context(ctx)
-		log.Infow("HTTP Request",
+		// Slow requests are always reported, even on excluded paths
+		if cfg.SlowThreshold > 0 && latency > cfg.SlowThreshold {
+			logger.Context(ctx).Warnw("Slow HTTP request",
+				"status", c.Writer.Status(),
+				"method", c.Request.Method,
+				"path", path,
+				"latency", latency,
+				"threshold", cfg.SlowThreshold,
+			)
+		}
+
+		if excluded[path] {
+			return
+		}
+
+		fields := []interface{}{
 			"status", c.Writer.Status(),
 			"method", c.Request.Method,
 			"path", path,
@@ -40,6 +104,52 @@ func Logger() gin.HandlerFunc {
 			"user-agent", c.Request.UserAgent(),
 			"request_id", reqID,
 			"errors", c.Errors.String(),
-		)
+		}
+		if responseBody != nil {
+			fields = append(fields,
+				"request_body", redactBody(requestBody, redacted),
+				"response_body", redactBody(responseBody.body.Bytes(), redacted),
+			)
+		}
+
+		// Log request details
+		log := logger.Sampled(ctx)
+		log.Infow("HTTP Request", fields...)
+	}
+}
+
+// redactBody returns the body for logging with sensitive JSON fields replaced.
+// Bodies that are not valid JSON (including truncated ones) are logged as-is.
+func redactBody(body []byte, fields map[string]bool) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	return redactValue(value, fields)
+}
+
+// redactValue recursively replaces values of sensitive keys
+func redactValue(value interface{}, fields map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if fields[strings.ToLower(key)] {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(inner, fields)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(inner, fields)
+		}
+		return v
+	default:
+		return v
 	}
 }
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// MaintenanceState holds the runtime maintenance mode switch
+type MaintenanceState struct {
+	mu         sync.RWMutex
+	enabled    bool
+	retryAfter time.Duration
+	message    string
+}
+
+// NewMaintenanceState creates the maintenance switch from its initial configuration
+func NewMaintenanceState(cfg configs.Maintenance) *MaintenanceState {
+	return &MaintenanceState{
+		enabled:    cfg.Enabled,
+		retryAfter: cfg.RetryAfter,
+		message:    cfg.Message,
+	}
+}
+
+// Get returns the current maintenance settings
+func (m *MaintenanceState) Get() (enabled bool, retryAfter time.Duration, message string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled, m.retryAfter, m.message
+}
+
+// Set changes the maintenance settings; zero retryAfter or empty message keep the current values
+func (m *MaintenanceState) Set(enabled bool, retryAfter time.Duration, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.enabled = enabled
+	if retryAfter > 0 {
+		m.retryAfter = retryAfter
+	}
+	if message != "" {
+		m.message = message
+	}
+}
+
+// Maintenance returns a middleware that rejects write operations with 503 while maintenance
+// mode is on. Reads are still served and admins bypass the switch.
+func Maintenance(state *MaintenanceState) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		enabled, retryAfter, message := state.Get()
+		if !enabled || isReadMethod(c.Request.Method) || c.GetString("role") == models.RoleAdmin {
+			c.Next()
+			return
+		}
+
+		seconds := int(retryAfter.Seconds())
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"code":       errors.ErrMaintenance,
+			"message":    message,
+			"retryAfter": seconds,
+		})
+	}
+}
+
+// isReadMethod reports whether the HTTP method does not modify state
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// MessageKey is the context key of the message loaded by LoadMessage
+const MessageKey = "message"
+
+// MessageLoader looks up messages by ID
+type MessageLoader interface {
+	GetMessage(ctx context.Context, id int64) (*dtos.MessageResponse, error)
+}
+
+// ChatOwnerLoader looks up the owners of chats
+type ChatOwnerLoader interface {
+	GetOwner(ctx context.Context, id int64) (*dtos.ChatOwner, error)
+}
+
+// LoadMessage returns a middleware loading the message of the id path parameter and checking
+// that the authenticated user owns its chat, before the request is handled. The message is
+// then read with LoadedMessage. Errors are sent with respondError and abort the request.
+func LoadMessage(messages MessageLoader, owners ChatOwnerLoader, respondError func(c *gin.Context, err error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		if userID == "" {
+			respondError(c, errors.New(errors.ErrUnauthorized, "User not authenticated"))
+			c.Abort()
+			return
+		}
+
+		idStr := c.Param("id")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			logger.Context(c.Request.Context()).Errorw("Invalid message ID", "id", idStr, "error", err)
+			respondError(c, errors.New(errors.ErrInvalidRequest, "Invalid message ID"))
+			c.Abort()
+			return
+		}
+
+		message, err := messages.GetMessage(c.Request.Context(), id)
+		if err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+
+		// Verify the user has access to the chat of the message
+		owner, err := owners.GetOwner(c.Request.Context(), message.ChatID)
+		if err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+		if owner.UserID != userID {
+			respondError(c, errors.New(errors.ErrForbidden, "User does not have access to this message"))
+			c.Abort()
+			return
+		}
+
+		c.Set(MessageKey, message)
+		c.Next()
+	}
+}
+
+// LoadedMessage returns the message loaded by LoadMessage, or nil when it did not run
+func LoadedMessage(c *gin.Context) *dtos.MessageResponse {
+	message, _ := c.Get(MessageKey)
+	loaded, _ := message.(*dtos.MessageResponse)
+	return loaded
+}
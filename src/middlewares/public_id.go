@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/pkg/ulid"
+)
+
+// PublicIDResolver looks up the IDs of chats or messages by their public IDs
+type PublicIDResolver interface {
+	GetIDByPublicID(ctx context.Context, publicID string) (int64, error)
+}
+
+// PublicIDs returns a middleware accepting the public IDs of chats and messages wherever their
+// IDs are: in the id path parameter of chat and message routes and in the chatId query
+// parameter. Public IDs are replaced with the IDs they resolve to before the request is
+// handled. It has to run after the region is selected, which holds the chats of the request.
+func PublicIDs(chats, messages PublicIDResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if publicID := c.Param("id"); ulid.Valid(publicID) {
+			var resolver PublicIDResolver
+			switch idResource(c.FullPath()) {
+			case "chats":
+				resolver = chats
+			case "messages":
+				resolver = messages
+			}
+			if resolver != nil {
+				id, ok := resolvePublicID(c, resolver, publicID)
+				if !ok {
+					return
+				}
+				for i := range c.Params {
+					if c.Params[i].Key == "id" {
+						c.Params[i].Value = strconv.FormatInt(id, 10)
+					}
+				}
+			}
+		}
+
+		query := c.Request.URL.Query()
+		if publicID := query.Get("chatId"); ulid.Valid(publicID) {
+			id, ok := resolvePublicID(c, chats, publicID)
+			if !ok {
+				return
+			}
+			query.Set("chatId", strconv.FormatInt(id, 10))
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		c.Next()
+	}
+}
+
+// idResource returns the resource named by the segment before the id parameter of a route,
+// such as chats for /api/v1/chats/:id/messages
+func idResource(route string) string {
+	segments := strings.Split(route, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i] == ":id" {
+			return segments[i-1]
+		}
+	}
+	return ""
+}
+
+// resolvePublicID returns the ID of the chat or message with a public ID, aborting the
+// request when it cannot be resolved
+func resolvePublicID(c *gin.Context, resolver PublicIDResolver, publicID string) (int64, bool) {
+	id, err := resolver.GetIDByPublicID(c.Request.Context(), publicID)
+	if err == nil {
+		return id, true
+	}
+
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		appErr = errors.Wrap(err, errors.ErrInternal, "Failed to resolve public ID")
+	}
+	if appErr.Code != errors.ErrNotFound {
+		logger.Context(c.Request.Context()).Errorw("Failed to resolve public ID", "error", err, "publicID", publicID)
+	}
+	c.AbortWithStatusJSON(appErr.StatusCode(), gin.H{
+		"code":    appErr.Code,
+		"message": appErr.Message,
+	})
+	return 0, false
+}
@@ -0,0 +1,110 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// fixedWindowLimiter counts requests per key in fixed time windows. Counts are
+// kept in memory, so limits apply per instance.
+type fixedWindowLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	start  time.Time
+	counts map[string]int
+}
+
+// newFixedWindowLimiter creates a limiter allowing limit requests per key and window
+func newFixedWindowLimiter(limit int, window time.Duration) *fixedWindowLimiter {
+	return &fixedWindowLimiter{
+		limit:  limit,
+		window: window,
+		counts: map[string]int{},
+	}
+}
+
+// allow counts a request for key and reports whether it is within the limit with the
+// requests remaining in the window, or else how long until the window resets
+func (l *fixedWindowLimiter) allow(key string) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.start) >= l.window {
+		l.start = now
+		l.counts = map[string]int{}
+	}
+
+	if l.counts[key] >= l.limit {
+		return false, 0, l.start.Add(l.window).Sub(now)
+	}
+	l.counts[key]++
+	return true, l.limit - l.counts[key], 0
+}
+
+// rateLimitWarnRatio is the share of the limit left in the window under which responses
+// carry a rate limit warning
+const rateLimitWarnRatio = 0.2
+
+// rateLimit returns a middleware limiting requests per minute by the key of a
+// request. Requests with an empty key are not limited. Limited requests get the
+// X-RateLimit-Limit and X-RateLimit-Remaining headers, and a warning once few
+// requests remain.
+func rateLimit(requestsPerMinute int, key func(c *gin.Context) string) gin.HandlerFunc {
+	limiter := newFixedWindowLimiter(requestsPerMinute, time.Minute)
+
+	return func(c *gin.Context) {
+		k := key(c)
+		if k == "" {
+			c.Next()
+			return
+		}
+
+		allowed, remaining, retryAfter := limiter.allow(k)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(requestsPerMinute))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			logger.Context(c.Request.Context()).Warnw("Rate limit exceeded", "key", k)
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"code":       errors.ErrRateLimited,
+				"message":    "Too many requests",
+				"retryAfter": seconds,
+			})
+			return
+		}
+		if float64(remaining) < float64(requestsPerMinute)*rateLimitWarnRatio {
+			AddWarning(c, WarningRateLimit, fmt.Sprintf("%d requests remain this minute", remaining))
+		}
+
+		c.Next()
+	}
+}
+
+// GuestRateLimit returns a middleware limiting the requests of each guest session per minute
+func GuestRateLimit(requestsPerMinute int) gin.HandlerFunc {
+	return rateLimit(requestsPerMinute, func(c *gin.Context) string {
+		if c.GetString("role") != models.RoleGuest {
+			return ""
+		}
+		return c.GetString("userID")
+	})
+}
+
+// IPRateLimit returns a middleware limiting the requests of each client IP per minute
+func IPRateLimit(requestsPerMinute int) gin.HandlerFunc {
+	return rateLimit(requestsPerMinute, func(c *gin.Context) string {
+		return c.ClientIP()
+	})
+}
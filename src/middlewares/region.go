@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// Region returns a middleware routing the queries of a request to the database of the data
+// residency region named by the region claim of its token. Requests without the claim, and
+// unauthenticated ones, use the main database. Tokens naming an unknown region are rejected
+// rather than having their data stored elsewhere.
+func Region(cfg configs.Database) gin.HandlerFunc {
+	known := map[string]bool{cfg.Region: true}
+	for name := range cfg.Regions {
+		known[name] = true
+	}
+
+	return func(c *gin.Context) {
+		claims, _ := c.Get("claims")
+		mapClaims, _ := claims.(jwt.MapClaims)
+		region, _ := mapClaims[cfg.RegionClaim].(string)
+		if region == "" {
+			c.Next()
+			return
+		}
+
+		if !known[region] {
+			logger.Context(c.Request.Context()).Warnw("Unknown data residency region", "region", region, "userID", c.GetString("userID"))
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"code":    errors.ErrForbidden,
+				"message": "Unknown data residency region",
+			})
+			return
+		}
+
+		c.Request = c.Request.WithContext(adapters.WithRegion(c.Request.Context(), region))
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// Scopes granted by tokens. A scope ending in :* grants every scope with its prefix.
+const (
+	ScopeChatsRead    = "chats:read"
+	ScopeChatsWrite   = "chats:write"
+	ScopeMessagesSend = "messages:send"
+	ScopeChatsDebug   = "chats:debug"
+	ScopeAdmin        = "admin:*"
+)
+
+// tokenScopes returns the scopes granted by the scope claim of a token, either a
+// space-separated string or a list, and whether the token is restricted to them at all.
+// Tokens without the claim keep full access.
+func tokenScopes(claims jwt.MapClaims) ([]string, bool) {
+	switch scope := claims["scope"].(type) {
+	case string:
+		return strings.Fields(scope), true
+	case []interface{}:
+		scopes := make([]string, 0, len(scope))
+		for _, s := range scope {
+			if s, ok := s.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes, true
+	default:
+		return nil, false
+	}
+}
+
+// hasScope reports whether the granted scopes include the required one
+func hasScope(granted []string, required string) bool {
+	for _, scope := range granted {
+		if scope == required {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(scope, "*"); ok && strings.HasPrefix(required, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope returns a middleware that only allows tokens granting the given scope.
+// Tokens without a scope claim are not restricted.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, restricted := c.Get("scopes")
+		if restricted && !hasScope(granted.([]string), scope) {
+			logger.Context(c.Request.Context()).Warnw("Insufficient scope", "required", scope, "userID", c.GetString("userID"))
+			c.AbortWithStatusJSON(403, gin.H{
+				"code":    errors.ErrForbidden,
+				"message": "Token is missing the " + scope + " scope",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,173 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// Timeout returns a middleware bounding how long requests take to be read and handled.
+// The request context is done at the handler timeout of the route, so database and provider
+// calls stop there, and the body has to be sent within the body read timeout. A request
+// running out of time is answered with 504, or 408 when the body was too slow, in place of
+// whatever its handler responds. streamingRoutes, given as method and path as registered,
+// have no deadlines.
+func Timeout(cfg configs.Timeouts, streamingRoutes ...string) gin.HandlerFunc {
+	streaming := make(map[string]bool, len(streamingRoutes))
+	for _, route := range streamingRoutes {
+		streaming[route] = true
+	}
+
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		if streaming[route] {
+			c.Next()
+			return
+		}
+
+		writer := &timeoutWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		timeout, ok := cfg.Routes[route]
+		if !ok {
+			timeout = cfg.Handler
+		}
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+		}
+		writer.ctx = c.Request.Context()
+
+		// The read deadline is that of the connection, or of the stream with HTTP/2
+		if cfg.BodyRead > 0 && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			controller := http.NewResponseController(writer.ResponseWriter)
+			if err := controller.SetReadDeadline(time.Now().Add(cfg.BodyRead)); err == nil {
+				writer.body = &deadlineBody{ReadCloser: c.Request.Body, controller: controller}
+				c.Request.Body = writer.body
+				defer writer.body.clearDeadline()
+			}
+		}
+
+		c.Next()
+
+		// Handlers giving up on a done context may not respond at all
+		writer.replace()
+	}
+}
+
+// timeoutWriter replaces the response of a request that ran out of time with a timeout error
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx  context.Context
+	body *deadlineBody
+	// replaced is set once the timeout error was written; the handler's response is dropped
+	replaced bool
+}
+
+// WriteHeader sets the response status, unless the response was replaced
+func (w *timeoutWriter) WriteHeader(code int) {
+	if !w.replaced {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// Write writes the response body, unless it was replaced
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.replace() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes the response body, unless it was replaced
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.replace() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// WriteHeaderNow writes the response headers, unless the response was replaced
+func (w *timeoutWriter) WriteHeaderNow() {
+	if !w.replace() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// replace writes the timeout error when the request ran out of time before anything was
+// written, and reports whether the response was replaced
+func (w *timeoutWriter) replace() bool {
+	if w.replaced {
+		return true
+	}
+	if w.ResponseWriter.Written() {
+		return false
+	}
+
+	var status int
+	var message string
+	switch {
+	case w.body != nil && w.body.timedOut:
+		status, message = http.StatusRequestTimeout, "Timed out reading the request body"
+	case stderrors.Is(w.ctx.Err(), context.DeadlineExceeded):
+		status, message = http.StatusGatewayTimeout, "The request timed out"
+	default:
+		return false
+	}
+
+	logger.Context(w.ctx).Warnw(message, "status", status)
+	w.replaced = true
+
+	data, _ := json.Marshal(gin.H{"code": errors.ErrTimeout, "message": message})
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Del("Content-Length")
+	header.Del("Content-Disposition")
+	if status == http.StatusRequestTimeout {
+		// The rest of the body is not read, so the connection cannot be reused
+		header.Set("Connection", "close")
+	}
+	w.ResponseWriter.WriteHeader(status)
+	_, _ = w.ResponseWriter.Write(data)
+	return true
+}
+
+// deadlineBody is a request body read under a read deadline, recording whether it was hit
+type deadlineBody struct {
+	io.ReadCloser
+	controller *http.ResponseController
+	timedOut   bool
+	cleared    bool
+}
+
+// Read reads the body, recording read deadline errors
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	switch {
+	case err == io.EOF:
+		// The server watches the connection for the client going away once the body is read,
+		// which the deadline would take for a disconnect
+		b.clearDeadline()
+	case err != nil && stderrors.Is(err, os.ErrDeadlineExceeded):
+		b.timedOut = true
+	}
+	return n, err
+}
+
+// clearDeadline removes the read deadline once the body is read
+func (b *deadlineBody) clearDeadline() {
+	if !b.cleared && !b.timedOut {
+		b.cleared = true
+		_ = b.controller.SetReadDeadline(time.Time{})
+	}
+}
@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/pkg/timing"
+)
+
+// TimingHeader is the request header asking for the Server-Timing header of the stages of
+// the request in its response
+const TimingHeader = "X-Debug-Timing"
+
+// Timing returns a middleware recording how long the stages of requests take, starting with
+// their authentication. It must be used before Auth.
+func Timing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(timing.WithRecorder(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// TimingRequested returns whether the client asked for the stages of the request
+func TimingRequested(c *gin.Context) bool {
+	requested, _ := strconv.ParseBool(c.GetHeader(TimingHeader))
+	return requested
+}
@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/configs"
+)
+
+// API versions
+const (
+	// APIVersion1 is frozen: its request and response formats no longer change
+	APIVersion1 = "v1"
+	// APIVersion2 wraps responses in an envelope and returns message exchanges
+	APIVersion2 = "v2"
+	// LatestAPIVersion is the version deprecated versions point clients to
+	LatestAPIVersion = APIVersion2
+)
+
+// APIVersionKey is the context key holding the API version of a request
+const APIVersionKey = "APIVersion"
+
+// APIVersion returns a middleware marking the requests of a route group with an API version
+func APIVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(APIVersionKey, version)
+		c.Next()
+	}
+}
+
+// GetAPIVersion returns the API version of a request, defaulting to v1
+func GetAPIVersion(c *gin.Context) string {
+	if version := c.GetString(APIVersionKey); version != "" {
+		return version
+	}
+	return APIVersion1
+}
+
+// Deprecation returns a middleware announcing the deprecation of an API version with
+// the Deprecation, Sunset and Link headers when the version is configured as deprecated
+func Deprecation(version string, cfg configs.API) gin.HandlerFunc {
+	deprecated := false
+	for _, v := range cfg.DeprecatedVersions {
+		if v == version {
+			deprecated = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		if deprecated {
+			c.Header("Deprecation", "true")
+			if cfg.Sunset != "" {
+				c.Header("Sunset", cfg.Sunset)
+			}
+			c.Header("Link", fmt.Sprintf(`</api/%s>; rel="successor-version"`, LatestAPIVersion))
+		}
+		c.Next()
+	}
+}
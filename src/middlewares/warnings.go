@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nvnamsss/chat/src/pkg/warnings"
+)
+
+// Warning codes
+const (
+	// WarningRateLimit warns that few requests remain in the rate limit window
+	WarningRateLimit = "RATE_LIMIT_LOW"
+	// WarningQuota warns that a monthly budget has crossed its warning threshold
+	WarningQuota = "QUOTA_LOW"
+)
+
+// WarningsKey is the context key holding the warnings of a request
+const WarningsKey = "Warnings"
+
+// Warning is a notice returned with a successful response, letting clients warn users
+// before a limit rejects their requests, or telling them how a reply was generated
+type Warning = warnings.Warning
+
+// Warnings returns a middleware collecting the warnings services add to the context of
+// requests while serving them
+func Warnings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(warnings.WithCollector(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// AddWarning adds a warning to the response of a request
+func AddWarning(c *gin.Context, code, message string) {
+	list, _ := c.Get(WarningsKey)
+	added, _ := list.([]Warning)
+	c.Set(WarningsKey, append(added, Warning{Code: code, Message: message}))
+}
+
+// GetWarnings returns the warnings of a request: those added by middlewares and
+// controllers, then those added by services
+func GetWarnings(c *gin.Context) []Warning {
+	list, _ := c.Get(WarningsKey)
+	added, _ := list.([]Warning)
+	return append(added, warnings.From(c.Request.Context())...)
+}
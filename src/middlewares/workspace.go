@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/pkg/workspace"
+)
+
+// maxWorkspaceIDLength is the longest workspace ID counted; longer claims count towards the
+// default workspace
+const maxWorkspaceIDLength = 64
+
+// WorkspaceRecorder counts the requests of the users of workspaces
+type WorkspaceRecorder interface {
+	RecordRequest(ctx context.Context, workspaceID, userID string, status int)
+}
+
+// Workspace returns a middleware serving authenticated requests for the workspace named by
+// the configured claim of their token, or the default workspace, and counting them with
+// their response status. It must be used after Auth.
+func Workspace(cfg configs.WorkspaceStats, recorder WorkspaceRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled || c.GetString("userID") == "" {
+			c.Next()
+			return
+		}
+
+		claims, _ := c.Get("claims")
+		mapClaims, _ := claims.(jwt.MapClaims)
+		workspaceID, _ := mapClaims[cfg.Claim].(string)
+		if workspaceID == "" || len(workspaceID) > maxWorkspaceIDLength {
+			workspaceID = cfg.DefaultWorkspace
+		}
+
+		ctx := workspace.With(c.Request.Context(), workspaceID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		recorder.RecordRequest(ctx, workspaceID, c.GetString("userID"), c.Writer.Status())
+	}
+}
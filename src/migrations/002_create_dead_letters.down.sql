@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_dead_letters_created_at;
+DROP INDEX IF EXISTS idx_dead_letters_topic;
+
+-- Drop tables
+DROP TABLE IF EXISTS dead_letters;
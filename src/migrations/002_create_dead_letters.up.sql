@@ -0,0 +1,18 @@
+-- Create dead_letters table
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id SERIAL PRIMARY KEY,
+    topic TEXT NOT NULL,
+    partition INTEGER NOT NULL,
+    "offset" BIGINT NOT NULL,
+    key TEXT NULL,
+    payload TEXT NOT NULL,
+    headers TEXT NULL,       -- JSON-encoded header map
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    replayed_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_dead_letters_topic ON dead_letters(topic);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_created_at ON dead_letters(created_at);
@@ -0,0 +1,8 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_message_revisions_message_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS message_revisions;
+
+-- Drop columns
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
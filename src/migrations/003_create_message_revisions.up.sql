@@ -0,0 +1,13 @@
+-- Mark edited messages
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- Create message_revisions table
+CREATE TABLE IF NOT EXISTS message_revisions (
+    id SERIAL PRIMARY KEY,
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,   -- content before the edit
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_message_revisions_message_id ON message_revisions(message_id);
@@ -0,0 +1,3 @@
+-- Drop columns
+ALTER TABLE chats DROP COLUMN IF EXISTS summary_message_id;
+ALTER TABLE chats DROP COLUMN IF EXISTS summary;
@@ -0,0 +1,3 @@
+-- Add rolling conversation summary used by the summary prompt strategy
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS summary TEXT NULL;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS summary_message_id BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_messages_user_id;
@@ -0,0 +1,2 @@
+-- Index messages by sender for guest limits and ownership transfers
+CREATE INDEX IF NOT EXISTS idx_messages_user_id ON messages(user_id);
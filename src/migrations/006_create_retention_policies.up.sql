@@ -0,0 +1,7 @@
+-- Create retention_policies table
+CREATE TABLE IF NOT EXISTS retention_policies (
+    user_id VARCHAR(255) PRIMARY KEY,
+    days INTEGER NOT NULL,   -- 0 keeps messages forever
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,9 @@
+-- Create chat_stats projection, maintained by the chat stats consumer from message events
+CREATE TABLE IF NOT EXISTS chat_stats (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(id) ON DELETE CASCADE,
+    message_count BIGINT NOT NULL DEFAULT 0,
+    total_tokens BIGINT NOT NULL DEFAULT 0,
+    last_message_id BIGINT NOT NULL DEFAULT 0,   -- latest message counted, for idempotent replays
+    last_activity_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
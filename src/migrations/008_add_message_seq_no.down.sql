@@ -0,0 +1,6 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_messages_chat_id_seq_no;
+
+-- Drop columns
+ALTER TABLE messages DROP COLUMN IF EXISTS seq_no;
+ALTER TABLE chats DROP COLUMN IF EXISTS last_seq_no;
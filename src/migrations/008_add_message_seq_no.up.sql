@@ -0,0 +1,16 @@
+-- Number the messages of each chat
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS last_seq_no BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS seq_no BIGINT NOT NULL DEFAULT 0;
+
+-- Backfill existing messages in insertion order
+UPDATE messages SET seq_no = numbered.seq_no
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY chat_id ORDER BY id) AS seq_no
+    FROM messages
+) AS numbered
+WHERE messages.id = numbered.id;
+
+UPDATE chats SET last_seq_no = COALESCE((SELECT MAX(seq_no) FROM messages WHERE messages.chat_id = chats.id), 0);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_chat_id_seq_no ON messages(chat_id, seq_no);
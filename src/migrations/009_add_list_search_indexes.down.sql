@@ -0,0 +1,4 @@
+-- Drop indexes, the pg_trgm extension is left installed
+DROP INDEX IF EXISTS idx_chats_title_trgm;
+DROP INDEX IF EXISTS idx_messages_chat_id_created_at;
+DROP INDEX IF EXISTS idx_chats_user_id_updated_at;
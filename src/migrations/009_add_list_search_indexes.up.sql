@@ -0,0 +1,7 @@
+-- Composite indexes for chat listing and message pagination
+CREATE INDEX IF NOT EXISTS idx_chats_user_id_updated_at ON chats(user_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_chat_id_created_at ON messages(chat_id, created_at);
+
+-- Trigram index for title search with ILIKE
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_chats_title_trgm ON chats USING gin (title gin_trgm_ops);
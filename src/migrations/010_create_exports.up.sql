@@ -0,0 +1,17 @@
+-- Create exports table
+CREATE TABLE IF NOT EXISTS exports (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,   -- pending, running, completed or failed
+    storage_key TEXT,
+    size BIGINT NOT NULL DEFAULT 0,
+    chats INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_exports_user_id ON exports(user_id);
+CREATE INDEX IF NOT EXISTS idx_exports_status ON exports(status);
@@ -0,0 +1,8 @@
+-- Create notification_preferences table
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id VARCHAR(255) PRIMARY KEY,
+    email VARCHAR(320) NOT NULL DEFAULT '',
+    disabled_triggers TEXT NOT NULL DEFAULT '',   -- comma-separated triggers the user opted out of
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Drop tables
+DROP TABLE IF EXISTS chat_mutes;
+DROP TABLE IF EXISTS devices;
@@ -0,0 +1,21 @@
+-- Create devices table
+CREATE TABLE IF NOT EXISTS devices (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    platform VARCHAR(10) NOT NULL,   -- fcm or apns
+    token TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_devices_user_id ON devices(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_devices_token ON devices(token);
+
+-- Create chat_mutes table
+CREATE TABLE IF NOT EXISTS chat_mutes (
+    chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    until TIMESTAMP WITH TIME ZONE,   -- NULL mutes until unmuted
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (chat_id, user_id)
+);
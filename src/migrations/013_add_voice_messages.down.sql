@@ -0,0 +1,6 @@
+-- Drop table
+DROP TABLE IF EXISTS attachments;
+
+-- Drop column
+DROP INDEX IF EXISTS idx_messages_status;
+ALTER TABLE messages DROP COLUMN IF EXISTS status;
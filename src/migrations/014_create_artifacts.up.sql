@@ -0,0 +1,11 @@
+-- Create artifacts table
+CREATE TABLE IF NOT EXISTS artifacts (
+    id BIGSERIAL PRIMARY KEY,
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    block_index INTEGER NOT NULL,   -- position of the code block in the message
+    language VARCHAR(64) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_artifacts_message_id_block_index ON artifacts(message_id, block_index);
@@ -0,0 +1 @@
+ALTER TABLE chats DROP COLUMN IF EXISTS url_context;
@@ -0,0 +1,2 @@
+-- Add the per-chat toggle for fetching linked pages into the LLM context
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS url_context BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS persona_id;
+DROP TABLE IF EXISTS personas;
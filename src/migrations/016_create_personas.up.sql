@@ -0,0 +1,17 @@
+-- Create personas table for the assistants of group chats
+CREATE TABLE IF NOT EXISTS personas (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    handle VARCHAR(32) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    system_prompt TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_personas_chat_id_handle ON personas(chat_id, handle);
+
+-- Label assistant messages with the persona that wrote them
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS persona_id BIGINT REFERENCES personas(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_messages_persona_id ON messages(persona_id) WHERE persona_id IS NOT NULL;
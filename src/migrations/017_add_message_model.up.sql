@@ -0,0 +1,2 @@
+-- Tag assistant messages with the LLM model that generated them
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS model VARCHAR(255) NOT NULL DEFAULT '';
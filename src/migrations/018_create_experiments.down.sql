@@ -0,0 +1,6 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS feedback;
+ALTER TABLE messages DROP COLUMN IF EXISTS latency_ms;
+ALTER TABLE messages DROP COLUMN IF EXISTS total_tokens;
+ALTER TABLE messages DROP COLUMN IF EXISTS variant_id;
+DROP TABLE IF EXISTS experiment_variants;
+DROP TABLE IF EXISTS experiments;
@@ -0,0 +1,30 @@
+-- Create usage table, one row per generated reply. Rows outlive their chats and messages
+-- so that spending stays accounted for.
+CREATE TABLE IF NOT EXISTS usage (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    chat_id BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    model VARCHAR(255) NOT NULL,
+    prompt_tokens INTEGER NOT NULL,
+    completion_tokens INTEGER NOT NULL,
+    cost DOUBLE PRECISION NOT NULL,   -- USD
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_user_id_created_at ON usage(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_usage_created_at ON usage(created_at);
+
+-- Create budgets table; the row without user is the service-wide budget
+CREATE TABLE IF NOT EXISTS budgets (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    monthly_limit DOUBLE PRECISION NOT NULL,
+    warn_threshold DOUBLE PRECISION NOT NULL,
+    warned_period VARCHAR(7) NOT NULL DEFAULT '',
+    exceeded_period VARCHAR(7) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets(user_id);
@@ -0,0 +1,33 @@
+-- Create batches tables
+CREATE TABLE IF NOT EXISTS batches (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL,   -- pending, running or completed
+    template TEXT NOT NULL DEFAULT '',
+    total INTEGER NOT NULL,
+    completed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_batches_user_id ON batches(user_id);
+
+CREATE TABLE IF NOT EXISTS batch_items (
+    id BIGSERIAL PRIMARY KEY,
+    batch_id BIGINT NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    chat_id BIGINT,   -- No foreign key: items of a deleted chat fail instead of being answered on their own
+    prompt TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,   -- pending, completed or failed
+    response TEXT NOT NULL DEFAULT '',
+    message_id BIGINT REFERENCES messages(id) ON DELETE SET NULL,
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    total_tokens INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_batch_items_batch_id ON batch_items(batch_id);
+CREATE INDEX IF NOT EXISTS idx_batch_items_status ON batch_items(status);
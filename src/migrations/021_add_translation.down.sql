@@ -0,0 +1,4 @@
+-- Drop columns
+ALTER TABLE messages DROP COLUMN IF EXISTS translation;
+ALTER TABLE messages DROP COLUMN IF EXISTS language;
+ALTER TABLE chats DROP COLUMN IF EXISTS language;
@@ -0,0 +1,5 @@
+-- Add the language replies of a chat are translated into, and the detected language
+-- and model-language translation of messages
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS language VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS language VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS translation TEXT NOT NULL DEFAULT '';
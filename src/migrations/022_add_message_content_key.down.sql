@@ -0,0 +1,3 @@
+-- Drop columns
+ALTER TABLE message_revisions DROP COLUMN IF EXISTS content_key;
+ALTER TABLE messages DROP COLUMN IF EXISTS content_key;
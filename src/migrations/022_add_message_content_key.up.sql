@@ -0,0 +1,4 @@
+-- Add the storage key of message content too large to be kept in the database, which then
+-- holds a preview of it
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS content_key VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE message_revisions ADD COLUMN IF NOT EXISTS content_key VARCHAR(255) NOT NULL DEFAULT '';
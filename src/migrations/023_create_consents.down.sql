@@ -0,0 +1,2 @@
+-- Drop consents table
+DROP TABLE IF EXISTS consents;
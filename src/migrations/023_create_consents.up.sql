@@ -0,0 +1,9 @@
+-- Create consents table, recording the data-usage terms versions accepted by each user
+CREATE TABLE IF NOT EXISTS consents (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    terms_version VARCHAR(64) NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_consents_user_version ON consents(user_id, terms_version);
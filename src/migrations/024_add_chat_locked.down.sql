@@ -0,0 +1,3 @@
+-- Drop columns
+ALTER TABLE chats DROP COLUMN IF EXISTS locked_by;
+ALTER TABLE chats DROP COLUMN IF EXISTS locked;
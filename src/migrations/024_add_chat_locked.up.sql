@@ -0,0 +1,3 @@
+-- Add the read-only flag of chats and who set it
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS locked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS locked_by VARCHAR(16) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Drop chat_transfers table
+DROP TABLE IF EXISTS chat_transfers;
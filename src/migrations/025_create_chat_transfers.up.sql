@@ -0,0 +1,11 @@
+-- Create chat_transfers table, recording the ownership transfers of chats
+CREATE TABLE IF NOT EXISTS chat_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    from_user_id VARCHAR(255) NOT NULL,
+    to_user_id VARCHAR(255) NOT NULL,
+    transferred_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_transfers_chat_id ON chat_transfers(chat_id);
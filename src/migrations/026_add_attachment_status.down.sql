@@ -0,0 +1,2 @@
+-- Drop column
+ALTER TABLE attachments DROP COLUMN IF EXISTS status;
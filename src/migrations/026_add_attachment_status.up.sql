@@ -0,0 +1,2 @@
+-- Add the status of attachments, pending until their file is uploaded
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'ready';
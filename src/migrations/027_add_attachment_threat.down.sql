@@ -0,0 +1,3 @@
+-- Drop index and column
+DROP INDEX IF EXISTS idx_attachments_status;
+ALTER TABLE attachments DROP COLUMN IF EXISTS threat;
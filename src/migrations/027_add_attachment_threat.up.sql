@@ -0,0 +1,4 @@
+-- Add the malware found in quarantined attachments
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS threat VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_attachments_status ON attachments(status);
@@ -0,0 +1,2 @@
+-- Drop column
+ALTER TABLE usage DROP COLUMN IF EXISTS images;
@@ -0,0 +1,2 @@
+-- Add the number of generated images to usage records
+ALTER TABLE usage ADD COLUMN IF NOT EXISTS images INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Drop tables
+DROP TABLE IF EXISTS chat_collections;
+DROP TABLE IF EXISTS document_chunks;
+DROP TABLE IF EXISTS documents;
+DROP TABLE IF EXISTS collections;
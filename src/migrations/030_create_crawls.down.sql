@@ -0,0 +1,3 @@
+-- Drop crawls table
+DROP INDEX IF EXISTS idx_documents_collection_id_source;
+DROP TABLE IF EXISTS crawls;
@@ -0,0 +1,23 @@
+-- Create crawls table, the website crawls ingesting pages into collections
+CREATE TABLE IF NOT EXISTS crawls (
+    id BIGSERIAL PRIMARY KEY,
+    collection_id BIGINT NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    mode VARCHAR(16) NOT NULL,
+    max_depth INTEGER NOT NULL,
+    max_pages INTEGER NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    pages INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_crawls_collection_id ON crawls(collection_id);
+CREATE INDEX IF NOT EXISTS idx_crawls_status ON crawls(status);
+
+-- Documents are replaced by source when a site is crawled again
+CREATE INDEX IF NOT EXISTS idx_documents_collection_id_source ON documents(collection_id, source);
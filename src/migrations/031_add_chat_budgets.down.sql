@@ -0,0 +1,6 @@
+-- Remove the budgets of chats
+ALTER TABLE chats DROP COLUMN IF EXISTS used_cost;
+ALTER TABLE chats DROP COLUMN IF EXISTS used_tokens;
+ALTER TABLE chats DROP COLUMN IF EXISTS budget_mode;
+ALTER TABLE chats DROP COLUMN IF EXISTS max_cost;
+ALTER TABLE chats DROP COLUMN IF EXISTS max_tokens;
@@ -0,0 +1,6 @@
+-- Add the token and cost budgets of chats, with their cumulative usage
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS max_tokens BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS max_cost DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS budget_mode VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS used_tokens BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS used_cost DOUBLE PRECISION NOT NULL DEFAULT 0;
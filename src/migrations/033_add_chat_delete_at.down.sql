@@ -0,0 +1,3 @@
+-- Remove the pending deletion of chats
+DROP INDEX IF EXISTS idx_chats_delete_at;
+ALTER TABLE chats DROP COLUMN IF EXISTS delete_at;
@@ -0,0 +1,4 @@
+-- Add the pending deletion of chats, which are deleted once their undo window ends
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS delete_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_chats_delete_at ON chats(delete_at);
@@ -0,0 +1,13 @@
+-- Keep the email opt-outs only
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS disabled_triggers TEXT NOT NULL DEFAULT '';
+
+UPDATE notification_preferences
+SET disabled_triggers = COALESCE((
+    SELECT string_agg(split_part(entry, ':', 1), ',' ORDER BY entry)
+    FROM unnest(string_to_array(disabled_notifications, ',')) AS entry
+    WHERE entry LIKE '%:email'
+), '');
+
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS disabled_notifications;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS webhook_secret;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS webhook_url;
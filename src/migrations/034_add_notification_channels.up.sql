@@ -0,0 +1,11 @@
+-- Add webhook notifications and opt-outs per channel; triggers opted out of were emailed
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS webhook_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS webhook_secret VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS disabled_notifications TEXT NOT NULL DEFAULT ''; -- comma-separated trigger:channel pairs
+
+UPDATE notification_preferences
+SET disabled_notifications = regexp_replace(disabled_triggers, '([^,]+)', '\1:email', 'g')
+WHERE disabled_triggers <> '';
+
+-- migrate:destructive disabled_triggers was copied into disabled_notifications above
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS disabled_triggers;
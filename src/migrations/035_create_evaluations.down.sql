@@ -0,0 +1,2 @@
+-- Drop evaluations table
+DROP TABLE IF EXISTS evaluations;
@@ -0,0 +1,15 @@
+-- Create evaluations table, the scores an LLM judge gave to sampled conversations
+CREATE TABLE IF NOT EXISTS evaluations (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    message_id BIGINT NOT NULL,
+    replies INTEGER NOT NULL,
+    refusals INTEGER NOT NULL,
+    helpfulness SMALLINT NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_evaluations_chat_id ON evaluations(chat_id);
+CREATE INDEX IF NOT EXISTS idx_evaluations_created_at ON evaluations(created_at);
@@ -0,0 +1,2 @@
+-- Drop teams_conversations table
+DROP TABLE IF EXISTS teams_conversations;
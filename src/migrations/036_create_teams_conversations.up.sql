@@ -0,0 +1,12 @@
+-- Create teams_conversations table, mapping the Microsoft Teams conversations of users to chats
+CREATE TABLE IF NOT EXISTS teams_conversations (
+    conversation_id VARCHAR(512) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+    service_url TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_teams_conversations_chat_id ON teams_conversations(chat_id);
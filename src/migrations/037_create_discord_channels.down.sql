@@ -0,0 +1,2 @@
+-- Drop discord_channels table
+DROP TABLE IF EXISTS discord_channels;
@@ -0,0 +1,11 @@
+-- Create discord_channels table, mapping the Discord channels and threads of users to chats
+CREATE TABLE IF NOT EXISTS discord_channels (
+    channel_id VARCHAR(32) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    guild_id VARCHAR(32) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (channel_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_discord_channels_chat_id ON discord_channels(chat_id);
@@ -0,0 +1,2 @@
+-- Drop widgets table
+DROP TABLE IF EXISTS widgets;
@@ -0,0 +1,16 @@
+-- Create widgets table for embeddable chat widgets
+CREATE TABLE IF NOT EXISTS widgets (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    allowed_origins TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    greeting TEXT NOT NULL DEFAULT '',
+    primary_color VARCHAR(16) NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    assistant_name VARCHAR(255) NOT NULL DEFAULT '',
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    system_prompt TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
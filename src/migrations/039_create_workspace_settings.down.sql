@@ -0,0 +1,2 @@
+-- Drop workspace_settings table
+DROP TABLE IF EXISTS workspace_settings;
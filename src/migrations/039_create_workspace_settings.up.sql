@@ -0,0 +1,10 @@
+-- Create workspace_settings table, holding the single row of workspace defaults
+CREATE TABLE IF NOT EXISTS workspace_settings (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    default_model VARCHAR(255) NOT NULL DEFAULT '',
+    system_prompt TEXT NOT NULL DEFAULT '',
+    moderation_level VARCHAR(16) NOT NULL DEFAULT '',
+    retention_days INTEGER,
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_audit_logs_action;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS message_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS chat_id;
+ALTER TABLE workspace_settings DROP COLUMN IF EXISTS guardrails;
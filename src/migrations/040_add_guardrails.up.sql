@@ -0,0 +1,8 @@
+-- Add the guardrail rules of the workspace, NULL checking every rule
+ALTER TABLE workspace_settings ADD COLUMN IF NOT EXISTS guardrails TEXT;
+
+-- Add the reply of guardrail violations to the audit log
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS chat_id BIGINT;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS message_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_messages_model_chat_id;
+DROP INDEX IF EXISTS idx_chats_user_created_at;
+DROP INDEX IF EXISTS idx_chats_tags;
+ALTER TABLE chats DROP COLUMN IF EXISTS archived;
+ALTER TABLE chats DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,9 @@
+-- Add the tags and archive flag of chats, which chat searches filter and count by
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS tags TEXT NOT NULL DEFAULT '';
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_chats_tags ON chats USING GIN (string_to_array(tags, ','));
+CREATE INDEX IF NOT EXISTS idx_chats_user_created_at ON chats(user_id, created_at);
+
+-- Find the chats where a model replied
+CREATE INDEX IF NOT EXISTS idx_messages_model_chat_id ON messages(model, chat_id) WHERE model <> '';
@@ -0,0 +1,18 @@
+-- Create saved_searches table, the smart folders of users, searched again each time they are opened
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    period VARCHAR(32) NOT NULL DEFAULT '',
+    from_time TIMESTAMP WITH TIME ZONE,
+    to_time TIMESTAMP WITH TIME ZONE,
+    tag VARCHAR(64) NOT NULL DEFAULT '',
+    archived BOOLEAN,
+    has_attachments BOOLEAN,
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_user_id ON saved_searches(user_id);
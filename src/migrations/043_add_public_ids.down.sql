@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_messages_public_id;
+DROP INDEX IF EXISTS idx_chats_public_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS public_id;
+ALTER TABLE chats DROP COLUMN IF EXISTS public_id;
@@ -0,0 +1,32 @@
+-- Add the public IDs of chats and messages, ULIDs exposed to clients instead of the
+-- sequential IDs, which reveal volumes and collide when regions are merged
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS public_id VARCHAR(26) NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS public_id VARCHAR(26) NOT NULL DEFAULT '';
+
+-- ulid_from returns a ULID of a time, used once to backfill the existing rows
+CREATE OR REPLACE FUNCTION ulid_from(t TIMESTAMP WITH TIME ZONE) RETURNS TEXT AS $$
+DECLARE
+    alphabet CONSTANT TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+    ms BIGINT := floor(EXTRACT(EPOCH FROM t) * 1000)::BIGINT;
+    id TEXT := '';
+BEGIN
+    -- 10 characters of milliseconds since the Unix epoch, most significant first
+    FOR i IN 1..10 LOOP
+        id := substr(alphabet, (ms % 32)::INT + 1, 1) || id;
+        ms := ms / 32;
+    END LOOP;
+    -- 16 random characters
+    FOR i IN 1..16 LOOP
+        id := id || substr(alphabet, floor(random() * 32)::INT + 1, 1);
+    END LOOP;
+    RETURN id;
+END;
+$$ LANGUAGE plpgsql VOLATILE;
+
+UPDATE chats SET public_id = ulid_from(created_at) WHERE public_id = '';
+UPDATE messages SET public_id = ulid_from(created_at) WHERE public_id = '';
+
+DROP FUNCTION ulid_from(TIMESTAMP WITH TIME ZONE);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chats_public_id ON chats(public_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_public_id ON messages(public_id);
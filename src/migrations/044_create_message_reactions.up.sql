@@ -0,0 +1,12 @@
+-- Create message_reactions table, the emoji reactions of users to messages
+CREATE TABLE IF NOT EXISTS message_reactions (
+    id BIGSERIAL PRIMARY KEY,
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- A user reacts to a message with each emoji at most once; the index also serves the
+-- aggregates per message
+CREATE UNIQUE INDEX IF NOT EXISTS idx_message_reactions_unique ON message_reactions(message_id, user_id, emoji);
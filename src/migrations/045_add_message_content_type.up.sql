@@ -0,0 +1,3 @@
+-- Add the content type of messages, telling clients and consumers how to render the content;
+-- existing messages are Markdown
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS content_type VARCHAR(32) NOT NULL DEFAULT 'text/markdown';
@@ -0,0 +1,2 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS suggestions;
+ALTER TABLE chats DROP COLUMN IF EXISTS suggestions;
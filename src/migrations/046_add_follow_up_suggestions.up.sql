@@ -0,0 +1,3 @@
+-- Let chats turn on follow-up suggestions, and keep the questions suggested after replies
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS suggestions BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS suggestions TEXT NOT NULL DEFAULT ''; -- one question per line
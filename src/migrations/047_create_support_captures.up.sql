@@ -0,0 +1,21 @@
+-- Create support_captures table, the LLM calls of requests captured for support until they expire
+CREATE TABLE IF NOT EXISTS support_captures (
+    id BIGSERIAL PRIMARY KEY,
+    request_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    route VARCHAR(255) NOT NULL DEFAULT '',
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    request TEXT NOT NULL,
+    response TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    streaming BOOLEAN NOT NULL DEFAULT FALSE,
+    chunks INTEGER NOT NULL DEFAULT 0,
+    first_chunk_ms BIGINT NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_support_captures_request_id ON support_captures(request_id);
+CREATE INDEX IF NOT EXISTS idx_support_captures_expires_at ON support_captures(expires_at);
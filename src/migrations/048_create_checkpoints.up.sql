@@ -0,0 +1,11 @@
+-- Create checkpoints table for the named points in the history of chats
+CREATE TABLE IF NOT EXISTS checkpoints (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    seq_no BIGINT NOT NULL DEFAULT 0,
+    message_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_checkpoints_chat_id ON checkpoints(chat_id);
@@ -0,0 +1,4 @@
+-- Drop columns
+ALTER TABLE messages DROP COLUMN IF EXISTS exclude_from_prompt;
+ALTER TABLE messages DROP COLUMN IF EXISTS tool_call_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS name;
@@ -0,0 +1,7 @@
+-- Add the fields of tool and function results to messages, and the flag keeping messages
+-- out of prompts; system messages were announcements until now, never sent to the LLM
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS name VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS tool_call_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS exclude_from_prompt BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE messages SET exclude_from_prompt = TRUE WHERE role = 'system';
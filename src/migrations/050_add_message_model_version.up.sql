@@ -0,0 +1,3 @@
+-- Record the LLM provider of assistant messages and the version of the model it reported
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS provider VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS model_version VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE chats DROP COLUMN IF EXISTS pinned_message_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS kind;
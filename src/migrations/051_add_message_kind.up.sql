@@ -0,0 +1,3 @@
+-- Set apart conversation summaries from the other messages, and let chats pin a message
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS kind VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS pinned_message_id BIGINT REFERENCES messages(id) ON DELETE SET NULL;
@@ -0,0 +1,17 @@
+-- Create tasks table for the action items extracted from chats
+CREATE TABLE IF NOT EXISTS tasks (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    assignee VARCHAR(255) NOT NULL DEFAULT '',
+    due_date DATE,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    source_message_id BIGINT NOT NULL DEFAULT 0,
+    tracker VARCHAR(100) NOT NULL DEFAULT '',
+    exported_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_chat_id ON tasks(chat_id);
@@ -0,0 +1,5 @@
+-- Remove the activity digests of users
+DROP TABLE IF EXISTS notification_digest_items;
+
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS digest_sent_at;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS digest_frequency;
@@ -0,0 +1,13 @@
+-- Add the activity digests of users, and the notifications queued for them
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS digest_frequency VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS digest_sent_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS notification_digest_items (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    trigger VARCHAR(50) NOT NULL,
+    summary TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_digest_items_user_id ON notification_digest_items(user_id, id);
@@ -0,0 +1,4 @@
+-- Remove the custom instructions of users and the system prompts of chats
+ALTER TABLE chats DROP COLUMN IF EXISTS system_prompt;
+
+DROP TABLE IF EXISTS user_preferences;
@@ -0,0 +1,9 @@
+-- Add the custom instructions of users and the system prompts of chats
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id VARCHAR(255) PRIMARY KEY,
+    instructions TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS system_prompt TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,20 @@
+-- Create usage_reconciliations table comparing the usage reported by the LLM vendor with
+-- the usage recorded, one row per UTC day and model, replaced on every import
+CREATE TABLE IF NOT EXISTS usage_reconciliations (
+    id BIGSERIAL PRIMARY KEY,
+    day DATE NOT NULL,
+    model VARCHAR(255) NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    vendor_requests BIGINT NOT NULL,
+    vendor_prompt_tokens BIGINT NOT NULL,
+    vendor_completion_tokens BIGINT NOT NULL,
+    vendor_cost DOUBLE PRECISION,           -- USD, NULL when the source reports no cost
+    local_requests BIGINT NOT NULL,
+    local_prompt_tokens BIGINT NOT NULL,
+    local_completion_tokens BIGINT NOT NULL,
+    local_cost DOUBLE PRECISION NOT NULL,   -- USD
+    status VARCHAR(20) NOT NULL,
+    imported_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usage_reconciliations_day_model ON usage_reconciliations(day, model);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_messages_updated_at_id;
+DROP INDEX IF EXISTS idx_chats_updated_at_id;
+
+ALTER TABLE workspace_settings DROP COLUMN IF EXISTS warehouse_opt_out;
+
+DROP TABLE IF EXISTS warehouse_cursors;
@@ -0,0 +1,17 @@
+-- Create warehouse_cursors table, recording how far each table was exported to the data
+-- warehouse
+CREATE TABLE IF NOT EXISTS warehouse_cursors (
+    table_name VARCHAR(64) PRIMARY KEY,
+    schema_version INTEGER NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    row_id BIGINT NOT NULL,
+    exported_rows BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Add the opt-out of the workspace from the warehouse export
+ALTER TABLE workspace_settings ADD COLUMN IF NOT EXISTS warehouse_opt_out BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Index the rows exported incrementally in order of change
+CREATE INDEX IF NOT EXISTS idx_chats_updated_at_id ON chats(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_messages_updated_at_id ON messages(updated_at, id);
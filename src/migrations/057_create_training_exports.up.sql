@@ -0,0 +1,20 @@
+-- Create training_exports table for the fine-tuning datasets exported by admins
+CREATE TABLE IF NOT EXISTS training_exports (
+    id BIGSERIAL PRIMARY KEY,
+    requested_by VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    from_time TIMESTAMP WITH TIME ZONE,
+    to_time TIMESTAMP WITH TIME ZONE,
+    tags TEXT NOT NULL DEFAULT '',
+    min_score INTEGER NOT NULL,
+    system_prompt TEXT NOT NULL DEFAULT '',
+    storage_key VARCHAR(255) NOT NULL DEFAULT '',
+    size BIGINT NOT NULL DEFAULT 0,
+    chats INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_training_exports_status ON training_exports(status);
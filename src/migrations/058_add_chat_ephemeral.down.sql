@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_chats_ephemeral;
+ALTER TABLE chats DROP COLUMN IF EXISTS ephemeral;
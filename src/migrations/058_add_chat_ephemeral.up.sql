@@ -0,0 +1,5 @@
+-- Privacy mode chats keep their messages out of the database
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS ephemeral BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Privacy mode chats are paged by ID to delete those whose messages expired
+CREATE INDEX IF NOT EXISTS idx_chats_ephemeral ON chats(id) WHERE ephemeral;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS chat_webhook_deliveries;
+DROP TABLE IF EXISTS chat_webhooks;
@@ -0,0 +1,27 @@
+-- Create chat_webhooks table for the URLs the completed exchanges of chats are posted to
+CREATE TABLE IF NOT EXISTS chat_webhooks (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    last_delivered_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_webhooks_chat_id ON chat_webhooks(chat_id);
+
+-- Create chat_webhook_deliveries table for the exchanges waiting to be posted
+CREATE TABLE IF NOT EXISTS chat_webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id BIGINT NOT NULL REFERENCES chat_webhooks(id) ON DELETE CASCADE,
+    message_id BIGINT NOT NULL,
+    body TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_webhook_deliveries_webhook_id ON chat_webhook_deliveries(webhook_id);
+CREATE INDEX IF NOT EXISTS idx_chat_webhook_deliveries_next_attempt_at ON chat_webhook_deliveries(next_attempt_at);
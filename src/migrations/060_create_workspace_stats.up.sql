@@ -0,0 +1,14 @@
+-- Create workspace_stats table for the hourly requests and usage of each workspace
+CREATE TABLE IF NOT EXISTS workspace_stats (
+    workspace_id VARCHAR(64) NOT NULL,
+    hour TIMESTAMP WITH TIME ZONE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    client_errors BIGINT NOT NULL DEFAULT 0,
+    server_errors BIGINT NOT NULL DEFAULT 0,
+    tokens BIGINT NOT NULL DEFAULT 0,
+    cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (workspace_id, hour)
+);
+
+-- Hours past the retention are deleted
+CREATE INDEX IF NOT EXISTS idx_workspace_stats_hour ON workspace_stats(hour);
@@ -0,0 +1,11 @@
+-- Create workspace_stat_users table for the distinct users of each workspace and hour,
+-- identified by a hash of their ID and the hour
+CREATE TABLE IF NOT EXISTS workspace_stat_users (
+    workspace_id VARCHAR(64) NOT NULL,
+    hour TIMESTAMP WITH TIME ZONE NOT NULL,
+    user_hash VARCHAR(64) NOT NULL,
+    PRIMARY KEY (workspace_id, hour, user_hash)
+);
+
+-- Hours past the retention are deleted
+CREATE INDEX IF NOT EXISTS idx_workspace_stat_users_hour ON workspace_stat_users(hour);
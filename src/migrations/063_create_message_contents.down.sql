@@ -0,0 +1,8 @@
+-- Put the shared content back in the messages referencing it
+UPDATE messages
+SET content = message_contents.content, content_hash = ''
+FROM message_contents
+WHERE messages.content_hash = message_contents.hash;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS content_hash;
+DROP TABLE IF EXISTS message_contents;
@@ -0,0 +1,32 @@
+-- Create message_contents table for the content repeated by messages, such as system
+-- prompts, templates and pasted documents, stored once under the hex SHA-256 of the content
+CREATE TABLE IF NOT EXISTS message_contents (
+    hash VARCHAR(64) PRIMARY KEY,
+    content TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Content no message references anymore is deleted some time after it was last used
+CREATE INDEX IF NOT EXISTS idx_message_contents_used_at ON message_contents(used_at);
+
+-- Messages sharing their content reference it by hash, keeping a preview in content
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_messages_content_hash ON messages(content_hash);
+
+-- Compact the existing messages: the content of over 4096 bytes, the default share
+-- threshold, repeated by several messages is stored once, and the messages keep its first
+-- 1000 characters. Content found once is left in its message, as sharing it saves nothing.
+INSERT INTO message_contents (hash, content, size, used_at)
+SELECT encode(sha256(convert_to(content, 'UTF8')), 'hex'), content, octet_length(content), NOW()
+FROM messages
+WHERE content_key = '' AND content_hash = '' AND octet_length(content) > 4096
+GROUP BY content
+HAVING COUNT(*) > 1
+ON CONFLICT (hash) DO NOTHING;
+
+UPDATE messages
+SET content_hash = message_contents.hash, content = LEFT(messages.content, 1000)
+FROM message_contents
+WHERE messages.content_key = '' AND messages.content_hash = '' AND octet_length(messages.content) > 4096
+    AND message_contents.hash = encode(sha256(convert_to(messages.content, 'UTF8')), 'hex');
@@ -0,0 +1,6 @@
+-- Drop columns
+ALTER TABLE workspace_stat_users DROP COLUMN IF EXISTS cost;
+ALTER TABLE workspace_stat_users DROP COLUMN IF EXISTS tokens;
+ALTER TABLE workspace_stat_users DROP COLUMN IF EXISTS server_errors;
+ALTER TABLE workspace_stat_users DROP COLUMN IF EXISTS client_errors;
+ALTER TABLE workspace_stat_users DROP COLUMN IF EXISTS requests;
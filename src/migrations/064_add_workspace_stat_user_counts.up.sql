@@ -0,0 +1,8 @@
+-- Count the requests and usage of each user of a workspace in an hour, so that the
+-- contribution of each user is clipped when statistics are released. Users recorded before
+-- contributed nothing.
+ALTER TABLE workspace_stat_users ADD COLUMN IF NOT EXISTS requests BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE workspace_stat_users ADD COLUMN IF NOT EXISTS client_errors BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE workspace_stat_users ADD COLUMN IF NOT EXISTS server_errors BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE workspace_stat_users ADD COLUMN IF NOT EXISTS tokens BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE workspace_stat_users ADD COLUMN IF NOT EXISTS cost DOUBLE PRECISION NOT NULL DEFAULT 0;
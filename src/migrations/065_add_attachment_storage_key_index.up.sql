@@ -0,0 +1,2 @@
+-- Uploads find their attachment by the storage key of the signed upload URL
+CREATE INDEX IF NOT EXISTS idx_attachments_storage_key ON attachments(storage_key);
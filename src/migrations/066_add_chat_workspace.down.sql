@@ -0,0 +1,2 @@
+-- Drop columns
+ALTER TABLE chats DROP COLUMN IF EXISTS workspace_id;
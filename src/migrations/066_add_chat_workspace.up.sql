@@ -0,0 +1,3 @@
+-- Record the workspace a chat was created in, cached with its owner. Chats created before
+-- belong to no workspace.
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS workspace_id VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,155 @@
+// Package migrations holds the SQL migrations of the database schema, embedded in the
+// binaries, and the runner applying and rolling them back.
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// files are the migrations, named <version>_<name>.up.sql and <version>_<name>.down.sql
+//
+//go:embed *.sql
+var files embed.FS
+
+// AcknowledgeDestructive is the comment marking the destructive statements of a migration as
+// reviewed, so that it is applied without -allow-destructive
+const AcknowledgeDestructive = "-- migrate:destructive"
+
+// fileName matches the names of migration files
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// destructivePatterns match the statements losing data, or breaking the binaries still
+// running against the previous schema during a rollout
+var destructivePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bDROP\s+(TABLE|COLUMN|SCHEMA|VIEW|MATERIALIZED\s+VIEW|TYPE)\b`),
+	regexp.MustCompile(`(?i)\bTRUNCATE\b`),
+	regexp.MustCompile(`(?i)\bDELETE\s+FROM\b`),
+	regexp.MustCompile(`(?i)\bRENAME\b`),
+	regexp.MustCompile(`(?i)\bALTER\s+COLUMN\s+\S+\s+(SET\s+DATA\s+)?TYPE\b`),
+}
+
+// Migration is a version of the database schema, with the SQL upgrading to it from the
+// previous version and downgrading back
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of Up, identifying what was applied
+}
+
+// Load returns the embedded migrations ordered by version
+func Load() ([]Migration, error) {
+	return parse(files)
+}
+
+// parse reads the migration files of fsys. Every version needs both an up and a down file.
+func parse(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(data)
+			sum := sha256.Sum256(data)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the version of the last migration, 0 without any
+func Latest(migrations []Migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Destructive returns the destructive statements of sql, with comments left out
+func Destructive(sql string) []string {
+	var code strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		if i := strings.Index(line, "--"); i >= 0 {
+			line = line[:i]
+		}
+		code.WriteString(line)
+		code.WriteByte('\n')
+	}
+
+	var statements []string
+	for _, statement := range strings.Split(code.String(), ";") {
+		statement = strings.Join(strings.Fields(statement), " ")
+		for _, pattern := range destructivePatterns {
+			if pattern.MatchString(statement) {
+				statements = append(statements, statement)
+				break
+			}
+		}
+	}
+	return statements
+}
+
+// Acknowledged reports whether the destructive statements of sql were marked as reviewed
+func Acknowledged(sql string) bool {
+	for _, line := range strings.Split(sql, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), AcknowledgeDestructive) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error listing the destructive statements of the up migration when they
+// were not acknowledged
+func (m Migration) Check() error {
+	if Acknowledged(m.Up) {
+		return nil
+	}
+	statements := Destructive(m.Up)
+	if len(statements) == 0 {
+		return nil
+	}
+	return fmt.Errorf("migration %d_%s has destructive statements; review them and add a %q comment: %s",
+		m.Version, m.Name, AcknowledgeDestructive, strings.Join(statements, "; "))
+}
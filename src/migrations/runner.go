@@ -0,0 +1,398 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+	"gorm.io/gorm"
+)
+
+// lockName serializes the runners of all instances, per region
+const lockName = "schema-migrations"
+
+// legacyTable is the table where golang-migrate, which migrated the database before the
+// runner, records the version of the schema
+const legacyTable = "schema_migrations"
+
+// schemaTable is a table of the schema telling a database migrated before the runner from
+// an empty one
+const schemaTable = "chats"
+
+// AppliedMigration records a migration applied to the database, with its down migration so
+// that binaries predating it can still roll it back. Migrations adopted by a baseline have
+// no down migration recorded.
+type AppliedMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	Checksum  string    `gorm:"type:varchar(64);not null"`
+	Down      string    `gorm:"type:text;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName returns the table name for the AppliedMigration model
+func (AppliedMigration) TableName() string {
+	return "chat_schema_migrations"
+}
+
+// Status is the state of a migration in a database
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // nil when pending
+	Drifted   bool       // applied with another checksum than the embedded migration
+	Unknown   bool       // applied by a newer binary, not embedded in this one
+}
+
+// Runner applies and rolls back migrations in every region of a database
+type Runner struct {
+	db         adapters.DBAdapter
+	lock       adapters.LockAdapter
+	migrations []Migration
+}
+
+// NewRunner creates a new runner of migrations
+func NewRunner(db adapters.DBAdapter, lock adapters.LockAdapter, migrations []Migration) *Runner {
+	return &Runner{db: db, lock: lock, migrations: migrations}
+}
+
+// Up applies the pending migrations in every region, each in a transaction, and returns
+// those applied per region. Nothing is applied when a region has drifted or is newer than
+// the binary, when it has a schema but no migrations recorded, as when it was migrated
+// before the runner, or when a pending migration has unacknowledged destructive statements,
+// unless allowDestructive is set.
+func (r *Runner) Up(ctx context.Context, allowDestructive bool) (map[string][]Migration, error) {
+	applied := map[string][]Migration{}
+	err := r.eachRegion(ctx, func(ctx context.Context, region string) error {
+		log := logger.Context(ctx)
+
+		records, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+		if err := r.verify(records); err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			if err := r.checkUnmigrated(ctx); err != nil {
+				return err
+			}
+		}
+
+		var pending []Migration
+		for _, migration := range r.migrations {
+			if _, ok := records[migration.Version]; ok {
+				continue
+			}
+			if !allowDestructive {
+				if err := migration.Check(); err != nil {
+					return err
+				}
+			}
+			pending = append(pending, migration)
+		}
+
+		for _, migration := range pending {
+			start := time.Now()
+			err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&AppliedMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					Checksum:  migration.Checksum,
+					Down:      migration.Down,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Infow("Applied migration", "version", migration.Version, "name", migration.Name, "duration", time.Since(start))
+			applied[region] = append(applied[region], migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Baseline records the migrations up to a version as applied in every region without running
+// them, adopting a schema migrated before the runner, such as by golang-migrate. Regions where
+// migrations were already recorded are left as they are, and nothing is recorded when the
+// version golang-migrate recorded is dirty or another one.
+func (r *Runner) Baseline(ctx context.Context, version int) (map[string][]Migration, error) {
+	if r.find(version).Version == 0 {
+		return nil, fmt.Errorf("there is no migration %d to baseline at", version)
+	}
+
+	adopted := map[string][]Migration{}
+	err := r.eachRegion(ctx, func(ctx context.Context, region string) error {
+		log := logger.Context(ctx)
+
+		records, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			log.Infow("Migrations already recorded, skipping the baseline", "count", len(records))
+			return nil
+		}
+
+		legacy, dirty, ok, err := r.legacyVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if ok && dirty {
+			return fmt.Errorf("golang-migrate left migration %d dirty; fix the schema and its %s row first", legacy, legacyTable)
+		}
+		if ok && legacy != version {
+			return fmt.Errorf("golang-migrate recorded version %d, not %d", legacy, version)
+		}
+
+		var list []Migration
+		for _, migration := range r.migrations {
+			if migration.Version > version {
+				break
+			}
+			list = append(list, migration)
+		}
+		now := time.Now()
+		err = r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, migration := range list {
+				err := tx.Create(&AppliedMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					Checksum:  migration.Checksum,
+					AppliedAt: now,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record the baseline: %w", err)
+		}
+		log.Infow("Adopted the schema", "version", version)
+		adopted[region] = list
+		return nil
+	})
+	return adopted, err
+}
+
+// Down rolls back the last n applied migrations in every region, latest first, each in a
+// transaction, and returns those rolled back per region. Migrations applied by newer
+// binaries are rolled back with the down migration recorded when they were applied.
+func (r *Runner) Down(ctx context.Context, n int) (map[string][]AppliedMigration, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("the number of migrations to roll back must be positive, got %d", n)
+	}
+
+	rolledBack := map[string][]AppliedMigration{}
+	err := r.eachRegion(ctx, func(ctx context.Context, region string) error {
+		log := logger.Context(ctx)
+
+		records, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+		if n > len(records) {
+			return fmt.Errorf("cannot roll back %d migrations, only %d are applied", n, len(records))
+		}
+		versions := make([]int, 0, len(records))
+		for version := range records {
+			versions = append(versions, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+		for _, version := range versions[:n] {
+			record := records[version]
+			down := record.Down
+			if down == "" {
+				down = r.find(version).Down
+			}
+			if down == "" {
+				return fmt.Errorf("migration %d_%s has no down migration", record.Version, record.Name)
+			}
+			for _, statement := range Destructive(down) {
+				log.Warnw("Rolling back with a destructive statement", "version", record.Version, "statement", statement)
+			}
+
+			start := time.Now()
+			err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&AppliedMigration{}, record.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("failed to roll back migration %d_%s: %w", record.Version, record.Name, err)
+			}
+			log.Infow("Rolled back migration", "version", record.Version, "name", record.Name, "duration", time.Since(start))
+			rolledBack[region] = append(rolledBack[region], record)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status returns the state of the migrations in every region, ordered by version
+func (r *Runner) Status(ctx context.Context) (map[string][]Status, error) {
+	statuses := map[string][]Status{}
+	err := r.eachRegion(ctx, func(ctx context.Context, region string) error {
+		records, err := r.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		var list []Status
+		for _, migration := range r.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if record, ok := records[migration.Version]; ok {
+				status.AppliedAt = &record.AppliedAt
+				status.Drifted = record.Checksum != migration.Checksum
+			}
+			list = append(list, status)
+		}
+		for _, record := range records {
+			if r.find(record.Version).Version == 0 {
+				appliedAt := record.AppliedAt
+				list = append(list, Status{Version: record.Version, Name: record.Name, AppliedAt: &appliedAt, Unknown: true})
+			}
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+		statuses[region] = list
+		return nil
+	})
+	return statuses, err
+}
+
+// CheckVersion returns an error when a region of the database was migrated past the
+// migrations embedded in the binary, which may not run correctly against it. Databases
+// never migrated by the runner pass.
+func CheckVersion(ctx context.Context, db adapters.DBAdapter) error {
+	migrations, err := Load()
+	if err != nil {
+		return err
+	}
+	latest := Latest(migrations)
+
+	for _, region := range db.Regions() {
+		ctx := adapters.WithRegion(ctx, region)
+		gormDB := db.GetDB().WithContext(ctx)
+		if !gormDB.Migrator().HasTable(&AppliedMigration{}) {
+			continue
+		}
+		var version sql.NullInt64
+		if err := gormDB.Model(&AppliedMigration{}).Select("MAX(version)").Row().Scan(&version); err != nil {
+			return fmt.Errorf("failed to read the schema version: %w", err)
+		}
+		if version.Valid && int(version.Int64) > latest {
+			return fmt.Errorf("the schema of region %q is at version %d, newer than the version %d of this binary; roll it back with migrate down first", region, version.Int64, latest)
+		}
+	}
+	return nil
+}
+
+// eachRegion runs fn with the context of every region, holding the migration lock of the region
+func (r *Runner) eachRegion(ctx context.Context, fn func(ctx context.Context, region string) error) error {
+	for _, region := range r.db.Regions() {
+		ctx := adapters.WithRegion(ctx, region)
+		unlock, err := r.lock.Lock(ctx, lockName)
+		if err != nil {
+			return err
+		}
+		err = func() error {
+			defer unlock()
+			if err := r.db.GetDB().WithContext(ctx).AutoMigrate(&AppliedMigration{}); err != nil {
+				return fmt.Errorf("failed to create the migrations table: %w", err)
+			}
+			return fn(ctx, region)
+		}()
+		if err != nil {
+			if region != "" {
+				return fmt.Errorf("region %s: %w", region, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// checkUnmigrated returns an error when the region of ctx has a schema although no migration
+// was recorded, so that migrations are not replayed over it
+func (r *Runner) checkUnmigrated(ctx context.Context) error {
+	if !r.db.GetDB().WithContext(ctx).Migrator().HasTable(schemaTable) {
+		return nil
+	}
+	legacy, _, ok, err := r.legacyVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return fmt.Errorf("the database was migrated by golang-migrate up to version %d; adopt it with migrate baseline %d first", legacy, legacy)
+	}
+	return fmt.Errorf("the database has a schema but no migrations recorded; adopt it with migrate baseline <version> first")
+}
+
+// legacyVersion returns the version golang-migrate recorded in the region of ctx, whether it
+// is dirty, and whether there is one
+func (r *Runner) legacyVersion(ctx context.Context) (int, bool, bool, error) {
+	db := r.db.GetDB().WithContext(ctx)
+	if !db.Migrator().HasTable(legacyTable) {
+		return 0, false, false, nil
+	}
+	var row struct {
+		Version int
+		Dirty   bool
+	}
+	result := db.Table(legacyTable).Select("version, dirty").Limit(1).Scan(&row)
+	if result.Error != nil {
+		return 0, false, false, fmt.Errorf("failed to read the golang-migrate version: %w", result.Error)
+	}
+	return row.Version, row.Dirty, result.RowsAffected > 0, nil
+}
+
+// applied returns the applied migrations of the region of ctx by version
+func (r *Runner) applied(ctx context.Context) (map[int]AppliedMigration, error) {
+	var records []AppliedMigration
+	if err := r.db.GetDB().WithContext(ctx).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read the applied migrations: %w", err)
+	}
+	byVersion := make(map[int]AppliedMigration, len(records))
+	for _, record := range records {
+		byVersion[record.Version] = record
+	}
+	return byVersion, nil
+}
+
+// verify returns an error when a migration was applied with another checksum than the
+// embedded one, or by a newer binary
+func (r *Runner) verify(records map[int]AppliedMigration) error {
+	latest := Latest(r.migrations)
+	for _, record := range records {
+		if record.Version > latest {
+			return fmt.Errorf("migration %d_%s was applied by a newer binary; this one only has migrations up to %d", record.Version, record.Name, latest)
+		}
+		if migration := r.find(record.Version); migration.Version != 0 && migration.Checksum != record.Checksum {
+			return fmt.Errorf("migration %d_%s changed since it was applied", record.Version, record.Name)
+		}
+	}
+	return nil
+}
+
+// find returns the embedded migration of a version, or the zero migration
+func (r *Runner) find(version int) Migration {
+	for _, migration := range r.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return Migration{}
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Artifact is a fenced code block extracted from an assistant message
+type Artifact struct {
+	ID        int64 `gorm:"primaryKey;column:id"`
+	MessageID int64 `gorm:"column:message_id;not null;index"`
+	// Index is the position of the code block in the message, starting at 0
+	Index int `gorm:"column:block_index;not null"`
+	// Language is the info string language of the code block; empty when not given
+	Language  string    `gorm:"column:language;not null;default:''"`
+	Content   string    `gorm:"column:content;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for Artifact
+func (Artifact) TableName() string {
+	return "artifacts"
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Attachment statuses
+const (
+	// AttachmentStatusPending is an attachment waiting for its file to be uploaded
+	AttachmentStatusPending = "pending"
+	// AttachmentStatusUploading is a pending attachment whose file is being written
+	AttachmentStatusUploading = "uploading"
+	// AttachmentStatusScanning is an uploaded attachment waiting for its malware scan
+	AttachmentStatusScanning    = "scanning"
+	AttachmentStatusReady       = "ready"
+	AttachmentStatusQuarantined = "quarantined"
+	AttachmentStatusScanFailed  = "scan_failed"
+)
+
+// Attachment event types
+const (
+	EventAttachmentScanned     = "attachment.scanned"
+	EventAttachmentQuarantined = "attachment.quarantined"
+)
+
+// Attachment is a file attached to a message, such as the audio of a voice message
+type Attachment struct {
+	ID          int64  `gorm:"primaryKey;column:id"`
+	MessageID   int64  `gorm:"column:message_id;not null;index"`
+	Filename    string `gorm:"column:filename;not null"`
+	ContentType string `gorm:"column:content_type;not null"`
+	Size        int64  `gorm:"column:size;not null"`
+	Status      string `gorm:"column:status;not null;default:ready;index"`
+	// Threat names the malware found in a quarantined attachment
+	Threat string `gorm:"column:threat;not null;default:''"`
+	// StorageKey locates the file in the storage
+	StorageKey string    `gorm:"column:storage_key;not null;index"`
+	CreatedAt  time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for Attachment
+func (Attachment) TableName() string {
+	return "attachments"
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit log actions
+const (
+	// AuditActionImpersonationStarted records an admin being issued an impersonation token
+	AuditActionImpersonationStarted = "impersonation.started"
+	// AuditActionImpersonatedRequest records a request made with an impersonation token
+	AuditActionImpersonatedRequest = "impersonation.request"
+	// AuditActionGuardrailViolation records an assistant reply violating a guardrail rule
+	AuditActionGuardrailViolation = "guardrail.violation"
+)
+
+// AuditLog records an action an admin took on behalf of a user, or a reply to a user
+// violating a guardrail rule, without an admin
+type AuditLog struct {
+	ID      int64  `gorm:"primaryKey;column:id"`
+	AdminID string `gorm:"column:admin_id;not null;index"`
+	UserID  string `gorm:"column:user_id;not null;index"`
+	Action  string `gorm:"column:action;not null"`
+	// Method, Path and Status describe the request of impersonated requests
+	Method    string `gorm:"column:method;not null;default:''"`
+	Path      string `gorm:"column:path;not null;default:''"`
+	Status    int    `gorm:"column:status;not null;default:0"`
+	Reason    string `gorm:"column:reason;not null;default:''"`
+	RequestID string `gorm:"column:request_id;not null;default:''"`
+	// ChatID and MessageID identify the reply of guardrail violations
+	ChatID    *int64    `gorm:"column:chat_id"`
+	MessageID *int64    `gorm:"column:message_id"`
+	CreatedAt time.Time `gorm:"column:created_at;not null;index"`
+}
+
+// TableName specifies the table name for AuditLog
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
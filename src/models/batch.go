@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+// Batch statuses
+const (
+	BatchStatusPending   = "pending"
+	BatchStatusRunning   = "running"
+	BatchStatusCompleted = "completed"
+)
+
+// Batch item statuses
+const (
+	BatchItemStatusPending   = "pending"
+	BatchItemStatusCompleted = "completed"
+	BatchItemStatusFailed    = "failed"
+)
+
+// Batch is a list of prompts of a user processed asynchronously
+type Batch struct {
+	ID     int64  `gorm:"primaryKey;column:id"`
+	UserID string `gorm:"column:user_id;not null;index"`
+	Status string `gorm:"column:status;not null"`
+	// Template is the prompt the variables of each item are filled into, when set
+	Template    string      `gorm:"column:template;not null;default:''"`
+	Total       int         `gorm:"column:total;not null"`
+	Completed   int         `gorm:"column:completed;not null;default:0"`
+	Failed      int         `gorm:"column:failed;not null;default:0"`
+	Items       []BatchItem `gorm:"foreignKey:BatchID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time   `gorm:"column:created_at;not null"`
+	UpdatedAt   time.Time   `gorm:"column:updated_at;not null"`
+	CompletedAt *time.Time  `gorm:"column:completed_at"`
+}
+
+// TableName specifies the table name for Batch
+func (Batch) TableName() string {
+	return "batches"
+}
+
+// BatchItem is a prompt of a batch, sent to a chat of the user or on its own
+type BatchItem struct {
+	ID      int64 `gorm:"primaryKey;column:id"`
+	BatchID int64 `gorm:"column:batch_id;not null;index"`
+	// Position is the index of the item in the batch request
+	Position int    `gorm:"column:position;not null"`
+	ChatID   *int64 `gorm:"column:chat_id"`
+	Prompt   string `gorm:"column:prompt;type:text;not null"`
+	Status   string `gorm:"column:status;not null;index"`
+	// Response and MessageID hold the reply; MessageID is only set for items sent to a chat
+	Response    string     `gorm:"column:response;type:text;not null;default:''"`
+	MessageID   *int64     `gorm:"column:message_id"`
+	Model       string     `gorm:"column:model;not null;default:''"`
+	TotalTokens int        `gorm:"column:total_tokens;not null;default:0"`
+	Error       string     `gorm:"column:error;not null;default:''"`
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+}
+
+// TableName specifies the table name for BatchItem
+func (BatchItem) TableName() string {
+	return "batch_items"
+}
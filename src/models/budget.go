@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+)
+
+// Event types for budget Kafka messages
+const (
+	EventBudgetWarning  = "budget.warning"
+	EventBudgetExceeded = "budget.exceeded"
+)
+
+// EventBillingExchangeCompleted is the event type of billing Kafka messages, published for
+// each usage record when billing is enabled
+const EventBillingExchangeCompleted = "billing.exchange_completed"
+
+// Budget caps the monthly LLM cost of a user, or of the whole service when UserID is empty
+type Budget struct {
+	ID     int64  `gorm:"primaryKey;column:id"`
+	UserID string `gorm:"column:user_id;not null;uniqueIndex"`
+	// MonthlyLimit is the most that may be spent in a calendar month, in USD
+	MonthlyLimit float64 `gorm:"column:monthly_limit;not null"`
+	// WarnThreshold is the share of the limit whose spending triggers a warning event
+	WarnThreshold float64 `gorm:"column:warn_threshold;not null"`
+	// WarnedPeriod and ExceededPeriod are the months, as 2006-01, whose events were published
+	WarnedPeriod   string    `gorm:"column:warned_period;not null;default:''"`
+	ExceededPeriod string    `gorm:"column:exceeded_period;not null;default:''"`
+	CreatedAt      time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for Budget
+func (Budget) TableName() string {
+	return "budgets"
+}
+
+// Usage records the LLM usage and cost of a generated reply, or of generated images
+type Usage struct {
+	ID     int64  `gorm:"primaryKey;column:id"`
+	UserID string `gorm:"column:user_id;not null"`
+	// ChatID and MessageID are 0 for batch prompts answered outside chats
+	ChatID           int64     `gorm:"column:chat_id;not null"`
+	MessageID        int64     `gorm:"column:message_id;not null"`
+	Model            string    `gorm:"column:model;not null"`
+	PromptTokens     int       `gorm:"column:prompt_tokens;not null"`
+	CompletionTokens int       `gorm:"column:completion_tokens;not null"`
+	Images           int       `gorm:"column:images;not null;default:0"`
+	Cost             float64   `gorm:"column:cost;not null"` // In USD
+	CreatedAt        time.Time `gorm:"column:created_at;not null;index"`
+}
+
+// UsageTotals aggregates usage records
+type UsageTotals struct {
+	Records          int64
+	PromptTokens     int64
+	CompletionTokens int64
+	Images           int64
+	Cost             float64
+}
+
+// DailyUsage aggregates the usage records of a model on a day, in UTC
+type DailyUsage struct {
+	Day              time.Time
+	Model            string
+	Records          int64
+	PromptTokens     int64
+	CompletionTokens int64
+	Cost             float64
+}
+
+// TableName specifies the table name for Usage
+func (Usage) TableName() string {
+	return "usage"
+}
@@ -1,17 +1,72 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
 // Chat represents a single chat session
 type Chat struct {
-	ID        int64     `gorm:"primaryKey;column:id"`
-	UserID    string    `gorm:"column:user_id;not null;index"`
-	Title     string    `gorm:"column:title;not null;check:title <> ''"`
-	Messages  []Message `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE"`
-	CreatedAt time.Time `gorm:"column:created_at;not null"`
-	UpdatedAt time.Time `gorm:"column:updated_at;not null"`
+	ID       int64  `gorm:"primaryKey;column:id"`
+	PublicID string `gorm:"column:public_id;not null;default:'';index"` // ULID identifying the chat to clients
+	UserID   string `gorm:"column:user_id;not null;index"`
+	// WorkspaceID is the workspace the chat was created in, empty when there was none
+	WorkspaceID string    `gorm:"column:workspace_id;size:64;not null;default:''"`
+	Title       string    `gorm:"column:title;not null;check:title <> ''"`
+	Messages    []Message `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE"`
+	// Summary condenses the messages up to SummaryMessageID for prompt building
+	Summary          string    `gorm:"column:summary"`
+	SummaryMessageID int64     `gorm:"column:summary_message_id;not null;default:0"`
+	LastSeqNo        int64     `gorm:"column:last_seq_no;not null;default:0"`     // Sequence number of the latest message
+	URLContext       bool      `gorm:"column:url_context;not null;default:false"` // Fetch linked pages into the LLM context
+	Language         string    `gorm:"column:language;not null;default:''"`       // Language replies are translated into; empty turns translation off
+	Suggestions      bool      `gorm:"column:suggestions;not null;default:false"` // Suggest follow-up questions after replies
+	Locked           bool      `gorm:"column:locked;not null;default:false"`      // Read-only: messages can be read and exported but not sent, edited or deleted
+	LockedBy         string    `gorm:"column:locked_by;not null;default:''"`      // Who locked the chat, owner or admin
+	CreatedAt        time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt        time.Time `gorm:"column:updated_at;not null"`
+	// DeleteAt is set while the chat is pending deletion, to when it is deleted unless restored
+	DeleteAt *time.Time `gorm:"column:delete_at;index"`
+	// Tags label the chat, comma-separated
+	Tags     string `gorm:"column:tags;not null;default:''"`
+	Archived bool   `gorm:"column:archived;not null;default:false"` // Archived chats are still listed; searches can leave them out
+	// PinnedMessageID is the message pinned to the top of the chat, such as a conversation summary
+	PinnedMessageID *int64 `gorm:"column:pinned_message_id"`
+	// SystemPrompt is the instructions of the chat, sent to the model after those of the
+	// workspace and the user
+	SystemPrompt string `gorm:"column:system_prompt;type:text;not null;default:''"`
+
+	// MaxTokens and MaxCost budget the usage of the chat, in tokens and USD; 0 is no limit
+	MaxTokens  int64   `gorm:"column:max_tokens;not null;default:0"`
+	MaxCost    float64 `gorm:"column:max_cost;not null;default:0"`
+	BudgetMode string  `gorm:"column:budget_mode;not null;default:''"` // Once the budget is used, reject or warn; empty rejects
+	// UsedTokens and UsedCost are the cumulative usage of the replies of the chat
+	UsedTokens int64   `gorm:"column:used_tokens;not null;default:0"`
+	UsedCost   float64 `gorm:"column:used_cost;not null;default:0"`
+
+	// Ephemeral marks privacy mode chats, whose messages are kept in a cache for a while
+	// instead of the database, and published without their content
+	Ephemeral bool `gorm:"column:ephemeral;not null;default:false"`
+}
+
+// Who locked a chat
+const (
+	ChatLockedByOwner = "owner"
+	ChatLockedByAdmin = "admin"
+)
+
+// What happens to the messages sent to a chat that has used its budget
+const (
+	ChatBudgetModeReject = "reject"
+	ChatBudgetModeWarn   = "warn"
+)
+
+// TagList returns the tags of the chat
+func (c *Chat) TagList() []string {
+	if c.Tags == "" {
+		return []string{}
+	}
+	return strings.Split(c.Tags, ",")
 }
 
 // TableName specifies the table name for Chat
@@ -19,16 +74,135 @@ func (Chat) TableName() string {
 	return "chats"
 }
 
+// HasBudget reports whether the usage of the chat is budgeted
+func (c *Chat) HasBudget() bool {
+	return c.MaxTokens > 0 || c.MaxCost > 0
+}
+
+// PendingDeletion reports whether the chat was deleted and can still be restored
+func (c *Chat) PendingDeletion() bool {
+	return c.DeleteAt != nil
+}
+
+// BudgetExceeded reports whether the chat has used its token or cost budget
+func (c *Chat) BudgetExceeded() bool {
+	return (c.MaxTokens > 0 && c.UsedTokens >= c.MaxTokens) || (c.MaxCost > 0 && c.UsedCost >= c.MaxCost)
+}
+
 // Message represents a single message in a chat
 type Message struct {
-	ID        int64     `gorm:"primaryKey;column:id"`
-	ChatID    int64     `gorm:"column:chat_id;not null;index"`
-	Chat      Chat      `gorm:"foreignKey:ChatID"`
-	UserID    *string   `gorm:"column:user_id"`       // Can be null for LLM responses
-	Role      string    `gorm:"column:role;not null"` // "user" or "assistant"
-	Content   string    `gorm:"column:content;not null"`
-	CreatedAt time.Time `gorm:"column:created_at;not null"`
-	UpdatedAt time.Time `gorm:"column:updated_at;not null"`
+	ID       int64   `gorm:"primaryKey;column:id"`
+	PublicID string  `gorm:"column:public_id;not null;default:'';index"` // ULID identifying the message to clients
+	ChatID   int64   `gorm:"column:chat_id;not null;index"`
+	Chat     Chat    `gorm:"foreignKey:ChatID" json:"-"`
+	SeqNo    int64   `gorm:"column:seq_no;not null;default:0"` // Increases by one per message of the chat
+	UserID   *string `gorm:"column:user_id;index"`             // Can be null for LLM responses
+	Role     string  `gorm:"column:role;not null"`             // One of the message roles
+	Content  string  `gorm:"column:content;not null"`
+	// Name is the function or tool a tool or function result came from
+	Name string `gorm:"column:name;not null;default:''"`
+	// ToolCallID is the call of the assistant a tool result answers
+	ToolCallID string `gorm:"column:tool_call_id;not null;default:''"`
+	// ExcludeFromPrompt keeps the message out of the history sent to the LLM, while it is
+	// still shown to users
+	ExcludeFromPrompt bool `gorm:"column:exclude_from_prompt;not null;default:false"`
+	// ContentType tells how to render the content: text/markdown, text/plain or application/json
+	ContentType string `gorm:"column:content_type;not null;default:'text/markdown'"`
+	// Kind sets apart special messages, such as conversation summaries; empty for the others
+	Kind string `gorm:"column:kind;not null;default:''"`
+	// ContentKey locates the content in the storage when it was too large to be kept in the
+	// database, whose content column then holds a preview of it
+	ContentKey string `gorm:"column:content_key;not null;default:''"`
+	// ContentHash references the content in message_contents when it is shared with the
+	// other messages repeating it, the content column then holding a preview of it
+	ContentHash string `gorm:"column:content_hash;not null;default:'';size:64;index"`
+	Status      string `gorm:"column:status;not null;default:''"` // Empty once the message is complete
+	PersonaID   *int64 `gorm:"column:persona_id;index"`           // Group chat persona of assistant messages
+	Model       string `gorm:"column:model;not null;default:''"`  // LLM model of assistant messages
+	// Provider is the LLM provider of assistant messages, and ModelVersion the version of the
+	// model it reported, such as the fingerprint of its deployment
+	Provider     string `gorm:"column:provider;not null;default:''"`
+	ModelVersion string `gorm:"column:model_version;not null;default:''"`
+	VariantID    *int64 `gorm:"column:variant_id;index"` // Experiment variant of assistant messages
+	// TotalTokens and LatencyMs are the LLM usage and generation time of assistant messages
+	TotalTokens int   `gorm:"column:total_tokens;not null;default:0"`
+	LatencyMs   int64 `gorm:"column:latency_ms;not null;default:0"`
+	// Feedback is the user rating of an assistant message: 1, -1, or 0 when not rated
+	Feedback int `gorm:"column:feedback;not null;default:0"`
+	// Language is the detected language of user messages, or the language assistant
+	// messages were translated into
+	Language string `gorm:"column:language;not null;default:''"`
+	// Suggestions are the follow-up questions suggested after an assistant reply, one per line
+	Suggestions string `gorm:"column:suggestions;not null;default:''"`
+	// Translation is the content in the language of the model, when it was translated
+	Translation string     `gorm:"column:translation;not null;default:''"`
+	EditedAt    *time.Time `gorm:"column:edited_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;not null"`
+}
+
+// InPrompt reports whether the message is sent to the LLM with the history of its chat:
+// it is complete and not excluded from prompts
+func (m *Message) InPrompt() bool {
+	return m.Status == "" && !m.ExcludeFromPrompt
+}
+
+// Ephemeral reports whether the message is of a privacy mode chat, and kept out of the database
+func (m *Message) Ephemeral() bool {
+	return IsEphemeralMessageID(m.ID)
+}
+
+// The messages of privacy mode chats are numbered from ephemeralMessageIDBase, below the IDs
+// of the database, by chat and sequence number: IDs of a chat increase with its messages,
+// and tell the chat they belong to. They stay within the integers JSON numbers represent
+// exactly, for chat IDs below 2^33 and sequence numbers below 2^20.
+const (
+	ephemeralMessageIDBase = -(1 << 53)
+	ephemeralSeqNoBits     = 20
+	// MaxEphemeralChatID and MaxEphemeralSeqNo bound the chats and sequence numbers of the
+	// messages of privacy mode chats
+	MaxEphemeralChatID = 1<<33 - 1
+	MaxEphemeralSeqNo  = 1<<ephemeralSeqNoBits - 1
+)
+
+// EphemeralMessageID returns the ID of the message of a privacy mode chat with a sequence
+// number
+func EphemeralMessageID(chatID, seqNo int64) int64 {
+	return ephemeralMessageIDBase + chatID<<ephemeralSeqNoBits + seqNo
+}
+
+// EphemeralMessageChatID returns the chat of the message of a privacy mode chat with an ID
+func EphemeralMessageChatID(id int64) int64 {
+	return (id - ephemeralMessageIDBase) >> ephemeralSeqNoBits
+}
+
+// IsEphemeralMessageID reports whether an ID is of a message of a privacy mode chat
+func IsEphemeralMessageID(id int64) bool {
+	return id < 0
+}
+
+// SuggestionList returns the follow-up questions suggested after the message
+func (m *Message) SuggestionList() []string {
+	if m.Suggestions == "" {
+		return nil
+	}
+	return strings.Split(m.Suggestions, "\n")
+}
+
+// ContentPreviewLength is the length in characters of the preview kept in the database
+// of content moved to the storage
+const ContentPreviewLength = 1000
+
+// ContentPreview returns the beginning of content, up to ContentPreviewLength characters
+func ContentPreview(content string) string {
+	count := 0
+	for i := range content {
+		if count == ContentPreviewLength {
+			return content[:i]
+		}
+		count++
+	}
+	return content
 }
 
 // TableName specifies the table name for Message
@@ -36,10 +210,82 @@ func (Message) TableName() string {
 	return "messages"
 }
 
+// User roles carried in the JWT role claim
+const (
+	RoleAdmin = "admin"
+	// RoleGuest marks anonymous users of ephemeral guest sessions
+	RoleGuest = "guest"
+)
+
+// GuestUserPrefix prefixes the user IDs of guest sessions
+const GuestUserPrefix = "guest:"
+
+// IsGuest reports whether a user ID belongs to a guest session
+func IsGuest(userID string) bool {
+	return strings.HasPrefix(userID, GuestUserPrefix)
+}
+
+// Message roles
+const (
+	MessageRoleUser      = "user"
+	MessageRoleAssistant = "assistant"
+	// MessageRoleSystem marks instructions to the LLM, sent before the rest of the history,
+	// and announcements, which are excluded from prompts
+	MessageRoleSystem = "system"
+	// MessageRoleTool and MessageRoleFunction mark the results of tool and function calls;
+	// they are sent after the assistant message making the calls
+	MessageRoleTool     = "tool"
+	MessageRoleFunction = "function"
+)
+
+// MessageKindSummary marks the assistant messages summarizing a conversation at the request
+// of its user, which are excluded from prompts
+const MessageKindSummary = "summary"
+
+// Message content types
+const (
+	ContentTypeMarkdown = "text/markdown"
+	ContentTypePlain    = "text/plain"
+	ContentTypeJSON     = "application/json"
+)
+
+// Message statuses of voice messages until their transcript is the message content
+const (
+	MessageStatusTranscribing        = "transcribing"
+	MessageStatusTranscriptionFailed = "transcription_failed"
+)
+
+// MessageStatusPartial marks an assistant reply saved while it is generated
+const MessageStatusPartial = "partial"
+
+// MessageStatusFailed marks an assistant reply whose generation was cut off, by an error or
+// by a crash found by the watchdog. It keeps the content generated until then, and can be
+// retried.
+const MessageStatusFailed = "failed"
+
 // Event types for Kafka messages
 const (
 	EventChatCreated    = "chat.created"
 	EventChatUpdated    = "chat.updated"
 	EventMessageCreated = "message.created"
 	EventMessageUpdated = "message.updated"
+	EventChatDeleted    = "chat.deleted"
+	EventMessageDeleted = "message.deleted"
+
+	// EventChatTransferred is published when a chat moves to another owner
+	EventChatTransferred = "chat.transferred"
+
+	// EventChatDeleteRequested is published when a chat becomes pending deletion, ahead of
+	// chat.deleted, and EventChatDeleteUndone when it is restored
+	EventChatDeleteRequested = "chat.delete_requested"
+	EventChatDeleteUndone    = "chat.delete_undone"
+
+	// EventMessageReactionAdded and EventMessageReactionRemoved are published when a user
+	// reacts to a message with an emoji, or takes the reaction back
+	EventMessageReactionAdded   = "message.reaction_added"
+	EventMessageReactionRemoved = "message.reaction_removed"
+
+	// EventMessageGenerationStalled is published when the watchdog fails a reply that stopped
+	// being generated without completing or failing, as after a crash
+	EventMessageGenerationStalled = "message.generation_stalled"
 )
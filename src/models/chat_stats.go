@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// ChatStats is the projection of a chat's activity maintained from message events
+type ChatStats struct {
+	ChatID       int64 `gorm:"primaryKey;autoIncrement:false;column:chat_id"`
+	Chat         Chat  `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE"`
+	MessageCount int64 `gorm:"column:message_count;not null;default:0"`
+	TotalTokens  int64 `gorm:"column:total_tokens;not null;default:0"`
+	// LastMessageID is the latest message counted, so redelivered events are not counted twice
+	LastMessageID  int64     `gorm:"column:last_message_id;not null;default:0"`
+	LastActivityAt time.Time `gorm:"column:last_activity_at;not null"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for ChatStats
+func (ChatStats) TableName() string {
+	return "chat_stats"
+}
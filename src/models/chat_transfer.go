@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// ChatTransfer records that the ownership of a chat moved from a user to another
+type ChatTransfer struct {
+	ID         int64  `gorm:"primaryKey;column:id"`
+	ChatID     int64  `gorm:"column:chat_id;not null;index"`
+	FromUserID string `gorm:"column:from_user_id;not null"`
+	ToUserID   string `gorm:"column:to_user_id;not null"`
+	// TransferredBy is the user who requested the transfer, the previous owner or an admin
+	TransferredBy string    `gorm:"column:transferred_by;not null"`
+	CreatedAt     time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for ChatTransfer
+func (ChatTransfer) TableName() string {
+	return "chat_transfers"
+}
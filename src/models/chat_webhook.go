@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// ChatWebhook is a URL the completed exchanges of a chat are posted to
+type ChatWebhook struct {
+	ID     int64  `gorm:"primaryKey;column:id"`
+	ChatID int64  `gorm:"column:chat_id;not null;index"`
+	Chat   Chat   `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE"`
+	URL    string `gorm:"column:url;not null"`
+	// Secret signs the deliveries of the webhook
+	Secret string `gorm:"column:secret;not null"`
+	// LastDeliveredAt is when an exchange was last delivered, and LastError why the latest
+	// exchange given up could not be
+	LastDeliveredAt *time.Time `gorm:"column:last_delivered_at"`
+	LastError       string     `gorm:"column:last_error;not null;default:''"`
+	CreatedAt       time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for ChatWebhook
+func (ChatWebhook) TableName() string {
+	return "chat_webhooks"
+}
+
+// ChatWebhookDelivery is an exchange waiting to be posted to a chat webhook. It is deleted
+// once delivered, or given up after the configured attempts.
+type ChatWebhookDelivery struct {
+	ID        int64       `gorm:"primaryKey;column:id"`
+	WebhookID int64       `gorm:"column:webhook_id;not null;index"`
+	Webhook   ChatWebhook `gorm:"foreignKey:WebhookID;constraint:OnDelete:CASCADE"`
+	MessageID int64       `gorm:"column:message_id;not null"` // Reply completing the exchange
+	// Body is the JSON posted, the same for every attempt
+	Body          string    `gorm:"column:body;not null"`
+	Attempts      int       `gorm:"column:attempts;not null;default:0"`
+	NextAttemptAt time.Time `gorm:"column:next_attempt_at;not null;index"`
+	CreatedAt     time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for ChatWebhookDelivery
+func (ChatWebhookDelivery) TableName() string {
+	return "chat_webhook_deliveries"
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Checkpoint is a named point in the history of a chat, which can be restored into a new chat
+type Checkpoint struct {
+	ID     int64  `gorm:"primaryKey;column:id"`
+	ChatID int64  `gorm:"column:chat_id;not null;index"`
+	Chat   Chat   `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE"`
+	Name   string `gorm:"column:name;not null"`
+	// SeqNo is the sequence number of the latest message of the chat when the checkpoint was created
+	SeqNo        int64     `gorm:"column:seq_no;not null;default:0"`
+	MessageCount int64     `gorm:"column:message_count;not null;default:0"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for Checkpoint
+func (Checkpoint) TableName() string {
+	return "checkpoints"
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"time"
+)
+
+// Document statuses
+const (
+	// DocumentStatusPending is a document waiting to be chunked, when added or re-indexed
+	DocumentStatusPending = "pending"
+	DocumentStatusReady   = "ready"
+	DocumentStatusFailed  = "failed"
+)
+
+// Collection is a set of documents of a user, chunked for retrieval and attached to chats
+type Collection struct {
+	ID          int64  `gorm:"primaryKey;column:id"`
+	UserID      string `gorm:"column:user_id;not null;index"`
+	Name        string `gorm:"column:name;not null"`
+	Description string `gorm:"column:description;not null;default:''"`
+	// ChunkSize and ChunkOverlap are the length in characters of the chunks of its documents,
+	// and of the text consecutive chunks share
+	ChunkSize    int       `gorm:"column:chunk_size;not null"`
+	ChunkOverlap int       `gorm:"column:chunk_overlap;not null"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt    time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for Collection
+func (Collection) TableName() string {
+	return "collections"
+}
+
+// Document is a text document of a collection
+type Document struct {
+	ID           int64  `gorm:"primaryKey;column:id"`
+	CollectionID int64  `gorm:"column:collection_id;not null;index"`
+	Title        string `gorm:"column:title;not null"`
+	// Source is where the document comes from, such as a filename or a URL
+	Source  string `gorm:"column:source;not null;default:''"`
+	Content string `gorm:"column:content;not null"`
+	Status  string `gorm:"column:status;not null;index"`
+	// Error is why the document could not be chunked
+	Error     string     `gorm:"column:error;not null;default:''"`
+	Chunks    int        `gorm:"column:chunks;not null;default:0"`
+	IndexedAt *time.Time `gorm:"column:indexed_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt time.Time  `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for Document
+func (Document) TableName() string {
+	return "documents"
+}
+
+// DocumentChunk is a chunk of the content of a document
+type DocumentChunk struct {
+	ID           int64  `gorm:"primaryKey;column:id"`
+	DocumentID   int64  `gorm:"column:document_id;not null;index"`
+	CollectionID int64  `gorm:"column:collection_id;not null;index"`
+	Position     int    `gorm:"column:position;not null"` // Order of the chunk in its document
+	Content      string `gorm:"column:content;not null"`
+}
+
+// TableName specifies the table name for DocumentChunk
+func (DocumentChunk) TableName() string {
+	return "document_chunks"
+}
+
+// ChatCollection attaches a collection to a chat
+type ChatCollection struct {
+	ChatID       int64     `gorm:"primaryKey;column:chat_id"`
+	CollectionID int64     `gorm:"primaryKey;column:collection_id;index"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for ChatCollection
+func (ChatCollection) TableName() string {
+	return "chat_collections"
+}
+
+// Crawl statuses
+const (
+	CrawlStatusPending   = "pending"
+	CrawlStatusRunning   = "running"
+	CrawlStatusCompleted = "completed"
+	CrawlStatusFailed    = "failed"
+)
+
+// Crawl modes
+const (
+	// CrawlModeSitemap crawls the pages listed by the sitemaps of a site
+	CrawlModeSitemap = "sitemap"
+	// CrawlModeLinks follows the links of a start page, up to a depth
+	CrawlModeLinks = "links"
+)
+
+// Crawl ingests the pages of a website into a collection
+type Crawl struct {
+	ID           int64  `gorm:"primaryKey;column:id"`
+	CollectionID int64  `gorm:"column:collection_id;not null;index"`
+	UserID       string `gorm:"column:user_id;not null"`
+	URL          string `gorm:"column:url;not null"`
+	Mode         string `gorm:"column:mode;not null"`
+	MaxDepth     int    `gorm:"column:max_depth;not null"`
+	MaxPages     int    `gorm:"column:max_pages;not null"`
+	Status       string `gorm:"column:status;not null;index"`
+	// Pages counts the pages ingested, Skipped those disallowed by robots.txt or that could
+	// not be fetched
+	Pages       int        `gorm:"column:pages;not null;default:0"`
+	Skipped     int        `gorm:"column:skipped;not null;default:0"`
+	Error       string     `gorm:"column:error;not null;default:''"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;not null"`
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+}
+
+// TableName specifies the table name for Crawl
+func (Crawl) TableName() string {
+	return "crawls"
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Consent records that a user accepted a version of the data-usage terms
+type Consent struct {
+	ID           int64     `gorm:"primaryKey;column:id"`
+	UserID       string    `gorm:"column:user_id;not null;uniqueIndex:idx_consents_user_version"`
+	TermsVersion string    `gorm:"column:terms_version;not null;uniqueIndex:idx_consents_user_version"`
+	AcceptedAt   time.Time `gorm:"column:accepted_at;not null"`
+}
+
+// TableName specifies the table name for Consent
+func (Consent) TableName() string {
+	return "consents"
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// CustomCommand is a slash command admins define for the whole workspace, sending its
+// prompt template, filled with the input of the command, as a message of the chat
+type CustomCommand struct {
+	ID int64 `gorm:"primaryKey;column:id"`
+	// Name is the command without its slash, unique and never that of a built-in command
+	Name        string `gorm:"column:name;size:32;not null;uniqueIndex"`
+	Description string `gorm:"column:description;not null;default:''"`
+	// Template is the prompt sent, with {{input}} replaced by the input of the command
+	Template  string    `gorm:"column:template;not null"`
+	UpdatedBy string    `gorm:"column:updated_by;not null;default:''"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for CustomCommand
+func (CustomCommand) TableName() string {
+	return "custom_commands"
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// DeadLetter represents a consumed message that kept failing processing and was
+// routed to the dead-letter topic
+type DeadLetter struct {
+	ID         int64      `gorm:"primaryKey;column:id"`
+	Topic      string     `gorm:"column:topic;not null;index"`
+	Partition  int        `gorm:"column:partition;not null"`
+	Offset     int64      `gorm:"column:offset;not null"`
+	Key        string     `gorm:"column:key"`
+	Payload    string     `gorm:"column:payload;not null"`
+	Headers    string     `gorm:"column:headers"` // JSON-encoded header map
+	Error      string     `gorm:"column:error;not null"`
+	Attempts   int        `gorm:"column:attempts;not null"`
+	ReplayedAt *time.Time `gorm:"column:replayed_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for DeadLetter
+func (DeadLetter) TableName() string {
+	return "dead_letters"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// Device is a mobile device registered by a user for push notifications
+type Device struct {
+	ID     int64  `gorm:"primaryKey;column:id"`
+	UserID string `gorm:"column:user_id;not null;index"`
+	// Platform is the push service of the device: fcm or apns
+	Platform  string    `gorm:"column:platform;not null"`
+	Token     string    `gorm:"column:token;not null;uniqueIndex"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for Device
+func (Device) TableName() string {
+	return "devices"
+}
+
+// ChatMute silences the push notifications of a chat for a user
+type ChatMute struct {
+	ChatID int64  `gorm:"primaryKey;column:chat_id"`
+	UserID string `gorm:"primaryKey;column:user_id"`
+	// Until is when the chat is unmuted; nil mutes it until unmuted explicitly
+	Until     *time.Time `gorm:"column:until"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for ChatMute
+func (ChatMute) TableName() string {
+	return "chat_mutes"
+}
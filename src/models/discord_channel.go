@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// DiscordChannel maps a Discord channel or thread of a user to the chat answering them in
+// it. Users asking in the same channel each have their own chat.
+type DiscordChannel struct {
+	ChannelID string `gorm:"primaryKey;column:channel_id"`
+	UserID    string `gorm:"primaryKey;column:user_id"`
+	ChatID    int64  `gorm:"column:chat_id;not null;index"`
+	Chat      Chat   `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE"`
+	// GuildID is empty for direct messages
+	GuildID   string    `gorm:"column:guild_id;not null;default:''"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for DiscordChannel
+func (DiscordChannel) TableName() string {
+	return "discord_channels"
+}
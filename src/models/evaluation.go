@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Evaluation is the score an LLM judge gave to the messages of a chat up to MessageID, since
+// the previous evaluation of the chat
+type Evaluation struct {
+	ID        int64 `gorm:"primaryKey;column:id"`
+	ChatID    int64 `gorm:"column:chat_id;not null;index"`
+	Chat      Chat  `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE"`
+	MessageID int64 `gorm:"column:message_id;not null"` // Latest message scored
+	// Replies is the number of assistant replies scored, and Refusals those the judge found
+	// refusing the request
+	Replies  int `gorm:"column:replies;not null"`
+	Refusals int `gorm:"column:refusals;not null"`
+	// Helpfulness rates the replies from 1 (unhelpful) to 5 (very helpful)
+	Helpfulness int       `gorm:"column:helpfulness;not null"`
+	Comment     string    `gorm:"column:comment;not null;default:''"`
+	Model       string    `gorm:"column:model;not null;default:''"` // Judge model
+	CreatedAt   time.Time `gorm:"column:created_at;not null;index"`
+}
+
+// TableName specifies the table name for Evaluation
+func (Evaluation) TableName() string {
+	return "evaluations"
+}
+
+// EvaluationTrend aggregates the evaluations of a day, in UTC
+type EvaluationTrend struct {
+	Day            time.Time
+	Evaluations    int64
+	Replies        int64
+	Refusals       int64
+	AvgHelpfulness float64
+}
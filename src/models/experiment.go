@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+// Experiment statuses. Experiments run at most once: draft, then running, then stopped.
+const (
+	ExperimentStatusDraft   = "draft"
+	ExperimentStatusRunning = "running"
+	ExperimentStatusStopped = "stopped"
+)
+
+// Experiment splits the replies of chats between prompt or model variants to compare them
+type Experiment struct {
+	ID          int64               `gorm:"primaryKey;column:id"`
+	Name        string              `gorm:"column:name;not null"`
+	Description string              `gorm:"column:description;not null;default:''"`
+	Status      string              `gorm:"column:status;not null;index"`
+	Variants    []ExperimentVariant `gorm:"foreignKey:ExperimentID;constraint:OnDelete:CASCADE"`
+	StartedAt   *time.Time          `gorm:"column:started_at"`
+	StoppedAt   *time.Time          `gorm:"column:stopped_at"`
+	CreatedAt   time.Time           `gorm:"column:created_at;not null"`
+	UpdatedAt   time.Time           `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for Experiment
+func (Experiment) TableName() string {
+	return "experiments"
+}
+
+// ExperimentVariant is a prompt or model variant of an experiment
+type ExperimentVariant struct {
+	ID           int64  `gorm:"primaryKey;column:id"`
+	ExperimentID int64  `gorm:"column:experiment_id;not null;index"`
+	Name         string `gorm:"column:name;not null"`
+	// Weight is the share of chats assigned to the variant, relative to the other variants
+	Weight int `gorm:"column:weight;not null"`
+	// Model and SystemPrompt override the defaults when set
+	Model        string `gorm:"column:model;not null;default:''"`
+	SystemPrompt string `gorm:"column:system_prompt;not null;default:''"`
+}
+
+// TableName specifies the table name for ExperimentVariant
+func (ExperimentVariant) TableName() string {
+	return "experiment_variants"
+}
+
+// VariantStats aggregates the replies generated with an experiment variant
+type VariantStats struct {
+	VariantID        int64
+	Chats            int64
+	Replies          int64
+	AvgLatencyMs     float64
+	AvgTokens        float64
+	TotalTokens      int64
+	PositiveFeedback int64
+	NegativeFeedback int64
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Export statuses
+const (
+	ExportStatusPending   = "pending"
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+)
+
+// Export is a request to package all the chats of a user into a zip archive
+type Export struct {
+	ID     int64  `gorm:"primaryKey;column:id"`
+	UserID string `gorm:"column:user_id;not null;index"`
+	Status string `gorm:"column:status;not null;index"`
+	// StorageKey locates the archive in the storage once completed
+	StorageKey  string     `gorm:"column:storage_key"`
+	Size        int64      `gorm:"column:size;not null;default:0"`
+	Chats       int        `gorm:"column:chats;not null;default:0"`
+	Error       string     `gorm:"column:error"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;not null"`
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+}
+
+// TableName specifies the table name for Export
+func (Export) TableName() string {
+	return "exports"
+}
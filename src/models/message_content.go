@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// MessageContent is content stored once for all the messages repeating it, such as system
+// prompts, templates and pasted documents, which reference it by hash
+type MessageContent struct {
+	Hash    string `gorm:"column:hash;primaryKey;size:64"` // Hex SHA-256 of the content
+	Content string `gorm:"column:content;not null"`
+	Size    int    `gorm:"column:size;not null"` // In bytes
+	// UsedAt is when a message last took the content; it is deleted some time after no
+	// message references it anymore
+	UsedAt time.Time `gorm:"column:used_at;not null;index"`
+}
+
+// TableName specifies the table name for MessageContent
+func (MessageContent) TableName() string {
+	return "message_contents"
+}
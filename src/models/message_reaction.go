@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// MessageReaction is an emoji reaction of a user to a message. A user reacts to a message
+// with each emoji at most once.
+type MessageReaction struct {
+	ID        int64     `gorm:"primaryKey;column:id"`
+	MessageID int64     `gorm:"column:message_id;not null;uniqueIndex:idx_message_reactions_unique"`
+	Message   Message   `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
+	UserID    string    `gorm:"column:user_id;not null;uniqueIndex:idx_message_reactions_unique"`
+	Emoji     string    `gorm:"column:emoji;not null;uniqueIndex:idx_message_reactions_unique"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for MessageReaction
+func (MessageReaction) TableName() string {
+	return "message_reactions"
+}
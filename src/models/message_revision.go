@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// MessageRevision is a previous version of an edited message
+type MessageRevision struct {
+	ID        int64   `gorm:"primaryKey;column:id"`
+	MessageID int64   `gorm:"column:message_id;not null;index"`
+	Message   Message `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
+	Content   string  `gorm:"column:content;not null"`
+	// ContentKey locates the content in the storage, like that of messages
+	ContentKey string    `gorm:"column:content_key;not null;default:''"`
+	CreatedAt  time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for MessageRevision
+func (MessageRevision) TableName() string {
+	return "message_revisions"
+}
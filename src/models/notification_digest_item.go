@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationDigestItem is a notification queued for the next activity digest of a user
+type NotificationDigestItem struct {
+	ID        int64     `gorm:"primaryKey;column:id"`
+	UserID    string    `gorm:"column:user_id;type:varchar(255);not null"`
+	Trigger   string    `gorm:"column:trigger;type:varchar(50);not null"`
+	Summary   string    `gorm:"column:summary;type:text;not null;default:''"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for NotificationDigestItem
+func (NotificationDigestItem) TableName() string {
+	return "notification_digest_items"
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Frequencies of activity digests
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// NotificationPreference is a user's choice of the notifications they receive
+type NotificationPreference struct {
+	UserID string `gorm:"primaryKey;column:user_id"`
+	// Email is the address notifications are sent to; empty disables email notifications
+	Email string `gorm:"column:email;not null;default:''"`
+	// WebhookURL is the URL notifications are posted to; empty disables webhook notifications
+	WebhookURL string `gorm:"column:webhook_url;not null;default:''"`
+	// WebhookSecret signs the webhook deliveries when set
+	WebhookSecret string `gorm:"column:webhook_secret;not null;default:''"`
+	// DisabledNotifications is the comma-separated list of the trigger:channel pairs the
+	// user opted out of
+	DisabledNotifications string `gorm:"column:disabled_notifications;not null;default:''"`
+	// DigestFrequency is daily or weekly when the user receives a digest of their activity by
+	// email in place of individual emails; empty when they do not
+	DigestFrequency string `gorm:"column:digest_frequency;type:varchar(20);not null;default:''"`
+	// DigestSentAt is when the last digest was sent
+	DigestSentAt *time.Time `gorm:"column:digest_sent_at"`
+	CreatedAt    time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for NotificationPreference
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
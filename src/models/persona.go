@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Persona is an assistant of a group chat, addressed in messages by @handle
+type Persona struct {
+	ID     int64  `gorm:"primaryKey;column:id"`
+	ChatID int64  `gorm:"column:chat_id;not null;uniqueIndex:idx_personas_chat_id_handle"`
+	Handle string `gorm:"column:handle;not null;uniqueIndex:idx_personas_chat_id_handle"`
+	Name   string `gorm:"column:name;not null"`
+	// Model overrides the configured LLM model when set
+	Model        string    `gorm:"column:model;not null;default:''"`
+	SystemPrompt string    `gorm:"column:system_prompt;not null;default:''"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt    time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for Persona
+func (Persona) TableName() string {
+	return "personas"
+}
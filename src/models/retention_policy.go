@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// RetentionPolicy is a user's preference for how long the messages of their chats are kept
+type RetentionPolicy struct {
+	UserID string `gorm:"primaryKey;column:user_id"`
+	// Days after which messages are deleted; 0 keeps messages forever
+	Days      int       `gorm:"column:days;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for RetentionPolicy
+func (RetentionPolicy) TableName() string {
+	return "retention_policies"
+}
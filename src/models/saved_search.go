@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// SavedSearch is a named chat search of a user, a smart folder whose chats are searched
+// again each time it is opened
+type SavedSearch struct {
+	ID     int64  `gorm:"primaryKey;column:id"`
+	UserID string `gorm:"column:user_id;not null;index"`
+	Name   string `gorm:"column:name;not null"`
+	Query  string `gorm:"column:query;not null;default:''"`
+	// Period is a date range relative to when the search runs, such as last_month; From and
+	// To are fixed bounds of when the chats were created
+	Period         string     `gorm:"column:period;not null;default:''"`
+	From           *time.Time `gorm:"column:from_time"`
+	To             *time.Time `gorm:"column:to_time"`
+	Tag            string     `gorm:"column:tag;not null;default:''"`
+	Archived       *bool      `gorm:"column:archived"`
+	HasAttachments *bool      `gorm:"column:has_attachments"`
+	Model          string     `gorm:"column:model;not null;default:''"`
+	CreatedAt      time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for SavedSearch
+func (SavedSearch) TableName() string {
+	return "saved_searches"
+}
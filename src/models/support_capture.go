@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// SupportCapture is an LLM call captured for support, kept until it expires. The captures of
+// a request make up its support bundle.
+type SupportCapture struct {
+	ID        int64  `gorm:"primaryKey;column:id"`
+	RequestID string `gorm:"column:request_id;not null;index"`
+	UserID    string `gorm:"column:user_id;not null;default:''"`
+	Route     string `gorm:"column:route;not null;default:''"`
+	Model     string `gorm:"column:model;not null;default:''"`
+	// Request and Response are the LLM request and response as JSON; Response is empty when
+	// the call failed with Error
+	Request      string    `gorm:"column:request;type:text;not null"`
+	Response     string    `gorm:"column:response;type:text;not null;default:''"`
+	Error        string    `gorm:"column:error;type:text;not null;default:''"`
+	Streaming    bool      `gorm:"column:streaming;not null;default:false"`
+	Chunks       int       `gorm:"column:chunks;not null;default:0"`
+	FirstChunkMs int64     `gorm:"column:first_chunk_ms;not null;default:0"`
+	DurationMs   int64     `gorm:"column:duration_ms;not null;default:0"`
+	StartedAt    time.Time `gorm:"column:started_at;not null"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null"`
+	ExpiresAt    time.Time `gorm:"column:expires_at;not null;index"`
+}
+
+// TableName specifies the table name for SupportCapture
+func (SupportCapture) TableName() string {
+	return "support_captures"
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Task statuses
+const (
+	TaskStatusOpen      = "open"
+	TaskStatusDone      = "done"
+	TaskStatusDismissed = "dismissed"
+)
+
+// Task is an action item extracted from a chat
+type Task struct {
+	ID          int64  `gorm:"primaryKey;column:id"`
+	ChatID      int64  `gorm:"column:chat_id;not null;index"`
+	Chat        Chat   `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE"`
+	Title       string `gorm:"column:title;not null"`
+	Description string `gorm:"column:description;not null;default:''"`
+	Assignee    string `gorm:"column:assignee;not null;default:''"` // Who the conversation assigned the task to, if anyone
+	// DueDate is the date the task is due, when the conversation set one
+	DueDate *time.Time `gorm:"column:due_date;type:date"`
+	Status  string     `gorm:"column:status;not null;default:'open'"`
+	// SourceMessageID is the latest message of the chat when the task was extracted; the next
+	// extraction starts after it
+	SourceMessageID int64 `gorm:"column:source_message_id;not null;default:0"`
+	// Tracker is the external tracker the task was exported to, when it was
+	Tracker    string     `gorm:"column:tracker;not null;default:''"`
+	ExportedAt *time.Time `gorm:"column:exported_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for Task
+func (Task) TableName() string {
+	return "tasks"
+}
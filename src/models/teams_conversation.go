@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// TeamsConversation maps a Microsoft Teams conversation of a user to the chat answering them
+// in it. Users posting in the same group chat or channel thread each have their own chat.
+type TeamsConversation struct {
+	ConversationID string `gorm:"primaryKey;column:conversation_id"`
+	UserID         string `gorm:"primaryKey;column:user_id"`
+	ChatID         int64  `gorm:"column:chat_id;not null;index"`
+	Chat           Chat   `gorm:"foreignKey:ChatID;constraint:OnDelete:CASCADE"`
+	TenantID       string `gorm:"column:tenant_id;not null;default:''"`
+	// ServiceURL is the Bot Framework connector of the conversation, replies are sent to
+	ServiceURL string    `gorm:"column:service_url;not null"`
+	CreatedAt  time.Time `gorm:"column:created_at;not null"`
+}
+
+// TableName specifies the table name for TeamsConversation
+func (TeamsConversation) TableName() string {
+	return "teams_conversations"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// TrainingExport is a request of an admin to export the conversations matching filters into
+// a fine-tuning dataset. It goes through the statuses of exports.
+type TrainingExport struct {
+	ID          int64  `gorm:"primaryKey;column:id"`
+	RequestedBy string `gorm:"column:requested_by;not null"`
+	Status      string `gorm:"column:status;not null;index"`
+	// From and To bound the creation time of the chats exported, when set
+	From *time.Time `gorm:"column:from_time"`
+	To   *time.Time `gorm:"column:to_time"`
+	// Tags are the tags the chats exported must all have, comma-separated
+	Tags string `gorm:"column:tags;not null;default:''"`
+	// MinScore is the least sum of the feedback of the replies of the chats exported
+	MinScore int `gorm:"column:min_score;not null"`
+	// SystemPrompt starts each conversation of the dataset, when set
+	SystemPrompt string `gorm:"column:system_prompt;type:text;not null;default:''"`
+	// StorageKey locates the dataset in the storage once completed
+	StorageKey  string     `gorm:"column:storage_key;not null;default:''"`
+	Size        int64      `gorm:"column:size;not null;default:0"`
+	Chats       int        `gorm:"column:chats;not null;default:0"`
+	Error       string     `gorm:"column:error;not null;default:''"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;not null"`
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+}
+
+// TableName specifies the table name for TrainingExport
+func (TrainingExport) TableName() string {
+	return "training_exports"
+}
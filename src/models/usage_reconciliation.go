@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// Statuses of usage reconciliations
+const (
+	// ReconciliationMatched is a day and model whose usage recorded matches the vendor's
+	ReconciliationMatched = "matched"
+	// ReconciliationDiscrepancy is a day and model whose tokens or cost differ beyond the tolerance
+	ReconciliationDiscrepancy = "discrepancy"
+	// ReconciliationMissingLocal is usage reported by the vendor that was not recorded
+	ReconciliationMissingLocal = "missing_local"
+	// ReconciliationMissingVendor is usage recorded that the vendor did not report
+	ReconciliationMissingVendor = "missing_vendor"
+)
+
+// UsageReconciliation compares the usage of a model on a UTC day reported by the LLM vendor
+// with the usage recorded
+type UsageReconciliation struct {
+	ID    int64     `gorm:"primaryKey;column:id"`
+	Day   time.Time `gorm:"column:day;type:date;not null"`
+	Model string    `gorm:"column:model;not null"`
+	// Source is where the usage of the vendor was imported from: openai or csv
+	Source                 string `gorm:"column:source;not null"`
+	VendorRequests         int64  `gorm:"column:vendor_requests;not null"`
+	VendorPromptTokens     int64  `gorm:"column:vendor_prompt_tokens;not null"`
+	VendorCompletionTokens int64  `gorm:"column:vendor_completion_tokens;not null"`
+	// VendorCost is nil when the source reports no cost, in USD otherwise
+	VendorCost            *float64  `gorm:"column:vendor_cost"`
+	LocalRequests         int64     `gorm:"column:local_requests;not null"`
+	LocalPromptTokens     int64     `gorm:"column:local_prompt_tokens;not null"`
+	LocalCompletionTokens int64     `gorm:"column:local_completion_tokens;not null"`
+	LocalCost             float64   `gorm:"column:local_cost;not null"` // In USD
+	Status                string    `gorm:"column:status;not null"`
+	ImportedAt            time.Time `gorm:"column:imported_at;not null"`
+}
+
+// TableName specifies the table name for UsageReconciliation
+func (UsageReconciliation) TableName() string {
+	return "usage_reconciliations"
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// UserPreference holds the settings a user applies to all their chats
+type UserPreference struct {
+	UserID string `gorm:"primaryKey;column:user_id"`
+	// Instructions are the custom instructions of the user, sent to the model after those of
+	// the workspace and before those of the chat
+	Instructions string    `gorm:"column:instructions;type:text;not null;default:''"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt    time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for UserPreference
+func (UserPreference) TableName() string {
+	return "user_preferences"
+}
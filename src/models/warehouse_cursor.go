@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// WarehouseCursor records how far a table was exported to the data warehouse: rows are
+// exported in order of their change time then ID, and those after the cursor remain
+type WarehouseCursor struct {
+	Table string `gorm:"primaryKey;column:table_name"`
+	// SchemaVersion is the version of the rows exported; the table is exported again from
+	// the start when it changes
+	SchemaVersion int       `gorm:"column:schema_version;not null"`
+	ChangedAt     time.Time `gorm:"column:changed_at;not null"`
+	RowID         int64     `gorm:"column:row_id;not null"`
+	ExportedRows  int64     `gorm:"column:exported_rows;not null;default:0"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for WarehouseCursor
+func (WarehouseCursor) TableName() string {
+	return "warehouse_cursors"
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Widget is an embeddable chat widget, started from the web origins it is allowed on, with its
+// branding and the default assistant answering its visitors
+type Widget struct {
+	ID   int64  `gorm:"primaryKey;column:id"`
+	Name string `gorm:"column:name;not null"`
+	// AllowedOrigins is the comma-separated list of the origins the widget is embedded on
+	AllowedOrigins string `gorm:"column:allowed_origins;not null"`
+	Enabled        bool   `gorm:"column:enabled;not null"`
+	// Title, Greeting, PrimaryColor and AvatarURL brand the widget
+	Title        string `gorm:"column:title;not null;default:''"`
+	Greeting     string `gorm:"column:greeting;not null;default:''"`
+	PrimaryColor string `gorm:"column:primary_color;not null;default:''"`
+	AvatarURL    string `gorm:"column:avatar_url;not null;default:''"`
+	// AssistantName, Model and SystemPrompt make up the default assistant of the widget chats;
+	// Model and SystemPrompt override the defaults when set
+	AssistantName string    `gorm:"column:assistant_name;not null;default:''"`
+	Model         string    `gorm:"column:model;not null;default:''"`
+	SystemPrompt  string    `gorm:"column:system_prompt;not null;default:''"`
+	CreatedAt     time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for Widget
+func (Widget) TableName() string {
+	return "widgets"
+}
+
+// WidgetUserPrefix prefixes the user IDs of widget sessions, which are guest sessions, followed
+// by the widget ID
+const WidgetUserPrefix = GuestUserPrefix + "widget:"
+
+// WidgetUserID returns the user ID of a new session of a widget
+func WidgetUserID(widgetID int64, sessionID string) string {
+	return WidgetUserPrefix + strconv.FormatInt(widgetID, 10) + ":" + sessionID
+}
+
+// WidgetOf returns the widget of the session a user ID belongs to, if any
+func WidgetOf(userID string) (int64, bool) {
+	rest, ok := strings.CutPrefix(userID, WidgetUserPrefix)
+	if !ok {
+		return 0, false
+	}
+	id, _, _ := strings.Cut(rest, ":")
+	widgetID, err := strconv.ParseInt(id, 10, 64)
+	return widgetID, err == nil
+}
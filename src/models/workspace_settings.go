@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// WorkspaceSettingsID is the ID of the only row of workspace settings
+const WorkspaceSettingsID = 1
+
+// WorkspaceSettings are the defaults admins set for the whole workspace, applied where users
+// and chats set nothing else. Empty settings fall back to the configuration.
+type WorkspaceSettings struct {
+	ID int `gorm:"primaryKey;column:id"`
+	// DefaultModel and SystemPrompt apply to replies without a persona, widget or experiment
+	// variant setting their own
+	DefaultModel string `gorm:"column:default_model;not null;default:''"`
+	SystemPrompt string `gorm:"column:system_prompt;not null;default:''"`
+	// ModerationLevel is off, redact or block; empty blocks, as configured moderation does
+	ModerationLevel string `gorm:"column:moderation_level;not null;default:''"`
+	// RetentionDays applies to users without a retention policy; nil falls back to retention.defaultDays
+	RetentionDays *int `gorm:"column:retention_days"`
+	// Guardrails are the names of the guardrail rules checked, comma-separated; nil checks every rule
+	Guardrails *string `gorm:"column:guardrails"`
+	// WarehouseOptOut keeps the chats, messages and usage of the workspace out of the data
+	// warehouse export
+	WarehouseOptOut bool      `gorm:"column:warehouse_opt_out;not null;default:false"`
+	UpdatedBy       string    `gorm:"column:updated_by;not null;default:''"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName specifies the table name for WorkspaceSettings
+func (WorkspaceSettings) TableName() string {
+	return "workspace_settings"
+}
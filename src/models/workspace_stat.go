@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// WorkspaceStat counts the requests and usage of a workspace in an hour, added to by every
+// instance
+type WorkspaceStat struct {
+	WorkspaceID string    `gorm:"column:workspace_id;primaryKey;size:64"`
+	Hour        time.Time `gorm:"column:hour;primaryKey;index"`
+	Requests    int64     `gorm:"column:requests;not null;default:0"`
+	// ClientErrors are the requests answered with a 4xx status, ServerErrors with a 5xx one
+	ClientErrors int64   `gorm:"column:client_errors;not null;default:0"`
+	ServerErrors int64   `gorm:"column:server_errors;not null;default:0"`
+	Tokens       int64   `gorm:"column:tokens;not null;default:0"`
+	Cost         float64 `gorm:"column:cost;not null;default:0"`
+	// Users is the number of distinct users making the requests, counted from the
+	// workspace_stat_users of the hour when listed
+	Users int64 `gorm:"column:users;->;-:migration"`
+}
+
+// TableName specifies the table name for WorkspaceStat
+func (WorkspaceStat) TableName() string {
+	return "workspace_stats"
+}
+
+// WorkspaceStatUser records the requests and usage of a user of a workspace in an hour,
+// added to by every instance, so that its distinct users are counted and the contribution of
+// each is bounded when released. Users are identified by a hash of their ID and the hour,
+// which only tells them apart within the hour.
+type WorkspaceStatUser struct {
+	WorkspaceID  string    `gorm:"column:workspace_id;primaryKey;size:64"`
+	Hour         time.Time `gorm:"column:hour;primaryKey;index"`
+	UserHash     string    `gorm:"column:user_hash;primaryKey;size:64"`
+	Requests     int64     `gorm:"column:requests;not null;default:0"`
+	ClientErrors int64     `gorm:"column:client_errors;not null;default:0"`
+	ServerErrors int64     `gorm:"column:server_errors;not null;default:0"`
+	Tokens       int64     `gorm:"column:tokens;not null;default:0"`
+	Cost         float64   `gorm:"column:cost;not null;default:0"`
+}
+
+// TableName specifies the table name for WorkspaceStatUser
+func (WorkspaceStatUser) TableName() string {
+	return "workspace_stat_users"
+}
@@ -0,0 +1,142 @@
+// Package analytics releases aggregated usage statistics so that they cannot be traced back
+// to the conversations of individual users: buckets counting fewer distinct users than a
+// threshold are suppressed, k-anonymity style, and the values released can be blurred with
+// Laplace noise, differential privacy style.
+package analytics
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// releasedValues is the number of values of a bucket, sharing the privacy budget of its
+// release
+const releasedValues = 6
+
+// Policy is how buckets are released
+type Policy struct {
+	// MinUsers is the fewest distinct users a bucket is released with
+	MinUsers int64
+	// Epsilon is the privacy budget spent by the release of each bucket, split evenly
+	// between its values. No noise is added when it is 0.
+	Epsilon float64
+	// RequestsPerUser, TokensPerUser and CostPerUser bound the contribution of a user to a
+	// bucket, calibrating the noise. Contributions are clipped to them when aggregated.
+	RequestsPerUser float64
+	TokensPerUser   float64
+	CostPerUser     float64
+	// Secret keys the noise of each release, so that releasing the same bucket again draws
+	// the same noise instead of letting it be averaged away
+	Secret []byte
+}
+
+// Bucket is the counts of a group of users, such as a workspace in an hour
+type Bucket struct {
+	Users        int64
+	Requests     int64
+	ClientErrors int64
+	ServerErrors int64
+	Tokens       int64
+	Cost         float64
+}
+
+// Contribution is the counts of a user in a bucket
+type Contribution struct {
+	Requests     int64
+	ClientErrors int64
+	ServerErrors int64
+	Tokens       int64
+	Cost         float64
+}
+
+// Clip returns a contribution bounded by the policy. Errors are scaled down with the
+// requests, so that they remain a part of them.
+func (p Policy) Clip(c Contribution) Contribution {
+	maxRequests := int64(math.Floor(p.RequestsPerUser))
+	if c.Requests > maxRequests {
+		c.ClientErrors = c.ClientErrors * maxRequests / c.Requests
+		c.ServerErrors = c.ServerErrors * maxRequests / c.Requests
+		c.Requests = maxRequests
+	}
+	c.Tokens = min(c.Tokens, int64(math.Floor(p.TokensPerUser)))
+	c.Cost = math.Min(c.Cost, p.CostPerUser)
+	return c
+}
+
+// Aggregate returns the bucket of the contributions of its distinct users, each clipped
+// first so that no user moves the bucket more than the noise of its release covers
+func (p Policy) Aggregate(contributions []Contribution) Bucket {
+	bucket := Bucket{Users: int64(len(contributions))}
+	for _, c := range contributions {
+		c = p.Clip(c)
+		bucket.Requests += c.Requests
+		bucket.ClientErrors += c.ClientErrors
+		bucket.ServerErrors += c.ServerErrors
+		bucket.Tokens += c.Tokens
+		bucket.Cost += c.Cost
+	}
+	return bucket
+}
+
+// Noisy tells whether the policy adds noise to the buckets it releases
+func (p Policy) Noisy() bool {
+	return p.Epsilon > 0
+}
+
+// Release returns a bucket identified by id as released by the policy, or false when it is
+// suppressed. The bucket is expected to be aggregated from clipped contributions. With
+// noise, the threshold applies to the noisy number of users, so that
+// suppressing a bucket does not reveal its exact number of users either.
+func (p Policy) Release(id string, bucket Bucket) (Bucket, bool) {
+	if !p.Noisy() {
+		return bucket, bucket.Users >= p.MinUsers
+	}
+
+	rnd := p.source(id, bucket)
+	released := Bucket{
+		Users:        p.count(rnd, bucket.Users, 1),
+		Requests:     p.count(rnd, bucket.Requests, p.RequestsPerUser),
+		ClientErrors: p.count(rnd, bucket.ClientErrors, p.RequestsPerUser),
+		ServerErrors: p.count(rnd, bucket.ServerErrors, p.RequestsPerUser),
+		Tokens:       p.count(rnd, bucket.Tokens, p.TokensPerUser),
+		Cost:         math.Max(0, bucket.Cost+p.noise(rnd, p.CostPerUser)),
+	}
+	if released.Users < p.MinUsers {
+		return Bucket{}, false
+	}
+	return released, true
+}
+
+// source returns the source of the noise of a release of a bucket, seeded by the secret,
+// the bucket and its values
+func (p Policy) source(id string, bucket Bucket) *rand.Rand {
+	mac := hmac.New(sha256.New, p.Secret)
+	fmt.Fprintf(mac, "%s\x00%+v", id, bucket)
+	return rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(mac.Sum(nil)))))
+}
+
+// count returns a count with noise for a sensitivity, rounded and kept positive
+func (p Policy) count(rnd *rand.Rand, value int64, sensitivity float64) int64 {
+	return max(0, int64(math.Round(float64(value)+p.noise(rnd, sensitivity))))
+}
+
+// noise draws Laplace noise for a value of a sensitivity, from its share of the budget
+func (p Policy) noise(rnd *rand.Rand, sensitivity float64) float64 {
+	return laplace(rnd, sensitivity*releasedValues/p.Epsilon)
+}
+
+// laplace draws from the Laplace distribution centered on 0 with a scale
+func laplace(rnd *rand.Rand, scale float64) float64 {
+	u := rnd.Float64() - 0.5
+	for u == -0.5 {
+		u = rnd.Float64() - 0.5
+	}
+	if u < 0 {
+		return scale * math.Log(1+2*u)
+	}
+	return -scale * math.Log(1-2*u)
+}
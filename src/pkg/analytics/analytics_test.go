@@ -0,0 +1,203 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPolicy bounds the contribution of a user to 100 requests, 1000 tokens and a cost of 1
+func testPolicy(epsilon float64) Policy {
+	return Policy{
+		MinUsers:        5,
+		Epsilon:         epsilon,
+		RequestsPerUser: 100,
+		TokensPerUser:   1000,
+		CostPerUser:     1,
+		Secret:          []byte("secret"),
+	}
+}
+
+func TestClip(t *testing.T) {
+	policy := testPolicy(1)
+
+	tests := []struct {
+		name         string
+		contribution Contribution
+		want         Contribution
+	}{
+		{
+			"within bounds",
+			Contribution{Requests: 10, ClientErrors: 2, ServerErrors: 1, Tokens: 500, Cost: 0.5},
+			Contribution{Requests: 10, ClientErrors: 2, ServerErrors: 1, Tokens: 500, Cost: 0.5},
+		},
+		{
+			"at bounds",
+			Contribution{Requests: 100, Tokens: 1000, Cost: 1},
+			Contribution{Requests: 100, Tokens: 1000, Cost: 1},
+		},
+		{
+			// Errors keep their share of the requests
+			"over bounds",
+			Contribution{Requests: 400, ClientErrors: 200, ServerErrors: 100, Tokens: 50000, Cost: 12.5},
+			Contribution{Requests: 100, ClientErrors: 50, ServerErrors: 25, Tokens: 1000, Cost: 1},
+		},
+		{
+			"only errors",
+			Contribution{Requests: 1000, ServerErrors: 1000},
+			Contribution{Requests: 100, ServerErrors: 100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Clip(tt.contribution))
+		})
+	}
+}
+
+func TestAggregateClipsEachUser(t *testing.T) {
+	policy := testPolicy(1)
+
+	bucket := policy.Aggregate([]Contribution{
+		{Requests: 10, Tokens: 100, Cost: 0.1},
+		{Requests: 20, ClientErrors: 4, Tokens: 200, Cost: 0.2},
+		// A heavy user counts no more than the bounds
+		{Requests: 100000, ServerErrors: 50000, Tokens: 10000000, Cost: 500},
+	})
+	assert.Equal(t, int64(3), bucket.Users)
+	assert.Equal(t, int64(130), bucket.Requests)
+	assert.Equal(t, int64(4), bucket.ClientErrors)
+	assert.Equal(t, int64(50), bucket.ServerErrors)
+	assert.Equal(t, int64(1300), bucket.Tokens)
+	assert.InDelta(t, 1.3, bucket.Cost, 1e-9)
+
+	assert.Equal(t, Bucket{}, policy.Aggregate(nil))
+}
+
+func TestReleaseSuppressesBelowMinUsers(t *testing.T) {
+	policy := testPolicy(0)
+
+	bucket := Bucket{Users: 4, Requests: 40, Tokens: 4000, Cost: 4}
+	_, ok := policy.Release("w/1", bucket)
+	assert.False(t, ok)
+
+	// Without noise, buckets are released as they are from MinUsers on
+	bucket.Users = 5
+	released, ok := policy.Release("w/1", bucket)
+	require.True(t, ok)
+	assert.Equal(t, bucket, released)
+}
+
+func TestReleaseSuppressesNoisyUsersBelowMinUsers(t *testing.T) {
+	// Noise this small rounds away, leaving the threshold exact
+	policy := testPolicy(1e9)
+
+	released, ok := policy.Release("w/1", Bucket{Users: 4, Requests: 40})
+	assert.False(t, ok)
+	assert.Equal(t, Bucket{}, released)
+
+	released, ok = policy.Release("w/1", Bucket{Users: 5, Requests: 40})
+	require.True(t, ok)
+	assert.Equal(t, int64(5), released.Users)
+	assert.Equal(t, int64(40), released.Requests)
+
+	// With noise, buckets around the threshold are suppressed by their noisy number of
+	// users, so some with enough users are suppressed and some with too few released
+	policy = testPolicy(1)
+	var suppressed, released4 int
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("w/%d", i)
+		if _, ok := policy.Release(id, Bucket{Users: 6}); !ok {
+			suppressed++
+		}
+		if _, ok := policy.Release(id, Bucket{Users: 4}); ok {
+			released4++
+		}
+	}
+	assert.Greater(t, suppressed, 0)
+	assert.Greater(t, released4, 0)
+}
+
+func TestLaplaceScale(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	const samples = 200000
+
+	// The mean absolute deviation of the Laplace distribution is its scale, and its mean 0
+	var sum, sumAbs float64
+	for i := 0; i < samples; i++ {
+		x := laplace(rnd, 3)
+		sum += x
+		sumAbs += math.Abs(x)
+	}
+	assert.InDelta(t, 0, sum/samples, 0.05)
+	assert.InDelta(t, 3, sumAbs/samples, 0.05)
+}
+
+func TestReleaseNoiseScale(t *testing.T) {
+	const samples = 20000
+	bucket := Bucket{Users: 1000000, Requests: 1000000, Tokens: 100000000, Cost: 1000000}
+
+	for _, epsilon := range []float64{0.5, 2} {
+		policy := testPolicy(epsilon)
+
+		// The noise of each value is scaled to its sensitivity, over its share of epsilon
+		var users, requests, tokens, cost float64
+		for i := 0; i < samples; i++ {
+			released, ok := policy.Release(fmt.Sprintf("w/%d", i), bucket)
+			require.True(t, ok)
+			users += math.Abs(float64(released.Users - bucket.Users))
+			requests += math.Abs(float64(released.Requests - bucket.Requests))
+			tokens += math.Abs(float64(released.Tokens - bucket.Tokens))
+			cost += math.Abs(released.Cost - bucket.Cost)
+		}
+		scale := releasedValues / epsilon
+		assert.InEpsilon(t, 1*scale, users/samples, 0.05, "epsilon %v", epsilon)
+		assert.InEpsilon(t, policy.RequestsPerUser*scale, requests/samples, 0.05, "epsilon %v", epsilon)
+		assert.InEpsilon(t, policy.TokensPerUser*scale, tokens/samples, 0.05, "epsilon %v", epsilon)
+		assert.InEpsilon(t, policy.CostPerUser*scale, cost/samples, 0.05, "epsilon %v", epsilon)
+	}
+}
+
+func TestReleaseNoiseSeeding(t *testing.T) {
+	policy := testPolicy(1)
+	bucket := Bucket{Users: 50, Requests: 5000, ClientErrors: 50, ServerErrors: 5, Tokens: 50000, Cost: 50}
+
+	// Releasing the same bucket again draws the same noise
+	first, ok := policy.Release("w/2024-01-01T00:00:00Z", bucket)
+	require.True(t, ok)
+	again, ok := policy.Release("w/2024-01-01T00:00:00Z", bucket)
+	require.True(t, ok)
+	assert.Equal(t, first, again)
+	assert.NotEqual(t, bucket, first)
+
+	// Other buckets, counts and secrets draw other noise
+	other, _ := policy.Release("w/2024-01-01T01:00:00Z", bucket)
+	assert.NotEqual(t, first, other)
+
+	grown := bucket
+	grown.Requests++
+	other, _ = policy.Release("w/2024-01-01T00:00:00Z", grown)
+	assert.NotEqual(t, first.Tokens, other.Tokens)
+
+	policy.Secret = []byte("other secret")
+	other, _ = policy.Release("w/2024-01-01T00:00:00Z", bucket)
+	assert.NotEqual(t, first, other)
+}
+
+func TestReleaseKeepsCountsPositive(t *testing.T) {
+	policy := testPolicy(0.1)
+	policy.MinUsers = 0
+
+	for i := 0; i < 1000; i++ {
+		released, ok := policy.Release(fmt.Sprintf("w/%d", i), Bucket{})
+		require.True(t, ok)
+		assert.GreaterOrEqual(t, released.Users, int64(0))
+		assert.GreaterOrEqual(t, released.Requests, int64(0))
+		assert.GreaterOrEqual(t, released.Tokens, int64(0))
+		assert.GreaterOrEqual(t, released.Cost, 0.0)
+	}
+}
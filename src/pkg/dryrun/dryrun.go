@@ -0,0 +1,19 @@
+// Package dryrun marks the requests that only validate a write: they run its validation,
+// policy checks and cost estimation, without persisting anything or calling the LLM.
+package dryrun
+
+import "context"
+
+// contextKey is the context key of the dry-run mark
+type contextKey struct{}
+
+// With returns a context marked as a dry run
+func With(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, true)
+}
+
+// Enabled reports whether ctx is marked as a dry run
+func Enabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(contextKey{}).(bool)
+	return enabled
+}
@@ -0,0 +1,359 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ConsumerConfig holds the settings of a Kafka group consumer
+type ConsumerConfig struct {
+	Brokers          []string
+	GroupID          string
+	Topic            string
+	Balancer         string // "sticky", "range", "roundrobin" or "rack"
+	SessionTimeout   time.Duration
+	RebalanceTimeout time.Duration
+}
+
+// kafkaProducer implements Producer with a Kafka writer
+type kafkaProducer struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaProducer creates a new Kafka producer. Messages with the same key are
+// written to the same partition so per-key ordering is preserved.
+func NewKafkaProducer(brokers []string) Producer {
+	return &kafkaProducer{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+// kafkaPingDialer dials brokers to check they are reachable
+var kafkaPingDialer = &kafka.Dialer{Timeout: 5 * time.Second}
+
+// PingKafka checks that one of the brokers accepts connections. The Kafka producer and
+// consumers only connect when first used, so this lets startup wait for the cluster.
+func PingKafka(ctx context.Context, brokers []string) error {
+	var err error
+	for _, broker := range brokers {
+		var conn *kafka.Conn
+		if conn, err = kafkaPingDialer.DialContext(ctx, "tcp", broker); err == nil {
+			return conn.Close()
+		}
+	}
+	return err
+}
+
+// Produce publishes messages to Kafka
+func (p *kafkaProducer) Produce(ctx context.Context, messages ...Message) error {
+	records := make([]kafka.Message, len(messages))
+	for i, msg := range messages {
+		records[i] = kafka.Message{
+			Topic:   msg.Topic,
+			Key:     msg.Key,
+			Value:   msg.Value,
+			Headers: toKafkaHeaders(msg.Headers),
+		}
+	}
+	return p.writer.WriteMessages(ctx, records...)
+}
+
+// Close flushes pending messages and closes the writer
+func (p *kafkaProducer) Close() error {
+	return p.writer.Close()
+}
+
+// kafkaConsumer implements Consumer with a Kafka group reader
+type kafkaConsumer struct {
+	reader *kafka.Reader
+	// sticky tracks the partitions consumed, with the sticky balancer
+	sticky *stickyBalancer
+}
+
+// NewKafkaConsumer creates a new Kafka group consumer. Offsets are committed
+// synchronously and only for processed messages, so a partition moving to another
+// instance during a rebalance resumes exactly after the last processed message.
+//
+// kafka-go only implements the eager rebalance protocol: every rebalance stops the
+// consumption of all the partitions of the group until they are assigned again, and
+// incremental cooperative rebalancing is not available. The sticky balancer keeps
+// partitions with the members that consumed them, so that a deploy only moves the
+// partitions of the instances restarted.
+func NewKafkaConsumer(config ConsumerConfig) Consumer {
+	consumer := &kafkaConsumer{}
+	var balancers []kafka.GroupBalancer
+	if config.Balancer == "sticky" {
+		consumer.sticky = newStickyBalancer()
+		// Members without the sticky balancer, such as instances of a previous version
+		// during a rolling deploy, agree on range with the others
+		balancers = []kafka.GroupBalancer{consumer.sticky, kafka.RangeGroupBalancer{}}
+	} else {
+		balancers = groupBalancers(config.Balancer)
+	}
+
+	consumer.reader = kafka.NewReader(kafka.ReaderConfig{
+		Brokers:          config.Brokers,
+		GroupID:          config.GroupID,
+		Topic:            config.Topic,
+		GroupBalancers:   balancers,
+		SessionTimeout:   config.SessionTimeout,
+		RebalanceTimeout: config.RebalanceTimeout,
+		StartOffset:      kafka.FirstOffset,
+		CommitInterval:   0,
+	})
+	return consumer
+}
+
+// Fetch reads the next message without committing it
+func (c *kafkaConsumer) Fetch(ctx context.Context) (Message, error) {
+	record, err := c.reader.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	if c.sticky != nil {
+		c.sticky.consumed(record.Topic, record.Partition)
+	}
+
+	headers := make(map[string]string, len(record.Headers))
+	for _, header := range record.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+
+	return Message{
+		Topic:     record.Topic,
+		Partition: record.Partition,
+		Offset:    record.Offset,
+		Key:       record.Key,
+		Value:     record.Value,
+		Headers:   headers,
+		Time:      record.Time,
+	}, nil
+}
+
+// Commit commits the offsets of processed messages
+func (c *kafkaConsumer) Commit(ctx context.Context, messages ...Message) error {
+	records := make([]kafka.Message, len(messages))
+	for i, msg := range messages {
+		records[i] = kafka.Message{
+			Topic:     msg.Topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+		}
+	}
+	return c.reader.CommitMessages(ctx, records...)
+}
+
+// Close leaves the consumer group and closes the reader
+func (c *kafkaConsumer) Close() error {
+	return c.reader.Close()
+}
+
+// groupBalancers returns the partition assignment strategies for the balancer name
+func groupBalancers(name string) []kafka.GroupBalancer {
+	switch name {
+	case "roundrobin":
+		return []kafka.GroupBalancer{kafka.RoundRobinGroupBalancer{}}
+	case "rack":
+		return []kafka.GroupBalancer{kafka.RackAffinityGroupBalancer{}, kafka.RangeGroupBalancer{}}
+	default:
+		return []kafka.GroupBalancer{kafka.RangeGroupBalancer{}}
+	}
+}
+
+// stickyBalancer assigns partitions evenly, keeping them with the members that consumed
+// them before the rebalance. Members claim the partitions they consumed in their user
+// data; kafka-go does not report assignments, so partitions without messages since the
+// previous rebalance are not claimed and may move.
+type stickyBalancer struct {
+	mu sync.Mutex
+	// owned are the partitions consumed since the previous join
+	owned map[string][]int
+	// claimed are the partitions claimed by the previous join
+	claimed map[string][]int
+}
+
+// stickyUserData is the user data of the members of the sticky balancer
+type stickyUserData struct {
+	Owned map[string][]int `json:"owned"`
+	// Stale marks claims repeated from a previous join, as nothing was consumed since
+	Stale bool `json:"stale,omitempty"`
+}
+
+// newStickyBalancer creates a sticky balancer without partitions
+func newStickyBalancer() *stickyBalancer {
+	return &stickyBalancer{owned: make(map[string][]int)}
+}
+
+// consumed records that a partition of a topic was consumed
+func (b *stickyBalancer) consumed(topic string, partition int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !slices.Contains(b.owned[topic], partition) {
+		b.owned[topic] = append(b.owned[topic], partition)
+	}
+}
+
+// ProtocolName names the assignment strategy; it differs from the sticky strategy of the
+// Java client, whose user data has another format
+func (b *stickyBalancer) ProtocolName() string {
+	return "chat-sticky"
+}
+
+// UserData claims the partitions consumed since the previous join, for the rebalance
+// starting. Joins are retried, and members may be assigned partitions without messages,
+// so without anything consumed since, the claims of the previous join are repeated as
+// stale claims, which give way to the claims of other members.
+func (b *stickyBalancer) UserData() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.owned) == 0 {
+		return json.Marshal(stickyUserData{Owned: b.claimed, Stale: true})
+	}
+	b.claimed, b.owned = b.owned, make(map[string][]int)
+	return json.Marshal(stickyUserData{Owned: b.claimed})
+}
+
+// AssignGroups gives each member of a topic the partitions it claims, up to its share,
+// then spreads the other partitions over the members with the fewest
+func (b *stickyBalancer) AssignGroups(members []kafka.GroupMember, partitions []kafka.Partition) kafka.GroupMemberAssignments {
+	assignments := kafka.GroupMemberAssignments{}
+	for _, member := range members {
+		assignments[member.ID] = map[string][]int{}
+	}
+
+	topics := make(map[string][]int)
+	for _, partition := range partitions {
+		topics[partition.Topic] = append(topics[partition.Topic], partition.ID)
+	}
+	for topic, ids := range topics {
+		var consumers []kafka.GroupMember
+		for _, member := range members {
+			if slices.Contains(member.Topics, topic) {
+				consumers = append(consumers, member)
+			}
+		}
+		if len(consumers) == 0 {
+			continue
+		}
+		slices.SortFunc(consumers, func(a, b kafka.GroupMember) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+		slices.Sort(ids)
+
+		for id, partitions := range assignSticky(consumers, topic, ids) {
+			assignments[id][topic] = partitions
+		}
+	}
+	return assignments
+}
+
+// assignSticky assigns the partitions of a topic to its consumers, sorted by ID. Each
+// consumer gets len(partitions)/len(consumers) partitions, and the first remaining ones
+// one more, preferably those the consumer claims.
+func assignSticky(consumers []kafka.GroupMember, topic string, partitions []int) map[string][]int {
+	base, extra := len(partitions)/len(consumers), len(partitions)%len(consumers)
+	assigned := make(map[string][]int, len(consumers))
+	taken := make(map[int]bool, len(partitions))
+
+	claims := make([][]int, len(consumers))
+	stale := make([]bool, len(consumers))
+	fresh := make(map[int]bool)
+	for i, member := range consumers {
+		var data stickyUserData
+		// Members claiming nothing readable are assigned like new members
+		if json.Unmarshal(member.UserData, &data) != nil {
+			continue
+		}
+		stale[i] = data.Stale
+		for _, id := range data.Owned[topic] {
+			if slices.Contains(partitions, id) && !slices.Contains(claims[i], id) {
+				claims[i] = append(claims[i], id)
+				fresh[id] = fresh[id] || !data.Stale
+			}
+		}
+	}
+	// Stale claims give way to fresh ones, as the partition moved since
+	for i := range consumers {
+		if stale[i] {
+			claims[i] = slices.DeleteFunc(claims[i], func(id int) bool { return fresh[id] })
+		}
+	}
+
+	// Claims are kept up to the base share first, then up to one more while the
+	// remainder lasts, so that no claim takes the share of another member
+	keep := func(i, limit int) {
+		for _, id := range claims[i] {
+			if len(assigned[consumers[i].ID]) >= limit {
+				return
+			}
+			if !taken[id] {
+				taken[id] = true
+				assigned[consumers[i].ID] = append(assigned[consumers[i].ID], id)
+			}
+		}
+	}
+	for i := range consumers {
+		keep(i, base)
+	}
+	for i := range consumers {
+		if extra > 0 && len(assigned[consumers[i].ID]) == base {
+			keep(i, base+1)
+			if len(assigned[consumers[i].ID]) > base {
+				extra--
+			}
+		}
+	}
+
+	// The partitions left go to the consumers with the fewest, within their share
+	for _, id := range partitions {
+		if taken[id] {
+			continue
+		}
+		best := -1
+		for i, member := range consumers {
+			count := len(assigned[member.ID])
+			if count > base || (count == base && extra == 0) {
+				continue
+			}
+			if best < 0 || count < len(assigned[consumers[best].ID]) {
+				best = i
+			}
+		}
+		if len(assigned[consumers[best].ID]) == base {
+			extra--
+		}
+		taken[id] = true
+		assigned[consumers[best].ID] = append(assigned[consumers[best].ID], id)
+	}
+
+	for id := range assigned {
+		slices.Sort(assigned[id])
+	}
+	return assigned
+}
+
+// toKafkaHeaders converts a header map to Kafka record headers
+func toKafkaHeaders(headers map[string]string) []kafka.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	result := make([]kafka.Header, 0, len(headers))
+	for key, value := range headers {
+		result = append(result, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	return result
+}
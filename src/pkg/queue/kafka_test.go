@@ -0,0 +1,217 @@
+package queue
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// claiming returns a member of topic t claiming partitions, with the user data of the
+// sticky balancer
+func claiming(id string, stale bool, partitions ...int) kafka.GroupMember {
+	data, _ := json.Marshal(stickyUserData{Owned: map[string][]int{"t": partitions}, Stale: stale})
+	return kafka.GroupMember{ID: id, Topics: []string{"t"}, UserData: data}
+}
+
+// withUserData returns a member of topic t with raw user data
+func withUserData(id string, data string) kafka.GroupMember {
+	return kafka.GroupMember{ID: id, Topics: []string{"t"}, UserData: []byte(data)}
+}
+
+// requireBalanced checks that every partition is assigned once, and that shares differ by
+// one at most
+func requireBalanced(t *testing.T, consumers int, partitions []int, assigned map[string][]int) {
+	seen := make(map[int]string)
+	for id, ids := range assigned {
+		assert.GreaterOrEqual(t, len(ids), len(partitions)/consumers, "share of %s", id)
+		assert.LessOrEqual(t, len(ids), (len(partitions)+consumers-1)/consumers, "share of %s", id)
+		for _, partition := range ids {
+			owner, ok := seen[partition]
+			require.False(t, ok, "partition %d assigned to %s and %s", partition, owner, id)
+			seen[partition] = id
+		}
+	}
+	assert.Len(t, seen, len(partitions))
+}
+
+func TestAssignSticky(t *testing.T) {
+	tests := []struct {
+		name       string
+		consumers  []kafka.GroupMember
+		partitions []int
+		want       map[string][]int
+	}{
+		{
+			"new members",
+			[]kafka.GroupMember{claiming("a", false), claiming("b", false), claiming("c", false)},
+			[]int{0, 1, 2, 3, 4, 5},
+			map[string][]int{"a": {0, 3}, "b": {1, 4}, "c": {2, 5}},
+		},
+		{
+			"claims kept",
+			[]kafka.GroupMember{claiming("a", false, 4, 5), claiming("b", false, 0, 1), claiming("c", false, 2, 3)},
+			[]int{0, 1, 2, 3, 4, 5},
+			map[string][]int{"a": {4, 5}, "b": {0, 1}, "c": {2, 3}},
+		},
+		{
+			// The new member takes a partition from each member beyond its share
+			"member joining",
+			[]kafka.GroupMember{claiming("a", false, 0, 1, 2, 3), claiming("b", false, 4, 5, 6, 7), claiming("c", false)},
+			[]int{0, 1, 2, 3, 4, 5, 6, 7},
+			map[string][]int{"a": {0, 1, 2}, "b": {4, 5, 6}, "c": {3, 7}},
+		},
+		{
+			// Only the partitions of the member leaving move
+			"member leaving",
+			[]kafka.GroupMember{claiming("a", false, 0, 1), claiming("b", false, 2, 3)},
+			[]int{0, 1, 2, 3, 4, 5},
+			map[string][]int{"a": {0, 1, 4}, "b": {2, 3, 5}},
+		},
+		{
+			"uneven partitions",
+			[]kafka.GroupMember{claiming("a", false, 0, 1, 2), claiming("b", false, 3, 4, 5), claiming("c", false, 6)},
+			[]int{0, 1, 2, 3, 4, 5, 6},
+			map[string][]int{"a": {0, 1, 2}, "b": {3, 4}, "c": {5, 6}},
+		},
+		{
+			"more members than partitions",
+			[]kafka.GroupMember{claiming("a", false), claiming("b", false, 1), claiming("c", false)},
+			[]int{0, 1},
+			map[string][]int{"a": {0}, "b": {1}},
+		},
+		{
+			"partitions claimed twice",
+			[]kafka.GroupMember{claiming("a", false, 0, 1), claiming("b", false, 0, 1)},
+			[]int{0, 1, 2, 3},
+			map[string][]int{"a": {0, 1}, "b": {2, 3}},
+		},
+		{
+			"partition claimed twice in a claim",
+			[]kafka.GroupMember{claiming("a", false, 0, 0, 0), claiming("b", false)},
+			[]int{0, 1, 2, 3},
+			map[string][]int{"a": {0, 2}, "b": {1, 3}},
+		},
+		{
+			// The partition moved to b since the claims of a
+			"stale claims give way",
+			[]kafka.GroupMember{claiming("a", true, 0, 1), claiming("b", false, 0)},
+			[]int{0, 1, 2, 3},
+			map[string][]int{"a": {1, 2}, "b": {0, 3}},
+		},
+		{
+			"stale claims kept",
+			[]kafka.GroupMember{claiming("a", true, 2, 3), claiming("b", false)},
+			[]int{0, 1, 2, 3},
+			map[string][]int{"a": {2, 3}, "b": {0, 1}},
+		},
+		{
+			"unknown partitions",
+			[]kafka.GroupMember{claiming("a", false, 8, 9), claiming("b", false, 1)},
+			[]int{0, 1, 2, 3},
+			map[string][]int{"a": {0, 2}, "b": {1, 3}},
+		},
+		{
+			"empty user data",
+			[]kafka.GroupMember{withUserData("a", ""), withUserData("b", "{}")},
+			[]int{0, 1, 2, 3},
+			map[string][]int{"a": {0, 2}, "b": {1, 3}},
+		},
+		{
+			"unreadable user data",
+			[]kafka.GroupMember{withUserData("a", "garbage"), withUserData("b", `{"owned": {"t": "2"}}`), claiming("c", false, 0)},
+			[]int{0, 1, 2},
+			map[string][]int{"a": {1}, "b": {2}, "c": {0}},
+		},
+		{
+			"claims of other topics",
+			[]kafka.GroupMember{withUserData("a", `{"owned": {"other": [2, 3]}}`), claiming("b", false)},
+			[]int{0, 1, 2, 3},
+			map[string][]int{"a": {0, 2}, "b": {1, 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assigned := assignSticky(tt.consumers, "t", tt.partitions)
+			assert.Equal(t, tt.want, assigned)
+			requireBalanced(t, len(tt.consumers), tt.partitions, assigned)
+		})
+	}
+}
+
+func TestAssignStickyRebalances(t *testing.T) {
+	partitions := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	// Members join and leave one at a time, each claiming what it was assigned
+	assigned := map[string][]int{}
+	for _, ids := range [][]string{{"a"}, {"a", "b"}, {"a", "b", "c"}, {"b", "c"}, {"b", "c", "d", "e"}, {"e"}} {
+		consumers := make([]kafka.GroupMember, len(ids))
+		for i, id := range ids {
+			consumers[i] = claiming(id, false, assigned[id]...)
+		}
+
+		next := assignSticky(consumers, "t", partitions)
+		requireBalanced(t, len(ids), partitions, next)
+
+		// Members keep their partitions up to their share
+		for _, id := range ids {
+			kept := 0
+			for _, partition := range next[id] {
+				if slices.Contains(assigned[id], partition) {
+					kept++
+				}
+			}
+			assert.Equal(t, min(len(assigned[id]), len(next[id])), kept, "members %v, member %s", ids, id)
+		}
+		assigned = next
+	}
+}
+
+func TestStickyBalancerAssignGroups(t *testing.T) {
+	balancer := newStickyBalancer()
+	members := []kafka.GroupMember{
+		{ID: "b", Topics: []string{"t", "u"}},
+		{ID: "a", Topics: []string{"t"}},
+		{ID: "c"},
+	}
+	partitions := []kafka.Partition{
+		{Topic: "t", ID: 1}, {Topic: "t", ID: 0}, {Topic: "u", ID: 0}, {Topic: "u", ID: 1},
+	}
+
+	// Topics are assigned to their consumers only, in the order of their IDs
+	assert.Equal(t, kafka.GroupMemberAssignments{
+		"a": {"t": {0}},
+		"b": {"t": {1}, "u": {0, 1}},
+		"c": {},
+	}, balancer.AssignGroups(members, partitions))
+}
+
+func TestStickyBalancerUserData(t *testing.T) {
+	balancer := newStickyBalancer()
+	userData := func() stickyUserData {
+		raw, err := balancer.UserData()
+		require.NoError(t, err)
+		var data stickyUserData
+		require.NoError(t, json.Unmarshal(raw, &data))
+		return data
+	}
+
+	// A new member claims nothing
+	assert.Equal(t, stickyUserData{Stale: true}, userData())
+
+	balancer.consumed("t", 1)
+	balancer.consumed("t", 0)
+	balancer.consumed("t", 1)
+	assert.Equal(t, stickyUserData{Owned: map[string][]int{"t": {1, 0}}}, userData())
+
+	// A join retried before consuming anything claims the same partitions, as stale
+	assert.Equal(t, stickyUserData{Owned: map[string][]int{"t": {1, 0}}, Stale: true}, userData())
+	assert.Equal(t, stickyUserData{Owned: map[string][]int{"t": {1, 0}}, Stale: true}, userData())
+
+	// The partitions consumed since replace them
+	balancer.consumed("t", 2)
+	assert.Equal(t, stickyUserData{Owned: map[string][]int{"t": {2}}}, userData())
+}
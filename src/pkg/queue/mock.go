@@ -0,0 +1,31 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// mockProducer is a Producer that only logs, used when no broker is configured
+type mockProducer struct{}
+
+// NewMockProducer creates a Producer that logs messages instead of publishing them
+func NewMockProducer() Producer {
+	return &mockProducer{}
+}
+
+// Produce logs the messages
+func (p *mockProducer) Produce(ctx context.Context, messages ...Message) error {
+	for _, msg := range messages {
+		logger.Context(ctx).Infow("Mock: Producing message",
+			"topic", msg.Topic,
+			"key", string(msg.Key),
+			"headers", msg.Headers)
+	}
+	return nil
+}
+
+// Close does nothing
+func (p *mockProducer) Close() error {
+	return nil
+}
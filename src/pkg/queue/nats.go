@@ -0,0 +1,76 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// HeaderKey carries the message key on brokers without native keys
+const HeaderKey = "X-Message-Key"
+
+// natsProducer implements Producer with NATS JetStream
+type natsProducer struct {
+	conn          *nats.Conn
+	js            nats.JetStreamContext
+	subjectPrefix string
+}
+
+// NewNATSProducer connects to NATS and creates a JetStream producer. The stream is
+// created if it does not exist, capturing every subject under subjectPrefix.
+// Topics are published to the subject subjectPrefix+topic.
+func NewNATSProducer(url, stream, subjectPrefix string) (Producer, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	if _, err := js.StreamInfo(stream); err != nil {
+		if _, err := js.AddStream(&nats.StreamConfig{
+			Name:     stream,
+			Subjects: []string{subjectPrefix + ">"},
+			Storage:  nats.FileStorage,
+		}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to create JetStream stream %s: %w", stream, err)
+		}
+	}
+
+	return &natsProducer{
+		conn:          conn,
+		js:            js,
+		subjectPrefix: subjectPrefix,
+	}, nil
+}
+
+// Produce publishes messages to JetStream, waiting for each to be acknowledged
+// so messages of the same key stay ordered
+func (p *natsProducer) Produce(ctx context.Context, messages ...Message) error {
+	for _, msg := range messages {
+		record := nats.NewMsg(p.subjectPrefix + msg.Topic)
+		record.Data = msg.Value
+		for key, value := range msg.Headers {
+			record.Header.Set(key, value)
+		}
+		if len(msg.Key) > 0 {
+			record.Header.Set(HeaderKey, string(msg.Key))
+		}
+
+		if _, err := p.js.PublishMsg(record, nats.Context(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes pending messages and closes the connection
+func (p *natsProducer) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Message is a record produced to or consumed from a topic
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Time      time.Time
+}
+
+// Producer publishes messages to topics
+type Producer interface {
+	// Produce publishes messages, each to its own topic
+	Produce(ctx context.Context, messages ...Message) error
+
+	// Close flushes pending messages and releases resources
+	Close() error
+}
+
+// Consumer reads messages of a topic as a member of a consumer group
+type Consumer interface {
+	// Fetch blocks until the next message is available or ctx is done
+	Fetch(ctx context.Context) (Message, error)
+
+	// Commit marks messages as processed for the consumer group
+	Commit(ctx context.Context, messages ...Message) error
+
+	// Close leaves the consumer group and releases resources
+	Close() error
+}
@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// rabbitMQProducer implements Producer with a RabbitMQ topic exchange
+type rabbitMQProducer struct {
+	conn     *amqp.Connection
+	channel  *amqp.Channel
+	exchange string
+	// mu serializes publishing; channels are not safe for concurrent use
+	mu sync.Mutex
+}
+
+// NewRabbitMQProducer connects to RabbitMQ and declares a durable topic exchange.
+// Topics are used as routing keys and publishes are confirmed by the broker.
+func NewRabbitMQProducer(url, exchange string) (Producer, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open RabbitMQ channel: %w", err)
+	}
+
+	if err := channel.ExchangeDeclare(exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to declare RabbitMQ exchange %s: %w", exchange, err)
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable RabbitMQ publisher confirms: %w", err)
+	}
+
+	return &rabbitMQProducer{
+		conn:     conn,
+		channel:  channel,
+		exchange: exchange,
+	}, nil
+}
+
+// Produce publishes persistent messages, waiting for each to be confirmed
+func (p *rabbitMQProducer) Produce(ctx context.Context, messages ...Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, msg := range messages {
+		headers := make(amqp.Table, len(msg.Headers)+1)
+		for key, value := range msg.Headers {
+			headers[key] = value
+		}
+		if len(msg.Key) > 0 {
+			headers[HeaderKey] = string(msg.Key)
+		}
+
+		confirmation, err := p.channel.PublishWithDeferredConfirmWithContext(ctx, p.exchange, msg.Topic, false, false, amqp.Publishing{
+			Headers:      headers,
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			Body:         msg.Value,
+		})
+		if err != nil {
+			return err
+		}
+
+		acked, err := confirmation.WaitContext(ctx)
+		if err != nil {
+			return err
+		}
+		if !acked {
+			return fmt.Errorf("RabbitMQ rejected message for %s", msg.Topic)
+		}
+	}
+	return nil
+}
+
+// Close closes the channel and connection
+func (p *rabbitMQProducer) Close() error {
+	if err := p.channel.Close(); err != nil {
+		p.conn.Close()
+		return err
+	}
+	return p.conn.Close()
+}
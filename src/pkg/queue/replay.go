@@ -0,0 +1,137 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// replayIdleTimeout is how long a replay waits for the next message of a partition before
+// considering it read up to its end
+const replayIdleTimeout = 30 * time.Second
+
+// ReplayConfig holds the settings of reading back the history of a Kafka topic
+type ReplayConfig struct {
+	Brokers []string
+	Topic   string
+	// Offsets are the offsets partitions are read from; partitions missing from it start at
+	// Since, or at their first offset when Since is zero
+	Offsets map[int]int64
+	Since   time.Time
+}
+
+// ReplayKafka reads the messages of a topic outside of any consumer group, so no committed
+// offset moves, and calls fn with each. Partitions are read one after another, each in order,
+// up to the last offset it had when the replay started. Messages still retained before the
+// start offsets are skipped; a fn error stops the replay.
+func ReplayKafka(ctx context.Context, config ReplayConfig, fn func(msg *Message) error) error {
+	if len(config.Brokers) == 0 {
+		return fmt.Errorf("no Kafka brokers configured")
+	}
+
+	conn, err := kafka.DialContext(ctx, "tcp", config.Brokers[0])
+	if err != nil {
+		return fmt.Errorf("failed to connect to Kafka: %w", err)
+	}
+	partitions, err := conn.ReadPartitions(config.Topic)
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read partitions of %s: %w", config.Topic, err)
+	}
+
+	ids := make([]int, len(partitions))
+	for i, partition := range partitions {
+		ids[i] = partition.ID
+	}
+	sort.Ints(ids)
+
+	for _, partition := range ids {
+		start, end, err := replayRange(ctx, config, partition)
+		if err != nil {
+			return err
+		}
+		if start >= end {
+			continue
+		}
+		if err := replayPartition(ctx, config, partition, start, end, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayRange returns the offsets a partition is replayed from and up to, excluded
+func replayRange(ctx context.Context, config ReplayConfig, partition int) (int64, int64, error) {
+	conn, err := kafka.DialLeader(ctx, "tcp", config.Brokers[0], config.Topic, partition)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to connect to the leader of partition %d: %w", partition, err)
+	}
+	defer conn.Close()
+
+	first, end, err := conn.ReadOffsets()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read offsets of partition %d: %w", partition, err)
+	}
+
+	start := first
+	if offset, ok := config.Offsets[partition]; ok {
+		start = max(offset, first)
+	} else if !config.Since.IsZero() {
+		if start, err = conn.ReadOffset(config.Since); err != nil {
+			return 0, 0, fmt.Errorf("failed to find the offset of partition %d at %s: %w", partition, config.Since, err)
+		}
+	}
+	return start, end, nil
+}
+
+// replayPartition reads the messages of a partition from start up to end, excluded
+func replayPartition(ctx context.Context, config ReplayConfig, partition int, start, end int64, fn func(msg *Message) error) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   config.Brokers,
+		Topic:     config.Topic,
+		Partition: partition,
+	})
+	defer reader.Close()
+
+	if err := reader.SetOffset(start); err != nil {
+		return fmt.Errorf("failed to seek partition %d to offset %d: %w", partition, start, err)
+	}
+
+	for {
+		// Compacted topics can have gaps up to the end, so a partition with nothing left to
+		// read before the end is done
+		fetchCtx, cancel := context.WithTimeout(ctx, replayIdleTimeout)
+		record, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			return fmt.Errorf("failed to read partition %d: %w", partition, err)
+		}
+
+		headers := make(map[string]string, len(record.Headers))
+		for _, header := range record.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+		if err := fn(&Message{
+			Topic:     record.Topic,
+			Partition: record.Partition,
+			Offset:    record.Offset,
+			Key:       record.Key,
+			Value:     record.Value,
+			Headers:   headers,
+			Time:      record.Time,
+		}); err != nil {
+			return err
+		}
+
+		if record.Offset >= end-1 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package redact scrubs personally identifiable information from text, replacing it with
+// placeholders naming what was removed
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Placeholders of the information removed
+const (
+	Email = "[EMAIL]"
+	Phone = "[PHONE]"
+	Card  = "[CARD]"
+	IP    = "[IP]"
+	URL   = "[URL]"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// URLs are only scrubbed when they carry credentials or a query, which may identify users
+	urlPattern = regexp.MustCompile(`https?://[^\s/?#]*@[^\s]*|https?://[^\s?#]+\?[^\s]+`)
+	// Card numbers are 13 to 19 digits, optionally grouped by spaces or dashes
+	cardPattern  = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[ .\-]?)?(?:\(\d{1,4}\)[ .\-]?)?\d{2,4}(?:[ .\-]?\d{2,4}){2,4}`)
+	datePattern  = regexp.MustCompile(`^\d{4}[\-.]\d{2}[\-.]\d{2}$|^\d{2}[\-.]\d{2}[\-.]\d{4}$`)
+	ipv4Pattern  = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)
+)
+
+// PII returns text with email addresses, URLs with credentials or queries, card numbers,
+// IPv4 addresses and phone numbers replaced by placeholders. Digit runs are only taken for
+// card numbers when they pass the Luhn check, and for phone numbers when they have 7 to 15
+// digits and are not dates, so that dates, amounts and other numbers are mostly kept.
+func PII(text string) string {
+	text = emailPattern.ReplaceAllString(text, Email)
+	text = urlPattern.ReplaceAllString(text, URL)
+	text = cardPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if luhn(digits(match)) {
+			return Card
+		}
+		return match
+	})
+	text = ipv4Pattern.ReplaceAllString(text, IP)
+	text = phonePattern.ReplaceAllStringFunc(text, func(match string) string {
+		if n := len(digits(match)); n >= 7 && n <= 15 && !datePattern.MatchString(match) {
+			return Phone
+		}
+		return match
+	})
+	return text
+}
+
+// digits returns the digits of s
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// luhn reports whether a number passes the Luhn checksum of card numbers
+func luhn(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// draft is the JSON schema dialect of generated schemas
+const draft = "http://json-schema.org/draft-07/schema#"
+
+// timeType is handled as a formatted string rather than a struct
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generate builds a JSON schema document describing the JSON encoding of v.
+// Fields tagged omitempty and pointer fields are optional; other fields are required.
+func Generate(title string, v interface{}) ([]byte, error) {
+	document := generate(reflect.TypeOf(v))
+	document["$schema"] = draft
+	document["title"] = title
+	return json.Marshal(document)
+}
+
+// generate returns the schema of a Go type
+func generate(t reflect.Type) map[string]interface{} {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema map[string]interface{}
+	switch {
+	case t == timeType:
+		schema = map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		schema = generateObject(t)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		schema = map[string]interface{}{"type": "array", "items": generate(t.Elem())}
+	case t.Kind() == reflect.Map:
+		schema = map[string]interface{}{"type": "object", "additionalProperties": generate(t.Elem())}
+	default:
+		schema = map[string]interface{}{"type": primitiveType(t.Kind())}
+	}
+
+	if nullable {
+		if typ, ok := schema["type"].(string); ok {
+			schema["type"] = []string{typ, "null"}
+		}
+	}
+	return schema
+}
+
+// generateObject returns the schema of a struct type from its JSON tags
+func generateObject(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		optional := field.Type.Kind() == reflect.Ptr
+		if tag := field.Tag.Get("json"); tag != "" {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, option := range parts[1:] {
+				if option == "omitempty" {
+					optional = true
+				}
+			}
+		}
+
+		properties[name] = generate(field.Type)
+		if !optional {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// primitiveType maps a Go kind to its JSON schema type
+func primitiveType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,115 @@
+// Package timing records how long the stages of serving a request take, so that operators
+// can see where its latency goes, in metrics and in the Server-Timing header of responses.
+package timing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stages of sending a message
+const (
+	// StageAuth is the authentication of the request
+	StageAuth = "auth"
+	// StageDBRead is the reads checking the chat and the message before it is saved
+	StageDBRead = "db_read"
+	// StageLock is the wait for the lock serializing the messages of the chat
+	StageLock = "lock"
+	// StagePromptBuild is the building of the LLM request from the history of the chat
+	StagePromptBuild = "prompt_build"
+	// StageLLM is the LLM call generating the reply
+	StageLLM = "llm"
+	// StageDBWrite is the writes saving the messages
+	StageDBWrite = "db_write"
+	// StagePublish is the publishing of the message events
+	StagePublish = "publish"
+)
+
+// Stage is the time spent in a stage of a request, summed when it ran several times
+type Stage struct {
+	Name     string
+	Duration time.Duration
+}
+
+// recorder holds the stages of a request, recorded from any goroutine serving it
+type recorder struct {
+	start time.Time
+
+	mu     sync.Mutex
+	stages []Stage
+}
+
+// recorderKey is the context key of the recorder of a request
+type recorderKey struct{}
+
+// WithRecorder returns a context recording the stages started with it, from now on. A
+// context already recording stages is returned as is.
+func WithRecorder(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(recorderKey{}).(*recorder); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, recorderKey{}, &recorder{start: time.Now()})
+}
+
+// Start starts a stage of the request of ctx, and returns the function ending it. It does
+// nothing when ctx records no stages.
+func Start(ctx context.Context, name string) func() {
+	r, ok := ctx.Value(recorderKey{}).(*recorder)
+	if !ok {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		r.add(name, time.Since(start))
+	}
+}
+
+// add adds d to the time spent in a stage
+func (r *recorder) add(name string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.stages {
+		if r.stages[i].Name == name {
+			r.stages[i].Duration += d
+			return
+		}
+	}
+	r.stages = append(r.stages, Stage{Name: name, Duration: d})
+}
+
+// From returns the stages recorded for the request of ctx, in the order they first ran
+func From(ctx context.Context) []Stage {
+	r, ok := ctx.Value(recorderKey{}).(*recorder)
+	if !ok {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Stage(nil), r.stages...)
+}
+
+// Header returns the Server-Timing header value of the stages recorded for the request of
+// ctx, followed by the total time since recording started, or "" when ctx records no stages
+func Header(ctx context.Context) string {
+	r, ok := ctx.Value(recorderKey{}).(*recorder)
+	if !ok {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, stage := range From(ctx) {
+		fmt.Fprintf(&b, "%s;dur=%.1f, ", stage.Name, milliseconds(stage.Duration))
+	}
+	fmt.Fprintf(&b, "total;dur=%.1f", milliseconds(time.Since(r.start)))
+	return b.String()
+}
+
+// milliseconds returns d in fractional milliseconds, the unit of Server-Timing durations
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
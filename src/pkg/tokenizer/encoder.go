@@ -0,0 +1,182 @@
+package tokenizer
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Patterns splitting text into the pieces encoded separately, those of tiktoken without the
+// lookahead Go does not support, which only changes how runs of spaces split
+var (
+	cl100kPattern = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+	o200kPattern  = regexp.MustCompile(`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+`)
+)
+
+// patternFor returns the pattern of an encoding; unknown encodings split like cl100k_base
+func patternFor(encoding string) *regexp.Regexp {
+	if encoding == O200kBase {
+		return o200kPattern
+	}
+	return cl100kPattern
+}
+
+// encoder counts the tokens of an encoding, by byte pair encoding with the ranks of its
+// tokens, or estimated when they are not loaded
+type encoder struct {
+	pattern *regexp.Regexp
+	ranks   map[string]int
+	err     error // Loading the ranks failed
+}
+
+// count returns the number of tokens of text
+func (e *encoder) count(text string) int {
+	total := 0
+	for _, piece := range e.pattern.FindAllString(text, -1) {
+		if e.ranks == nil {
+			total += estimatePiece(piece)
+		} else {
+			total += e.countPiece(piece)
+		}
+	}
+	return total
+}
+
+// estimatePiece estimates the tokens of a piece: short ASCII pieces such as words are mostly
+// one token, while other scripts take about a token per character
+func estimatePiece(piece string) int {
+	bytesPerToken := 6
+	for i := 0; i < len(piece); i++ {
+		if piece[i] >= utf8.RuneSelf {
+			bytesPerToken = 3
+			break
+		}
+	}
+	return max(1, (len(piece)+bytesPerToken-1)/bytesPerToken)
+}
+
+// countPiece returns the number of tokens of a piece: the parts left once the adjacent parts
+// forming the lowest ranked token, the leftmost of equal ones, are merged, until no adjacent
+// parts form a token. Parts are linked in a list and the candidate merges kept in a heap, so
+// that each merge only ranks the pairs it changes and long pieces take O(n log n).
+func (e *encoder) countPiece(piece string) int {
+	if _, ok := e.ranks[piece]; ok {
+		return 1
+	}
+
+	// Parts start as single bytes; part i starts at byte i, and next[i] is where the part
+	// after it starts, len(piece) for the last one
+	n := len(piece)
+	next := make([]int, n)
+	prev := make([]int, n)
+	for i := range next {
+		next[i], prev[i] = i+1, i-1
+	}
+
+	merges := &mergeHeap{}
+	// push adds the merge of the part at left with the next one, when they form a token
+	push := func(left int) {
+		if left < 0 || next[left] >= n {
+			return
+		}
+		end := nextEnd(next, next[left], n)
+		if rank, ok := e.ranks[piece[left:end]]; ok {
+			heap.Push(merges, merge{rank: rank, left: left, right: next[left], end: end})
+		}
+	}
+	for i := 0; i < n; i++ {
+		push(i)
+	}
+
+	parts := n
+	for merges.Len() > 0 {
+		m := heap.Pop(merges).(merge)
+		// Merges of parts merged since they were pushed are stale
+		if next[m.left] != m.right || nextEnd(next, m.right, n) != m.end {
+			continue
+		}
+		next[m.left] = m.end
+		if m.end < n {
+			prev[m.end] = m.left
+		}
+		next[m.right] = -1
+		parts--
+
+		push(prev[m.left])
+		push(m.left)
+	}
+	return parts
+}
+
+// nextEnd returns where the part starting at i ends
+func nextEnd(next []int, i, n int) int {
+	if i >= n {
+		return n
+	}
+	return next[i]
+}
+
+// merge is a candidate merge of the part starting at left with the part after it, starting
+// at right and ending at end, into a token of rank
+type merge struct {
+	rank, left, right, end int
+}
+
+// mergeHeap orders candidate merges by rank, then from left to right
+type mergeHeap []merge
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].left < h[j].left
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(merge)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// loadRanks reads a tiktoken rank file, whose lines hold a base64 token and its rank
+func loadRanks(path string) (map[string]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ranks := map[string]int{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a token and its rank", path, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid token: %w", path, line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid rank: %w", path, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ranks, nil
+}
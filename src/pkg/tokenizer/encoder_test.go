@@ -0,0 +1,135 @@
+package tokenizer
+
+import (
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countPieceNaive counts the tokens of a piece by ranking every adjacent pair after each
+// merge, as byte pair encoding is defined
+func countPieceNaive(ranks map[string]int, piece string) int {
+	if _, ok := ranks[piece]; ok {
+		return 1
+	}
+
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, 0
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := ranks[piece[bounds[i]:bounds[i+2]]]; ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	return len(bounds) - 1
+}
+
+// testRanks ranks the tokens of a small vocabulary over the letters a to d, with merges of
+// equal pairs at several positions and tokens only reachable through others
+var testRanks = map[string]int{
+	"a": 0, "b": 1, "c": 2, "d": 3,
+	"ab": 4, "aa": 5, "bc": 6, "cd": 7,
+	"abc": 8, "aaa": 9, "aaaa": 10, "bcd": 11,
+	"abab": 12, "dd": 13, "ddd": 14, "abcd": 15,
+}
+
+func TestCountPiece(t *testing.T) {
+	e := &encoder{pattern: cl100kPattern, ranks: testRanks}
+
+	tests := []struct {
+		piece string
+		want  int
+	}{
+		{"a", 1},
+		{"abcd", 1},
+		// ab, cd, then abcd: bcd cannot form once ab is merged
+		{"abcdd", 2},
+		// The leftmost aa merges first: aa aa, then aaaa
+		{"aaaa", 1},
+		{"aaaaa", 2},
+		{"ababab", 2},
+		{"dcba", 4},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, e.countPiece(tt.piece), "piece %q", tt.piece)
+	}
+}
+
+func TestCountPieceMatchesNaive(t *testing.T) {
+	e := &encoder{pattern: cl100kPattern, ranks: testRanks}
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2000; i++ {
+		piece := make([]byte, rnd.Intn(40))
+		for j := range piece {
+			piece[j] = "abcd"[rnd.Intn(4)]
+		}
+		assert.Equal(t, countPieceNaive(testRanks, string(piece)), e.countPiece(string(piece)), "piece %q", piece)
+	}
+}
+
+func TestCountPieceLongRun(t *testing.T) {
+	e := &encoder{pattern: cl100kPattern, ranks: testRanks}
+
+	// A run this long took minutes when every merge ranked every pair again
+	start := time.Now()
+	assert.Equal(t, 50000, e.countPiece(strings.Repeat("abab", 50000)))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+// TestKnownCounts compares counts with those of tiktoken. Rank files are not distributed
+// with the service; set TOKENIZER_ENCODINGS_DIR to a directory holding cl100k_base.tiktoken
+// and o200k_base.tiktoken to run it.
+func TestKnownCounts(t *testing.T) {
+	dir := os.Getenv("TOKENIZER_ENCODINGS_DIR")
+	if dir == "" {
+		t.Skip("TOKENIZER_ENCODINGS_DIR is not set")
+	}
+	tokenizer := New(dir)
+
+	tests := []struct {
+		model string
+		text  string
+		want  int
+	}{
+		{"gpt-4", "hello world", 2},
+		{"gpt-4", "tiktoken is great!", 6},
+		{"gpt-4", "antidisestablishmentarianism", 6},
+		{"gpt-4", "2 + 2 = 4", 7},
+		{"gpt-4", "お誕生日おめでとう", 9},
+		{"gpt-4o", "hello world", 2},
+		{"gpt-4o", "antidisestablishmentarianism", 6},
+		{"gpt-4o", "2 + 2 = 4", 7},
+		{"gpt-4o", "お誕生日おめでとう", 8},
+	}
+	for _, tt := range tests {
+		require.NoError(t, tokenizer.Err(tt.model))
+		require.True(t, tokenizer.Exact(tt.model), "no rank file for %s", tt.model)
+		assert.Equal(t, tt.want, tokenizer.Count(tt.model, tt.text), "%s: %q", tt.model, tt.text)
+	}
+}
+
+func TestEstimatedCounts(t *testing.T) {
+	tokenizer := New("")
+
+	assert.False(t, tokenizer.Exact("gpt-4"))
+	// Short ASCII words are a token each, other scripts about a token per character
+	assert.Equal(t, 2, tokenizer.Count("gpt-4", "hello world"))
+	assert.Equal(t, 9, tokenizer.Count("gpt-4", "お誕生日おめでとう"))
+	// Every message takes 3 tokens, and 3 more prime the reply
+	assert.Equal(t, 3+3+1+2, tokenizer.CountTokens("gpt-4", []Message{{Role: "user", Content: "hello world"}}))
+}
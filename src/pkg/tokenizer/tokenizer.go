@@ -0,0 +1,136 @@
+package tokenizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Encodings
+const (
+	// Cl100kBase is the encoding of GPT-4 and GPT-3.5 models
+	Cl100kBase = "cl100k_base"
+	// O200kBase is the encoding of GPT-4o and later models
+	O200kBase = "o200k_base"
+)
+
+// modelEncodings maps model name prefixes to their encodings, more specific prefixes first
+var modelEncodings = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o", O200kBase},
+	{"gpt-4.1", O200kBase},
+	{"gpt-4.5", O200kBase},
+	{"gpt-5", O200kBase},
+	{"o1", O200kBase},
+	{"o3", O200kBase},
+	{"o4", O200kBase},
+	{"gpt-4", Cl100kBase},
+	{"gpt-3.5", Cl100kBase},
+	{"text-embedding-3", Cl100kBase},
+	{"text-embedding-ada-002", Cl100kBase},
+}
+
+// Tokens added by chat formatting: around every message, for the name of a message, and
+// priming the reply
+const (
+	tokensPerMessage = 3
+	tokensPerName    = 1
+	tokensPerReply   = 3
+)
+
+// Message is a message of a chat prompt
+type Message struct {
+	Role    string
+	Content string
+	Name    string // Function or tool of tool and function results, when set
+}
+
+// Tokenizer counts the tokens of text as the encodings of models split it. Encodings are
+// exact when their tiktoken rank file, such as cl100k_base.tiktoken, is in the encodings
+// directory; otherwise, and for models of other vendors, counts are estimated from the
+// pieces the text splits into. Encoders are loaded once and shared.
+type Tokenizer struct {
+	dir      string
+	mu       sync.Mutex
+	encoders map[string]*encoder
+}
+
+// New creates a tokenizer reading rank files from dir; an empty dir estimates every count
+func New(dir string) *Tokenizer {
+	return &Tokenizer{
+		dir:      dir,
+		encoders: map[string]*encoder{},
+	}
+}
+
+// EncodingFor returns the encoding of a model, or an empty string when it is unknown
+func EncodingFor(model string) string {
+	model = strings.ToLower(model)
+	for _, entry := range modelEncodings {
+		if strings.HasPrefix(model, entry.prefix) {
+			return entry.encoding
+		}
+	}
+	return ""
+}
+
+// Exact reports whether the token counts of a model are exact rather than estimated
+func (t *Tokenizer) Exact(model string) bool {
+	return t.encoder(EncodingFor(model)).ranks != nil
+}
+
+// Count returns the number of tokens of text for a model
+func (t *Tokenizer) Count(model, text string) int {
+	return t.encoder(EncodingFor(model)).count(text)
+}
+
+// CountMessage returns the number of tokens a message takes in a chat prompt for a model
+func (t *Tokenizer) CountMessage(model string, message Message) int {
+	encoder := t.encoder(EncodingFor(model))
+	count := tokensPerMessage + encoder.count(message.Role) + encoder.count(message.Content)
+	if message.Name != "" {
+		count += tokensPerName + encoder.count(message.Name)
+	}
+	return count
+}
+
+// CountTokens returns the number of tokens of a chat prompt for a model, including those
+// priming the reply
+func (t *Tokenizer) CountTokens(model string, messages []Message) int {
+	total := tokensPerReply
+	for _, message := range messages {
+		total += t.CountMessage(model, message)
+	}
+	return total
+}
+
+// encoder returns the encoder of an encoding, loading it on first use. Encodings without a
+// rank file, and the empty encoding, get an estimating encoder.
+func (t *Tokenizer) encoder(encoding string) *encoder {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if e, ok := t.encoders[encoding]; ok {
+		return e
+	}
+
+	e := &encoder{pattern: patternFor(encoding)}
+	if encoding != "" && t.dir != "" {
+		// A missing or invalid rank file leaves the encoding estimated
+		if ranks, err := loadRanks(filepath.Join(t.dir, encoding+".tiktoken")); err == nil {
+			e.ranks = ranks
+		} else if !os.IsNotExist(err) {
+			e.err = err
+		}
+	}
+	t.encoders[encoding] = e
+	return e
+}
+
+// Err returns the error loading the rank file of the encoding of a model, if any
+func (t *Tokenizer) Err(model string) error {
+	return t.encoder(EncodingFor(model)).err
+}
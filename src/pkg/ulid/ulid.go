@@ -0,0 +1,63 @@
+package ulid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"strings"
+	"time"
+)
+
+// Length is the number of characters of a ULID
+const Length = 26
+
+// alphabet is Crockford's base32, which leaves out I, L, O and U
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// New returns a new ULID of the current time
+func New() string {
+	return Make(time.Now())
+}
+
+// Make returns a ULID of a time: 48 bits of milliseconds since the Unix epoch followed by 80
+// random bits, encoded in 26 characters of Crockford's base32. ULIDs sort by time as strings.
+func Make(t time.Time) string {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(t.UnixMilli())<<16)
+	// crypto/rand does not fail on supported platforms
+	_, _ = rand.Read(id[6:])
+
+	// Each character encodes 5 bits, most significant first; the 128 bits leave the first
+	// character 3 bits
+	var out [Length]byte
+	for i := Length - 1; i >= 0; i-- {
+		bit := 128 - (Length-i)*5
+		out[i] = alphabet[bits(id, bit)]
+	}
+	return string(out[:])
+}
+
+// bits returns the 5 bits of id starting at bit from the most significant one, with the
+// bits before the start of id taken as zeros
+func bits(id [16]byte, start int) byte {
+	var value byte
+	for i := start; i < start+5; i++ {
+		value <<= 1
+		if i >= 0 && id[i/8]&(0x80>>(i%8)) != 0 {
+			value |= 1
+		}
+	}
+	return value
+}
+
+// Valid reports whether s is a ULID in canonical form
+func Valid(s string) bool {
+	if len(s) != Length || s[0] > '7' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(alphabet, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+// Package warnings collects the notices about how a request was served, such as a prompt
+// cut to fit its budget, so that they are returned to clients with the response.
+package warnings
+
+import (
+	"context"
+	"sync"
+)
+
+// Warning codes added while requests are served
+const (
+	// ContextTruncated warns that older messages of the chat were left out of the prompt
+	ContextTruncated = "CONTEXT_TRUNCATED"
+	// SummaryUsed warns that the prompt carried the chat summary instead of the earlier messages
+	SummaryUsed = "SUMMARY_USED"
+	// ModerationRedacted warns that moderation redacted blocked terms from the reply
+	ModerationRedacted = "MODERATION_REDACTED"
+	// FallbackModel warns that the reply was generated by a fallback model
+	FallbackModel = "FALLBACK_MODEL_USED"
+	// ModelDowngraded warns that the reply was generated by a cheaper model under heavy load
+	ModelDowngraded = "MODEL_DOWNGRADED"
+	// TaskExportFailed warns that tasks were saved but could not be exported to their tracker
+	TaskExportFailed = "TASK_EXPORT_FAILED"
+)
+
+// Warning is a notice returned with a successful response
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// collector holds the warnings of a request, added from any goroutine serving it
+type collector struct {
+	mu       sync.Mutex
+	warnings []Warning
+}
+
+// collectorKey is the context key of the collector of a request
+type collectorKey struct{}
+
+// WithCollector returns a context collecting the warnings added with it
+func WithCollector(ctx context.Context) context.Context {
+	return context.WithValue(ctx, collectorKey{}, &collector{})
+}
+
+// Add adds a warning to the request of ctx, once per code and message. It does nothing when
+// ctx collects no warnings, such as in background jobs.
+func Add(ctx context.Context, code, message string) {
+	c, ok := ctx.Value(collectorKey{}).(*collector)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, warning := range c.warnings {
+		if warning.Code == code && warning.Message == message {
+			return
+		}
+	}
+	c.warnings = append(c.warnings, Warning{Code: code, Message: message})
+}
+
+// From returns the warnings added to the request of ctx, in the order they were added
+func From(ctx context.Context) []Warning {
+	c, ok := ctx.Value(collectorKey{}).(*collector)
+	if !ok {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Warning(nil), c.warnings...)
+}
@@ -0,0 +1,21 @@
+// Package workspace carries the workspace a request is served for in its context, so that
+// its requests and usage are counted towards the workspace.
+package workspace
+
+import (
+	"context"
+)
+
+// workspaceKey is the context key of the workspace of a request
+type workspaceKey struct{}
+
+// With returns a context serving the workspace of an ID
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, id)
+}
+
+// Of returns the ID of the workspace ctx serves, empty when it serves none
+func Of(ctx context.Context) string {
+	id, _ := ctx.Value(workspaceKey{}).(string)
+	return id
+}
@@ -0,0 +1,52 @@
+package plugins
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+func init() {
+	Register("logging", func() Plugin { return &loggingPlugin{} })
+}
+
+// loggingPlugin logs every lifecycle hook
+type loggingPlugin struct {
+	Base
+}
+
+// Name returns the plugin name
+func (p *loggingPlugin) Name() string {
+	return "logging"
+}
+
+// OnChatCreated logs the created chat
+func (p *loggingPlugin) OnChatCreated(ctx context.Context, chat *dtos.ChatResponse) error {
+	logger.Context(ctx).Infow("Plugin: chat created", "chatID", chat.ID, "userID", chat.UserID)
+	return nil
+}
+
+// OnChatDeleted logs the deleted chat
+func (p *loggingPlugin) OnChatDeleted(ctx context.Context, chatID int64) error {
+	logger.Context(ctx).Infow("Plugin: chat deleted", "chatID", chatID)
+	return nil
+}
+
+// OnBeforeSend logs the message request
+func (p *loggingPlugin) OnBeforeSend(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest) error {
+	logger.Context(ctx).Infow("Plugin: sending message", "chatID", chatID, "userID", userID, "length", len(req.Content))
+	return nil
+}
+
+// OnBeforeGenerate logs the LLM request
+func (p *loggingPlugin) OnBeforeGenerate(ctx context.Context, chatID int64, request *dtos.LLMRequest) error {
+	logger.Context(ctx).Infow("Plugin: generating response", "chatID", chatID, "messages", len(request.Messages))
+	return nil
+}
+
+// OnAfterGenerate logs the LLM response
+func (p *loggingPlugin) OnAfterGenerate(ctx context.Context, chatID int64, response *dtos.LLMResponse) error {
+	logger.Context(ctx).Infow("Plugin: generated response", "chatID", chatID, "length", len(response.Message.Content))
+	return nil
+}
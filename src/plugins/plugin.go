@@ -0,0 +1,54 @@
+package plugins
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// Plugin receives chat lifecycle hooks. Plugins embed Base and override the
+// hooks they need. Returning an error from a "before" hook aborts the operation;
+// errors from other hooks are logged and ignored.
+type Plugin interface {
+	// Name identifies the plugin in configuration and logs
+	Name() string
+
+	// OnChatCreated is called after a chat is created
+	OnChatCreated(ctx context.Context, chat *dtos.ChatResponse) error
+
+	// OnChatDeleted is called after a chat and its messages are deleted
+	OnChatDeleted(ctx context.Context, chatID int64) error
+
+	// OnBeforeSend is called before a user message is saved; it may modify the request
+	OnBeforeSend(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest) error
+
+	// OnBeforeGenerate is called before the LLM request is sent; it may modify the request
+	OnBeforeGenerate(ctx context.Context, chatID int64, request *dtos.LLMRequest) error
+
+	// OnAfterGenerate is called with the LLM response before it is saved; it may modify the response
+	OnAfterGenerate(ctx context.Context, chatID int64, response *dtos.LLMResponse) error
+}
+
+// Base implements every hook as a no-op
+type Base struct{}
+
+// OnChatCreated does nothing
+func (Base) OnChatCreated(ctx context.Context, chat *dtos.ChatResponse) error { return nil }
+
+// OnChatDeleted does nothing
+func (Base) OnChatDeleted(ctx context.Context, chatID int64) error { return nil }
+
+// OnBeforeSend does nothing
+func (Base) OnBeforeSend(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest) error {
+	return nil
+}
+
+// OnBeforeGenerate does nothing
+func (Base) OnBeforeGenerate(ctx context.Context, chatID int64, request *dtos.LLMRequest) error {
+	return nil
+}
+
+// OnAfterGenerate does nothing
+func (Base) OnAfterGenerate(ctx context.Context, chatID int64, response *dtos.LLMResponse) error {
+	return nil
+}
@@ -0,0 +1,127 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// Factory creates a plugin
+type Factory func() Plugin
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes a plugin available under a name. It is called from the init
+// function of plugin files, which can be compiled in selectively with build tags.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if _, exists := factories[name]; exists {
+		panic(fmt.Sprintf("plugins: plugin %s registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// Available returns the names of the compiled-in plugins
+func Available() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Hooks dispatches lifecycle hooks to the enabled plugins in order
+type Hooks struct {
+	plugins []Plugin
+}
+
+// Load creates the enabled plugins in the configured order
+func Load(enabled []string) (*Hooks, error) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	hooks := &Hooks{}
+	for _, name := range enabled {
+		factory, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("plugin %s is not compiled in", name)
+		}
+		hooks.plugins = append(hooks.plugins, factory())
+	}
+	return hooks, nil
+}
+
+// NewHooks creates hooks dispatching to the given plugins
+func NewHooks(plugins ...Plugin) *Hooks {
+	return &Hooks{plugins: plugins}
+}
+
+// Names returns the names of the enabled plugins
+func (h *Hooks) Names() []string {
+	names := make([]string, len(h.plugins))
+	for i, plugin := range h.plugins {
+		names[i] = plugin.Name()
+	}
+	return names
+}
+
+// ChatCreated notifies plugins of a created chat
+func (h *Hooks) ChatCreated(ctx context.Context, chat *dtos.ChatResponse) {
+	for _, plugin := range h.plugins {
+		if err := plugin.OnChatCreated(ctx, chat); err != nil {
+			logger.Context(ctx).Errorw("Plugin hook failed", "plugin", plugin.Name(), "hook", "OnChatCreated", "error", err)
+		}
+	}
+}
+
+// ChatDeleted notifies plugins of a deleted chat
+func (h *Hooks) ChatDeleted(ctx context.Context, chatID int64) {
+	for _, plugin := range h.plugins {
+		if err := plugin.OnChatDeleted(ctx, chatID); err != nil {
+			logger.Context(ctx).Errorw("Plugin hook failed", "plugin", plugin.Name(), "hook", "OnChatDeleted", "error", err)
+		}
+	}
+}
+
+// BeforeSend runs plugins on a message request, stopping at the first error
+func (h *Hooks) BeforeSend(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest) error {
+	for _, plugin := range h.plugins {
+		if err := plugin.OnBeforeSend(ctx, chatID, userID, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BeforeGenerate runs plugins on an LLM request, stopping at the first error
+func (h *Hooks) BeforeGenerate(ctx context.Context, chatID int64, request *dtos.LLMRequest) error {
+	for _, plugin := range h.plugins {
+		if err := plugin.OnBeforeGenerate(ctx, chatID, request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AfterGenerate runs plugins on an LLM response. Failures are logged and the
+// response is kept, since it has already been paid for.
+func (h *Hooks) AfterGenerate(ctx context.Context, chatID int64, response *dtos.LLMResponse) {
+	for _, plugin := range h.plugins {
+		if err := plugin.OnAfterGenerate(ctx, chatID, response); err != nil {
+			logger.Context(ctx).Errorw("Plugin hook failed", "plugin", plugin.Name(), "hook", "OnAfterGenerate", "error", err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+//go:build plugin_usage
+
+package plugins
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// The usage plugin is only compiled in with -tags plugin_usage
+func init() {
+	Register("usage", func() Plugin { return &usagePlugin{} })
+}
+
+// usagePlugin records the token usage of every generated response for billing
+type usagePlugin struct {
+	Base
+}
+
+// Name returns the plugin name
+func (p *usagePlugin) Name() string {
+	return "usage"
+}
+
+// OnAfterGenerate records the token usage of the response
+func (p *usagePlugin) OnAfterGenerate(ctx context.Context, chatID int64, response *dtos.LLMResponse) error {
+	logger.Context(ctx).Infow("Plugin: token usage",
+		"chatID", chatID,
+		"model", response.Model,
+		"promptTokens", response.Usage.PromptTokens,
+		"completionTokens", response.Usage.CompletionTokens,
+		"totalTokens", response.Usage.TotalTokens)
+	return nil
+}
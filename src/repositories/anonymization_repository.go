@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// AnonymizationRepository defines the interface for copying chats into a separate schema of
+// the database, as the anonymize command does
+type AnonymizationRepository interface {
+	// PrepareSchema creates schema with empty chats and messages tables shaped as the source
+	// ones, replacing the tables of an earlier copy
+	PrepareSchema(ctx context.Context, schema string) error
+
+	// ListChats retrieves up to limit chats with an ID above afterID, by ID, leaving out the
+	// chats pending deletion
+	ListChats(ctx context.Context, afterID int64, limit int) ([]*models.Chat, error)
+
+	// ListMessages retrieves the messages of chats by ID, with their content in the storage
+	ListMessages(ctx context.Context, chatIDs []int64) ([]*models.Message, error)
+
+	// Copy inserts chats and their messages into the tables of schema in a transaction
+	Copy(ctx context.Context, schema string, chats []*models.Chat, messages []*models.Message) error
+}
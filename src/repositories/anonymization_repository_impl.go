@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// anonymizationCopyBatchSize is the number of rows inserted per statement
+const anonymizationCopyBatchSize = 500
+
+// anonymizationRepository implements the AnonymizationRepository interface
+type anonymizationRepository struct {
+	db      adapters.DBAdapter
+	content *MessageContentStore
+}
+
+// NewAnonymizationRepository creates a new anonymization repository, loading the content of
+// large messages from content
+func NewAnonymizationRepository(db adapters.DBAdapter, content *MessageContentStore) AnonymizationRepository {
+	return &anonymizationRepository{db: db, content: content}
+}
+
+// PrepareSchema creates schema with empty chats and messages tables shaped as the source
+// ones. The copies keep the columns, defaults and indexes of the source tables, but not
+// their foreign keys or the sequences generating their IDs, so the schema can be dumped
+// and restored on its own. schema must be a valid identifier.
+func (r *anonymizationRepository) PrepareSchema(ctx context.Context, schema string) error {
+	log := logger.Context(ctx)
+
+	statements := []string{
+		fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %q`, schema),
+		fmt.Sprintf(`DROP TABLE IF EXISTS %q.messages, %q.chats`, schema, schema),
+		fmt.Sprintf(`CREATE TABLE %q.chats (LIKE chats INCLUDING DEFAULTS INCLUDING INDEXES)`, schema),
+		fmt.Sprintf(`ALTER TABLE %q.chats ALTER COLUMN id DROP DEFAULT`, schema),
+		fmt.Sprintf(`CREATE TABLE %q.messages (LIKE messages INCLUDING DEFAULTS INCLUDING INDEXES)`, schema),
+		fmt.Sprintf(`ALTER TABLE %q.messages ALTER COLUMN id DROP DEFAULT`, schema),
+	}
+	err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorw("Failed to prepare anonymization schema", "error", err, "schema", schema)
+		return dbError(err, "Failed to prepare anonymization schema")
+	}
+
+	return nil
+}
+
+// ListChats retrieves up to limit chats with an ID above afterID, by ID
+func (r *anonymizationRepository) ListChats(ctx context.Context, afterID int64, limit int) ([]*models.Chat, error) {
+	log := logger.Context(ctx)
+	var chats []*models.Chat
+
+	result := r.db.GetDB().WithContext(ctx).
+		Where("id > ? AND delete_at IS NULL", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&chats)
+	if result.Error != nil {
+		log.Errorw("Failed to list chats to anonymize", "error", result.Error, "afterID", afterID)
+		return nil, dbError(result.Error, "Failed to list chats to anonymize")
+	}
+
+	return chats, nil
+}
+
+// ListMessages retrieves the messages of chats by ID
+func (r *anonymizationRepository) ListMessages(ctx context.Context, chatIDs []int64) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if len(chatIDs) == 0 {
+		return messages, nil
+	}
+
+	result := r.db.GetDB().WithContext(ctx).
+		Where("chat_id IN ?", chatIDs).
+		Order("id").
+		Find(&messages)
+	if result.Error != nil {
+		log.Errorw("Failed to list messages to anonymize", "error", result.Error, "chats", len(chatIDs))
+		return nil, dbError(result.Error, "Failed to list messages to anonymize")
+	}
+	r.content.load(ctx, messages...)
+
+	return messages, nil
+}
+
+// Copy inserts chats and their messages into the tables of schema in a transaction
+func (r *anonymizationRepository) Copy(ctx context.Context, schema string, chats []*models.Chat, messages []*models.Message) error {
+	log := logger.Context(ctx)
+
+	err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(chats) > 0 {
+			if err := tx.Table(schema+".chats").Omit(clause.Associations).CreateInBatches(chats, anonymizationCopyBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(messages) > 0 {
+			if err := tx.Table(schema+".messages").Omit(clause.Associations).CreateInBatches(messages, anonymizationCopyBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorw("Failed to copy anonymized chats", "error", err, "schema", schema, "chats", len(chats))
+		return dbError(err, "Failed to copy anonymized chats")
+	}
+
+	return nil
+}
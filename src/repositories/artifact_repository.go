@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// ArtifactRepository defines the interface for message artifact data access
+type ArtifactRepository interface {
+	// CreateBatch creates the artifacts of a message
+	CreateBatch(ctx context.Context, artifacts []*models.Artifact) error
+
+	// ListByMessageID retrieves the artifacts of a message in their order in the message
+	ListByMessageID(ctx context.Context, messageID int64) ([]*models.Artifact, error)
+
+	// Get retrieves an artifact by ID
+	Get(ctx context.Context, id int64) (*models.Artifact, error)
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// artifactRepository implements the ArtifactRepository interface
+type artifactRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewArtifactRepository creates a new message artifact repository
+func NewArtifactRepository(db adapters.DBAdapter) ArtifactRepository {
+	return &artifactRepository{db: db}
+}
+
+// CreateBatch creates the artifacts of a message
+func (r *artifactRepository) CreateBatch(ctx context.Context, artifacts []*models.Artifact) error {
+	log := logger.Context(ctx)
+	if len(artifacts) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for _, artifact := range artifacts {
+		artifact.CreatedAt = now
+	}
+
+	if err := r.db.GetDB().WithContext(ctx).Create(&artifacts).Error; err != nil {
+		log.Errorw("Failed to create artifacts", "error", err, "messageID", artifacts[0].MessageID)
+		return dbError(err, "Failed to create artifacts")
+	}
+
+	return nil
+}
+
+// ListByMessageID retrieves the artifacts of a message in their order in the message
+func (r *artifactRepository) ListByMessageID(ctx context.Context, messageID int64) ([]*models.Artifact, error) {
+	log := logger.Context(ctx)
+	var artifacts []*models.Artifact
+
+	if err := r.db.GetDB().WithContext(ctx).Where("message_id = ?", messageID).Order("block_index ASC").Find(&artifacts).Error; err != nil {
+		log.Errorw("Failed to list artifacts", "error", err, "messageID", messageID)
+		return nil, dbError(err, "Failed to list artifacts")
+	}
+
+	return artifacts, nil
+}
+
+// Get retrieves an artifact by ID
+func (r *artifactRepository) Get(ctx context.Context, id int64) (*models.Artifact, error) {
+	log := logger.Context(ctx)
+	var artifact models.Artifact
+
+	result := r.db.GetDB().WithContext(ctx).First(&artifact, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Artifact not found")
+		}
+		log.Errorw("Failed to get artifact", "error", result.Error, "artifactID", id)
+		return nil, dbError(result.Error, "Failed to get artifact")
+	}
+
+	return &artifact, nil
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// AttachmentRepository defines the interface for message attachment data access
+type AttachmentRepository interface {
+	// Create creates a new attachment
+	Create(ctx context.Context, attachment *models.Attachment) error
+
+	// Get retrieves an attachment by ID
+	Get(ctx context.Context, id int64) (*models.Attachment, error)
+
+	// GetByStorageKey retrieves the attachment whose file is stored under a key
+	GetByStorageKey(ctx context.Context, key string) (*models.Attachment, error)
+
+	// ListByMessageIDs retrieves the ready attachments of messages
+	ListByMessageIDs(ctx context.Context, messageIDs []int64) ([]*models.Attachment, error)
+
+	// ListByMessageID retrieves all the attachments of a message, including pending uploads
+	ListByMessageID(ctx context.Context, messageID int64) ([]*models.Attachment, error)
+
+	// ListByStatus retrieves the oldest attachments with a status
+	ListByStatus(ctx context.Context, status string, limit int) ([]*models.Attachment, error)
+
+	// MarkUploaded moves a pending attachment to status with the size of its uploaded file.
+	// It fails with a conflict when the attachment is not pending.
+	MarkUploaded(ctx context.Context, id int64, size int64, status string) error
+
+	// UpdateStatus moves an attachment from one status to another. It fails with a conflict
+	// when the attachment is not in the from status.
+	UpdateStatus(ctx context.Context, id int64, from, to string) error
+
+	// SetScanResult records the scan result of an attachment being scanned: its status, the
+	// malware found and the storage key of its file, which moves when it is quarantined
+	SetScanResult(ctx context.Context, id int64, status, threat, storageKey string) error
+}
@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// attachmentRepository implements the AttachmentRepository interface
+type attachmentRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewAttachmentRepository creates a new message attachment repository
+func NewAttachmentRepository(db adapters.DBAdapter) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+// Create creates a new attachment
+func (r *attachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	log := logger.Context(ctx)
+	attachment.CreatedAt = time.Now()
+	if attachment.Status == "" {
+		attachment.Status = models.AttachmentStatusReady
+	}
+
+	if err := r.db.GetDB().WithContext(ctx).Create(attachment).Error; err != nil {
+		log.Errorw("Failed to create attachment", "error", err, "messageID", attachment.MessageID)
+		return dbError(err, "Failed to create attachment")
+	}
+
+	return nil
+}
+
+// Get retrieves an attachment by ID
+func (r *attachmentRepository) Get(ctx context.Context, id int64) (*models.Attachment, error) {
+	log := logger.Context(ctx)
+	var attachment models.Attachment
+
+	result := r.db.GetDB().WithContext(ctx).First(&attachment, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Attachment not found")
+		}
+		log.Errorw("Failed to get attachment", "error", result.Error, "attachmentID", id)
+		return nil, dbError(result.Error, "Failed to get attachment")
+	}
+
+	return &attachment, nil
+}
+
+// GetByStorageKey retrieves the attachment whose file is stored under a key
+func (r *attachmentRepository) GetByStorageKey(ctx context.Context, key string) (*models.Attachment, error) {
+	log := logger.Context(ctx)
+	var attachment models.Attachment
+
+	result := r.db.GetDB().WithContext(ctx).Where("storage_key = ?", key).First(&attachment)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Attachment not found")
+		}
+		log.Errorw("Failed to get attachment by storage key", "error", result.Error, "key", key)
+		return nil, dbError(result.Error, "Failed to get attachment")
+	}
+
+	return &attachment, nil
+}
+
+// ListByMessageIDs retrieves the ready attachments of messages
+func (r *attachmentRepository) ListByMessageIDs(ctx context.Context, messageIDs []int64) ([]*models.Attachment, error) {
+	log := logger.Context(ctx)
+	var attachments []*models.Attachment
+
+	if len(messageIDs) == 0 {
+		return attachments, nil
+	}
+
+	if err := r.db.GetDB().WithContext(ctx).Where("message_id IN ? AND status = ?", messageIDs, models.AttachmentStatusReady).Order("id").Find(&attachments).Error; err != nil {
+		log.Errorw("Failed to list attachments", "error", err)
+		return nil, dbError(err, "Failed to list attachments")
+	}
+
+	return attachments, nil
+}
+
+// ListByMessageID retrieves all the attachments of a message, including pending uploads
+func (r *attachmentRepository) ListByMessageID(ctx context.Context, messageID int64) ([]*models.Attachment, error) {
+	log := logger.Context(ctx)
+	var attachments []*models.Attachment
+
+	if err := r.db.GetDB().WithContext(ctx).Where("message_id = ?", messageID).Order("id").Find(&attachments).Error; err != nil {
+		log.Errorw("Failed to list attachments", "error", err, "messageID", messageID)
+		return nil, dbError(err, "Failed to list attachments")
+	}
+
+	return attachments, nil
+}
+
+// ListByStatus retrieves the oldest attachments with a status
+func (r *attachmentRepository) ListByStatus(ctx context.Context, status string, limit int) ([]*models.Attachment, error) {
+	log := logger.Context(ctx)
+	var attachments []*models.Attachment
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("status = ?", status).
+		Order("id ASC").
+		Limit(limit).
+		Find(&attachments).Error; err != nil {
+		log.Errorw("Failed to list attachments by status", "error", err, "status", status)
+		return nil, dbError(err, "Failed to list attachments")
+	}
+
+	return attachments, nil
+}
+
+// MarkUploaded moves a pending attachment to status with the size of its uploaded file
+func (r *attachmentRepository) MarkUploaded(ctx context.Context, id int64, size int64, status string) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Attachment{}).
+		Where("id = ? AND status = ?", id, models.AttachmentStatusPending).
+		Updates(map[string]interface{}{"status": status, "size": size})
+	if result.Error != nil {
+		log.Errorw("Failed to mark attachment as uploaded", "error", result.Error, "attachmentID", id)
+		return dbError(result.Error, "Failed to update attachment")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrConflict, "Attachment is not waiting for an upload")
+	}
+
+	return nil
+}
+
+// UpdateStatus moves an attachment from one status to another
+func (r *attachmentRepository) UpdateStatus(ctx context.Context, id int64, from, to string) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Attachment{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	if result.Error != nil {
+		log.Errorw("Failed to update attachment status", "error", result.Error, "attachmentID", id, "status", to)
+		return dbError(result.Error, "Failed to update attachment")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrConflict, "Attachment status has changed")
+	}
+
+	return nil
+}
+
+// SetScanResult records the scan result of an attachment being scanned
+func (r *attachmentRepository) SetScanResult(ctx context.Context, id int64, status, threat, storageKey string) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Attachment{}).
+		Where("id = ? AND status = ?", id, models.AttachmentStatusScanning).
+		Updates(map[string]interface{}{"status": status, "threat": threat, "storage_key": storageKey})
+	if result.Error != nil {
+		log.Errorw("Failed to record attachment scan result", "error", result.Error, "attachmentID", id)
+		return dbError(result.Error, "Failed to update attachment")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrConflict, "Attachment is not being scanned")
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// AuditLogRepository defines the interface for audit log data access
+type AuditLogRepository interface {
+	// Create creates a new audit log entry
+	Create(ctx context.Context, entry *models.AuditLog) error
+
+	// List retrieves audit log entries, optionally filtered by admin, user and action, newest first
+	List(ctx context.Context, adminID, userID, action string, limit, offset int) ([]*models.AuditLog, int64, error)
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// auditLogRepository implements the AuditLogRepository interface
+type auditLogRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db adapters.DBAdapter) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// Create creates a new audit log entry
+func (r *auditLogRepository) Create(ctx context.Context, entry *models.AuditLog) error {
+	log := logger.Context(ctx)
+	entry.CreatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Create(entry)
+	if result.Error != nil {
+		log.Errorw("Failed to create audit log entry", "error", result.Error, "adminID", entry.AdminID, "userID", entry.UserID)
+		return dbError(result.Error, "Failed to create audit log entry")
+	}
+
+	return nil
+}
+
+// List retrieves audit log entries, optionally filtered by admin and user, newest first
+func (r *auditLogRepository) List(ctx context.Context, adminID, userID, action string, limit, offset int) ([]*models.AuditLog, int64, error) {
+	log := logger.Context(ctx)
+	var entries []*models.AuditLog
+	var total int64
+
+	query := r.db.GetDB().WithContext(ctx).Model(&models.AuditLog{})
+	if adminID != "" {
+		query = query.Where("admin_id = ?", adminID)
+	}
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		log.Errorw("Failed to count audit log entries", "error", err, "adminID", adminID, "userID", userID)
+		return nil, 0, dbError(err, "Failed to count audit log entries")
+	}
+
+	// Get entries with pagination
+	if err := query.Order("created_at DESC").
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries).Error; err != nil {
+		log.Errorw("Failed to get audit log entries", "error", err, "adminID", adminID, "userID", userID)
+		return nil, 0, dbError(err, "Failed to get audit log entries")
+	}
+
+	return entries, total, nil
+}
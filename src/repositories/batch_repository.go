@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// BatchRepository defines the interface for batch prompt data access
+type BatchRepository interface {
+	// Create creates a new batch with its items
+	Create(ctx context.Context, batch *models.Batch) error
+
+	// GetByID retrieves a batch by ID, without its items
+	GetByID(ctx context.Context, id int64) (*models.Batch, error)
+
+	// ListItems retrieves a page of the items of a batch in request order, with their total count
+	ListItems(ctx context.Context, batchID int64, limit, offset int) ([]*models.BatchItem, int64, error)
+
+	// ListPendingItems retrieves the oldest items waiting to be processed, of any batch
+	ListPendingItems(ctx context.Context, limit int) ([]*models.BatchItem, error)
+
+	// UpdateItem saves the status and result of an item
+	UpdateItem(ctx context.Context, item *models.BatchItem) error
+
+	// UpdateProgress recounts the processed items of a batch and completes it once none is pending.
+	// It reports whether this call completed the batch.
+	UpdateProgress(ctx context.Context, batchID int64) (bool, error)
+}
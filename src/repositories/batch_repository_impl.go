@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// batchRepository implements the BatchRepository interface
+type batchRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewBatchRepository creates a new batch prompt repository
+func NewBatchRepository(db adapters.DBAdapter) BatchRepository {
+	return &batchRepository{db: db}
+}
+
+// Create creates a new batch with its items
+func (r *batchRepository) Create(ctx context.Context, batch *models.Batch) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	batch.CreatedAt = now
+	batch.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(batch).Error; err != nil {
+		log.Errorw("Failed to create batch", "error", err, "userID", batch.UserID)
+		return dbError(err, "Failed to create batch")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a batch by ID, without its items
+func (r *batchRepository) GetByID(ctx context.Context, id int64) (*models.Batch, error) {
+	log := logger.Context(ctx)
+	var batch models.Batch
+
+	result := r.db.GetDB().WithContext(ctx).First(&batch, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Batch not found")
+		}
+		log.Errorw("Failed to get batch", "error", result.Error, "batchID", id)
+		return nil, dbError(result.Error, "Failed to get batch")
+	}
+
+	return &batch, nil
+}
+
+// ListItems retrieves a page of the items of a batch in request order, with their total count
+func (r *batchRepository) ListItems(ctx context.Context, batchID int64, limit, offset int) ([]*models.BatchItem, int64, error) {
+	log := logger.Context(ctx)
+	var items []*models.BatchItem
+	var total int64
+
+	query := r.db.GetDB().WithContext(ctx).Model(&models.BatchItem{}).Where("batch_id = ?", batchID)
+	if err := query.Count(&total).Error; err != nil {
+		log.Errorw("Failed to count batch items", "error", err, "batchID", batchID)
+		return nil, 0, dbError(err, "Failed to count batch items")
+	}
+
+	if err := query.Order("position ASC").Limit(limit).Offset(offset).Find(&items).Error; err != nil {
+		log.Errorw("Failed to list batch items", "error", err, "batchID", batchID)
+		return nil, 0, dbError(err, "Failed to list batch items")
+	}
+
+	return items, total, nil
+}
+
+// ListPendingItems retrieves the oldest items waiting to be processed, of any batch
+func (r *batchRepository) ListPendingItems(ctx context.Context, limit int) ([]*models.BatchItem, error) {
+	log := logger.Context(ctx)
+	var items []*models.BatchItem
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("status = ?", models.BatchItemStatusPending).
+		Order("id ASC").
+		Limit(limit).
+		Find(&items).Error; err != nil {
+		log.Errorw("Failed to list pending batch items", "error", err)
+		return nil, dbError(err, "Failed to list pending batch items")
+	}
+
+	return items, nil
+}
+
+// UpdateItem saves the status and result of an item
+func (r *batchRepository) UpdateItem(ctx context.Context, item *models.BatchItem) error {
+	log := logger.Context(ctx)
+
+	if err := r.db.GetDB().WithContext(ctx).Save(item).Error; err != nil {
+		log.Errorw("Failed to update batch item", "error", err, "itemID", item.ID)
+		return dbError(err, "Failed to update batch item")
+	}
+
+	return nil
+}
+
+// UpdateProgress recounts the processed items of a batch and completes it once none is pending.
+// It reports whether this call completed the batch, which only one call does.
+func (r *batchRepository) UpdateProgress(ctx context.Context, batchID int64) (bool, error) {
+	log := logger.Context(ctx)
+	now := time.Now()
+
+	countItems := func(status string) *gorm.DB {
+		return r.db.GetDB().Model(&models.BatchItem{}).Select("COUNT(*)").Where("batch_id = ? AND status = ?", batchID, status)
+	}
+	pending := r.db.GetDB().Model(&models.BatchItem{}).Select("1").Where("batch_id = ? AND status = ?", batchID, models.BatchItemStatusPending)
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Batch{}).Where("id = ?", batchID).Updates(map[string]interface{}{
+		"completed":    countItems(models.BatchItemStatusCompleted),
+		"failed":       countItems(models.BatchItemStatusFailed),
+		"status":       gorm.Expr("CASE WHEN EXISTS (?) THEN ? ELSE status END", pending, models.BatchStatusRunning),
+		"completed_at": gorm.Expr("CASE WHEN EXISTS (?) THEN NULL ELSE completed_at END", pending),
+		"updated_at":   now,
+	}).Error; err != nil {
+		log.Errorw("Failed to update batch progress", "error", err, "batchID", batchID)
+		return false, dbError(err, "Failed to update batch progress")
+	}
+
+	// Completed conditionally, so that of the items finishing last together, one completes the batch
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Batch{}).
+		Where("id = ? AND status <> ? AND NOT EXISTS (?)", batchID, models.BatchStatusCompleted, pending).
+		Updates(map[string]interface{}{
+			"status":       models.BatchStatusCompleted,
+			"completed_at": now,
+		})
+	if result.Error != nil {
+		log.Errorw("Failed to complete batch", "error", result.Error, "batchID", batchID)
+		return false, dbError(result.Error, "Failed to complete batch")
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// BudgetRepository defines the interface for cost budget and usage data access
+type BudgetRepository interface {
+	// RecordUsage records the usage of a generated reply
+	RecordUsage(ctx context.Context, usage *models.Usage) error
+
+	// ListUsage retrieves the usage recorded from a time, inclusive, to another, exclusive,
+	// with an ID above afterID, in ID order
+	ListUsage(ctx context.Context, from, to time.Time, afterID int64, limit int) ([]*models.Usage, error)
+
+	// SumUsage aggregates the usage recorded from a time, inclusive, to another, exclusive
+	SumUsage(ctx context.Context, from, to time.Time) (*models.UsageTotals, error)
+
+	// SumUsageByDay aggregates the usage of replies recorded from a time, inclusive, to
+	// another, exclusive, by UTC day and model. Image generations are left out.
+	SumUsageByDay(ctx context.Context, from, to time.Time) ([]*models.DailyUsage, error)
+
+	// SumCost sums the cost recorded since a time, of a user or of all users when userID is empty
+	SumCost(ctx context.Context, userID string, since time.Time) (float64, error)
+
+	// Get retrieves the budget of a user, or the service-wide budget when userID is empty.
+	// It returns nil when no budget is set.
+	Get(ctx context.Context, userID string) (*models.Budget, error)
+
+	// Upsert sets the limit and warning threshold of a budget, creating it if needed
+	Upsert(ctx context.Context, budget *models.Budget) error
+
+	// Delete removes a budget
+	Delete(ctx context.Context, userID string) error
+
+	// MarkPeriod records that the event of a budget for a period was published. It returns
+	// false when it already was, so that each event is published once per period.
+	MarkPeriod(ctx context.Context, budgetID int64, column, period string) (bool, error)
+}
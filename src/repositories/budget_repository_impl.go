@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm/clause"
+)
+
+// budgetPeriodColumns are the budget columns MarkPeriod may set
+var budgetPeriodColumns = map[string]bool{
+	"warned_period":   true,
+	"exceeded_period": true,
+}
+
+// budgetRepository implements the BudgetRepository interface
+type budgetRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewBudgetRepository creates a new budget repository
+func NewBudgetRepository(db adapters.DBAdapter) BudgetRepository {
+	return &budgetRepository{db: db}
+}
+
+// RecordUsage records the usage of a generated reply
+func (r *budgetRepository) RecordUsage(ctx context.Context, usage *models.Usage) error {
+	log := logger.Context(ctx)
+	usage.CreatedAt = time.Now()
+
+	if err := r.db.GetDB().WithContext(ctx).Create(usage).Error; err != nil {
+		log.Errorw("Failed to record usage", "error", err, "messageID", usage.MessageID)
+		return dbError(err, "Failed to record usage")
+	}
+
+	return nil
+}
+
+// ListUsage retrieves the usage recorded within a period after an ID, in ID order
+func (r *budgetRepository) ListUsage(ctx context.Context, from, to time.Time, afterID int64, limit int) ([]*models.Usage, error) {
+	log := logger.Context(ctx)
+	var usage []*models.Usage
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&usage).Error; err != nil {
+		log.Errorw("Failed to list usage", "error", err, "from", from, "to", to, "afterID", afterID)
+		return nil, dbError(err, "Failed to list usage")
+	}
+
+	return usage, nil
+}
+
+// SumUsage aggregates the usage recorded within a period
+func (r *budgetRepository) SumUsage(ctx context.Context, from, to time.Time) (*models.UsageTotals, error) {
+	log := logger.Context(ctx)
+	var totals models.UsageTotals
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Model(&models.Usage{}).
+		Select(`COUNT(*) AS records,
+			COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens,
+			COALESCE(SUM(completion_tokens), 0) AS completion_tokens,
+			COALESCE(SUM(images), 0) AS images,
+			COALESCE(SUM(cost), 0) AS cost`).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Scan(&totals).Error; err != nil {
+		log.Errorw("Failed to sum usage", "error", err, "from", from, "to", to)
+		return nil, dbError(err, "Failed to sum usage")
+	}
+
+	return &totals, nil
+}
+
+// SumUsageByDay aggregates the usage of replies within a period by day and model
+func (r *budgetRepository) SumUsageByDay(ctx context.Context, from, to time.Time) ([]*models.DailyUsage, error) {
+	log := logger.Context(ctx)
+	var usage []*models.DailyUsage
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Model(&models.Usage{}).
+		Select(`date_trunc('day', created_at AT TIME ZONE 'UTC') AS day,
+			model,
+			COUNT(*) AS records,
+			COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens,
+			COALESCE(SUM(completion_tokens), 0) AS completion_tokens,
+			COALESCE(SUM(cost), 0) AS cost`).
+		Where("created_at >= ? AND created_at < ? AND images = 0", from, to).
+		Group("day, model").
+		Order("day, model").
+		Scan(&usage).Error; err != nil {
+		log.Errorw("Failed to sum usage by day", "error", err, "from", from, "to", to)
+		return nil, dbError(err, "Failed to sum usage")
+	}
+
+	return usage, nil
+}
+
+// SumCost sums the cost recorded since a time, of a user or of all users when userID is empty
+func (r *budgetRepository) SumCost(ctx context.Context, userID string, since time.Time) (float64, error) {
+	log := logger.Context(ctx)
+	var total float64
+
+	query := r.db.GetDB().WithContext(ctx).Model(&models.Usage{}).Where("created_at >= ?", since)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.Select("COALESCE(SUM(cost), 0)").Scan(&total).Error; err != nil {
+		log.Errorw("Failed to sum usage cost", "error", err, "userID", userID)
+		return 0, dbError(err, "Failed to sum usage cost")
+	}
+
+	return total, nil
+}
+
+// Get retrieves the budget of a user, or the service-wide budget when userID is empty
+func (r *budgetRepository) Get(ctx context.Context, userID string) (*models.Budget, error) {
+	log := logger.Context(ctx)
+	var budgets []*models.Budget
+
+	if err := r.db.GetDB().WithContext(ctx).Where("user_id = ?", userID).Limit(1).Find(&budgets).Error; err != nil {
+		log.Errorw("Failed to get budget", "error", err, "userID", userID)
+		return nil, dbError(err, "Failed to get budget")
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+
+	return budgets[0], nil
+}
+
+// Upsert sets the limit and warning threshold of a budget, creating it if needed
+func (r *budgetRepository) Upsert(ctx context.Context, budget *models.Budget) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	budget.CreatedAt = now
+	budget.UpdatedAt = now
+
+	// A changed limit gets fresh warnings in the current period
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"monthly_limit":   budget.MonthlyLimit,
+			"warn_threshold":  budget.WarnThreshold,
+			"warned_period":   "",
+			"exceeded_period": "",
+			"updated_at":      now,
+		}),
+	}).Create(budget)
+	if result.Error != nil {
+		log.Errorw("Failed to set budget", "error", result.Error, "userID", budget.UserID)
+		return dbError(result.Error, "Failed to set budget")
+	}
+
+	return nil
+}
+
+// Delete removes a budget
+func (r *budgetRepository) Delete(ctx context.Context, userID string) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Budget{})
+	if result.Error != nil {
+		log.Errorw("Failed to delete budget", "error", result.Error, "userID", userID)
+		return dbError(result.Error, "Failed to delete budget")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Budget not found")
+	}
+
+	return nil
+}
+
+// MarkPeriod records that the event of a budget for a period was published
+func (r *budgetRepository) MarkPeriod(ctx context.Context, budgetID int64, column, period string) (bool, error) {
+	log := logger.Context(ctx)
+	if !budgetPeriodColumns[column] {
+		return false, errors.New(errors.ErrInternal, "Unknown budget period column")
+	}
+
+	// The conditional update lets a single instance win when replies finish concurrently
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Budget{}).
+		Where("id = ? AND "+column+" <> ?", budgetID, period).
+		UpdateColumn(column, period)
+	if result.Error != nil {
+		log.Errorw("Failed to mark budget period", "error", result.Error, "budgetID", budgetID)
+		return false, dbError(result.Error, "Failed to mark budget period")
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/nvnamsss/chat/src/dtos"
 	"github.com/nvnamsss/chat/src/models"
@@ -15,15 +16,70 @@ type ChatRepository interface {
 	// Get retrieves a chat by ID
 	Get(ctx context.Context, id int64) (*models.Chat, error)
 
+	// GetIDByPublicID returns the ID of the chat with a public ID
+	GetIDByPublicID(ctx context.Context, publicID string) (int64, error)
+
 	// GetByUserID retrieves all chats for a user
 	GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Chat, int64, error)
 
-	// Search searches chats by title
+	// ListByUserID retrieves a page of chats for a user without counting them
+	ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Chat, error)
+
+	// Search searches chats by title, narrowed by the filters of the request
 	Search(ctx context.Context, req *dtos.SearchChatsRequest, userID string) ([]*models.Chat, int64, error)
 
+	// Facets counts the chats matching a search per value of each filter
+	Facets(ctx context.Context, req *dtos.SearchChatsRequest, userID string) (*dtos.ChatFacets, error)
+
+	// ListByIDs retrieves the chats with the given IDs of a user, in no order
+	ListByIDs(ctx context.Context, userID string, ids []int64) ([]*models.Chat, error)
+
 	// Update updates a chat
 	Update(ctx context.Context, chat *models.Chat) error
 
+	// AddUsage adds the tokens and cost of a reply to the usage of a chat
+	AddUsage(ctx context.Context, id int64, tokens int, cost float64) error
+
+	// SetLocked locks a chat on behalf of lockedBy, or unlocks it when lockedBy is empty
+	SetLocked(ctx context.Context, id int64, lockedBy string) error
+
+	// MarkPendingDelete makes an unlocked chat pending deletion until deleteAt
+	MarkPendingDelete(ctx context.Context, id int64, deleteAt time.Time) error
+
+	// UndoPendingDelete restores a chat pending deletion whose grace period has not ended
+	UndoPendingDelete(ctx context.Context, id int64) error
+
+	// ListIDsPendingDelete lists the IDs of chats pending deletion whose grace period ended before a time
+	ListIDsPendingDelete(ctx context.Context, before time.Time, limit int) ([]int64, error)
+
 	// Delete deletes a chat
 	Delete(ctx context.Context, id int64) error
+
+	// DeleteWithMessages deletes a chat and returns the IDs of the messages deleted with it
+	DeleteWithMessages(ctx context.Context, id int64) ([]int64, error)
+
+	// Pin pins a message to the top of a chat, in place of the message pinned before
+	Pin(ctx context.Context, chatID, messageID int64) error
+
+	// UpdateSummary stores the summary of a chat's messages up to messageID
+	UpdateSummary(ctx context.Context, chatID int64, summary string, messageID int64) error
+
+	// ListPendingSummary lists chats with more than minMessages messages after their summary
+	ListPendingSummary(ctx context.Context, minMessages, limit int) ([]*models.Chat, error)
+
+	// ListIDs lists the IDs of existing unlocked chats not pending deletion, optionally restricted to the given chats and users
+	ListIDs(ctx context.Context, chatIDs []int64, userIDs []string) ([]int64, error)
+
+	// ListIDsCreatedBefore lists the IDs of chats created before a time by users whose ID has the given prefix
+	ListIDsCreatedBefore(ctx context.Context, userIDPrefix string, before time.Time, limit int) ([]int64, error)
+
+	// ListEphemeralIDs lists the IDs of privacy mode chats created before a time, above afterID
+	ListEphemeralIDs(ctx context.Context, before time.Time, afterID int64, limit int) ([]int64, error)
+
+	// TransferOwnership moves the chats and messages of a user to another user and returns the moved chats
+	TransferOwnership(ctx context.Context, fromUserID, toUserID string) ([]*models.Chat, error)
+
+	// Transfer moves a chat and its messages to another user and records the transfer, returning the
+	// chat with its previous owner
+	Transfer(ctx context.Context, id int64, toUserID, transferredBy string) (*models.Chat, string, error)
 }
@@ -10,17 +10,21 @@ import (
 	"github.com/nvnamsss/chat/src/errors"
 	"github.com/nvnamsss/chat/src/logger"
 	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/ulid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // chatRepository implements the ChatRepository interface
 type chatRepository struct {
-	db adapters.DBAdapter
+	db      adapters.DBAdapter
+	content *MessageContentStore
 }
 
-// NewChatRepository creates a new chat repository
-func NewChatRepository(db adapters.DBAdapter) ChatRepository {
-	return &chatRepository{db: db}
+// NewChatRepository creates a new chat repository. The stored content of the messages of
+// deleted chats is removed from the content store, when given.
+func NewChatRepository(db adapters.DBAdapter, content *MessageContentStore) ChatRepository {
+	return &chatRepository{db: db, content: content}
 }
 
 // Create creates a new chat
@@ -29,11 +33,14 @@ func (r *chatRepository) Create(ctx context.Context, chat *models.Chat) error {
 	now := time.Now()
 	chat.CreatedAt = now
 	chat.UpdatedAt = now
+	if chat.PublicID == "" {
+		chat.PublicID = ulid.Make(now)
+	}
 
 	result := r.db.GetDB().WithContext(ctx).Create(chat)
 	if result.Error != nil {
 		log.Errorw("Failed to create chat", "error", result.Error)
-		return errors.Wrap(result.Error, errors.ErrInternal, "Failed to create chat")
+		return dbError(result.Error, "Failed to create chat")
 	}
 
 	return nil
@@ -51,28 +58,56 @@ func (r *chatRepository) Get(ctx context.Context, id int64) (*models.Chat, error
 			return nil, errors.New(errors.ErrNotFound, "Chat not found")
 		}
 		log.Errorw("Failed to get chat", "error", result.Error, "id", id)
-		return nil, errors.Wrap(result.Error, errors.ErrInternal, "Failed to get chat")
+		return nil, dbError(result.Error, "Failed to get chat")
 	}
 
 	return &chat, nil
 }
 
+// GetIDByPublicID returns the ID of the chat with a public ID
+func (r *chatRepository) GetIDByPublicID(ctx context.Context, publicID string) (int64, error) {
+	log := logger.Context(ctx)
+	var ids []int64
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).Where("public_id = ?", publicID).Limit(1).Pluck("id", &ids)
+	if result.Error != nil {
+		log.Errorw("Failed to get chat by public ID", "error", result.Error, "publicID", publicID)
+		return 0, dbError(result.Error, "Failed to get chat")
+	}
+	if len(ids) == 0 {
+		return 0, errors.New(errors.ErrNotFound, "Chat not found")
+	}
+
+	return ids[0], nil
+}
+
 // GetByUserID retrieves all chats for a user
 func (r *chatRepository) GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Chat, int64, error) {
 	log := logger.Context(ctx)
-	var chats []*models.Chat
 	var total int64
 
 	// Get total count
-	result := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).Where("user_id = ?", userID).Count(&total)
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).Where("user_id = ? AND delete_at IS NULL", userID).Count(&total)
 	if result.Error != nil {
 		log.Errorw("Failed to count chats", "error", result.Error, "userID", userID)
-		return nil, 0, errors.Wrap(result.Error, errors.ErrInternal, "Failed to count chats")
+		return nil, 0, dbError(result.Error, "Failed to count chats")
 	}
 
-	// Get chats with pagination
-	result = r.db.GetDB().WithContext(ctx).
-		Where("user_id = ?", userID).
+	chats, err := r.ListByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return chats, total, nil
+}
+
+// ListByUserID retrieves a page of chats for a user without counting them
+func (r *chatRepository) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Chat, error) {
+	log := logger.Context(ctx)
+	var chats []*models.Chat
+
+	result := r.db.GetDB().WithContext(ctx).
+		Where("user_id = ? AND delete_at IS NULL", userID).
 		Order("updated_at DESC").
 		Limit(limit).
 		Offset(offset).
@@ -80,56 +115,188 @@ func (r *chatRepository) GetByUserID(ctx context.Context, userID string, limit,
 
 	if result.Error != nil {
 		log.Errorw("Failed to get chats", "error", result.Error, "userID", userID)
-		return nil, 0, errors.Wrap(result.Error, errors.ErrInternal, "Failed to get chats")
+		return nil, dbError(result.Error, "Failed to get chats")
 	}
 
-	return chats, total, nil
+	return chats, nil
 }
 
-// Search searches chats by title
+// Chat search filters, named to leave one out of the facet counting by it
+const (
+	chatFilterDate        = "date"
+	chatFilterTag         = "tag"
+	chatFilterArchived    = "archived"
+	chatFilterAttachments = "attachments"
+	chatFilterModel       = "model"
+)
+
+// chatFacetLimit bounds the values listed by the tag, model and month facets
+const chatFacetLimit = 20
+
+// hasAttachmentsSQL tells whether a chat has a message with attachments
+const hasAttachmentsSQL = "EXISTS (SELECT 1 FROM attachments JOIN messages ON messages.id = attachments.message_id WHERE messages.chat_id = chats.id)"
+
+// Search searches chats by title, narrowed by the filters of the request
 func (r *chatRepository) Search(ctx context.Context, req *dtos.SearchChatsRequest, userID string) ([]*models.Chat, int64, error) {
 	log := logger.Context(ctx)
 	var chats []*models.Chat
 	var total int64
 
-	db := r.db.GetDB().WithContext(ctx)
-	query := db.Model(&models.Chat{}).Where("user_id = ?", userID)
-
-	if req.Query != "" {
-		query = query.Where("title ILIKE ?", "%"+req.Query+"%")
-	}
+	// Patterns matching most titles are slow even with the trigram index; searches get a shorter timeout
+	db := r.db.GetDB().WithContext(adapters.WithSearchTimeout(ctx))
+	query := searchChats(db, req, userID, "")
 
 	// Get total count
 	if err := query.Count(&total).Error; err != nil {
 		log.Errorw("Failed to count chats in search", "error", err, "query", req.Query)
-		return nil, 0, errors.Wrap(err, errors.ErrInternal, "Failed to search chats")
+		return nil, 0, dbError(err, "Failed to search chats")
 	}
 
 	// Get chats with pagination
-	if err := query.Order("updated_at DESC").
+	if err := query.Order("chats.updated_at DESC").
 		Limit(req.Limit).
 		Offset(req.Offset).
 		Find(&chats).Error; err != nil {
 		log.Errorw("Failed to search chats", "error", err, "query", req.Query)
-		return nil, 0, errors.Wrap(err, errors.ErrInternal, "Failed to search chats")
+		return nil, 0, dbError(err, "Failed to search chats")
 	}
 
 	return chats, total, nil
 }
 
+// Facets counts the chats matching a search per value of each filter, every facet applying
+// the filters of the request but its own
+func (r *chatRepository) Facets(ctx context.Context, req *dtos.SearchChatsRequest, userID string) (*dtos.ChatFacets, error) {
+	log := logger.Context(ctx)
+	db := r.db.GetDB().WithContext(adapters.WithSearchTimeout(ctx))
+	facets := &dtos.ChatFacets{}
+
+	counts := []struct {
+		facet *[]dtos.FacetCount
+		query *gorm.DB
+	}{
+		{
+			facet: &facets.Tags,
+			query: searchChats(db, req, userID, chatFilterTag).
+				Joins("CROSS JOIN unnest(string_to_array(chats.tags, ',')) AS tag").
+				Select("tag AS value, COUNT(*) AS count").Group("tag").
+				Order("count DESC, value").Limit(chatFacetLimit),
+		},
+		{
+			facet: &facets.Models,
+			query: searchChats(db, req, userID, chatFilterModel).
+				Joins("JOIN messages ON messages.chat_id = chats.id AND messages.model <> ''").
+				Select("messages.model AS value, COUNT(DISTINCT chats.id) AS count").Group("messages.model").
+				Order("count DESC, value").Limit(chatFacetLimit),
+		},
+		{
+			facet: &facets.Months,
+			query: searchChats(db, req, userID, chatFilterDate).
+				Select("to_char(chats.created_at AT TIME ZONE 'UTC', 'YYYY-MM') AS value, COUNT(*) AS count").Group("value").
+				Order("value DESC").Limit(chatFacetLimit),
+		},
+		{
+			facet: &facets.Archived,
+			query: searchChats(db, req, userID, chatFilterArchived).
+				Select("chats.archived::text AS value, COUNT(*) AS count").Group("chats.archived").
+				Order("value"),
+		},
+		{
+			facet: &facets.HasAttachments,
+			query: searchChats(db, req, userID, chatFilterAttachments).
+				Select(hasAttachmentsSQL + "::text AS value, COUNT(*) AS count").Group("value").
+				Order("value"),
+		},
+	}
+
+	for _, count := range counts {
+		*count.facet = []dtos.FacetCount{}
+		if err := count.query.Scan(count.facet).Error; err != nil {
+			log.Errorw("Failed to count chat facets", "error", err, "userID", userID)
+			return nil, dbError(err, "Failed to count chat facets")
+		}
+	}
+
+	return facets, nil
+}
+
+// searchChats returns the query of the chats of a user matching a search, with every filter
+// of the request but the one named by except
+func searchChats(db *gorm.DB, req *dtos.SearchChatsRequest, userID, except string) *gorm.DB {
+	query := db.Model(&models.Chat{}).Where("chats.user_id = ? AND chats.delete_at IS NULL", userID)
+
+	if req.Query != "" {
+		query = query.Where("chats.title ILIKE ?", "%"+req.Query+"%")
+	}
+	if except != chatFilterDate {
+		if req.From != nil {
+			query = query.Where("chats.created_at >= ?", *req.From)
+		}
+		if req.To != nil {
+			query = query.Where("chats.created_at < ?", *req.To)
+		}
+	}
+	if req.Tag != "" && except != chatFilterTag {
+		query = query.Where("string_to_array(chats.tags, ',') @> ARRAY[?]::text[]", req.Tag)
+	}
+	if req.Archived != nil && except != chatFilterArchived {
+		query = query.Where("chats.archived = ?", *req.Archived)
+	}
+	if req.HasAttachments != nil && except != chatFilterAttachments {
+		if *req.HasAttachments {
+			query = query.Where(hasAttachmentsSQL)
+		} else {
+			query = query.Where("NOT " + hasAttachmentsSQL)
+		}
+	}
+	if req.Model != "" && except != chatFilterModel {
+		query = query.Where("EXISTS (SELECT 1 FROM messages WHERE messages.model = ? AND messages.chat_id = chats.id)", req.Model)
+	}
+
+	return query
+}
+
+// ListByIDs retrieves the chats with the given IDs of a user, in no order
+func (r *chatRepository) ListByIDs(ctx context.Context, userID string, ids []int64) ([]*models.Chat, error) {
+	log := logger.Context(ctx)
+	var chats []*models.Chat
+
+	if len(ids) == 0 {
+		return chats, nil
+	}
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("id IN ? AND user_id = ? AND delete_at IS NULL", ids, userID).
+		Find(&chats).Error; err != nil {
+		log.Errorw("Failed to list chats by ID", "error", err, "userID", userID)
+		return nil, dbError(err, "Failed to list chats")
+	}
+
+	return chats, nil
+}
+
 // Update updates a chat
 func (r *chatRepository) Update(ctx context.Context, chat *models.Chat) error {
 	log := logger.Context(ctx)
 	chat.UpdatedAt = time.Now()
 
 	result := r.db.GetDB().WithContext(ctx).Model(chat).Updates(map[string]interface{}{
-		"title":      chat.Title,
-		"updated_at": chat.UpdatedAt,
+		"title":         chat.Title,
+		"url_context":   chat.URLContext,
+		"suggestions":   chat.Suggestions,
+		"language":      chat.Language,
+		"max_tokens":    chat.MaxTokens,
+		"max_cost":      chat.MaxCost,
+		"budget_mode":   chat.BudgetMode,
+		"tags":          chat.Tags,
+		"archived":      chat.Archived,
+		"system_prompt": chat.SystemPrompt,
+		"updated_at":    chat.UpdatedAt,
 	})
 
 	if result.Error != nil {
 		log.Errorw("Failed to update chat", "error", result.Error, "id", chat.ID)
-		return errors.Wrap(result.Error, errors.ErrInternal, "Failed to update chat")
+		return dbError(result.Error, "Failed to update chat")
 	}
 
 	if result.RowsAffected == 0 {
@@ -139,15 +306,125 @@ func (r *chatRepository) Update(ctx context.Context, chat *models.Chat) error {
 	return nil
 }
 
+// AddUsage adds the tokens and cost of a reply to the usage of a chat. The usage is
+// incremented in place, so concurrent replies all count.
+func (r *chatRepository) AddUsage(ctx context.Context, id int64, tokens int, cost float64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"used_tokens": gorm.Expr("used_tokens + ?", tokens),
+			"used_cost":   gorm.Expr("used_cost + ?", cost),
+		})
+	if result.Error != nil {
+		log.Errorw("Failed to add chat usage", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to add chat usage")
+	}
+
+	return nil
+}
+
+// SetLocked locks a chat on behalf of lockedBy, or unlocks it when lockedBy is empty
+func (r *chatRepository) SetLocked(ctx context.Context, id int64, lockedBy string) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"locked":     lockedBy != "",
+		"locked_by":  lockedBy,
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		log.Errorw("Failed to lock chat", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to lock chat")
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, fmt.Sprintf("Chat with ID %d not found", id))
+	}
+
+	return nil
+}
+
+// MarkPendingDelete makes an unlocked chat pending deletion until deleteAt
+func (r *chatRepository) MarkPendingDelete(ctx context.Context, id int64, deleteAt time.Time) error {
+	log := logger.Context(ctx)
+
+	// The condition keeps a concurrent lock or deletion from being overwritten
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).
+		Where("id = ? AND locked = ? AND delete_at IS NULL", id, false).
+		Updates(map[string]interface{}{
+			"delete_at":  deleteAt,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Errorw("Failed to mark chat pending deletion", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to delete chat")
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrConflict, "The chat is locked or already deleted")
+	}
+
+	return nil
+}
+
+// UndoPendingDelete restores a chat pending deletion whose grace period has not ended
+func (r *chatRepository) UndoPendingDelete(ctx context.Context, id int64) error {
+	log := logger.Context(ctx)
+
+	// Chats past their grace period may be being removed, so they stay deleted
+	now := time.Now()
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).
+		Where("id = ? AND delete_at > ?", id, now).
+		Updates(map[string]interface{}{
+			"delete_at":  nil,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		log.Errorw("Failed to undo chat deletion", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to undo chat deletion")
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrConflict, "The chat is not pending deletion")
+	}
+
+	return nil
+}
+
+// ListIDsPendingDelete lists the IDs of chats pending deletion whose grace period ended before a time
+func (r *chatRepository) ListIDsPendingDelete(ctx context.Context, before time.Time, limit int) ([]int64, error) {
+	log := logger.Context(ctx)
+	var ids []int64
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).
+		Where("delete_at <= ?", before).
+		Order("delete_at").
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		log.Errorw("Failed to list chats pending deletion", "error", err)
+		return nil, dbError(err, "Failed to list chats")
+	}
+
+	return ids, nil
+}
+
 // Delete deletes a chat
 func (r *chatRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.DeleteWithMessages(ctx, id)
+	return err
+}
+
+// DeleteWithMessages deletes a chat and returns the IDs of the messages deleted with it
+func (r *chatRepository) DeleteWithMessages(ctx context.Context, id int64) ([]int64, error) {
 	log := logger.Context(ctx)
 
 	// Start a transaction
 	tx := r.db.GetDB().WithContext(ctx).Begin()
 	if tx.Error != nil {
 		log.Errorw("Failed to begin transaction", "error", tx.Error)
-		return errors.Wrap(tx.Error, errors.ErrInternal, "Failed to begin transaction")
+		return nil, dbError(tx.Error, "Failed to begin transaction")
 	}
 
 	// Rollback transaction on error
@@ -157,24 +434,223 @@ func (r *chatRepository) Delete(ctx context.Context, id int64) error {
 		}
 	}()
 
+	// Collect the messages removed by the cascade
+	var messageIDs []int64
+	if err := tx.Model(&models.Message{}).Where("chat_id = ?", id).Order("id").Pluck("id", &messageIDs).Error; err != nil {
+		tx.Rollback()
+		log.Errorw("Failed to list chat messages", "error", err, "id", id)
+		return nil, dbError(err, "Failed to delete chat")
+	}
+	contentKeys, err := storedContentKeys(tx, "messages.chat_id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		log.Errorw("Failed to list stored message content", "error", err, "id", id)
+		return nil, dbError(err, "Failed to delete chat")
+	}
+
 	// Delete the chat (messages will be deleted automatically due to ON DELETE CASCADE)
 	result := tx.Delete(&models.Chat{}, id)
 	if result.Error != nil {
 		tx.Rollback()
 		log.Errorw("Failed to delete chat", "error", result.Error, "id", id)
-		return errors.Wrap(result.Error, errors.ErrInternal, "Failed to delete chat")
+		return nil, dbError(result.Error, "Failed to delete chat")
 	}
 
 	if result.RowsAffected == 0 {
 		tx.Rollback()
-		return errors.New(errors.ErrNotFound, fmt.Sprintf("Chat with ID %d not found", id))
+		return nil, errors.New(errors.ErrNotFound, fmt.Sprintf("Chat with ID %d not found", id))
 	}
 
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
 		log.Errorw("Failed to commit transaction", "error", err)
-		return errors.Wrap(err, errors.ErrInternal, "Failed to commit transaction")
+		return nil, dbError(err, "Failed to commit transaction")
+	}
+
+	r.content.remove(ctx, contentKeys...)
+	return messageIDs, nil
+}
+
+// ListIDs lists the IDs of existing unlocked chats not pending deletion, optionally restricted to the given chats and users
+func (r *chatRepository) ListIDs(ctx context.Context, chatIDs []int64, userIDs []string) ([]int64, error) {
+	log := logger.Context(ctx)
+	var ids []int64
+
+	query := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).Where("locked = ? AND delete_at IS NULL", false)
+	if len(chatIDs) > 0 {
+		query = query.Where("id IN ?", chatIDs)
+	}
+	if len(userIDs) > 0 {
+		query = query.Where("user_id IN ?", userIDs)
+	}
+
+	if err := query.Order("id").Pluck("id", &ids).Error; err != nil {
+		log.Errorw("Failed to list chat IDs", "error", err)
+		return nil, dbError(err, "Failed to list chats")
+	}
+
+	return ids, nil
+}
+
+// Pin pins a message to the top of a chat, in place of the message pinned before
+func (r *chatRepository) Pin(ctx context.Context, chatID, messageID int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).
+		Where("id = ?", chatID).
+		Update("pinned_message_id", messageID)
+	if result.Error != nil {
+		log.Errorw("Failed to pin message", "error", result.Error, "id", chatID, "messageID", messageID)
+		return dbError(result.Error, "Failed to pin message")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Chat not found")
 	}
 
 	return nil
 }
+
+// UpdateSummary stores the summary of a chat's messages up to messageID
+func (r *chatRepository) UpdateSummary(ctx context.Context, chatID int64, summary string, messageID int64) error {
+	log := logger.Context(ctx)
+
+	// The summary only moves forward, so concurrent runs cannot regress it
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).
+		Where("id = ? AND summary_message_id < ?", chatID, messageID).
+		Updates(map[string]interface{}{
+			"summary":            summary,
+			"summary_message_id": messageID,
+		})
+	if result.Error != nil {
+		log.Errorw("Failed to update chat summary", "error", result.Error, "id", chatID)
+		return dbError(result.Error, "Failed to update chat summary")
+	}
+
+	return nil
+}
+
+// ListPendingSummary lists chats with more than minMessages messages after their summary
+func (r *chatRepository) ListPendingSummary(ctx context.Context, minMessages, limit int) ([]*models.Chat, error) {
+	log := logger.Context(ctx)
+	var chats []*models.Chat
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("delete_at IS NULL").
+		Where("(SELECT COUNT(*) FROM messages WHERE messages.chat_id = chats.id AND messages.id > chats.summary_message_id) > ?", minMessages).
+		Order("updated_at DESC").
+		Limit(limit).
+		Find(&chats).Error; err != nil {
+		log.Errorw("Failed to list chats pending summary", "error", err)
+		return nil, dbError(err, "Failed to list chats")
+	}
+
+	return chats, nil
+}
+
+// ListIDsCreatedBefore lists the IDs of chats created before a time by users whose ID has the given prefix
+func (r *chatRepository) ListIDsCreatedBefore(ctx context.Context, userIDPrefix string, before time.Time, limit int) ([]int64, error) {
+	log := logger.Context(ctx)
+	var ids []int64
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).
+		Where("user_id LIKE ? AND created_at < ?", userIDPrefix+"%", before).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		log.Errorw("Failed to list chat IDs", "error", err, "userIDPrefix", userIDPrefix)
+		return nil, dbError(err, "Failed to list chats")
+	}
+
+	return ids, nil
+}
+
+// ListEphemeralIDs lists the IDs of privacy mode chats created before a time, above afterID
+func (r *chatRepository) ListEphemeralIDs(ctx context.Context, before time.Time, afterID int64, limit int) ([]int64, error) {
+	log := logger.Context(ctx)
+	var ids []int64
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Chat{}).
+		Where("ephemeral AND created_at < ? AND id > ?", before, afterID).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		log.Errorw("Failed to list privacy mode chat IDs", "error", err)
+		return nil, dbError(err, "Failed to list chats")
+	}
+
+	return ids, nil
+}
+
+// TransferOwnership moves the chats and messages of a user to another user and returns the moved chats
+func (r *chatRepository) TransferOwnership(ctx context.Context, fromUserID, toUserID string) ([]*models.Chat, error) {
+	log := logger.Context(ctx)
+	var chats []*models.Chat
+	now := time.Now()
+
+	err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", fromUserID).Order("id").Find(&chats).Error; err != nil {
+			return err
+		}
+		if len(chats) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.Chat{}).Where("user_id = ?", fromUserID).
+			Updates(map[string]interface{}{"user_id": toUserID, "updated_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Message{}).Where("user_id = ?", fromUserID).Update("user_id", toUserID).Error
+	})
+	if err != nil {
+		log.Errorw("Failed to transfer chats", "error", err, "from", fromUserID, "to", toUserID)
+		return nil, dbError(err, "Failed to transfer chats")
+	}
+
+	for _, chat := range chats {
+		chat.UserID = toUserID
+		chat.UpdatedAt = now
+	}
+	return chats, nil
+}
+
+// Transfer moves a chat and its messages to another user and records the transfer, returning the
+// chat with its previous owner
+func (r *chatRepository) Transfer(ctx context.Context, id int64, toUserID, transferredBy string) (*models.Chat, string, error) {
+	log := logger.Context(ctx)
+	var chat models.Chat
+	var fromUserID string
+	now := time.Now()
+
+	err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the chat so concurrent transfers are applied one after the other
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&chat, id).Error; err != nil {
+			return err
+		}
+		fromUserID = chat.UserID
+
+		if err := tx.Model(&chat).Updates(map[string]interface{}{"user_id": toUserID, "updated_at": now}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Message{}).Where("chat_id = ? AND user_id = ?", id, fromUserID).Update("user_id", toUserID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ChatTransfer{
+			ChatID:        id,
+			FromUserID:    fromUserID,
+			ToUserID:      toUserID,
+			TransferredBy: transferredBy,
+			CreatedAt:     now,
+		}).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, "", errors.New(errors.ErrNotFound, fmt.Sprintf("Chat with ID %d not found", id))
+		}
+		log.Errorw("Failed to transfer chat", "error", err, "id", id, "to", toUserID)
+		return nil, "", dbError(err, "Failed to transfer chat")
+	}
+
+	chat.UserID = toUserID
+	chat.UpdatedAt = now
+	return &chat, fromUserID, nil
+}
@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// chatRepositoryMetrics decorates a ChatRepository, recording the calls of its methods
+type chatRepositoryMetrics struct {
+	next ChatRepository
+}
+
+// NewChatRepositoryMetrics wraps a chat repository to record the count, latency and
+// outcome of the calls of each of its methods
+func NewChatRepositoryMetrics(next ChatRepository) ChatRepository {
+	return &chatRepositoryMetrics{next: next}
+}
+
+// Create records the call of Create of the wrapped repository
+func (r *chatRepositoryMetrics) Create(ctx context.Context, chat *models.Chat) error {
+	start := time.Now()
+	err := r.next.Create(ctx, chat)
+	observeCall(chatRepositoryName, "Create", start, err)
+	return err
+}
+
+// Get records the call of Get of the wrapped repository
+func (r *chatRepositoryMetrics) Get(ctx context.Context, id int64) (*models.Chat, error) {
+	start := time.Now()
+	chat, err := r.next.Get(ctx, id)
+	observeCall(chatRepositoryName, "Get", start, err)
+	return chat, err
+}
+
+// GetIDByPublicID records the call of GetIDByPublicID of the wrapped repository
+func (r *chatRepositoryMetrics) GetIDByPublicID(ctx context.Context, publicID string) (int64, error) {
+	start := time.Now()
+	id, err := r.next.GetIDByPublicID(ctx, publicID)
+	observeCall(chatRepositoryName, "GetIDByPublicID", start, err)
+	return id, err
+}
+
+// GetByUserID records the call of GetByUserID of the wrapped repository
+func (r *chatRepositoryMetrics) GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Chat, int64, error) {
+	start := time.Now()
+	chats, total, err := r.next.GetByUserID(ctx, userID, limit, offset)
+	observeCall(chatRepositoryName, "GetByUserID", start, err)
+	return chats, total, err
+}
+
+// ListByUserID records the call of ListByUserID of the wrapped repository
+func (r *chatRepositoryMetrics) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Chat, error) {
+	start := time.Now()
+	chats, err := r.next.ListByUserID(ctx, userID, limit, offset)
+	observeCall(chatRepositoryName, "ListByUserID", start, err)
+	return chats, err
+}
+
+// Search records the call of Search of the wrapped repository
+func (r *chatRepositoryMetrics) Search(ctx context.Context, req *dtos.SearchChatsRequest, userID string) ([]*models.Chat, int64, error) {
+	start := time.Now()
+	chats, total, err := r.next.Search(ctx, req, userID)
+	observeCall(chatRepositoryName, "Search", start, err)
+	return chats, total, err
+}
+
+// Facets records the call of Facets of the wrapped repository
+func (r *chatRepositoryMetrics) Facets(ctx context.Context, req *dtos.SearchChatsRequest, userID string) (*dtos.ChatFacets, error) {
+	start := time.Now()
+	facets, err := r.next.Facets(ctx, req, userID)
+	observeCall(chatRepositoryName, "Facets", start, err)
+	return facets, err
+}
+
+// ListByIDs records the call of ListByIDs of the wrapped repository
+func (r *chatRepositoryMetrics) ListByIDs(ctx context.Context, userID string, ids []int64) ([]*models.Chat, error) {
+	start := time.Now()
+	chats, err := r.next.ListByIDs(ctx, userID, ids)
+	observeCall(chatRepositoryName, "ListByIDs", start, err)
+	return chats, err
+}
+
+// Update records the call of Update of the wrapped repository
+func (r *chatRepositoryMetrics) Update(ctx context.Context, chat *models.Chat) error {
+	start := time.Now()
+	err := r.next.Update(ctx, chat)
+	observeCall(chatRepositoryName, "Update", start, err)
+	return err
+}
+
+// AddUsage records the call of AddUsage of the wrapped repository
+func (r *chatRepositoryMetrics) AddUsage(ctx context.Context, id int64, tokens int, cost float64) error {
+	start := time.Now()
+	err := r.next.AddUsage(ctx, id, tokens, cost)
+	observeCall(chatRepositoryName, "AddUsage", start, err)
+	return err
+}
+
+// SetLocked records the call of SetLocked of the wrapped repository
+func (r *chatRepositoryMetrics) SetLocked(ctx context.Context, id int64, lockedBy string) error {
+	start := time.Now()
+	err := r.next.SetLocked(ctx, id, lockedBy)
+	observeCall(chatRepositoryName, "SetLocked", start, err)
+	return err
+}
+
+// MarkPendingDelete records the call of MarkPendingDelete of the wrapped repository
+func (r *chatRepositoryMetrics) MarkPendingDelete(ctx context.Context, id int64, deleteAt time.Time) error {
+	start := time.Now()
+	err := r.next.MarkPendingDelete(ctx, id, deleteAt)
+	observeCall(chatRepositoryName, "MarkPendingDelete", start, err)
+	return err
+}
+
+// UndoPendingDelete records the call of UndoPendingDelete of the wrapped repository
+func (r *chatRepositoryMetrics) UndoPendingDelete(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := r.next.UndoPendingDelete(ctx, id)
+	observeCall(chatRepositoryName, "UndoPendingDelete", start, err)
+	return err
+}
+
+// ListIDsPendingDelete records the call of ListIDsPendingDelete of the wrapped repository
+func (r *chatRepositoryMetrics) ListIDsPendingDelete(ctx context.Context, before time.Time, limit int) ([]int64, error) {
+	start := time.Now()
+	ids, err := r.next.ListIDsPendingDelete(ctx, before, limit)
+	observeCall(chatRepositoryName, "ListIDsPendingDelete", start, err)
+	return ids, err
+}
+
+// Delete records the call of Delete of the wrapped repository
+func (r *chatRepositoryMetrics) Delete(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	observeCall(chatRepositoryName, "Delete", start, err)
+	return err
+}
+
+// DeleteWithMessages records the call of DeleteWithMessages of the wrapped repository
+func (r *chatRepositoryMetrics) DeleteWithMessages(ctx context.Context, id int64) ([]int64, error) {
+	start := time.Now()
+	ids, err := r.next.DeleteWithMessages(ctx, id)
+	observeCall(chatRepositoryName, "DeleteWithMessages", start, err)
+	return ids, err
+}
+
+// Pin records the call of Pin of the wrapped repository
+func (r *chatRepositoryMetrics) Pin(ctx context.Context, chatID, messageID int64) error {
+	start := time.Now()
+	err := r.next.Pin(ctx, chatID, messageID)
+	observeCall(chatRepositoryName, "Pin", start, err)
+	return err
+}
+
+// UpdateSummary records the call of UpdateSummary of the wrapped repository
+func (r *chatRepositoryMetrics) UpdateSummary(ctx context.Context, chatID int64, summary string, messageID int64) error {
+	start := time.Now()
+	err := r.next.UpdateSummary(ctx, chatID, summary, messageID)
+	observeCall(chatRepositoryName, "UpdateSummary", start, err)
+	return err
+}
+
+// ListPendingSummary records the call of ListPendingSummary of the wrapped repository
+func (r *chatRepositoryMetrics) ListPendingSummary(ctx context.Context, minMessages, limit int) ([]*models.Chat, error) {
+	start := time.Now()
+	chats, err := r.next.ListPendingSummary(ctx, minMessages, limit)
+	observeCall(chatRepositoryName, "ListPendingSummary", start, err)
+	return chats, err
+}
+
+// ListIDs records the call of ListIDs of the wrapped repository
+func (r *chatRepositoryMetrics) ListIDs(ctx context.Context, chatIDs []int64, userIDs []string) ([]int64, error) {
+	start := time.Now()
+	ids, err := r.next.ListIDs(ctx, chatIDs, userIDs)
+	observeCall(chatRepositoryName, "ListIDs", start, err)
+	return ids, err
+}
+
+// ListIDsCreatedBefore records the call of ListIDsCreatedBefore of the wrapped repository
+func (r *chatRepositoryMetrics) ListIDsCreatedBefore(ctx context.Context, userIDPrefix string, before time.Time, limit int) ([]int64, error) {
+	start := time.Now()
+	ids, err := r.next.ListIDsCreatedBefore(ctx, userIDPrefix, before, limit)
+	observeCall(chatRepositoryName, "ListIDsCreatedBefore", start, err)
+	return ids, err
+}
+
+// ListEphemeralIDs records the call of ListEphemeralIDs of the wrapped repository
+func (r *chatRepositoryMetrics) ListEphemeralIDs(ctx context.Context, before time.Time, afterID int64, limit int) ([]int64, error) {
+	start := time.Now()
+	ids, err := r.next.ListEphemeralIDs(ctx, before, afterID, limit)
+	observeCall(chatRepositoryName, "ListEphemeralIDs", start, err)
+	return ids, err
+}
+
+// TransferOwnership records the call of TransferOwnership of the wrapped repository
+func (r *chatRepositoryMetrics) TransferOwnership(ctx context.Context, fromUserID, toUserID string) ([]*models.Chat, error) {
+	start := time.Now()
+	chats, err := r.next.TransferOwnership(ctx, fromUserID, toUserID)
+	observeCall(chatRepositoryName, "TransferOwnership", start, err)
+	return chats, err
+}
+
+// Transfer records the call of Transfer of the wrapped repository
+func (r *chatRepositoryMetrics) Transfer(ctx context.Context, id int64, toUserID, transferredBy string) (*models.Chat, string, error) {
+	start := time.Now()
+	chat, previousUserID, err := r.next.Transfer(ctx, id, toUserID, transferredBy)
+	observeCall(chatRepositoryName, "Transfer", start, err)
+	return chat, previousUserID, err
+}
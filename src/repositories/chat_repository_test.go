@@ -69,7 +69,7 @@ func getEnvOrDefault(key, defaultValue string) string {
 
 func setupTest(t *testing.T) (ChatRepository, func()) {
 	// Create a new repository instance
-	repo := NewChatRepository(testDB)
+	repo := NewChatRepository(testDB, nil)
 
 	// Create cleanup function
 	cleanup := func() {
@@ -175,9 +175,8 @@ func TestChatRepository_Search(t *testing.T) {
 
 		// Search for chats
 		req := &dtos.SearchChatsRequest{
-			Query:  "AI",
-			Limit:  10,
-			Offset: 0,
+			Query:       "AI",
+			PageRequest: dtos.PageRequest{Limit: 10, Offset: 0},
 		}
 
 		chats, total, err := repo.Search(context.Background(), req, userID)
@@ -188,9 +187,8 @@ func TestChatRepository_Search(t *testing.T) {
 
 	t.Run("no results", func(t *testing.T) {
 		req := &dtos.SearchChatsRequest{
-			Query:  "NonexistentTerm",
-			Limit:  10,
-			Offset: 0,
+			Query:       "NonexistentTerm",
+			PageRequest: dtos.PageRequest{Limit: 10, Offset: 0},
 		}
 
 		chats, total, err := repo.Search(context.Background(), req, "user1")
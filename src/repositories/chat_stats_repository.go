@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// ChatStatsRepository defines the interface for chat statistics projection access
+type ChatStatsRepository interface {
+	// RecordMessage counts a created message in the stats of its chat. Messages at or
+	// below the last counted message are ignored, so replays are idempotent.
+	RecordMessage(ctx context.Context, chatID, messageID int64, tokens int, at time.Time) error
+
+	// RecordDeletion removes a deleted message from the count of its chat
+	RecordDeletion(ctx context.Context, chatID int64) error
+
+	// GetByChatIDs retrieves the stats of the given chats, keyed by chat ID
+	GetByChatIDs(ctx context.Context, chatIDs []int64) (map[int64]*models.ChatStats, error)
+
+	// SumByUserID sums the stats of a user's chats
+	SumByUserID(ctx context.Context, userID string) (*models.ChatStats, error)
+
+	// Reset deletes the stats of every chat, before rebuilding them from the message events
+	Reset(ctx context.Context) error
+}
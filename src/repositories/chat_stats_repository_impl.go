@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// chatStatsRepository implements the ChatStatsRepository interface
+type chatStatsRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewChatStatsRepository creates a new chat stats repository
+func NewChatStatsRepository(db adapters.DBAdapter) ChatStatsRepository {
+	return &chatStatsRepository{db: db}
+}
+
+// recordMessageSQL upserts the stats of a chat with a created message. Events of
+// deleted chats are skipped, and messages already counted leave the row unchanged.
+const recordMessageSQL = `
+INSERT INTO chat_stats (chat_id, message_count, total_tokens, last_message_id, last_activity_at, updated_at)
+SELECT ?, 1, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM chats WHERE id = ?)
+ON CONFLICT (chat_id) DO UPDATE SET
+    message_count = chat_stats.message_count + 1,
+    total_tokens = chat_stats.total_tokens + EXCLUDED.total_tokens,
+    last_message_id = EXCLUDED.last_message_id,
+    last_activity_at = GREATEST(chat_stats.last_activity_at, EXCLUDED.last_activity_at),
+    updated_at = EXCLUDED.updated_at
+WHERE chat_stats.last_message_id < EXCLUDED.last_message_id`
+
+// RecordMessage counts a created message in the stats of its chat
+func (r *chatStatsRepository) RecordMessage(ctx context.Context, chatID, messageID int64, tokens int, at time.Time) error {
+	log := logger.Context(ctx)
+
+	if err := r.db.GetDB().WithContext(ctx).Exec(recordMessageSQL, chatID, tokens, messageID, at, time.Now(), chatID).Error; err != nil {
+		log.Errorw("Failed to record message in chat stats", "error", err, "chatID", chatID, "messageID", messageID)
+		return dbError(err, "Failed to update chat stats")
+	}
+
+	return nil
+}
+
+// RecordDeletion removes a deleted message from the count of its chat
+func (r *chatStatsRepository) RecordDeletion(ctx context.Context, chatID int64) error {
+	log := logger.Context(ctx)
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.ChatStats{}).
+		Where("chat_id = ? AND message_count > 0", chatID).
+		Updates(map[string]interface{}{
+			"message_count": gorm.Expr("message_count - 1"),
+			"updated_at":    time.Now(),
+		}).Error; err != nil {
+		log.Errorw("Failed to record deletion in chat stats", "error", err, "chatID", chatID)
+		return dbError(err, "Failed to update chat stats")
+	}
+
+	return nil
+}
+
+// GetByChatIDs retrieves the stats of the given chats, keyed by chat ID
+func (r *chatStatsRepository) GetByChatIDs(ctx context.Context, chatIDs []int64) (map[int64]*models.ChatStats, error) {
+	log := logger.Context(ctx)
+	stats := make(map[int64]*models.ChatStats, len(chatIDs))
+
+	if len(chatIDs) == 0 {
+		return stats, nil
+	}
+
+	var rows []*models.ChatStats
+	if err := r.db.GetDB().WithContext(ctx).Where("chat_id IN ?", chatIDs).Find(&rows).Error; err != nil {
+		log.Errorw("Failed to get chat stats", "error", err)
+		return nil, dbError(err, "Failed to get chat stats")
+	}
+
+	for _, row := range rows {
+		stats[row.ChatID] = row
+	}
+	return stats, nil
+}
+
+// SumByUserID sums the stats of a user's chats
+func (r *chatStatsRepository) SumByUserID(ctx context.Context, userID string) (*models.ChatStats, error) {
+	log := logger.Context(ctx)
+	var sum struct {
+		MessageCount   int64
+		TotalTokens    int64
+		LastActivityAt *time.Time
+	}
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.ChatStats{}).
+		Select("COALESCE(SUM(chat_stats.message_count), 0) AS message_count, "+
+			"COALESCE(SUM(chat_stats.total_tokens), 0) AS total_tokens, "+
+			"MAX(chat_stats.last_activity_at) AS last_activity_at").
+		Joins("JOIN chats ON chats.id = chat_stats.chat_id").
+		Where("chats.user_id = ?", userID).
+		Scan(&sum).Error; err != nil {
+		log.Errorw("Failed to sum chat stats", "error", err, "userID", userID)
+		return nil, dbError(err, "Failed to get chat stats")
+	}
+
+	stats := &models.ChatStats{MessageCount: sum.MessageCount, TotalTokens: sum.TotalTokens}
+	if sum.LastActivityAt != nil {
+		stats.LastActivityAt = *sum.LastActivityAt
+	}
+	return stats, nil
+}
+
+// Reset deletes the stats of every chat, before rebuilding them from the message events
+func (r *chatStatsRepository) Reset(ctx context.Context) error {
+	log := logger.Context(ctx)
+
+	if err := r.db.GetDB().WithContext(ctx).Where("1 = 1").Delete(&models.ChatStats{}).Error; err != nil {
+		log.Errorw("Failed to reset chat stats", "error", err)
+		return dbError(err, "Failed to reset chat stats")
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// ChatWebhookRepository defines the interface for chat webhook data access
+type ChatWebhookRepository interface {
+	// Create creates a webhook
+	Create(ctx context.Context, webhook *models.ChatWebhook) error
+
+	// ListByChatID retrieves the webhooks of a chat, oldest first
+	ListByChatID(ctx context.Context, chatID int64) ([]*models.ChatWebhook, error)
+
+	// CountByChatID counts the webhooks of a chat
+	CountByChatID(ctx context.Context, chatID int64) (int64, error)
+
+	// Delete deletes a webhook of a chat, along with its pending deliveries
+	Delete(ctx context.Context, chatID, id int64) error
+
+	// RecordResult records the latest exchange delivered to a webhook, when deliveredAt is
+	// set, or why the latest exchange given up could not be
+	RecordResult(ctx context.Context, id int64, deliveredAt *time.Time, lastError string) error
+
+	// CreateDeliveries creates deliveries of exchanges
+	CreateDeliveries(ctx context.Context, deliveries []*models.ChatWebhookDelivery) error
+
+	// ListDueDeliveries retrieves the deliveries whose next attempt is due, oldest first, with
+	// their webhook
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.ChatWebhookDelivery, error)
+
+	// Reschedule records a failed attempt of a delivery and when to make the next one
+	Reschedule(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time) error
+
+	// DeleteDelivery deletes a delivery, once delivered or given up
+	DeleteDelivery(ctx context.Context, id int64) error
+}
@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// chatWebhookRepository implements the ChatWebhookRepository interface
+type chatWebhookRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewChatWebhookRepository creates a new chat webhook repository
+func NewChatWebhookRepository(db adapters.DBAdapter) ChatWebhookRepository {
+	return &chatWebhookRepository{db: db}
+}
+
+// Create creates a webhook
+func (r *chatWebhookRepository) Create(ctx context.Context, webhook *models.ChatWebhook) error {
+	log := logger.Context(ctx)
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = webhook.CreatedAt
+
+	if err := r.db.GetDB().WithContext(ctx).Create(webhook).Error; err != nil {
+		log.Errorw("Failed to create chat webhook", "error", err, "chatID", webhook.ChatID)
+		return dbError(err, "Failed to create webhook")
+	}
+
+	return nil
+}
+
+// ListByChatID retrieves the webhooks of a chat, oldest first
+func (r *chatWebhookRepository) ListByChatID(ctx context.Context, chatID int64) ([]*models.ChatWebhook, error) {
+	log := logger.Context(ctx)
+	var webhooks []*models.ChatWebhook
+
+	if err := r.db.GetDB().WithContext(ctx).Where("chat_id = ?", chatID).Order("id").Find(&webhooks).Error; err != nil {
+		log.Errorw("Failed to list chat webhooks", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to list webhooks")
+	}
+
+	return webhooks, nil
+}
+
+// CountByChatID counts the webhooks of a chat
+func (r *chatWebhookRepository) CountByChatID(ctx context.Context, chatID int64) (int64, error) {
+	log := logger.Context(ctx)
+	var count int64
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.ChatWebhook{}).Where("chat_id = ?", chatID).Count(&count).Error; err != nil {
+		log.Errorw("Failed to count chat webhooks", "error", err, "chatID", chatID)
+		return 0, dbError(err, "Failed to count webhooks")
+	}
+
+	return count, nil
+}
+
+// Delete deletes a webhook of a chat, along with its pending deliveries
+func (r *chatWebhookRepository) Delete(ctx context.Context, chatID, id int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Where("chat_id = ? AND id = ?", chatID, id).Delete(&models.ChatWebhook{})
+	if result.Error != nil {
+		log.Errorw("Failed to delete chat webhook", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to delete webhook")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Webhook not found")
+	}
+
+	return nil
+}
+
+// RecordResult records the latest exchange delivered to a webhook, when deliveredAt is set,
+// or why the latest exchange given up could not be
+func (r *chatWebhookRepository) RecordResult(ctx context.Context, id int64, deliveredAt *time.Time, lastError string) error {
+	log := logger.Context(ctx)
+
+	columns := map[string]interface{}{"last_error": lastError, "updated_at": time.Now()}
+	if deliveredAt != nil {
+		columns["last_delivered_at"] = *deliveredAt
+	}
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.ChatWebhook{}).Where("id = ?", id).Updates(columns).Error; err != nil {
+		log.Errorw("Failed to record chat webhook result", "error", err, "id", id)
+		return dbError(err, "Failed to update webhook")
+	}
+
+	return nil
+}
+
+// CreateDeliveries creates deliveries of exchanges
+func (r *chatWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*models.ChatWebhookDelivery) error {
+	log := logger.Context(ctx)
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for _, delivery := range deliveries {
+		delivery.CreatedAt = now
+	}
+	if err := r.db.GetDB().WithContext(ctx).Omit("Webhook").Create(&deliveries).Error; err != nil {
+		log.Errorw("Failed to create chat webhook deliveries", "error", err, "count", len(deliveries))
+		return dbError(err, "Failed to create webhook deliveries")
+	}
+
+	return nil
+}
+
+// ListDueDeliveries retrieves the deliveries whose next attempt is due, oldest first, with
+// their webhook
+func (r *chatWebhookRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.ChatWebhookDelivery, error) {
+	log := logger.Context(ctx)
+	var deliveries []*models.ChatWebhookDelivery
+
+	if err := r.db.GetDB().WithContext(ctx).Preload("Webhook").
+		Where("next_attempt_at <= ?", now).
+		Order("id").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		log.Errorw("Failed to list due chat webhook deliveries", "error", err)
+		return nil, dbError(err, "Failed to list webhook deliveries")
+	}
+
+	return deliveries, nil
+}
+
+// Reschedule records a failed attempt of a delivery and when to make the next one
+func (r *chatWebhookRepository) Reschedule(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time) error {
+	log := logger.Context(ctx)
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.ChatWebhookDelivery{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"attempts": attempts, "next_attempt_at": nextAttemptAt}).Error; err != nil {
+		log.Errorw("Failed to reschedule chat webhook delivery", "error", err, "id", id)
+		return dbError(err, "Failed to update webhook delivery")
+	}
+
+	return nil
+}
+
+// DeleteDelivery deletes a delivery, once delivered or given up
+func (r *chatWebhookRepository) DeleteDelivery(ctx context.Context, id int64) error {
+	log := logger.Context(ctx)
+
+	if err := r.db.GetDB().WithContext(ctx).Delete(&models.ChatWebhookDelivery{}, id).Error; err != nil {
+		log.Errorw("Failed to delete chat webhook delivery", "error", err, "id", id)
+		return dbError(err, "Failed to delete webhook delivery")
+	}
+
+	return nil
+}
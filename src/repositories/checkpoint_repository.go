@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// CheckpointRepository defines the interface for chat checkpoint data access
+type CheckpointRepository interface {
+	// Create creates a checkpoint
+	Create(ctx context.Context, checkpoint *models.Checkpoint) error
+
+	// Get retrieves a checkpoint of a chat
+	Get(ctx context.Context, chatID, id int64) (*models.Checkpoint, error)
+
+	// ListByChatID retrieves the checkpoints of a chat, newest first
+	ListByChatID(ctx context.Context, chatID int64) ([]*models.Checkpoint, error)
+
+	// CountByChatID counts the checkpoints of a chat
+	CountByChatID(ctx context.Context, chatID int64) (int64, error)
+
+	// Delete deletes a checkpoint of a chat
+	Delete(ctx context.Context, chatID, id int64) error
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// checkpointRepository implements the CheckpointRepository interface
+type checkpointRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewCheckpointRepository creates a new checkpoint repository
+func NewCheckpointRepository(db adapters.DBAdapter) CheckpointRepository {
+	return &checkpointRepository{db: db}
+}
+
+// Create creates a checkpoint
+func (r *checkpointRepository) Create(ctx context.Context, checkpoint *models.Checkpoint) error {
+	log := logger.Context(ctx)
+	checkpoint.CreatedAt = time.Now()
+
+	if err := r.db.GetDB().WithContext(ctx).Create(checkpoint).Error; err != nil {
+		log.Errorw("Failed to create checkpoint", "error", err, "chatID", checkpoint.ChatID)
+		return dbError(err, "Failed to create checkpoint")
+	}
+
+	return nil
+}
+
+// Get retrieves a checkpoint of a chat
+func (r *checkpointRepository) Get(ctx context.Context, chatID, id int64) (*models.Checkpoint, error) {
+	log := logger.Context(ctx)
+	var checkpoint models.Checkpoint
+
+	result := r.db.GetDB().WithContext(ctx).Where("chat_id = ?", chatID).First(&checkpoint, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Checkpoint not found")
+		}
+		log.Errorw("Failed to get checkpoint", "error", result.Error, "checkpointID", id)
+		return nil, dbError(result.Error, "Failed to get checkpoint")
+	}
+
+	return &checkpoint, nil
+}
+
+// ListByChatID retrieves the checkpoints of a chat, newest first
+func (r *checkpointRepository) ListByChatID(ctx context.Context, chatID int64) ([]*models.Checkpoint, error) {
+	log := logger.Context(ctx)
+	var checkpoints []*models.Checkpoint
+
+	if err := r.db.GetDB().WithContext(ctx).Where("chat_id = ?", chatID).Order("id DESC").Find(&checkpoints).Error; err != nil {
+		log.Errorw("Failed to list checkpoints", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to list checkpoints")
+	}
+
+	return checkpoints, nil
+}
+
+// CountByChatID counts the checkpoints of a chat
+func (r *checkpointRepository) CountByChatID(ctx context.Context, chatID int64) (int64, error) {
+	log := logger.Context(ctx)
+	var count int64
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Checkpoint{}).Where("chat_id = ?", chatID).Count(&count).Error; err != nil {
+		log.Errorw("Failed to count checkpoints", "error", err, "chatID", chatID)
+		return 0, dbError(err, "Failed to count checkpoints")
+	}
+
+	return count, nil
+}
+
+// Delete deletes a checkpoint of a chat
+func (r *checkpointRepository) Delete(ctx context.Context, chatID, id int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Where("chat_id = ? AND id = ?", chatID, id).Delete(&models.Checkpoint{})
+	if result.Error != nil {
+		log.Errorw("Failed to delete checkpoint", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to delete checkpoint")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Checkpoint not found")
+	}
+
+	return nil
+}
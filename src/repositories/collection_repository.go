@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// CollectionRepository defines the interface for document collection data access
+type CollectionRepository interface {
+	// Create creates a collection
+	Create(ctx context.Context, collection *models.Collection) error
+
+	// Get retrieves a collection by ID
+	Get(ctx context.Context, id int64) (*models.Collection, error)
+
+	// ListByUserID retrieves the collections of a user in creation order
+	ListByUserID(ctx context.Context, userID string) ([]*models.Collection, error)
+
+	// Update updates the name, description and chunking of a collection
+	Update(ctx context.Context, collection *models.Collection) error
+
+	// Delete deletes a collection with its documents and chat attachments
+	Delete(ctx context.Context, id int64) error
+
+	// Attach attaches a collection to a chat; attaching it again does nothing
+	Attach(ctx context.Context, chatID, collectionID int64) error
+
+	// Detach detaches a collection from a chat
+	Detach(ctx context.Context, chatID, collectionID int64) error
+
+	// ListByChatID retrieves the collections attached to a chat
+	ListByChatID(ctx context.Context, chatID int64) ([]*models.Collection, error)
+}
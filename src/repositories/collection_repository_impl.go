@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// collectionRepository implements the CollectionRepository interface
+type collectionRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewCollectionRepository creates a new document collection repository
+func NewCollectionRepository(db adapters.DBAdapter) CollectionRepository {
+	return &collectionRepository{db: db}
+}
+
+// Create creates a collection
+func (r *collectionRepository) Create(ctx context.Context, collection *models.Collection) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	collection.CreatedAt = now
+	collection.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(collection).Error; err != nil {
+		log.Errorw("Failed to create collection", "error", err, "userID", collection.UserID)
+		return dbError(err, "Failed to create collection")
+	}
+
+	return nil
+}
+
+// Get retrieves a collection by ID
+func (r *collectionRepository) Get(ctx context.Context, id int64) (*models.Collection, error) {
+	log := logger.Context(ctx)
+	var collection models.Collection
+
+	result := r.db.GetDB().WithContext(ctx).First(&collection, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Collection not found")
+		}
+		log.Errorw("Failed to get collection", "error", result.Error, "collectionID", id)
+		return nil, dbError(result.Error, "Failed to get collection")
+	}
+
+	return &collection, nil
+}
+
+// ListByUserID retrieves the collections of a user in creation order
+func (r *collectionRepository) ListByUserID(ctx context.Context, userID string) ([]*models.Collection, error) {
+	log := logger.Context(ctx)
+	var collections []*models.Collection
+
+	if err := r.db.GetDB().WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&collections).Error; err != nil {
+		log.Errorw("Failed to list collections", "error", err, "userID", userID)
+		return nil, dbError(err, "Failed to list collections")
+	}
+
+	return collections, nil
+}
+
+// Update updates the name, description and chunking of a collection
+func (r *collectionRepository) Update(ctx context.Context, collection *models.Collection) error {
+	log := logger.Context(ctx)
+	collection.UpdatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Model(collection).Updates(map[string]interface{}{
+		"name":          collection.Name,
+		"description":   collection.Description,
+		"chunk_size":    collection.ChunkSize,
+		"chunk_overlap": collection.ChunkOverlap,
+		"updated_at":    collection.UpdatedAt,
+	})
+	if result.Error != nil {
+		log.Errorw("Failed to update collection", "error", result.Error, "collectionID", collection.ID)
+		return dbError(result.Error, "Failed to update collection")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Collection not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a collection; its documents, chunks and chat attachments are deleted by cascade
+func (r *collectionRepository) Delete(ctx context.Context, id int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Delete(&models.Collection{}, id)
+	if result.Error != nil {
+		log.Errorw("Failed to delete collection", "error", result.Error, "collectionID", id)
+		return dbError(result.Error, "Failed to delete collection")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Collection not found")
+	}
+
+	return nil
+}
+
+// Attach attaches a collection to a chat; attaching it again does nothing
+func (r *collectionRepository) Attach(ctx context.Context, chatID, collectionID int64) error {
+	log := logger.Context(ctx)
+
+	link := &models.ChatCollection{ChatID: chatID, CollectionID: collectionID, CreatedAt: time.Now()}
+	if err := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(link).Error; err != nil {
+		log.Errorw("Failed to attach collection", "error", err, "chatID", chatID, "collectionID", collectionID)
+		return dbError(err, "Failed to attach collection")
+	}
+
+	return nil
+}
+
+// Detach detaches a collection from a chat
+func (r *collectionRepository) Detach(ctx context.Context, chatID, collectionID int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).
+		Where("chat_id = ? AND collection_id = ?", chatID, collectionID).
+		Delete(&models.ChatCollection{})
+	if result.Error != nil {
+		log.Errorw("Failed to detach collection", "error", result.Error, "chatID", chatID, "collectionID", collectionID)
+		return dbError(result.Error, "Failed to detach collection")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Collection is not attached to this chat")
+	}
+
+	return nil
+}
+
+// ListByChatID retrieves the collections attached to a chat, in the order they were attached
+func (r *collectionRepository) ListByChatID(ctx context.Context, chatID int64) ([]*models.Collection, error) {
+	log := logger.Context(ctx)
+	var collections []*models.Collection
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Joins("JOIN chat_collections ON chat_collections.collection_id = collections.id").
+		Where("chat_collections.chat_id = ?", chatID).
+		Order("chat_collections.created_at, collections.id").
+		Find(&collections).Error; err != nil {
+		log.Errorw("Failed to list chat collections", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to list chat collections")
+	}
+
+	return collections, nil
+}
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// ConsentRepository defines the interface for consent data access
+type ConsentRepository interface {
+	// Create records a consent; accepting a terms version again keeps the first acceptance
+	Create(ctx context.Context, consent *models.Consent) error
+
+	// Exists reports whether a user accepted a terms version
+	Exists(ctx context.Context, userID, termsVersion string) (bool, error)
+
+	// ListByUserID lists the consents of a user, oldest first
+	ListByUserID(ctx context.Context, userID string) ([]*models.Consent, error)
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm/clause"
+)
+
+// consentRepository implements the ConsentRepository interface
+type consentRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewConsentRepository creates a new consent repository
+func NewConsentRepository(db adapters.DBAdapter) ConsentRepository {
+	return &consentRepository{db: db}
+}
+
+// Create records a consent; accepting a terms version again keeps the first acceptance
+func (r *consentRepository) Create(ctx context.Context, consent *models.Consent) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "terms_version"}},
+		DoNothing: true,
+	}).Create(consent)
+	if result.Error != nil {
+		log.Errorw("Failed to record consent", "error", result.Error, "userID", consent.UserID)
+		return dbError(result.Error, "Failed to record consent")
+	}
+
+	return nil
+}
+
+// Exists reports whether a user accepted a terms version
+func (r *consentRepository) Exists(ctx context.Context, userID, termsVersion string) (bool, error) {
+	log := logger.Context(ctx)
+	var count int64
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Consent{}).
+		Where("user_id = ? AND terms_version = ?", userID, termsVersion).
+		Count(&count).Error; err != nil {
+		log.Errorw("Failed to check consent", "error", err, "userID", userID)
+		return false, dbError(err, "Failed to check consent")
+	}
+
+	return count > 0, nil
+}
+
+// ListByUserID lists the consents of a user, oldest first
+func (r *consentRepository) ListByUserID(ctx context.Context, userID string) ([]*models.Consent, error) {
+	log := logger.Context(ctx)
+	var consents []*models.Consent
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("accepted_at ASC, id ASC").
+		Find(&consents).Error; err != nil {
+		log.Errorw("Failed to list consents", "error", err, "userID", userID)
+		return nil, dbError(err, "Failed to list consents")
+	}
+
+	return consents, nil
+}
@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// CrawlRepository defines the interface for website crawl data access
+type CrawlRepository interface {
+	// Create creates a new crawl
+	Create(ctx context.Context, crawl *models.Crawl) error
+
+	// Get retrieves a crawl of a collection
+	Get(ctx context.Context, collectionID, id int64) (*models.Crawl, error)
+
+	// ListByCollectionID retrieves the crawls of a collection, most recent first
+	ListByCollectionID(ctx context.Context, collectionID int64) ([]*models.Crawl, error)
+
+	// ListPending retrieves the oldest crawls waiting to run
+	ListPending(ctx context.Context, limit int) ([]*models.Crawl, error)
+
+	// Update saves the status and progress of a crawl
+	Update(ctx context.Context, crawl *models.Crawl) error
+}
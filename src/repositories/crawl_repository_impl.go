@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// crawlRepository implements the CrawlRepository interface
+type crawlRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewCrawlRepository creates a new website crawl repository
+func NewCrawlRepository(db adapters.DBAdapter) CrawlRepository {
+	return &crawlRepository{db: db}
+}
+
+// Create creates a new crawl
+func (r *crawlRepository) Create(ctx context.Context, crawl *models.Crawl) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	crawl.CreatedAt = now
+	crawl.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(crawl).Error; err != nil {
+		log.Errorw("Failed to create crawl", "error", err, "collectionID", crawl.CollectionID)
+		return dbError(err, "Failed to create crawl")
+	}
+
+	return nil
+}
+
+// Get retrieves a crawl of a collection
+func (r *crawlRepository) Get(ctx context.Context, collectionID, id int64) (*models.Crawl, error) {
+	log := logger.Context(ctx)
+	var crawl models.Crawl
+
+	result := r.db.GetDB().WithContext(ctx).Where("collection_id = ?", collectionID).First(&crawl, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Crawl not found")
+		}
+		log.Errorw("Failed to get crawl", "error", result.Error, "crawlID", id)
+		return nil, dbError(result.Error, "Failed to get crawl")
+	}
+
+	return &crawl, nil
+}
+
+// ListByCollectionID retrieves the crawls of a collection, most recent first
+func (r *crawlRepository) ListByCollectionID(ctx context.Context, collectionID int64) ([]*models.Crawl, error) {
+	log := logger.Context(ctx)
+	var crawls []*models.Crawl
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("collection_id = ?", collectionID).
+		Order("id DESC").
+		Find(&crawls).Error; err != nil {
+		log.Errorw("Failed to list crawls", "error", err, "collectionID", collectionID)
+		return nil, dbError(err, "Failed to list crawls")
+	}
+
+	return crawls, nil
+}
+
+// ListPending retrieves the oldest crawls waiting to run
+func (r *crawlRepository) ListPending(ctx context.Context, limit int) ([]*models.Crawl, error) {
+	log := logger.Context(ctx)
+	var crawls []*models.Crawl
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("status = ?", models.CrawlStatusPending).
+		Order("id ASC").
+		Limit(limit).
+		Find(&crawls).Error; err != nil {
+		log.Errorw("Failed to list pending crawls", "error", err)
+		return nil, dbError(err, "Failed to list pending crawls")
+	}
+
+	return crawls, nil
+}
+
+// Update saves the status and progress of a crawl
+func (r *crawlRepository) Update(ctx context.Context, crawl *models.Crawl) error {
+	log := logger.Context(ctx)
+	crawl.UpdatedAt = time.Now()
+
+	if err := r.db.GetDB().WithContext(ctx).Save(crawl).Error; err != nil {
+		log.Errorw("Failed to update crawl", "error", err, "crawlID", crawl.ID)
+		return dbError(err, "Failed to update crawl")
+	}
+
+	return nil
+}
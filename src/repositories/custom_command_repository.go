@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// CustomCommandRepository defines the interface for the data access of the slash commands
+// defined for the workspace
+type CustomCommandRepository interface {
+	// Create creates a custom command
+	Create(ctx context.Context, command *models.CustomCommand) error
+
+	// List retrieves every custom command by name
+	List(ctx context.Context) ([]*models.CustomCommand, error)
+
+	// GetByName retrieves a custom command by its name
+	GetByName(ctx context.Context, name string) (*models.CustomCommand, error)
+
+	// Update updates the description and template of a custom command
+	Update(ctx context.Context, command *models.CustomCommand) error
+
+	// Delete deletes a custom command
+	Delete(ctx context.Context, id int64) error
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// customCommandRepository implements the CustomCommandRepository interface
+type customCommandRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewCustomCommandRepository creates a new custom command repository
+func NewCustomCommandRepository(db adapters.DBAdapter) CustomCommandRepository {
+	return &customCommandRepository{db: db}
+}
+
+// Create creates a custom command
+func (r *customCommandRepository) Create(ctx context.Context, command *models.CustomCommand) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	command.CreatedAt = now
+	command.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(command).Error; err != nil {
+		log.Errorw("Failed to create custom command", "error", err, "name", command.Name)
+		return dbError(err, "Failed to create custom command")
+	}
+
+	return nil
+}
+
+// List retrieves every custom command by name
+func (r *customCommandRepository) List(ctx context.Context) ([]*models.CustomCommand, error) {
+	log := logger.Context(ctx)
+	var commands []*models.CustomCommand
+
+	if err := r.db.GetDB().WithContext(ctx).Order("name").Find(&commands).Error; err != nil {
+		log.Errorw("Failed to list custom commands", "error", err)
+		return nil, dbError(err, "Failed to list custom commands")
+	}
+
+	return commands, nil
+}
+
+// GetByName retrieves a custom command by its name
+func (r *customCommandRepository) GetByName(ctx context.Context, name string) (*models.CustomCommand, error) {
+	log := logger.Context(ctx)
+	var command models.CustomCommand
+
+	result := r.db.GetDB().WithContext(ctx).Where("name = ?", name).First(&command)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Command not found")
+		}
+		log.Errorw("Failed to get custom command", "error", result.Error, "name", name)
+		return nil, dbError(result.Error, "Failed to get custom command")
+	}
+
+	return &command, nil
+}
+
+// Update updates the description and template of a custom command
+func (r *customCommandRepository) Update(ctx context.Context, command *models.CustomCommand) error {
+	log := logger.Context(ctx)
+	command.UpdatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Model(command).Updates(map[string]interface{}{
+		"description": command.Description,
+		"template":    command.Template,
+		"updated_by":  command.UpdatedBy,
+		"updated_at":  command.UpdatedAt,
+	})
+	if result.Error != nil {
+		log.Errorw("Failed to update custom command", "error", result.Error, "id", command.ID)
+		return dbError(result.Error, "Failed to update custom command")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Command not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a custom command
+func (r *customCommandRepository) Delete(ctx context.Context, id int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Delete(&models.CustomCommand{}, id)
+	if result.Error != nil {
+		log.Errorw("Failed to delete custom command", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to delete custom command")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Command not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// DeadLetterRepository defines the interface for dead letter data access
+type DeadLetterRepository interface {
+	// Create creates a new dead letter
+	Create(ctx context.Context, deadLetter *models.DeadLetter) error
+
+	// Get retrieves a dead letter by ID
+	Get(ctx context.Context, id int64) (*models.DeadLetter, error)
+
+	// List retrieves dead letters, optionally filtered by topic, newest first
+	List(ctx context.Context, topic string, limit, offset int) ([]*models.DeadLetter, int64, error)
+
+	// MarkReplayed records that a dead letter was replayed
+	MarkReplayed(ctx context.Context, id int64) error
+}
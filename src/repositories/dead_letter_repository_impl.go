@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// deadLetterRepository implements the DeadLetterRepository interface
+type deadLetterRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewDeadLetterRepository creates a new dead letter repository
+func NewDeadLetterRepository(db adapters.DBAdapter) DeadLetterRepository {
+	return &deadLetterRepository{db: db}
+}
+
+// Create creates a new dead letter
+func (r *deadLetterRepository) Create(ctx context.Context, deadLetter *models.DeadLetter) error {
+	log := logger.Context(ctx)
+	deadLetter.CreatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Create(deadLetter)
+	if result.Error != nil {
+		log.Errorw("Failed to create dead letter", "error", result.Error)
+		return dbError(result.Error, "Failed to create dead letter")
+	}
+
+	return nil
+}
+
+// Get retrieves a dead letter by ID
+func (r *deadLetterRepository) Get(ctx context.Context, id int64) (*models.DeadLetter, error) {
+	log := logger.Context(ctx)
+	var deadLetter models.DeadLetter
+
+	result := r.db.GetDB().WithContext(ctx).First(&deadLetter, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			log.Debugw("Dead letter not found", "id", id)
+			return nil, errors.New(errors.ErrNotFound, "Dead letter not found")
+		}
+		log.Errorw("Failed to get dead letter", "error", result.Error, "id", id)
+		return nil, dbError(result.Error, "Failed to get dead letter")
+	}
+
+	return &deadLetter, nil
+}
+
+// List retrieves dead letters, optionally filtered by topic, newest first
+func (r *deadLetterRepository) List(ctx context.Context, topic string, limit, offset int) ([]*models.DeadLetter, int64, error) {
+	log := logger.Context(ctx)
+	var deadLetters []*models.DeadLetter
+	var total int64
+
+	query := r.db.GetDB().WithContext(ctx).Model(&models.DeadLetter{})
+	if topic != "" {
+		query = query.Where("topic = ?", topic)
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		log.Errorw("Failed to count dead letters", "error", err, "topic", topic)
+		return nil, 0, dbError(err, "Failed to count dead letters")
+	}
+
+	// Get dead letters with pagination
+	if err := query.Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deadLetters).Error; err != nil {
+		log.Errorw("Failed to get dead letters", "error", err, "topic", topic)
+		return nil, 0, dbError(err, "Failed to get dead letters")
+	}
+
+	return deadLetters, total, nil
+}
+
+// MarkReplayed records that a dead letter was replayed
+func (r *deadLetterRepository) MarkReplayed(ctx context.Context, id int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.DeadLetter{}).
+		Where("id = ?", id).
+		Update("replayed_at", time.Now())
+
+	if result.Error != nil {
+		log.Errorw("Failed to mark dead letter as replayed", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to update dead letter")
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, fmt.Sprintf("Dead letter with ID %d not found", id))
+	}
+
+	return nil
+}
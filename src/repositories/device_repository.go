@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// DeviceRepository defines the interface for push device and chat mute data access
+type DeviceRepository interface {
+	// Upsert registers a device, moving its token to the user when another user registered it
+	Upsert(ctx context.Context, device *models.Device) error
+
+	// ListByUserID retrieves the devices of a user
+	ListByUserID(ctx context.Context, userID string) ([]*models.Device, error)
+
+	// Delete unregisters a device of a user
+	Delete(ctx context.Context, userID string, token string) error
+
+	// DeleteByToken unregisters a device whatever its user, for tokens the push service rejected
+	DeleteByToken(ctx context.Context, token string) error
+
+	// SetMute mutes a chat for a user
+	SetMute(ctx context.Context, mute *models.ChatMute) error
+
+	// DeleteMute unmutes a chat for a user
+	DeleteMute(ctx context.Context, chatID int64, userID string) error
+
+	// IsMuted reports whether a chat is currently muted for a user
+	IsMuted(ctx context.Context, chatID int64, userID string) (bool, error)
+}
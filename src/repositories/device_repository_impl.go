@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm/clause"
+)
+
+// deviceRepository implements the DeviceRepository interface
+type deviceRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewDeviceRepository creates a new push device repository
+func NewDeviceRepository(db adapters.DBAdapter) DeviceRepository {
+	return &deviceRepository{db: db}
+}
+
+// Upsert registers a device, moving its token to the user when another user registered it
+func (r *deviceRepository) Upsert(ctx context.Context, device *models.Device) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	device.CreatedAt = now
+	device.UpdatedAt = now
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "platform", "updated_at"}),
+	}).Create(device)
+	if result.Error != nil {
+		log.Errorw("Failed to register device", "error", result.Error, "userID", device.UserID)
+		return dbError(result.Error, "Failed to register device")
+	}
+
+	return nil
+}
+
+// ListByUserID retrieves the devices of a user
+func (r *deviceRepository) ListByUserID(ctx context.Context, userID string) ([]*models.Device, error) {
+	log := logger.Context(ctx)
+	var devices []*models.Device
+
+	if err := r.db.GetDB().WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&devices).Error; err != nil {
+		log.Errorw("Failed to list devices", "error", err, "userID", userID)
+		return nil, dbError(err, "Failed to list devices")
+	}
+
+	return devices, nil
+}
+
+// Delete unregisters a device of a user
+func (r *deviceRepository) Delete(ctx context.Context, userID string, token string) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Where("user_id = ? AND token = ?", userID, token).Delete(&models.Device{})
+	if result.Error != nil {
+		log.Errorw("Failed to unregister device", "error", result.Error, "userID", userID)
+		return dbError(result.Error, "Failed to unregister device")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Device not found")
+	}
+
+	return nil
+}
+
+// DeleteByToken unregisters a device whatever its user
+func (r *deviceRepository) DeleteByToken(ctx context.Context, token string) error {
+	log := logger.Context(ctx)
+
+	if err := r.db.GetDB().WithContext(ctx).Where("token = ?", token).Delete(&models.Device{}).Error; err != nil {
+		log.Errorw("Failed to delete device", "error", err)
+		return dbError(err, "Failed to delete device")
+	}
+
+	return nil
+}
+
+// SetMute mutes a chat for a user
+func (r *deviceRepository) SetMute(ctx context.Context, mute *models.ChatMute) error {
+	log := logger.Context(ctx)
+	mute.CreatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"until", "created_at"}),
+	}).Create(mute)
+	if result.Error != nil {
+		log.Errorw("Failed to mute chat", "error", result.Error, "chatID", mute.ChatID, "userID", mute.UserID)
+		return dbError(result.Error, "Failed to mute chat")
+	}
+
+	return nil
+}
+
+// DeleteMute unmutes a chat for a user
+func (r *deviceRepository) DeleteMute(ctx context.Context, chatID int64, userID string) error {
+	log := logger.Context(ctx)
+
+	if err := r.db.GetDB().WithContext(ctx).Where("chat_id = ? AND user_id = ?", chatID, userID).Delete(&models.ChatMute{}).Error; err != nil {
+		log.Errorw("Failed to unmute chat", "error", err, "chatID", chatID, "userID", userID)
+		return dbError(err, "Failed to unmute chat")
+	}
+
+	return nil
+}
+
+// IsMuted reports whether a chat is currently muted for a user
+func (r *deviceRepository) IsMuted(ctx context.Context, chatID int64, userID string) (bool, error) {
+	log := logger.Context(ctx)
+	var count int64
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.ChatMute{}).
+		Where("chat_id = ? AND user_id = ? AND (until IS NULL OR until > ?)", chatID, userID, time.Now()).
+		Count(&count).Error; err != nil {
+		log.Errorw("Failed to check chat mute", "error", err, "chatID", chatID, "userID", userID)
+		return false, dbError(err, "Failed to check chat mute")
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// DiscordRepository defines the interface for data access of the Discord integration
+type DiscordRepository interface {
+	// GetChannel retrieves the mapping of a Discord channel of a user to a chat
+	GetChannel(ctx context.Context, channelID, userID string) (*models.DiscordChannel, error)
+
+	// CreateChannel maps a Discord channel of a user to a chat. It reports whether the mapping
+	// was created, which it is not when the channel of the user is already mapped.
+	CreateChannel(ctx context.Context, channel *models.DiscordChannel) (bool, error)
+}
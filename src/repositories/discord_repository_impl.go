@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// discordRepository implements the DiscordRepository interface
+type discordRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewDiscordRepository creates a new Discord integration repository
+func NewDiscordRepository(db adapters.DBAdapter) DiscordRepository {
+	return &discordRepository{db: db}
+}
+
+// GetChannel retrieves the mapping of a Discord channel of a user to a chat
+func (r *discordRepository) GetChannel(ctx context.Context, channelID, userID string) (*models.DiscordChannel, error) {
+	log := logger.Context(ctx)
+	var channel models.DiscordChannel
+
+	result := r.db.GetDB().WithContext(ctx).Where("channel_id = ? AND user_id = ?", channelID, userID).First(&channel)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Discord channel not found")
+		}
+		log.Errorw("Failed to get Discord channel", "error", result.Error, "userID", userID)
+		return nil, dbError(result.Error, "Failed to get Discord channel")
+	}
+
+	return &channel, nil
+}
+
+// CreateChannel maps a Discord channel of a user to a chat, unless it is already mapped
+func (r *discordRepository) CreateChannel(ctx context.Context, channel *models.DiscordChannel) (bool, error) {
+	log := logger.Context(ctx)
+	channel.CreatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel_id"}, {Name: "user_id"}},
+		DoNothing: true,
+	}).Create(channel)
+	if result.Error != nil {
+		log.Errorw("Failed to create Discord channel", "error", result.Error, "userID", channel.UserID, "chatID", channel.ChatID)
+		return false, dbError(result.Error, "Failed to create Discord channel")
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// DocumentRepository defines the interface for the data access of collection documents
+// and their chunks
+type DocumentRepository interface {
+	// Create creates a document
+	Create(ctx context.Context, document *models.Document) error
+
+	// Replace creates a document, replacing the documents of its collection with the same source
+	Replace(ctx context.Context, document *models.Document) error
+
+	// Get retrieves a document of a collection, with its content
+	Get(ctx context.Context, collectionID, id int64) (*models.Document, error)
+
+	// ListByCollectionID retrieves the documents of a collection without their content
+	ListByCollectionID(ctx context.Context, collectionID int64) ([]*models.Document, error)
+
+	// Delete deletes a document of a collection with its chunks
+	Delete(ctx context.Context, collectionID, id int64) error
+
+	// Reindex marks the documents of a collection as pending, so they are chunked again,
+	// and returns their number
+	Reindex(ctx context.Context, collectionID int64) (int64, error)
+
+	// ListPending retrieves the oldest documents waiting to be chunked
+	ListPending(ctx context.Context, limit int) ([]*models.Document, error)
+
+	// SaveChunks replaces the chunks of a pending document and marks it as ready
+	SaveChunks(ctx context.Context, document *models.Document, chunks []*models.DocumentChunk) error
+
+	// MarkFailed marks a pending document as failed with the reason
+	MarkFailed(ctx context.Context, id int64, reason string) error
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// documentChunkBatchSize bounds the chunks inserted per statement
+const documentChunkBatchSize = 500
+
+// documentRepository implements the DocumentRepository interface
+type documentRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewDocumentRepository creates a new collection document repository
+func NewDocumentRepository(db adapters.DBAdapter) DocumentRepository {
+	return &documentRepository{db: db}
+}
+
+// Create creates a document
+func (r *documentRepository) Create(ctx context.Context, document *models.Document) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	document.CreatedAt = now
+	document.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(document).Error; err != nil {
+		log.Errorw("Failed to create document", "error", err, "collectionID", document.CollectionID)
+		return dbError(err, "Failed to create document")
+	}
+
+	return nil
+}
+
+// Replace creates a document, replacing the documents of its collection with the same
+// source along with their chunks
+func (r *documentRepository) Replace(ctx context.Context, document *models.Document) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	document.CreatedAt = now
+	document.UpdatedAt = now
+
+	err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ? AND source = ?", document.CollectionID, document.Source).
+			Delete(&models.Document{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(document).Error
+	})
+	if err != nil {
+		log.Errorw("Failed to replace document", "error", err, "collectionID", document.CollectionID)
+		return dbError(err, "Failed to replace document")
+	}
+
+	return nil
+}
+
+// Get retrieves a document of a collection, with its content
+func (r *documentRepository) Get(ctx context.Context, collectionID, id int64) (*models.Document, error) {
+	log := logger.Context(ctx)
+	var document models.Document
+
+	result := r.db.GetDB().WithContext(ctx).Where("collection_id = ?", collectionID).First(&document, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Document not found")
+		}
+		log.Errorw("Failed to get document", "error", result.Error, "documentID", id)
+		return nil, dbError(result.Error, "Failed to get document")
+	}
+
+	return &document, nil
+}
+
+// ListByCollectionID retrieves the documents of a collection without their content
+func (r *documentRepository) ListByCollectionID(ctx context.Context, collectionID int64) ([]*models.Document, error) {
+	log := logger.Context(ctx)
+	var documents []*models.Document
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Omit("content").
+		Where("collection_id = ?", collectionID).
+		Order("id").
+		Find(&documents).Error; err != nil {
+		log.Errorw("Failed to list documents", "error", err, "collectionID", collectionID)
+		return nil, dbError(err, "Failed to list documents")
+	}
+
+	return documents, nil
+}
+
+// Delete deletes a document of a collection; its chunks are deleted by cascade
+func (r *documentRepository) Delete(ctx context.Context, collectionID, id int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Where("collection_id = ? AND id = ?", collectionID, id).Delete(&models.Document{})
+	if result.Error != nil {
+		log.Errorw("Failed to delete document", "error", result.Error, "documentID", id)
+		return dbError(result.Error, "Failed to delete document")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Document not found")
+	}
+
+	return nil
+}
+
+// Reindex marks the documents of a collection as pending and returns their number
+func (r *documentRepository) Reindex(ctx context.Context, collectionID int64) (int64, error) {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Document{}).
+		Where("collection_id = ?", collectionID).
+		Updates(map[string]interface{}{
+			"status":     models.DocumentStatusPending,
+			"error":      "",
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Errorw("Failed to reindex documents", "error", result.Error, "collectionID", collectionID)
+		return 0, dbError(result.Error, "Failed to reindex documents")
+	}
+
+	return result.RowsAffected, nil
+}
+
+// ListPending retrieves the oldest documents waiting to be chunked
+func (r *documentRepository) ListPending(ctx context.Context, limit int) ([]*models.Document, error) {
+	log := logger.Context(ctx)
+	var documents []*models.Document
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("status = ?", models.DocumentStatusPending).
+		Order("updated_at ASC, id ASC").
+		Limit(limit).
+		Find(&documents).Error; err != nil {
+		log.Errorw("Failed to list pending documents", "error", err)
+		return nil, dbError(err, "Failed to list pending documents")
+	}
+
+	return documents, nil
+}
+
+// SaveChunks replaces the chunks of a pending document and marks it as ready. A document
+// re-indexed meanwhile stays pending and its chunks are replaced on the next run.
+func (r *documentRepository) SaveChunks(ctx context.Context, document *models.Document, chunks []*models.DocumentChunk) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+
+	err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Document{}).
+			Where("id = ? AND status = ? AND updated_at = ?", document.ID, models.DocumentStatusPending, document.UpdatedAt).
+			Updates(map[string]interface{}{
+				"status":     models.DocumentStatusReady,
+				"error":      "",
+				"chunks":     len(chunks),
+				"indexed_at": now,
+				"updated_at": now,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if err := tx.Where("document_id = ?", document.ID).Delete(&models.DocumentChunk{}).Error; err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(chunks, documentChunkBatchSize).Error
+	})
+	if err != nil {
+		log.Errorw("Failed to save document chunks", "error", err, "documentID", document.ID)
+		return dbError(err, "Failed to save document chunks")
+	}
+
+	return nil
+}
+
+// MarkFailed marks a pending document as failed with the reason
+func (r *documentRepository) MarkFailed(ctx context.Context, id int64, reason string) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Document{}).
+		Where("id = ? AND status = ?", id, models.DocumentStatusPending).
+		Updates(map[string]interface{}{
+			"status":     models.DocumentStatusFailed,
+			"error":      reason,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Errorw("Failed to mark document as failed", "error", result.Error, "documentID", id)
+		return dbError(result.Error, "Failed to update document")
+	}
+
+	return nil
+}
@@ -0,0 +1,602 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/ulid"
+)
+
+// ephemeralFlagsMax bounds the privacy mode flags of chats remembered; they are forgotten
+// all at once past it
+const ephemeralFlagsMax = 100000
+
+// ephemeralMessageRepository decorates a MessageRepository, keeping the messages of privacy
+// mode chats in an ephemeral message store instead of the database. Messages of other chats
+// are passed to the wrapped repository.
+type ephemeralMessageRepository struct {
+	next  MessageRepository
+	chats ChatRepository
+	store *EphemeralMessageStore
+
+	mu    sync.Mutex
+	flags map[string]bool // Whether chats are in privacy mode, which never changes, by region and ID
+}
+
+// NewEphemeralMessageRepository wraps a message repository to keep the messages of privacy
+// mode chats in store. Reactions and revisions are not kept for them, and the listings of
+// messages across chats, such as expired or pending ones, leave them out.
+func NewEphemeralMessageRepository(next MessageRepository, chats ChatRepository, store *EphemeralMessageStore) MessageRepository {
+	return &ephemeralMessageRepository{
+		next:  next,
+		chats: chats,
+		store: store,
+		flags: map[string]bool{},
+	}
+}
+
+// ephemeral reports whether a chat is in privacy mode. Chats that cannot be found are not,
+// and are left to the wrapped repository to report.
+func (r *ephemeralMessageRepository) ephemeral(ctx context.Context, chatID int64) (bool, error) {
+	key := adapters.RegionOf(ctx) + ":" + strconv.FormatInt(chatID, 10)
+	r.mu.Lock()
+	flag, ok := r.flags[key]
+	r.mu.Unlock()
+	if ok {
+		return flag, nil
+	}
+
+	chat, err := r.chats.Get(ctx, chatID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	r.mu.Lock()
+	if len(r.flags) >= ephemeralFlagsMax {
+		r.flags = map[string]bool{}
+	}
+	r.flags[key] = chat.Ephemeral
+	r.mu.Unlock()
+	return chat.Ephemeral, nil
+}
+
+// Create keeps the message of a privacy mode chat in the store, numbered after the messages
+// of the chat
+func (r *ephemeralMessageRepository) Create(ctx context.Context, message *models.Message) error {
+	ephemeral, err := r.ephemeral(ctx, message.ChatID)
+	if err != nil {
+		return err
+	}
+	if !ephemeral {
+		return r.next.Create(ctx, message)
+	}
+	if message.ChatID > models.MaxEphemeralChatID {
+		return errors.New(errors.ErrInternal, "The chat cannot be kept in privacy mode")
+	}
+
+	err = r.store.update(ctx, message.ChatID, func(chat *ephemeralChat) error {
+		if chat.LastSeqNo >= models.MaxEphemeralSeqNo {
+			return errors.New(errors.ErrInvalidRequest, "The chat has reached its maximum number of messages")
+		}
+		now := time.Now()
+		chat.LastSeqNo++
+		message.ID = models.EphemeralMessageID(message.ChatID, chat.LastSeqNo)
+		message.SeqNo = chat.LastSeqNo
+		message.PublicID = ulid.Make(now)
+		message.CreatedAt = now
+		message.UpdatedAt = now
+		if message.ContentType == "" {
+			message.ContentType = models.ContentTypeMarkdown
+		}
+		stored := *message
+		chat.Messages = append(chat.Messages, &stored)
+		return nil
+	})
+	if err != nil {
+		return ephemeralError(err, "Failed to create message")
+	}
+	return ephemeralError(r.store.setPublicID(ctx, message), "Failed to create message")
+}
+
+// Get retrieves a message by ID
+func (r *ephemeralMessageRepository) Get(ctx context.Context, id int64) (*models.Message, error) {
+	if !models.IsEphemeralMessageID(id) {
+		return r.next.Get(ctx, id)
+	}
+
+	messages, err := r.store.messages(ctx, models.EphemeralMessageChatID(id))
+	if err != nil {
+		return nil, ephemeralError(err, "Failed to get message")
+	}
+	for _, message := range messages {
+		if message.ID == id {
+			return message, nil
+		}
+	}
+	return nil, errors.New(errors.ErrNotFound, "Message not found")
+}
+
+// GetIDByPublicID returns the ID of the message with a public ID
+func (r *ephemeralMessageRepository) GetIDByPublicID(ctx context.Context, publicID string) (int64, error) {
+	id, ok, err := r.store.getIDByPublicID(ctx, publicID)
+	if err != nil {
+		return 0, ephemeralError(err, "Failed to get message")
+	}
+	if ok {
+		return id, nil
+	}
+	return r.next.GetIDByPublicID(ctx, publicID)
+}
+
+// GetByChatID retrieves all messages for a chat
+func (r *ephemeralMessageRepository) GetByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*models.Message, int64, error) {
+	messages, ok, err := r.chatMessages(ctx, chatID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !ok {
+		return r.next.GetByChatID(ctx, chatID, limit, offset)
+	}
+	return ephemeralPage(messages, limit, offset), int64(len(messages)), nil
+}
+
+// ListByChatID retrieves a page of messages for a chat without counting them
+func (r *ephemeralMessageRepository) ListByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*models.Message, error) {
+	messages, ok, err := r.chatMessages(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return r.next.ListByChatID(ctx, chatID, limit, offset)
+	}
+	return ephemeralPage(messages, limit, offset), nil
+}
+
+// ListByStatus retrieves the oldest messages with a status in the database
+func (r *ephemeralMessageRepository) ListByStatus(ctx context.Context, status string, limit int) ([]*models.Message, error) {
+	return r.next.ListByStatus(ctx, status, limit)
+}
+
+// ListStalled retrieves the oldest messages with a status not updated since a time in the
+// database
+func (r *ephemeralMessageRepository) ListStalled(ctx context.Context, status string, before time.Time, limit int) ([]*models.Message, error) {
+	return r.next.ListStalled(ctx, status, before, limit)
+}
+
+// GetRecent retrieves the latest messages of a chat with IDs above afterID, oldest first
+func (r *ephemeralMessageRepository) GetRecent(ctx context.Context, chatID, afterID int64, limit int) ([]*models.Message, error) {
+	messages, ok, err := r.chatMessages(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return r.next.GetRecent(ctx, chatID, afterID, limit)
+	}
+
+	recent := filterMessages(messages, func(message *models.Message) bool {
+		return ephemeralAfter(message.ID, afterID)
+	})
+	if limit >= 0 && len(recent) > limit {
+		recent = recent[len(recent)-limit:]
+	}
+	return recent, nil
+}
+
+// GetRange retrieves the messages of a chat with afterID < ID <= untilID, oldest first
+func (r *ephemeralMessageRepository) GetRange(ctx context.Context, chatID, afterID, untilID int64) ([]*models.Message, error) {
+	messages, ok, err := r.chatMessages(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return r.next.GetRange(ctx, chatID, afterID, untilID)
+	}
+
+	return filterMessages(messages, func(message *models.Message) bool {
+		return ephemeralAfter(message.ID, afterID) && message.ID <= untilID
+	}), nil
+}
+
+// ListUpToSeqNo retrieves the messages of a chat with a sequence number up to seqNo, oldest first
+func (r *ephemeralMessageRepository) ListUpToSeqNo(ctx context.Context, chatID, seqNo int64) ([]*models.Message, error) {
+	messages, ok, err := r.chatMessages(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return r.next.ListUpToSeqNo(ctx, chatID, seqNo)
+	}
+
+	return filterMessages(messages, func(message *models.Message) bool {
+		return message.SeqNo <= seqNo
+	}), nil
+}
+
+// Update updates the content and status of a message
+func (r *ephemeralMessageRepository) Update(ctx context.Context, message *models.Message) error {
+	if !message.Ephemeral() {
+		return r.next.Update(ctx, message)
+	}
+	return r.replace(ctx, message, false)
+}
+
+// UpdateReply updates the content and status of an assistant reply along with its generation
+func (r *ephemeralMessageRepository) UpdateReply(ctx context.Context, message *models.Message) error {
+	if !message.Ephemeral() {
+		return r.next.UpdateReply(ctx, message)
+	}
+	return r.replace(ctx, message, false)
+}
+
+// SetStalledStatus changes the status of a message that still has status and was not
+// updated since a time, reporting whether it did
+func (r *ephemeralMessageRepository) SetStalledStatus(ctx context.Context, id int64, status, newStatus string, before time.Time) (bool, error) {
+	if !models.IsEphemeralMessageID(id) {
+		return r.next.SetStalledStatus(ctx, id, status, newStatus, before)
+	}
+
+	changed := false
+	err := r.store.update(ctx, models.EphemeralMessageChatID(id), func(chat *ephemeralChat) error {
+		changed = false
+		for _, message := range chat.Messages {
+			if message.ID == id && message.Status == status && message.UpdatedAt.Before(before) {
+				message.Status, message.UpdatedAt = newStatus, time.Now()
+				changed = true
+			}
+		}
+		return nil
+	})
+	return changed, ephemeralError(err, "Failed to update message")
+}
+
+// Delete deletes a message
+func (r *ephemeralMessageRepository) Delete(ctx context.Context, id int64) error {
+	if !models.IsEphemeralMessageID(id) {
+		return r.next.Delete(ctx, id)
+	}
+
+	err := r.store.update(ctx, models.EphemeralMessageChatID(id), func(chat *ephemeralChat) error {
+		for i, message := range chat.Messages {
+			if message.ID == id {
+				chat.Messages = append(chat.Messages[:i], chat.Messages[i+1:]...)
+				return nil
+			}
+		}
+		return errors.New(errors.ErrNotFound, fmt.Sprintf("Message with ID %d not found", id))
+	})
+	return ephemeralError(err, "Failed to delete message")
+}
+
+// Revise updates the content of a message; no revision is kept for the messages of privacy
+// mode chats
+func (r *ephemeralMessageRepository) Revise(ctx context.Context, message *models.Message, previousContent string) error {
+	if !message.Ephemeral() {
+		return r.next.Revise(ctx, message, previousContent)
+	}
+	return r.replace(ctx, message, true)
+}
+
+// SetFeedback sets the user rating of a message
+func (r *ephemeralMessageRepository) SetFeedback(ctx context.Context, id int64, feedback int) error {
+	if !models.IsEphemeralMessageID(id) {
+		return r.next.SetFeedback(ctx, id, feedback)
+	}
+
+	err := r.store.update(ctx, models.EphemeralMessageChatID(id), func(chat *ephemeralChat) error {
+		for _, message := range chat.Messages {
+			if message.ID == id {
+				message.Feedback = feedback
+				return nil
+			}
+		}
+		return errors.New(errors.ErrNotFound, fmt.Sprintf("Message with ID %d not found", id))
+	})
+	return ephemeralError(err, "Failed to set message feedback")
+}
+
+// AddReaction adds an emoji reaction to a message; messages of privacy mode chats take none
+func (r *ephemeralMessageRepository) AddReaction(ctx context.Context, reaction *models.MessageReaction) (bool, error) {
+	if models.IsEphemeralMessageID(reaction.MessageID) {
+		return false, errors.New(errors.ErrInvalidRequest, "Messages of privacy mode chats cannot be reacted to")
+	}
+	return r.next.AddReaction(ctx, reaction)
+}
+
+// RemoveReaction removes the emoji reaction of a user to a message
+func (r *ephemeralMessageRepository) RemoveReaction(ctx context.Context, messageID int64, userID, emoji string) (bool, error) {
+	if models.IsEphemeralMessageID(messageID) {
+		return false, errors.New(errors.ErrInvalidRequest, "Messages of privacy mode chats cannot be reacted to")
+	}
+	return r.next.RemoveReaction(ctx, messageID, userID, emoji)
+}
+
+// CountReactions counts the reactions to the messages of the database
+func (r *ephemeralMessageRepository) CountReactions(ctx context.Context, messageIDs []int64, userID string) (map[int64][]dtos.ReactionCount, error) {
+	stored := make([]int64, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		if !models.IsEphemeralMessageID(id) {
+			stored = append(stored, id)
+		}
+	}
+	return r.next.CountReactions(ctx, stored, userID)
+}
+
+// ListRevisions lists the revisions of a message, none for the messages of privacy mode chats
+func (r *ephemeralMessageRepository) ListRevisions(ctx context.Context, messageID int64) ([]*models.MessageRevision, error) {
+	if models.IsEphemeralMessageID(messageID) {
+		return []*models.MessageRevision{}, nil
+	}
+	return r.next.ListRevisions(ctx, messageID)
+}
+
+// ListModels retrieves the assistant messages of a chat generated by a known model, oldest first
+func (r *ephemeralMessageRepository) ListModels(ctx context.Context, chatID int64) ([]*models.Message, error) {
+	messages, ok, err := r.chatMessages(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return r.next.ListModels(ctx, chatID)
+	}
+
+	return filterMessages(messages, func(message *models.Message) bool {
+		return message.Role == models.MessageRoleAssistant && message.Model != ""
+	}), nil
+}
+
+// ListRevisionsAfter retrieves the first revision after a time of each message of a chat
+// edited since then, none for privacy mode chats
+func (r *ephemeralMessageRepository) ListRevisionsAfter(ctx context.Context, chatID int64, after time.Time) (map[int64]*models.MessageRevision, error) {
+	ephemeral, err := r.ephemeral(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if ephemeral {
+		return map[int64]*models.MessageRevision{}, nil
+	}
+	return r.next.ListRevisionsAfter(ctx, chatID, after)
+}
+
+// CountByUser counts the messages sent by a user in the database
+func (r *ephemeralMessageRepository) CountByUser(ctx context.Context, userID string) (int64, error) {
+	return r.next.CountByUser(ctx, userID)
+}
+
+// CountExpired counts the messages of the database created before a time
+func (r *ephemeralMessageRepository) CountExpired(ctx context.Context, userIDs, excludeUserIDs []string, before time.Time) (int64, error) {
+	return r.next.CountExpired(ctx, userIDs, excludeUserIDs, before)
+}
+
+// ListExpired lists the oldest messages of the database created before a time
+func (r *ephemeralMessageRepository) ListExpired(ctx context.Context, userIDs, excludeUserIDs []string, before time.Time, limit int) ([]*models.Message, error) {
+	return r.next.ListExpired(ctx, userIDs, excludeUserIDs, before, limit)
+}
+
+// DeleteByChatID deletes the messages of a chat matching the filters of a request, and
+// returns them without their content
+func (r *ephemeralMessageRepository) DeleteByChatID(ctx context.Context, chatID int64, req *dtos.DeleteMessagesRequest) ([]*models.Message, error) {
+	ephemeral, err := r.ephemeral(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !ephemeral {
+		return r.next.DeleteByChatID(ctx, chatID, req)
+	}
+
+	ids := map[int64]bool{}
+	for _, id := range req.IDs {
+		ids[id] = true
+	}
+	var deleted []*models.Message
+	err = r.store.update(ctx, chatID, func(chat *ephemeralChat) error {
+		deleted = nil
+		kept := chat.Messages[:0]
+		for _, message := range chat.Messages {
+			if (req.Before != nil && !message.CreatedAt.Before(*req.Before)) ||
+				(req.Role != "" && message.Role != req.Role) ||
+				(len(ids) > 0 && !ids[message.ID]) {
+				kept = append(kept, message)
+				continue
+			}
+			deleted = append(deleted, &models.Message{
+				ID:     message.ID,
+				ChatID: message.ChatID,
+				SeqNo:  message.SeqNo,
+				UserID: message.UserID,
+				Role:   message.Role,
+			})
+		}
+		chat.Messages = kept
+		return nil
+	})
+	if err != nil {
+		return nil, ephemeralError(err, "Failed to delete messages")
+	}
+	return deleted, nil
+}
+
+// DeleteBatch deletes messages by ID
+func (r *ephemeralMessageRepository) DeleteBatch(ctx context.Context, ids []int64) error {
+	stored := make([]int64, 0, len(ids))
+	byChat := map[int64]map[int64]bool{}
+	for _, id := range ids {
+		if !models.IsEphemeralMessageID(id) {
+			stored = append(stored, id)
+			continue
+		}
+		chatID := models.EphemeralMessageChatID(id)
+		if byChat[chatID] == nil {
+			byChat[chatID] = map[int64]bool{}
+		}
+		byChat[chatID][id] = true
+	}
+
+	for chatID, deleted := range byChat {
+		err := r.store.update(ctx, chatID, func(chat *ephemeralChat) error {
+			chat.Messages = filterMessages(chat.Messages, func(message *models.Message) bool {
+				return !deleted[message.ID]
+			})
+			return nil
+		})
+		if err != nil {
+			return ephemeralError(err, "Failed to delete messages")
+		}
+	}
+
+	if len(stored) == 0 {
+		return nil
+	}
+	return r.next.DeleteBatch(ctx, stored)
+}
+
+// CreateBatch creates messages in batches, keeping those of privacy mode chats in the store
+func (r *ephemeralMessageRepository) CreateBatch(ctx context.Context, messages []*models.Message) error {
+	stored := make([]*models.Message, 0, len(messages))
+	for _, message := range messages {
+		ephemeral, err := r.ephemeral(ctx, message.ChatID)
+		if err != nil {
+			return err
+		}
+		if !ephemeral {
+			stored = append(stored, message)
+			continue
+		}
+		if err := r.Create(ctx, message); err != nil {
+			return err
+		}
+	}
+
+	if len(stored) == 0 {
+		return nil
+	}
+	return r.next.CreateBatch(ctx, stored)
+}
+
+// ListByIDs retrieves the messages with the given IDs in the chats of a user, in no order
+func (r *ephemeralMessageRepository) ListByIDs(ctx context.Context, userID string, ids []int64) ([]*models.Message, error) {
+	stored := make([]int64, 0, len(ids))
+	byChat := map[int64]map[int64]bool{}
+	for _, id := range ids {
+		if !models.IsEphemeralMessageID(id) {
+			stored = append(stored, id)
+			continue
+		}
+		chatID := models.EphemeralMessageChatID(id)
+		if byChat[chatID] == nil {
+			byChat[chatID] = map[int64]bool{}
+		}
+		byChat[chatID][id] = true
+	}
+
+	messages, err := r.next.ListByIDs(ctx, userID, stored)
+	if err != nil {
+		return nil, err
+	}
+	for chatID, listed := range byChat {
+		chat, err := r.chats.Get(ctx, chatID)
+		if err != nil {
+			if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if !chat.Ephemeral || chat.UserID != userID || chat.PendingDeletion() {
+			continue
+		}
+
+		kept, err := r.store.messages(ctx, chatID)
+		if err != nil {
+			return nil, ephemeralError(err, "Failed to get messages")
+		}
+		messages = append(messages, filterMessages(kept, func(message *models.Message) bool {
+			return listed[message.ID]
+		})...)
+	}
+	return messages, nil
+}
+
+// chatMessages returns the messages kept for a chat, and whether it is in privacy mode
+func (r *ephemeralMessageRepository) chatMessages(ctx context.Context, chatID int64) ([]*models.Message, bool, error) {
+	ephemeral, err := r.ephemeral(ctx, chatID)
+	if err != nil || !ephemeral {
+		return nil, false, err
+	}
+
+	messages, err := r.store.messages(ctx, chatID)
+	if err != nil {
+		return nil, false, ephemeralError(err, "Failed to get messages")
+	}
+	return messages, true, nil
+}
+
+// replace replaces a message kept with message, marking it edited when revised
+func (r *ephemeralMessageRepository) replace(ctx context.Context, message *models.Message, revised bool) error {
+	now := time.Now()
+	err := r.store.update(ctx, models.EphemeralMessageChatID(message.ID), func(chat *ephemeralChat) error {
+		for i, kept := range chat.Messages {
+			if kept.ID != message.ID {
+				continue
+			}
+			message.UpdatedAt = now
+			if revised {
+				message.EditedAt = &now
+			}
+			stored := *message
+			chat.Messages[i] = &stored
+			return nil
+		}
+		return errors.New(errors.ErrNotFound, fmt.Sprintf("Message with ID %d not found", message.ID))
+	})
+	return ephemeralError(err, "Failed to update message")
+}
+
+// ephemeralPage returns a page of messages, all of them from offset when limit is negative
+func ephemeralPage(messages []*models.Message, limit, offset int) []*models.Message {
+	if offset >= len(messages) {
+		return []*models.Message{}
+	}
+	messages = messages[offset:]
+	if limit >= 0 && len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages
+}
+
+// ephemeralAfter reports whether a message of a privacy mode chat comes after afterID, 0
+// standing for none
+func ephemeralAfter(id, afterID int64) bool {
+	return afterID == 0 || id > afterID
+}
+
+// filterMessages returns the messages keep reports true for, in order
+func filterMessages(messages []*models.Message, keep func(message *models.Message) bool) []*models.Message {
+	filtered := make([]*models.Message, 0, len(messages))
+	for _, message := range messages {
+		if keep(message) {
+			filtered = append(filtered, message)
+		}
+	}
+	return filtered
+}
+
+// ephemeralError returns the application errors of the store as they are, and wraps the
+// others in an internal error with message
+func ephemeralError(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr
+	}
+	return errors.Wrap(err, errors.ErrInternal, message)
+}
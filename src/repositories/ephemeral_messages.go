@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// Prefixes of the keys of the ephemeral message store in the cache
+const (
+	ephemeralChatKeyPrefix     = "ephemeral-chat:"
+	ephemeralPublicIDKeyPrefix = "ephemeral-message:"
+)
+
+// ephemeralUpdateAttempts is the number of times a change to the messages of a chat is tried
+// while other changes to the chat keep winning the race
+const ephemeralUpdateAttempts = 10
+
+// EphemeralMessageStore keeps the messages of privacy mode chats in a cache, each for the TTL
+// after it was sent, instead of the database. The messages of a chat are kept together under
+// one key. Every change rewrites them with an atomic compare-and-swap, and starts over when
+// they changed meanwhile, so changes to a chat from any instance sharing the cache are never
+// lost, and changes to different chats never wait for each other.
+type EphemeralMessageStore struct {
+	cache  adapters.CacheAdapter
+	config configs.Privacy
+}
+
+// ephemeralChat is the value kept for the messages of a chat
+type ephemeralChat struct {
+	// LastSeqNo is the sequence number of the latest message, so that numbers are not reused
+	// while messages are kept
+	LastSeqNo int64             `json:"lastSeqNo"`
+	Messages  []*models.Message `json:"messages"` // Oldest first
+}
+
+// NewEphemeralMessageStore creates the store of the messages of privacy mode chats
+func NewEphemeralMessageStore(cache adapters.CacheAdapter, config configs.Privacy) *EphemeralMessageStore {
+	return &EphemeralMessageStore{cache: cache, config: config}
+}
+
+// Live reports whether messages of a chat are still kept
+func (s *EphemeralMessageStore) Live(ctx context.Context, chatID int64) (bool, error) {
+	chat, err := s.load(ctx, chatID)
+	if err != nil {
+		return false, err
+	}
+	return len(chat.Messages) > 0, nil
+}
+
+// Forget drops the messages of a chat
+func (s *EphemeralMessageStore) Forget(ctx context.Context, chatID int64) error {
+	var forgotten []*models.Message
+	err := s.update(ctx, chatID, func(chat *ephemeralChat) error {
+		forgotten = chat.Messages
+		chat.Messages = nil
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, message := range forgotten {
+		if err := s.cache.Delete(ctx, ephemeralPublicIDKey(ctx, message.PublicID)); err != nil {
+			return fmt.Errorf("failed to forget ephemeral message: %w", err)
+		}
+	}
+	return nil
+}
+
+// messages returns the messages of a chat kept, oldest first
+func (s *EphemeralMessageStore) messages(ctx context.Context, chatID int64) ([]*models.Message, error) {
+	chat, err := s.load(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	return chat.Messages, nil
+}
+
+// update changes the messages of a chat with fn and keeps them, unless fn fails. When the
+// messages changed since they were read, fn runs again on the messages as changed, so it must
+// start over each time rather than accumulate across runs.
+func (s *EphemeralMessageStore) update(ctx context.Context, chatID int64, fn func(chat *ephemeralChat) error) error {
+	key := ephemeralChatKey(ctx, chatID)
+	for attempt := 0; attempt < ephemeralUpdateAttempts; attempt++ {
+		value, ok, err := s.cache.Get(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to read ephemeral messages: %w", err)
+		}
+		var old *string
+		if ok {
+			old = &value
+		}
+		chat, err := s.decode(old)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(chat); err != nil {
+			return err
+		}
+		if over := len(chat.Messages) - s.config.MaxMessages; over > 0 {
+			chat.Messages = chat.Messages[over:]
+		}
+
+		swapped, err := s.swap(ctx, key, old, chat)
+		if err != nil || swapped {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to write ephemeral messages: chat %d kept changing", chatID)
+}
+
+// load reads the messages of a chat, leaving out the expired ones
+func (s *EphemeralMessageStore) load(ctx context.Context, chatID int64) (*ephemeralChat, error) {
+	value, ok, err := s.cache.Get(ctx, ephemeralChatKey(ctx, chatID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ephemeral messages: %w", err)
+	}
+	if !ok {
+		return &ephemeralChat{}, nil
+	}
+	return s.decode(&value)
+}
+
+// decode decodes the value kept for the messages of a chat, nil when none is, leaving out the
+// expired messages
+func (s *EphemeralMessageStore) decode(value *string) (*ephemeralChat, error) {
+	chat := &ephemeralChat{}
+	if value == nil {
+		return chat, nil
+	}
+	if err := json.Unmarshal([]byte(*value), chat); err != nil {
+		return nil, fmt.Errorf("failed to decode ephemeral messages: %w", err)
+	}
+
+	expired := time.Now().Add(-s.config.TTL)
+	live := chat.Messages[:0]
+	for _, message := range chat.Messages {
+		if message.CreatedAt.After(expired) {
+			live = append(live, message)
+		}
+	}
+	chat.Messages = live
+	return chat, nil
+}
+
+// swap writes the messages of a chat in place of old, kept until the latest of them expires,
+// and reports whether old was still the value kept
+func (s *EphemeralMessageStore) swap(ctx context.Context, key string, old *string, chat *ephemeralChat) (bool, error) {
+	var latest time.Time
+	for _, message := range chat.Messages {
+		if message.CreatedAt.After(latest) {
+			latest = message.CreatedAt
+		}
+	}
+	ttl := time.Until(latest.Add(s.config.TTL))
+
+	var value *string
+	if len(chat.Messages) > 0 && ttl > 0 {
+		encoded, err := json.Marshal(chat)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode ephemeral messages: %w", err)
+		}
+		value = new(string)
+		*value = string(encoded)
+	}
+	if old == nil && value == nil {
+		return true, nil
+	}
+
+	swapped, err := s.cache.CompareAndSwap(ctx, key, old, value, ttl)
+	if err != nil {
+		return false, fmt.Errorf("failed to write ephemeral messages: %w", err)
+	}
+	return swapped, nil
+}
+
+// setPublicID records the ID of a message by its public ID, for as long as the message is kept
+func (s *EphemeralMessageStore) setPublicID(ctx context.Context, message *models.Message) error {
+	id := strconv.FormatInt(message.ID, 10)
+	if err := s.cache.Set(ctx, ephemeralPublicIDKey(ctx, message.PublicID), id, s.config.TTL); err != nil {
+		return fmt.Errorf("failed to write ephemeral message ID: %w", err)
+	}
+	return nil
+}
+
+// getIDByPublicID returns the ID of a message kept by its public ID, and whether it was found
+func (s *EphemeralMessageStore) getIDByPublicID(ctx context.Context, publicID string) (int64, bool, error) {
+	value, ok, err := s.cache.Get(ctx, ephemeralPublicIDKey(ctx, publicID))
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid ephemeral message ID %q: %w", value, err)
+	}
+	return id, true, nil
+}
+
+// ephemeralChatKey returns the cache key of the messages of a chat, scoped to the data
+// residency region of ctx since the IDs of different regions overlap
+func ephemeralChatKey(ctx context.Context, chatID int64) string {
+	return ephemeralChatKeyPrefix + adapters.RegionOf(ctx) + ":" + strconv.FormatInt(chatID, 10)
+}
+
+// ephemeralPublicIDKey returns the cache key of the ID of a message by its public ID
+func ephemeralPublicIDKey(ctx context.Context, publicID string) string {
+	return ephemeralPublicIDKeyPrefix + adapters.RegionOf(ctx) + ":" + publicID
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEphemeralMessageStoreConcurrentUpdates(t *testing.T) {
+	// Two stores sharing a cache stand for two instances sharing Redis
+	cache := adapters.NewMemoryCacheAdapter()
+	config := configs.Privacy{TTL: time.Hour, MaxMessages: 1000}
+	stores := []*EphemeralMessageStore{NewEphemeralMessageStore(cache, config), NewEphemeralMessageStore(cache, config)}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(store *EphemeralMessageStore) {
+			defer wg.Done()
+			err := store.update(ctx, 1, func(chat *ephemeralChat) error {
+				chat.LastSeqNo++
+				chat.Messages = append(chat.Messages, &models.Message{SeqNo: chat.LastSeqNo, CreatedAt: time.Now()})
+				return nil
+			})
+			assert.NoError(t, err)
+		}(stores[i%2])
+	}
+	wg.Wait()
+
+	// No change was lost, and no sequence number reused
+	messages, err := stores[0].messages(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, messages, 100)
+	for i, message := range messages {
+		assert.Equal(t, int64(i+1), message.SeqNo)
+	}
+}
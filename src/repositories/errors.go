@@ -0,0 +1,15 @@
+package repositories
+
+import (
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+)
+
+// dbError wraps a database error: statements that ran out of time are reported as
+// database timeouts, so clients can tell them from other failures and retry later
+func dbError(err error, msg string) *errors.AppError {
+	if adapters.IsQueryTimeout(err) {
+		return errors.Wrap(err, errors.ErrDatabaseTimeout)
+	}
+	return errors.Wrap(err, errors.ErrInternal, msg)
+}
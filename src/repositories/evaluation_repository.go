@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// EvaluationRepository defines the interface for conversation evaluation data access
+type EvaluationRepository interface {
+	// Create creates a new evaluation
+	Create(ctx context.Context, evaluation *models.Evaluation) error
+
+	// ListCandidates samples at random up to limit chats with assistant replies created since
+	// a time that were not evaluated yet
+	ListCandidates(ctx context.Context, since time.Time, limit int) ([]*models.Chat, error)
+
+	// GetLatestMessageID returns the latest message evaluated of a chat, or 0
+	GetLatestMessageID(ctx context.Context, chatID int64) (int64, error)
+
+	// GetTrends aggregates the evaluations created since a time by day, oldest first
+	GetTrends(ctx context.Context, since time.Time) ([]*models.EvaluationTrend, error)
+}
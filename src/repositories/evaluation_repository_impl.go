@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// evaluationRepository implements the EvaluationRepository interface
+type evaluationRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewEvaluationRepository creates a new evaluation repository
+func NewEvaluationRepository(db adapters.DBAdapter) EvaluationRepository {
+	return &evaluationRepository{db: db}
+}
+
+// Create creates a new evaluation
+func (r *evaluationRepository) Create(ctx context.Context, evaluation *models.Evaluation) error {
+	log := logger.Context(ctx)
+	evaluation.CreatedAt = time.Now()
+
+	if err := r.db.GetDB().WithContext(ctx).Create(evaluation).Error; err != nil {
+		log.Errorw("Failed to create evaluation", "error", err, "chatID", evaluation.ChatID)
+		return dbError(err, "Failed to create evaluation")
+	}
+
+	return nil
+}
+
+// ListCandidates samples at random up to limit chats with assistant replies created since
+// a time that were not evaluated yet
+func (r *evaluationRepository) ListCandidates(ctx context.Context, since time.Time, limit int) ([]*models.Chat, error) {
+	log := logger.Context(ctx)
+	var chats []*models.Chat
+
+	evaluated := r.db.GetDB().Model(&models.Evaluation{}).Select("COALESCE(MAX(message_id), 0)").Where("evaluations.chat_id = chats.id")
+	replies := r.db.GetDB().Model(&models.Message{}).Select("1").
+		Where("messages.chat_id = chats.id AND messages.role = ? AND messages.status = '' AND messages.created_at >= ?", models.MessageRoleAssistant, since).
+		Where("messages.id > (?)", evaluated)
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("delete_at IS NULL").
+		Where("EXISTS (?)", replies).
+		Order("random()").
+		Limit(limit).
+		Find(&chats).Error; err != nil {
+		log.Errorw("Failed to list chats to evaluate", "error", err)
+		return nil, dbError(err, "Failed to list chats")
+	}
+
+	return chats, nil
+}
+
+// GetLatestMessageID returns the latest message evaluated of a chat, or 0
+func (r *evaluationRepository) GetLatestMessageID(ctx context.Context, chatID int64) (int64, error) {
+	log := logger.Context(ctx)
+	var messageID int64
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Evaluation{}).
+		Where("chat_id = ?", chatID).
+		Select("COALESCE(MAX(message_id), 0)").
+		Scan(&messageID).Error; err != nil {
+		log.Errorw("Failed to get latest evaluated message", "error", err, "chatID", chatID)
+		return 0, dbError(err, "Failed to get evaluations")
+	}
+
+	return messageID, nil
+}
+
+// GetTrends aggregates the evaluations created since a time by day, oldest first
+func (r *evaluationRepository) GetTrends(ctx context.Context, since time.Time) ([]*models.EvaluationTrend, error) {
+	log := logger.Context(ctx)
+	var trends []*models.EvaluationTrend
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Evaluation{}).
+		Select(`date_trunc('day', created_at AT TIME ZONE 'UTC') AS day,
+			COUNT(*) AS evaluations,
+			SUM(replies) AS replies,
+			SUM(refusals) AS refusals,
+			AVG(helpfulness)::float8 AS avg_helpfulness`).
+		Where("created_at >= ?", since).
+		Group("day").
+		Order("day").
+		Scan(&trends).Error; err != nil {
+		log.Errorw("Failed to get evaluation trends", "error", err)
+		return nil, dbError(err, "Failed to get evaluation trends")
+	}
+
+	return trends, nil
+}
@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// ExperimentRepository defines the interface for experiment data access
+type ExperimentRepository interface {
+	// Create creates an experiment with its variants
+	Create(ctx context.Context, experiment *models.Experiment) error
+
+	// List retrieves all experiments with their variants, newest first
+	List(ctx context.Context) ([]*models.Experiment, error)
+
+	// Get retrieves an experiment with its variants by ID
+	Get(ctx context.Context, id int64) (*models.Experiment, error)
+
+	// GetRunning retrieves the running experiment with its variants, or nil when none is
+	GetRunning(ctx context.Context) (*models.Experiment, error)
+
+	// UpdateStatus updates the status and start and stop times of an experiment
+	UpdateStatus(ctx context.Context, experiment *models.Experiment) error
+
+	// GetVariantStats aggregates the assistant messages of each variant of an experiment
+	// that generated at least one reply
+	GetVariantStats(ctx context.Context, experimentID int64) ([]*models.VariantStats, error)
+}
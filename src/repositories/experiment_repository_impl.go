@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// experimentRepository implements the ExperimentRepository interface
+type experimentRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewExperimentRepository creates a new experiment repository
+func NewExperimentRepository(db adapters.DBAdapter) ExperimentRepository {
+	return &experimentRepository{db: db}
+}
+
+// Create creates an experiment with its variants
+func (r *experimentRepository) Create(ctx context.Context, experiment *models.Experiment) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	experiment.CreatedAt = now
+	experiment.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(experiment).Error; err != nil {
+		log.Errorw("Failed to create experiment", "error", err)
+		return dbError(err, "Failed to create experiment")
+	}
+
+	return nil
+}
+
+// List retrieves all experiments with their variants, newest first
+func (r *experimentRepository) List(ctx context.Context) ([]*models.Experiment, error) {
+	log := logger.Context(ctx)
+	var experiments []*models.Experiment
+
+	if err := r.preloadVariants(ctx).Order("id DESC").Find(&experiments).Error; err != nil {
+		log.Errorw("Failed to list experiments", "error", err)
+		return nil, dbError(err, "Failed to list experiments")
+	}
+
+	return experiments, nil
+}
+
+// Get retrieves an experiment with its variants by ID
+func (r *experimentRepository) Get(ctx context.Context, id int64) (*models.Experiment, error) {
+	log := logger.Context(ctx)
+	var experiment models.Experiment
+
+	if err := r.preloadVariants(ctx).First(&experiment, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Experiment not found")
+		}
+		log.Errorw("Failed to get experiment", "error", err, "id", id)
+		return nil, dbError(err, "Failed to get experiment")
+	}
+
+	return &experiment, nil
+}
+
+// GetRunning retrieves the running experiment with its variants, or nil when none is
+func (r *experimentRepository) GetRunning(ctx context.Context) (*models.Experiment, error) {
+	log := logger.Context(ctx)
+	var experiments []*models.Experiment
+
+	err := r.preloadVariants(ctx).Where("status = ?", models.ExperimentStatusRunning).Limit(1).Find(&experiments).Error
+	if err != nil {
+		log.Errorw("Failed to get running experiment", "error", err)
+		return nil, dbError(err, "Failed to get running experiment")
+	}
+	if len(experiments) == 0 {
+		return nil, nil
+	}
+
+	return experiments[0], nil
+}
+
+// UpdateStatus updates the status and start and stop times of an experiment
+func (r *experimentRepository) UpdateStatus(ctx context.Context, experiment *models.Experiment) error {
+	log := logger.Context(ctx)
+	experiment.UpdatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Model(experiment).Updates(map[string]interface{}{
+		"status":     experiment.Status,
+		"started_at": experiment.StartedAt,
+		"stopped_at": experiment.StoppedAt,
+		"updated_at": experiment.UpdatedAt,
+	})
+	if result.Error != nil {
+		log.Errorw("Failed to update experiment", "error", result.Error, "id", experiment.ID)
+		return dbError(result.Error, "Failed to update experiment")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Experiment not found")
+	}
+
+	return nil
+}
+
+// GetVariantStats aggregates the assistant messages of each variant of an experiment
+func (r *experimentRepository) GetVariantStats(ctx context.Context, experimentID int64) ([]*models.VariantStats, error) {
+	log := logger.Context(ctx)
+	var stats []*models.VariantStats
+
+	err := r.db.GetDB().WithContext(ctx).
+		Model(&models.Message{}).
+		Select(`variant_id,
+			COUNT(DISTINCT chat_id) AS chats,
+			COUNT(*) AS replies,
+			AVG(latency_ms)::float8 AS avg_latency_ms,
+			AVG(total_tokens)::float8 AS avg_tokens,
+			SUM(total_tokens) AS total_tokens,
+			COUNT(*) FILTER (WHERE feedback > 0) AS positive_feedback,
+			COUNT(*) FILTER (WHERE feedback < 0) AS negative_feedback`).
+		Where("variant_id IN (?)", r.db.GetDB().Model(&models.ExperimentVariant{}).Select("id").Where("experiment_id = ?", experimentID)).
+		Where("role = ?", models.MessageRoleAssistant).
+		Group("variant_id").
+		Scan(&stats).Error
+	if err != nil {
+		log.Errorw("Failed to get experiment variant stats", "error", err, "experimentID", experimentID)
+		return nil, dbError(err, "Failed to get experiment results")
+	}
+
+	return stats, nil
+}
+
+// preloadVariants starts a query of experiments loading their variants in ID order
+func (r *experimentRepository) preloadVariants(ctx context.Context) *gorm.DB {
+	return r.db.GetDB().WithContext(ctx).Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// ExportRepository defines the interface for chat export data access
+type ExportRepository interface {
+	// Create creates a new export
+	Create(ctx context.Context, export *models.Export) error
+
+	// GetByID retrieves an export by ID
+	GetByID(ctx context.Context, id int64) (*models.Export, error)
+
+	// ListPending retrieves the oldest exports waiting to be packaged
+	ListPending(ctx context.Context, limit int) ([]*models.Export, error)
+
+	// Update saves the status and result of an export
+	Update(ctx context.Context, export *models.Export) error
+}
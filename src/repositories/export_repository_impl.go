@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// exportRepository implements the ExportRepository interface
+type exportRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewExportRepository creates a new chat export repository
+func NewExportRepository(db adapters.DBAdapter) ExportRepository {
+	return &exportRepository{db: db}
+}
+
+// Create creates a new export
+func (r *exportRepository) Create(ctx context.Context, export *models.Export) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	export.CreatedAt = now
+	export.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(export).Error; err != nil {
+		log.Errorw("Failed to create export", "error", err, "userID", export.UserID)
+		return dbError(err, "Failed to create export")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an export by ID
+func (r *exportRepository) GetByID(ctx context.Context, id int64) (*models.Export, error) {
+	log := logger.Context(ctx)
+	var export models.Export
+
+	result := r.db.GetDB().WithContext(ctx).First(&export, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Export not found")
+		}
+		log.Errorw("Failed to get export", "error", result.Error, "exportID", id)
+		return nil, dbError(result.Error, "Failed to get export")
+	}
+
+	return &export, nil
+}
+
+// ListPending retrieves the oldest exports waiting to be packaged
+func (r *exportRepository) ListPending(ctx context.Context, limit int) ([]*models.Export, error) {
+	log := logger.Context(ctx)
+	var exports []*models.Export
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("status = ?", models.ExportStatusPending).
+		Order("id ASC").
+		Limit(limit).
+		Find(&exports).Error; err != nil {
+		log.Errorw("Failed to list pending exports", "error", err)
+		return nil, dbError(err, "Failed to list pending exports")
+	}
+
+	return exports, nil
+}
+
+// Update saves the status and result of an export
+func (r *exportRepository) Update(ctx context.Context, export *models.Export) error {
+	log := logger.Context(ctx)
+	export.UpdatedAt = time.Now()
+
+	if err := r.db.GetDB().WithContext(ctx).Save(export).Error; err != nil {
+		log.Errorw("Failed to update export", "error", err, "exportID", export.ID)
+		return dbError(err, "Failed to update export")
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package repositories
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MessageContentStore keeps the content of large messages in the storage, and the content
+// repeated by messages once in the message_contents table, with a preview in the messages,
+// and loads it back when messages are read. A nil store keeps all content in the messages.
+type MessageContentStore struct {
+	storage        adapters.StorageAdapter
+	db             adapters.DBAdapter
+	threshold      int
+	shareThreshold int
+}
+
+// NewMessageContentStore creates the store of large message content. Content already in the
+// storage or shared is still loaded when the thresholds are 0.
+func NewMessageContentStore(storage adapters.StorageAdapter, db adapters.DBAdapter, config configs.MessageContent) *MessageContentStore {
+	return &MessageContentStore{
+		storage:        storage,
+		db:             db,
+		threshold:      config.StorageThreshold,
+		shareThreshold: config.ShareThreshold,
+	}
+}
+
+// store keeps the content of a message out of its row when it is large: content over the
+// threshold moves to the storage, and content over the share threshold is shared. It returns
+// what the row holds, the content itself or a preview, with the storage key or the hash of
+// the shared content.
+func (s *MessageContentStore) store(ctx context.Context, chatID int64, content string) (string, string, string, error) {
+	preview, key, err := s.put(ctx, chatID, content)
+	if err != nil || key != "" {
+		return preview, key, "", err
+	}
+	preview, hash, err := s.share(ctx, content)
+	return preview, "", hash, err
+}
+
+// put moves content over the threshold to the storage, returning the preview to keep in the
+// database and the storage key, or the content itself and an empty key
+func (s *MessageContentStore) put(ctx context.Context, chatID int64, content string) (string, string, error) {
+	if s == nil || s.threshold <= 0 || len(content) <= s.threshold {
+		return content, "", nil
+	}
+
+	key := fmt.Sprintf("messages/%d/%s.txt", chatID, uuid.NewString())
+	if _, err := s.storage.Put(ctx, key, strings.NewReader(content)); err != nil {
+		return "", "", fmt.Errorf("failed to store message content: %w", err)
+	}
+	return models.ContentPreview(content), key, nil
+}
+
+// share stores content over the share threshold once in the message_contents table, for all
+// the messages repeating it, returning the preview to keep in the messages and the hash of
+// the content, or the content itself and an empty hash. Sharing content marks it used, so
+// that it is not deleted before the message referencing it is written.
+func (s *MessageContentStore) share(ctx context.Context, content string) (string, string, error) {
+	if s == nil || s.shareThreshold <= 0 || len(content) <= s.shareThreshold {
+		return content, "", nil
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	shared := &models.MessageContent{
+		Hash:    hex.EncodeToString(sum[:]),
+		Content: content,
+		Size:    len(content),
+		UsedAt:  time.Now(),
+	}
+	if err := s.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"used_at"}),
+	}).Create(shared).Error; err != nil {
+		return "", "", fmt.Errorf("failed to share message content: %w", err)
+	}
+	return models.ContentPreview(content), shared.Hash, nil
+}
+
+// putMessages moves the content of large messages to the storage or shares it, so their rows
+// hold previews. It returns the keys of the stored content and the function restoring the
+// content of the messages once their rows are written.
+func (s *MessageContentStore) putMessages(ctx context.Context, messages []*models.Message) (func(), []string, error) {
+	var keys []string
+	contents := make([]string, len(messages))
+	restore := func() {
+		for i, message := range messages {
+			if message.ContentKey != "" || message.ContentHash != "" {
+				message.Content = contents[i]
+			}
+		}
+	}
+
+	for i, message := range messages {
+		contents[i] = message.Content
+		preview, key, hash, err := s.store(ctx, message.ChatID, message.Content)
+		if err != nil {
+			restore()
+			s.remove(ctx, keys...)
+			return nil, nil, err
+		}
+		message.Content, message.ContentKey, message.ContentHash = preview, key, hash
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return restore, keys, nil
+}
+
+// load replaces the previews of messages with their content in the storage or shared.
+// Content that cannot be read is logged and left as its preview, so the rest of the chat
+// stays readable.
+func (s *MessageContentStore) load(ctx context.Context, messages ...*models.Message) {
+	s.loadShared(ctx, messages...)
+	for _, message := range messages {
+		if message.ContentKey == "" {
+			continue
+		}
+		content, err := s.read(ctx, message.ContentKey)
+		if err != nil {
+			logger.Context(ctx).Warnw("Failed to load message content, serving its preview", "error", err, "id", message.ID, "key", message.ContentKey)
+			continue
+		}
+		message.Content = content
+	}
+}
+
+// loadShared replaces the previews of messages with the content they share, read at once
+func (s *MessageContentStore) loadShared(ctx context.Context, messages ...*models.Message) {
+	var hashes []string
+	for _, message := range messages {
+		if message.ContentHash != "" {
+			hashes = append(hashes, message.ContentHash)
+		}
+	}
+	if len(hashes) == 0 {
+		return
+	}
+	if s == nil {
+		logger.Context(ctx).Warnw("Message content storage is not configured, serving the previews of shared content", "count", len(hashes))
+		return
+	}
+
+	var contents []*models.MessageContent
+	if err := s.db.GetDB().WithContext(ctx).Where("hash IN ?", hashes).Find(&contents).Error; err != nil {
+		logger.Context(ctx).Warnw("Failed to load shared message content, serving its preview", "error", err, "count", len(hashes))
+		return
+	}
+	shared := make(map[string]string, len(contents))
+	for _, content := range contents {
+		shared[content.Hash] = content.Content
+	}
+	for _, message := range messages {
+		if message.ContentHash == "" {
+			continue
+		}
+		content, ok := shared[message.ContentHash]
+		if !ok {
+			logger.Context(ctx).Warnw("Shared message content not found, serving its preview", "id", message.ID, "hash", message.ContentHash)
+			continue
+		}
+		message.Content = content
+	}
+}
+
+// loadRevisions replaces the previews of revisions with their content in the storage
+func (s *MessageContentStore) loadRevisions(ctx context.Context, revisions []*models.MessageRevision) {
+	for _, revision := range revisions {
+		if revision.ContentKey == "" {
+			continue
+		}
+		content, err := s.read(ctx, revision.ContentKey)
+		if err != nil {
+			logger.Context(ctx).Warnw("Failed to load revision content, serving its preview", "error", err, "id", revision.ID, "key", revision.ContentKey)
+			continue
+		}
+		revision.Content = content
+	}
+}
+
+// read reads the content stored under key
+func (s *MessageContentStore) read(ctx context.Context, key string) (string, error) {
+	if s == nil {
+		return "", fmt.Errorf("message content storage is not configured")
+	}
+
+	file, err := s.storage.Open(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// remove deletes stored content no longer referenced. Failures are only logged: the rows
+// are gone, so the content is unreachable either way. Shared content is left to
+// DeleteUnreferenced, as other messages may still reference it.
+func (s *MessageContentStore) remove(ctx context.Context, keys ...string) {
+	if s == nil {
+		return
+	}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.Delete(ctx, key); err != nil {
+			logger.Context(ctx).Warnw("Failed to delete stored message content", "error", err, "key", key)
+		}
+	}
+}
+
+// DeleteUnreferenced deletes the shared content no message references anymore and last used
+// before a time, returning how many were deleted
+func (s *MessageContentStore) DeleteUnreferenced(ctx context.Context, usedBefore time.Time) (int64, error) {
+	log := logger.Context(ctx)
+
+	result := s.db.GetDB().WithContext(ctx).
+		Where("used_at < ? AND NOT EXISTS (SELECT 1 FROM messages WHERE messages.content_hash = message_contents.hash)", usedBefore).
+		Delete(&models.MessageContent{})
+	if result.Error != nil {
+		log.Errorw("Failed to delete unreferenced message content", "error", result.Error)
+		return 0, dbError(result.Error, "Failed to delete unreferenced message content")
+	}
+
+	return result.RowsAffected, nil
+}
+
+// storedContentKeys lists the storage keys of the content of the messages matched by query
+// and of their revisions
+func storedContentKeys(tx *gorm.DB, query string, args ...interface{}) ([]string, error) {
+	var keys []string
+	err := tx.Raw(`SELECT content_key FROM messages WHERE content_key <> '' AND `+query+`
+		UNION ALL
+		SELECT r.content_key FROM message_revisions r JOIN messages ON messages.id = r.message_id
+		WHERE r.content_key <> '' AND `+query, append(args, args...)...).
+		Scan(&keys).Error
+	return keys, err
+}
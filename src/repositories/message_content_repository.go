@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+	"time"
+)
+
+// MessageContentRepository defines the interface for the content shared by messages,
+// implemented by MessageContentStore
+type MessageContentRepository interface {
+	// DeleteUnreferenced deletes the shared content no message references anymore and last
+	// used before a time, returning how many were deleted
+	DeleteUnreferenced(ctx context.Context, usedBefore time.Time) (int64, error)
+}
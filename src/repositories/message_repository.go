@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"time"
 
+	"github.com/nvnamsss/chat/src/dtos"
 	"github.com/nvnamsss/chat/src/models"
 )
 
@@ -14,12 +16,96 @@ type MessageRepository interface {
 	// Get retrieves a message by ID
 	Get(ctx context.Context, id int64) (*models.Message, error)
 
+	// GetIDByPublicID returns the ID of the message with a public ID
+	GetIDByPublicID(ctx context.Context, publicID string) (int64, error)
+
 	// GetByChatID retrieves all messages for a chat
 	GetByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*models.Message, int64, error)
 
-	// Update updates a message
+	// ListByChatID retrieves a page of messages for a chat without counting them
+	ListByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*models.Message, error)
+
+	// ListByStatus retrieves the oldest messages with a status
+	ListByStatus(ctx context.Context, status string, limit int) ([]*models.Message, error)
+
+	// ListStalled retrieves the oldest messages with a status not updated since a time,
+	// without their content
+	ListStalled(ctx context.Context, status string, before time.Time, limit int) ([]*models.Message, error)
+
+	// GetRecent retrieves the latest messages of a chat with IDs above afterID, oldest first
+	GetRecent(ctx context.Context, chatID, afterID int64, limit int) ([]*models.Message, error)
+
+	// GetRange retrieves the messages of a chat with afterID < ID <= untilID, oldest first
+	GetRange(ctx context.Context, chatID, afterID, untilID int64) ([]*models.Message, error)
+
+	// ListUpToSeqNo retrieves the messages of a chat with a sequence number up to seqNo, oldest first
+	ListUpToSeqNo(ctx context.Context, chatID, seqNo int64) ([]*models.Message, error)
+
+	// Update updates the content and status of a message
 	Update(ctx context.Context, message *models.Message) error
 
+	// UpdateReply updates the content and status of an assistant reply saved while it was
+	// generated, along with the provider and model, token usage and latency of its generation
+	UpdateReply(ctx context.Context, message *models.Message) error
+
+	// SetStalledStatus changes the status of a message that still has status and was not
+	// updated since a time, reporting whether it did; messages updated meanwhile are left alone
+	SetStalledStatus(ctx context.Context, id int64, status, newStatus string, before time.Time) (bool, error)
+
 	// Delete deletes a message
 	Delete(ctx context.Context, id int64) error
+
+	// Revise updates the content of a message, recording the previous content as a revision
+	Revise(ctx context.Context, message *models.Message, previousContent string) error
+
+	// SetFeedback sets the user rating of a message
+	SetFeedback(ctx context.Context, id int64, feedback int) error
+
+	// AddReaction adds an emoji reaction to a message, reporting whether the user had not
+	// reacted with the emoji yet
+	AddReaction(ctx context.Context, reaction *models.MessageReaction) (bool, error)
+
+	// RemoveReaction removes the emoji reaction of a user to a message, reporting whether
+	// there was one
+	RemoveReaction(ctx context.Context, messageID int64, userID, emoji string) (bool, error)
+
+	// CountReactions counts the reactions to messages per emoji, most used first, flagging
+	// those of a user
+	CountReactions(ctx context.Context, messageIDs []int64, userID string) (map[int64][]dtos.ReactionCount, error)
+
+	// ListRevisions lists the revisions of a message, oldest first
+	ListRevisions(ctx context.Context, messageID int64) ([]*models.MessageRevision, error)
+
+	// ListModels retrieves the assistant messages of a chat generated by a known model, oldest
+	// first, with only their IDs, creation times, and the provider, model and model version
+	// that generated them
+	ListModels(ctx context.Context, chatID int64) ([]*models.Message, error)
+
+	// ListRevisionsAfter retrieves, by message ID, the first revision after a time of each
+	// message of a chat edited since then, which holds the content of the message at that time
+	ListRevisionsAfter(ctx context.Context, chatID int64, after time.Time) (map[int64]*models.MessageRevision, error)
+
+	// CountByUser counts the messages sent by a user
+	CountByUser(ctx context.Context, userID string) (int64, error)
+
+	// CountExpired counts the messages created before a time in the chats of the given
+	// users, or of all users but excludeUserIDs when userIDs is empty
+	CountExpired(ctx context.Context, userIDs, excludeUserIDs []string, before time.Time) (int64, error)
+
+	// ListExpired lists the oldest messages created before a time in the chats of the given
+	// users, or of all users but excludeUserIDs when userIDs is empty
+	ListExpired(ctx context.Context, userIDs, excludeUserIDs []string, before time.Time, limit int) ([]*models.Message, error)
+
+	// DeleteByChatID deletes the messages of a chat matching the filters of a request in one
+	// transaction, and returns them without their content
+	DeleteByChatID(ctx context.Context, chatID int64, req *dtos.DeleteMessagesRequest) ([]*models.Message, error)
+
+	// DeleteBatch deletes messages by ID
+	DeleteBatch(ctx context.Context, ids []int64) error
+
+	// CreateBatch creates messages in batches
+	CreateBatch(ctx context.Context, messages []*models.Message) error
+
+	// ListByIDs retrieves the messages with the given IDs in the chats of a user, in no order
+	ListByIDs(ctx context.Context, userID string, ids []int64) ([]*models.Message, error)
 }
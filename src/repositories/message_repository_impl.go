@@ -6,20 +6,25 @@ import (
 	"time"
 
 	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/dtos"
 	"github.com/nvnamsss/chat/src/errors"
 	"github.com/nvnamsss/chat/src/logger"
 	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/ulid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // messageRepository implements the MessageRepository interface
 type messageRepository struct {
-	db adapters.DBAdapter
+	db      adapters.DBAdapter
+	content *MessageContentStore
 }
 
-// NewMessageRepository creates a new message repository
-func NewMessageRepository(db adapters.DBAdapter) MessageRepository {
-	return &messageRepository{db: db}
+// NewMessageRepository creates a new message repository. Large content is kept in the content
+// store, when given.
+func NewMessageRepository(db adapters.DBAdapter, content *MessageContentStore) MessageRepository {
+	return &messageRepository{db: db, content: content}
 }
 
 // Create creates a new message
@@ -28,16 +33,74 @@ func (r *messageRepository) Create(ctx context.Context, message *models.Message)
 	now := time.Now()
 	message.CreatedAt = now
 	message.UpdatedAt = now
+	if message.PublicID == "" {
+		message.PublicID = ulid.Make(now)
+	}
+	if message.ContentType == "" {
+		message.ContentType = models.ContentTypeMarkdown
+	}
 
-	result := r.db.GetDB().WithContext(ctx).Create(message)
-	if result.Error != nil {
-		log.Errorw("Failed to create message", "error", result.Error)
-		return errors.Wrap(result.Error, errors.ErrInternal, "Failed to create message")
+	restore, keys, err := r.content.putMessages(ctx, []*models.Message{message})
+	if err != nil {
+		log.Errorw("Failed to store message content", "error", err, "chatID", message.ChatID)
+		return errors.Wrap(err, errors.ErrInternal, "Failed to create message")
+	}
+	defer restore()
+
+	err = r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seqNos, err := nextSeqNos(tx, map[int64]int{message.ChatID: 1})
+		if err != nil {
+			return err
+		}
+		message.SeqNo = seqNos[message.ChatID]
+
+		return tx.Create(message).Error
+	})
+	if err != nil {
+		r.content.remove(ctx, keys...)
+		if appErr, ok := err.(*errors.AppError); ok {
+			return appErr
+		}
+		log.Errorw("Failed to create message", "error", err)
+		return dbError(err, "Failed to create message")
 	}
 
 	return nil
 }
 
+// nextSeqNos reserves count sequence numbers in each chat and returns the first
+// reserved number per chat. The chat rows stay locked until the transaction ends,
+// so messages are numbered in commit order without gaps.
+func nextSeqNos(tx *gorm.DB, counts map[int64]int) (map[int64]int64, error) {
+	// Chats reserving the same count are updated in one statement
+	byCount := map[int][]int64{}
+	for chatID, count := range counts {
+		byCount[count] = append(byCount[count], chatID)
+	}
+
+	first := make(map[int64]int64, len(counts))
+	for count, chatIDs := range byCount {
+		var rows []struct {
+			ID        int64
+			LastSeqNo int64
+		}
+		if err := tx.Raw("UPDATE chats SET last_seq_no = last_seq_no + ? WHERE id IN ? RETURNING id, last_seq_no", count, chatIDs).
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			first[row.ID] = row.LastSeqNo - int64(count) + 1
+		}
+	}
+
+	for chatID := range counts {
+		if _, ok := first[chatID]; !ok {
+			return nil, errors.New(errors.ErrNotFound, fmt.Sprintf("Chat with ID %d not found", chatID))
+		}
+	}
+	return first, nil
+}
+
 // Get retrieves a message by ID
 func (r *messageRepository) Get(ctx context.Context, id int64) (*models.Message, error) {
 	log := logger.Context(ctx)
@@ -50,58 +113,215 @@ func (r *messageRepository) Get(ctx context.Context, id int64) (*models.Message,
 			return nil, errors.New(errors.ErrNotFound, "Message not found")
 		}
 		log.Errorw("Failed to get message", "error", result.Error, "id", id)
-		return nil, errors.Wrap(result.Error, errors.ErrInternal, "Failed to get message")
+		return nil, dbError(result.Error, "Failed to get message")
 	}
 
+	r.content.load(ctx, &message)
 	return &message, nil
 }
 
+// GetIDByPublicID returns the ID of the message with a public ID
+func (r *messageRepository) GetIDByPublicID(ctx context.Context, publicID string) (int64, error) {
+	log := logger.Context(ctx)
+	var ids []int64
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Message{}).Where("public_id = ?", publicID).Limit(1).Pluck("id", &ids)
+	if result.Error != nil {
+		log.Errorw("Failed to get message by public ID", "error", result.Error, "publicID", publicID)
+		return 0, dbError(result.Error, "Failed to get message")
+	}
+	if len(ids) == 0 {
+		return 0, errors.New(errors.ErrNotFound, "Message not found")
+	}
+
+	return ids[0], nil
+}
+
 // GetByChatID retrieves all messages for a chat
 func (r *messageRepository) GetByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*models.Message, int64, error) {
 	log := logger.Context(ctx)
-	var messages []*models.Message
 	var total int64
 
-	db := r.db.GetDB().WithContext(ctx)
-
 	// Get total count
-	if err := db.Model(&models.Message{}).Where("chat_id = ?", chatID).Count(&total).Error; err != nil {
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Message{}).Where("chat_id = ?", chatID).Count(&total).Error; err != nil {
 		log.Errorw("Failed to count messages", "error", err, "chatID", chatID)
-		return nil, 0, errors.Wrap(err, errors.ErrInternal, "Failed to count messages")
+		return nil, 0, dbError(err, "Failed to count messages")
+	}
+
+	messages, err := r.ListByChatID(ctx, chatID, limit, offset)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	// Get messages with pagination
-	if err := db.Where("chat_id = ?", chatID).
-		Order("created_at ASC").
+	return messages, total, nil
+}
+
+// ListByStatus retrieves the oldest messages with a status
+func (r *messageRepository) ListByStatus(ctx context.Context, status string, limit int) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if err := r.db.GetDB().WithContext(ctx).Where("status = ?", status).
+		Order("id ASC").
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		log.Errorw("Failed to list messages by status", "error", err, "status", status)
+		return nil, dbError(err, "Failed to list messages")
+	}
+
+	r.content.load(ctx, messages...)
+	return messages, nil
+}
+
+// ListStalled retrieves the oldest messages with a status not updated since a time,
+// without their content
+func (r *messageRepository) ListStalled(ctx context.Context, status string, before time.Time, limit int) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if err := r.db.GetDB().WithContext(ctx).Omit("content", "translation").
+		Where("status = ? AND updated_at < ?", status, before).
+		Order("id ASC").
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		log.Errorw("Failed to list stalled messages", "error", err, "status", status)
+		return nil, dbError(err, "Failed to list messages")
+	}
+
+	return messages, nil
+}
+
+// ListByChatID retrieves a page of messages for a chat without counting them
+func (r *messageRepository) ListByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if err := r.db.GetDB().WithContext(ctx).Where("chat_id = ?", chatID).
+		Order("seq_no ASC").
 		Limit(limit).
 		Offset(offset).
 		Find(&messages).Error; err != nil {
 		log.Errorw("Failed to get messages", "error", err, "chatID", chatID)
-		return nil, 0, errors.Wrap(err, errors.ErrInternal, "Failed to get messages")
+		return nil, dbError(err, "Failed to get messages")
 	}
 
-	return messages, total, nil
+	r.content.load(ctx, messages...)
+	return messages, nil
+}
+
+// GetRecent retrieves the latest messages of a chat with IDs above afterID, oldest first
+func (r *messageRepository) GetRecent(ctx context.Context, chatID, afterID int64, limit int) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("chat_id = ? AND id > ?", chatID, afterID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		log.Errorw("Failed to get recent messages", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to get messages")
+	}
+
+	// Restore chronological order
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	r.content.load(ctx, messages...)
+	return messages, nil
+}
+
+// GetRange retrieves the messages of a chat with afterID < ID <= untilID, oldest first
+func (r *messageRepository) GetRange(ctx context.Context, chatID, afterID, untilID int64) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("chat_id = ? AND id > ? AND id <= ?", chatID, afterID, untilID).
+		Order("id ASC").
+		Find(&messages).Error; err != nil {
+		log.Errorw("Failed to get messages", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to get messages")
+	}
+
+	r.content.load(ctx, messages...)
+	return messages, nil
+}
+
+// ListUpToSeqNo retrieves the messages of a chat with a sequence number up to seqNo, oldest first
+func (r *messageRepository) ListUpToSeqNo(ctx context.Context, chatID, seqNo int64) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("chat_id = ? AND seq_no <= ?", chatID, seqNo).
+		Order("seq_no ASC").
+		Find(&messages).Error; err != nil {
+		log.Errorw("Failed to list messages", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to get messages")
+	}
+
+	r.content.load(ctx, messages...)
+	return messages, nil
 }
 
 // Update updates a message
 func (r *messageRepository) Update(ctx context.Context, message *models.Message) error {
+	return r.update(ctx, message, map[string]interface{}{})
+}
+
+// UpdateReply updates the content and status of an assistant reply saved while it was
+// generated, along with the provider and model, token usage and latency of its generation
+func (r *messageRepository) UpdateReply(ctx context.Context, message *models.Message) error {
+	return r.update(ctx, message, map[string]interface{}{
+		"model":         message.Model,
+		"provider":      message.Provider,
+		"model_version": message.ModelVersion,
+		"total_tokens":  message.TotalTokens,
+		"latency_ms":    message.LatencyMs,
+		"suggestions":   message.Suggestions,
+	})
+}
+
+// update updates the content, status and translation of a message along with the given columns
+func (r *messageRepository) update(ctx context.Context, message *models.Message, columns map[string]interface{}) error {
 	log := logger.Context(ctx)
 	message.UpdatedAt = time.Now()
 
-	result := r.db.GetDB().WithContext(ctx).Model(message).Updates(map[string]interface{}{
-		"content":    message.Content,
-		"updated_at": message.UpdatedAt,
-	})
+	content, key, hash, err := r.content.store(ctx, message.ChatID, message.Content)
+	if err != nil {
+		log.Errorw("Failed to store message content", "error", err, "id", message.ID)
+		return errors.Wrap(err, errors.ErrInternal, "Failed to update message")
+	}
+
+	columns["content"] = content
+	columns["content_key"] = key
+	columns["content_hash"] = hash
+	if message.ContentType != "" {
+		columns["content_type"] = message.ContentType
+	}
+	columns["status"] = message.Status
+	columns["language"] = message.Language
+	columns["translation"] = message.Translation
+	columns["updated_at"] = message.UpdatedAt
+
+	result := r.db.GetDB().WithContext(ctx).Model(message).Updates(columns)
 
 	if result.Error != nil {
+		r.content.remove(ctx, key)
 		log.Errorw("Failed to update message", "error", result.Error, "id", message.ID)
-		return errors.Wrap(result.Error, errors.ErrInternal, "Failed to update message")
+		return dbError(result.Error, "Failed to update message")
 	}
 
 	if result.RowsAffected == 0 {
+		r.content.remove(ctx, key)
 		return errors.New(errors.ErrNotFound, fmt.Sprintf("Message with ID %d not found", message.ID))
 	}
 
+	// The content previously stored is replaced
+	r.content.remove(ctx, message.ContentKey)
+	message.ContentKey, message.ContentHash = key, hash
 	return nil
 }
 
@@ -109,10 +329,166 @@ func (r *messageRepository) Update(ctx context.Context, message *models.Message)
 func (r *messageRepository) Delete(ctx context.Context, id int64) error {
 	log := logger.Context(ctx)
 
-	result := r.db.GetDB().WithContext(ctx).Delete(&models.Message{}, id)
+	var contentKeys []string
+	err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if contentKeys, err = storedContentKeys(tx, "messages.id = ?", id); err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.Message{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(errors.ErrNotFound, fmt.Sprintf("Message with ID %d not found", id))
+		}
+		return nil
+	})
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return appErr
+		}
+		log.Errorw("Failed to delete message", "error", err, "id", id)
+		return dbError(err, "Failed to delete message")
+	}
+
+	r.content.remove(ctx, contentKeys...)
+	return nil
+}
+
+// messageBatchSize is the number of messages inserted per statement by CreateBatch
+const messageBatchSize = 500
+
+// CreateBatch creates messages in batches
+func (r *messageRepository) CreateBatch(ctx context.Context, messages []*models.Message) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	for _, message := range messages {
+		message.CreatedAt = now
+		message.UpdatedAt = now
+		if message.PublicID == "" {
+			message.PublicID = ulid.Make(now)
+		}
+		if message.ContentType == "" {
+			message.ContentType = models.ContentTypeMarkdown
+		}
+	}
+
+	restore, keys, err := r.content.putMessages(ctx, messages)
+	if err != nil {
+		log.Errorw("Failed to store message content", "error", err, "count", len(messages))
+		return errors.Wrap(err, errors.ErrInternal, "Failed to create messages")
+	}
+	defer restore()
+
+	err = r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		counts := map[int64]int{}
+		for _, message := range messages {
+			counts[message.ChatID]++
+		}
+
+		seqNos, err := nextSeqNos(tx, counts)
+		if err != nil {
+			return err
+		}
+		for _, message := range messages {
+			message.SeqNo = seqNos[message.ChatID]
+			seqNos[message.ChatID]++
+		}
+
+		return tx.CreateInBatches(messages, messageBatchSize).Error
+	})
+	if err != nil {
+		r.content.remove(ctx, keys...)
+		if appErr, ok := err.(*errors.AppError); ok {
+			return appErr
+		}
+		log.Errorw("Failed to create messages", "error", err, "count", len(messages))
+		return dbError(err, "Failed to create messages")
+	}
+
+	return nil
+}
+
+// Revise updates the content of a message, recording the previous content as a revision
+func (r *messageRepository) Revise(ctx context.Context, message *models.Message, previousContent string) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+
+	// The revision takes over the previous content in the storage, if any
+	revision := &models.MessageRevision{
+		MessageID:  message.ID,
+		Content:    previousContent,
+		ContentKey: message.ContentKey,
+		CreatedAt:  now,
+	}
+	var keys []string // Content stored here, removed when the revision fails
+	var err error
+	if revision.ContentKey != "" {
+		revision.Content = models.ContentPreview(previousContent)
+	} else {
+		revision.Content, revision.ContentKey, err = r.content.put(ctx, message.ChatID, previousContent)
+		keys = append(keys, revision.ContentKey)
+	}
+	var content, key, hash string
+	if err == nil {
+		content, key, hash, err = r.content.store(ctx, message.ChatID, message.Content)
+		keys = append(keys, key)
+	}
+	if err != nil {
+		r.content.remove(ctx, keys...)
+		log.Errorw("Failed to store message content", "error", err, "id", message.ID)
+		return errors.Wrap(err, errors.ErrInternal, "Failed to update message")
+	}
+
+	err = r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Message").Create(revision).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(message).Updates(map[string]interface{}{
+			"content":      content,
+			"content_key":  key,
+			"content_hash": hash,
+			"content_type": message.ContentType,
+			"language":     message.Language,
+			"translation":  message.Translation,
+			"edited_at":    now,
+			"updated_at":   now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(errors.ErrNotFound, fmt.Sprintf("Message with ID %d not found", message.ID))
+		}
+		return nil
+	})
+	if err != nil {
+		r.content.remove(ctx, keys...)
+		if appErr, ok := err.(*errors.AppError); ok {
+			return appErr
+		}
+		log.Errorw("Failed to revise message", "error", err, "id", message.ID)
+		return dbError(err, "Failed to update message")
+	}
+
+	message.ContentKey, message.ContentHash = key, hash
+	message.EditedAt = &now
+	message.UpdatedAt = now
+	return nil
+}
+
+// SetFeedback sets the user rating of a message
+func (r *messageRepository) SetFeedback(ctx context.Context, id int64, feedback int) error {
+	log := logger.Context(ctx)
+
+	// Rating does not change the message, so updated_at is left alone
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Message{}).Where("id = ?", id).UpdateColumn("feedback", feedback)
 	if result.Error != nil {
-		log.Errorw("Failed to delete message", "error", result.Error, "id", id)
-		return errors.Wrap(result.Error, errors.ErrInternal, "Failed to delete message")
+		log.Errorw("Failed to set message feedback", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to set message feedback")
 	}
 
 	if result.RowsAffected == 0 {
@@ -121,3 +497,285 @@ func (r *messageRepository) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// SetStalledStatus changes the status of a message that still has status and was not updated
+// since a time, reporting whether it did; messages updated meanwhile are left alone
+func (r *messageRepository) SetStalledStatus(ctx context.Context, id int64, status, newStatus string, before time.Time) (bool, error) {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Message{}).
+		Where("id = ? AND status = ? AND updated_at < ?", id, status, before).
+		UpdateColumns(map[string]interface{}{"status": newStatus, "updated_at": time.Now()})
+	if result.Error != nil {
+		log.Errorw("Failed to set message status", "error", result.Error, "id", id)
+		return false, dbError(result.Error, "Failed to update message")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// AddReaction adds an emoji reaction to a message, reporting whether the user had not
+// reacted with the emoji yet
+func (r *messageRepository) AddReaction(ctx context.Context, reaction *models.MessageReaction) (bool, error) {
+	log := logger.Context(ctx)
+	reaction.CreatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Omit("Message").Clauses(clause.OnConflict{DoNothing: true}).Create(reaction)
+	if result.Error != nil {
+		log.Errorw("Failed to add message reaction", "error", result.Error, "messageID", reaction.MessageID)
+		return false, dbError(result.Error, "Failed to add message reaction")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// RemoveReaction removes the emoji reaction of a user to a message, reporting whether there
+// was one
+func (r *messageRepository) RemoveReaction(ctx context.Context, messageID int64, userID, emoji string) (bool, error) {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).
+		Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+		Delete(&models.MessageReaction{})
+	if result.Error != nil {
+		log.Errorw("Failed to remove message reaction", "error", result.Error, "messageID", messageID)
+		return false, dbError(result.Error, "Failed to remove message reaction")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// CountReactions counts the reactions to messages per emoji, most used first, flagging those
+// of a user
+func (r *messageRepository) CountReactions(ctx context.Context, messageIDs []int64, userID string) (map[int64][]dtos.ReactionCount, error) {
+	log := logger.Context(ctx)
+	counts := map[int64][]dtos.ReactionCount{}
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		MessageID int64
+		Emoji     string
+		Count     int64
+		Reacted   bool
+	}
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.MessageReaction{}).
+		Select("message_id, emoji, COUNT(*) AS count, BOOL_OR(user_id = ?) AS reacted", userID).
+		Where("message_id IN ?", messageIDs).
+		Group("message_id, emoji").
+		Order("message_id, count DESC, MIN(created_at)").
+		Scan(&rows).Error; err != nil {
+		log.Errorw("Failed to count message reactions", "error", err, "messages", len(messageIDs))
+		return nil, dbError(err, "Failed to count message reactions")
+	}
+
+	for _, row := range rows {
+		counts[row.MessageID] = append(counts[row.MessageID], dtos.ReactionCount{Emoji: row.Emoji, Count: row.Count, Reacted: row.Reacted})
+	}
+	return counts, nil
+}
+
+// ListRevisions lists the revisions of a message, oldest first
+func (r *messageRepository) ListRevisions(ctx context.Context, messageID int64) ([]*models.MessageRevision, error) {
+	log := logger.Context(ctx)
+	var revisions []*models.MessageRevision
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("message_id = ?", messageID).
+		Order("created_at ASC, id ASC").
+		Find(&revisions).Error; err != nil {
+		log.Errorw("Failed to list message revisions", "error", err, "messageID", messageID)
+		return nil, dbError(err, "Failed to list message revisions")
+	}
+
+	r.content.loadRevisions(ctx, revisions)
+	return revisions, nil
+}
+
+// ListModels retrieves the assistant messages of a chat generated by a known model, oldest
+// first, with only their IDs, creation times, and the provider, model and model version that
+// generated them
+func (r *messageRepository) ListModels(ctx context.Context, chatID int64) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Select("id", "chat_id", "seq_no", "role", "provider", "model", "model_version", "created_at").
+		Where("chat_id = ? AND role = ? AND model <> ''", chatID, models.MessageRoleAssistant).
+		Order("seq_no ASC").
+		Find(&messages).Error; err != nil {
+		log.Errorw("Failed to list message models", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to list message models")
+	}
+
+	return messages, nil
+}
+
+// ListRevisionsAfter retrieves, by message ID, the first revision after a time of each
+// message of a chat edited since then, which holds the content of the message at that time
+func (r *messageRepository) ListRevisionsAfter(ctx context.Context, chatID int64, after time.Time) (map[int64]*models.MessageRevision, error) {
+	log := logger.Context(ctx)
+	var revisions []*models.MessageRevision
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Select("DISTINCT ON (message_revisions.message_id) message_revisions.*").
+		Joins("JOIN messages ON messages.id = message_revisions.message_id").
+		Where("messages.chat_id = ? AND message_revisions.created_at > ?", chatID, after).
+		Order("message_revisions.message_id, message_revisions.created_at ASC, message_revisions.id ASC").
+		Find(&revisions).Error; err != nil {
+		log.Errorw("Failed to list message revisions", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to list message revisions")
+	}
+
+	r.content.loadRevisions(ctx, revisions)
+	byMessageID := make(map[int64]*models.MessageRevision, len(revisions))
+	for _, revision := range revisions {
+		byMessageID[revision.MessageID] = revision
+	}
+	return byMessageID, nil
+}
+
+// CountByUser counts the messages sent by a user
+func (r *messageRepository) CountByUser(ctx context.Context, userID string) (int64, error) {
+	log := logger.Context(ctx)
+	var count int64
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Message{}).
+		Where("user_id = ?", userID).
+		Count(&count).Error; err != nil {
+		log.Errorw("Failed to count user messages", "error", err, "userID", userID)
+		return 0, dbError(err, "Failed to count messages")
+	}
+
+	return count, nil
+}
+
+// expiredQuery selects the messages created before a time in the chats of the given
+// users, or of all users but excludeUserIDs when userIDs is empty
+func (r *messageRepository) expiredQuery(ctx context.Context, userIDs, excludeUserIDs []string, before time.Time) *gorm.DB {
+	query := r.db.GetDB().WithContext(ctx).Model(&models.Message{}).
+		Joins("JOIN chats ON chats.id = messages.chat_id").
+		Where("messages.created_at < ?", before)
+
+	if len(userIDs) > 0 {
+		query = query.Where("chats.user_id IN ?", userIDs)
+	} else if len(excludeUserIDs) > 0 {
+		query = query.Where("chats.user_id NOT IN ?", excludeUserIDs)
+	}
+	return query
+}
+
+// CountExpired counts the messages created before a time in the chats of the given users
+func (r *messageRepository) CountExpired(ctx context.Context, userIDs, excludeUserIDs []string, before time.Time) (int64, error) {
+	log := logger.Context(ctx)
+	var count int64
+
+	if err := r.expiredQuery(ctx, userIDs, excludeUserIDs, before).Count(&count).Error; err != nil {
+		log.Errorw("Failed to count expired messages", "error", err)
+		return 0, dbError(err, "Failed to count messages")
+	}
+
+	return count, nil
+}
+
+// ListExpired lists the oldest messages created before a time in the chats of the given users
+func (r *messageRepository) ListExpired(ctx context.Context, userIDs, excludeUserIDs []string, before time.Time, limit int) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if err := r.expiredQuery(ctx, userIDs, excludeUserIDs, before).
+		Select("messages.*").
+		Order("messages.id ASC").
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		log.Errorw("Failed to list expired messages", "error", err)
+		return nil, dbError(err, "Failed to list messages")
+	}
+
+	return messages, nil
+}
+
+// DeleteBatch deletes messages by ID
+func (r *messageRepository) DeleteBatch(ctx context.Context, ids []int64) error {
+	log := logger.Context(ctx)
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var contentKeys []string
+	err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if contentKeys, err = storedContentKeys(tx, "messages.id IN ?", ids); err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&models.Message{}).Error
+	})
+	if err != nil {
+		log.Errorw("Failed to delete messages", "error", err, "count", len(ids))
+		return dbError(err, "Failed to delete messages")
+	}
+
+	r.content.remove(ctx, contentKeys...)
+	return nil
+}
+
+// DeleteByChatID deletes the messages of a chat matching the filters of a request in one
+// transaction, and returns them without their content
+func (r *messageRepository) DeleteByChatID(ctx context.Context, chatID int64, req *dtos.DeleteMessagesRequest) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+
+	query, args := "messages.chat_id = ?", []interface{}{chatID}
+	if req.Before != nil {
+		query += " AND messages.created_at < ?"
+		args = append(args, *req.Before)
+	}
+	if req.Role != "" {
+		query += " AND messages.role = ?"
+		args = append(args, req.Role)
+	}
+	if len(req.IDs) > 0 {
+		query += " AND messages.id IN ?"
+		args = append(args, req.IDs)
+	}
+
+	var messages []*models.Message
+	var contentKeys []string
+	err := r.db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if contentKeys, err = storedContentKeys(tx, query, args...); err != nil {
+			return err
+		}
+		returning := clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "chat_id"}, {Name: "seq_no"}, {Name: "user_id"}, {Name: "role"}}}
+		return tx.Clauses(returning).Where(query, args...).Delete(&messages).Error
+	})
+	if err != nil {
+		log.Errorw("Failed to delete chat messages", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to delete messages")
+	}
+
+	r.content.remove(ctx, contentKeys...)
+	return messages, nil
+}
+
+// ListByIDs retrieves the messages with the given IDs in the chats of a user, in no order
+func (r *messageRepository) ListByIDs(ctx context.Context, userID string, ids []int64) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if len(ids) == 0 {
+		return messages, nil
+	}
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Joins("JOIN chats ON chats.id = messages.chat_id").
+		Where("messages.id IN ? AND chats.user_id = ? AND chats.delete_at IS NULL", ids, userID).
+		Find(&messages).Error; err != nil {
+		log.Errorw("Failed to list messages by ID", "error", err, "userID", userID)
+		return nil, dbError(err, "Failed to list messages")
+	}
+
+	r.content.load(ctx, messages...)
+	return messages, nil
+}
@@ -0,0 +1,252 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// messageRepositoryMetrics decorates a MessageRepository, recording the calls of its methods
+type messageRepositoryMetrics struct {
+	next MessageRepository
+}
+
+// NewMessageRepositoryMetrics wraps a message repository to record the count, latency and
+// outcome of the calls of each of its methods
+func NewMessageRepositoryMetrics(next MessageRepository) MessageRepository {
+	return &messageRepositoryMetrics{next: next}
+}
+
+// Create records the call of Create of the wrapped repository
+func (r *messageRepositoryMetrics) Create(ctx context.Context, message *models.Message) error {
+	start := time.Now()
+	err := r.next.Create(ctx, message)
+	observeCall(messageRepositoryName, "Create", start, err)
+	return err
+}
+
+// Get records the call of Get of the wrapped repository
+func (r *messageRepositoryMetrics) Get(ctx context.Context, id int64) (*models.Message, error) {
+	start := time.Now()
+	message, err := r.next.Get(ctx, id)
+	observeCall(messageRepositoryName, "Get", start, err)
+	return message, err
+}
+
+// GetIDByPublicID records the call of GetIDByPublicID of the wrapped repository
+func (r *messageRepositoryMetrics) GetIDByPublicID(ctx context.Context, publicID string) (int64, error) {
+	start := time.Now()
+	id, err := r.next.GetIDByPublicID(ctx, publicID)
+	observeCall(messageRepositoryName, "GetIDByPublicID", start, err)
+	return id, err
+}
+
+// GetByChatID records the call of GetByChatID of the wrapped repository
+func (r *messageRepositoryMetrics) GetByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*models.Message, int64, error) {
+	start := time.Now()
+	messages, total, err := r.next.GetByChatID(ctx, chatID, limit, offset)
+	observeCall(messageRepositoryName, "GetByChatID", start, err)
+	return messages, total, err
+}
+
+// ListByChatID records the call of ListByChatID of the wrapped repository
+func (r *messageRepositoryMetrics) ListByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*models.Message, error) {
+	start := time.Now()
+	messages, err := r.next.ListByChatID(ctx, chatID, limit, offset)
+	observeCall(messageRepositoryName, "ListByChatID", start, err)
+	return messages, err
+}
+
+// ListByStatus records the call of ListByStatus of the wrapped repository
+func (r *messageRepositoryMetrics) ListByStatus(ctx context.Context, status string, limit int) ([]*models.Message, error) {
+	start := time.Now()
+	messages, err := r.next.ListByStatus(ctx, status, limit)
+	observeCall(messageRepositoryName, "ListByStatus", start, err)
+	return messages, err
+}
+
+// ListStalled records the call of ListStalled of the wrapped repository
+func (r *messageRepositoryMetrics) ListStalled(ctx context.Context, status string, before time.Time, limit int) ([]*models.Message, error) {
+	start := time.Now()
+	messages, err := r.next.ListStalled(ctx, status, before, limit)
+	observeCall(messageRepositoryName, "ListStalled", start, err)
+	return messages, err
+}
+
+// GetRecent records the call of GetRecent of the wrapped repository
+func (r *messageRepositoryMetrics) GetRecent(ctx context.Context, chatID, afterID int64, limit int) ([]*models.Message, error) {
+	start := time.Now()
+	messages, err := r.next.GetRecent(ctx, chatID, afterID, limit)
+	observeCall(messageRepositoryName, "GetRecent", start, err)
+	return messages, err
+}
+
+// GetRange records the call of GetRange of the wrapped repository
+func (r *messageRepositoryMetrics) GetRange(ctx context.Context, chatID, afterID, untilID int64) ([]*models.Message, error) {
+	start := time.Now()
+	messages, err := r.next.GetRange(ctx, chatID, afterID, untilID)
+	observeCall(messageRepositoryName, "GetRange", start, err)
+	return messages, err
+}
+
+// ListUpToSeqNo records the call of ListUpToSeqNo of the wrapped repository
+func (r *messageRepositoryMetrics) ListUpToSeqNo(ctx context.Context, chatID, seqNo int64) ([]*models.Message, error) {
+	start := time.Now()
+	messages, err := r.next.ListUpToSeqNo(ctx, chatID, seqNo)
+	observeCall(messageRepositoryName, "ListUpToSeqNo", start, err)
+	return messages, err
+}
+
+// Update records the call of Update of the wrapped repository
+func (r *messageRepositoryMetrics) Update(ctx context.Context, message *models.Message) error {
+	start := time.Now()
+	err := r.next.Update(ctx, message)
+	observeCall(messageRepositoryName, "Update", start, err)
+	return err
+}
+
+// UpdateReply records the call of UpdateReply of the wrapped repository
+func (r *messageRepositoryMetrics) UpdateReply(ctx context.Context, message *models.Message) error {
+	start := time.Now()
+	err := r.next.UpdateReply(ctx, message)
+	observeCall(messageRepositoryName, "UpdateReply", start, err)
+	return err
+}
+
+// SetStalledStatus records the call of SetStalledStatus of the wrapped repository
+func (r *messageRepositoryMetrics) SetStalledStatus(ctx context.Context, id int64, status, newStatus string, before time.Time) (bool, error) {
+	start := time.Now()
+	changed, err := r.next.SetStalledStatus(ctx, id, status, newStatus, before)
+	observeCall(messageRepositoryName, "SetStalledStatus", start, err)
+	return changed, err
+}
+
+// Delete records the call of Delete of the wrapped repository
+func (r *messageRepositoryMetrics) Delete(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	observeCall(messageRepositoryName, "Delete", start, err)
+	return err
+}
+
+// Revise records the call of Revise of the wrapped repository
+func (r *messageRepositoryMetrics) Revise(ctx context.Context, message *models.Message, previousContent string) error {
+	start := time.Now()
+	err := r.next.Revise(ctx, message, previousContent)
+	observeCall(messageRepositoryName, "Revise", start, err)
+	return err
+}
+
+// SetFeedback records the call of SetFeedback of the wrapped repository
+func (r *messageRepositoryMetrics) SetFeedback(ctx context.Context, id int64, feedback int) error {
+	start := time.Now()
+	err := r.next.SetFeedback(ctx, id, feedback)
+	observeCall(messageRepositoryName, "SetFeedback", start, err)
+	return err
+}
+
+// AddReaction records the call of AddReaction of the wrapped repository
+func (r *messageRepositoryMetrics) AddReaction(ctx context.Context, reaction *models.MessageReaction) (bool, error) {
+	start := time.Now()
+	added, err := r.next.AddReaction(ctx, reaction)
+	observeCall(messageRepositoryName, "AddReaction", start, err)
+	return added, err
+}
+
+// RemoveReaction records the call of RemoveReaction of the wrapped repository
+func (r *messageRepositoryMetrics) RemoveReaction(ctx context.Context, messageID int64, userID, emoji string) (bool, error) {
+	start := time.Now()
+	removed, err := r.next.RemoveReaction(ctx, messageID, userID, emoji)
+	observeCall(messageRepositoryName, "RemoveReaction", start, err)
+	return removed, err
+}
+
+// CountReactions records the call of CountReactions of the wrapped repository
+func (r *messageRepositoryMetrics) CountReactions(ctx context.Context, messageIDs []int64, userID string) (map[int64][]dtos.ReactionCount, error) {
+	start := time.Now()
+	counts, err := r.next.CountReactions(ctx, messageIDs, userID)
+	observeCall(messageRepositoryName, "CountReactions", start, err)
+	return counts, err
+}
+
+// ListRevisions records the call of ListRevisions of the wrapped repository
+func (r *messageRepositoryMetrics) ListRevisions(ctx context.Context, messageID int64) ([]*models.MessageRevision, error) {
+	start := time.Now()
+	revisions, err := r.next.ListRevisions(ctx, messageID)
+	observeCall(messageRepositoryName, "ListRevisions", start, err)
+	return revisions, err
+}
+
+// ListModels records the call of ListModels of the wrapped repository
+func (r *messageRepositoryMetrics) ListModels(ctx context.Context, chatID int64) ([]*models.Message, error) {
+	start := time.Now()
+	messages, err := r.next.ListModels(ctx, chatID)
+	observeCall(messageRepositoryName, "ListModels", start, err)
+	return messages, err
+}
+
+// ListRevisionsAfter records the call of ListRevisionsAfter of the wrapped repository
+func (r *messageRepositoryMetrics) ListRevisionsAfter(ctx context.Context, chatID int64, after time.Time) (map[int64]*models.MessageRevision, error) {
+	start := time.Now()
+	revisions, err := r.next.ListRevisionsAfter(ctx, chatID, after)
+	observeCall(messageRepositoryName, "ListRevisionsAfter", start, err)
+	return revisions, err
+}
+
+// CountByUser records the call of CountByUser of the wrapped repository
+func (r *messageRepositoryMetrics) CountByUser(ctx context.Context, userID string) (int64, error) {
+	start := time.Now()
+	count, err := r.next.CountByUser(ctx, userID)
+	observeCall(messageRepositoryName, "CountByUser", start, err)
+	return count, err
+}
+
+// CountExpired records the call of CountExpired of the wrapped repository
+func (r *messageRepositoryMetrics) CountExpired(ctx context.Context, userIDs, excludeUserIDs []string, before time.Time) (int64, error) {
+	start := time.Now()
+	count, err := r.next.CountExpired(ctx, userIDs, excludeUserIDs, before)
+	observeCall(messageRepositoryName, "CountExpired", start, err)
+	return count, err
+}
+
+// ListExpired records the call of ListExpired of the wrapped repository
+func (r *messageRepositoryMetrics) ListExpired(ctx context.Context, userIDs, excludeUserIDs []string, before time.Time, limit int) ([]*models.Message, error) {
+	start := time.Now()
+	messages, err := r.next.ListExpired(ctx, userIDs, excludeUserIDs, before, limit)
+	observeCall(messageRepositoryName, "ListExpired", start, err)
+	return messages, err
+}
+
+// DeleteByChatID records the call of DeleteByChatID of the wrapped repository
+func (r *messageRepositoryMetrics) DeleteByChatID(ctx context.Context, chatID int64, req *dtos.DeleteMessagesRequest) ([]*models.Message, error) {
+	start := time.Now()
+	messages, err := r.next.DeleteByChatID(ctx, chatID, req)
+	observeCall(messageRepositoryName, "DeleteByChatID", start, err)
+	return messages, err
+}
+
+// DeleteBatch records the call of DeleteBatch of the wrapped repository
+func (r *messageRepositoryMetrics) DeleteBatch(ctx context.Context, ids []int64) error {
+	start := time.Now()
+	err := r.next.DeleteBatch(ctx, ids)
+	observeCall(messageRepositoryName, "DeleteBatch", start, err)
+	return err
+}
+
+// CreateBatch records the call of CreateBatch of the wrapped repository
+func (r *messageRepositoryMetrics) CreateBatch(ctx context.Context, messages []*models.Message) error {
+	start := time.Now()
+	err := r.next.CreateBatch(ctx, messages)
+	observeCall(messageRepositoryName, "CreateBatch", start, err)
+	return err
+}
+
+// ListByIDs records the call of ListByIDs of the wrapped repository
+func (r *messageRepositoryMetrics) ListByIDs(ctx context.Context, userID string, ids []int64) ([]*models.Message, error) {
+	start := time.Now()
+	messages, err := r.next.ListByIDs(ctx, userID, ids)
+	observeCall(messageRepositoryName, "ListByIDs", start, err)
+	return messages, err
+}
@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/metrics"
+)
+
+// Repository names in metrics
+const (
+	chatRepositoryName    = "chat"
+	messageRepositoryName = "message"
+)
+
+// observeCall records the latency and outcome of a call of a repository method that started
+// at start. Not found errors are answers rather than failures, so they are counted apart.
+func observeCall(repository, method string, start time.Time, err error) {
+	status := "success"
+	if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+		status = "not_found"
+	} else if err != nil {
+		status = "error"
+	}
+
+	metrics.RepositoryLatency.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	metrics.RepositoryCalls.WithLabelValues(repository, method, status).Inc()
+}
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// NotificationRepository defines the interface for notification preference data access
+type NotificationRepository interface {
+	// Get retrieves the notification preferences of a user
+	Get(ctx context.Context, userID string) (*models.NotificationPreference, error)
+
+	// Upsert creates or replaces the notification preferences of a user
+	Upsert(ctx context.Context, preference *models.NotificationPreference) error
+
+	// ListDigestsDue lists up to limit preferences of users with an email receiving digests of
+	// frequency, whose last digest was sent before sentBefore or never, longest waiting first
+	ListDigestsDue(ctx context.Context, frequency string, sentBefore time.Time, limit int) ([]*models.NotificationPreference, error)
+
+	// MarkDigestSent records when the last digest of a user was sent
+	MarkDigestSent(ctx context.Context, userID string, sentAt time.Time) error
+
+	// AddDigestItem queues a notification for the next digest of a user
+	AddDigestItem(ctx context.Context, item *models.NotificationDigestItem) error
+
+	// ListDigestItems lists up to limit notifications queued for the digest of a user, oldest
+	// first, with the number queued
+	ListDigestItems(ctx context.Context, userID string, limit int) ([]*models.NotificationDigestItem, int64, error)
+
+	// DeleteDigestItems removes the notifications queued for the digest of a user up to an ID
+	DeleteDigestItems(ctx context.Context, userID string, throughID int64) error
+}
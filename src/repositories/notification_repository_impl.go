@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// notificationRepository implements the NotificationRepository interface
+type notificationRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewNotificationRepository creates a new notification preference repository
+func NewNotificationRepository(db adapters.DBAdapter) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+// Get retrieves the notification preferences of a user
+func (r *notificationRepository) Get(ctx context.Context, userID string) (*models.NotificationPreference, error) {
+	log := logger.Context(ctx)
+	var preference models.NotificationPreference
+
+	result := r.db.GetDB().WithContext(ctx).Where("user_id = ?", userID).First(&preference)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Notification preferences not found")
+		}
+		log.Errorw("Failed to get notification preferences", "error", result.Error, "userID", userID)
+		return nil, dbError(result.Error, "Failed to get notification preferences")
+	}
+
+	return &preference, nil
+}
+
+// Upsert creates or replaces the notification preferences of a user
+func (r *notificationRepository) Upsert(ctx context.Context, preference *models.NotificationPreference) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	preference.CreatedAt = now
+	preference.UpdatedAt = now
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"email", "webhook_url", "webhook_secret", "disabled_notifications", "digest_frequency", "updated_at"}),
+	}).Create(preference)
+	if result.Error != nil {
+		log.Errorw("Failed to save notification preferences", "error", result.Error, "userID", preference.UserID)
+		return dbError(result.Error, "Failed to save notification preferences")
+	}
+
+	return nil
+}
+
+// ListDigestsDue lists up to limit preferences of users with an email receiving digests of
+// frequency, whose last digest was sent before sentBefore or never, longest waiting first
+func (r *notificationRepository) ListDigestsDue(ctx context.Context, frequency string, sentBefore time.Time, limit int) ([]*models.NotificationPreference, error) {
+	log := logger.Context(ctx)
+	var preferences []*models.NotificationPreference
+
+	result := r.db.GetDB().WithContext(ctx).
+		Where("digest_frequency = ? AND email <> '' AND (digest_sent_at IS NULL OR digest_sent_at < ?)", frequency, sentBefore).
+		Order("digest_sent_at ASC NULLS FIRST").
+		Limit(limit).
+		Find(&preferences)
+	if result.Error != nil {
+		log.Errorw("Failed to list digests due", "error", result.Error, "frequency", frequency)
+		return nil, dbError(result.Error, "Failed to list digests due")
+	}
+
+	return preferences, nil
+}
+
+// MarkDigestSent records when the last digest of a user was sent
+func (r *notificationRepository) MarkDigestSent(ctx context.Context, userID string, sentAt time.Time) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.NotificationPreference{}).
+		Where("user_id = ?", userID).
+		Update("digest_sent_at", sentAt)
+	if result.Error != nil {
+		log.Errorw("Failed to mark digest sent", "error", result.Error, "userID", userID)
+		return dbError(result.Error, "Failed to mark digest sent")
+	}
+
+	return nil
+}
+
+// AddDigestItem queues a notification for the next digest of a user
+func (r *notificationRepository) AddDigestItem(ctx context.Context, item *models.NotificationDigestItem) error {
+	log := logger.Context(ctx)
+	item.CreatedAt = time.Now()
+
+	if result := r.db.GetDB().WithContext(ctx).Create(item); result.Error != nil {
+		log.Errorw("Failed to add digest item", "error", result.Error, "userID", item.UserID)
+		return dbError(result.Error, "Failed to add digest item")
+	}
+
+	return nil
+}
+
+// ListDigestItems lists up to limit notifications queued for the digest of a user, oldest
+// first, with the number queued
+func (r *notificationRepository) ListDigestItems(ctx context.Context, userID string, limit int) ([]*models.NotificationDigestItem, int64, error) {
+	log := logger.Context(ctx)
+	query := r.db.GetDB().WithContext(ctx).Model(&models.NotificationDigestItem{}).Where("user_id = ?", userID)
+
+	var total int64
+	if result := query.Count(&total); result.Error != nil {
+		log.Errorw("Failed to count digest items", "error", result.Error, "userID", userID)
+		return nil, 0, dbError(result.Error, "Failed to count digest items")
+	}
+
+	var items []*models.NotificationDigestItem
+	if result := query.Order("id ASC").Limit(limit).Find(&items); result.Error != nil {
+		log.Errorw("Failed to list digest items", "error", result.Error, "userID", userID)
+		return nil, 0, dbError(result.Error, "Failed to list digest items")
+	}
+
+	return items, total, nil
+}
+
+// DeleteDigestItems removes the notifications queued for the digest of a user up to an ID
+func (r *notificationRepository) DeleteDigestItems(ctx context.Context, userID string, throughID int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).
+		Where("user_id = ? AND id <= ?", userID, throughID).
+		Delete(&models.NotificationDigestItem{})
+	if result.Error != nil {
+		log.Errorw("Failed to delete digest items", "error", result.Error, "userID", userID)
+		return dbError(result.Error, "Failed to delete digest items")
+	}
+
+	return nil
+}
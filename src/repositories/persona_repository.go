@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// PersonaRepository defines the interface for group chat persona data access
+type PersonaRepository interface {
+	// Create creates a persona
+	Create(ctx context.Context, persona *models.Persona) error
+
+	// ListByChatID retrieves the personas of a chat in creation order
+	ListByChatID(ctx context.Context, chatID int64) ([]*models.Persona, error)
+
+	// Update updates the name, model and system prompt of a persona
+	Update(ctx context.Context, persona *models.Persona) error
+
+	// Delete deletes a persona of a chat
+	Delete(ctx context.Context, chatID, id int64) error
+}
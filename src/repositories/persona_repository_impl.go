@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// personaRepository implements the PersonaRepository interface
+type personaRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewPersonaRepository creates a new persona repository
+func NewPersonaRepository(db adapters.DBAdapter) PersonaRepository {
+	return &personaRepository{db: db}
+}
+
+// Create creates a persona
+func (r *personaRepository) Create(ctx context.Context, persona *models.Persona) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	persona.CreatedAt = now
+	persona.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(persona).Error; err != nil {
+		log.Errorw("Failed to create persona", "error", err, "chatID", persona.ChatID)
+		return dbError(err, "Failed to create persona")
+	}
+
+	return nil
+}
+
+// ListByChatID retrieves the personas of a chat in creation order
+func (r *personaRepository) ListByChatID(ctx context.Context, chatID int64) ([]*models.Persona, error) {
+	log := logger.Context(ctx)
+	var personas []*models.Persona
+
+	if err := r.db.GetDB().WithContext(ctx).Where("chat_id = ?", chatID).Order("id").Find(&personas).Error; err != nil {
+		log.Errorw("Failed to list personas", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to list personas")
+	}
+
+	return personas, nil
+}
+
+// Update updates the name, model and system prompt of a persona
+func (r *personaRepository) Update(ctx context.Context, persona *models.Persona) error {
+	log := logger.Context(ctx)
+	persona.UpdatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Model(persona).Updates(map[string]interface{}{
+		"name":          persona.Name,
+		"model":         persona.Model,
+		"system_prompt": persona.SystemPrompt,
+		"updated_at":    persona.UpdatedAt,
+	})
+	if result.Error != nil {
+		log.Errorw("Failed to update persona", "error", result.Error, "id", persona.ID)
+		return dbError(result.Error, "Failed to update persona")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Persona not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a persona of a chat
+func (r *personaRepository) Delete(ctx context.Context, chatID, id int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Where("chat_id = ? AND id = ?", chatID, id).Delete(&models.Persona{})
+	if result.Error != nil {
+		log.Errorw("Failed to delete persona", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to delete persona")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Persona not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// RetentionRepository defines the interface for retention policy data access
+type RetentionRepository interface {
+	// Get retrieves the retention policy of a user
+	Get(ctx context.Context, userID string) (*models.RetentionPolicy, error)
+
+	// Upsert creates or replaces the retention policy of a user
+	Upsert(ctx context.Context, policy *models.RetentionPolicy) error
+
+	// List retrieves all retention policies
+	List(ctx context.Context) ([]*models.RetentionPolicy, error)
+}
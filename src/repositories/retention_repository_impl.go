@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// retentionRepository implements the RetentionRepository interface
+type retentionRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewRetentionRepository creates a new retention policy repository
+func NewRetentionRepository(db adapters.DBAdapter) RetentionRepository {
+	return &retentionRepository{db: db}
+}
+
+// Get retrieves the retention policy of a user
+func (r *retentionRepository) Get(ctx context.Context, userID string) (*models.RetentionPolicy, error) {
+	log := logger.Context(ctx)
+	var policy models.RetentionPolicy
+
+	result := r.db.GetDB().WithContext(ctx).Where("user_id = ?", userID).First(&policy)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Retention policy not found")
+		}
+		log.Errorw("Failed to get retention policy", "error", result.Error, "userID", userID)
+		return nil, dbError(result.Error, "Failed to get retention policy")
+	}
+
+	return &policy, nil
+}
+
+// Upsert creates or replaces the retention policy of a user
+func (r *retentionRepository) Upsert(ctx context.Context, policy *models.RetentionPolicy) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"days", "updated_at"}),
+	}).Create(policy)
+	if result.Error != nil {
+		log.Errorw("Failed to save retention policy", "error", result.Error, "userID", policy.UserID)
+		return dbError(result.Error, "Failed to save retention policy")
+	}
+
+	return nil
+}
+
+// List retrieves all retention policies
+func (r *retentionRepository) List(ctx context.Context) ([]*models.RetentionPolicy, error) {
+	log := logger.Context(ctx)
+	var policies []*models.RetentionPolicy
+
+	if err := r.db.GetDB().WithContext(ctx).Order("user_id").Find(&policies).Error; err != nil {
+		log.Errorw("Failed to list retention policies", "error", err)
+		return nil, dbError(err, "Failed to list retention policies")
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// SavedSearchRepository defines the interface for saved search data access
+type SavedSearchRepository interface {
+	// Create creates a saved search
+	Create(ctx context.Context, search *models.SavedSearch) error
+
+	// Get retrieves a saved search by ID
+	Get(ctx context.Context, id int64) (*models.SavedSearch, error)
+
+	// ListByUserID retrieves the saved searches of a user in creation order
+	ListByUserID(ctx context.Context, userID string) ([]*models.SavedSearch, error)
+
+	// Update replaces the name and filters of a saved search
+	Update(ctx context.Context, search *models.SavedSearch) error
+
+	// Delete deletes a saved search
+	Delete(ctx context.Context, id int64) error
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// savedSearchRepository implements the SavedSearchRepository interface
+type savedSearchRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewSavedSearchRepository creates a new saved search repository
+func NewSavedSearchRepository(db adapters.DBAdapter) SavedSearchRepository {
+	return &savedSearchRepository{db: db}
+}
+
+// Create creates a saved search
+func (r *savedSearchRepository) Create(ctx context.Context, search *models.SavedSearch) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	search.CreatedAt = now
+	search.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(search).Error; err != nil {
+		log.Errorw("Failed to create saved search", "error", err, "userID", search.UserID)
+		return dbError(err, "Failed to create saved search")
+	}
+
+	return nil
+}
+
+// Get retrieves a saved search by ID
+func (r *savedSearchRepository) Get(ctx context.Context, id int64) (*models.SavedSearch, error) {
+	log := logger.Context(ctx)
+	var search models.SavedSearch
+
+	result := r.db.GetDB().WithContext(ctx).First(&search, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Saved search not found")
+		}
+		log.Errorw("Failed to get saved search", "error", result.Error, "savedSearchID", id)
+		return nil, dbError(result.Error, "Failed to get saved search")
+	}
+
+	return &search, nil
+}
+
+// ListByUserID retrieves the saved searches of a user in creation order
+func (r *savedSearchRepository) ListByUserID(ctx context.Context, userID string) ([]*models.SavedSearch, error) {
+	log := logger.Context(ctx)
+	var searches []*models.SavedSearch
+
+	if err := r.db.GetDB().WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&searches).Error; err != nil {
+		log.Errorw("Failed to list saved searches", "error", err, "userID", userID)
+		return nil, dbError(err, "Failed to list saved searches")
+	}
+
+	return searches, nil
+}
+
+// Update replaces the name and filters of a saved search
+func (r *savedSearchRepository) Update(ctx context.Context, search *models.SavedSearch) error {
+	log := logger.Context(ctx)
+	search.UpdatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Model(search).Updates(map[string]interface{}{
+		"name":            search.Name,
+		"query":           search.Query,
+		"period":          search.Period,
+		"from_time":       search.From,
+		"to_time":         search.To,
+		"tag":             search.Tag,
+		"archived":        search.Archived,
+		"has_attachments": search.HasAttachments,
+		"model":           search.Model,
+		"updated_at":      search.UpdatedAt,
+	})
+	if result.Error != nil {
+		log.Errorw("Failed to update saved search", "error", result.Error, "savedSearchID", search.ID)
+		return dbError(result.Error, "Failed to update saved search")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Saved search not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a saved search
+func (r *savedSearchRepository) Delete(ctx context.Context, id int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Delete(&models.SavedSearch{}, id)
+	if result.Error != nil {
+		log.Errorw("Failed to delete saved search", "error", result.Error, "savedSearchID", id)
+		return dbError(result.Error, "Failed to delete saved search")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Saved search not found")
+	}
+
+	return nil
+}
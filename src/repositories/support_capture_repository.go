@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// SupportCaptureRepository defines the interface for support capture data access
+type SupportCaptureRepository interface {
+	// Create creates a new support capture
+	Create(ctx context.Context, capture *models.SupportCapture) error
+
+	// ListByRequestID retrieves the captures of a request that have not expired by now, in
+	// the order they were made
+	ListByRequestID(ctx context.Context, requestID string, now time.Time) ([]*models.SupportCapture, error)
+
+	// DeleteExpired deletes the captures expired by now, returning how many were deleted
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
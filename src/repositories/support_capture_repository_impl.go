@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// supportCaptureRepository implements the SupportCaptureRepository interface
+type supportCaptureRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewSupportCaptureRepository creates a new support capture repository
+func NewSupportCaptureRepository(db adapters.DBAdapter) SupportCaptureRepository {
+	return &supportCaptureRepository{db: db}
+}
+
+// Create creates a new support capture
+func (r *supportCaptureRepository) Create(ctx context.Context, capture *models.SupportCapture) error {
+	log := logger.Context(ctx)
+	capture.CreatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Create(capture)
+	if result.Error != nil {
+		log.Errorw("Failed to create support capture", "error", result.Error, "requestID", capture.RequestID)
+		return dbError(result.Error, "Failed to create support capture")
+	}
+
+	return nil
+}
+
+// ListByRequestID retrieves the captures of a request that have not expired by now, oldest first
+func (r *supportCaptureRepository) ListByRequestID(ctx context.Context, requestID string, now time.Time) ([]*models.SupportCapture, error) {
+	log := logger.Context(ctx)
+	var captures []*models.SupportCapture
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("request_id = ? AND expires_at > ?", requestID, now).
+		Order("started_at, id").
+		Find(&captures).Error; err != nil {
+		log.Errorw("Failed to list support captures", "error", err, "requestID", requestID)
+		return nil, dbError(err, "Failed to list support captures")
+	}
+
+	return captures, nil
+}
+
+// DeleteExpired deletes the captures expired by now
+func (r *supportCaptureRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.SupportCapture{})
+	if result.Error != nil {
+		log.Errorw("Failed to delete expired support captures", "error", result.Error)
+		return 0, dbError(result.Error, "Failed to delete expired support captures")
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// TaskRepository defines the interface for the data access of the tasks of chats
+type TaskRepository interface {
+	// CreateBatch creates tasks
+	CreateBatch(ctx context.Context, tasks []*models.Task) error
+
+	// Get retrieves a task of a chat
+	Get(ctx context.Context, chatID, id int64) (*models.Task, error)
+
+	// ListByChatID retrieves the tasks of a chat with a status, or all of them when status is
+	// empty, oldest first
+	ListByChatID(ctx context.Context, chatID int64, status string) ([]*models.Task, error)
+
+	// ListByIDs retrieves the tasks of a chat with the given IDs, oldest first
+	ListByIDs(ctx context.Context, chatID int64, ids []int64) ([]*models.Task, error)
+
+	// ListUnexported retrieves the open tasks of a chat not exported yet, oldest first
+	ListUnexported(ctx context.Context, chatID int64) ([]*models.Task, error)
+
+	// LastSourceMessageID returns the latest message tasks were extracted up to in a chat, or 0
+	LastSourceMessageID(ctx context.Context, chatID int64) (int64, error)
+
+	// UpdateStatus changes the status of a task of a chat
+	UpdateStatus(ctx context.Context, task *models.Task) error
+
+	// MarkExported records that tasks were exported to a tracker at a time
+	MarkExported(ctx context.Context, ids []int64, tracker string, at time.Time) error
+}
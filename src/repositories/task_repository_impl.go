@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// taskRepository implements the TaskRepository interface
+type taskRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewTaskRepository creates a new task repository
+func NewTaskRepository(db adapters.DBAdapter) TaskRepository {
+	return &taskRepository{db: db}
+}
+
+// CreateBatch creates tasks
+func (r *taskRepository) CreateBatch(ctx context.Context, tasks []*models.Task) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	for _, task := range tasks {
+		task.CreatedAt = now
+		task.UpdatedAt = now
+	}
+
+	if err := r.db.GetDB().WithContext(ctx).Create(tasks).Error; err != nil {
+		log.Errorw("Failed to create tasks", "error", err, "count", len(tasks))
+		return dbError(err, "Failed to create tasks")
+	}
+
+	return nil
+}
+
+// Get retrieves a task of a chat
+func (r *taskRepository) Get(ctx context.Context, chatID, id int64) (*models.Task, error) {
+	log := logger.Context(ctx)
+	var task models.Task
+
+	result := r.db.GetDB().WithContext(ctx).Where("chat_id = ?", chatID).First(&task, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Task not found")
+		}
+		log.Errorw("Failed to get task", "error", result.Error, "taskID", id)
+		return nil, dbError(result.Error, "Failed to get task")
+	}
+
+	return &task, nil
+}
+
+// ListByChatID retrieves the tasks of a chat with a status, or all of them when status is
+// empty, oldest first
+func (r *taskRepository) ListByChatID(ctx context.Context, chatID int64, status string) ([]*models.Task, error) {
+	log := logger.Context(ctx)
+	var tasks []*models.Task
+
+	query := r.db.GetDB().WithContext(ctx).Where("chat_id = ?", chatID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("id ASC").Find(&tasks).Error; err != nil {
+		log.Errorw("Failed to list tasks", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to list tasks")
+	}
+
+	return tasks, nil
+}
+
+// ListByIDs retrieves the tasks of a chat with the given IDs, oldest first
+func (r *taskRepository) ListByIDs(ctx context.Context, chatID int64, ids []int64) ([]*models.Task, error) {
+	log := logger.Context(ctx)
+	var tasks []*models.Task
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("chat_id = ? AND id IN ?", chatID, ids).
+		Order("id ASC").
+		Find(&tasks).Error; err != nil {
+		log.Errorw("Failed to list tasks", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to list tasks")
+	}
+
+	return tasks, nil
+}
+
+// ListUnexported retrieves the open tasks of a chat not exported yet, oldest first
+func (r *taskRepository) ListUnexported(ctx context.Context, chatID int64) ([]*models.Task, error) {
+	log := logger.Context(ctx)
+	var tasks []*models.Task
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("chat_id = ? AND status = ? AND exported_at IS NULL", chatID, models.TaskStatusOpen).
+		Order("id ASC").
+		Find(&tasks).Error; err != nil {
+		log.Errorw("Failed to list unexported tasks", "error", err, "chatID", chatID)
+		return nil, dbError(err, "Failed to list tasks")
+	}
+
+	return tasks, nil
+}
+
+// LastSourceMessageID returns the latest message tasks were extracted up to in a chat, or 0
+func (r *taskRepository) LastSourceMessageID(ctx context.Context, chatID int64) (int64, error) {
+	log := logger.Context(ctx)
+	var id sql.NullInt64
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Task{}).
+		Where("chat_id = ?", chatID).
+		Select("MAX(source_message_id)").
+		Row().Scan(&id); err != nil {
+		log.Errorw("Failed to get the last extraction of tasks", "error", err, "chatID", chatID)
+		return 0, dbError(err, "Failed to list tasks")
+	}
+
+	return id.Int64, nil
+}
+
+// UpdateStatus changes the status of a task of a chat
+func (r *taskRepository) UpdateStatus(ctx context.Context, task *models.Task) error {
+	log := logger.Context(ctx)
+	task.UpdatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Model(&models.Task{}).
+		Where("chat_id = ? AND id = ?", task.ChatID, task.ID).
+		Updates(map[string]interface{}{
+			"status":     task.Status,
+			"updated_at": task.UpdatedAt,
+		})
+	if result.Error != nil {
+		log.Errorw("Failed to update task", "error", result.Error, "id", task.ID)
+		return dbError(result.Error, "Failed to update task")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Task not found")
+	}
+
+	return nil
+}
+
+// MarkExported records that tasks were exported to a tracker at a time
+func (r *taskRepository) MarkExported(ctx context.Context, ids []int64, tracker string, at time.Time) error {
+	log := logger.Context(ctx)
+
+	if err := r.db.GetDB().WithContext(ctx).Model(&models.Task{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"tracker":     tracker,
+			"exported_at": at,
+			"updated_at":  at,
+		}).Error; err != nil {
+		log.Errorw("Failed to mark tasks exported", "error", err, "count", len(ids))
+		return dbError(err, "Failed to update tasks")
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// TeamsRepository defines the interface for data access of the Microsoft Teams integration
+type TeamsRepository interface {
+	// GetConversation retrieves the mapping of a Teams conversation of a user to a chat
+	GetConversation(ctx context.Context, conversationID, userID string) (*models.TeamsConversation, error)
+
+	// CreateConversation maps a Teams conversation of a user to a chat. It reports whether the
+	// mapping was created, which it is not when the conversation of the user is already mapped.
+	CreateConversation(ctx context.Context, conversation *models.TeamsConversation) (bool, error)
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// teamsRepository implements the TeamsRepository interface
+type teamsRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewTeamsRepository creates a new Teams integration repository
+func NewTeamsRepository(db adapters.DBAdapter) TeamsRepository {
+	return &teamsRepository{db: db}
+}
+
+// GetConversation retrieves the mapping of a Teams conversation of a user to a chat
+func (r *teamsRepository) GetConversation(ctx context.Context, conversationID, userID string) (*models.TeamsConversation, error) {
+	log := logger.Context(ctx)
+	var conversation models.TeamsConversation
+
+	result := r.db.GetDB().WithContext(ctx).Where("conversation_id = ? AND user_id = ?", conversationID, userID).First(&conversation)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Teams conversation not found")
+		}
+		log.Errorw("Failed to get Teams conversation", "error", result.Error, "userID", userID)
+		return nil, dbError(result.Error, "Failed to get Teams conversation")
+	}
+
+	return &conversation, nil
+}
+
+// CreateConversation maps a Teams conversation of a user to a chat, unless it is already mapped
+func (r *teamsRepository) CreateConversation(ctx context.Context, conversation *models.TeamsConversation) (bool, error) {
+	log := logger.Context(ctx)
+	conversation.CreatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "conversation_id"}, {Name: "user_id"}},
+		DoNothing: true,
+	}).Create(conversation)
+	if result.Error != nil {
+		log.Errorw("Failed to create Teams conversation", "error", result.Error, "userID", conversation.UserID, "chatID", conversation.ChatID)
+		return false, dbError(result.Error, "Failed to create Teams conversation")
+	}
+
+	return result.RowsAffected > 0, nil
+}
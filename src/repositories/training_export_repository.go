@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// TrainingExportRepository defines the interface for fine-tuning dataset export data access
+type TrainingExportRepository interface {
+	// Create creates a new dataset export
+	Create(ctx context.Context, export *models.TrainingExport) error
+
+	// GetByID retrieves a dataset export by ID
+	GetByID(ctx context.Context, id int64) (*models.TrainingExport, error)
+
+	// List retrieves the latest dataset exports, newest first
+	List(ctx context.Context, limit int) ([]*models.TrainingExport, error)
+
+	// ListPending retrieves the oldest dataset exports waiting to be built
+	ListPending(ctx context.Context, limit int) ([]*models.TrainingExport, error)
+
+	// Update saves the status and result of a dataset export
+	Update(ctx context.Context, export *models.TrainingExport) error
+
+	// ListChats retrieves the chats matching the filters of a dataset export with an ID
+	// above afterID, in ID order. Chats pending deletion are left out.
+	ListChats(ctx context.Context, export *models.TrainingExport, afterID int64, limit int) ([]*models.Chat, error)
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// trainingExportRepository implements the TrainingExportRepository interface
+type trainingExportRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewTrainingExportRepository creates a new fine-tuning dataset export repository
+func NewTrainingExportRepository(db adapters.DBAdapter) TrainingExportRepository {
+	return &trainingExportRepository{db: db}
+}
+
+// Create creates a new dataset export
+func (r *trainingExportRepository) Create(ctx context.Context, export *models.TrainingExport) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	export.CreatedAt = now
+	export.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(export).Error; err != nil {
+		log.Errorw("Failed to create training export", "error", err, "requestedBy", export.RequestedBy)
+		return dbError(err, "Failed to create training export")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a dataset export by ID
+func (r *trainingExportRepository) GetByID(ctx context.Context, id int64) (*models.TrainingExport, error) {
+	log := logger.Context(ctx)
+	var export models.TrainingExport
+
+	result := r.db.GetDB().WithContext(ctx).First(&export, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Training export not found")
+		}
+		log.Errorw("Failed to get training export", "error", result.Error, "exportID", id)
+		return nil, dbError(result.Error, "Failed to get training export")
+	}
+
+	return &export, nil
+}
+
+// List retrieves the latest dataset exports
+func (r *trainingExportRepository) List(ctx context.Context, limit int) ([]*models.TrainingExport, error) {
+	log := logger.Context(ctx)
+	var exports []*models.TrainingExport
+
+	if err := r.db.GetDB().WithContext(ctx).Order("id DESC").Limit(limit).Find(&exports).Error; err != nil {
+		log.Errorw("Failed to list training exports", "error", err)
+		return nil, dbError(err, "Failed to list training exports")
+	}
+
+	return exports, nil
+}
+
+// ListPending retrieves the oldest dataset exports waiting to be built
+func (r *trainingExportRepository) ListPending(ctx context.Context, limit int) ([]*models.TrainingExport, error) {
+	log := logger.Context(ctx)
+	var exports []*models.TrainingExport
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Where("status = ?", models.ExportStatusPending).
+		Order("id ASC").
+		Limit(limit).
+		Find(&exports).Error; err != nil {
+		log.Errorw("Failed to list pending training exports", "error", err)
+		return nil, dbError(err, "Failed to list pending training exports")
+	}
+
+	return exports, nil
+}
+
+// Update saves the status and result of a dataset export
+func (r *trainingExportRepository) Update(ctx context.Context, export *models.TrainingExport) error {
+	log := logger.Context(ctx)
+	export.UpdatedAt = time.Now()
+
+	if err := r.db.GetDB().WithContext(ctx).Save(export).Error; err != nil {
+		log.Errorw("Failed to update training export", "error", err, "exportID", export.ID)
+		return dbError(err, "Failed to update training export")
+	}
+
+	return nil
+}
+
+// ListChats retrieves the chats matching the filters of a dataset export: created within its
+// period, with all its tags, and whose replies add up to at least its feedback score. Privacy
+// mode chats are left out.
+func (r *trainingExportRepository) ListChats(ctx context.Context, export *models.TrainingExport, afterID int64, limit int) ([]*models.Chat, error) {
+	log := logger.Context(ctx)
+	var chats []*models.Chat
+
+	query := r.db.GetDB().WithContext(ctx).
+		Where("id > ? AND delete_at IS NULL AND NOT ephemeral", afterID).
+		Where(`(SELECT COALESCE(SUM(messages.feedback), 0) FROM messages
+			WHERE messages.chat_id = chats.id AND messages.role = ?) >= ?`, models.MessageRoleAssistant, export.MinScore)
+	if export.From != nil {
+		query = query.Where("created_at >= ?", *export.From)
+	}
+	if export.To != nil {
+		query = query.Where("created_at < ?", *export.To)
+	}
+	if export.Tags != "" {
+		query = query.Where("string_to_array(tags, ',') @> ARRAY[?]::text[]", strings.Split(export.Tags, ","))
+	}
+	if err := query.Order("id").Limit(limit).Find(&chats).Error; err != nil {
+		log.Errorw("Failed to list chats for training export", "error", err, "exportID", export.ID)
+		return nil, dbError(err, "Failed to list chats")
+	}
+
+	return chats, nil
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// UsageReconciliationRepository defines the interface for usage reconciliation data access
+type UsageReconciliationRepository interface {
+	// Upsert creates the reconciliations, replacing those of the same day and model
+	Upsert(ctx context.Context, reconciliations []*models.UsageReconciliation) error
+
+	// List retrieves the reconciliations of the days from a day, inclusive, to another,
+	// exclusive, optionally with a status, by day and model
+	List(ctx context.Context, from, to time.Time, status string) ([]*models.UsageReconciliation, error)
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm/clause"
+)
+
+// usageReconciliationRepository implements the UsageReconciliationRepository interface
+type usageReconciliationRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewUsageReconciliationRepository creates a new usage reconciliation repository
+func NewUsageReconciliationRepository(db adapters.DBAdapter) UsageReconciliationRepository {
+	return &usageReconciliationRepository{db: db}
+}
+
+// Upsert creates the reconciliations, replacing those of the same day and model
+func (r *usageReconciliationRepository) Upsert(ctx context.Context, reconciliations []*models.UsageReconciliation) error {
+	if len(reconciliations) == 0 {
+		return nil
+	}
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "day"}, {Name: "model"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"source", "vendor_requests", "vendor_prompt_tokens", "vendor_completion_tokens", "vendor_cost",
+			"local_requests", "local_prompt_tokens", "local_completion_tokens", "local_cost",
+			"status", "imported_at",
+		}),
+	}).Create(&reconciliations)
+	if result.Error != nil {
+		log.Errorw("Failed to save usage reconciliations", "error", result.Error, "count", len(reconciliations))
+		return dbError(result.Error, "Failed to save usage reconciliations")
+	}
+
+	return nil
+}
+
+// List retrieves the reconciliations of a period, optionally with a status
+func (r *usageReconciliationRepository) List(ctx context.Context, from, to time.Time, status string) ([]*models.UsageReconciliation, error) {
+	log := logger.Context(ctx)
+	var reconciliations []*models.UsageReconciliation
+
+	query := r.db.GetDB().WithContext(ctx).Where("day >= ? AND day < ?", from, to)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("day, model").Find(&reconciliations).Error; err != nil {
+		log.Errorw("Failed to list usage reconciliations", "error", err, "from", from, "to", to)
+		return nil, dbError(err, "Failed to list usage reconciliations")
+	}
+
+	return reconciliations, nil
+}
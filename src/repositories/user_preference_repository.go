@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// UserPreferenceRepository defines the interface for user preference data access
+type UserPreferenceRepository interface {
+	// Get retrieves the preferences of a user
+	Get(ctx context.Context, userID string) (*models.UserPreference, error)
+
+	// Upsert creates or replaces the preferences of a user
+	Upsert(ctx context.Context, preference *models.UserPreference) error
+}
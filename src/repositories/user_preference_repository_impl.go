@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// userPreferenceRepository implements the UserPreferenceRepository interface
+type userPreferenceRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewUserPreferenceRepository creates a new user preference repository
+func NewUserPreferenceRepository(db adapters.DBAdapter) UserPreferenceRepository {
+	return &userPreferenceRepository{db: db}
+}
+
+// Get retrieves the preferences of a user
+func (r *userPreferenceRepository) Get(ctx context.Context, userID string) (*models.UserPreference, error) {
+	log := logger.Context(ctx)
+	var preference models.UserPreference
+
+	result := r.db.GetDB().WithContext(ctx).Where("user_id = ?", userID).First(&preference)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "User preferences not found")
+		}
+		log.Errorw("Failed to get user preferences", "error", result.Error, "userID", userID)
+		return nil, dbError(result.Error, "Failed to get user preferences")
+	}
+
+	return &preference, nil
+}
+
+// Upsert creates or replaces the preferences of a user
+func (r *userPreferenceRepository) Upsert(ctx context.Context, preference *models.UserPreference) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	preference.CreatedAt = now
+	preference.UpdatedAt = now
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"instructions", "updated_at"}),
+	}).Create(preference)
+	if result.Error != nil {
+		log.Errorw("Failed to save user preferences", "error", result.Error, "userID", preference.UserID)
+		return dbError(result.Error, "Failed to save user preferences")
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// WarehouseRepository defines the interface for the data access of the warehouse export
+type WarehouseRepository interface {
+	// GetCursor retrieves the export cursor of a table. It returns nil when the table was
+	// never exported.
+	GetCursor(ctx context.Context, table string) (*models.WarehouseCursor, error)
+
+	// SaveCursor creates or replaces the export cursor of a table
+	SaveCursor(ctx context.Context, cursor *models.WarehouseCursor) error
+
+	// ListChats retrieves the chats changed after a cursor and before until, in order of
+	// change then ID
+	ListChats(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]*models.Chat, error)
+
+	// ListMessages retrieves the messages changed after a cursor and before until, in order
+	// of change then ID, with the content they share
+	ListMessages(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]*models.Message, error)
+
+	// ListUsage retrieves the usage recorded after a cursor and before until, in order of
+	// creation then ID
+	ListUsage(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]*models.Usage, error)
+
+	// ListWorkspaceStats retrieves the statistics of workspaces, with their numbers of
+	// distinct users, of the hours from that of a cursor and before until, in order of hour
+	// then workspace. The cursor's RowID is the number of statistics of its hour to skip.
+	ListWorkspaceStats(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]*models.WorkspaceStat, error)
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// warehouseRepository implements the WarehouseRepository interface
+type warehouseRepository struct {
+	db      adapters.DBAdapter
+	content *MessageContentStore
+}
+
+// NewWarehouseRepository creates a new warehouse repository, loading the content messages
+// share from content
+func NewWarehouseRepository(db adapters.DBAdapter, content *MessageContentStore) WarehouseRepository {
+	return &warehouseRepository{db: db, content: content}
+}
+
+// GetCursor retrieves the export cursor of a table, or nil
+func (r *warehouseRepository) GetCursor(ctx context.Context, table string) (*models.WarehouseCursor, error) {
+	log := logger.Context(ctx)
+	var cursor models.WarehouseCursor
+
+	result := r.db.GetDB().WithContext(ctx).Where("table_name = ?", table).First(&cursor)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		log.Errorw("Failed to get warehouse cursor", "error", result.Error, "table", table)
+		return nil, dbError(result.Error, "Failed to get warehouse cursor")
+	}
+
+	return &cursor, nil
+}
+
+// SaveCursor creates or replaces the export cursor of a table
+func (r *warehouseRepository) SaveCursor(ctx context.Context, cursor *models.WarehouseCursor) error {
+	log := logger.Context(ctx)
+	cursor.UpdatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "table_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"schema_version", "changed_at", "row_id", "exported_rows", "updated_at"}),
+	}).Create(cursor)
+	if result.Error != nil {
+		log.Errorw("Failed to save warehouse cursor", "error", result.Error, "table", cursor.Table)
+		return dbError(result.Error, "Failed to save warehouse cursor")
+	}
+
+	return nil
+}
+
+// ListChats retrieves the chats changed after a cursor
+func (r *warehouseRepository) ListChats(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]*models.Chat, error) {
+	log := logger.Context(ctx)
+	var chats []*models.Chat
+
+	if err := r.changedAfter(ctx, "updated_at", after, until, limit).Find(&chats).Error; err != nil {
+		log.Errorw("Failed to list chats for the warehouse", "error", err)
+		return nil, dbError(err, "Failed to list chats")
+	}
+
+	return chats, nil
+}
+
+// ListMessages retrieves the messages changed after a cursor, with the content they share.
+// Content in the storage is left as its preview.
+func (r *warehouseRepository) ListMessages(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]*models.Message, error) {
+	log := logger.Context(ctx)
+	var messages []*models.Message
+
+	if err := r.changedAfter(ctx, "updated_at", after, until, limit).Find(&messages).Error; err != nil {
+		log.Errorw("Failed to list messages for the warehouse", "error", err)
+		return nil, dbError(err, "Failed to list messages")
+	}
+
+	r.content.loadShared(ctx, messages...)
+	return messages, nil
+}
+
+// ListUsage retrieves the usage recorded after a cursor
+func (r *warehouseRepository) ListUsage(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]*models.Usage, error) {
+	log := logger.Context(ctx)
+	var usage []*models.Usage
+
+	if err := r.changedAfter(ctx, "created_at", after, until, limit).Find(&usage).Error; err != nil {
+		log.Errorw("Failed to list usage for the warehouse", "error", err)
+		return nil, dbError(err, "Failed to list usage")
+	}
+
+	return usage, nil
+}
+
+// ListWorkspaceStats retrieves the statistics of workspaces of the hours from that of a
+// cursor, skipping the first RowID statistics of that hour
+func (r *warehouseRepository) ListWorkspaceStats(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]*models.WorkspaceStat, error) {
+	log := logger.Context(ctx)
+	var stats []*models.WorkspaceStat
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Select(workspaceStatColumns).
+		Where("hour >= ? AND hour < ?", after.ChangedAt, until).
+		Order("hour, workspace_id").
+		Offset(int(after.RowID)).
+		Limit(limit).
+		Find(&stats).Error; err != nil {
+		log.Errorw("Failed to list workspace statistics for the warehouse", "error", err)
+		return nil, dbError(err, "Failed to list workspace statistics")
+	}
+
+	return stats, nil
+}
+
+// changedAfter returns the query of the rows whose time column and ID follow a cursor, up
+// to until, in that order
+func (r *warehouseRepository) changedAfter(ctx context.Context, column string, after *models.WarehouseCursor, until time.Time, limit int) *gorm.DB {
+	return r.db.GetDB().WithContext(ctx).
+		Where("("+column+", id) > (?, ?) AND "+column+" < ?", after.ChangedAt, after.RowID, until).
+		Order(column + ", id").
+		Limit(limit)
+}
@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// WidgetRepository defines the interface for embeddable chat widget data access
+type WidgetRepository interface {
+	// Create creates a widget
+	Create(ctx context.Context, widget *models.Widget) error
+
+	// Get retrieves a widget by ID
+	Get(ctx context.Context, id int64) (*models.Widget, error)
+
+	// List retrieves all widgets in creation order
+	List(ctx context.Context) ([]*models.Widget, error)
+
+	// Update updates the settings of a widget
+	Update(ctx context.Context, widget *models.Widget) error
+
+	// Delete deletes a widget
+	Delete(ctx context.Context, id int64) error
+}
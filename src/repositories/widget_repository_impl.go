@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+)
+
+// widgetRepository implements the WidgetRepository interface
+type widgetRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewWidgetRepository creates a new widget repository
+func NewWidgetRepository(db adapters.DBAdapter) WidgetRepository {
+	return &widgetRepository{db: db}
+}
+
+// Create creates a widget
+func (r *widgetRepository) Create(ctx context.Context, widget *models.Widget) error {
+	log := logger.Context(ctx)
+	now := time.Now()
+	widget.CreatedAt = now
+	widget.UpdatedAt = now
+
+	if err := r.db.GetDB().WithContext(ctx).Create(widget).Error; err != nil {
+		log.Errorw("Failed to create widget", "error", err, "name", widget.Name)
+		return dbError(err, "Failed to create widget")
+	}
+
+	return nil
+}
+
+// Get retrieves a widget by ID
+func (r *widgetRepository) Get(ctx context.Context, id int64) (*models.Widget, error) {
+	log := logger.Context(ctx)
+	var widget models.Widget
+
+	result := r.db.GetDB().WithContext(ctx).First(&widget, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Widget not found")
+		}
+		log.Errorw("Failed to get widget", "error", result.Error, "id", id)
+		return nil, dbError(result.Error, "Failed to get widget")
+	}
+
+	return &widget, nil
+}
+
+// List retrieves all widgets in creation order
+func (r *widgetRepository) List(ctx context.Context) ([]*models.Widget, error) {
+	log := logger.Context(ctx)
+	var widgets []*models.Widget
+
+	if err := r.db.GetDB().WithContext(ctx).Order("id").Find(&widgets).Error; err != nil {
+		log.Errorw("Failed to list widgets", "error", err)
+		return nil, dbError(err, "Failed to list widgets")
+	}
+
+	return widgets, nil
+}
+
+// Update updates the settings of a widget
+func (r *widgetRepository) Update(ctx context.Context, widget *models.Widget) error {
+	log := logger.Context(ctx)
+	widget.UpdatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Model(widget).Updates(map[string]interface{}{
+		"name":            widget.Name,
+		"allowed_origins": widget.AllowedOrigins,
+		"enabled":         widget.Enabled,
+		"title":           widget.Title,
+		"greeting":        widget.Greeting,
+		"primary_color":   widget.PrimaryColor,
+		"avatar_url":      widget.AvatarURL,
+		"assistant_name":  widget.AssistantName,
+		"model":           widget.Model,
+		"system_prompt":   widget.SystemPrompt,
+		"updated_at":      widget.UpdatedAt,
+	})
+	if result.Error != nil {
+		log.Errorw("Failed to update widget", "error", result.Error, "id", widget.ID)
+		return dbError(result.Error, "Failed to update widget")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Widget not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a widget
+func (r *widgetRepository) Delete(ctx context.Context, id int64) error {
+	log := logger.Context(ctx)
+
+	result := r.db.GetDB().WithContext(ctx).Delete(&models.Widget{}, id)
+	if result.Error != nil {
+		log.Errorw("Failed to delete widget", "error", result.Error, "id", id)
+		return dbError(result.Error, "Failed to delete widget")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "Widget not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// WorkspaceRepository defines the interface for workspace settings data access
+type WorkspaceRepository interface {
+	// GetSettings retrieves the workspace settings
+	GetSettings(ctx context.Context) (*models.WorkspaceSettings, error)
+
+	// SaveSettings creates or replaces the workspace settings
+	SaveSettings(ctx context.Context, settings *models.WorkspaceSettings) error
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// workspaceRepository implements the WorkspaceRepository interface
+type workspaceRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewWorkspaceRepository creates a new workspace settings repository
+func NewWorkspaceRepository(db adapters.DBAdapter) WorkspaceRepository {
+	return &workspaceRepository{db: db}
+}
+
+// GetSettings retrieves the workspace settings
+func (r *workspaceRepository) GetSettings(ctx context.Context) (*models.WorkspaceSettings, error) {
+	log := logger.Context(ctx)
+	var settings models.WorkspaceSettings
+
+	result := r.db.GetDB().WithContext(ctx).First(&settings, models.WorkspaceSettingsID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrNotFound, "Workspace settings not found")
+		}
+		log.Errorw("Failed to get workspace settings", "error", result.Error)
+		return nil, dbError(result.Error, "Failed to get workspace settings")
+	}
+
+	return &settings, nil
+}
+
+// SaveSettings creates or replaces the workspace settings
+func (r *workspaceRepository) SaveSettings(ctx context.Context, settings *models.WorkspaceSettings) error {
+	log := logger.Context(ctx)
+	settings.ID = models.WorkspaceSettingsID
+	settings.UpdatedAt = time.Now()
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"default_model", "system_prompt", "moderation_level", "retention_days", "guardrails", "warehouse_opt_out", "updated_by", "updated_at"}),
+	}).Create(settings)
+	if result.Error != nil {
+		log.Errorw("Failed to save workspace settings", "error", result.Error)
+		return dbError(result.Error, "Failed to save workspace settings")
+	}
+
+	return nil
+}
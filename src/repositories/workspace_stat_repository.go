@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// WorkspaceStatRepository defines the interface for the hourly statistics of workspaces
+type WorkspaceStatRepository interface {
+	// Add adds counts to the statistics of their workspaces and hours, creating them if needed
+	Add(ctx context.Context, stats []*models.WorkspaceStat) error
+
+	// AddUsers adds the counts of users to those of their workspaces and hours, recording the
+	// users not recorded yet
+	AddUsers(ctx context.Context, users []*models.WorkspaceStatUser) error
+
+	// ListUsers lists the users of statistics with their counts
+	ListUsers(ctx context.Context, stats []*models.WorkspaceStat) ([]*models.WorkspaceStatUser, error)
+
+	// ListByWorkspace lists the statistics of a workspace from an hour on, oldest first, with
+	// their numbers of distinct users
+	ListByWorkspace(ctx context.Context, workspaceID string, since time.Time) ([]*models.WorkspaceStat, error)
+
+	// DeleteBefore deletes the statistics and users of the hours before a time, returning how
+	// many statistics
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// workspaceStatColumns selects the statistics of workspaces with their numbers of distinct
+// users
+const workspaceStatColumns = "workspace_stats.*, (SELECT COUNT(*) FROM workspace_stat_users u " +
+	"WHERE u.workspace_id = workspace_stats.workspace_id AND u.hour = workspace_stats.hour) AS users"
+
+// workspaceStatRepository implements the WorkspaceStatRepository interface
+type workspaceStatRepository struct {
+	db adapters.DBAdapter
+}
+
+// NewWorkspaceStatRepository creates a new workspace statistics repository
+func NewWorkspaceStatRepository(db adapters.DBAdapter) WorkspaceStatRepository {
+	return &workspaceStatRepository{db: db}
+}
+
+// Add adds counts to the statistics of their workspaces and hours in place, so the counts of
+// every instance add up
+func (r *workspaceStatRepository) Add(ctx context.Context, stats []*models.WorkspaceStat) error {
+	log := logger.Context(ctx)
+	if len(stats) == 0 {
+		return nil
+	}
+
+	result := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "workspace_id"}, {Name: "hour"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"requests":      gorm.Expr("workspace_stats.requests + excluded.requests"),
+			"client_errors": gorm.Expr("workspace_stats.client_errors + excluded.client_errors"),
+			"server_errors": gorm.Expr("workspace_stats.server_errors + excluded.server_errors"),
+			"tokens":        gorm.Expr("workspace_stats.tokens + excluded.tokens"),
+			"cost":          gorm.Expr("workspace_stats.cost + excluded.cost"),
+		}),
+	}).Create(&stats)
+	if result.Error != nil {
+		log.Errorw("Failed to add workspace statistics", "error", result.Error, "count", len(stats))
+		return dbError(result.Error, "Failed to add workspace statistics")
+	}
+
+	return nil
+}
+
+// AddUsers adds the counts of users to those of their workspaces and hours in place, so the
+// counts of every instance add up
+func (r *workspaceStatRepository) AddUsers(ctx context.Context, users []*models.WorkspaceStatUser) error {
+	log := logger.Context(ctx)
+	if len(users) == 0 {
+		return nil
+	}
+
+	if err := r.db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "workspace_id"}, {Name: "hour"}, {Name: "user_hash"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"requests":      gorm.Expr("workspace_stat_users.requests + excluded.requests"),
+			"client_errors": gorm.Expr("workspace_stat_users.client_errors + excluded.client_errors"),
+			"server_errors": gorm.Expr("workspace_stat_users.server_errors + excluded.server_errors"),
+			"tokens":        gorm.Expr("workspace_stat_users.tokens + excluded.tokens"),
+			"cost":          gorm.Expr("workspace_stat_users.cost + excluded.cost"),
+		}),
+	}).Create(&users).Error; err != nil {
+		log.Errorw("Failed to add workspace statistics users", "error", err, "count", len(users))
+		return dbError(err, "Failed to add workspace statistics users")
+	}
+
+	return nil
+}
+
+// ListUsers lists the users of statistics with their counts
+func (r *workspaceStatRepository) ListUsers(ctx context.Context, stats []*models.WorkspaceStat) ([]*models.WorkspaceStatUser, error) {
+	log := logger.Context(ctx)
+	if len(stats) == 0 {
+		return nil, nil
+	}
+
+	keys := make([][]interface{}, len(stats))
+	for i, stat := range stats {
+		keys[i] = []interface{}{stat.WorkspaceID, stat.Hour}
+	}
+	var users []*models.WorkspaceStatUser
+	if err := r.db.GetDB().WithContext(ctx).Where("(workspace_id, hour) IN ?", keys).Find(&users).Error; err != nil {
+		log.Errorw("Failed to list workspace statistics users", "error", err, "count", len(stats))
+		return nil, dbError(err, "Failed to list workspace statistics users")
+	}
+
+	return users, nil
+}
+
+// ListByWorkspace lists the statistics of a workspace from an hour on, oldest first, with
+// their numbers of distinct users
+func (r *workspaceStatRepository) ListByWorkspace(ctx context.Context, workspaceID string, since time.Time) ([]*models.WorkspaceStat, error) {
+	log := logger.Context(ctx)
+	var stats []*models.WorkspaceStat
+
+	if err := r.db.GetDB().WithContext(ctx).
+		Select(workspaceStatColumns).
+		Where("workspace_id = ? AND hour >= ?", workspaceID, since).
+		Order("hour ASC").
+		Find(&stats).Error; err != nil {
+		log.Errorw("Failed to list workspace statistics", "error", err, "workspaceID", workspaceID)
+		return nil, dbError(err, "Failed to list workspace statistics")
+	}
+
+	return stats, nil
+}
+
+// DeleteBefore deletes the statistics and users of the hours before a time, returning how
+// many statistics
+func (r *workspaceStatRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	log := logger.Context(ctx)
+
+	if err := r.db.GetDB().WithContext(ctx).Where("hour < ?", before).Delete(&models.WorkspaceStatUser{}).Error; err != nil {
+		log.Errorw("Failed to delete workspace statistics users", "error", err, "before", before)
+		return 0, dbError(err, "Failed to delete workspace statistics users")
+	}
+
+	result := r.db.GetDB().WithContext(ctx).Where("hour < ?", before).Delete(&models.WorkspaceStat{})
+	if result.Error != nil {
+		log.Errorw("Failed to delete workspace statistics", "error", result.Error, "before", before)
+		return 0, dbError(result.Error, "Failed to delete workspace statistics")
+	}
+
+	return result.RowsAffected, nil
+}
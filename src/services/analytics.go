@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/analytics"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// NewAnalyticsPolicy returns the policy releasing the statistics of workspaces, or nil when
+// analytics are disabled and statistics are released as they are. The secret keying the
+// noise and the hashes of users is required, as instances drawing their own would release
+// noise that averages away across them, and count users apart.
+func NewAnalyticsPolicy(config configs.Analytics) (*analytics.Policy, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	if config.NoiseSecret == "" {
+		return nil, fmt.Errorf("analytics noise secret is required")
+	}
+
+	return &analytics.Policy{
+		MinUsers:        config.MinUsers,
+		Epsilon:         config.Epsilon,
+		RequestsPerUser: config.RequestsPerUser,
+		TokensPerUser:   config.TokensPerUser,
+		CostPerUser:     config.CostPerUser,
+		Secret:          []byte(config.NoiseSecret),
+	}, nil
+}
+
+// clipWorkspaceStats returns statistics counted from the contributions of their users, each
+// clipped by a policy so that the noise of their release covers any user. Requests without a
+// user are left out, as their contribution cannot be bounded. Statistics are returned as they
+// are without a policy.
+func clipWorkspaceStats(ctx context.Context, policy *analytics.Policy, statRepo repositories.WorkspaceStatRepository, stats []*models.WorkspaceStat) ([]*models.WorkspaceStat, error) {
+	if policy == nil || len(stats) == 0 {
+		return stats, nil
+	}
+
+	users, err := statRepo.ListUsers(ctx, stats)
+	if err != nil {
+		return nil, err
+	}
+	contributions := map[workspaceStatKey][]analytics.Contribution{}
+	for _, user := range users {
+		key := workspaceStatKey{workspaceID: user.WorkspaceID, hour: user.Hour.UTC()}
+		contributions[key] = append(contributions[key], analytics.Contribution{
+			Requests:     user.Requests,
+			ClientErrors: user.ClientErrors,
+			ServerErrors: user.ServerErrors,
+			Tokens:       user.Tokens,
+			Cost:         user.Cost,
+		})
+	}
+
+	clipped := make([]*models.WorkspaceStat, len(stats))
+	for i, stat := range stats {
+		bucket := policy.Aggregate(contributions[workspaceStatKey{workspaceID: stat.WorkspaceID, hour: stat.Hour.UTC()}])
+		clipped[i] = &models.WorkspaceStat{
+			WorkspaceID:  stat.WorkspaceID,
+			Hour:         stat.Hour,
+			Users:        bucket.Users,
+			Requests:     bucket.Requests,
+			ClientErrors: bucket.ClientErrors,
+			ServerErrors: bucket.ServerErrors,
+			Tokens:       bucket.Tokens,
+			Cost:         bucket.Cost,
+		}
+	}
+	return clipped, nil
+}
+
+// releaseWorkspaceStat returns the statistics of a workspace in an hour as released by a
+// policy, or false when they are suppressed. Statistics are returned as they are without a
+// policy.
+func releaseWorkspaceStat(policy *analytics.Policy, stat *models.WorkspaceStat) (*models.WorkspaceStat, bool) {
+	if policy == nil {
+		return stat, true
+	}
+
+	bucket, ok := policy.Release(stat.WorkspaceID+"/"+stat.Hour.UTC().Format(time.RFC3339), analytics.Bucket{
+		Users:        stat.Users,
+		Requests:     stat.Requests,
+		ClientErrors: stat.ClientErrors,
+		ServerErrors: stat.ServerErrors,
+		Tokens:       stat.Tokens,
+		Cost:         stat.Cost,
+	})
+	if !ok {
+		return nil, false
+	}
+	return &models.WorkspaceStat{
+		WorkspaceID:  stat.WorkspaceID,
+		Hour:         stat.Hour,
+		Users:        bucket.Users,
+		Requests:     bucket.Requests,
+		ClientErrors: bucket.ClientErrors,
+		ServerErrors: bucket.ServerErrors,
+		Tokens:       bucket.Tokens,
+		Cost:         bucket.Cost,
+	}, true
+}
+
+// workspaceUserHash returns the hash telling a user apart from the other users of an hour,
+// which differs from hour to hour. It is keyed with secret, so that it cannot be matched
+// with the IDs of users without it.
+func workspaceUserHash(secret []byte, userID string, hour time.Time) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\x00%s", hour.UTC().Format(time.RFC3339), userID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
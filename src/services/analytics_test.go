@@ -0,0 +1,40 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAnalyticsPolicy(t *testing.T) {
+	policy, err := NewAnalyticsPolicy(configs.Analytics{})
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	// Instances drawing their own secrets would release noise averaging away across them
+	_, err = NewAnalyticsPolicy(configs.Analytics{Enabled: true, Epsilon: 1})
+	assert.Error(t, err)
+
+	policy, err = NewAnalyticsPolicy(configs.Analytics{Enabled: true, Epsilon: 1, NoiseSecret: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), policy.Secret)
+}
+
+func TestWorkspaceUserHash(t *testing.T) {
+	hour := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	hash := workspaceUserHash([]byte("secret"), "bob", hour)
+
+	assert.Equal(t, hash, workspaceUserHash([]byte("secret"), "bob", hour.In(time.FixedZone("", 3600))))
+	assert.NotEqual(t, hash, workspaceUserHash([]byte("secret"), "alice", hour))
+	assert.NotEqual(t, hash, workspaceUserHash([]byte("secret"), "bob", hour.Add(time.Hour)))
+
+	// Without the secret, the hash cannot be computed from the ID of the user
+	assert.NotEqual(t, hash, workspaceUserHash([]byte("other secret"), "bob", hour))
+	unkeyed := sha256.Sum256([]byte(hour.Format(time.RFC3339) + "\x00bob"))
+	assert.NotEqual(t, hex.EncodeToString(unkeyed[:]), hash)
+}
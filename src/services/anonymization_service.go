@@ -0,0 +1,16 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// AnonymizationService defines the interface for anonymized copies of chats, for staging
+// and analytics environments
+type AnonymizationService interface {
+	// Anonymize copies the chats of the region of ctx and their messages into the configured
+	// schema, replacing an earlier copy, with user IDs hashed and personal information
+	// scrubbed from their text
+	Anonymize(ctx context.Context) (*dtos.AnonymizationReport, error)
+}
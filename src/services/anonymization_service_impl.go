@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/redact"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// anonymizationSchemaPattern matches the schemas the copy can be written to
+var anonymizationSchemaPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// anonymizationService implements the AnonymizationService interface
+type anonymizationService struct {
+	config configs.Anonymization
+	repo   repositories.AnonymizationRepository
+}
+
+// NewAnonymizationService creates a new anonymization service
+func NewAnonymizationService(config configs.Anonymization, repo repositories.AnonymizationRepository) AnonymizationService {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 200
+	}
+	return &anonymizationService{config: config, repo: repo}
+}
+
+// Anonymize copies the chats of the region of ctx anonymized, a batch of chats at a time. A
+// copy interrupted by an error is left partial, and replaced by the next run.
+func (s *anonymizationService) Anonymize(ctx context.Context) (*dtos.AnonymizationReport, error) {
+	log := logger.Context(ctx)
+
+	schema := s.config.Schema
+	if !anonymizationSchemaPattern.MatchString(schema) || schema == "public" {
+		return nil, errors.New(errors.ErrInvalidRequest, "The anonymization schema must be a lowercase identifier other than public")
+	}
+	if s.config.Salt == "" {
+		return nil, errors.New(errors.ErrInvalidRequest, "The anonymization salt is not configured")
+	}
+
+	if err := s.repo.PrepareSchema(ctx, schema); err != nil {
+		return nil, err
+	}
+
+	report := &dtos.AnonymizationReport{Schema: schema}
+	afterID := int64(0)
+	for {
+		chats, err := s.repo.ListChats(ctx, afterID, s.config.BatchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(chats) == 0 {
+			break
+		}
+
+		chatIDs := make([]int64, len(chats))
+		for i, chat := range chats {
+			chatIDs[i] = chat.ID
+			s.anonymizeChat(chat)
+		}
+		messages, err := s.repo.ListMessages(ctx, chatIDs)
+		if err != nil {
+			return report, err
+		}
+		for _, message := range messages {
+			s.anonymizeMessage(message)
+		}
+
+		if err := s.repo.Copy(ctx, schema, chats, messages); err != nil {
+			return report, err
+		}
+		report.Chats += len(chats)
+		report.Messages += len(messages)
+		afterID = chats[len(chats)-1].ID
+		log.Debugw("Copied anonymized chats", "schema", schema, "chats", report.Chats, "messages", report.Messages)
+	}
+
+	return report, nil
+}
+
+// anonymizeChat hashes the owner of a chat and scrubs its text
+func (s *anonymizationService) anonymizeChat(chat *models.Chat) {
+	chat.UserID = s.hashUserID(chat.UserID)
+	chat.Title = redact.PII(chat.Title)
+	chat.Summary = redact.PII(chat.Summary)
+	chat.Tags = redact.PII(chat.Tags)
+}
+
+// anonymizeMessage hashes the author of a message and scrubs its text. The content is kept
+// in the copy itself, leaving the storage of large content out.
+func (s *anonymizationService) anonymizeMessage(message *models.Message) {
+	if message.UserID != nil {
+		userID := s.hashUserID(*message.UserID)
+		message.UserID = &userID
+	}
+	message.Content = redact.PII(message.Content)
+	message.ContentKey, message.ContentHash = "", ""
+	message.Translation = redact.PII(message.Translation)
+	message.Suggestions = redact.PII(message.Suggestions)
+}
+
+// hashUserID returns the hash of a user ID keyed by the salt, keeping the prefix of guest
+// sessions so that they are still told apart
+func (s *anonymizationService) hashUserID(userID string) string {
+	prefix := ""
+	if models.IsGuest(userID) {
+		prefix = models.GuestUserPrefix
+	}
+	mac := hmac.New(sha256.New, []byte(s.config.Salt))
+	mac.Write([]byte(userID))
+	return prefix + hex.EncodeToString(mac.Sum(nil))[:32]
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+	"io"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// AttachmentService defines the interface for the files attached to messages. Files are
+// transferred directly to and from the storage through signed URLs.
+type AttachmentService interface {
+	// ListAttachments lists the attachments of a message of the user, with the download URLs
+	// of the uploaded files
+	ListAttachments(ctx context.Context, userID string, messageID int64) (*dtos.ListAttachmentsResponse, error)
+
+	// CreateUpload creates a pending attachment on a message of the user and returns the URL
+	// its file is uploaded to
+	CreateUpload(ctx context.Context, userID string, messageID int64, req *dtos.CreateUploadRequest) (*dtos.UploadURLResponse, error)
+
+	// CompleteUpload marks a pending attachment as uploaded once its file is in the storage
+	CompleteUpload(ctx context.Context, userID string, messageID, attachmentID int64) (*dtos.AttachmentResponse, error)
+
+	// Upload stores the file of a signed upload URL and returns its size
+	Upload(ctx context.Context, req *dtos.UploadRequest, r io.Reader) (int64, error)
+
+	// ProcessPending scans the uploaded attachments for malware and returns the number processed
+	ProcessPending(ctx context.Context) (int, error)
+}
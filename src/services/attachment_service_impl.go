@@ -0,0 +1,407 @@
+package services
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// attachmentService implements the AttachmentService interface
+type attachmentService struct {
+	config         configs.Attachments
+	chatRepo       repositories.ChatRepository
+	messageRepo    repositories.MessageRepository
+	attachmentRepo repositories.AttachmentRepository
+	storage        adapters.StorageAdapter
+	scanner        adapters.ScannerAdapter
+	events         EventPublisher
+}
+
+// NewAttachmentService creates a new message attachment service. Uploaded files are
+// scanned for malware before they can be downloaded, unless scanner is nil.
+func NewAttachmentService(
+	config configs.Attachments,
+	chatRepo repositories.ChatRepository,
+	messageRepo repositories.MessageRepository,
+	attachmentRepo repositories.AttachmentRepository,
+	storage adapters.StorageAdapter,
+	scanner adapters.ScannerAdapter,
+	events EventPublisher,
+) AttachmentService {
+	if config.URLTTL <= 0 {
+		config.URLTTL = 15 * time.Minute
+	}
+	if config.Scanning.BatchSize <= 0 {
+		config.Scanning.BatchSize = 10
+	}
+
+	return &attachmentService{
+		config:         config,
+		chatRepo:       chatRepo,
+		messageRepo:    messageRepo,
+		attachmentRepo: attachmentRepo,
+		storage:        storage,
+		scanner:        scanner,
+		events:         events,
+	}
+}
+
+// ListAttachments lists the attachments of a message of the user. Pending uploads are
+// listed without a download URL.
+func (s *attachmentService) ListAttachments(ctx context.Context, userID string, messageID int64) (*dtos.ListAttachmentsResponse, error) {
+	if _, _, err := s.ownedMessage(ctx, userID, messageID); err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.attachmentRepo.ListByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dtos.ListAttachmentsResponse{Attachments: make([]dtos.AttachmentResponse, 0, len(attachments))}
+	for _, attachment := range attachments {
+		item, err := s.attachmentResponse(attachment)
+		if err != nil {
+			return nil, err
+		}
+		response.Attachments = append(response.Attachments, *item)
+	}
+
+	return response, nil
+}
+
+// CreateUpload creates a pending attachment on a message of the user and returns the URL
+// its file is uploaded to. Files are only attached to the user's own messages.
+func (s *attachmentService) CreateUpload(ctx context.Context, userID string, messageID int64, req *dtos.CreateUploadRequest) (*dtos.UploadURLResponse, error) {
+	log := logger.Context(ctx)
+
+	if models.IsGuest(userID) {
+		return nil, errors.New(errors.ErrForbidden, "Attachments are not available to guests")
+	}
+	if s.config.MaxSize > 0 && req.Size > s.config.MaxSize {
+		return nil, errors.New(errors.ErrPayloadTooLarge, "File is too large")
+	}
+
+	message, chat, err := s.ownedMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Role != models.MessageRoleUser {
+		return nil, errors.New(errors.ErrInvalidRequest, "Files can only be attached to user messages")
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return nil, err
+	}
+	if chat.Ephemeral {
+		return nil, errors.New(errors.ErrInvalidRequest, "Files cannot be attached to messages of privacy mode chats")
+	}
+
+	attachment := &models.Attachment{
+		MessageID:   messageID,
+		Filename:    filepath.Base(req.Filename),
+		ContentType: req.ContentType,
+		Size:        req.Size,
+		Status:      models.AttachmentStatusPending,
+		StorageKey:  "attachments/" + uuid.NewString() + fileExtension(req.Filename),
+	}
+
+	url, err := s.storage.SignedUploadURL(attachment.StorageKey, s.config.URLTTL)
+	if err != nil {
+		log.Errorw("Failed to sign upload URL", "error", err, "messageID", messageID)
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to sign upload URL")
+	}
+	expiresAt := time.Now().Add(s.config.URLTTL)
+
+	if err := s.attachmentRepo.Create(ctx, attachment); err != nil {
+		return nil, err
+	}
+
+	log.Infow("Attachment upload created", "messageID", messageID, "attachmentID", attachment.ID, "size", req.Size)
+	return &dtos.UploadURLResponse{
+		Attachment:      *toAttachmentResponse(attachment),
+		UploadURL:       url,
+		UploadExpiresAt: expiresAt,
+	}, nil
+}
+
+// CompleteUpload marks a pending attachment as uploaded once its file is in the storage,
+// recording the size of the uploaded file. With scanning enabled, the attachment waits for
+// its scan before it can be downloaded.
+func (s *attachmentService) CompleteUpload(ctx context.Context, userID string, messageID, attachmentID int64) (*dtos.AttachmentResponse, error) {
+	log := logger.Context(ctx)
+
+	if _, _, err := s.ownedMessage(ctx, userID, messageID); err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.attachmentRepo.Get(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if attachment.MessageID != messageID {
+		return nil, errors.New(errors.ErrNotFound, "Attachment not found")
+	}
+	if attachment.Status == models.AttachmentStatusUploading {
+		return nil, errors.New(errors.ErrConflict, "File is still being uploaded")
+	}
+	if attachment.Status != models.AttachmentStatusPending {
+		return nil, errors.New(errors.ErrConflict, "Attachment is not waiting for an upload")
+	}
+
+	size, err := s.storage.Size(ctx, attachment.StorageKey)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(errors.ErrInvalidRequest, "File has not been uploaded")
+		}
+		log.Errorw("Failed to check uploaded file", "error", err, "attachmentID", attachmentID)
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to check uploaded file")
+	}
+
+	status := models.AttachmentStatusReady
+	if s.scanner != nil {
+		status = models.AttachmentStatusScanning
+	}
+	if err := s.attachmentRepo.MarkUploaded(ctx, attachmentID, size, status); err != nil {
+		return nil, err
+	}
+	attachment.Status = status
+	attachment.Size = size
+
+	log.Infow("Attachment uploaded", "messageID", messageID, "attachmentID", attachmentID, "size", size)
+	return s.attachmentResponse(attachment)
+}
+
+// Upload stores the file of a signed upload URL and returns its size. Files over the
+// maximum size are discarded. Files are only accepted while their attachment waits for its
+// upload, and the attachment is marked uploading while its file is written, so that it
+// cannot be completed meanwhile: once completed, and possibly scanned, its file cannot be
+// replaced with the URL, even by an upload started before.
+func (s *attachmentService) Upload(ctx context.Context, req *dtos.UploadRequest, r io.Reader) (int64, error) {
+	log := logger.Context(ctx)
+
+	if !s.storage.VerifyUpload(req.Key, req.Expires, req.Signature) {
+		return 0, errors.New(errors.ErrForbidden, "Invalid or expired upload URL")
+	}
+	attachment, err := s.attachmentRepo.GetByStorageKey(ctx, req.Key)
+	if err != nil {
+		return 0, err
+	}
+	if attachment.Status != models.AttachmentStatusPending {
+		return 0, errors.New(errors.ErrConflict, "Attachment is not waiting for an upload")
+	}
+	if err := s.attachmentRepo.UpdateStatus(ctx, attachment.ID, models.AttachmentStatusPending, models.AttachmentStatusUploading); err != nil {
+		return 0, err
+	}
+	defer func() {
+		// The attachment becomes pending again even when the client disconnected
+		ctx, cancel := detachedContext(ctx)
+		defer cancel()
+		if err := s.attachmentRepo.UpdateStatus(ctx, attachment.ID, models.AttachmentStatusUploading, models.AttachmentStatusPending); err != nil {
+			log.Errorw("Failed to mark attachment as pending after its upload", "error", err, "attachmentID", attachment.ID)
+		}
+	}()
+
+	// Read one byte over the limit to tell a file of the maximum size from a larger one
+	if s.config.MaxSize > 0 {
+		r = io.LimitReader(r, s.config.MaxSize+1)
+	}
+	size, err := s.storage.Put(ctx, req.Key, r)
+	if err != nil {
+		log.Errorw("Failed to store upload", "error", err, "key", req.Key)
+		return 0, errors.Wrap(err, errors.ErrInternal, "Failed to store upload")
+	}
+
+	if s.config.MaxSize > 0 && size > s.config.MaxSize {
+		if err := s.storage.Delete(ctx, req.Key); err != nil {
+			log.Warnw("Failed to delete oversized upload", "error", err, "key", req.Key)
+		}
+		return 0, errors.New(errors.ErrPayloadTooLarge, "File is too large")
+	}
+
+	return size, nil
+}
+
+// ProcessPending scans the uploaded attachments for malware. Infected files are moved to
+// the quarantine and can no longer be downloaded. A file that cannot be scanned is marked
+// as such and does not stop the others.
+func (s *attachmentService) ProcessPending(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	if s.scanner == nil {
+		return 0, nil
+	}
+
+	attachments, err := s.attachmentRepo.ListByStatus(ctx, models.AttachmentStatusScanning, s.config.Scanning.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, attachment := range attachments {
+		status, threat, key := models.AttachmentStatusReady, "", attachment.StorageKey
+
+		result, err := s.scan(ctx, attachment)
+		switch {
+		case err != nil:
+			log.Errorw("Failed to scan attachment", "error", err, "attachmentID", attachment.ID)
+			status = models.AttachmentStatusScanFailed
+		case result.Infected:
+			key, err = s.quarantine(ctx, attachment)
+			if err != nil {
+				// The attachment stays unavailable and is scanned again on the next run
+				log.Errorw("Failed to quarantine attachment", "error", err, "attachmentID", attachment.ID)
+				continue
+			}
+			status, threat = models.AttachmentStatusQuarantined, result.Threat
+		}
+
+		if err := s.attachmentRepo.SetScanResult(ctx, attachment.ID, status, threat, key); err != nil {
+			if key != attachment.StorageKey {
+				s.deleteFile(ctx, key)
+			}
+			return processed, err
+		}
+		if key != attachment.StorageKey {
+			s.deleteFile(ctx, attachment.StorageKey)
+		}
+		processed++
+
+		attachment.Status, attachment.Threat, attachment.StorageKey = status, threat, key
+		if status == models.AttachmentStatusQuarantined {
+			log.Warnw("Attachment quarantined", "attachmentID", attachment.ID, "messageID", attachment.MessageID, "threat", threat)
+		}
+		s.publishScanned(ctx, attachment)
+	}
+
+	return processed, nil
+}
+
+// scan scans the file of an attachment
+func (s *attachmentService) scan(ctx context.Context, attachment *models.Attachment) (*adapters.ScanResult, error) {
+	file, err := s.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return s.scanner.Scan(ctx, file, attachment.Filename)
+}
+
+// quarantine copies the file of an infected attachment to the quarantine and returns its
+// new storage key. The original file is deleted once the attachment points to the copy.
+func (s *attachmentService) quarantine(ctx context.Context, attachment *models.Attachment) (string, error) {
+	file, err := s.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	key := "quarantine/" + attachment.StorageKey
+	if _, err := s.storage.Put(ctx, key, file); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// deleteFile deletes a stored file; failures are only logged as the file is unreachable
+func (s *attachmentService) deleteFile(ctx context.Context, key string) {
+	if err := s.storage.Delete(ctx, key); err != nil {
+		logger.Context(ctx).Warnw("Failed to delete attachment file", "error", err, "key", key)
+	}
+}
+
+// publishScanned publishes the scan result of an attachment; failures are only logged
+func (s *attachmentService) publishScanned(ctx context.Context, attachment *models.Attachment) {
+	log := logger.Context(ctx)
+
+	message, err := s.messageRepo.Get(ctx, attachment.MessageID)
+	if err != nil {
+		log.Errorw("Failed to publish attachment scanned event", "error", err, "attachmentID", attachment.ID)
+		return
+	}
+	chat, err := s.chatRepo.Get(ctx, message.ChatID)
+	if err != nil {
+		log.Errorw("Failed to publish attachment scanned event", "error", err, "attachmentID", attachment.ID)
+		return
+	}
+
+	event := models.EventAttachmentScanned
+	if attachment.Status == models.AttachmentStatusQuarantined {
+		event = models.EventAttachmentQuarantined
+	}
+	payload := dtos.AttachmentPayload{
+		AttachmentID: attachment.ID,
+		MessageID:    attachment.MessageID,
+		ChatID:       chat.ID,
+		UserID:       chat.UserID,
+		Filename:     attachment.Filename,
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		Status:       attachment.Status,
+		Threat:       attachment.Threat,
+	}
+	if err := s.events.PublishAttachmentEvent(ctx, newEvent(ctx, event, payload)); err != nil {
+		log.Errorw("Failed to publish attachment scanned event", "error", err, "attachmentID", attachment.ID)
+	}
+}
+
+// ownedMessage retrieves a message and its chat, checking the user owns the chat
+func (s *attachmentService) ownedMessage(ctx context.Context, userID string, messageID int64) (*models.Message, *models.Chat, error) {
+	message, err := s.messageRepo.Get(ctx, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chat, err := s.chatRepo.Get(ctx, message.ChatID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if chat.UserID != userID {
+		return nil, nil, errors.New(errors.ErrForbidden, "User does not have access to this message")
+	}
+
+	return message, chat, nil
+}
+
+// attachmentResponse converts an attachment to its response, with a download URL once
+// its file is uploaded
+func (s *attachmentService) attachmentResponse(attachment *models.Attachment) (*dtos.AttachmentResponse, error) {
+	response := toAttachmentResponse(attachment)
+
+	if attachment.Status == models.AttachmentStatusReady {
+		url, err := s.storage.SignedURL(attachment.StorageKey, s.config.URLTTL)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrInternal, "Failed to sign download URL")
+		}
+		expiresAt := time.Now().Add(s.config.URLTTL)
+		response.DownloadURL = url
+		response.DownloadExpiresAt = &expiresAt
+	}
+
+	return response, nil
+}
+
+// toAttachmentResponse converts an attachment to its response, without URLs
+func toAttachmentResponse(attachment *models.Attachment) *dtos.AttachmentResponse {
+	return &dtos.AttachmentResponse{
+		ID:          attachment.ID,
+		MessageID:   attachment.MessageID,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Status:      attachment.Status,
+		Threat:      attachment.Threat,
+		CreatedAt:   attachment.CreatedAt,
+	}
+}
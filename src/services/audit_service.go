@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// AuditService defines the interface for admin impersonation and its audit log
+type AuditService interface {
+	// StartImpersonation issues a short-lived token acting as a user on behalf of an admin
+	StartImpersonation(ctx context.Context, adminID string, req *dtos.ImpersonationRequest) (*dtos.ImpersonationResponse, error)
+
+	// Record writes an entry to the audit log
+	Record(ctx context.Context, entry *models.AuditLog) error
+
+	// List lists audit log entries, optionally filtered by admin, user and action
+	List(ctx context.Context, req *dtos.ListAuditLogsRequest) (*dtos.ListAuditLogsResponse, error)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// impersonationScopes are the scopes of impersonation tokens not restricting them, those of
+// regular users. Impersonation never grants admin scopes.
+var impersonationScopes = []string{middlewares.ScopeChatsRead, middlewares.ScopeChatsWrite, middlewares.ScopeMessagesSend}
+
+// auditService implements the AuditService interface
+type auditService struct {
+	config       configs.Impersonation
+	secret       string
+	auditLogRepo repositories.AuditLogRepository
+}
+
+// NewAuditService creates a new audit service signing impersonation tokens with secret
+func NewAuditService(config configs.Impersonation, secret string, auditLogRepo repositories.AuditLogRepository) AuditService {
+	return &auditService{
+		config:       config,
+		secret:       secret,
+		auditLogRepo: auditLogRepo,
+	}
+}
+
+// StartImpersonation issues a short-lived token acting as a user on behalf of an admin. The
+// token names the admin in its act claim, so every request made with it is audited.
+func (s *auditService) StartImpersonation(ctx context.Context, adminID string, req *dtos.ImpersonationRequest) (*dtos.ImpersonationResponse, error) {
+	log := logger.Context(ctx)
+
+	if !s.config.Enabled {
+		return nil, errors.New(errors.ErrForbidden, "Impersonation is disabled")
+	}
+	if req.UserID == adminID {
+		return nil, errors.New(errors.ErrInvalidRequest, "Admins cannot impersonate themselves")
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = impersonationScopes
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.config.TTL)
+	claims := jwt.MapClaims{
+		"sub":   req.UserID,
+		"act":   map[string]string{"sub": adminID},
+		"scope": strings.Join(scopes, " "),
+		"iat":   now.Unix(),
+		"exp":   expiresAt.Unix(),
+	}
+	if models.IsGuest(req.UserID) {
+		claims["role"] = models.RoleGuest
+	}
+
+	// The token is only handed out once its issuance is on record
+	if err := s.Record(ctx, &models.AuditLog{
+		AdminID:   adminID,
+		UserID:    req.UserID,
+		Action:    models.AuditActionImpersonationStarted,
+		Reason:    req.Reason,
+		RequestID: logger.GetRequestID(ctx),
+	}); err != nil {
+		return nil, err
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.secret))
+	if err != nil {
+		log.Errorw("Failed to sign impersonation token", "error", err)
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to start impersonation")
+	}
+
+	log.Warnw("Impersonation started", "adminID", adminID, "userID", req.UserID, "expiresAt", expiresAt)
+	return &dtos.ImpersonationResponse{
+		Token:     signed,
+		UserID:    req.UserID,
+		AdminID:   adminID,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Record writes an entry to the audit log
+func (s *auditService) Record(ctx context.Context, entry *models.AuditLog) error {
+	return s.auditLogRepo.Create(ctx, entry)
+}
+
+// List lists audit log entries, optionally filtered by admin, user and action
+func (s *auditService) List(ctx context.Context, req *dtos.ListAuditLogsRequest) (*dtos.ListAuditLogsResponse, error) {
+	log := logger.Context(ctx)
+	log.Debugw("Listing audit log", "adminID", req.AdminID, "userID", req.UserID, "action", req.Action, "limit", req.Limit, "offset", req.Offset)
+
+	entries, total, err := s.auditLogRepo.List(ctx, req.AdminID, req.UserID, req.Action, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to response DTOs
+	responses := make([]dtos.AuditLogResponse, len(entries))
+	for i, entry := range entries {
+		responses[i] = toAuditLogResponse(entry)
+	}
+
+	return &dtos.ListAuditLogsResponse{
+		Entries: responses,
+		Total:   total,
+	}, nil
+}
+
+// toAuditLogResponse converts an audit log model to its response DTO
+func toAuditLogResponse(entry *models.AuditLog) dtos.AuditLogResponse {
+	return dtos.AuditLogResponse{
+		ID:        entry.ID,
+		AdminID:   entry.AdminID,
+		UserID:    entry.UserID,
+		Action:    entry.Action,
+		Method:    entry.Method,
+		Path:      entry.Path,
+		Status:    entry.Status,
+		Reason:    entry.Reason,
+		RequestID: entry.RequestID,
+		ChatID:    entry.ChatID,
+		MessageID: entry.MessageID,
+		CreatedAt: entry.CreatedAt,
+	}
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// BatchService defines the interface for batch prompts
+type BatchService interface {
+	// CreateBatch queues a list of prompts of a user for asynchronous processing
+	CreateBatch(ctx context.Context, userID string, req *dtos.BatchRequest) (*dtos.BatchResponse, error)
+
+	// GetBatch retrieves the progress of a batch of a user
+	GetBatch(ctx context.Context, userID string, id int64) (*dtos.BatchResponse, error)
+
+	// ListItems lists the results of the prompts of a batch of a user
+	ListItems(ctx context.Context, userID string, id int64, req *dtos.ListBatchItemsRequest) (*dtos.ListBatchItemsResponse, error)
+
+	// ProcessPending answers the pending batch prompts and returns the number processed
+	ProcessPending(ctx context.Context) (int, error)
+}
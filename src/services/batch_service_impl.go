@@ -0,0 +1,406 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// templatePlaceholder matches a {{name}} placeholder of a batch template
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// batchService implements the BatchService interface
+type batchService struct {
+	config         configs.Batch
+	batchRepo      repositories.BatchRepository
+	chatRepo       repositories.ChatRepository
+	messageRepo    repositories.MessageRepository
+	messageService MessageService
+	llmAdapter     adapters.LLMAdapter
+	budgets        BudgetService
+	consent        ConsentService
+	notifications  NotificationService
+}
+
+// NewBatchService creates a new batch prompt service
+func NewBatchService(
+	config configs.Batch,
+	batchRepo repositories.BatchRepository,
+	chatRepo repositories.ChatRepository,
+	messageRepo repositories.MessageRepository,
+	messageService MessageService,
+	llmAdapter adapters.LLMAdapter,
+	budgets BudgetService,
+	consent ConsentService,
+	notifications NotificationService,
+) BatchService {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 20
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+
+	return &batchService{
+		config:         config,
+		batchRepo:      batchRepo,
+		chatRepo:       chatRepo,
+		messageRepo:    messageRepo,
+		messageService: messageService,
+		llmAdapter:     llmAdapter,
+		budgets:        budgets,
+		consent:        consent,
+		notifications:  notifications,
+	}
+}
+
+// CreateBatch queues a list of prompts of a user for asynchronous processing
+func (s *batchService) CreateBatch(ctx context.Context, userID string, req *dtos.BatchRequest) (*dtos.BatchResponse, error) {
+	if models.IsGuest(userID) {
+		return nil, errors.New(errors.ErrForbidden, "Guest sessions cannot send batches, sign in to continue")
+	}
+	if s.config.MaxItems > 0 && len(req.Items) > s.config.MaxItems {
+		return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("A batch has at most %d prompts", s.config.MaxItems))
+	}
+	if err := s.consent.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	batch := &models.Batch{
+		UserID:   userID,
+		Status:   models.BatchStatusPending,
+		Template: req.Template,
+		Total:    len(req.Items),
+		Items:    make([]models.BatchItem, len(req.Items)),
+	}
+	checked := make(map[int64]bool)
+	for i, itemReq := range req.Items {
+		prompt, err := batchPrompt(req.Template, &itemReq, i)
+		if err != nil {
+			return nil, err
+		}
+
+		// Verify the user owns the chats prompts are sent to
+		if itemReq.ChatID != nil && !checked[*itemReq.ChatID] {
+			chat, err := s.chatRepo.Get(ctx, *itemReq.ChatID)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkChatOwner(chat.UserID, userID); err != nil {
+				return nil, err
+			}
+			if err := checkUnlocked(chat); err != nil {
+				return nil, err
+			}
+			// Batch items keep their prompt and reply in the database
+			if chat.Ephemeral {
+				return nil, errors.New(errors.ErrInvalidRequest, "Batch prompts cannot be sent to privacy mode chats")
+			}
+			checked[*itemReq.ChatID] = true
+		}
+
+		batch.Items[i] = models.BatchItem{
+			Position: i,
+			ChatID:   itemReq.ChatID,
+			Prompt:   prompt,
+			Status:   models.BatchItemStatusPending,
+		}
+	}
+
+	if err := s.batchRepo.Create(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Batch queued", "batchID", batch.ID, "userID", userID, "items", batch.Total)
+	return toBatchResponse(batch), nil
+}
+
+// batchPrompt returns the prompt of the item at index i of a batch: the template filled
+// with the item variables, or the item prompt without template
+func batchPrompt(template string, item *dtos.BatchItemRequest, i int) (string, error) {
+	if template == "" {
+		if item.Prompt == "" {
+			return "", errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Item %d has no prompt", i))
+		}
+		return item.Prompt, nil
+	}
+	if item.Prompt != "" {
+		return "", errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Item %d has a prompt, but the batch has a template to fill with its variables", i))
+	}
+
+	var missing string
+	prompt := templatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := templatePlaceholder.FindStringSubmatch(placeholder)[1]
+		value, ok := item.Variables[name]
+		if !ok && missing == "" {
+			missing = name
+		}
+		return value
+	})
+	if missing != "" {
+		return "", errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Item %d has no value for the template variable %s", i, missing))
+	}
+	return prompt, nil
+}
+
+// GetBatch retrieves the progress of a batch of a user
+func (s *batchService) GetBatch(ctx context.Context, userID string, id int64) (*dtos.BatchResponse, error) {
+	batch, err := s.getOwnedBatch(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toBatchResponse(batch), nil
+}
+
+// ListItems lists the results of the prompts of a batch of a user
+func (s *batchService) ListItems(ctx context.Context, userID string, id int64, req *dtos.ListBatchItemsRequest) (*dtos.ListBatchItemsResponse, error) {
+	if _, err := s.getOwnedBatch(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	items, total, err := s.batchRepo.ListItems(ctx, id, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+	page := countedPage(total, req.Offset, len(items))
+
+	responses := make([]dtos.BatchItemResponse, len(items))
+	for i, item := range items {
+		responses[i] = toBatchItemResponse(item)
+	}
+
+	return &dtos.ListBatchItemsResponse{
+		Items:   responses,
+		Total:   page.total,
+		HasMore: page.hasMore,
+	}, nil
+}
+
+// getOwnedBatch retrieves a batch, verifying the user owns it
+func (s *batchService) getOwnedBatch(ctx context.Context, userID string, id int64) (*models.Batch, error) {
+	batch, err := s.batchRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if batch.UserID != userID {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this batch")
+	}
+
+	return batch, nil
+}
+
+// ProcessPending answers the pending batch prompts and returns the number processed.
+// Up to the configured concurrency, items are processed in parallel, except that the
+// items of the same chat are processed in order. A failed item is marked as such and
+// does not stop the others.
+func (s *batchService) ProcessPending(ctx context.Context) (int, error) {
+	items, err := s.batchRepo.ListPendingItems(ctx, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// Items of the same chat share a lane so their messages are sent in order
+	owners := make(map[int64]string)
+	var lanes [][]*models.BatchItem
+	chatLanes := make(map[int64]int)
+	for _, item := range items {
+		if _, ok := owners[item.BatchID]; !ok {
+			batch, err := s.batchRepo.GetByID(ctx, item.BatchID)
+			if err != nil {
+				return 0, err
+			}
+			owners[item.BatchID] = batch.UserID
+		}
+
+		if item.ChatID == nil {
+			lanes = append(lanes, []*models.BatchItem{item})
+			continue
+		}
+		lane, ok := chatLanes[*item.ChatID]
+		if !ok {
+			lane = len(lanes)
+			chatLanes[*item.ChatID] = lane
+			lanes = append(lanes, nil)
+		}
+		lanes[lane] = append(lanes[lane], item)
+	}
+
+	semaphore := make(chan struct{}, s.config.Concurrency)
+	var wg sync.WaitGroup
+	for _, lane := range lanes {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			for _, item := range lane {
+				s.processItem(ctx, owners[item.BatchID], item)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return len(items), nil
+}
+
+// processItem answers a batch prompt, saves its result and updates the progress of its batch
+func (s *batchService) processItem(ctx context.Context, userID string, item *models.BatchItem) {
+	log := logger.Context(ctx)
+
+	var err error
+	if item.ChatID != nil {
+		err = s.sendToChat(ctx, userID, item)
+	} else {
+		err = s.generate(ctx, userID, item)
+	}
+
+	// Items interrupted by shutdown stay pending to be processed again
+	if ctx.Err() != nil {
+		return
+	}
+
+	item.Status = models.BatchItemStatusCompleted
+	if err != nil {
+		log.Warnw("Batch item failed", "error", err, "batchID", item.BatchID, "itemID", item.ID)
+		item.Status = models.BatchItemStatusFailed
+		item.Error = "Failed to generate response"
+		if appErr, ok := err.(*errors.AppError); ok {
+			item.Error = appErr.Message
+		}
+	}
+
+	now := time.Now()
+	item.CompletedAt = &now
+	if err := s.batchRepo.UpdateItem(ctx, item); err != nil {
+		return
+	}
+	completed, err := s.batchRepo.UpdateProgress(ctx, item.BatchID)
+	if err != nil {
+		log.Errorw("Failed to update batch progress", "error", err, "batchID", item.BatchID)
+		return
+	}
+	if completed {
+		s.notifyDone(ctx, userID, item.BatchID)
+	}
+}
+
+// notifyDone notifies the user that the replies to a batch are done; failures are only
+// logged as the results are also available from the batch
+func (s *batchService) notifyDone(ctx context.Context, userID string, batchID int64) {
+	batch, err := s.batchRepo.GetByID(ctx, batchID)
+	if err == nil {
+		err = s.notifications.Notify(ctx, userID, NotificationReplyDone, &dtos.ReplyDoneNotification{
+			BatchID:   batch.ID,
+			Completed: batch.Completed,
+			Failed:    batch.Failed,
+		})
+	}
+	if err != nil {
+		logger.Context(ctx).Warnw("Failed to notify batch done", "error", err, "batchID", batchID)
+	}
+}
+
+// sendToChat sends a batch prompt as a message of its chat, as if the user had sent it
+func (s *batchService) sendToChat(ctx context.Context, userID string, item *models.BatchItem) error {
+	// The chat may have been transferred since the batch was created
+	chat, err := s.chatRepo.Get(ctx, *item.ChatID)
+	if err != nil {
+		return err
+	}
+	if err := checkChatOwner(chat.UserID, userID); err != nil {
+		return err
+	}
+
+	exchange, err := s.messageService.SendMessage(ctx, *item.ChatID, userID, &dtos.MessageRequest{Content: item.Prompt})
+	if err != nil {
+		return err
+	}
+
+	reply, err := s.messageRepo.Get(ctx, exchange.AssistantMessage.ID)
+	if err != nil {
+		return err
+	}
+
+	item.Response = reply.Content
+	item.MessageID = &reply.ID
+	item.Model = reply.Model
+	item.TotalTokens = reply.TotalTokens
+	return nil
+}
+
+// generate answers a batch prompt on its own, without chat history
+func (s *batchService) generate(ctx context.Context, userID string, item *models.BatchItem) error {
+	if err := s.budgets.Enforce(ctx, userID); err != nil {
+		return err
+	}
+
+	llmCtx, cancel := llmContext(ctx, 0)
+	defer cancel()
+
+	response, err := s.llmAdapter.GenerateResponse(llmCtx, &dtos.LLMRequest{
+		Messages: []dtos.LLMMessage{{Role: models.MessageRoleUser, Content: item.Prompt}},
+	})
+	if err != nil {
+		if llmCtx.Err() == context.DeadlineExceeded {
+			return errors.Wrap(err, errors.ErrTimeout, "LLM service did not respond in time")
+		}
+		return errors.Wrap(err, errors.ErrLLMService, "Failed to get response from LLM service")
+	}
+
+	model := response.Model
+	if model == "" {
+		model = configs.AppConfig.LLM.Model
+	}
+	item.Response = response.Message.Content
+	item.Model = model
+	item.TotalTokens = response.Usage.TotalTokens
+
+	// Prompts outside chats are accounted to the user without chat and message
+	if err := s.budgets.RecordUsage(ctx, userID, 0, 0, model, response.Usage); err != nil {
+		logger.Context(ctx).Errorw("Failed to record usage", "error", err, "itemID", item.ID)
+	}
+	return nil
+}
+
+// toBatchResponse converts a batch to its API representation
+func toBatchResponse(batch *models.Batch) *dtos.BatchResponse {
+	return &dtos.BatchResponse{
+		ID:          batch.ID,
+		Status:      batch.Status,
+		Total:       batch.Total,
+		Completed:   batch.Completed,
+		Failed:      batch.Failed,
+		CreatedAt:   batch.CreatedAt,
+		CompletedAt: batch.CompletedAt,
+	}
+}
+
+// toBatchItemResponse converts a batch item to its API representation
+func toBatchItemResponse(item *models.BatchItem) dtos.BatchItemResponse {
+	return dtos.BatchItemResponse{
+		ID:          item.ID,
+		Position:    item.Position,
+		ChatID:      item.ChatID,
+		Prompt:      item.Prompt,
+		Status:      item.Status,
+		Response:    item.Response,
+		MessageID:   item.MessageID,
+		Model:       item.Model,
+		TotalTokens: item.TotalTokens,
+		Error:       item.Error,
+		CompletedAt: item.CompletedAt,
+	}
+}
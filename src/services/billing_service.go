@@ -0,0 +1,25 @@
+package services
+
+import (
+	"context"
+	"io"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// BillingService defines the interface for the reconciliation of billable exchanges with
+// finance systems, and of the usage recorded with the usage reported by the LLM vendor
+type BillingService interface {
+	// ListExchanges lists the billable exchanges of a period, with its totals
+	ListExchanges(ctx context.Context, req *dtos.ListBillingExchangesRequest) (*dtos.ListBillingExchangesResponse, error)
+
+	// ReconcileUsage imports the usage of the past days from the configured source and
+	// reconciles it with the usage recorded, returning the number of discrepancies flagged
+	ReconcileUsage(ctx context.Context) (int, error)
+
+	// ImportUsage reconciles the days of an uploaded CSV usage export of the vendor
+	ImportUsage(ctx context.Context, export io.Reader) (*dtos.UsageImportResponse, error)
+
+	// GetReconciliation reports the usage reconciliations of a period
+	GetReconciliation(ctx context.Context, req *dtos.UsageReconciliationRequest) (*dtos.UsageReconciliationResponse, error)
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"io"
+	"math"
+	"regexp"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// defaultBillingPageSize is the number of exchanges of a page when not requested
+const defaultBillingPageSize = 100
+
+// reconciliationDay is the layout of the days of usage reconciliations
+const reconciliationDay = "2006-01-02"
+
+// modelSnapshotSuffix matches the date suffix of the snapshots vendors report usage of, such
+// as gpt-4o-2024-08-06 for gpt-4o
+var modelSnapshotSuffix = regexp.MustCompile(`-\d{4}-\d{2}-\d{2}$`)
+
+// billingService implements the BillingService interface
+type billingService struct {
+	config             configs.Billing
+	budgetRepo         repositories.BudgetRepository
+	reconciliationRepo repositories.UsageReconciliationRepository
+	// usageExport is nil when the reconciliation is not enabled
+	usageExport adapters.UsageExportAdapter
+}
+
+// NewBillingService creates a new billing service
+func NewBillingService(config configs.Billing, budgetRepo repositories.BudgetRepository, reconciliationRepo repositories.UsageReconciliationRepository, usageExport adapters.UsageExportAdapter) BillingService {
+	return &billingService{
+		config:             config,
+		budgetRepo:         budgetRepo,
+		reconciliationRepo: reconciliationRepo,
+		usageExport:        usageExport,
+	}
+}
+
+// ListExchanges lists the billable exchanges of a period in exchange ID order. Pages follow
+// the last exchange of the previous one rather than an offset, so listing a closed period
+// again returns the same pages.
+func (s *billingService) ListExchanges(ctx context.Context, req *dtos.ListBillingExchangesRequest) (*dtos.ListBillingExchangesResponse, error) {
+	if !s.config.Enabled {
+		return nil, errors.New(errors.ErrInvalidRequest, "Billing is not enabled")
+	}
+	logger.Context(ctx).Debugw("Listing billing exchanges", "from", req.From, "to", req.To, "afterID", req.AfterID, "limit", req.Limit)
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultBillingPageSize
+	}
+	// One more exchange tells whether another page follows
+	usage, err := s.budgetRepo.ListUsage(ctx, req.From, req.To, req.AfterID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.budgetRepo.SumUsage(ctx, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dtos.ListBillingExchangesResponse{
+		Exchanges: make([]dtos.BillingExchange, 0, min(len(usage), limit)),
+		Totals: dtos.BillingTotals{
+			Exchanges:        totals.Records,
+			PromptTokens:     totals.PromptTokens,
+			CompletionTokens: totals.CompletionTokens,
+			Images:           totals.Images,
+			Cost:             totals.Cost,
+			Currency:         dtos.BillingCurrency,
+		},
+	}
+	if len(usage) > limit {
+		usage = usage[:limit]
+		response.NextAfterID = &usage[limit-1].ID
+	}
+	for _, record := range usage {
+		response.Exchanges = append(response.Exchanges, toBillingExchange(s.config.Workspace, record))
+	}
+
+	return response, nil
+}
+
+// ReconcileUsage imports the usage of the configured number of complete UTC days before
+// today, which vendors may still revise, and reconciles it with the usage of the region
+func (s *billingService) ReconcileUsage(ctx context.Context) (int, error) {
+	if s.usageExport == nil {
+		return 0, errors.New(errors.ErrInvalidRequest, "Usage reconciliation is not enabled")
+	}
+
+	days := max(s.config.Reconciliation.Days, 1)
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -days)
+
+	usage, err := s.usageExport.DailyUsage(ctx, from, to, s.project(ctx))
+	if err != nil {
+		return 0, errors.Wrap(err, errors.ErrInternal, "Failed to read the usage of the vendor")
+	}
+	result, err := s.reconcile(ctx, usage, from, to, s.config.Reconciliation.Source)
+	if err != nil {
+		return 0, err
+	}
+	return result.Discrepancies, nil
+}
+
+// ImportUsage reconciles the days of an uploaded CSV usage export, from its first day to
+// its last
+func (s *billingService) ImportUsage(ctx context.Context, export io.Reader) (*dtos.UsageImportResponse, error) {
+	if !s.config.Enabled && !s.config.Reconciliation.Enabled {
+		return nil, errors.New(errors.ErrInvalidRequest, "Billing is not enabled")
+	}
+
+	usage, err := adapters.ParseUsageCSV(export, s.project(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidRequest, "Invalid usage export")
+	}
+	if len(usage) == 0 {
+		return nil, errors.New(errors.ErrInvalidRequest, "The usage export holds no usage")
+	}
+	from, to := usage[0].Day, usage[0].Day
+	for _, entry := range usage {
+		if entry.Day.Before(from) {
+			from = entry.Day
+		}
+		if entry.Day.After(to) {
+			to = entry.Day
+		}
+	}
+
+	return s.reconcile(ctx, usage, from, to.AddDate(0, 0, 1), configs.ReconciliationSourceCSV)
+}
+
+// GetReconciliation reports the usage reconciliations of a period, with the totals of both
+// sides
+func (s *billingService) GetReconciliation(ctx context.Context, req *dtos.UsageReconciliationRequest) (*dtos.UsageReconciliationResponse, error) {
+	if !s.config.Enabled && !s.config.Reconciliation.Enabled {
+		return nil, errors.New(errors.ErrInvalidRequest, "Billing is not enabled")
+	}
+	logger.Context(ctx).Debugw("Getting usage reconciliation", "from", req.From, "to", req.To, "status", req.Status)
+
+	reconciliations, err := s.reconciliationRepo.List(ctx, req.From, req.To, req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dtos.UsageReconciliationResponse{
+		Reconciliations: make([]dtos.UsageReconciliation, 0, len(reconciliations)),
+		Totals:          dtos.UsageReconciliationTotals{Currency: dtos.BillingCurrency},
+	}
+	var vendorCost, localCost float64
+	vendorCosts := false
+	for _, reconciliation := range reconciliations {
+		item := toUsageReconciliation(reconciliation)
+		response.Reconciliations = append(response.Reconciliations, item)
+		if reconciliation.Status != models.ReconciliationMatched {
+			response.Discrepancies++
+		}
+		addReconciledUsage(&response.Totals.Vendor, item.Vendor)
+		addReconciledUsage(&response.Totals.Local, item.Local)
+		if item.Vendor.Cost != nil {
+			vendorCost += *item.Vendor.Cost
+			vendorCosts = true
+		}
+		localCost += reconciliation.LocalCost
+	}
+	if vendorCosts {
+		response.Totals.Vendor.Cost = &vendorCost
+	}
+	response.Totals.Local.Cost = &localCost
+
+	return response, nil
+}
+
+// reconcile compares the usage of the vendor with the usage recorded from a day, inclusive,
+// to another, exclusive, by day and model, and saves the reconciliations. Models reported
+// as snapshots are matched to the model recorded without their date suffix.
+func (s *billingService) reconcile(ctx context.Context, vendor []dtos.VendorUsage, from, to time.Time, source string) (*dtos.UsageImportResponse, error) {
+	log := logger.Context(ctx)
+
+	local, err := s.budgetRepo.SumUsageByDay(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		day   string
+		model string
+	}
+	now := time.Now()
+	byKey := map[key]*models.UsageReconciliation{}
+	var reconciliations []*models.UsageReconciliation
+	get := func(day time.Time, model string) *models.UsageReconciliation {
+		day = day.UTC()
+		k := key{day.Format(reconciliationDay), model}
+		reconciliation, ok := byKey[k]
+		if !ok {
+			reconciliation = &models.UsageReconciliation{
+				Day:        time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC),
+				Model:      model,
+				Source:     source,
+				ImportedAt: now,
+			}
+			byKey[k] = reconciliation
+			reconciliations = append(reconciliations, reconciliation)
+		}
+		return reconciliation
+	}
+
+	recorded := map[string]bool{}
+	for _, usage := range local {
+		recorded[usage.Model] = true
+		reconciliation := get(usage.Day, usage.Model)
+		reconciliation.LocalRequests += usage.Records
+		reconciliation.LocalPromptTokens += usage.PromptTokens
+		reconciliation.LocalCompletionTokens += usage.CompletionTokens
+		reconciliation.LocalCost += usage.Cost
+	}
+	for _, usage := range vendor {
+		model := usage.Model
+		if base := modelSnapshotSuffix.ReplaceAllString(model, ""); !recorded[model] && recorded[base] {
+			model = base
+		}
+		reconciliation := get(usage.Day, model)
+		reconciliation.VendorRequests += usage.Requests
+		reconciliation.VendorPromptTokens += usage.PromptTokens
+		reconciliation.VendorCompletionTokens += usage.CompletionTokens
+		if usage.Cost != nil {
+			cost := *usage.Cost
+			if reconciliation.VendorCost != nil {
+				cost += *reconciliation.VendorCost
+			}
+			reconciliation.VendorCost = &cost
+		}
+	}
+
+	result := &dtos.UsageImportResponse{
+		Days:            int(to.Sub(from).Hours() / 24),
+		Reconciliations: len(reconciliations),
+	}
+	for _, reconciliation := range reconciliations {
+		reconciliation.Status = s.reconciliationStatus(reconciliation)
+		if reconciliation.Status == models.ReconciliationMatched {
+			continue
+		}
+		result.Discrepancies++
+		log.Warnw("Usage discrepancy with the vendor",
+			"day", reconciliation.Day.Format(reconciliationDay),
+			"model", reconciliation.Model,
+			"status", reconciliation.Status,
+			"vendorTokens", reconciliation.VendorPromptTokens+reconciliation.VendorCompletionTokens,
+			"localTokens", reconciliation.LocalPromptTokens+reconciliation.LocalCompletionTokens,
+			"vendorCost", reconciliation.VendorCost,
+			"localCost", reconciliation.LocalCost)
+	}
+
+	if err := s.reconciliationRepo.Upsert(ctx, reconciliations); err != nil {
+		return nil, err
+	}
+	log.Infow("Reconciled usage with the vendor", "source", source, "from", from, "to", to,
+		"reconciliations", result.Reconciliations, "discrepancies", result.Discrepancies)
+	return result, nil
+}
+
+// reconciliationStatus returns whether the usage of the vendor and the usage recorded match,
+// within the configured tolerance of tokens and, when the vendor reports it, of cost
+func (s *billingService) reconciliationStatus(r *models.UsageReconciliation) string {
+	vendorTokens := r.VendorPromptTokens + r.VendorCompletionTokens
+	localTokens := r.LocalPromptTokens + r.LocalCompletionTokens
+	vendorCost := 0.0
+	if r.VendorCost != nil {
+		vendorCost = *r.VendorCost
+	}
+
+	switch {
+	case r.VendorRequests == 0 && vendorTokens == 0 && vendorCost == 0:
+		return models.ReconciliationMissingVendor
+	case r.LocalRequests == 0:
+		return models.ReconciliationMissingLocal
+	case relativeDifference(float64(vendorTokens), float64(localTokens)) > s.config.Reconciliation.Tolerance:
+		return models.ReconciliationDiscrepancy
+	case r.VendorCost != nil && relativeDifference(vendorCost, r.LocalCost) > s.config.Reconciliation.Tolerance:
+		return models.ReconciliationDiscrepancy
+	default:
+		return models.ReconciliationMatched
+	}
+}
+
+// project returns the project of the vendor whose usage is that of the region of ctx
+func (s *billingService) project(ctx context.Context) string {
+	if region := adapters.RegionOf(ctx); region != "" {
+		return s.config.Reconciliation.Projects[region]
+	}
+	return s.config.Reconciliation.Project
+}
+
+// relativeDifference returns the difference of two amounts relative to the larger one
+func relativeDifference(a, b float64) float64 {
+	larger := math.Max(math.Abs(a), math.Abs(b))
+	if larger == 0 {
+		return 0
+	}
+	return math.Abs(a-b) / larger
+}
+
+// addReconciledUsage adds the counts of usage to total, leaving the cost out
+func addReconciledUsage(total *dtos.ReconciledUsage, usage dtos.ReconciledUsage) {
+	total.Requests += usage.Requests
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+}
+
+// toUsageReconciliation converts a usage reconciliation to its response
+func toUsageReconciliation(r *models.UsageReconciliation) dtos.UsageReconciliation {
+	localCost := r.LocalCost
+	item := dtos.UsageReconciliation{
+		Day:    r.Day.Format(reconciliationDay),
+		Model:  r.Model,
+		Source: r.Source,
+		Status: r.Status,
+		Vendor: dtos.ReconciledUsage{
+			Requests:         r.VendorRequests,
+			PromptTokens:     r.VendorPromptTokens,
+			CompletionTokens: r.VendorCompletionTokens,
+			Cost:             r.VendorCost,
+		},
+		Local: dtos.ReconciledUsage{
+			Requests:         r.LocalRequests,
+			PromptTokens:     r.LocalPromptTokens,
+			CompletionTokens: r.LocalCompletionTokens,
+			Cost:             &localCost,
+		},
+		TokenDiff:  r.VendorPromptTokens + r.VendorCompletionTokens - r.LocalPromptTokens - r.LocalCompletionTokens,
+		ImportedAt: r.ImportedAt,
+	}
+	if r.VendorCost != nil {
+		diff := *r.VendorCost - r.LocalCost
+		item.CostDiff = &diff
+	}
+	return item
+}
+
+// toBillingExchange converts a usage record to the billable exchange of a workspace
+func toBillingExchange(workspace string, record *models.Usage) dtos.BillingExchange {
+	return dtos.BillingExchange{
+		ExchangeID:       record.ID,
+		Workspace:        workspace,
+		UserID:           record.UserID,
+		ChatID:           record.ChatID,
+		MessageID:        record.MessageID,
+		Model:            record.Model,
+		PromptTokens:     record.PromptTokens,
+		CompletionTokens: record.CompletionTokens,
+		TotalTokens:      record.PromptTokens + record.CompletionTokens,
+		Images:           record.Images,
+		Cost:             record.Cost,
+		Currency:         dtos.BillingCurrency,
+		CompletedAt:      record.CreatedAt,
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// BudgetService defines the interface for monthly cost budgets. An empty user ID
+// stands for the service-wide budget, which caps the spending of all users together.
+type BudgetService interface {
+	// Enforce returns a quota exceeded error when the user or the service has spent its budget of the month
+	Enforce(ctx context.Context, userID string) error
+
+	// RecordUsage records the cost of a reply to a user and publishes a warning or exceeded
+	// event for each budget crossing its threshold, and a billing event when billing is enabled.
+	// The usage also counts towards the workspace of ctx.
+	RecordUsage(ctx context.Context, userID string, chatID, messageID int64, model string, usage dtos.LLMUsage) error
+
+	// RecordImageUsage records the cost of images generated for a user, like RecordUsage
+	RecordImageUsage(ctx context.Context, userID string, chatID, messageID int64, model string, images int) error
+
+	// EstimateCost prices the usage of a model with the price sheet, in USD
+	EstimateCost(model string, usage dtos.LLMUsage) float64
+
+	// EstimateImageCost prices images generated with a model with the price sheet, in USD
+	EstimateImageCost(model string, images int) float64
+
+	// Quota reports the budget covering a user with the least remaining this month, the user's
+	// or the service-wide one, or nil when budgets are disabled or none is set
+	Quota(ctx context.Context, userID string) (*dtos.BudgetResponse, error)
+
+	// GetBudget reports the spending of the month against a budget, with its burn rate and projection
+	GetBudget(ctx context.Context, userID string) (*dtos.BudgetResponse, error)
+
+	// SetBudget sets a budget
+	SetBudget(ctx context.Context, userID string, req *dtos.BudgetRequest) (*dtos.BudgetResponse, error)
+
+	// DeleteBudget removes a budget
+	DeleteBudget(ctx context.Context, userID string) error
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// budgetPeriodLayout formats the month a budget applies to
+const budgetPeriodLayout = "2006-01"
+
+// budgetService implements the BudgetService interface
+type budgetService struct {
+	config     configs.Budgets
+	billing    configs.Billing
+	budgetRepo repositories.BudgetRepository
+	events     EventPublisher
+	workspaces WorkspaceStatsService
+}
+
+// NewBudgetService creates a new budget service. Usage is recorded when budgets or billing
+// are enabled, and counted towards workspaces in any case.
+func NewBudgetService(config configs.Budgets, billing configs.Billing, budgetRepo repositories.BudgetRepository, events EventPublisher, workspaces WorkspaceStatsService) BudgetService {
+	if config.DefaultWarnThreshold <= 0 || config.DefaultWarnThreshold > 1 {
+		config.DefaultWarnThreshold = 0.8
+	}
+
+	return &budgetService{
+		config:     config,
+		billing:    billing,
+		budgetRepo: budgetRepo,
+		events:     events,
+		workspaces: workspaces,
+	}
+}
+
+// Enforce returns a quota exceeded error when the user or the service has spent its budget of the month
+func (s *budgetService) Enforce(ctx context.Context, userID string) error {
+	if !s.config.Enabled {
+		return nil
+	}
+
+	start, _ := budgetPeriod(time.Now())
+	for _, owner := range []string{userID, ""} {
+		budget, err := s.budgetRepo.Get(ctx, owner)
+		if err != nil {
+			return err
+		}
+		if budget == nil {
+			continue
+		}
+
+		spent, err := s.budgetRepo.SumCost(ctx, owner, start)
+		if err != nil {
+			return err
+		}
+		if spent >= budget.MonthlyLimit {
+			logger.Context(ctx).Warnw("Budget exceeded, message rejected", "userID", userID, "budgetUserID", owner, "spent", spent)
+			if owner == "" {
+				return errors.New(errors.ErrQuotaExceeded, "The service has reached its monthly budget")
+			}
+			return errors.New(errors.ErrQuotaExceeded, "Monthly budget reached")
+		}
+	}
+
+	return nil
+}
+
+// RecordUsage records the cost of a reply to a user and publishes a warning or exceeded
+// event for each budget crossing its threshold, and a billing event when billing is enabled
+func (s *budgetService) RecordUsage(ctx context.Context, userID string, chatID, messageID int64, model string, usage dtos.LLMUsage) error {
+	s.workspaces.RecordUsage(ctx, userID, usage.TotalTokens, s.cost(model, usage))
+	if !s.config.Enabled && !s.billing.Enabled {
+		return nil
+	}
+
+	record := &models.Usage{
+		UserID:           userID,
+		ChatID:           chatID,
+		MessageID:        messageID,
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		Cost:             s.cost(model, usage),
+	}
+	return s.record(ctx, record)
+}
+
+// RecordImageUsage records the cost of images generated for a user and publishes a warning
+// or exceeded event for each budget crossing its threshold
+func (s *budgetService) RecordImageUsage(ctx context.Context, userID string, chatID, messageID int64, model string, images int) error {
+	s.workspaces.RecordUsage(ctx, userID, 0, s.EstimateImageCost(model, images))
+	if !s.config.Enabled && !s.billing.Enabled {
+		return nil
+	}
+
+	record := &models.Usage{
+		UserID:    userID,
+		ChatID:    chatID,
+		MessageID: messageID,
+		Model:     model,
+		Images:    images,
+		Cost:      s.EstimateImageCost(model, images),
+	}
+	return s.record(ctx, record)
+}
+
+// record saves a usage record, publishes it as a billing exchange and checks the thresholds
+// of the budgets it counts towards
+func (s *budgetService) record(ctx context.Context, record *models.Usage) error {
+	if err := s.budgetRepo.RecordUsage(ctx, record); err != nil {
+		return err
+	}
+
+	if s.billing.Enabled {
+		// Exchanges missing from the billing topic are still listed for reconciliation
+		message := newEvent(ctx, models.EventBillingExchangeCompleted, toBillingExchange(s.billing.Workspace, record))
+		if err := s.events.PublishBillingEvent(ctx, message); err != nil {
+			logger.Context(ctx).Errorw("Failed to publish billing event", "error", err, "exchangeID", record.ID)
+		}
+	}
+	if !s.config.Enabled {
+		return nil
+	}
+
+	for _, owner := range []string{record.UserID, ""} {
+		if err := s.checkThresholds(ctx, owner); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EstimateCost prices the usage of a model with the price sheet, in USD
+func (s *budgetService) EstimateCost(model string, usage dtos.LLMUsage) float64 {
+	return s.cost(model, usage)
+}
+
+// EstimateImageCost prices images generated with a model with the price sheet, in USD
+func (s *budgetService) EstimateImageCost(model string, images int) float64 {
+	return float64(images) * s.price(model).Image
+}
+
+// cost prices the usage of a model with the price sheet
+func (s *budgetService) cost(model string, usage dtos.LLMUsage) float64 {
+	price := s.price(model)
+	return (float64(usage.PromptTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6
+}
+
+// price returns the price of a model in the price sheet, or the default price
+func (s *budgetService) price(model string) configs.ModelPrice {
+	price, ok := s.config.Prices[model]
+	if !ok {
+		price = s.config.DefaultPrice
+	}
+	return price
+}
+
+// checkThresholds publishes the event of the highest threshold of a budget crossed this
+// month, once per month
+func (s *budgetService) checkThresholds(ctx context.Context, userID string) error {
+	budget, err := s.budgetRepo.Get(ctx, userID)
+	if err != nil || budget == nil {
+		return err
+	}
+
+	now := time.Now()
+	start, _ := budgetPeriod(now)
+	spent, err := s.budgetRepo.SumCost(ctx, userID, start)
+	if err != nil {
+		return err
+	}
+
+	event, column := "", ""
+	switch {
+	case spent >= budget.MonthlyLimit:
+		event, column = models.EventBudgetExceeded, "exceeded_period"
+	case spent >= budget.MonthlyLimit*budget.WarnThreshold:
+		event, column = models.EventBudgetWarning, "warned_period"
+	default:
+		return nil
+	}
+
+	period := now.UTC().Format(budgetPeriodLayout)
+	marked, err := s.budgetRepo.MarkPeriod(ctx, budget.ID, column, period)
+	if err != nil || !marked {
+		return err
+	}
+
+	logger.Context(ctx).Infow("Budget threshold crossed", "event", event, "userID", userID, "spent", spent, "limit", budget.MonthlyLimit)
+	message := newEvent(ctx, event, dtos.BudgetPayload{
+		Scope:         budgetScope(userID),
+		UserID:        userID,
+		Period:        period,
+		MonthlyLimit:  budget.MonthlyLimit,
+		WarnThreshold: budget.WarnThreshold,
+		Spent:         spent,
+	})
+	return s.events.PublishBudgetEvent(ctx, message)
+}
+
+// Quota reports the budget covering a user with the least remaining this month, the user's
+// or the service-wide one, or nil when budgets are disabled or none is set
+func (s *budgetService) Quota(ctx context.Context, userID string) (*dtos.BudgetResponse, error) {
+	if !s.config.Enabled {
+		return nil, nil
+	}
+
+	var quota *dtos.BudgetResponse
+	for _, owner := range []string{userID, ""} {
+		budget, err := s.GetBudget(ctx, owner)
+		if err != nil {
+			return nil, err
+		}
+		if budget.Remaining != nil && (quota == nil || *budget.Remaining < *quota.Remaining) {
+			quota = budget
+		}
+	}
+
+	return quota, nil
+}
+
+// GetBudget reports the spending of the month against a budget, with its burn rate and projection
+func (s *budgetService) GetBudget(ctx context.Context, userID string) (*dtos.BudgetResponse, error) {
+	if !s.config.Enabled {
+		return nil, errors.New(errors.ErrInvalidRequest, "Budgets are not enabled")
+	}
+
+	budget, err := s.budgetRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	start, end := budgetPeriod(now)
+	spent, err := s.budgetRepo.SumCost(ctx, userID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	// Spending of the first day of the month is not extrapolated from a few hours
+	elapsed := max(now.Sub(start).Hours()/24, 1)
+	burnRate := spent / elapsed
+	response := &dtos.BudgetResponse{
+		Scope:       budgetScope(userID),
+		UserID:      userID,
+		Spent:       spent,
+		BurnRate:    burnRate,
+		Projected:   burnRate * end.Sub(start).Hours() / 24,
+		Status:      dtos.BudgetStatusOK,
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}
+
+	if budget != nil {
+		remaining := max(budget.MonthlyLimit-spent, 0)
+		response.MonthlyLimit = &budget.MonthlyLimit
+		response.WarnThreshold = &budget.WarnThreshold
+		response.Remaining = &remaining
+		switch {
+		case spent >= budget.MonthlyLimit:
+			response.Status = dtos.BudgetStatusExceeded
+		case spent >= budget.MonthlyLimit*budget.WarnThreshold:
+			response.Status = dtos.BudgetStatusWarning
+		}
+	}
+
+	return response, nil
+}
+
+// SetBudget sets a budget
+func (s *budgetService) SetBudget(ctx context.Context, userID string, req *dtos.BudgetRequest) (*dtos.BudgetResponse, error) {
+	if !s.config.Enabled {
+		return nil, errors.New(errors.ErrInvalidRequest, "Budgets are not enabled")
+	}
+
+	budget := &models.Budget{
+		UserID:        userID,
+		MonthlyLimit:  req.MonthlyLimit,
+		WarnThreshold: s.config.DefaultWarnThreshold,
+	}
+	if req.WarnThreshold != nil {
+		budget.WarnThreshold = *req.WarnThreshold
+	}
+	if err := s.budgetRepo.Upsert(ctx, budget); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Budget updated", "userID", userID, "monthlyLimit", budget.MonthlyLimit, "warnThreshold", budget.WarnThreshold)
+	return s.GetBudget(ctx, userID)
+}
+
+// DeleteBudget removes a budget
+func (s *budgetService) DeleteBudget(ctx context.Context, userID string) error {
+	if !s.config.Enabled {
+		return errors.New(errors.ErrInvalidRequest, "Budgets are not enabled")
+	}
+
+	if err := s.budgetRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	logger.Context(ctx).Infow("Budget deleted", "userID", userID)
+	return nil
+}
+
+// budgetPeriod returns the start and end of the calendar month, in UTC, of a time
+func budgetPeriod(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// budgetScope returns the scope of the budget of a user, or of the service when userID is empty
+func budgetScope(userID string) string {
+	if userID == "" {
+		return dtos.BudgetScopeGlobal
+	}
+	return dtos.BudgetScopeUser
+}
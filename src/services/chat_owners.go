@@ -0,0 +1,262 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// chatOwnerKeyPrefix prefixes the keys of chat owners in the shared cache
+const chatOwnerKeyPrefix = "chat-owner:"
+
+// chatOwnersChannel is the channel the keys of invalidated chat owners are broadcast on
+const chatOwnersChannel = "chat-owners:invalidated"
+
+// chatOwnerTombstone is the value keeping an invalidated owner out of the shared cache, so
+// that an owner loaded before the invalidation is not cached again by another instance
+const chatOwnerTombstone = ""
+
+// chatOwnersResubscribe is how long Watch waits before subscribing again to the
+// invalidations, once it lost them
+const chatOwnersResubscribe = time.Second
+
+// ChatOwners caches the owners of chats, with their workspaces, in two levels: a least
+// recently used cache in memory with a short TTL, then optionally a cache shared by the
+// instances. Chat events invalidate an owner in the shared cache and broadcast the
+// invalidation to the memory of every instance. While an instance does not receive the
+// invalidations, it caches nothing in memory.
+type ChatOwners struct {
+	config    configs.ChatOwners
+	shared    adapters.CacheAdapter
+	broadcast adapters.PubSubAdapter
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List // Most recently used first
+	watching bool       // Whether the invalidations are received
+	// invalidations counts the invalidations in memory, so that owners looked up before one
+	// are not cached
+	invalidations uint64
+}
+
+// chatOwnerEntry is a chat owner cached in memory
+type chatOwnerEntry struct {
+	key       string
+	owner     dtos.ChatOwner
+	expiresAt time.Time
+}
+
+// NewChatOwners creates a cache of chat owners; shared may be nil. Invalidations are
+// broadcast to the instances with broadcast, received once Watch runs. A nil broadcast
+// serves a single instance, invalidated by its own chat events.
+func NewChatOwners(config configs.ChatOwners, shared adapters.CacheAdapter, broadcast adapters.PubSubAdapter) *ChatOwners {
+	if config.MaxEntries < 1 {
+		config.MaxEntries = 1
+	}
+	return &ChatOwners{
+		config:    config,
+		shared:    shared,
+		broadcast: broadcast,
+		entries:   map[string]*list.Element{},
+		order:     list.New(),
+		watching:  broadcast == nil,
+	}
+}
+
+// Get returns the owner of a chat, loading it when it is not cached. The shared cache is
+// only filled when the owner is absent from it, never over an invalidation. Errors of the
+// shared cache are logged and fall back to load.
+func (c *ChatOwners) Get(ctx context.Context, chatID int64, load func(ctx context.Context) (*dtos.ChatOwner, error)) (*dtos.ChatOwner, error) {
+	log := logger.Context(ctx)
+	key := chatOwnerKey(ctx, chatID)
+
+	owner, invalidations, ok := c.getLocal(key)
+	if ok {
+		metrics.ChatOwnerLookups.WithLabelValues("memory").Inc()
+		return &owner, nil
+	}
+
+	fill := c.shared != nil
+	if c.shared != nil {
+		value, ok, err := c.shared.Get(ctx, key)
+		switch {
+		case err != nil:
+			log.Warnw("Failed to get chat owner from the shared cache", "chatID", chatID, "error", err)
+		case ok && value == chatOwnerTombstone:
+			fill = false
+		case ok:
+			var owner dtos.ChatOwner
+			if err := json.Unmarshal([]byte(value), &owner); err != nil {
+				log.Warnw("Failed to decode chat owner from the shared cache", "chatID", chatID, "error", err)
+				fill = false
+				break
+			}
+			metrics.ChatOwnerLookups.WithLabelValues("redis").Inc()
+			c.setLocal(key, owner, invalidations)
+			return &owner, nil
+		}
+	}
+
+	loaded, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metrics.ChatOwnerLookups.WithLabelValues("database").Inc()
+	c.setLocal(key, *loaded, invalidations)
+	if fill {
+		value, _ := json.Marshal(loaded)
+		filled := string(value)
+		if _, err := c.shared.CompareAndSwap(ctx, key, nil, &filled, c.config.Redis.TTL); err != nil {
+			log.Warnw("Failed to cache chat owner in the shared cache", "chatID", chatID, "error", err)
+		}
+	}
+	return loaded, nil
+}
+
+// Invalidate forgets the owner of a chat in memory and in the shared cache, where it leaves a
+// tombstone for the TTL of the shared cache, and broadcasts the invalidation to the instances
+func (c *ChatOwners) Invalidate(ctx context.Context, chatID int64) {
+	log := logger.Context(ctx)
+	key := chatOwnerKey(ctx, chatID)
+
+	c.forgetLocal(key)
+	if c.shared != nil {
+		if err := c.shared.Set(ctx, key, chatOwnerTombstone, c.config.Redis.TTL); err != nil {
+			log.Warnw("Failed to invalidate chat owner in the shared cache", "chatID", chatID, "error", err)
+		}
+	}
+	if c.broadcast != nil {
+		if err := c.broadcast.Publish(ctx, chatOwnersChannel, key); err != nil {
+			log.Warnw("Failed to broadcast the invalidation of chat owner", "chatID", chatID, "error", err)
+		}
+	}
+}
+
+// Watch receives the invalidations broadcast by the instances until ctx is done, caching
+// owners in memory only while it does. Lost subscriptions are renewed after
+// chatOwnersResubscribe, forgetting the owners cached before.
+func (c *ChatOwners) Watch(ctx context.Context) {
+	if c.broadcast == nil {
+		return
+	}
+	log := logger.Context(ctx)
+
+	for {
+		invalidated, err := c.broadcast.Subscribe(ctx, chatOwnersChannel)
+		if err == nil {
+			c.setWatching(true)
+			for key := range invalidated {
+				c.forgetLocal(key)
+			}
+			c.setWatching(false)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warnw("Lost the invalidations of chat owners, which are not cached in memory until resubscribed", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(chatOwnersResubscribe):
+		}
+	}
+}
+
+// Events returns the event bus subscriber invalidating the owners of the chats changed,
+// transferred or deleted
+func (c *ChatOwners) Events() EventPublisher {
+	return EventHandlers{
+		Chat: func(ctx context.Context, message *dtos.KafkaMessage[dtos.ChatPayload]) error {
+			if message.Event != models.EventChatCreated {
+				c.Invalidate(ctx, message.Payload.ChatID)
+			}
+			return nil
+		},
+	}
+}
+
+// getLocal returns the owner cached in memory under key, unless it expired, and the number of
+// invalidations so far
+func (c *ChatOwners) getLocal(key string) (dtos.ChatOwner, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return dtos.ChatOwner{}, c.invalidations, false
+	}
+	entry := element.Value.(*chatOwnerEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return dtos.ChatOwner{}, c.invalidations, false
+	}
+	c.order.MoveToFront(element)
+	return entry.owner, c.invalidations, true
+}
+
+// setLocal caches an owner in memory, evicting the least recently used one when full. The
+// owner is not cached when invalidations were made since it was looked up, as it may be the
+// previous one, nor while the invalidations are not received.
+func (c *ChatOwners) setLocal(key string, owner dtos.ChatOwner, invalidations uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.watching || invalidations != c.invalidations {
+		return
+	}
+	expiresAt := time.Now().Add(c.config.TTL)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*chatOwnerEntry)
+		entry.owner, entry.expiresAt = owner, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.config.MaxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*chatOwnerEntry).key)
+	}
+	c.entries[key] = c.order.PushFront(&chatOwnerEntry{key: key, owner: owner, expiresAt: expiresAt})
+}
+
+// forgetLocal forgets the owner cached in memory under key
+func (c *ChatOwners) forgetLocal(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidations++
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// setWatching records whether the invalidations are received, forgetting every owner cached
+// in memory, since invalidations may have been missed
+func (c *ChatOwners) setWatching(watching bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.watching = watching
+	c.invalidations++
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+}
+
+// chatOwnerKey returns the cache key of a chat, scoped to the data residency region of ctx
+// since the IDs of different regions overlap
+func chatOwnerKey(ctx context.Context, chatID int64) string {
+	return chatOwnerKeyPrefix + adapters.RegionOf(ctx) + ":" + strconv.FormatInt(chatID, 10)
+}
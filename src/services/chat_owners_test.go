@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	logger.Init("error", "test")
+}
+
+// ownerLoader loads the owners of owners, in the workspace of their chat ID, counting the
+// loads of each chat
+type ownerLoader struct {
+	owners map[int64]string
+	loads  map[int64]int
+}
+
+func newOwnerLoader(owners map[int64]string) *ownerLoader {
+	return &ownerLoader{owners: owners, loads: map[int64]int{}}
+}
+
+func (l *ownerLoader) load(chatID int64) func(ctx context.Context) (*dtos.ChatOwner, error) {
+	return func(ctx context.Context) (*dtos.ChatOwner, error) {
+		l.loads[chatID]++
+		return &dtos.ChatOwner{UserID: l.owners[chatID], WorkspaceID: fmt.Sprintf("w%d", chatID)}, nil
+	}
+}
+
+// failingCache is a shared cache failing every command
+type failingCache struct{}
+
+func (failingCache) Get(ctx context.Context, key string) (string, bool, error) {
+	return "", false, errors.New("unavailable")
+}
+
+func (failingCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return errors.New("unavailable")
+}
+
+func (failingCache) Delete(ctx context.Context, key string) error {
+	return errors.New("unavailable")
+}
+
+func (failingCache) CompareAndSwap(ctx context.Context, key string, old, value *string, ttl time.Duration) (bool, error) {
+	return false, errors.New("unavailable")
+}
+
+func TestChatOwnersCachesInMemory(t *testing.T) {
+	owners := NewChatOwners(configs.ChatOwners{TTL: time.Minute, MaxEntries: 10}, nil, nil)
+	loader := newOwnerLoader(map[int64]string{1: "alice"})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		owner, err := owners.Get(ctx, 1, loader.load(1))
+		require.NoError(t, err)
+		assert.Equal(t, &dtos.ChatOwner{UserID: "alice", WorkspaceID: "w1"}, owner)
+	}
+	assert.Equal(t, 1, loader.loads[1])
+}
+
+func TestChatOwnersLoadError(t *testing.T) {
+	owners := NewChatOwners(configs.ChatOwners{TTL: time.Minute, MaxEntries: 10}, nil, nil)
+	ctx := context.Background()
+
+	_, err := owners.Get(ctx, 1, func(ctx context.Context) (*dtos.ChatOwner, error) {
+		return nil, errors.New("not found")
+	})
+	require.Error(t, err)
+
+	// Failed loads are not cached
+	loader := newOwnerLoader(map[int64]string{1: "alice"})
+	owner, err := owners.Get(ctx, 1, loader.load(1))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", owner.UserID)
+	assert.Equal(t, 1, loader.loads[1])
+}
+
+func TestChatOwnersEvictsLeastRecentlyUsed(t *testing.T) {
+	owners := NewChatOwners(configs.ChatOwners{TTL: time.Minute, MaxEntries: 2}, nil, nil)
+	loader := newOwnerLoader(map[int64]string{1: "alice", 2: "bob", 3: "carol"})
+	ctx := context.Background()
+
+	for _, chatID := range []int64{1, 2, 1, 3} {
+		_, err := owners.Get(ctx, chatID, loader.load(chatID))
+		require.NoError(t, err)
+	}
+	// Chat 1 was used after chat 2, so chat 2 made room for chat 3
+	assert.Len(t, owners.entries, 2)
+	assert.Equal(t, 2, owners.order.Len())
+
+	for _, chatID := range []int64{1, 3} {
+		_, err := owners.Get(ctx, chatID, loader.load(chatID))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, map[int64]int{1: 1, 2: 1, 3: 1}, loader.loads)
+
+	owner, err := owners.Get(ctx, 2, loader.load(2))
+	require.NoError(t, err)
+	assert.Equal(t, "bob", owner.UserID)
+	assert.Equal(t, 2, loader.loads[2])
+}
+
+func TestChatOwnersExpire(t *testing.T) {
+	owners := NewChatOwners(configs.ChatOwners{TTL: 10 * time.Millisecond, MaxEntries: 10}, nil, nil)
+	loader := newOwnerLoader(map[int64]string{1: "alice"})
+	ctx := context.Background()
+
+	_, err := owners.Get(ctx, 1, loader.load(1))
+	require.NoError(t, err)
+
+	// Owners changed elsewhere are seen once the entry expires
+	loader.owners[1] = "bob"
+	time.Sleep(20 * time.Millisecond)
+
+	owner, err := owners.Get(ctx, 1, loader.load(1))
+	require.NoError(t, err)
+	assert.Equal(t, "bob", owner.UserID)
+	assert.Equal(t, 2, loader.loads[1])
+}
+
+func TestChatOwnersSharedCache(t *testing.T) {
+	shared := adapters.NewMemoryCacheAdapter()
+	config := configs.ChatOwners{TTL: time.Minute, MaxEntries: 10, Redis: configs.ChatOwnersRedis{TTL: time.Minute}}
+	loader := newOwnerLoader(map[int64]string{1: "alice"})
+	ctx := context.Background()
+
+	_, err := NewChatOwners(config, shared, nil).Get(ctx, 1, loader.load(1))
+	require.NoError(t, err)
+
+	// Another instance finds the owner, with the workspace, in the shared cache
+	owner, err := NewChatOwners(config, shared, nil).Get(ctx, 1, loader.load(1))
+	require.NoError(t, err)
+	assert.Equal(t, &dtos.ChatOwner{UserID: "alice", WorkspaceID: "w1"}, owner)
+	assert.Equal(t, 1, loader.loads[1])
+
+	// Keys are scoped to the region, since chat IDs of regions overlap
+	_, err = NewChatOwners(config, shared, nil).Get(adapters.WithRegion(ctx, "eu"), 1, loader.load(1))
+	require.NoError(t, err)
+	assert.Equal(t, 2, loader.loads[1])
+}
+
+func TestChatOwnersSharedCacheErrors(t *testing.T) {
+	owners := NewChatOwners(configs.ChatOwners{TTL: time.Minute, MaxEntries: 10}, failingCache{}, nil)
+	loader := newOwnerLoader(map[int64]string{1: "alice"})
+	ctx := context.Background()
+
+	// Errors of the shared cache fall back to loading the owner
+	owner, err := owners.Get(ctx, 1, loader.load(1))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", owner.UserID)
+
+	owners.Invalidate(ctx, 1)
+	_, err = owners.Get(ctx, 1, loader.load(1))
+	require.NoError(t, err)
+	assert.Equal(t, 2, loader.loads[1])
+}
+
+func TestChatOwnersInvalidatedByChatEvents(t *testing.T) {
+	tests := []struct {
+		event       string
+		invalidated bool
+	}{
+		{models.EventChatCreated, false},
+		{models.EventChatUpdated, true},
+		{models.EventChatTransferred, true},
+		{models.EventChatDeleted, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			shared := adapters.NewMemoryCacheAdapter()
+			config := configs.ChatOwners{TTL: time.Minute, MaxEntries: 10, Redis: configs.ChatOwnersRedis{TTL: time.Minute}}
+			owners := NewChatOwners(config, shared, nil)
+			loader := newOwnerLoader(map[int64]string{1: "alice", 2: "bob"})
+			ctx := context.Background()
+
+			for _, chatID := range []int64{1, 2} {
+				_, err := owners.Get(ctx, chatID, loader.load(chatID))
+				require.NoError(t, err)
+			}
+
+			loader.owners[1] = "carol"
+			err := owners.Events().PublishChatEvent(ctx, &dtos.KafkaMessage[dtos.ChatPayload]{
+				Event:   tt.event,
+				Payload: dtos.ChatPayload{ChatID: 1, UserID: "carol"},
+			})
+			require.NoError(t, err)
+
+			// Both levels forget the chat, so it is loaded again, leaving a tombstone in the
+			// shared cache
+			value, inShared, err := shared.Get(ctx, chatOwnerKey(ctx, 1))
+			require.NoError(t, err)
+			assert.True(t, inShared)
+			assert.Equal(t, tt.invalidated, value == chatOwnerTombstone)
+
+			owner, err := owners.Get(ctx, 1, loader.load(1))
+			require.NoError(t, err)
+			if tt.invalidated {
+				assert.Equal(t, "carol", owner.UserID)
+				assert.Equal(t, 2, loader.loads[1])
+			} else {
+				assert.Equal(t, "alice", owner.UserID)
+				assert.Equal(t, 1, loader.loads[1])
+			}
+
+			// Other chats stay cached
+			_, err = owners.Get(ctx, 2, loader.load(2))
+			require.NoError(t, err)
+			assert.Equal(t, 1, loader.loads[2])
+		})
+	}
+}
+
+// watchOwners starts receiving the invalidations of owners until the test ends
+func watchOwners(t *testing.T, owners *ChatOwners) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go owners.Watch(ctx)
+
+	require.Eventually(t, func() bool {
+		owners.mu.Lock()
+		defer owners.mu.Unlock()
+		return owners.watching
+	}, time.Second, time.Millisecond)
+}
+
+func TestChatOwnersBroadcastInvalidations(t *testing.T) {
+	shared := adapters.NewMemoryCacheAdapter()
+	broadcast := adapters.NewMemoryPubSubAdapter()
+	config := configs.ChatOwners{TTL: time.Minute, MaxEntries: 10, Redis: configs.ChatOwnersRedis{TTL: time.Minute}}
+	first, second := NewChatOwners(config, shared, broadcast), NewChatOwners(config, shared, broadcast)
+	watchOwners(t, first)
+	watchOwners(t, second)
+	loader := newOwnerLoader(map[int64]string{1: "alice"})
+	ctx := context.Background()
+
+	for _, owners := range []*ChatOwners{first, second} {
+		_, err := owners.Get(ctx, 1, loader.load(1))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, loader.loads[1])
+
+	// A transfer through the first instance reaches the memory of the second
+	loader.owners[1] = "bob"
+	first.Invalidate(ctx, 1)
+	require.Eventually(t, func() bool {
+		owner, err := second.Get(ctx, 1, loader.load(1))
+		return err == nil && owner.UserID == "bob"
+	}, time.Second, time.Millisecond)
+}
+
+func TestChatOwnersNotCachedInMemoryWithoutInvalidations(t *testing.T) {
+	owners := NewChatOwners(configs.ChatOwners{TTL: time.Minute, MaxEntries: 10}, nil, adapters.NewMemoryPubSubAdapter())
+	loader := newOwnerLoader(map[int64]string{1: "alice"})
+	ctx := context.Background()
+
+	// Until Watch receives the invalidations, every lookup loads the owner
+	for i := 0; i < 2; i++ {
+		_, err := owners.Get(ctx, 1, loader.load(1))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, loader.loads[1])
+
+	watchOwners(t, owners)
+	for i := 0; i < 2; i++ {
+		_, err := owners.Get(ctx, 1, loader.load(1))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, loader.loads[1])
+}
+
+func TestChatOwnersDoNotCacheOwnersInvalidatedWhileLoading(t *testing.T) {
+	shared := adapters.NewMemoryCacheAdapter()
+	config := configs.ChatOwners{TTL: time.Minute, MaxEntries: 10, Redis: configs.ChatOwnersRedis{TTL: time.Minute}}
+	owners := NewChatOwners(config, shared, nil)
+	loader := newOwnerLoader(map[int64]string{1: "alice"})
+	ctx := context.Background()
+
+	// The chat is transferred between loading its previous owner and caching it
+	owner, err := owners.Get(ctx, 1, func(ctx context.Context) (*dtos.ChatOwner, error) {
+		owner, _ := loader.load(1)(ctx)
+		loader.owners[1] = "bob"
+		owners.Invalidate(ctx, 1)
+		return owner, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", owner.UserID)
+
+	// The previous owner was cached at neither level
+	value, _, err := shared.Get(ctx, chatOwnerKey(ctx, 1))
+	require.NoError(t, err)
+	assert.Equal(t, chatOwnerTombstone, value)
+
+	owner, err = owners.Get(ctx, 1, loader.load(1))
+	require.NoError(t, err)
+	assert.Equal(t, "bob", owner.UserID)
+	assert.Equal(t, 2, loader.loads[1])
+}
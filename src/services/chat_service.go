@@ -14,8 +14,11 @@ type ChatService interface {
 	// GetChat retrieves a chat by ID
 	GetChat(ctx context.Context, id int64) (*dtos.ChatResponse, error)
 
+	// GetOwner returns the user owning a chat and its workspace, cached for ownership checks
+	GetOwner(ctx context.Context, id int64) (*dtos.ChatOwner, error)
+
 	// ListChats lists all chats for a user
-	ListChats(ctx context.Context, userID string, limit, offset int) (*dtos.ListChatsResponse, error)
+	ListChats(ctx context.Context, userID string, limit, offset int, count bool) (*dtos.ListChatsResponse, error)
 
 	// SearchChats searches chats by title for a user
 	SearchChats(ctx context.Context, userID string, req *dtos.SearchChatsRequest) (*dtos.ListChatsResponse, error)
@@ -23,6 +26,30 @@ type ChatService interface {
 	// UpdateChat updates a chat
 	UpdateChat(ctx context.Context, id int64, req *dtos.ChatRequest) (*dtos.ChatResponse, error)
 
-	// DeleteChat deletes a chat
+	// SetLocked locks or unlocks a chat. Only admins can unlock a chat locked by an admin.
+	SetLocked(ctx context.Context, id int64, locked, admin bool) (*dtos.ChatResponse, error)
+
+	// TransferChat moves a chat and its messages to another user on behalf of transferredBy
+	TransferChat(ctx context.Context, id int64, transferredBy string, req *dtos.TransferChatRequest) (*dtos.ChatResponse, error)
+
+	// RequestDeleteChat makes a chat pending deletion for the grace period, during which the
+	// deletion can be undone. Without a grace period the chat is deleted at once and nil returned.
+	RequestDeleteChat(ctx context.Context, id int64) (*dtos.ChatResponse, error)
+
+	// UndoDeleteChat restores a chat pending deletion before its grace period ends
+	UndoDeleteChat(ctx context.Context, id int64) (*dtos.ChatResponse, error)
+
+	// DeleteChat deletes a chat at once
 	DeleteChat(ctx context.Context, id int64) error
+
+	// PurgeDeleted deletes the chats pending deletion whose grace period has ended and
+	// returns how many were deleted
+	PurgeDeleted(ctx context.Context) (int, error)
+
+	// PurgeEphemeral deletes the privacy mode chats whose messages all expired and returns
+	// how many were deleted
+	PurgeEphemeral(ctx context.Context) (int, error)
+
+	// GetStats sums the activity across a user's chats
+	GetStats(ctx context.Context, userID string) (*dtos.UserStatsResponse, error)
 }
@@ -2,26 +2,54 @@ package services
 
 import (
 	"context"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
 	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
 	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
 	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/dryrun"
+	"github.com/nvnamsss/chat/src/pkg/workspace"
+	"github.com/nvnamsss/chat/src/plugins"
 	"github.com/nvnamsss/chat/src/repositories"
 )
 
+// chatDeletionBatchSize bounds the chats removed per deletion run
+const chatDeletionBatchSize = 100
+
 // chatService implements the ChatService interface
 type chatService struct {
-	chatRepo repositories.ChatRepository
-	kafka    KafkaProducer
+	config    configs.ChatDeletion
+	chatRepo  repositories.ChatRepository
+	statsRepo repositories.ChatStatsRepository
+	events    EventPublisher
+	hooks     *plugins.Hooks
+	search    adapters.SearchAdapter
+	owners    *ChatOwners
+	recent    *RecentChats
+	ephemeral *repositories.EphemeralMessageStore
 }
 
-// NewChatService creates a new chat service
-func NewChatService(chatRepo repositories.ChatRepository, kafka KafkaProducer) ChatService {
+// NewChatService creates a new chat service. Chat searches are served by the search adapter
+// when one is given, and by the database otherwise. Owners are read from the database on
+// every check when owners is nil, and chat lists from the database when recent is nil.
+// Privacy mode chats are only created with an ephemeral message store.
+func NewChatService(config configs.ChatDeletion, chatRepo repositories.ChatRepository, statsRepo repositories.ChatStatsRepository, events EventPublisher, hooks *plugins.Hooks, search adapters.SearchAdapter, owners *ChatOwners, recent *RecentChats, ephemeral *repositories.EphemeralMessageStore) ChatService {
 	return &chatService{
-		chatRepo: chatRepo,
-		kafka:    kafka,
+		config:    config,
+		chatRepo:  chatRepo,
+		statsRepo: statsRepo,
+		events:    events,
+		hooks:     hooks,
+		search:    search,
+		owners:    owners,
+		recent:    recent,
+		ephemeral: ephemeral,
 	}
 }
 
@@ -32,8 +60,41 @@ func (s *chatService) CreateChat(ctx context.Context, userID string, req *dtos.C
 
 	// Create chat entity
 	chat := &models.Chat{
-		UserID: userID,
-		Title:  req.Title,
+		UserID:      userID,
+		WorkspaceID: workspace.Of(ctx),
+		Title:       req.Title,
+	}
+	if req.URLContext != nil {
+		chat.URLContext = *req.URLContext
+	}
+	if req.Suggestions != nil {
+		chat.Suggestions = *req.Suggestions
+	}
+	if req.Language != nil {
+		chat.Language = strings.ToLower(strings.TrimSpace(*req.Language))
+	}
+	if req.Budget != nil {
+		applyChatBudget(chat, req.Budget)
+	}
+	if req.Tags != nil {
+		chat.Tags = joinTags(*req.Tags)
+	}
+	if req.Archived != nil {
+		chat.Archived = *req.Archived
+	}
+	if req.SystemPrompt != nil {
+		chat.SystemPrompt = *req.SystemPrompt
+	}
+	if req.Ephemeral {
+		if s.ephemeral == nil {
+			return nil, errors.New(errors.ErrInvalidRequest, "Privacy mode is not enabled")
+		}
+		chat.Ephemeral = true
+	}
+
+	// A dry run returns the chat that would be created, without ID
+	if dryrun.Enabled(ctx) {
+		return toChatResponse(chat), nil
 	}
 
 	// Save to database
@@ -42,30 +103,22 @@ func (s *chatService) CreateChat(ctx context.Context, userID string, req *dtos.C
 	}
 
 	// Publish event
-	event := &dtos.KafkaMessage[dtos.ChatPayload]{
-		ID:        uuid.New().String(),
-		Event:     models.EventChatCreated,
-		Timestamp: time.Now().Unix(),
-		Payload: dtos.ChatPayload{
-			ChatID: chat.ID,
-			UserID: chat.UserID,
-			Title:  chat.Title,
-		},
-	}
-
-	if err := s.kafka.PublishChatEvent(ctx, event); err != nil {
+	event := newEvent(ctx, models.EventChatCreated, dtos.ChatPayload{
+		ChatID: chat.ID,
+		UserID: chat.UserID,
+		Title:  chat.Title,
+	})
+
+	if err := s.events.PublishChatEvent(ctx, event); err != nil {
 		// Just log the error but don't fail the request
 		log.Errorw("Failed to publish chat created event", "error", err, "chatID", chat.ID)
 	}
 
 	// Convert to response DTO
-	return &dtos.ChatResponse{
-		ID:        chat.ID,
-		UserID:    chat.UserID,
-		Title:     chat.Title,
-		CreatedAt: chat.CreatedAt,
-		UpdatedAt: chat.UpdatedAt,
-	}, nil
+	response := toChatResponse(chat)
+	s.hooks.ChatCreated(ctx, response)
+
+	return response, nil
 }
 
 // GetChat retrieves a chat by ID
@@ -78,79 +131,189 @@ func (s *chatService) GetChat(ctx context.Context, id int64) (*dtos.ChatResponse
 		return nil, err
 	}
 
-	return &dtos.ChatResponse{
-		ID:        chat.ID,
-		UserID:    chat.UserID,
-		Title:     chat.Title,
-		CreatedAt: chat.CreatedAt,
-		UpdatedAt: chat.UpdatedAt,
-	}, nil
+	return toChatResponse(chat), nil
+}
+
+// GetOwner returns the user owning a chat and its workspace
+func (s *chatService) GetOwner(ctx context.Context, id int64) (*dtos.ChatOwner, error) {
+	load := func(ctx context.Context) (*dtos.ChatOwner, error) {
+		chat, err := s.chatRepo.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &dtos.ChatOwner{UserID: chat.UserID, WorkspaceID: chat.WorkspaceID}, nil
+	}
+	if s.owners == nil {
+		return load(ctx)
+	}
+	return s.owners.Get(ctx, id, load)
 }
 
 // ListChats lists all chats for a user
-func (s *chatService) ListChats(ctx context.Context, userID string, limit, offset int) (*dtos.ListChatsResponse, error) {
+func (s *chatService) ListChats(ctx context.Context, userID string, limit, offset int, count bool) (*dtos.ListChatsResponse, error) {
 	log := logger.Context(ctx)
-	log.Debugw("Listing chats", "userID", userID, "limit", limit, "offset", offset)
-
-	if limit <= 0 {
-		limit = 10
+	log.Debugw("Listing chats", "userID", userID, "limit", limit, "offset", offset, "count", count)
+
+	var chats []*models.Chat
+	var page pageInfo
+	if s.recent != nil && s.recent.Covers(limit, offset) {
+		var total int64
+		var err error
+		chats, total, err = s.listRecentChats(ctx, userID, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		page = countedPage(total, offset, len(chats))
+	} else if count {
+		var total int64
+		var err error
+		chats, total, err = s.chatRepo.GetByUserID(ctx, userID, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		page = countedPage(total, offset, len(chats))
+	} else {
+		// Fetch one extra chat to tell whether there is a next page
+		var err error
+		chats, err = s.chatRepo.ListByUserID(ctx, userID, limit+1, offset)
+		if err != nil {
+			return nil, err
+		}
+		page = uncountedPage(offset, len(chats), limit)
+		chats = chats[:min(len(chats), limit)]
 	}
 
-	chats, total, err := s.chatRepo.GetByUserID(ctx, userID, limit, offset)
+	chatResponses, err := s.toChatResponses(ctx, chats)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to response DTOs
-	chatResponses := make([]dtos.ChatResponse, len(chats))
-	for i, chat := range chats {
-		chatResponses[i] = dtos.ChatResponse{
-			ID:        chat.ID,
-			UserID:    chat.UserID,
-			Title:     chat.Title,
-			CreatedAt: chat.CreatedAt,
-			UpdatedAt: chat.UpdatedAt,
+	return &dtos.ListChatsResponse{
+		Chats:          chatResponses,
+		Total:          page.total,
+		HasMore:        page.hasMore,
+		TotalEstimated: page.estimated,
+	}, nil
+}
+
+// listRecentChats returns a page of the chats of a user within the recent chats view, and
+// the number of chats of the user. The view is rebuilt with a single query when it is
+// missing, or when chats it lists are gone.
+func (s *chatService) listRecentChats(ctx context.Context, userID string, limit, offset int) ([]*models.Chat, int64, error) {
+	if ids, total, ok := s.recent.Get(ctx, userID); ok {
+		window := ids[min(offset, len(ids)):min(offset+limit, len(ids))]
+		chats, err := s.chatRepo.ListByIDs(ctx, userID, window)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(chats) == len(window) {
+			metrics.RecentChatsLookups.WithLabelValues("hit").Inc()
+			positions := make(map[int64]int, len(window))
+			for i, id := range window {
+				positions[id] = i
+			}
+			slices.SortFunc(chats, func(a, b *models.Chat) int {
+				return positions[a.ID] - positions[b.ID]
+			})
+			return chats, total, nil
 		}
 	}
 
-	return &dtos.ListChatsResponse{
-		Chats: chatResponses,
-		Total: total,
-	}, nil
+	metrics.RecentChatsLookups.WithLabelValues("miss").Inc()
+	recent, total, err := s.chatRepo.GetByUserID(ctx, userID, s.recent.config.Size, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	ids := make([]int64, len(recent))
+	for i, chat := range recent {
+		ids[i] = chat.ID
+	}
+	s.recent.Set(ctx, userID, ids, total)
+
+	return recent[min(offset, len(recent)):min(offset+limit, len(recent))], total, nil
 }
 
-// SearchChats searches chats by title for a user
+// SearchChats searches chats by title for a user, narrowed by filters and with the facet
+// counts of the matching chats when asked for. Filtered searches are answered by the
+// database, the search index only knowing titles.
 func (s *chatService) SearchChats(ctx context.Context, userID string, req *dtos.SearchChatsRequest) (*dtos.ListChatsResponse, error) {
 	log := logger.Context(ctx)
 	log.Debugw("Searching chats", "userID", userID, "query", req.Query, "limit", req.Limit, "offset", req.Offset)
 
-	if req.Limit <= 0 {
-		req.Limit = 10
+	if req.From != nil && req.To != nil && !req.To.After(*req.From) {
+		return nil, errors.New(errors.ErrInvalidRequest, "to must be after from")
 	}
-
-	chats, total, err := s.chatRepo.Search(ctx, req, userID)
-	if err != nil {
-		return nil, err
+	req.Tag = normalizeTag(req.Tag)
+
+	var chats []*models.Chat
+	var total int64
+	var err error
+	if !req.Filtered() {
+		chats, total, err = s.searchIndex(ctx, userID, req)
+		if err != nil {
+			// The database still answers when the search service does not
+			log.Errorw("Failed to search chats in the search index", "error", err, "userID", userID)
+		}
+	}
+	if s.search == nil || err != nil || req.Filtered() {
+		chats, total, err = s.chatRepo.Search(ctx, req, userID)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Convert to response DTOs
-	chatResponses := make([]dtos.ChatResponse, len(chats))
-	for i, chat := range chats {
-		chatResponses[i] = dtos.ChatResponse{
-			ID:        chat.ID,
-			UserID:    chat.UserID,
-			Title:     chat.Title,
-			CreatedAt: chat.CreatedAt,
-			UpdatedAt: chat.UpdatedAt,
+	var facets *dtos.ChatFacets
+	if req.Facets {
+		facets, err = s.chatRepo.Facets(ctx, req, userID)
+		if err != nil {
+			return nil, err
 		}
 	}
 
+	chatResponses, err := s.toChatResponses(ctx, chats)
+	if err != nil {
+		return nil, err
+	}
+
+	page := countedPage(total, req.Offset, len(chats))
 	return &dtos.ListChatsResponse{
-		Chats: chatResponses,
-		Total: total,
+		Chats:   chatResponses,
+		Total:   page.total,
+		HasMore: page.hasMore,
+		Facets:  facets,
 	}, nil
 }
 
+// searchIndex searches the chats of a user in the search index, when there is one. The index
+// trails the database, so chats deleted since they were indexed are left out of the page.
+func (s *chatService) searchIndex(ctx context.Context, userID string, req *dtos.SearchChatsRequest) ([]*models.Chat, int64, error) {
+	if s.search == nil {
+		return nil, 0, nil
+	}
+
+	ids, total, err := s.search.SearchChats(ctx, userID, req.Query, req.Limit, req.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	chats, err := s.chatRepo.ListByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Keep the ranking of the index
+	byID := make(map[int64]*models.Chat, len(chats))
+	for _, chat := range chats {
+		byID[chat.ID] = chat
+	}
+	ranked := make([]*models.Chat, 0, len(chats))
+	for _, id := range ids {
+		if chat, ok := byID[id]; ok {
+			ranked = append(ranked, chat)
+		}
+	}
+	return ranked, total, nil
+}
+
 // UpdateChat updates a chat
 func (s *chatService) UpdateChat(ctx context.Context, id int64, req *dtos.ChatRequest) (*dtos.ChatResponse, error) {
 	log := logger.Context(ctx)
@@ -161,9 +324,38 @@ func (s *chatService) UpdateChat(ctx context.Context, id int64, req *dtos.ChatRe
 	if err != nil {
 		return nil, err
 	}
+	if err := checkUnlocked(chat); err != nil {
+		return nil, err
+	}
 
 	// Update chat
 	chat.Title = req.Title
+	if req.URLContext != nil {
+		chat.URLContext = *req.URLContext
+	}
+	if req.Suggestions != nil {
+		chat.Suggestions = *req.Suggestions
+	}
+	if req.Language != nil {
+		chat.Language = strings.ToLower(strings.TrimSpace(*req.Language))
+	}
+	if req.Budget != nil {
+		applyChatBudget(chat, req.Budget)
+	}
+	if req.Tags != nil {
+		chat.Tags = joinTags(*req.Tags)
+	}
+	if req.Archived != nil {
+		chat.Archived = *req.Archived
+	}
+	if req.SystemPrompt != nil {
+		chat.SystemPrompt = *req.SystemPrompt
+	}
+
+	// A dry run returns the chat as it would be updated
+	if dryrun.Enabled(ctx) {
+		return toChatResponse(chat), nil
+	}
 
 	// Save to database
 	if err := s.chatRepo.Update(ctx, chat); err != nil {
@@ -171,35 +363,410 @@ func (s *chatService) UpdateChat(ctx context.Context, id int64, req *dtos.ChatRe
 	}
 
 	// Publish event
-	event := &dtos.KafkaMessage[dtos.ChatPayload]{
-		ID:        uuid.New().String(),
-		Event:     models.EventChatUpdated,
-		Timestamp: time.Now().Unix(),
-		Payload: dtos.ChatPayload{
-			ChatID: chat.ID,
-			UserID: chat.UserID,
-			Title:  chat.Title,
-		},
-	}
-
-	if err := s.kafka.PublishChatEvent(ctx, event); err != nil {
+	event := newEvent(ctx, models.EventChatUpdated, dtos.ChatPayload{
+		ChatID: chat.ID,
+		UserID: chat.UserID,
+		Title:  chat.Title,
+	})
+
+	if err := s.events.PublishChatEvent(ctx, event); err != nil {
 		// Just log the error but don't fail the request
 		log.Errorw("Failed to publish chat updated event", "error", err, "chatID", chat.ID)
 	}
 
-	return &dtos.ChatResponse{
-		ID:        chat.ID,
-		UserID:    chat.UserID,
-		Title:     chat.Title,
-		CreatedAt: chat.CreatedAt,
-		UpdatedAt: chat.UpdatedAt,
-	}, nil
+	return toChatResponse(chat), nil
+}
+
+// SetLocked locks or unlocks a chat. Only admins can unlock a chat locked by an admin.
+func (s *chatService) SetLocked(ctx context.Context, id int64, locked, admin bool) (*dtos.ChatResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Setting chat lock", "id", id, "locked", locked, "admin", admin)
+
+	chat, err := s.chatRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Guest chats are removed with their session, so they cannot be frozen
+	if models.IsGuest(chat.UserID) {
+		return nil, errors.New(errors.ErrForbidden, "Guest chats cannot be locked")
+	}
+	if err := checkNotPendingDeletion(chat); err != nil {
+		return nil, err
+	}
+	if !locked && chat.LockedBy == models.ChatLockedByAdmin && !admin {
+		return nil, errors.New(errors.ErrForbidden, "The chat was locked by an administrator")
+	}
+
+	lockedBy := ""
+	if locked {
+		lockedBy = models.ChatLockedByOwner
+		if admin {
+			lockedBy = models.ChatLockedByAdmin
+		}
+	}
+	if err := s.chatRepo.SetLocked(ctx, id, lockedBy); err != nil {
+		return nil, err
+	}
+
+	event := newEvent(ctx, models.EventChatUpdated, dtos.ChatPayload{
+		ChatID: chat.ID,
+		UserID: chat.UserID,
+		Title:  chat.Title,
+	})
+	if err := s.events.PublishChatEvent(ctx, event); err != nil {
+		// Just log the error but don't fail the request
+		log.Errorw("Failed to publish chat updated event", "error", err, "chatID", chat.ID)
+	}
+
+	return s.GetChat(ctx, id)
+}
+
+// TransferChat moves a chat and its messages to another user on behalf of transferredBy
+func (s *chatService) TransferChat(ctx context.Context, id int64, transferredBy string, req *dtos.TransferChatRequest) (*dtos.ChatResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Transferring chat", "id", id, "to", req.UserID, "by", transferredBy)
+
+	// Guest sessions are ephemeral, so chats are never handed to one
+	if models.IsGuest(req.UserID) {
+		return nil, errors.New(errors.ErrInvalidRequest, "Chats cannot be transferred to guests")
+	}
+
+	chat, err := s.chatRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID == req.UserID {
+		return nil, errors.New(errors.ErrInvalidRequest, "The user already owns the chat")
+	}
+	if err := checkNotPendingDeletion(chat); err != nil {
+		return nil, err
+	}
+
+	chat, previousUserID, err := s.chatRepo.Transfer(ctx, id, req.UserID, transferredBy)
+	if err != nil {
+		return nil, err
+	}
+
+	event := newEvent(ctx, models.EventChatTransferred, dtos.ChatPayload{
+		ChatID:         chat.ID,
+		UserID:         chat.UserID,
+		Title:          chat.Title,
+		PreviousUserID: previousUserID,
+	})
+	if err := s.events.PublishChatEvent(ctx, event); err != nil {
+		// Just log the error but don't fail the request
+		log.Errorw("Failed to publish chat transferred event", "error", err, "chatID", chat.ID)
+	}
+
+	log.Infow("Chat transferred", "id", id, "from", previousUserID, "to", chat.UserID, "by", transferredBy)
+	return s.GetChat(ctx, id)
+}
+
+// RequestDeleteChat makes a chat pending deletion for the grace period, during which the
+// deletion can be undone. Without a grace period the chat is deleted at once and nil returned.
+func (s *chatService) RequestDeleteChat(ctx context.Context, id int64) (*dtos.ChatResponse, error) {
+	if s.config.GracePeriod <= 0 {
+		return nil, s.DeleteChat(ctx, id)
+	}
+
+	log := logger.Context(ctx)
+	log.Infow("Requesting chat deletion", "id", id)
+
+	chat, err := s.chatRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return nil, err
+	}
+
+	deleteAt := time.Now().Add(s.config.GracePeriod)
+	if err := s.chatRepo.MarkPendingDelete(ctx, id, deleteAt); err != nil {
+		return nil, err
+	}
+
+	// Published ahead of the deletion, so consumers can hide the chat until it is restored or deleted
+	event := newEvent(ctx, models.EventChatDeleteRequested, dtos.ChatPayload{
+		ChatID:   chat.ID,
+		UserID:   chat.UserID,
+		Title:    chat.Title,
+		DeleteAt: &deleteAt,
+	})
+	if err := s.events.PublishChatEvent(ctx, event); err != nil {
+		// Just log the error but don't fail the request
+		log.Errorw("Failed to publish chat delete requested event", "error", err, "chatID", chat.ID)
+	}
+
+	return s.GetChat(ctx, id)
 }
 
-// DeleteChat deletes a chat
+// UndoDeleteChat restores a chat pending deletion before its grace period ends
+func (s *chatService) UndoDeleteChat(ctx context.Context, id int64) (*dtos.ChatResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Undoing chat deletion", "id", id)
+
+	chat, err := s.chatRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.chatRepo.UndoPendingDelete(ctx, id); err != nil {
+		return nil, err
+	}
+
+	event := newEvent(ctx, models.EventChatDeleteUndone, dtos.ChatPayload{
+		ChatID: chat.ID,
+		UserID: chat.UserID,
+		Title:  chat.Title,
+	})
+	if err := s.events.PublishChatEvent(ctx, event); err != nil {
+		// Just log the error but don't fail the request
+		log.Errorw("Failed to publish chat delete undone event", "error", err, "chatID", chat.ID)
+	}
+
+	return s.GetChat(ctx, id)
+}
+
+// DeleteChat deletes a chat at once
 func (s *chatService) DeleteChat(ctx context.Context, id int64) error {
 	log := logger.Context(ctx)
 	log.Infow("Deleting chat", "id", id)
 
-	return s.chatRepo.Delete(ctx, id)
+	// Get existing chat for the event payload
+	chat, err := s.chatRepo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return err
+	}
+
+	return s.deleteChat(ctx, chat)
+}
+
+// PurgeDeleted deletes the chats pending deletion whose grace period has ended
+func (s *chatService) PurgeDeleted(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	ids, err := s.chatRepo.ListIDsPendingDelete(ctx, time.Now(), chatDeletionBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		chat, err := s.chatRepo.Get(ctx, id)
+		if err == nil {
+			err = s.deleteChat(ctx, chat)
+		}
+		if err != nil {
+			log.Errorw("Failed to delete chat pending deletion", "error", err, "chatID", id)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// PurgeEphemeral deletes the privacy mode chats whose messages all expired
+func (s *chatService) PurgeEphemeral(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+	if s.ephemeral == nil {
+		return 0, nil
+	}
+
+	// Chats younger than the TTL may just have had no message yet
+	before := time.Now().Add(-configs.AppConfig.Privacy.TTL)
+	purged := 0
+	var afterID int64
+	for {
+		ids, err := s.chatRepo.ListEphemeralIDs(ctx, before, afterID, chatDeletionBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, id := range ids {
+			afterID = id
+			live, err := s.ephemeral.Live(ctx, id)
+			if err != nil {
+				log.Errorw("Failed to check privacy mode chat messages", "error", err, "chatID", id)
+				continue
+			}
+			if live {
+				continue
+			}
+
+			chat, err := s.chatRepo.Get(ctx, id)
+			if err == nil {
+				err = s.deleteChat(ctx, chat)
+			}
+			if err != nil {
+				log.Errorw("Failed to delete expired privacy mode chat", "error", err, "chatID", id)
+				continue
+			}
+			purged++
+		}
+
+		if len(ids) < chatDeletionBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// deleteChat deletes a chat with its messages and publishes the deletion
+func (s *chatService) deleteChat(ctx context.Context, chat *models.Chat) error {
+	log := logger.Context(ctx)
+
+	messageIDs, err := s.chatRepo.DeleteWithMessages(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	if chat.Ephemeral && s.ephemeral != nil {
+		if err := s.ephemeral.Forget(ctx, chat.ID); err != nil {
+			// The messages expire anyway
+			log.Errorw("Failed to forget privacy mode chat messages", "error", err, "chatID", chat.ID)
+		}
+	}
+
+	// Publish event
+	event := newEvent(ctx, models.EventChatDeleted, dtos.ChatPayload{
+		ChatID:     chat.ID,
+		UserID:     chat.UserID,
+		Title:      chat.Title,
+		MessageIDs: messageIDs,
+	})
+
+	if err := s.events.PublishChatEvent(ctx, event); err != nil {
+		// Just log the error but don't fail the request
+		log.Errorw("Failed to publish chat deleted event", "error", err, "chatID", chat.ID)
+	}
+	s.hooks.ChatDeleted(ctx, chat.ID)
+
+	return nil
+}
+
+// GetStats sums the activity across a user's chats
+func (s *chatService) GetStats(ctx context.Context, userID string) (*dtos.UserStatsResponse, error) {
+	_, total, err := s.chatRepo.GetByUserID(ctx, userID, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.statsRepo.SumByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dtos.UserStatsResponse{
+		Chats:       total,
+		Messages:    stats.MessageCount,
+		TotalTokens: stats.TotalTokens,
+	}
+	if !stats.LastActivityAt.IsZero() {
+		response.LastActivityAt = &stats.LastActivityAt
+	}
+	return response, nil
+}
+
+// applyChatBudget sets the budget of a request on a chat, keeping its usage
+func applyChatBudget(chat *models.Chat, req *dtos.ChatBudgetRequest) {
+	chat.MaxTokens, chat.MaxCost, chat.BudgetMode = req.MaxTokens, req.MaxCost, req.Mode
+	if !chat.HasBudget() {
+		chat.BudgetMode = ""
+	} else if chat.BudgetMode == "" {
+		chat.BudgetMode = models.ChatBudgetModeReject
+	}
+}
+
+// joinTags normalizes the tags of a chat and joins them into the comma-separated list stored
+// with it, without duplicates
+func joinTags(tags []string) string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return strings.Join(normalized, ",")
+}
+
+// normalizeTag trims and lowercases a tag, so tags match whatever their case
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// toChatResponse converts a chat to its response DTO
+func toChatResponse(chat *models.Chat) *dtos.ChatResponse {
+	return &dtos.ChatResponse{
+		ID:              chat.ID,
+		PublicID:        chat.PublicID,
+		UserID:          chat.UserID,
+		Title:           chat.Title,
+		URLContext:      chat.URLContext,
+		Suggestions:     chat.Suggestions,
+		Language:        chat.Language,
+		Locked:          chat.Locked,
+		LockedBy:        chat.LockedBy,
+		Tags:            chat.TagList(),
+		Archived:        chat.Archived,
+		SystemPrompt:    chat.SystemPrompt,
+		CreatedAt:       chat.CreatedAt,
+		UpdatedAt:       chat.UpdatedAt,
+		PinnedMessageID: chat.PinnedMessageID,
+		Budget:          toChatBudgetResponse(chat),
+		DeleteAt:        chat.DeleteAt,
+		Ephemeral:       chat.Ephemeral,
+	}
+}
+
+// toChatBudgetResponse converts the budget of a chat to its response DTO, nil when the chat
+// has no budget
+func toChatBudgetResponse(chat *models.Chat) *dtos.ChatBudgetResponse {
+	if !chat.HasBudget() {
+		return nil
+	}
+
+	budget := &dtos.ChatBudgetResponse{
+		MaxTokens:  chat.MaxTokens,
+		MaxCost:    chat.MaxCost,
+		Mode:       chat.BudgetMode,
+		UsedTokens: chat.UsedTokens,
+		UsedCost:   chat.UsedCost,
+		Exceeded:   chat.BudgetExceeded(),
+	}
+	if chat.MaxTokens > 0 {
+		remaining := max(chat.MaxTokens-chat.UsedTokens, 0)
+		budget.RemainingTokens = &remaining
+	}
+	if chat.MaxCost > 0 {
+		remaining := max(chat.MaxCost-chat.UsedCost, 0)
+		budget.RemainingCost = &remaining
+	}
+	return budget
+}
+
+// toChatResponses converts chats to response DTOs with their stats from the projection
+func (s *chatService) toChatResponses(ctx context.Context, chats []*models.Chat) ([]dtos.ChatResponse, error) {
+	ids := make([]int64, len(chats))
+	for i, chat := range chats {
+		ids[i] = chat.ID
+	}
+
+	stats, err := s.statsRepo.GetByChatIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.ChatResponse, len(chats))
+	for i, chat := range chats {
+		responses[i] = *toChatResponse(chat)
+		if chatStats, ok := stats[chat.ID]; ok {
+			responses[i].Stats = &dtos.ChatStatsResponse{
+				MessageCount:   chatStats.MessageCount,
+				TotalTokens:    chatStats.TotalTokens,
+				LastActivityAt: chatStats.LastActivityAt,
+			}
+		}
+	}
+	return responses, nil
 }
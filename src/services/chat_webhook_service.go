@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// ChatWebhookService defines the interface for the webhooks of chats, which receive each
+// completed exchange of their chat
+type ChatWebhookService interface {
+	// CreateWebhook attaches a webhook to a chat owned by the user
+	CreateWebhook(ctx context.Context, userID string, chatID int64, req *dtos.ChatWebhookRequest) (*dtos.ChatWebhookResponse, error)
+
+	// ListWebhooks lists the webhooks of a chat owned by the user
+	ListWebhooks(ctx context.Context, userID string, chatID int64) (*dtos.ListChatWebhooksResponse, error)
+
+	// DeleteWebhook removes a webhook of a chat owned by the user, dropping its pending
+	// deliveries
+	DeleteWebhook(ctx context.Context, userID string, chatID, webhookID int64) error
+
+	// DeliverPending posts the exchanges whose next attempt is due, and returns how many
+	// were delivered
+	DeliverPending(ctx context.Context) (int, error)
+
+	// Events returns the event bus subscriber queuing the exchanges completed by replies for
+	// the webhooks of their chat
+	Events() EventPublisher
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// chatWebhookExchangeEvent is the event of the exchanges posted to chat webhooks
+const chatWebhookExchangeEvent = "exchange.completed"
+
+// exchangeLookback is how many of the latest messages of a chat are searched for the user
+// message a reply answers
+const exchangeLookback = 10
+
+// chatWebhookService implements the ChatWebhookService interface
+type chatWebhookService struct {
+	config      configs.ChatWebhooks
+	webhookRepo repositories.ChatWebhookRepository
+	chatRepo    repositories.ChatRepository
+	messageRepo repositories.MessageRepository
+	webhook     adapters.WebhookAdapter
+}
+
+// NewChatWebhookService creates a new chat webhook service, posting through webhook
+func NewChatWebhookService(
+	config configs.ChatWebhooks,
+	webhookRepo repositories.ChatWebhookRepository,
+	chatRepo repositories.ChatRepository,
+	messageRepo repositories.MessageRepository,
+	webhook adapters.WebhookAdapter,
+) ChatWebhookService {
+	return &chatWebhookService{
+		config:      config,
+		webhookRepo: webhookRepo,
+		chatRepo:    chatRepo,
+		messageRepo: messageRepo,
+		webhook:     webhook,
+	}
+}
+
+// CreateWebhook attaches a webhook to a chat owned by the user. The secret signing its
+// deliveries is returned only now.
+func (s *chatWebhookService) CreateWebhook(ctx context.Context, userID string, chatID int64, req *dtos.ChatWebhookRequest) (*dtos.ChatWebhookResponse, error) {
+	log := logger.Context(ctx)
+
+	if !s.config.Enabled {
+		return nil, errors.New(errors.ErrInvalidRequest, "Chat webhooks are not enabled")
+	}
+	chat, err := s.getOwned(ctx, userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	// Deliveries are kept in the database until they are posted
+	if chat.Ephemeral {
+		return nil, errors.New(errors.ErrInvalidRequest, "Privacy mode chats cannot have webhooks")
+	}
+	if err := s.webhook.CheckURL(req.URL); err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidRequest, "Webhook URL is not allowed")
+	}
+
+	count, err := s.webhookRepo.CountByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= int64(s.config.MaxPerChat) {
+		return nil, errors.New(errors.ErrInvalidRequest, "Chat has too many webhooks")
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, errors.Wrap(err, errors.ErrInternal, "Failed to generate webhook secret")
+		}
+		secret = hex.EncodeToString(key)
+	}
+
+	webhook := &models.ChatWebhook{ChatID: chatID, URL: req.URL, Secret: secret}
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+	log.Infow("Chat webhook created", "chatID", chatID, "webhookID", webhook.ID)
+
+	response := toChatWebhookResponse(webhook)
+	response.Secret = secret
+	return response, nil
+}
+
+// ListWebhooks lists the webhooks of a chat owned by the user
+func (s *chatWebhookService) ListWebhooks(ctx context.Context, userID string, chatID int64) (*dtos.ListChatWebhooksResponse, error) {
+	if _, err := s.getOwned(ctx, userID, chatID); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.webhookRepo.ListByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.ChatWebhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		responses[i] = *toChatWebhookResponse(webhook)
+	}
+
+	return &dtos.ListChatWebhooksResponse{Webhooks: responses}, nil
+}
+
+// DeleteWebhook removes a webhook of a chat owned by the user, dropping its pending deliveries
+func (s *chatWebhookService) DeleteWebhook(ctx context.Context, userID string, chatID, webhookID int64) error {
+	if _, err := s.getOwned(ctx, userID, chatID); err != nil {
+		return err
+	}
+
+	return s.webhookRepo.Delete(ctx, chatID, webhookID)
+}
+
+// DeliverPending posts the exchanges whose next attempt is due, up to BatchSize, and returns
+// how many were delivered. A failed attempt is retried after a backoff doubling each time,
+// until MaxAttempts; the exchange is then given up and the error kept on the webhook.
+func (s *chatWebhookService) DeliverPending(ctx context.Context) (int, error) {
+	deliveries, err := s.webhookRepo.ListDueDeliveries(ctx, time.Now(), s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		ok, err := s.deliver(ctx, delivery)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+// Events returns the event bus subscriber queuing the exchanges completed by replies for the
+// webhooks of their chat. Exchanges are queued and first posted in the background, so that
+// replies do not wait on them.
+func (s *chatWebhookService) Events() EventPublisher {
+	return EventHandlers{
+		Message: func(ctx context.Context, message *dtos.KafkaMessage[dtos.MessagePayload]) error {
+			payload := message.Payload
+			if message.Event != models.EventMessageCreated || payload.Role != models.MessageRoleAssistant || payload.Kind != "" {
+				return nil
+			}
+			// The messages of privacy mode chats are never kept in the database
+			if models.IsEphemeralMessageID(payload.MessageID) {
+				return nil
+			}
+
+			go func() {
+				ctx := context.WithoutCancel(ctx)
+				if err := s.enqueue(ctx, payload.ChatID, payload.MessageID); err != nil {
+					logger.Context(ctx).Errorw("Failed to queue chat webhook deliveries", "error", err, "messageID", payload.MessageID)
+				}
+			}()
+			return nil
+		},
+	}
+}
+
+// enqueue queues the exchange completed by a reply for each webhook of its chat, then makes
+// the first attempts. The next attempt is scheduled ahead, so that the job does not post the
+// exchanges again while they are being posted.
+func (s *chatWebhookService) enqueue(ctx context.Context, chatID, replyID int64) error {
+	webhooks, err := s.webhookRepo.ListByChatID(ctx, chatID)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	reply, err := s.messageRepo.Get(ctx, replyID)
+	if err != nil {
+		return err
+	}
+	recent, err := s.messageRepo.GetRecent(ctx, chatID, 0, exchangeLookback)
+	if err != nil {
+		return err
+	}
+	var userMessage *dtos.MessageResponse
+	for i := len(recent) - 1; i >= 0; i-- {
+		if recent[i].SeqNo < reply.SeqNo && recent[i].Role == models.MessageRoleUser {
+			userMessage = toMessageResponse(recent[i])
+			break
+		}
+	}
+
+	exchange := dtos.ChatWebhookExchange{
+		Event:       chatWebhookExchangeEvent,
+		ChatID:      chatID,
+		UserMessage: userMessage,
+		Reply:       *toMessageResponse(reply),
+		Usage: dtos.ChatWebhookUsage{
+			Model:       reply.Model,
+			TotalTokens: reply.TotalTokens,
+			LatencyMs:   reply.LatencyMs,
+		},
+		CompletedAt: reply.CreatedAt,
+	}
+	nextAttemptAt := time.Now().Add(s.config.RetryBackoff)
+	deliveries := make([]*models.ChatWebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		exchange.ID = uuid.NewString()
+		body, err := json.Marshal(&exchange)
+		if err != nil {
+			return err
+		}
+		deliveries[i] = &models.ChatWebhookDelivery{
+			WebhookID:     webhook.ID,
+			Webhook:       *webhook,
+			MessageID:     replyID,
+			Body:          string(body),
+			NextAttemptAt: nextAttemptAt,
+		}
+	}
+	if err := s.webhookRepo.CreateDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if _, err := s.deliver(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver makes an attempt of a delivery, reporting whether the exchange was delivered.
+// Errors are those of recording the attempt; failed posts are rescheduled or given up.
+func (s *chatWebhookService) deliver(ctx context.Context, delivery *models.ChatWebhookDelivery) (bool, error) {
+	log := logger.Context(ctx)
+	webhook := &delivery.Webhook
+
+	postCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	err := s.webhook.Post(postCtx, webhook.URL, webhook.Secret, []byte(delivery.Body))
+	cancel()
+
+	if err == nil {
+		now := time.Now()
+		if err := s.webhookRepo.RecordResult(ctx, webhook.ID, &now, webhook.LastError); err != nil {
+			return false, err
+		}
+		return true, s.webhookRepo.DeleteDelivery(ctx, delivery.ID)
+	}
+
+	attempts := delivery.Attempts + 1
+	if attempts >= s.config.MaxAttempts {
+		log.Warnw("Chat webhook delivery given up", "error", err, "webhookID", webhook.ID, "messageID", delivery.MessageID, "attempts", attempts)
+		if err := s.webhookRepo.RecordResult(ctx, webhook.ID, nil, err.Error()); err != nil {
+			return false, err
+		}
+		return false, s.webhookRepo.DeleteDelivery(ctx, delivery.ID)
+	}
+
+	log.Infow("Chat webhook delivery failed, retrying later", "error", err, "webhookID", webhook.ID, "messageID", delivery.MessageID, "attempts", attempts)
+	backoff := s.config.RetryBackoff << (attempts - 1)
+	return false, s.webhookRepo.Reschedule(ctx, delivery.ID, attempts, time.Now().Add(backoff))
+}
+
+// getOwned returns a chat, or a forbidden error when the user does not own it
+func (s *chatWebhookService) getOwned(ctx context.Context, userID string, chatID int64) (*models.Chat, error) {
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat.UserID != userID {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+	return chat, nil
+}
+
+// toChatWebhookResponse converts a chat webhook model to its response DTO, without its secret
+func toChatWebhookResponse(webhook *models.ChatWebhook) *dtos.ChatWebhookResponse {
+	return &dtos.ChatWebhookResponse{
+		ID:              webhook.ID,
+		ChatID:          webhook.ChatID,
+		URL:             webhook.URL,
+		LastDeliveredAt: webhook.LastDeliveredAt,
+		LastError:       webhook.LastError,
+		CreatedAt:       webhook.CreatedAt,
+	}
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// CheckpointService defines the interface for managing the checkpoints of chats
+type CheckpointService interface {
+	// CreateCheckpoint records the current end of a chat owned by the user under a name
+	CreateCheckpoint(ctx context.Context, userID string, chatID int64, req *dtos.CheckpointRequest) (*dtos.CheckpointResponse, error)
+
+	// ListCheckpoints lists the checkpoints of a chat owned by the user, newest first
+	ListCheckpoints(ctx context.Context, userID string, chatID int64) (*dtos.ListCheckpointsResponse, error)
+
+	// RestoreCheckpoint copies the messages of a chat owned by the user, as they were at a
+	// checkpoint, into a new chat; the chat itself is left unchanged
+	RestoreCheckpoint(ctx context.Context, userID string, chatID, checkpointID int64, req *dtos.RestoreCheckpointRequest) (*dtos.RestoreCheckpointResponse, error)
+
+	// DeleteCheckpoint removes a checkpoint of a chat owned by the user
+	DeleteCheckpoint(ctx context.Context, userID string, chatID, checkpointID int64) error
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// maxCheckpointsPerChat bounds the checkpoints of a chat
+const maxCheckpointsPerChat = 50
+
+// checkpointService implements the CheckpointService interface
+type checkpointService struct {
+	checkpointRepo repositories.CheckpointRepository
+	chatRepo       repositories.ChatRepository
+	messageRepo    repositories.MessageRepository
+	chatService    ChatService
+	events         EventPublisher
+}
+
+// NewCheckpointService creates a new checkpoint service. Restored chats are created through
+// the chat service, so they publish the same events as other new chats.
+func NewCheckpointService(checkpointRepo repositories.CheckpointRepository, chatRepo repositories.ChatRepository, messageRepo repositories.MessageRepository, chatService ChatService, events EventPublisher) CheckpointService {
+	return &checkpointService{
+		checkpointRepo: checkpointRepo,
+		chatRepo:       chatRepo,
+		messageRepo:    messageRepo,
+		chatService:    chatService,
+		events:         events,
+	}
+}
+
+// CreateCheckpoint records the current end of a chat owned by the user under a name
+func (s *checkpointService) CreateCheckpoint(ctx context.Context, userID string, chatID int64, req *dtos.CheckpointRequest) (*dtos.CheckpointResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Creating checkpoint", "chatID", chatID, "name", req.Name)
+
+	chat, err := s.getOwned(ctx, userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.checkpointRepo.CountByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxCheckpointsPerChat {
+		return nil, errors.New(errors.ErrInvalidRequest, "Chat has too many checkpoints")
+	}
+
+	_, messageCount, err := s.messageRepo.GetByChatID(ctx, chatID, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &models.Checkpoint{
+		ChatID:       chatID,
+		Name:         req.Name,
+		SeqNo:        chat.LastSeqNo,
+		MessageCount: messageCount,
+	}
+	if err := s.checkpointRepo.Create(ctx, checkpoint); err != nil {
+		return nil, err
+	}
+
+	return toCheckpointResponse(checkpoint), nil
+}
+
+// ListCheckpoints lists the checkpoints of a chat owned by the user, newest first
+func (s *checkpointService) ListCheckpoints(ctx context.Context, userID string, chatID int64) (*dtos.ListCheckpointsResponse, error) {
+	if _, err := s.getOwned(ctx, userID, chatID); err != nil {
+		return nil, err
+	}
+
+	checkpoints, err := s.checkpointRepo.ListByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.CheckpointResponse, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		responses[i] = *toCheckpointResponse(checkpoint)
+	}
+
+	return &dtos.ListCheckpointsResponse{Checkpoints: responses}, nil
+}
+
+// RestoreCheckpoint copies the messages of a chat owned by the user, as they were at a
+// checkpoint, into a new chat with the settings of the chat. Messages edited since the
+// checkpoint get their content of that time; messages deleted since are lost.
+func (s *checkpointService) RestoreCheckpoint(ctx context.Context, userID string, chatID, checkpointID int64, req *dtos.RestoreCheckpointRequest) (*dtos.RestoreCheckpointResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Restoring checkpoint", "chatID", chatID, "checkpointID", checkpointID)
+
+	chat, err := s.getOwned(ctx, userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := s.checkpointRepo.Get(ctx, chatID, checkpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.messageRepo.ListUpToSeqNo(ctx, chatID, checkpoint.SeqNo)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := s.messageRepo.ListRevisionsAfter(ctx, chatID, checkpoint.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	title := req.Title
+	if title == "" {
+		title = chat.Title + " (" + checkpoint.Name + ")"
+	}
+	tags := chat.TagList()
+	created, err := s.chatService.CreateChat(ctx, userID, &dtos.ChatRequest{
+		Title:       title,
+		URLContext:  &chat.URLContext,
+		Suggestions: &chat.Suggestions,
+		Language:    &chat.Language,
+		Budget: &dtos.ChatBudgetRequest{
+			MaxTokens: chat.MaxTokens,
+			MaxCost:   chat.MaxCost,
+			Mode:      chat.BudgetMode,
+		},
+		Tags:         &tags,
+		SystemPrompt: &chat.SystemPrompt,
+		Ephemeral:    chat.Ephemeral,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	copies := make([]*models.Message, 0, len(messages))
+	for _, message := range messages {
+		// Replies still being generated or transcribed are left out
+		if message.Status != "" {
+			continue
+		}
+		content, editedAt := message.Content, message.EditedAt
+		if revision, ok := revisions[message.ID]; ok {
+			content, editedAt = revision.Content, nil
+		}
+		copies = append(copies, &models.Message{
+			ChatID:            created.ID,
+			UserID:            message.UserID,
+			Role:              message.Role,
+			Content:           content,
+			ContentType:       message.ContentType,
+			Kind:              message.Kind,
+			Name:              message.Name,
+			ToolCallID:        message.ToolCallID,
+			Model:             message.Model,
+			Provider:          message.Provider,
+			ModelVersion:      message.ModelVersion,
+			ExcludeFromPrompt: message.ExcludeFromPrompt,
+			TotalTokens:       message.TotalTokens,
+			LatencyMs:         message.LatencyMs,
+			Language:          message.Language,
+			Suggestions:       message.Suggestions,
+			Translation:       message.Translation,
+			EditedAt:          editedAt,
+		})
+	}
+
+	if len(copies) > 0 {
+		if err := s.messageRepo.CreateBatch(ctx, copies); err != nil {
+			if deleteErr := s.chatRepo.Delete(ctx, created.ID); deleteErr != nil {
+				log.Errorw("Failed to delete the chat of a failed restore", "error", deleteErr, "chatID", created.ID)
+			}
+			return nil, err
+		}
+	}
+
+	for _, message := range copies {
+		event := newEvent(ctx, models.EventMessageCreated, dtos.MessagePayload{
+			MessageID:        message.ID,
+			SeqNo:            message.SeqNo,
+			ChatID:           message.ChatID,
+			UserID:           message.UserID,
+			Role:             message.Role,
+			Content:          eventContent(message),
+			ContentType:      message.ContentType,
+			ContentTruncated: message.ContentKey != "",
+			ContentHash:      eventContentHash(message),
+			Model:            message.Model,
+		})
+		if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+			log.Errorw("Failed to publish message event", "error", err, "messageID", message.ID)
+		}
+	}
+
+	log.Infow("Checkpoint restored", "chatID", chatID, "checkpointID", checkpointID, "newChatID", created.ID, "count", len(copies))
+	return &dtos.RestoreCheckpointResponse{
+		Chat:         *created,
+		CheckpointID: checkpoint.ID,
+		MessageCount: len(copies),
+	}, nil
+}
+
+// DeleteCheckpoint removes a checkpoint of a chat owned by the user
+func (s *checkpointService) DeleteCheckpoint(ctx context.Context, userID string, chatID, checkpointID int64) error {
+	log := logger.Context(ctx)
+	log.Infow("Deleting checkpoint", "chatID", chatID, "checkpointID", checkpointID)
+
+	if _, err := s.getOwned(ctx, userID, chatID); err != nil {
+		return err
+	}
+
+	return s.checkpointRepo.Delete(ctx, chatID, checkpointID)
+}
+
+// getOwned returns a chat after verifying that the user owns it
+func (s *checkpointService) getOwned(ctx context.Context, userID string, chatID int64) (*models.Chat, error) {
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat.UserID != userID {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+	return chat, nil
+}
+
+// toCheckpointResponse converts a checkpoint model to its response DTO
+func toCheckpointResponse(checkpoint *models.Checkpoint) *dtos.CheckpointResponse {
+	return &dtos.CheckpointResponse{
+		ID:           checkpoint.ID,
+		ChatID:       checkpoint.ChatID,
+		Name:         checkpoint.Name,
+		SeqNo:        checkpoint.SeqNo,
+		MessageCount: checkpoint.MessageCount,
+		CreatedAt:    checkpoint.CreatedAt,
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// chunkText splits text into chunks of at most size characters, consecutive chunks sharing
+// overlap characters. Chunks end at a whitespace when one is in their last fifth, so words
+// are rarely cut.
+func chunkText(text string, size, overlap int) []string {
+	runes := []rune(strings.TrimSpace(text))
+	var chunks []string
+
+	for start := 0; start < len(runes); {
+		end := min(start+size, len(runes))
+		if end < len(runes) {
+			for i := end; i > start+size*4/5; i-- {
+				if unicode.IsSpace(runes[i-1]) {
+					end = i
+					break
+				}
+			}
+		}
+
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+		// Always move forward, even when the chunk is shorter than the overlap
+		start = max(end-overlap, start+1)
+	}
+
+	return chunks
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// codeBlock is a fenced code block of a Markdown message
+type codeBlock struct {
+	Language string
+	Content  string
+}
+
+// extractCodeBlocks returns the fenced code blocks of a Markdown message in order.
+// Fences are runs of at least three backticks or tildes, closed by a run of the same
+// character at least as long; an unclosed block runs to the end of the message.
+func extractCodeBlocks(content string) []codeBlock {
+	var blocks []codeBlock
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		fence, info, ok := openingFence(lines[i])
+		if !ok {
+			continue
+		}
+
+		var body []string
+		for i++; i < len(lines); i++ {
+			if isClosingFence(lines[i], fence) {
+				break
+			}
+			body = append(body, lines[i])
+		}
+
+		blocks = append(blocks, codeBlock{
+			Language: strings.ToLower(strings.Fields(info + " ")[0]),
+			Content:  strings.Join(body, "\n"),
+		})
+	}
+
+	return blocks
+}
+
+// openingFence parses a line opening a code block, returning its fence and info string
+func openingFence(line string) (string, string, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return "", "", false
+	}
+
+	fence := fenceRun(trimmed)
+	if len(fence) < 3 {
+		return "", "", false
+	}
+
+	info := strings.TrimSpace(trimmed[len(fence):])
+	// Backtick fences cannot have backticks in their info string
+	if fence[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+	return fence, info, true
+}
+
+// isClosingFence reports whether a line closes a code block opened with fence
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	run := fenceRun(trimmed)
+	return len(run) >= len(fence) && run[0] == fence[0] && len(run) == len(trimmed)
+}
+
+// fenceRun returns the leading run of backticks or tildes of a line
+func fenceRun(line string) string {
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+	end := 1
+	for end < len(line) && line[end] == line[0] {
+		end++
+	}
+	return line[:end]
+}
+
+// artifactExtensions maps code block languages to file extensions
+var artifactExtensions = map[string]string{
+	"bash":       "sh",
+	"c":          "c",
+	"cpp":        "cpp",
+	"csharp":     "cs",
+	"css":        "css",
+	"go":         "go",
+	"html":       "html",
+	"java":       "java",
+	"javascript": "js",
+	"js":         "js",
+	"json":       "json",
+	"kotlin":     "kt",
+	"markdown":   "md",
+	"php":        "php",
+	"python":     "py",
+	"py":         "py",
+	"ruby":       "rb",
+	"rust":       "rs",
+	"sh":         "sh",
+	"shell":      "sh",
+	"sql":        "sql",
+	"swift":      "swift",
+	"ts":         "ts",
+	"typescript": "ts",
+	"xml":        "xml",
+	"yaml":       "yaml",
+	"yml":        "yaml",
+}
+
+// artifactFilename returns the download filename of an artifact
+func artifactFilename(artifact *models.Artifact) string {
+	ext, ok := artifactExtensions[artifact.Language]
+	if !ok {
+		ext = "txt"
+	}
+	return fmt.Sprintf("%d-%d.%s", artifact.MessageID, artifact.Index, ext)
+}
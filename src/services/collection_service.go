@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// CollectionService defines the interface for the document collections of users, their
+// documents and the chats they are attached to
+type CollectionService interface {
+	// CreateCollection creates a collection of the user
+	CreateCollection(ctx context.Context, userID string, req *dtos.CollectionRequest) (*dtos.CollectionResponse, error)
+
+	// ListCollections lists the collections of the user
+	ListCollections(ctx context.Context, userID string) (*dtos.ListCollectionsResponse, error)
+
+	// GetCollection retrieves a collection of the user
+	GetCollection(ctx context.Context, userID string, collectionID int64) (*dtos.CollectionResponse, error)
+
+	// UpdateCollection updates a collection of the user; changing its chunking re-indexes
+	// its documents
+	UpdateCollection(ctx context.Context, userID string, collectionID int64, req *dtos.CollectionRequest) (*dtos.CollectionResponse, error)
+
+	// DeleteCollection deletes a collection of the user with its documents
+	DeleteCollection(ctx context.Context, userID string, collectionID int64) error
+
+	// AddDocument adds a document to a collection of the user; it is chunked in the background
+	AddDocument(ctx context.Context, userID string, collectionID int64, req *dtos.DocumentRequest) (*dtos.DocumentResponse, error)
+
+	// ListDocuments lists the documents of a collection of the user with their processing status
+	ListDocuments(ctx context.Context, userID string, collectionID int64) (*dtos.ListDocumentsResponse, error)
+
+	// GetDocument retrieves a document of a collection of the user with its content
+	GetDocument(ctx context.Context, userID string, collectionID, documentID int64) (*dtos.DocumentResponse, error)
+
+	// DeleteDocument deletes a document of a collection of the user
+	DeleteDocument(ctx context.Context, userID string, collectionID, documentID int64) error
+
+	// Reindex queues the documents of a collection of the user to be chunked again
+	Reindex(ctx context.Context, userID string, collectionID int64) (*dtos.ReindexResponse, error)
+
+	// AttachCollection attaches a collection of the user to a chat of the user
+	AttachCollection(ctx context.Context, userID string, chatID, collectionID int64) error
+
+	// DetachCollection detaches a collection from a chat of the user
+	DetachCollection(ctx context.Context, userID string, chatID, collectionID int64) error
+
+	// ListChatCollections lists the collections attached to a chat of the user
+	ListChatCollections(ctx context.Context, userID string, chatID int64) (*dtos.ListCollectionsResponse, error)
+
+	// ProcessPending chunks the pending documents and returns the number processed
+	ProcessPending(ctx context.Context) (int, error)
+}
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// collectionService implements the CollectionService interface
+type collectionService struct {
+	config         configs.Collections
+	collectionRepo repositories.CollectionRepository
+	documentRepo   repositories.DocumentRepository
+	chatRepo       repositories.ChatRepository
+}
+
+// NewCollectionService creates a new document collection service
+func NewCollectionService(
+	config configs.Collections,
+	collectionRepo repositories.CollectionRepository,
+	documentRepo repositories.DocumentRepository,
+	chatRepo repositories.ChatRepository,
+) CollectionService {
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = 1000
+	}
+	if config.MaxChunkSize < config.ChunkSize {
+		config.MaxChunkSize = config.ChunkSize
+	}
+	if config.ChunkOverlap < 0 || config.ChunkOverlap >= config.ChunkSize {
+		config.ChunkOverlap = 0
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 10
+	}
+
+	return &collectionService{
+		config:         config,
+		collectionRepo: collectionRepo,
+		documentRepo:   documentRepo,
+		chatRepo:       chatRepo,
+	}
+}
+
+// CreateCollection creates a collection of the user
+func (s *collectionService) CreateCollection(ctx context.Context, userID string, req *dtos.CollectionRequest) (*dtos.CollectionResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Creating collection", "userID", userID)
+
+	if models.IsGuest(userID) {
+		return nil, errors.New(errors.ErrForbidden, "Collections are not available to guests")
+	}
+
+	collection := &models.Collection{
+		UserID:       userID,
+		Name:         req.Name,
+		Description:  req.Description,
+		ChunkSize:    s.config.ChunkSize,
+		ChunkOverlap: s.config.ChunkOverlap,
+	}
+	if err := s.applyChunking(collection, req); err != nil {
+		return nil, err
+	}
+	if err := s.collectionRepo.Create(ctx, collection); err != nil {
+		return nil, err
+	}
+
+	return toCollectionResponse(collection), nil
+}
+
+// ListCollections lists the collections of the user
+func (s *collectionService) ListCollections(ctx context.Context, userID string) (*dtos.ListCollectionsResponse, error) {
+	collections, err := s.collectionRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toListCollectionsResponse(collections), nil
+}
+
+// GetCollection retrieves a collection of the user
+func (s *collectionService) GetCollection(ctx context.Context, userID string, collectionID int64) (*dtos.CollectionResponse, error) {
+	collection, err := s.ownedCollection(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toCollectionResponse(collection), nil
+}
+
+// UpdateCollection updates a collection of the user. Changing its chunking re-indexes its
+// documents, so their chunks all follow the chunking of the collection.
+func (s *collectionService) UpdateCollection(ctx context.Context, userID string, collectionID int64, req *dtos.CollectionRequest) (*dtos.CollectionResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Updating collection", "collectionID", collectionID)
+
+	collection, err := s.ownedCollection(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkSize, chunkOverlap := collection.ChunkSize, collection.ChunkOverlap
+	collection.Name = req.Name
+	collection.Description = req.Description
+	if err := s.applyChunking(collection, req); err != nil {
+		return nil, err
+	}
+	if err := s.collectionRepo.Update(ctx, collection); err != nil {
+		return nil, err
+	}
+
+	if collection.ChunkSize != chunkSize || collection.ChunkOverlap != chunkOverlap {
+		count, err := s.documentRepo.Reindex(ctx, collection.ID)
+		if err != nil {
+			return nil, err
+		}
+		log.Infow("Chunking changed, re-indexing documents", "collectionID", collection.ID, "documents", count)
+	}
+
+	return toCollectionResponse(collection), nil
+}
+
+// DeleteCollection deletes a collection of the user with its documents
+func (s *collectionService) DeleteCollection(ctx context.Context, userID string, collectionID int64) error {
+	log := logger.Context(ctx)
+	log.Infow("Deleting collection", "collectionID", collectionID)
+
+	if _, err := s.ownedCollection(ctx, userID, collectionID); err != nil {
+		return err
+	}
+
+	return s.collectionRepo.Delete(ctx, collectionID)
+}
+
+// AddDocument adds a document to a collection of the user; it is chunked in the background
+func (s *collectionService) AddDocument(ctx context.Context, userID string, collectionID int64, req *dtos.DocumentRequest) (*dtos.DocumentResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Adding document", "collectionID", collectionID)
+
+	if _, err := s.ownedCollection(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+	if s.config.MaxDocumentSize > 0 && len(req.Content) > s.config.MaxDocumentSize {
+		return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Document content is larger than %d bytes", s.config.MaxDocumentSize))
+	}
+
+	document := &models.Document{
+		CollectionID: collectionID,
+		Title:        req.Title,
+		Source:       req.Source,
+		Content:      req.Content,
+		Status:       models.DocumentStatusPending,
+	}
+	if err := s.documentRepo.Create(ctx, document); err != nil {
+		return nil, err
+	}
+
+	return toDocumentResponse(document), nil
+}
+
+// ListDocuments lists the documents of a collection of the user with their processing status
+func (s *collectionService) ListDocuments(ctx context.Context, userID string, collectionID int64) (*dtos.ListDocumentsResponse, error) {
+	if _, err := s.ownedCollection(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+
+	documents, err := s.documentRepo.ListByCollectionID(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.DocumentResponse, len(documents))
+	for i, document := range documents {
+		responses[i] = *toDocumentResponse(document)
+	}
+
+	return &dtos.ListDocumentsResponse{Documents: responses}, nil
+}
+
+// GetDocument retrieves a document of a collection of the user with its content
+func (s *collectionService) GetDocument(ctx context.Context, userID string, collectionID, documentID int64) (*dtos.DocumentResponse, error) {
+	if _, err := s.ownedCollection(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+
+	document, err := s.documentRepo.Get(ctx, collectionID, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDocumentResponse(document), nil
+}
+
+// DeleteDocument deletes a document of a collection of the user
+func (s *collectionService) DeleteDocument(ctx context.Context, userID string, collectionID, documentID int64) error {
+	log := logger.Context(ctx)
+	log.Infow("Deleting document", "collectionID", collectionID, "documentID", documentID)
+
+	if _, err := s.ownedCollection(ctx, userID, collectionID); err != nil {
+		return err
+	}
+
+	return s.documentRepo.Delete(ctx, collectionID, documentID)
+}
+
+// Reindex queues the documents of a collection of the user to be chunked again
+func (s *collectionService) Reindex(ctx context.Context, userID string, collectionID int64) (*dtos.ReindexResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Re-indexing collection", "collectionID", collectionID)
+
+	if _, err := s.ownedCollection(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+
+	count, err := s.documentRepo.Reindex(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.ReindexResponse{Documents: count}, nil
+}
+
+// AttachCollection attaches a collection of the user to a chat of the user
+func (s *collectionService) AttachCollection(ctx context.Context, userID string, chatID, collectionID int64) error {
+	log := logger.Context(ctx)
+	log.Infow("Attaching collection", "chatID", chatID, "collectionID", collectionID)
+
+	if err := s.checkChat(ctx, userID, chatID); err != nil {
+		return err
+	}
+	if _, err := s.ownedCollection(ctx, userID, collectionID); err != nil {
+		return err
+	}
+
+	return s.collectionRepo.Attach(ctx, chatID, collectionID)
+}
+
+// DetachCollection detaches a collection from a chat of the user
+func (s *collectionService) DetachCollection(ctx context.Context, userID string, chatID, collectionID int64) error {
+	log := logger.Context(ctx)
+	log.Infow("Detaching collection", "chatID", chatID, "collectionID", collectionID)
+
+	if err := s.checkChat(ctx, userID, chatID); err != nil {
+		return err
+	}
+
+	return s.collectionRepo.Detach(ctx, chatID, collectionID)
+}
+
+// ListChatCollections lists the collections attached to a chat of the user
+func (s *collectionService) ListChatCollections(ctx context.Context, userID string, chatID int64) (*dtos.ListCollectionsResponse, error) {
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID != userID {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+
+	collections, err := s.collectionRepo.ListByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toListCollectionsResponse(collections), nil
+}
+
+// ProcessPending chunks the pending documents with the chunking of their collection and
+// returns the number processed
+func (s *collectionService) ProcessPending(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	documents, err := s.documentRepo.ListPending(ctx, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	collections := make(map[int64]*models.Collection)
+	processed := 0
+	for _, document := range documents {
+		collection, ok := collections[document.CollectionID]
+		if !ok {
+			collection, err = s.collectionRepo.Get(ctx, document.CollectionID)
+			if err != nil {
+				// The collection may have been deleted with the document meanwhile
+				log.Warnw("Failed to get collection of document", "error", err, "documentID", document.ID)
+				continue
+			}
+			collections[collection.ID] = collection
+		}
+
+		texts := chunkText(document.Content, collection.ChunkSize, collection.ChunkOverlap)
+		if len(texts) == 0 {
+			if err := s.documentRepo.MarkFailed(ctx, document.ID, "Document has no text"); err != nil {
+				return processed, err
+			}
+			processed++
+			continue
+		}
+
+		chunks := make([]*models.DocumentChunk, len(texts))
+		for i, text := range texts {
+			chunks[i] = &models.DocumentChunk{
+				DocumentID:   document.ID,
+				CollectionID: document.CollectionID,
+				Position:     i,
+				Content:      text,
+			}
+		}
+		if err := s.documentRepo.SaveChunks(ctx, document, chunks); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// applyChunking sets the chunking of a request on a collection, keeping the current values
+// of the fields left out
+func (s *collectionService) applyChunking(collection *models.Collection, req *dtos.CollectionRequest) error {
+	if req.ChunkSize != nil {
+		collection.ChunkSize = *req.ChunkSize
+	}
+	if req.ChunkOverlap != nil {
+		collection.ChunkOverlap = *req.ChunkOverlap
+	}
+
+	if collection.ChunkSize > s.config.MaxChunkSize {
+		return errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Chunk size must be at most %d", s.config.MaxChunkSize))
+	}
+	if collection.ChunkOverlap >= collection.ChunkSize {
+		return errors.New(errors.ErrInvalidRequest, "Chunk overlap must be smaller than the chunk size")
+	}
+	return nil
+}
+
+// ownedCollection retrieves a collection, verifying that the user owns it
+func (s *collectionService) ownedCollection(ctx context.Context, userID string, collectionID int64) (*models.Collection, error) {
+	collection, err := s.collectionRepo.Get(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if collection.UserID != userID {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this collection")
+	}
+	return collection, nil
+}
+
+// checkChat verifies that the user owns the chat and that it is not locked
+func (s *collectionService) checkChat(ctx context.Context, userID string, chatID int64) error {
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	if chat.UserID != userID {
+		return errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+	return checkUnlocked(chat)
+}
+
+// toCollectionResponse converts a collection model to its response DTO
+func toCollectionResponse(collection *models.Collection) *dtos.CollectionResponse {
+	return &dtos.CollectionResponse{
+		ID:           collection.ID,
+		Name:         collection.Name,
+		Description:  collection.Description,
+		ChunkSize:    collection.ChunkSize,
+		ChunkOverlap: collection.ChunkOverlap,
+		CreatedAt:    collection.CreatedAt,
+		UpdatedAt:    collection.UpdatedAt,
+	}
+}
+
+// toListCollectionsResponse converts collection models to their list response DTO
+func toListCollectionsResponse(collections []*models.Collection) *dtos.ListCollectionsResponse {
+	responses := make([]dtos.CollectionResponse, len(collections))
+	for i, collection := range collections {
+		responses[i] = *toCollectionResponse(collection)
+	}
+	return &dtos.ListCollectionsResponse{Collections: responses}
+}
+
+// toDocumentResponse converts a document model to its response DTO
+func toDocumentResponse(document *models.Document) *dtos.DocumentResponse {
+	return &dtos.DocumentResponse{
+		ID:           document.ID,
+		CollectionID: document.CollectionID,
+		Title:        document.Title,
+		Source:       document.Source,
+		Content:      document.Content,
+		Status:       document.Status,
+		Error:        document.Error,
+		Chunks:       document.Chunks,
+		IndexedAt:    document.IndexedAt,
+		CreatedAt:    document.CreatedAt,
+		UpdatedAt:    document.UpdatedAt,
+	}
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// CommandService defines the interface for running the slash commands users type, the
+// built-in ones and those admins define for the workspace
+type CommandService interface {
+	// ListCommands lists the commands available to users, built-in ones first
+	ListCommands(ctx context.Context) (*dtos.ListCommandsResponse, error)
+
+	// RunCommand parses a command and performs its action for the user
+	RunCommand(ctx context.Context, userID string, req *dtos.CommandRequest) (*dtos.CommandResponse, error)
+
+	// ListCustomCommands lists the commands defined for the workspace, with their templates
+	ListCustomCommands(ctx context.Context) (*dtos.ListCustomCommandsResponse, error)
+
+	// CreateCustomCommand defines a command for the workspace
+	CreateCustomCommand(ctx context.Context, adminID string, req *dtos.CustomCommandRequest) (*dtos.CustomCommandResponse, error)
+
+	// UpdateCustomCommand updates a command defined for the workspace
+	UpdateCustomCommand(ctx context.Context, adminID, name string, req *dtos.UpdateCustomCommandRequest) (*dtos.CustomCommandResponse, error)
+
+	// DeleteCustomCommand deletes a command defined for the workspace
+	DeleteCustomCommand(ctx context.Context, name string) error
+}
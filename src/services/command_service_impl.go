@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// commandNamePattern matches valid command names
+var commandNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+// inputPlaceholder matches the {{input}} placeholder of custom command templates
+var inputPlaceholder = regexp.MustCompile(`\{\{\s*input\s*\}\}`)
+
+// defaultCommandChatTitle is the title of the chats created by /new without one
+const defaultCommandChatTitle = "New chat"
+
+// builtinCommand is a command every workspace has, run with the arguments of its input
+type builtinCommand struct {
+	name        string
+	usage       string
+	description string
+	needsChat   bool
+	run         func(s *commandService, ctx context.Context, userID string, chatID int64, args []string) (*dtos.CommandResponse, error)
+}
+
+// builtinCommands are the built-in commands, in the order they are listed
+var builtinCommands = []builtinCommand{
+	{
+		name:        "new",
+		usage:       `/new ["title"]`,
+		description: "Start a new chat",
+		run:         (*commandService).newChat,
+	},
+	{
+		name:        "summarize",
+		usage:       "/summarize [pin]",
+		description: "Summarize the chat, pinning the summary with pin",
+		needsChat:   true,
+		run:         (*commandService).summarize,
+	},
+	{
+		name:        "translate",
+		usage:       "/translate <language|off>",
+		description: "Translate the replies of the chat into a language, such as fr",
+		needsChat:   true,
+		run:         (*commandService).translate,
+	},
+}
+
+// findBuiltinCommand returns the built-in command of a name
+func findBuiltinCommand(name string) (builtinCommand, bool) {
+	for _, command := range builtinCommands {
+		if command.name == name {
+			return command, true
+		}
+	}
+	return builtinCommand{}, false
+}
+
+// commandService implements the CommandService interface
+type commandService struct {
+	commandRepo    repositories.CustomCommandRepository
+	chatService    ChatService
+	messageService MessageService
+}
+
+// NewCommandService creates a new command service
+func NewCommandService(commandRepo repositories.CustomCommandRepository, chatService ChatService, messageService MessageService) CommandService {
+	return &commandService{
+		commandRepo:    commandRepo,
+		chatService:    chatService,
+		messageService: messageService,
+	}
+}
+
+// ListCommands lists the built-in commands, then those defined for the workspace by name
+func (s *commandService) ListCommands(ctx context.Context) (*dtos.ListCommandsResponse, error) {
+	custom, err := s.commandRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := make([]dtos.CommandInfo, 0, len(builtinCommands)+len(custom))
+	for _, command := range builtinCommands {
+		commands = append(commands, dtos.CommandInfo{
+			Name:        command.name,
+			Usage:       command.usage,
+			Description: command.description,
+			NeedsChat:   command.needsChat,
+		})
+	}
+	for _, command := range custom {
+		commands = append(commands, dtos.CommandInfo{
+			Name:        command.Name,
+			Usage:       "/" + command.Name + " [input]",
+			Description: command.Description,
+			Custom:      true,
+			NeedsChat:   true,
+		})
+	}
+
+	return &dtos.ListCommandsResponse{Commands: commands}, nil
+}
+
+// RunCommand parses a command and performs its action for the user. Built-in commands take
+// arguments, which may be quoted; custom commands take the rest of the input as it is.
+func (s *commandService) RunCommand(ctx context.Context, userID string, req *dtos.CommandRequest) (*dtos.CommandResponse, error) {
+	name, input, err := parseCommand(req.Input)
+	if err != nil {
+		return nil, err
+	}
+	logger.Context(ctx).Infow("Running command", "command", name, "chatID", req.ChatID)
+
+	// Commands act on the chat of the request body, which the user must own
+	if req.ChatID != 0 {
+		owner, err := s.chatService.GetOwner(ctx, req.ChatID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkChatOwner(owner.UserID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if builtin, ok := findBuiltinCommand(name); ok {
+		if builtin.needsChat && req.ChatID == 0 {
+			return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("/%s needs a chatId", name))
+		}
+		args, err := splitCommandArgs(input)
+		if err != nil {
+			return nil, err
+		}
+		return builtin.run(s, ctx, userID, req.ChatID, args)
+	}
+
+	command, err := s.commandRepo.GetByName(ctx, name)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+			return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Unknown command /%s", name))
+		}
+		return nil, err
+	}
+	if req.ChatID == 0 {
+		return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("/%s needs a chatId", name))
+	}
+
+	prompt := renderCommandTemplate(command.Template, input)
+	if strings.TrimSpace(prompt) == "" {
+		return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("/%s needs an input", name))
+	}
+	exchange, err := s.messageService.SendMessage(ctx, req.ChatID, userID, &dtos.MessageRequest{Content: prompt})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.CommandResponse{Command: name, Action: dtos.CommandActionSendMessage, Exchange: exchange}, nil
+}
+
+// newChat creates a chat with the title given, or a default one
+func (s *commandService) newChat(ctx context.Context, userID string, _ int64, args []string) (*dtos.CommandResponse, error) {
+	title := strings.TrimSpace(strings.Join(args, " "))
+	if title == "" {
+		title = defaultCommandChatTitle
+	}
+
+	chat, err := s.chatService.CreateChat(ctx, userID, &dtos.ChatRequest{Title: title})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.CommandResponse{Command: "new", Action: dtos.CommandActionCreateChat, Chat: chat}, nil
+}
+
+// summarize summarizes a chat of the user, pinning the summary when asked to
+func (s *commandService) summarize(ctx context.Context, userID string, chatID int64, args []string) (*dtos.CommandResponse, error) {
+	pin := false
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && strings.EqualFold(args[0], "pin"):
+		pin = true
+	default:
+		return nil, errors.New(errors.ErrInvalidRequest, "Usage: /summarize [pin]")
+	}
+
+	summary, err := s.messageService.SummarizeChat(ctx, chatID, userID, &dtos.SummarizeChatRequest{Pin: pin})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.CommandResponse{Command: "summarize", Action: dtos.CommandActionSummarize, Summary: summary}, nil
+}
+
+// translate sets the language the replies of a chat of the user are translated into, or
+// turns translation off
+func (s *commandService) translate(ctx context.Context, userID string, chatID int64, args []string) (*dtos.CommandResponse, error) {
+	if len(args) != 1 || len(args[0]) > 16 {
+		return nil, errors.New(errors.ErrInvalidRequest, "Usage: /translate <language|off>")
+	}
+	language := strings.ToLower(args[0])
+	if language == "off" {
+		language = ""
+	}
+
+	chat, err := s.chatService.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	chat, err = s.chatService.UpdateChat(ctx, chatID, &dtos.ChatRequest{Title: chat.Title, Language: &language})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.CommandResponse{Command: "translate", Action: dtos.CommandActionSetLanguage, Chat: chat}, nil
+}
+
+// ListCustomCommands lists the commands defined for the workspace by name
+func (s *commandService) ListCustomCommands(ctx context.Context) (*dtos.ListCustomCommandsResponse, error) {
+	commands, err := s.commandRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.CustomCommandResponse, len(commands))
+	for i, command := range commands {
+		responses[i] = *toCustomCommandResponse(command)
+	}
+
+	return &dtos.ListCustomCommandsResponse{Commands: responses}, nil
+}
+
+// CreateCustomCommand defines a command for the workspace, named unlike any other command
+func (s *commandService) CreateCustomCommand(ctx context.Context, adminID string, req *dtos.CustomCommandRequest) (*dtos.CustomCommandResponse, error) {
+	name := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(req.Name), "/"))
+	if !commandNamePattern.MatchString(name) {
+		return nil, errors.New(errors.ErrInvalidRequest, "Name must be 1 to 32 lowercase letters, digits, '-' or '_', starting with a letter")
+	}
+	if _, ok := findBuiltinCommand(name); ok {
+		return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("/%s is a built-in command", name))
+	}
+	if strings.TrimSpace(req.Template) == "" {
+		return nil, errors.New(errors.ErrInvalidRequest, "Template must not be empty")
+	}
+
+	_, err := s.commandRepo.GetByName(ctx, name)
+	if err == nil {
+		return nil, errors.New(errors.ErrConflict, fmt.Sprintf("/%s is already defined", name))
+	}
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		return nil, err
+	}
+
+	command := &models.CustomCommand{
+		Name:        name,
+		Description: req.Description,
+		Template:    req.Template,
+		UpdatedBy:   adminID,
+	}
+	if err := s.commandRepo.Create(ctx, command); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Custom command created", "command", name, "adminID", adminID)
+	return toCustomCommandResponse(command), nil
+}
+
+// UpdateCustomCommand updates the description and template of a command of the workspace
+func (s *commandService) UpdateCustomCommand(ctx context.Context, adminID, name string, req *dtos.UpdateCustomCommandRequest) (*dtos.CustomCommandResponse, error) {
+	if strings.TrimSpace(req.Template) == "" {
+		return nil, errors.New(errors.ErrInvalidRequest, "Template must not be empty")
+	}
+
+	command, err := s.commandRepo.GetByName(ctx, strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	command.Description = req.Description
+	command.Template = req.Template
+	command.UpdatedBy = adminID
+	if err := s.commandRepo.Update(ctx, command); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Custom command updated", "command", command.Name, "adminID", adminID)
+	return toCustomCommandResponse(command), nil
+}
+
+// DeleteCustomCommand deletes a command of the workspace
+func (s *commandService) DeleteCustomCommand(ctx context.Context, name string) error {
+	command, err := s.commandRepo.GetByName(ctx, strings.ToLower(name))
+	if err != nil {
+		return err
+	}
+
+	logger.Context(ctx).Infow("Deleting custom command", "command", command.Name)
+	return s.commandRepo.Delete(ctx, command.ID)
+}
+
+// parseCommand splits a command into its lowercased name, without the slash, and the rest of
+// its input
+func parseCommand(input string) (string, string, error) {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, "/") {
+		return "", "", errors.New(errors.ErrInvalidRequest, "Commands start with /")
+	}
+
+	name, rest := input[1:], ""
+	if i := strings.IndexFunc(name, unicode.IsSpace); i >= 0 {
+		name, rest = name[:i], name[i:]
+	}
+	name = strings.ToLower(name)
+	if !commandNamePattern.MatchString(name) {
+		return "", "", errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Invalid command /%s", name))
+	}
+	return name, strings.TrimSpace(rest), nil
+}
+
+// splitCommandArgs splits the input of a command into arguments separated by whitespace.
+// Double quotes group words into one argument, and a backslash escapes the next character
+// within them.
+func splitCommandArgs(input string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg, quoted, escaped := false, false, false
+
+	for _, r := range input {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case !quoted && unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quoted {
+		return nil, errors.New(errors.ErrInvalidRequest, "Unterminated quote in command")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// renderCommandTemplate replaces the {{input}} placeholders of a template with the input of
+// a command, or appends the input to a template without one
+func renderCommandTemplate(template, input string) string {
+	if inputPlaceholder.MatchString(template) {
+		return inputPlaceholder.ReplaceAllLiteralString(template, input)
+	}
+	if input == "" {
+		return template
+	}
+	return template + "\n\n" + input
+}
+
+// toCustomCommandResponse converts a custom command model to its response DTO
+func toCustomCommandResponse(command *models.CustomCommand) *dtos.CustomCommandResponse {
+	return &dtos.CustomCommandResponse{
+		ID:          command.ID,
+		Name:        command.Name,
+		Description: command.Description,
+		Template:    command.Template,
+		UpdatedBy:   command.UpdatedBy,
+		CreatedAt:   command.CreatedAt,
+		UpdatedAt:   command.UpdatedAt,
+	}
+}
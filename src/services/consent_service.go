@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// ConsentService defines the interface for tracking the data-usage terms users accepted
+type ConsentService interface {
+	// GetConsent returns the current terms and the terms versions a user accepted
+	GetConsent(ctx context.Context, userID string) (*dtos.ConsentResponse, error)
+
+	// Accept records that a user accepted the current terms
+	Accept(ctx context.Context, userID string, req *dtos.ConsentRequest) (*dtos.ConsentResponse, error)
+
+	// Enforce returns a consent required error when consent is required and the user has not
+	// accepted the current terms
+	Enforce(ctx context.Context, userID string) error
+}
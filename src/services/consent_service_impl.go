@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// consentService implements the ConsentService interface
+type consentService struct {
+	config      configs.Consent
+	consentRepo repositories.ConsentRepository
+}
+
+// NewConsentService creates a new consent service
+func NewConsentService(config configs.Consent, consentRepo repositories.ConsentRepository) ConsentService {
+	return &consentService{
+		config:      config,
+		consentRepo: consentRepo,
+	}
+}
+
+// GetConsent returns the current terms and the terms versions a user accepted
+func (s *consentService) GetConsent(ctx context.Context, userID string) (*dtos.ConsentResponse, error) {
+	consents, err := s.consentRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dtos.ConsentResponse{
+		TermsVersion: s.config.TermsVersion,
+		TermsURL:     s.config.TermsURL,
+		Required:     s.config.Required,
+		History:      make([]dtos.ConsentRecord, len(consents)),
+	}
+	for i, consent := range consents {
+		response.History[i] = dtos.ConsentRecord{
+			TermsVersion: consent.TermsVersion,
+			AcceptedAt:   consent.AcceptedAt,
+		}
+		if consent.TermsVersion == s.config.TermsVersion {
+			response.Accepted = true
+		}
+	}
+
+	return response, nil
+}
+
+// Accept records that a user accepted the current terms. Only the current version can be
+// accepted, so users do not consent to terms they were not shown.
+func (s *consentService) Accept(ctx context.Context, userID string, req *dtos.ConsentRequest) (*dtos.ConsentResponse, error) {
+	if s.config.TermsVersion == "" {
+		return nil, errors.New(errors.ErrNotFound, "No data-usage terms are configured")
+	}
+	if req.TermsVersion != s.config.TermsVersion {
+		return nil, errors.New(errors.ErrInvalidRequest, "Only the current terms version can be accepted")
+	}
+
+	consent := &models.Consent{
+		UserID:       userID,
+		TermsVersion: req.TermsVersion,
+		AcceptedAt:   time.Now(),
+	}
+	if err := s.consentRepo.Create(ctx, consent); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Terms accepted", "userID", userID, "termsVersion", req.TermsVersion)
+	return s.GetConsent(ctx, userID)
+}
+
+// Enforce returns a consent required error when the user has not accepted the current terms
+func (s *consentService) Enforce(ctx context.Context, userID string) error {
+	if !s.config.Required {
+		return nil
+	}
+
+	accepted, err := s.consentRepo.Exists(ctx, userID, s.config.TermsVersion)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return errors.New(errors.ErrConsentRequired, "The data-usage terms "+s.config.TermsVersion+" must be accepted before sending messages")
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// CrawlService defines the interface for crawling websites into document collections
+type CrawlService interface {
+	// StartCrawl queues a crawl of a website into a collection of the user
+	StartCrawl(ctx context.Context, userID string, collectionID int64, req *dtos.CrawlRequest) (*dtos.CrawlResponse, error)
+
+	// ListCrawls lists the crawls of a collection of the user
+	ListCrawls(ctx context.Context, userID string, collectionID int64) (*dtos.ListCrawlsResponse, error)
+
+	// GetCrawl retrieves a crawl of a collection of the user with its progress
+	GetCrawl(ctx context.Context, userID string, collectionID, crawlID int64) (*dtos.CrawlResponse, error)
+
+	// ProcessPending runs the pending crawls and returns the number processed
+	ProcessPending(ctx context.Context) (int, error)
+}
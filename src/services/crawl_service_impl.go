@@ -0,0 +1,409 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// maxCrawlSitemaps bounds the sitemaps fetched by a crawl, sitemap indexes included
+const maxCrawlSitemaps = 50
+
+// crawlService implements the CrawlService interface
+type crawlService struct {
+	config         configs.Collections
+	crawlRepo      repositories.CrawlRepository
+	collectionRepo repositories.CollectionRepository
+	documentRepo   repositories.DocumentRepository
+	crawler        adapters.CrawlerAdapter
+}
+
+// NewCrawlService creates a new website crawl service
+func NewCrawlService(
+	config configs.Collections,
+	crawlRepo repositories.CrawlRepository,
+	collectionRepo repositories.CollectionRepository,
+	documentRepo repositories.DocumentRepository,
+	crawler adapters.CrawlerAdapter,
+) CrawlService {
+	if config.Crawler.MaxPages <= 0 {
+		config.Crawler.MaxPages = 200
+	}
+	if config.Crawler.BatchSize <= 0 {
+		config.Crawler.BatchSize = 1
+	}
+
+	return &crawlService{
+		config:         config,
+		crawlRepo:      crawlRepo,
+		collectionRepo: collectionRepo,
+		documentRepo:   documentRepo,
+		crawler:        crawler,
+	}
+}
+
+// StartCrawl queues a crawl of a website into a collection of the user
+func (s *crawlService) StartCrawl(ctx context.Context, userID string, collectionID int64, req *dtos.CrawlRequest) (*dtos.CrawlResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Starting crawl", "collectionID", collectionID, "mode", req.Mode)
+
+	if !s.config.Crawler.Enabled {
+		return nil, errors.New(errors.ErrInvalidRequest, "Website crawling is not enabled")
+	}
+	if err := s.checkOwner(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+
+	start, err := url.Parse(req.URL)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return nil, errors.New(errors.ErrInvalidRequest, "URL must be an HTTP or HTTPS URL")
+	}
+	start.Fragment, start.RawFragment = "", ""
+
+	crawl := &models.Crawl{
+		CollectionID: collectionID,
+		UserID:       userID,
+		URL:          start.String(),
+		Mode:         req.Mode,
+		MaxDepth:     s.config.Crawler.MaxDepth,
+		MaxPages:     s.config.Crawler.MaxPages,
+		Status:       models.CrawlStatusPending,
+	}
+	if req.MaxDepth != nil {
+		if *req.MaxDepth > s.config.Crawler.MaxDepth {
+			return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Crawl depth must be at most %d", s.config.Crawler.MaxDepth))
+		}
+		crawl.MaxDepth = *req.MaxDepth
+	}
+	if req.MaxPages > 0 {
+		if req.MaxPages > s.config.Crawler.MaxPages {
+			return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Crawls can visit at most %d pages", s.config.Crawler.MaxPages))
+		}
+		crawl.MaxPages = req.MaxPages
+	}
+
+	if err := s.crawlRepo.Create(ctx, crawl); err != nil {
+		return nil, err
+	}
+
+	return toCrawlResponse(crawl), nil
+}
+
+// ListCrawls lists the crawls of a collection of the user
+func (s *crawlService) ListCrawls(ctx context.Context, userID string, collectionID int64) (*dtos.ListCrawlsResponse, error) {
+	if err := s.checkOwner(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+
+	crawls, err := s.crawlRepo.ListByCollectionID(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.CrawlResponse, len(crawls))
+	for i, crawl := range crawls {
+		responses[i] = *toCrawlResponse(crawl)
+	}
+
+	return &dtos.ListCrawlsResponse{Crawls: responses}, nil
+}
+
+// GetCrawl retrieves a crawl of a collection of the user with its progress
+func (s *crawlService) GetCrawl(ctx context.Context, userID string, collectionID, crawlID int64) (*dtos.CrawlResponse, error) {
+	if err := s.checkOwner(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+
+	crawl, err := s.crawlRepo.Get(ctx, collectionID, crawlID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toCrawlResponse(crawl), nil
+}
+
+// ProcessPending runs the pending crawls and returns the number processed
+func (s *crawlService) ProcessPending(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	crawls, err := s.crawlRepo.ListPending(ctx, s.config.Crawler.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, crawl := range crawls {
+		crawl.Status = models.CrawlStatusRunning
+		if err := s.crawlRepo.Update(ctx, crawl); err != nil {
+			return 0, err
+		}
+
+		if err := s.crawl(ctx, crawl); err != nil {
+			log.Errorw("Failed to crawl website", "error", err, "crawlID", crawl.ID, "url", crawl.URL)
+			crawl.Status = models.CrawlStatusFailed
+			crawl.Error = err.Error()
+		} else {
+			crawl.Status = models.CrawlStatusCompleted
+			log.Infow("Crawl completed", "crawlID", crawl.ID, "pages", crawl.Pages, "skipped", crawl.Skipped)
+		}
+
+		now := time.Now()
+		crawl.CompletedAt = &now
+		if err := s.crawlRepo.Update(ctx, crawl); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(crawls), nil
+}
+
+// crawl ingests the pages of the site of a crawl into its collection, respecting the
+// robots.txt rules of the site and spacing the requests to it
+func (s *crawlService) crawl(ctx context.Context, crawl *models.Crawl) error {
+	start, err := url.Parse(crawl.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+
+	limiter := &siteLimiter{interval: s.config.Crawler.RequestInterval}
+	if err := limiter.wait(ctx); err != nil {
+		return err
+	}
+	robots, err := s.crawler.Robots(ctx, crawl.URL)
+	if err != nil {
+		return fmt.Errorf("failed to read robots.txt: %w", err)
+	}
+	limiter.interval = max(limiter.interval, min(robots.CrawlDelay, s.config.Crawler.MaxCrawlDelay))
+
+	if crawl.Mode == models.CrawlModeSitemap {
+		err = s.crawlSitemaps(ctx, crawl, start, robots, limiter)
+	} else {
+		err = s.crawlLinks(ctx, crawl, start, robots, limiter)
+	}
+	if err != nil {
+		return err
+	}
+
+	if crawl.Pages == 0 {
+		return fmt.Errorf("no page could be crawled")
+	}
+	return nil
+}
+
+// crawlSitemaps ingests the pages of the site listed by its sitemaps: the sitemap at the
+// URL of the crawl, or else those of robots.txt, or else /sitemap.xml
+func (s *crawlService) crawlSitemaps(ctx context.Context, crawl *models.Crawl, start *url.URL, robots *adapters.RobotsRules, limiter *siteLimiter) error {
+	log := logger.Context(ctx)
+
+	var sitemaps []string
+	switch {
+	case strings.HasSuffix(strings.ToLower(start.Path), ".xml"):
+		sitemaps = []string{start.String()}
+	case len(robots.Sitemaps) > 0:
+		sitemaps = robots.Sitemaps
+	default:
+		sitemaps = []string{(&url.URL{Scheme: start.Scheme, Host: start.Host, Path: "/sitemap.xml"}).String()}
+	}
+
+	// Collect the pages first, so sitemap indexes do not count toward the visited pages
+	var pages []string
+	seen := make(map[string]bool)
+	for fetched := 0; len(sitemaps) > 0 && fetched < maxCrawlSitemaps && len(pages) < crawl.MaxPages; fetched++ {
+		sitemapURL := sitemaps[0]
+		sitemaps = sitemaps[1:]
+		if seen[sitemapURL] || !sameHost(start, sitemapURL) {
+			continue
+		}
+		seen[sitemapURL] = true
+
+		if err := limiter.wait(ctx); err != nil {
+			return err
+		}
+		sitemap, err := s.crawler.Sitemap(ctx, sitemapURL)
+		if err != nil {
+			log.Warnw("Failed to read sitemap", "error", err, "crawlID", crawl.ID, "sitemap", sitemapURL)
+			continue
+		}
+
+		sitemaps = append(sitemaps, sitemap.Sitemaps...)
+		for _, page := range sitemap.Pages {
+			if len(pages) < crawl.MaxPages && !seen[page] && sameHost(start, page) {
+				seen[page] = true
+				pages = append(pages, page)
+			}
+		}
+	}
+	if len(pages) == 0 {
+		return fmt.Errorf("no page of the site was found in its sitemaps")
+	}
+
+	for _, page := range pages {
+		if _, err := s.ingest(ctx, crawl, page, robots, limiter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crawlLinks ingests the start page of a crawl and the pages it links to, breadth first up
+// to the depth of the crawl. Only the pages under the directory of the start page are followed.
+func (s *crawlService) crawlLinks(ctx context.Context, crawl *models.Crawl, start *url.URL, robots *adapters.RobotsRules, limiter *siteLimiter) error {
+	scope := start.Path[:strings.LastIndex(start.Path, "/")+1]
+	if scope == "" {
+		scope = "/"
+	}
+
+	type queuedPage struct {
+		url   string
+		depth int
+	}
+	queue := []queuedPage{{url: start.String()}}
+	visited := map[string]bool{start.String(): true}
+
+	for len(queue) > 0 && crawl.Pages+crawl.Skipped < crawl.MaxPages {
+		page := queue[0]
+		queue = queue[1:]
+
+		links, err := s.ingest(ctx, crawl, page.url, robots, limiter)
+		if err != nil {
+			return err
+		}
+		if page.depth >= crawl.MaxDepth {
+			continue
+		}
+
+		for _, link := range links {
+			if crawl.Pages+crawl.Skipped+len(queue) >= crawl.MaxPages {
+				break
+			}
+			u, err := url.Parse(link)
+			if err != nil || visited[link] || !strings.EqualFold(u.Host, start.Host) || !strings.HasPrefix(u.Path, scope) {
+				continue
+			}
+			visited[link] = true
+			queue = append(queue, queuedPage{url: link, depth: page.depth + 1})
+		}
+	}
+	return nil
+}
+
+// ingest fetches a page allowed by robots.txt and saves its text as a document of the
+// collection, replacing the document of a previous crawl. It returns the links of the page;
+// pages that cannot be ingested are counted as skipped.
+func (s *crawlService) ingest(ctx context.Context, crawl *models.Crawl, pageURL string, robots *adapters.RobotsRules, limiter *siteLimiter) ([]string, error) {
+	log := logger.Context(ctx)
+
+	u, err := url.Parse(pageURL)
+	if err != nil || !robots.Allowed(u.RequestURI()) {
+		crawl.Skipped++
+		return nil, nil
+	}
+
+	if err := limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	page, err := s.crawler.Page(ctx, pageURL)
+	if err != nil {
+		log.Warnw("Failed to crawl page", "error", err, "crawlID", crawl.ID, "url", pageURL)
+		crawl.Skipped++
+		return nil, nil
+	}
+	if page.Text == "" {
+		crawl.Skipped++
+		return page.Links, nil
+	}
+
+	title := page.Title
+	if title == "" {
+		title = page.URL
+	}
+	content := page.Text
+	if s.config.MaxDocumentSize > 0 {
+		content = truncateBytes(content, s.config.MaxDocumentSize)
+	}
+
+	document := &models.Document{
+		CollectionID: crawl.CollectionID,
+		Title:        truncateRunes(title, 255),
+		Source:       page.URL,
+		Content:      content,
+		Status:       models.DocumentStatusPending,
+	}
+	if err := s.documentRepo.Replace(ctx, document); err != nil {
+		return nil, err
+	}
+
+	// Save the progress, so it can be followed while the crawl runs
+	crawl.Pages++
+	if err := s.crawlRepo.Update(ctx, crawl); err != nil {
+		return nil, err
+	}
+	return page.Links, nil
+}
+
+// checkOwner verifies that the user owns the collection
+func (s *crawlService) checkOwner(ctx context.Context, userID string, collectionID int64) error {
+	collection, err := s.collectionRepo.Get(ctx, collectionID)
+	if err != nil {
+		return err
+	}
+
+	if collection.UserID != userID {
+		return errors.New(errors.ErrForbidden, "User does not have access to this collection")
+	}
+	return nil
+}
+
+// siteLimiter spaces the requests of a crawl to its site
+type siteLimiter struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// wait waits until the interval since the previous request has passed
+func (l *siteLimiter) wait(ctx context.Context) error {
+	if delay := time.Until(l.last.Add(l.interval)); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	l.last = time.Now()
+	return nil
+}
+
+// sameHost reports whether a URL is on the host of the start URL of a crawl
+func sameHost(start *url.URL, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && strings.EqualFold(u.Host, start.Host)
+}
+
+// toCrawlResponse converts a crawl model to its response DTO
+func toCrawlResponse(crawl *models.Crawl) *dtos.CrawlResponse {
+	return &dtos.CrawlResponse{
+		ID:           crawl.ID,
+		CollectionID: crawl.CollectionID,
+		URL:          crawl.URL,
+		Mode:         crawl.Mode,
+		MaxDepth:     crawl.MaxDepth,
+		MaxPages:     crawl.MaxPages,
+		Status:       crawl.Status,
+		Pages:        crawl.Pages,
+		Skipped:      crawl.Skipped,
+		Error:        crawl.Error,
+		CreatedAt:    crawl.CreatedAt,
+		CompletedAt:  crawl.CompletedAt,
+	}
+}
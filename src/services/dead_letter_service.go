@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+)
+
+// DeadLetterService defines the interface for dead letter operations
+type DeadLetterService interface {
+	// Record stores a message that failed processing after the given number of attempts
+	Record(ctx context.Context, msg *queue.Message, cause error, attempts int) error
+
+	// List lists dead letters, optionally filtered by topic
+	List(ctx context.Context, req *dtos.ListDeadLettersRequest) (*dtos.ListDeadLettersResponse, error)
+
+	// Replay republishes a dead letter to its original topic
+	Replay(ctx context.Context, id int64) (*dtos.DeadLetterResponse, error)
+}
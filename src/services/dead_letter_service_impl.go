@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// HeaderReplayOf marks a message republished from the dead letter with the given ID
+const HeaderReplayOf = "X-DLQ-Replay-Of"
+
+// deadLetterService implements the DeadLetterService interface
+type deadLetterService struct {
+	deadLetterRepo repositories.DeadLetterRepository
+	producer       queue.Producer
+}
+
+// NewDeadLetterService creates a new dead letter service
+func NewDeadLetterService(deadLetterRepo repositories.DeadLetterRepository, producer queue.Producer) DeadLetterService {
+	return &deadLetterService{
+		deadLetterRepo: deadLetterRepo,
+		producer:       producer,
+	}
+}
+
+// Record stores a message that failed processing after the given number of attempts
+func (s *deadLetterService) Record(ctx context.Context, msg *queue.Message, cause error, attempts int) error {
+	log := logger.Context(ctx)
+	log.Warnw("Recording dead letter", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "attempts", attempts)
+
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrInternal, "Failed to encode dead letter headers")
+	}
+
+	return s.deadLetterRepo.Create(ctx, &models.DeadLetter{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       string(msg.Key),
+		Payload:   string(msg.Value),
+		Headers:   string(headers),
+		Error:     cause.Error(),
+		Attempts:  attempts,
+	})
+}
+
+// List lists dead letters, optionally filtered by topic
+func (s *deadLetterService) List(ctx context.Context, req *dtos.ListDeadLettersRequest) (*dtos.ListDeadLettersResponse, error) {
+	log := logger.Context(ctx)
+	log.Debugw("Listing dead letters", "topic", req.Topic, "limit", req.Limit, "offset", req.Offset)
+
+	deadLetters, total, err := s.deadLetterRepo.List(ctx, req.Topic, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to response DTOs
+	responses := make([]dtos.DeadLetterResponse, len(deadLetters))
+	for i, deadLetter := range deadLetters {
+		responses[i] = toDeadLetterResponse(deadLetter)
+	}
+
+	return &dtos.ListDeadLettersResponse{
+		DeadLetters: responses,
+		Total:       total,
+	}, nil
+}
+
+// Replay republishes a dead letter to its original topic
+func (s *deadLetterService) Replay(ctx context.Context, id int64) (*dtos.DeadLetterResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Replaying dead letter", "id", id)
+
+	deadLetter, err := s.deadLetterRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := decodeHeaders(deadLetter.Headers)
+	headers[HeaderReplayOf] = strconv.FormatInt(deadLetter.ID, 10)
+
+	msg := queue.Message{
+		Topic:   deadLetter.Topic,
+		Key:     []byte(deadLetter.Key),
+		Value:   []byte(deadLetter.Payload),
+		Headers: headers,
+	}
+	if err := s.producer.Produce(ctx, msg); err != nil {
+		log.Errorw("Failed to replay dead letter", "error", err, "id", id)
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to replay dead letter")
+	}
+
+	if err := s.deadLetterRepo.MarkReplayed(ctx, id); err != nil {
+		return nil, err
+	}
+
+	deadLetter, err = s.deadLetterRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := toDeadLetterResponse(deadLetter)
+	return &response, nil
+}
+
+// toDeadLetterResponse converts a dead letter model to its response DTO
+func toDeadLetterResponse(deadLetter *models.DeadLetter) dtos.DeadLetterResponse {
+	return dtos.DeadLetterResponse{
+		ID:         deadLetter.ID,
+		Topic:      deadLetter.Topic,
+		Partition:  deadLetter.Partition,
+		Offset:     deadLetter.Offset,
+		Key:        deadLetter.Key,
+		Payload:    deadLetter.Payload,
+		Headers:    decodeHeaders(deadLetter.Headers),
+		Error:      deadLetter.Error,
+		Attempts:   deadLetter.Attempts,
+		ReplayedAt: deadLetter.ReplayedAt,
+		CreatedAt:  deadLetter.CreatedAt,
+	}
+}
+
+// decodeHeaders decodes JSON-encoded headers, returning an empty map on failure
+func decodeHeaders(encoded string) map[string]string {
+	headers := map[string]string{}
+	if encoded != "" {
+		_ = json.Unmarshal([]byte(encoded), &headers)
+	}
+	return headers
+}
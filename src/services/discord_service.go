@@ -0,0 +1,15 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// DiscordService defines the interface for answering users in Discord
+type DiscordService interface {
+	// HandleInteraction handles an interaction received from Discord and returns the response
+	// to it. Commands are acknowledged and answered in the background, in the chat their
+	// channel maps to, streaming the reply into the response.
+	HandleInteraction(ctx context.Context, interaction *dtos.DiscordInteraction) (*dtos.DiscordInteractionResponse, error)
+}
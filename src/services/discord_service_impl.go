@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// Discord limits
+const (
+	// discordMessageLength is the most characters of a Discord message; longer replies are
+	// split into follow-up messages
+	discordMessageLength = 2000
+	// discordTitleLength is the most characters of the first prompt used as the title of a chat
+	discordTitleLength = 50
+)
+
+// discordPromptOption is the option of the command carrying the prompt
+const discordPromptOption = "prompt"
+
+// discordCursor ends the reply while it is streamed
+const discordCursor = " ▌"
+
+// discordService implements the DiscordService interface
+type discordService struct {
+	config         configs.Discord
+	discordRepo    repositories.DiscordRepository
+	chatService    ChatService
+	messageService MessageService
+	discordAdapter adapters.DiscordAdapter
+	limiter        *commandLimiter
+}
+
+// NewDiscordService creates a new Discord service
+func NewDiscordService(
+	config configs.Discord,
+	discordRepo repositories.DiscordRepository,
+	chatService ChatService,
+	messageService MessageService,
+	discordAdapter adapters.DiscordAdapter,
+) DiscordService {
+	return &discordService{
+		config:         config,
+		discordRepo:    discordRepo,
+		chatService:    chatService,
+		messageService: messageService,
+		discordAdapter: discordAdapter,
+		limiter:        newCommandLimiter(time.Minute),
+	}
+}
+
+// HandleInteraction answers the command of the bot; commands rejected by the configuration
+// of the guild or its rate limit are answered with a message only the user sees
+func (s *discordService) HandleInteraction(ctx context.Context, interaction *dtos.DiscordInteraction) (*dtos.DiscordInteractionResponse, error) {
+	log := logger.Context(ctx)
+
+	switch interaction.Type {
+	case dtos.DiscordInteractionPing:
+		return &dtos.DiscordInteractionResponse{Type: dtos.DiscordResponsePong}, nil
+	case dtos.DiscordInteractionCommand:
+	default:
+		return nil, errors.New(errors.ErrInvalidRequest, "Unsupported interaction type")
+	}
+
+	if interaction.Data == nil || interaction.Data.Name != s.config.Command {
+		return ephemeralResponse("Unknown command"), nil
+	}
+	user := interaction.User
+	if interaction.Member != nil {
+		user = interaction.Member.User
+	}
+	if user == nil || user.ID == "" || interaction.ChannelID == "" {
+		return nil, errors.New(errors.ErrInvalidRequest, "The interaction has no user or channel")
+	}
+
+	guild, reason := s.guildOf(interaction)
+	if reason != "" {
+		log.Infow("Discord command not allowed", "guildID", interaction.GuildID, "channelID", interaction.ChannelID, "reason", reason)
+		return ephemeralResponse(reason), nil
+	}
+
+	limit := s.config.RequestsPerMinute
+	if guild.RequestsPerMinute > 0 {
+		limit = guild.RequestsPerMinute
+	}
+	if limit > 0 {
+		if allowed, retryAfter := s.limiter.allow(interaction.GuildID+":"+user.ID, limit); !allowed {
+			log.Warnw("Discord rate limit exceeded", "guildID", interaction.GuildID, "discordUserID", user.ID)
+			return ephemeralResponse(fmt.Sprintf("You are asking too fast. Try again in %d seconds.", int(math.Ceil(retryAfter.Seconds())))), nil
+		}
+	}
+
+	prompt := ""
+	for _, option := range interaction.Data.Options {
+		if value, ok := option.Value.(string); ok && option.Name == discordPromptOption {
+			prompt = strings.TrimSpace(value)
+		}
+	}
+	if prompt == "" {
+		return ephemeralResponse("Ask with the " + discordPromptOption + " option."), nil
+	}
+
+	userID := s.config.UserPrefix + user.ID
+	// Interactions must be acknowledged within 3 seconds; the reply is streamed into the
+	// deferred response
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		if err := s.answer(ctx, interaction, guild, userID, prompt); err != nil {
+			logger.Context(ctx).Errorw("Failed to answer Discord command", "error", err, "userID", userID)
+		}
+	}()
+	return &dtos.DiscordInteractionResponse{Type: dtos.DiscordResponseDeferred}, nil
+}
+
+// guildOf returns the configuration of the guild of an interaction, or the reason the bot
+// does not answer there
+func (s *discordService) guildOf(interaction *dtos.DiscordInteraction) (configs.DiscordGuild, string) {
+	if interaction.GuildID == "" {
+		if !s.config.DirectMessages {
+			return configs.DiscordGuild{}, "The assistant does not answer direct messages."
+		}
+		return configs.DiscordGuild{}, ""
+	}
+
+	guild, ok := s.config.Guilds[interaction.GuildID]
+	if !ok && len(s.config.Guilds) > 0 {
+		return guild, "The assistant is not enabled in this server."
+	}
+	if len(guild.Channels) > 0 && !slices.Contains(guild.Channels, interaction.ChannelID) &&
+		(interaction.Channel == nil || !slices.Contains(guild.Channels, interaction.Channel.ParentID)) {
+		return guild, "The assistant is not enabled in this channel."
+	}
+	return guild, ""
+}
+
+// answer sends a prompt to the chat of its channel and streams the reply into the response
+// to the interaction, or replies with the error when there is none
+func (s *discordService) answer(ctx context.Context, interaction *dtos.DiscordInteraction, guild configs.DiscordGuild, userID, prompt string) error {
+	log := logger.Context(ctx)
+
+	var content strings.Builder
+	var editedAt time.Time
+	onChunk := func(chunk *dtos.LLMChunk) error {
+		content.WriteString(chunk.Content)
+		if chunk.Done || content.Len() == 0 || time.Since(editedAt) < s.config.EditInterval {
+			return nil
+		}
+		editedAt = time.Now()
+		// A failed edit only skips a step of the typing effect
+		if err := s.discordAdapter.EditResponse(ctx, interaction.Token, discordMessage(truncateRunes(content.String(), discordMessageLength-len([]rune(discordCursor)))+discordCursor)); err != nil {
+			log.Warnw("Failed to stream Discord reply", "error", err)
+		}
+		return nil
+	}
+
+	reply := "Sorry, something went wrong. Please try again."
+	chatID, err := s.chatOf(ctx, interaction, guild, userID, prompt)
+	if err == nil {
+		var exchange *dtos.MessageExchangeResponse
+		exchange, err = s.messageService.StreamMessage(ctx, chatID, userID, &dtos.MessageRequest{Content: prompt}, onChunk)
+		if err == nil && exchange.AssistantMessage != nil {
+			reply = exchange.AssistantMessage.Content
+		}
+	}
+	if appErr, ok := err.(*errors.AppError); ok {
+		reply = "Sorry, " + appErr.Message
+	}
+
+	parts := splitDiscordMessage(reply, discordMessageLength)
+	if sendErr := s.discordAdapter.EditResponse(ctx, interaction.Token, discordMessage(parts[0])); sendErr != nil {
+		return sendErr
+	}
+	for _, part := range parts[1:] {
+		if sendErr := s.discordAdapter.SendFollowup(ctx, interaction.Token, discordMessage(part)); sendErr != nil {
+			return sendErr
+		}
+	}
+	return err
+}
+
+// chatOf returns the chat of the Discord channel of a user, creating it on their first command
+func (s *discordService) chatOf(ctx context.Context, interaction *dtos.DiscordInteraction, guild configs.DiscordGuild, userID, prompt string) (int64, error) {
+	channel, err := s.discordRepo.GetChannel(ctx, interaction.ChannelID, userID)
+	if err == nil {
+		// The chat may have been transferred since
+		owner, err := s.chatService.GetOwner(ctx, channel.ChatID)
+		if err != nil {
+			return 0, err
+		}
+		return channel.ChatID, checkChatOwner(owner.UserID, userID)
+	}
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		return 0, err
+	}
+
+	req := &dtos.ChatRequest{Title: truncateRunes(prompt, discordTitleLength)}
+	if guild.Language != "" {
+		req.Language = &guild.Language
+	}
+	chat, err := s.chatService.CreateChat(ctx, userID, req)
+	if err != nil {
+		return 0, err
+	}
+	created, err := s.discordRepo.CreateChannel(ctx, &models.DiscordChannel{
+		ChannelID: interaction.ChannelID,
+		UserID:    userID,
+		ChatID:    chat.ID,
+		GuildID:   interaction.GuildID,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !created {
+		// Another command of the user created the chat of the channel at the same time
+		if err := s.chatService.DeleteChat(ctx, chat.ID); err != nil {
+			return 0, err
+		}
+		channel, err := s.discordRepo.GetChannel(ctx, interaction.ChannelID, userID)
+		if err != nil {
+			return 0, err
+		}
+		return channel.ChatID, nil
+	}
+
+	logger.Context(ctx).Infow("Discord channel mapped to a chat", "userID", userID, "chatID", chat.ID, "guildID", interaction.GuildID)
+	return chat.ID, nil
+}
+
+// ephemeralResponse replies to an interaction with a message only its user sees
+func ephemeralResponse(content string) *dtos.DiscordInteractionResponse {
+	message := discordMessage(content)
+	message.Flags = dtos.DiscordMessageEphemeral
+	return &dtos.DiscordInteractionResponse{Type: dtos.DiscordResponseMessage, Data: message}
+}
+
+// discordMessage returns a message with content that mentions no one, whatever the reply says
+func discordMessage(content string) *dtos.DiscordMessage {
+	return &dtos.DiscordMessage{Content: content, AllowedMentions: &dtos.DiscordAllowedMentions{Parse: []string{}}}
+}
+
+// splitDiscordMessage splits content into messages of at most limit characters, at the last
+// line break of each when there is one in its second half
+func splitDiscordMessage(content string, limit int) []string {
+	var parts []string
+	runes := []rune(content)
+	for len(runes) > limit {
+		cut := limit
+		for i := limit - 1; i >= limit/2; i-- {
+			if runes[i] == '\n' {
+				cut = i + 1
+				break
+			}
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	return append(parts, string(runes))
+}
+
+// commandLimiter counts commands per key in fixed time windows, in memory like the rate
+// limit middleware, so limits apply per instance
+type commandLimiter struct {
+	mu     sync.Mutex
+	window time.Duration
+	start  time.Time
+	counts map[string]int
+}
+
+// newCommandLimiter creates a limiter counting commands per window
+func newCommandLimiter(window time.Duration) *commandLimiter {
+	return &commandLimiter{window: window, counts: map[string]int{}}
+}
+
+// allow counts a command for key and reports whether it is within limit, or else how long
+// until the window resets
+func (l *commandLimiter) allow(key string, limit int) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.start) >= l.window {
+		l.start = now
+		l.counts = map[string]int{}
+	}
+
+	if l.counts[key] >= limit {
+		return false, l.start.Add(l.window).Sub(now)
+	}
+	l.counts[key]++
+	return true, 0
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// EvaluationService defines the interface for scoring the quality of conversations offline
+type EvaluationService interface {
+	// EvaluatePending scores a sample of the recent conversations with an LLM judge and returns
+	// the number of conversations scored
+	EvaluatePending(ctx context.Context) (int, error)
+
+	// GetStats aggregates the scores of the conversations evaluated over a number of days
+	GetStats(ctx context.Context, req *dtos.AdminStatsRequest) (*dtos.AdminStatsResponse, error)
+}
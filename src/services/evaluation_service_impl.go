@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// evaluationInstructions is the system prompt of the LLM judge
+const evaluationInstructions = "You evaluate the quality of an assistant's replies in a conversation with a user. " +
+	"Rate how helpful the replies are as a whole, from 1 (unhelpful or wrong) to 5 (accurate, complete and clear), " +
+	"and count the replies that refuse or avoid the user's request. Only the replies marked with a number are evaluated; " +
+	"earlier messages are context. Reply with a JSON object only, without formatting: " +
+	`{"helpfulness": <1 to 5>, "refusals": <number of refusing replies>, "comment": "<one sentence justifying the rating>"}`
+
+// defaultStatsDays is the number of days covered by the statistics when not requested
+const defaultStatsDays = 30
+
+// judgeVerdict is the reply of the LLM judge
+type judgeVerdict struct {
+	Helpfulness int    `json:"helpfulness"`
+	Refusals    int    `json:"refusals"`
+	Comment     string `json:"comment"`
+}
+
+// evaluationService implements the EvaluationService interface
+type evaluationService struct {
+	config         configs.Evaluation
+	evaluationRepo repositories.EvaluationRepository
+	messageRepo    repositories.MessageRepository
+	llmAdapter     adapters.LLMAdapter
+}
+
+// NewEvaluationService creates a new conversation evaluation service
+func NewEvaluationService(
+	config configs.Evaluation,
+	evaluationRepo repositories.EvaluationRepository,
+	messageRepo repositories.MessageRepository,
+	llmAdapter adapters.LLMAdapter,
+) EvaluationService {
+	return &evaluationService{
+		config:         config,
+		evaluationRepo: evaluationRepo,
+		messageRepo:    messageRepo,
+		llmAdapter:     llmAdapter,
+	}
+}
+
+// EvaluatePending scores the replies of a random sample of the chats active within the
+// lookback. Each evaluation covers the replies since the previous one of its chat, so
+// replies are scored once. A chat failing to be scored does not stop the others.
+func (s *evaluationService) EvaluatePending(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	chats, err := s.evaluationRepo.ListCandidates(ctx, time.Now().Add(-s.config.Lookback), s.config.SampleSize)
+	if err != nil {
+		return 0, err
+	}
+
+	evaluated := 0
+	for _, chat := range chats {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := s.evaluate(ctx, chat); err != nil {
+			log.Errorw("Failed to evaluate chat", "error", err, "chatID", chat.ID)
+			continue
+		}
+		evaluated++
+	}
+
+	return evaluated, nil
+}
+
+// evaluate scores the replies of a chat since its previous evaluation, with the latest
+// messages before them as context
+func (s *evaluationService) evaluate(ctx context.Context, chat *models.Chat) error {
+	log := logger.Context(ctx)
+
+	afterID, err := s.evaluationRepo.GetLatestMessageID(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	messages, err := s.messageRepo.GetRecent(ctx, chat.ID, 0, s.config.MaxMessages)
+	if err != nil {
+		return err
+	}
+
+	var transcript strings.Builder
+	replies := 0
+	lastID := int64(0)
+	for _, message := range messages {
+		if !message.InPrompt() {
+			continue
+		}
+		content := message.Content
+		if message.Translation != "" {
+			content = message.Translation
+		}
+		if message.Role == models.MessageRoleAssistant && message.ID > afterID {
+			replies++
+			fmt.Fprintf(&transcript, "%s (reply %d): %s\n", message.Role, replies, content)
+		} else {
+			fmt.Fprintf(&transcript, "%s: %s\n", message.Role, content)
+		}
+		lastID = message.ID
+	}
+	if replies == 0 {
+		return nil
+	}
+
+	model := s.config.Model
+	if model == "" {
+		model = configs.AppConfig.LLM.Model
+	}
+	llmCtx, cancel := llmContext(ctx, 0)
+	defer cancel()
+	response, err := s.llmAdapter.GenerateResponse(llmCtx, &dtos.LLMRequest{
+		Model: model,
+		Messages: []dtos.LLMMessage{
+			{Role: "system", Content: evaluationInstructions},
+			{Role: models.MessageRoleUser, Content: transcript.String()},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, errors.ErrLLMService, "Failed to get response from LLM service")
+	}
+
+	verdict, err := parseVerdict(response.Message.Content)
+	if err != nil {
+		return err
+	}
+
+	evaluation := &models.Evaluation{
+		ChatID:      chat.ID,
+		MessageID:   lastID,
+		Replies:     replies,
+		Refusals:    min(max(verdict.Refusals, 0), replies),
+		Helpfulness: verdict.Helpfulness,
+		Comment:     verdict.Comment,
+		Model:       model,
+	}
+	if err := s.evaluationRepo.Create(ctx, evaluation); err != nil {
+		return err
+	}
+
+	log.Infow("Chat evaluated", "chatID", chat.ID, "replies", replies, "helpfulness", evaluation.Helpfulness, "refusals", evaluation.Refusals)
+	return nil
+}
+
+// parseVerdict parses the JSON object replied by the judge, which models tend to wrap in
+// code fences or prose
+func parseVerdict(content string) (*judgeVerdict, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, errors.New(errors.ErrLLMService, "The judge did not reply with a verdict")
+	}
+
+	var verdict judgeVerdict
+	if err := json.Unmarshal([]byte(content[start:end+1]), &verdict); err != nil {
+		return nil, errors.Wrap(err, errors.ErrLLMService, "The judge did not reply with a verdict")
+	}
+	if verdict.Helpfulness < 1 || verdict.Helpfulness > 5 {
+		return nil, errors.New(errors.ErrLLMService, fmt.Sprintf("The judge rated helpfulness %d, out of 1 to 5", verdict.Helpfulness))
+	}
+	return &verdict, nil
+}
+
+// GetStats aggregates the scores of the conversations evaluated over a number of days, up to today
+func (s *evaluationService) GetStats(ctx context.Context, req *dtos.AdminStatsRequest) (*dtos.AdminStatsResponse, error) {
+	days := req.Days
+	if days == 0 {
+		days = defaultStatsDays
+	}
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1-days)
+
+	trends, err := s.evaluationRepo.GetTrends(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := dtos.EvaluationStats{Days: make([]dtos.EvaluationDayStats, len(trends))}
+	var refusals int64
+	var helpfulness float64
+	for i, trend := range trends {
+		stats.Days[i] = dtos.EvaluationDayStats{
+			Date:           trend.Day.Format("2006-01-02"),
+			Evaluations:    trend.Evaluations,
+			Replies:        trend.Replies,
+			RefusalRate:    ratio(trend.Refusals, trend.Replies),
+			AvgHelpfulness: trend.AvgHelpfulness,
+		}
+		stats.Evaluations += trend.Evaluations
+		stats.Replies += trend.Replies
+		refusals += trend.Refusals
+		helpfulness += trend.AvgHelpfulness * float64(trend.Evaluations)
+	}
+	stats.RefusalRate = ratio(refusals, stats.Replies)
+	if stats.Evaluations > 0 {
+		stats.AvgHelpfulness = helpfulness / float64(stats.Evaluations)
+	}
+
+	return &dtos.AdminStatsResponse{Since: since, Evaluations: stats}, nil
+}
+
+// ratio divides part by total, or returns 0 when total is 0
+func ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/metrics"
+)
+
+// EventBus is the EventPublisher services publish to. Every event is delivered synchronously
+// to the subscribers, in the order they subscribed, before publishing returns. The broker
+// publisher is one subscriber among others, so in-process components get events whether or
+// not a broker is configured.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []*eventSubscriber
+}
+
+// eventSubscriber is a named subscriber of the event bus
+type eventSubscriber struct {
+	name      string
+	publisher EventPublisher
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe delivers the events of the bus to a subscriber until the returned function is
+// called. Subscribers get the published messages themselves and must not modify them.
+func (b *EventBus) Subscribe(name string, subscriber EventPublisher) (unsubscribe func()) {
+	s := &eventSubscriber{name: name, publisher: subscriber}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Subscribers are replaced rather than changed, so deliveries can go on unlocked
+	b.subscribers = append(slices.Clip(b.subscribers), s)
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.subscribers = slices.DeleteFunc(slices.Clone(b.subscribers), func(other *eventSubscriber) bool {
+			return other == s
+		})
+	}
+}
+
+// PublishChatEvent delivers a chat event to the subscribers
+func (b *EventBus) PublishChatEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.ChatPayload]) error {
+	return b.deliver(message.Event, func(publisher EventPublisher) error {
+		return publisher.PublishChatEvent(ctx, message)
+	})
+}
+
+// PublishMessageEvent delivers a message event to the subscribers
+func (b *EventBus) PublishMessageEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.MessagePayload]) error {
+	return b.deliver(message.Event, func(publisher EventPublisher) error {
+		return publisher.PublishMessageEvent(ctx, message)
+	})
+}
+
+// PublishBudgetEvent delivers a budget event to the subscribers
+func (b *EventBus) PublishBudgetEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.BudgetPayload]) error {
+	return b.deliver(message.Event, func(publisher EventPublisher) error {
+		return publisher.PublishBudgetEvent(ctx, message)
+	})
+}
+
+// PublishAttachmentEvent delivers an attachment event to the subscribers
+func (b *EventBus) PublishAttachmentEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.AttachmentPayload]) error {
+	return b.deliver(message.Event, func(publisher EventPublisher) error {
+		return publisher.PublishAttachmentEvent(ctx, message)
+	})
+}
+
+// PublishBillingEvent delivers a billing event to the subscribers
+func (b *EventBus) PublishBillingEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.BillingPayload]) error {
+	return b.deliver(message.Event, func(publisher EventPublisher) error {
+		return publisher.PublishBillingEvent(ctx, message)
+	})
+}
+
+// deliver passes an event to every subscriber. A failing subscriber does not keep the event
+// from the others; the errors of all of them are returned, for the publisher to log.
+func (b *EventBus) deliver(event string, publish func(publisher EventPublisher) error) error {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		err := deliverTo(s, publish)
+		status := "ok"
+		if err != nil {
+			status = "error"
+			errs = append(errs, fmt.Errorf("subscriber %s failed on %s: %w", s.name, event, err))
+		}
+		metrics.EventDeliveries.WithLabelValues(s.name, status).Inc()
+	}
+	return stderrors.Join(errs...)
+}
+
+// deliverTo passes an event to a subscriber, turning its panics into errors
+func deliverTo(s *eventSubscriber, publish func(publisher EventPublisher) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("subscriber panicked: %v", r)
+		}
+	}()
+	return publish(s.publisher)
+}
+
+// EventHandlers subscribes functions to the event bus, for subscribers interested in some
+// kinds of events only. Events without a handler are skipped.
+type EventHandlers struct {
+	Chat       func(ctx context.Context, message *dtos.KafkaMessage[dtos.ChatPayload]) error
+	Message    func(ctx context.Context, message *dtos.KafkaMessage[dtos.MessagePayload]) error
+	Budget     func(ctx context.Context, message *dtos.KafkaMessage[dtos.BudgetPayload]) error
+	Attachment func(ctx context.Context, message *dtos.KafkaMessage[dtos.AttachmentPayload]) error
+	Billing    func(ctx context.Context, message *dtos.KafkaMessage[dtos.BillingPayload]) error
+}
+
+// PublishChatEvent passes a chat event to the chat handler
+func (h EventHandlers) PublishChatEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.ChatPayload]) error {
+	if h.Chat == nil {
+		return nil
+	}
+	return h.Chat(ctx, message)
+}
+
+// PublishMessageEvent passes a message event to the message handler
+func (h EventHandlers) PublishMessageEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.MessagePayload]) error {
+	if h.Message == nil {
+		return nil
+	}
+	return h.Message(ctx, message)
+}
+
+// PublishBudgetEvent passes a budget event to the budget handler
+func (h EventHandlers) PublishBudgetEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.BudgetPayload]) error {
+	if h.Budget == nil {
+		return nil
+	}
+	return h.Budget(ctx, message)
+}
+
+// PublishAttachmentEvent passes an attachment event to the attachment handler
+func (h EventHandlers) PublishAttachmentEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.AttachmentPayload]) error {
+	if h.Attachment == nil {
+		return nil
+	}
+	return h.Attachment(ctx, message)
+}
+
+// PublishBillingEvent passes a billing event to the billing handler
+func (h EventHandlers) PublishBillingEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.BillingPayload]) error {
+	if h.Billing == nil {
+		return nil
+	}
+	return h.Billing(ctx, message)
+}
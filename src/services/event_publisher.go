@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// EventPublisher defines the interface for publishing events to the message broker.
+// Implementations send KafkaMessage.Headers as message headers so consumers
+// can restore the request ID with logger.FromHeaders, and the data residency region.
+type EventPublisher interface {
+	// PublishChatEvent publishes a chat event
+	PublishChatEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.ChatPayload]) error
+
+	// PublishMessageEvent publishes a message event
+	PublishMessageEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.MessagePayload]) error
+
+	// PublishBudgetEvent publishes a budget event
+	PublishBudgetEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.BudgetPayload]) error
+
+	// PublishAttachmentEvent publishes an attachment event
+	PublishAttachmentEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.AttachmentPayload]) error
+
+	// PublishBillingEvent publishes a billing event
+	PublishBillingEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.BillingPayload]) error
+}
+
+// newEvent creates an event envelope of the current schema version, traced with the request ID of ctx
+func newEvent[T any](ctx context.Context, event string, payload T) *dtos.KafkaMessage[T] {
+	return &dtos.KafkaMessage[T]{
+		ID:            uuid.New().String(),
+		Event:         event,
+		Timestamp:     time.Now().Unix(),
+		SchemaVersion: dtos.CurrentSchemaVersion,
+		Producer:      configs.AppConfig.App.Name,
+		TraceID:       logger.GetRequestID(ctx),
+		Payload:       payload,
+		Headers:       eventHeaders(ctx),
+	}
+}
+
+// eventHeaders returns the headers propagating the request ID and the data residency region
+// of ctx to consumers
+func eventHeaders(ctx context.Context) map[string]string {
+	headers := logger.Headers(ctx)
+	if region := adapters.RegionOf(ctx); region != "" {
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers[adapters.RegionHeader] = region
+	}
+	return headers
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/queue"
+)
+
+// Event formats
+const (
+	EventFormatNative      = "native"
+	EventFormatCloudEvents = "cloudevents"
+)
+
+// Event content modes, applied to message content over the size limit
+const (
+	EventContentTruncate  = "truncate"
+	EventContentReference = "reference"
+)
+
+// headerContentType is the message header carrying the content type of a CloudEvent
+const headerContentType = "content-type"
+
+// eventPublisher implements the EventPublisher interface on top of a queue producer
+type eventPublisher struct {
+	producer queue.Producer
+	config   configs.Kafka
+}
+
+// NewEventPublisher creates a new EventPublisher publishing to the configured topics
+// with any broker. Chat and message events are keyed by chat ID so all events of a chat
+// stay ordered, as are attachment events; budget and billing events are keyed by user ID.
+func NewEventPublisher(producer queue.Producer, config configs.Kafka) EventPublisher {
+	return &eventPublisher{
+		producer: producer,
+		config:   config,
+	}
+}
+
+// PublishChatEvent publishes a chat event
+func (p *eventPublisher) PublishChatEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.ChatPayload]) error {
+	key := strconv.FormatInt(message.Payload.ChatID, 10)
+	if p.cloudEvents() {
+		event := message.ToCloudEvent(p.config.CloudEvents.Source, p.config.CloudEvents.TypePrefix, "chats/"+key)
+		return p.publish(ctx, p.config.Topics.Chat, key, event, p.cloudEventHeaders(message.Headers))
+	}
+	return p.publish(ctx, p.config.Topics.Chat, key, message, message.Headers)
+}
+
+// PublishMessageEvent publishes a message event, cutting content over the size limit. The
+// content of the messages of privacy mode chats is never published.
+func (p *eventPublisher) PublishMessageEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.MessagePayload]) error {
+	limited := *message
+	limited.Payload = p.limitContent(message.Payload)
+	if models.IsEphemeralMessageID(limited.Payload.MessageID) {
+		limited.Payload.Ephemeral = true
+		limited.Payload.Content = ""
+		limited.Payload.ContentHash = ""
+		limited.Payload.ContentTruncated = false
+	}
+	message = &limited
+
+	key := strconv.FormatInt(message.Payload.ChatID, 10)
+	if p.cloudEvents() {
+		event := message.ToCloudEvent(p.config.CloudEvents.Source, p.config.CloudEvents.TypePrefix, "chats/"+key)
+		return p.publish(ctx, p.config.Topics.Message, key, event, p.cloudEventHeaders(message.Headers))
+	}
+	return p.publish(ctx, p.config.Topics.Message, key, message, message.Headers)
+}
+
+// PublishBudgetEvent publishes a budget event
+func (p *eventPublisher) PublishBudgetEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.BudgetPayload]) error {
+	key := message.Payload.UserID
+	subject := "users/" + key
+	if key == "" {
+		key = dtos.BudgetScopeGlobal
+		subject = "budgets/" + key
+	}
+	if p.cloudEvents() {
+		event := message.ToCloudEvent(p.config.CloudEvents.Source, p.config.CloudEvents.TypePrefix, subject)
+		return p.publish(ctx, p.config.Topics.Budget, key, event, p.cloudEventHeaders(message.Headers))
+	}
+	return p.publish(ctx, p.config.Topics.Budget, key, message, message.Headers)
+}
+
+// PublishAttachmentEvent publishes an attachment event
+func (p *eventPublisher) PublishAttachmentEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.AttachmentPayload]) error {
+	key := strconv.FormatInt(message.Payload.ChatID, 10)
+	if p.cloudEvents() {
+		event := message.ToCloudEvent(p.config.CloudEvents.Source, p.config.CloudEvents.TypePrefix, "chats/"+key)
+		return p.publish(ctx, p.config.Topics.Attachment, key, event, p.cloudEventHeaders(message.Headers))
+	}
+	return p.publish(ctx, p.config.Topics.Attachment, key, message, message.Headers)
+}
+
+// PublishBillingEvent publishes a billing event
+func (p *eventPublisher) PublishBillingEvent(ctx context.Context, message *dtos.KafkaMessage[dtos.BillingPayload]) error {
+	key := message.Payload.UserID
+	if p.cloudEvents() {
+		event := message.ToCloudEvent(p.config.CloudEvents.Source, p.config.CloudEvents.TypePrefix, "users/"+key)
+		return p.publish(ctx, p.config.Topics.Billing, key, event, p.cloudEventHeaders(message.Headers))
+	}
+	return p.publish(ctx, p.config.Topics.Billing, key, message, message.Headers)
+}
+
+// limitContent truncates or drops message content over the configured size, keeping the hash
+// of the full content so consumers can match it with the message read through the API
+func (p *eventPublisher) limitContent(payload dtos.MessagePayload) dtos.MessagePayload {
+	limit := p.config.EventContent.MaxBytes
+	if limit <= 0 || len(payload.Content) <= limit {
+		return payload
+	}
+
+	if payload.ContentHash == "" {
+		payload.ContentHash = contentHash(payload.Content)
+	}
+	payload.ContentTruncated = true
+	if p.config.EventContent.Mode == EventContentReference {
+		payload.Content = ""
+	} else {
+		payload.Content = truncateBytes(payload.Content, limit)
+	}
+	return payload
+}
+
+// cloudEvents reports whether events are published in CloudEvents format
+func (p *eventPublisher) cloudEvents() bool {
+	return p.config.EventFormat == EventFormatCloudEvents
+}
+
+// cloudEventHeaders adds the structured mode content type to the event headers
+func (p *eventPublisher) cloudEventHeaders(headers map[string]string) map[string]string {
+	result := make(map[string]string, len(headers)+1)
+	for key, value := range headers {
+		result[key] = value
+	}
+	result[headerContentType] = dtos.CloudEventsContentType
+	return result
+}
+
+// publish encodes the event as JSON and produces it with the given key
+func (p *eventPublisher) publish(ctx context.Context, topic, key string, event interface{}, headers map[string]string) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.producer.Produce(ctx, queue.Message{
+		Topic:   topic,
+		Key:     []byte(key),
+		Value:   value,
+		Headers: headers,
+	})
+}
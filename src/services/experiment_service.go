@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// ExperimentService defines the interface for prompt and model experiments
+type ExperimentService interface {
+	// CreateExperiment creates a draft experiment
+	CreateExperiment(ctx context.Context, req *dtos.ExperimentRequest) (*dtos.ExperimentResponse, error)
+
+	// ListExperiments lists all experiments, newest first
+	ListExperiments(ctx context.Context) (*dtos.ListExperimentsResponse, error)
+
+	// GetExperiment retrieves an experiment by ID
+	GetExperiment(ctx context.Context, id int64) (*dtos.ExperimentResponse, error)
+
+	// StartExperiment starts a draft experiment; only one experiment runs at a time
+	StartExperiment(ctx context.Context, id int64) (*dtos.ExperimentResponse, error)
+
+	// StopExperiment stops a running experiment for good
+	StopExperiment(ctx context.Context, id int64) (*dtos.ExperimentResponse, error)
+
+	// GetResults reports the feedback, latency and token metrics of each variant of an experiment
+	GetResults(ctx context.Context, id int64) (*dtos.ExperimentResultsResponse, error)
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// experimentService implements the ExperimentService interface
+type experimentService struct {
+	experimentRepo repositories.ExperimentRepository
+}
+
+// NewExperimentService creates a new experiment service
+func NewExperimentService(experimentRepo repositories.ExperimentRepository) ExperimentService {
+	return &experimentService{experimentRepo: experimentRepo}
+}
+
+// CreateExperiment creates a draft experiment
+func (s *experimentService) CreateExperiment(ctx context.Context, req *dtos.ExperimentRequest) (*dtos.ExperimentResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Creating experiment", "name", req.Name, "variants", len(req.Variants))
+
+	experiment := &models.Experiment{
+		Name:        req.Name,
+		Description: req.Description,
+		Status:      models.ExperimentStatusDraft,
+		Variants:    make([]models.ExperimentVariant, len(req.Variants)),
+	}
+	for i, variant := range req.Variants {
+		experiment.Variants[i] = models.ExperimentVariant{
+			Name:         variant.Name,
+			Weight:       variant.Weight,
+			Model:        variant.Model,
+			SystemPrompt: variant.SystemPrompt,
+		}
+	}
+
+	if err := s.experimentRepo.Create(ctx, experiment); err != nil {
+		return nil, err
+	}
+
+	return toExperimentResponse(experiment), nil
+}
+
+// ListExperiments lists all experiments, newest first
+func (s *experimentService) ListExperiments(ctx context.Context) (*dtos.ListExperimentsResponse, error) {
+	experiments, err := s.experimentRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.ExperimentResponse, len(experiments))
+	for i, experiment := range experiments {
+		responses[i] = *toExperimentResponse(experiment)
+	}
+
+	return &dtos.ListExperimentsResponse{Experiments: responses}, nil
+}
+
+// GetExperiment retrieves an experiment by ID
+func (s *experimentService) GetExperiment(ctx context.Context, id int64) (*dtos.ExperimentResponse, error) {
+	experiment, err := s.experimentRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toExperimentResponse(experiment), nil
+}
+
+// StartExperiment starts a draft experiment; only one experiment runs at a time
+func (s *experimentService) StartExperiment(ctx context.Context, id int64) (*dtos.ExperimentResponse, error) {
+	log := logger.Context(ctx)
+
+	experiment, err := s.experimentRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if experiment.Status != models.ExperimentStatusDraft {
+		return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Experiment is %s and cannot be started", experiment.Status))
+	}
+
+	running, err := s.experimentRepo.GetRunning(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if running != nil {
+		return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Experiment %d is already running", running.ID))
+	}
+
+	now := time.Now()
+	experiment.Status = models.ExperimentStatusRunning
+	experiment.StartedAt = &now
+	if err := s.experimentRepo.UpdateStatus(ctx, experiment); err != nil {
+		return nil, err
+	}
+
+	log.Infow("Experiment started", "id", experiment.ID, "name", experiment.Name)
+	return toExperimentResponse(experiment), nil
+}
+
+// StopExperiment stops a running experiment for good
+func (s *experimentService) StopExperiment(ctx context.Context, id int64) (*dtos.ExperimentResponse, error) {
+	log := logger.Context(ctx)
+
+	experiment, err := s.experimentRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if experiment.Status != models.ExperimentStatusRunning {
+		return nil, errors.New(errors.ErrInvalidRequest, "Experiment is not running")
+	}
+
+	now := time.Now()
+	experiment.Status = models.ExperimentStatusStopped
+	experiment.StoppedAt = &now
+	if err := s.experimentRepo.UpdateStatus(ctx, experiment); err != nil {
+		return nil, err
+	}
+
+	log.Infow("Experiment stopped", "id", experiment.ID, "name", experiment.Name)
+	return toExperimentResponse(experiment), nil
+}
+
+// GetResults reports the feedback, latency and token metrics of each variant of an experiment
+func (s *experimentService) GetResults(ctx context.Context, id int64) (*dtos.ExperimentResultsResponse, error) {
+	experiment, err := s.experimentRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.experimentRepo.GetVariantStats(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	byVariant := make(map[int64]*models.VariantStats, len(stats))
+	for _, variantStats := range stats {
+		byVariant[variantStats.VariantID] = variantStats
+	}
+
+	// Variants without replies yet are reported with zero metrics
+	results := make([]dtos.VariantResults, len(experiment.Variants))
+	for i, variant := range experiment.Variants {
+		results[i] = dtos.VariantResults{VariantID: variant.ID, Name: variant.Name}
+		variantStats, ok := byVariant[variant.ID]
+		if !ok {
+			continue
+		}
+
+		results[i].Chats = variantStats.Chats
+		results[i].Replies = variantStats.Replies
+		results[i].AvgLatencyMs = variantStats.AvgLatencyMs
+		results[i].AvgTokens = variantStats.AvgTokens
+		results[i].TotalTokens = variantStats.TotalTokens
+		results[i].PositiveFeedback = variantStats.PositiveFeedback
+		results[i].NegativeFeedback = variantStats.NegativeFeedback
+		if rated := variantStats.PositiveFeedback + variantStats.NegativeFeedback; rated > 0 {
+			satisfaction := float64(variantStats.PositiveFeedback) / float64(rated)
+			results[i].Satisfaction = &satisfaction
+		}
+	}
+
+	return &dtos.ExperimentResultsResponse{
+		Experiment: *toExperimentResponse(experiment),
+		Variants:   results,
+	}, nil
+}
+
+// assignVariant picks the variant of an experiment for a chat, in proportion to the variant
+// weights. The pick hashes the experiment and chat IDs, so a chat keeps its variant for the
+// whole experiment without storing assignments.
+func assignVariant(experiment *models.Experiment, chatID int64) *models.ExperimentVariant {
+	total := 0
+	for _, variant := range experiment.Variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%d:%d", experiment.ID, chatID)
+	bucket := int(hash.Sum32() % uint32(total))
+
+	for i := range experiment.Variants {
+		bucket -= experiment.Variants[i].Weight
+		if bucket < 0 {
+			return &experiment.Variants[i]
+		}
+	}
+	return nil
+}
+
+// applyVariant makes the request use the model and system prompt of an experiment variant
+func applyVariant(request *dtos.LLMRequest, variant *models.ExperimentVariant) {
+	if variant.Model != "" {
+		request.Model = variant.Model
+	}
+	if variant.SystemPrompt != "" {
+		prompt := dtos.LLMMessage{Role: "system", Content: variant.SystemPrompt}
+		request.Messages = append([]dtos.LLMMessage{prompt}, request.Messages...)
+	}
+}
+
+// toExperimentResponse converts an experiment model to its response DTO
+func toExperimentResponse(experiment *models.Experiment) *dtos.ExperimentResponse {
+	variants := make([]dtos.ExperimentVariantResponse, len(experiment.Variants))
+	for i, variant := range experiment.Variants {
+		variants[i] = dtos.ExperimentVariantResponse{
+			ID:           variant.ID,
+			Name:         variant.Name,
+			Weight:       variant.Weight,
+			Model:        variant.Model,
+			SystemPrompt: variant.SystemPrompt,
+		}
+	}
+
+	return &dtos.ExperimentResponse{
+		ID:          experiment.ID,
+		Name:        experiment.Name,
+		Description: experiment.Description,
+		Status:      experiment.Status,
+		Variants:    variants,
+		StartedAt:   experiment.StartedAt,
+		StoppedAt:   experiment.StoppedAt,
+		CreatedAt:   experiment.CreatedAt,
+	}
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"io"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// ExportService defines the interface for bulk exports of users' chats
+type ExportService interface {
+	// CreateExport queues an export of all the chats of a user
+	CreateExport(ctx context.Context, userID string) (*dtos.ExportResponse, error)
+
+	// GetExport returns the status of an export of the user, with its download URL once completed
+	GetExport(ctx context.Context, userID string, id int64) (*dtos.ExportResponse, error)
+
+	// ProcessPending packages the pending exports and returns the number processed
+	ProcessPending(ctx context.Context) (int, error)
+
+	// OpenDownload opens the file of a signed download URL
+	OpenDownload(ctx context.Context, req *dtos.DownloadRequest) (io.ReadCloser, error)
+}
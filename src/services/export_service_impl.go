@@ -0,0 +1,405 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// exportPageSize is the number of chats or messages read at once while packaging an export
+const exportPageSize = 500
+
+// exportService implements the ExportService interface
+type exportService struct {
+	config         configs.Export
+	exportRepo     repositories.ExportRepository
+	chatRepo       repositories.ChatRepository
+	messageRepo    repositories.MessageRepository
+	attachmentRepo repositories.AttachmentRepository
+	storage        adapters.StorageAdapter
+	notifications  NotificationService
+	consent        ConsentService
+}
+
+// NewExportService creates a new export service
+func NewExportService(
+	config configs.Export,
+	exportRepo repositories.ExportRepository,
+	chatRepo repositories.ChatRepository,
+	messageRepo repositories.MessageRepository,
+	attachmentRepo repositories.AttachmentRepository,
+	storage adapters.StorageAdapter,
+	notifications NotificationService,
+	consent ConsentService,
+) ExportService {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 5
+	}
+
+	return &exportService{
+		config:         config,
+		exportRepo:     exportRepo,
+		chatRepo:       chatRepo,
+		messageRepo:    messageRepo,
+		attachmentRepo: attachmentRepo,
+		storage:        storage,
+		notifications:  notifications,
+		consent:        consent,
+	}
+}
+
+// CreateExport queues an export of all the chats of a user
+func (s *exportService) CreateExport(ctx context.Context, userID string) (*dtos.ExportResponse, error) {
+	export := &models.Export{
+		UserID: userID,
+		Status: models.ExportStatusPending,
+	}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Export queued", "exportID", export.ID, "userID", userID)
+	return s.toExportResponse(export)
+}
+
+// GetExport returns the status of an export of the user, with its download URL once completed
+func (s *exportService) GetExport(ctx context.Context, userID string, id int64) (*dtos.ExportResponse, error) {
+	export, err := s.exportRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if export.UserID != userID {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this export")
+	}
+
+	return s.toExportResponse(export)
+}
+
+// ProcessPending packages the pending exports and returns the number processed.
+// A failed export is marked as such and does not stop the others.
+func (s *exportService) ProcessPending(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	exports, err := s.exportRepo.ListPending(ctx, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, export := range exports {
+		export.Status = models.ExportStatusRunning
+		if err := s.exportRepo.Update(ctx, export); err != nil {
+			return 0, err
+		}
+
+		if err := s.build(ctx, export); err != nil {
+			log.Errorw("Failed to build export", "error", err, "exportID", export.ID)
+			export.Status = models.ExportStatusFailed
+			export.Error = "Failed to build the export archive"
+		} else {
+			export.Status = models.ExportStatusCompleted
+			log.Infow("Export completed", "exportID", export.ID, "chats", export.Chats, "size", export.Size)
+		}
+
+		now := time.Now()
+		export.CompletedAt = &now
+		if err := s.exportRepo.Update(ctx, export); err != nil {
+			return 0, err
+		}
+
+		if export.Status == models.ExportStatusCompleted {
+			s.notifyReady(ctx, export)
+		}
+	}
+
+	return len(exports), nil
+}
+
+// OpenDownload opens the file of a signed download URL
+func (s *exportService) OpenDownload(ctx context.Context, req *dtos.DownloadRequest) (io.ReadCloser, error) {
+	if !s.storage.Verify(req.Key, req.Expires, req.Signature) {
+		return nil, errors.New(errors.ErrForbidden, "Invalid or expired download URL")
+	}
+
+	file, err := s.storage.Open(ctx, req.Key)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New(errors.ErrNotFound, "File not found")
+		}
+		logger.Context(ctx).Errorw("Failed to open download", "error", err, "key", req.Key)
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to open download")
+	}
+
+	return file, nil
+}
+
+// notifyReady notifies the user that an export is ready; failures are only logged
+// as the download URL is also available from the export status
+func (s *exportService) notifyReady(ctx context.Context, export *models.Export) {
+	response, err := s.toExportResponse(export)
+	if err == nil {
+		err = s.notifications.Notify(ctx, export.UserID, NotificationExportReady, &dtos.ExportReadyNotification{
+			Chats:       export.Chats,
+			DownloadURL: response.DownloadURL,
+			ExpiresAt:   *response.DownloadExpiresAt,
+		})
+	}
+	if err != nil {
+		logger.Context(ctx).Warnw("Failed to notify export ready", "error", err, "exportID", export.ID)
+	}
+}
+
+// build packages the chats of an export into a zip archive, staged in a temporary
+// file, and stores it
+func (s *exportService) build(ctx context.Context, export *models.Export) error {
+	tmp, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	chats, err := s.writeArchive(ctx, tmp, export.UserID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%d/chats-%d.zip", export.ID, export.ID)
+	size, err := s.storage.Put(ctx, key, tmp)
+	if err != nil {
+		return err
+	}
+
+	export.StorageKey = key
+	export.Size = size
+	export.Chats = chats
+	return nil
+}
+
+// writeArchive writes a JSON and a Markdown file per chat of the user, along with the
+// message attachments and the terms the user accepted, into a zip archive and returns the
+// number of chats written. Privacy mode chats are left out, since their messages are never
+// written to storage.
+func (s *exportService) writeArchive(ctx context.Context, w io.Writer, userID string) (int, error) {
+	archive := zip.NewWriter(w)
+
+	if err := s.writeConsent(ctx, archive, userID); err != nil {
+		return 0, err
+	}
+
+	chats := 0
+	for offset := 0; ; offset += exportPageSize {
+		page, err := s.chatRepo.ListByUserID(ctx, userID, exportPageSize, offset)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, chat := range page {
+			if chat.Ephemeral {
+				continue
+			}
+			if err := s.writeChat(ctx, archive, chat); err != nil {
+				return 0, err
+			}
+			chats++
+		}
+
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+	return chats, nil
+}
+
+// writeChat writes the JSON and Markdown files and the attachments of a chat into the archive
+func (s *exportService) writeChat(ctx context.Context, archive *zip.Writer, chat *models.Chat) error {
+	chatExport := dtos.ChatExport{
+		Chat: dtos.ChatResponse{
+			ID:              chat.ID,
+			PublicID:        chat.PublicID,
+			UserID:          chat.UserID,
+			Title:           chat.Title,
+			URLContext:      chat.URLContext,
+			Suggestions:     chat.Suggestions,
+			Language:        chat.Language,
+			Locked:          chat.Locked,
+			LockedBy:        chat.LockedBy,
+			Tags:            chat.TagList(),
+			Archived:        chat.Archived,
+			SystemPrompt:    chat.SystemPrompt,
+			CreatedAt:       chat.CreatedAt,
+			UpdatedAt:       chat.UpdatedAt,
+			PinnedMessageID: chat.PinnedMessageID,
+			Budget:          toChatBudgetResponse(chat),
+		},
+		Messages: []dtos.MessageResponse{},
+	}
+
+	for offset := 0; ; offset += exportPageSize {
+		messages, err := s.messageRepo.ListByChatID(ctx, chat.ID, exportPageSize, offset)
+		if err != nil {
+			return err
+		}
+
+		messageIDs := make([]int64, len(messages))
+		for i, message := range messages {
+			chatExport.Messages = append(chatExport.Messages, *toMessageResponse(message))
+			messageIDs[i] = message.ID
+		}
+
+		attachments, err := s.attachmentRepo.ListByMessageIDs(ctx, messageIDs)
+		if err != nil {
+			return err
+		}
+		for _, attachment := range attachments {
+			exported, err := s.writeAttachment(ctx, archive, attachment)
+			if err != nil {
+				return err
+			}
+			chatExport.Attachments = append(chatExport.Attachments, *exported)
+		}
+
+		if len(messages) < exportPageSize {
+			break
+		}
+	}
+
+	jsonFile, err := archive.Create(fmt.Sprintf("chats/%d.json", chat.ID))
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(jsonFile)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(chatExport); err != nil {
+		return err
+	}
+
+	markdownFile, err := archive.Create(fmt.Sprintf("chats/%d.md", chat.ID))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(markdownFile, toMarkdown(&chatExport))
+	return err
+}
+
+// writeConsent writes the data-usage terms versions the user accepted into the archive
+func (s *exportService) writeConsent(ctx context.Context, archive *zip.Writer, userID string) error {
+	consent, err := s.consent.GetConsent(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	file, err := archive.Create("consent.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(consent)
+}
+
+// writeAttachment copies an attachment from the storage into the archive
+func (s *exportService) writeAttachment(ctx context.Context, archive *zip.Writer, attachment *models.Attachment) (*dtos.ExportAttachment, error) {
+	file, err := s.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Prefix with the attachment ID as filenames are not unique
+	archivePath := fmt.Sprintf("attachments/%d/%d-%s", attachment.MessageID, attachment.ID, attachment.Filename)
+	w, err := archive.Create(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(w, file); err != nil {
+		return nil, err
+	}
+
+	return &dtos.ExportAttachment{
+		MessageID:   attachment.MessageID,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Path:        archivePath,
+	}, nil
+}
+
+// toExportResponse converts an export to its response DTO, signing a download URL
+// when it is completed
+func (s *exportService) toExportResponse(export *models.Export) (*dtos.ExportResponse, error) {
+	response := &dtos.ExportResponse{
+		ID:          export.ID,
+		Status:      export.Status,
+		Chats:       export.Chats,
+		Size:        export.Size,
+		Error:       export.Error,
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+	}
+
+	if export.Status == models.ExportStatusCompleted {
+		url, err := s.storage.SignedURL(export.StorageKey, s.config.URLTTL)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrInternal, "Failed to sign download URL")
+		}
+		expiresAt := time.Now().Add(s.config.URLTTL)
+		response.DownloadURL = url
+		response.DownloadExpiresAt = &expiresAt
+	}
+
+	return response, nil
+}
+
+// toMarkdown renders a chat transcript as Markdown
+func toMarkdown(chatExport *dtos.ChatExport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", chatExport.Chat.Title)
+	fmt.Fprintf(&b, "_Created %s_\n", chatExport.Chat.CreatedAt.UTC().Format(time.RFC3339))
+
+	for _, message := range chatExport.Messages {
+		fmt.Fprintf(&b, "\n## %s · %s\n\n%s\n", message.Role, message.CreatedAt.UTC().Format(time.RFC3339), markdownContent(&message))
+	}
+	return b.String()
+}
+
+// markdownEscaper escapes the characters of plain text Markdown would format
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`, `~`, `\~`,
+)
+
+// markdownContent renders the content of a message as Markdown according to its content
+// type: JSON in a code block, and plain text escaped with its line breaks kept
+func markdownContent(message *dtos.MessageResponse) string {
+	switch message.ContentType {
+	case models.ContentTypeJSON:
+		return "```json\n" + message.Content + "\n```"
+	case models.ContentTypePlain:
+		lines := strings.Split(markdownEscaper.Replace(message.Content), "\n")
+		return strings.Join(lines, "  \n")
+	default:
+		return message.Content
+	}
+}
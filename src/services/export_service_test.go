@@ -0,0 +1,93 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportChatRepo lists the chats of a user; other methods are not implemented
+type exportChatRepo struct {
+	repositories.ChatRepository
+	chats []*models.Chat
+}
+
+func (r *exportChatRepo) ListByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Chat, error) {
+	if offset >= len(r.chats) {
+		return nil, nil
+	}
+	return r.chats[offset:min(offset+limit, len(r.chats))], nil
+}
+
+// exportMessageRepo lists the messages of chats, recording the chats read
+type exportMessageRepo struct {
+	repositories.MessageRepository
+	messages map[int64][]*models.Message
+	read     []int64
+}
+
+func (r *exportMessageRepo) ListByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*models.Message, error) {
+	r.read = append(r.read, chatID)
+	messages := r.messages[chatID]
+	if offset >= len(messages) {
+		return nil, nil
+	}
+	return messages[offset:min(offset+limit, len(messages))], nil
+}
+
+// exportAttachmentRepo holds no attachments
+type exportAttachmentRepo struct {
+	repositories.AttachmentRepository
+}
+
+func (r *exportAttachmentRepo) ListByMessageIDs(ctx context.Context, messageIDs []int64) ([]*models.Attachment, error) {
+	return nil, nil
+}
+
+// exportConsent returns no accepted terms
+type exportConsent struct {
+	ConsentService
+}
+
+func (c *exportConsent) GetConsent(ctx context.Context, userID string) (*dtos.ConsentResponse, error) {
+	return &dtos.ConsentResponse{}, nil
+}
+
+func TestWriteArchiveSkipsPrivacyModeChats(t *testing.T) {
+	messageRepo := &exportMessageRepo{messages: map[int64][]*models.Message{
+		1: {{ID: 10, ChatID: 1, Role: models.MessageRoleUser, Content: "kept"}},
+		2: {{ID: models.EphemeralMessageID(2, 1), ChatID: 2, Role: models.MessageRoleUser, Content: "private"}},
+	}}
+	s := &exportService{
+		chatRepo: &exportChatRepo{chats: []*models.Chat{
+			{ID: 1, UserID: "user", Title: "Regular"},
+			{ID: 2, UserID: "user", Title: "Private", Ephemeral: true},
+		}},
+		messageRepo:    messageRepo,
+		attachmentRepo: &exportAttachmentRepo{},
+		consent:        &exportConsent{},
+	}
+
+	var buf bytes.Buffer
+	chats, err := s.writeArchive(context.Background(), &buf, "user")
+	require.NoError(t, err)
+	assert.Equal(t, 1, chats)
+
+	// The messages of the privacy mode chat are not even read
+	assert.Equal(t, []int64{1}, messageRepo.read)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.ElementsMatch(t, []string{"consent.json", "chats/1.json", "chats/1.md"}, names)
+}
@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// Guardrail rule types
+const (
+	GuardrailRegex      = "regex"
+	GuardrailJSONSchema = "json_schema"
+	GuardrailModel      = "model"
+)
+
+// Guardrail actions, applied to the replies violating a rule
+const (
+	GuardrailBlock      = "block"
+	GuardrailRewrite    = "rewrite"
+	GuardrailDisclaimer = "disclaimer"
+)
+
+// guardrailCheckInstructions is the system prompt of the secondary model checking replies
+const guardrailCheckInstructions = "You check whether an assistant reply violates a content policy. " +
+	"Reply with a JSON object only, without formatting: " +
+	`{"violation": <true or false>, "reason": "<one sentence explaining the violation, empty otherwise>"}`
+
+// guardrailRewriteInstructions is the system prompt of the secondary model rewriting replies
+const guardrailRewriteInstructions = "You rewrite an assistant reply so that it meets a requirement, changing as " +
+	"little of it as possible. Reply with the rewritten reply only."
+
+// GuardrailViolation is a rule an assistant reply violated, and the action applied to it
+type GuardrailViolation struct {
+	Rule   string
+	Action string
+	Reason string
+}
+
+// Guardrails checks assistant replies against output policies once they are generated,
+// blocking, rewriting or appending disclaimers to those violating them
+type Guardrails interface {
+	// Check checks a reply against the rules of a workspace policy, nil for every rule, and
+	// returns its content with the actions of the rules it violates applied
+	Check(ctx context.Context, content string, rules []string) (string, []GuardrailViolation)
+	// Record writes the violations of a saved reply to the audit log
+	Record(ctx context.Context, chat *models.Chat, message *models.Message, violations []GuardrailViolation)
+}
+
+// guardrailRule is a configured rule, compiled
+type guardrailRule struct {
+	configs.GuardrailRule
+	pattern *regexp.Regexp
+	schema  map[string]any
+}
+
+// guardrails implements the Guardrails interface
+type guardrails struct {
+	config       configs.Guardrails
+	rules        []*guardrailRule
+	llmAdapter   adapters.LLMAdapter
+	auditLogRepo repositories.AuditLogRepository
+}
+
+// NewGuardrails creates the guardrails of the configured rules, rejecting invalid rules
+func NewGuardrails(config configs.Guardrails, llmAdapter adapters.LLMAdapter, auditLogRepo repositories.AuditLogRepository) (Guardrails, error) {
+	g := &guardrails{config: config, llmAdapter: llmAdapter, auditLogRepo: auditLogRepo}
+	names := make(map[string]bool)
+	for i, rule := range config.Rules {
+		if rule.Name == "" || names[rule.Name] || strings.Contains(rule.Name, ",") {
+			return nil, fmt.Errorf("guardrail rule %d needs a unique name without commas", i)
+		}
+		names[rule.Name] = true
+
+		compiled := &guardrailRule{GuardrailRule: rule}
+		switch rule.Type {
+		case GuardrailRegex:
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil || rule.Pattern == "" {
+				return nil, fmt.Errorf("guardrail rule %q needs a valid pattern: %v", rule.Name, err)
+			}
+			compiled.pattern = pattern
+		case GuardrailJSONSchema:
+			if err := json.Unmarshal([]byte(rule.Schema), &compiled.schema); err != nil {
+				return nil, fmt.Errorf("guardrail rule %q needs a JSON schema object: %w", rule.Name, err)
+			}
+		case GuardrailModel:
+			if rule.Policy == "" {
+				return nil, fmt.Errorf("guardrail rule %q needs a policy", rule.Name)
+			}
+		default:
+			return nil, fmt.Errorf("guardrail rule %q has unknown type %q", rule.Name, rule.Type)
+		}
+
+		switch rule.Action {
+		case GuardrailBlock, GuardrailRewrite:
+		case GuardrailDisclaimer:
+			if rule.Disclaimer == "" {
+				return nil, fmt.Errorf("guardrail rule %q needs a disclaimer", rule.Name)
+			}
+		default:
+			return nil, fmt.Errorf("guardrail rule %q has unknown action %q", rule.Name, rule.Action)
+		}
+		g.rules = append(g.rules, compiled)
+	}
+
+	return g, nil
+}
+
+// Check checks a reply against the rules in order. A blocking rule replaces the reply with
+// the blocked message and ends the check; rewrites apply to the content the next rules
+// check, and disclaimers are appended once every rule is checked.
+func (g *guardrails) Check(ctx context.Context, content string, rules []string) (string, []GuardrailViolation) {
+	log := logger.Context(ctx)
+
+	var violations []GuardrailViolation
+	var disclaimers []string
+	for _, rule := range g.rules {
+		if rules != nil && !slices.Contains(rules, rule.Name) {
+			continue
+		}
+
+		reason, err := g.violation(ctx, rule, content)
+		if err != nil {
+			log.Warnw("Failed to check reply against guardrail", "error", err, "rule", rule.Name)
+			if !g.config.BlockOnError {
+				continue
+			}
+			reason = "The reply could not be checked: " + err.Error()
+		}
+		if reason == "" {
+			continue
+		}
+
+		action := rule.Action
+		if err != nil {
+			action = GuardrailBlock
+		}
+		switch action {
+		case GuardrailRewrite:
+			rewritten, err := g.rewrite(ctx, rule, content)
+			if err == nil {
+				content = rewritten
+				break
+			}
+			log.Warnw("Failed to rewrite reply, blocking it", "error", err, "rule", rule.Name)
+			action = GuardrailBlock
+		case GuardrailDisclaimer:
+			if !slices.Contains(disclaimers, rule.Disclaimer) {
+				disclaimers = append(disclaimers, rule.Disclaimer)
+			}
+		}
+
+		violations = append(violations, GuardrailViolation{Rule: rule.Name, Action: action, Reason: reason})
+		if action == GuardrailBlock {
+			return g.config.BlockedMessage, violations
+		}
+	}
+
+	if len(disclaimers) > 0 {
+		content = strings.TrimRight(content, "\n") + "\n\n" + strings.Join(disclaimers, "\n\n")
+	}
+	return content, violations
+}
+
+// violation returns why a reply violates a rule, or nothing when it does not
+func (g *guardrails) violation(ctx context.Context, rule *guardrailRule, content string) (string, error) {
+	switch rule.Type {
+	case GuardrailRegex:
+		if rule.pattern.MatchString(content) {
+			return "The reply matches the rule pattern", nil
+		}
+		return "", nil
+	case GuardrailJSONSchema:
+		var value any
+		if err := json.Unmarshal([]byte(unfenceJSON(content)), &value); err != nil {
+			return "The reply is not JSON", nil
+		}
+		if err := validateJSONSchema(rule.schema, value, "$"); err != nil {
+			return "The reply does not match the schema: " + err.Error(), nil
+		}
+		return "", nil
+	default:
+		response, err := g.generate(ctx, guardrailCheckInstructions, "Policy:\n"+rule.Policy+"\n\nReply:\n"+content)
+		if err != nil {
+			return "", err
+		}
+		start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+		var verdict struct {
+			Violation bool   `json:"violation"`
+			Reason    string `json:"reason"`
+		}
+		if start < 0 || end < start || json.Unmarshal([]byte(response[start:end+1]), &verdict) != nil {
+			return "", fmt.Errorf("the guardrail model did not reply with a verdict")
+		}
+		if !verdict.Violation {
+			return "", nil
+		}
+		if verdict.Reason == "" {
+			verdict.Reason = "The reply violates the rule policy"
+		}
+		return verdict.Reason, nil
+	}
+}
+
+// rewrite rewrites a reply violating a rule: regex rules replace the matches, the other
+// rules have the secondary model rewrite the reply to meet them
+func (g *guardrails) rewrite(ctx context.Context, rule *guardrailRule, content string) (string, error) {
+	switch rule.Type {
+	case GuardrailRegex:
+		return rule.pattern.ReplaceAllString(content, rule.Replacement), nil
+	case GuardrailJSONSchema:
+		rewritten, err := g.generate(ctx, guardrailRewriteInstructions,
+			"Requirement:\nThe reply is JSON only, matching this JSON schema:\n"+rule.Schema+"\n\nReply:\n"+content)
+		if err != nil {
+			return "", err
+		}
+		// The rewrite is only kept when it does meet the schema
+		if reason, err := g.violation(ctx, rule, rewritten); err != nil || reason != "" {
+			return "", fmt.Errorf("the rewritten reply does not match the schema")
+		}
+		return unfenceJSON(rewritten), nil
+	default:
+		return g.generate(ctx, guardrailRewriteInstructions, "Requirement:\n"+rule.Policy+"\n\nReply:\n"+content)
+	}
+}
+
+// generate prompts the secondary model
+func (g *guardrails) generate(ctx context.Context, instructions, prompt string) (string, error) {
+	model := g.config.Model
+	if model == "" {
+		model = configs.AppConfig.LLM.Model
+	}
+	llmCtx, cancel := llmContext(ctx, 0)
+	defer cancel()
+
+	response, err := g.llmAdapter.GenerateResponse(llmCtx, &dtos.LLMRequest{
+		Model: model,
+		Messages: []dtos.LLMMessage{
+			{Role: "system", Content: instructions},
+			{Role: models.MessageRoleUser, Content: prompt},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return response.Message.Content, nil
+}
+
+// Record writes each violation of a saved reply to the audit log. Failures are only logged,
+// as the reply is already saved.
+func (g *guardrails) Record(ctx context.Context, chat *models.Chat, message *models.Message, violations []GuardrailViolation) {
+	for _, violation := range violations {
+		logger.Context(ctx).Warnw("Reply violated guardrail", "rule", violation.Rule, "action", violation.Action,
+			"chatID", chat.ID, "messageID", message.ID)
+		if err := g.auditLogRepo.Create(ctx, &models.AuditLog{
+			UserID:    chat.UserID,
+			Action:    models.AuditActionGuardrailViolation,
+			Reason:    fmt.Sprintf("%s (%s): %s", violation.Rule, violation.Action, violation.Reason),
+			RequestID: logger.GetRequestID(ctx),
+			ChatID:    &chat.ID,
+			MessageID: &message.ID,
+		}); err != nil {
+			logger.Context(ctx).Errorw("Failed to record guardrail violation", "error", err, "messageID", message.ID)
+		}
+	}
+}
+
+// unfenceJSON returns the JSON of a reply made of a single fenced code block, which models
+// tend to wrap JSON in, or else the trimmed reply
+func unfenceJSON(content string) string {
+	content = strings.TrimSpace(content)
+	lines := strings.Split(content, "\n")
+	if len(lines) >= 2 && isFence(lines[0]) && isFence(lines[len(lines)-1]) {
+		return strings.Join(lines[1:len(lines)-1], "\n")
+	}
+	return content
+}
+
+// validateJSONSchema validates a JSON value against the keywords of a JSON schema commonly
+// used to describe replies: type, enum, properties, required, additionalProperties, items,
+// minLength, maxLength, minimum, maximum, minItems and maxItems. Other keywords are ignored.
+func validateJSONSchema(schema map[string]any, value any, path string) error {
+	if schemaType, ok := schema["type"]; ok {
+		types, _ := schemaType.([]any)
+		if name, ok := schemaType.(string); ok {
+			types = []any{name}
+		}
+		if !slices.ContainsFunc(types, func(t any) bool { return jsonTypeMatches(t, value) }) {
+			return fmt.Errorf("%s is not of type %v", path, schemaType)
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		encoded, _ := json.Marshal(value)
+		if !slices.ContainsFunc(enum, func(option any) bool {
+			encodedOption, _ := json.Marshal(option)
+			return string(encodedOption) == string(encoded)
+		}) {
+			return fmt.Errorf("%s is not one of %v", path, enum)
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if name, ok := name.(string); ok {
+					if _, ok := value[name]; !ok {
+						return fmt.Errorf("%s.%s is required", path, name)
+					}
+				}
+			}
+		}
+		for name, property := range value {
+			propertySchema, ok := properties[name].(map[string]any)
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s.%s is not allowed", path, name)
+				}
+				continue
+			}
+			if err := validateJSONSchema(propertySchema, property, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if err := checkBounds(schema, "minItems", "maxItems", float64(len(value)), path+" items"); err != nil {
+			return err
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				if err := validateJSONSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		return checkBounds(schema, "minLength", "maxLength", float64(len([]rune(value))), path+" length")
+	case float64:
+		return checkBounds(schema, "minimum", "maximum", value, path)
+	}
+	return nil
+}
+
+// jsonTypeMatches reports whether a JSON value is of a JSON schema type
+func jsonTypeMatches(schemaType any, value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return schemaType == "null"
+	case bool:
+		return schemaType == "boolean"
+	case string:
+		return schemaType == "string"
+	case float64:
+		return schemaType == "number" || (schemaType == "integer" && value == math.Trunc(value))
+	case []any:
+		return schemaType == "array"
+	case map[string]any:
+		return schemaType == "object"
+	}
+	return false
+}
+
+// checkBounds checks a quantity against the lower and upper bound keywords of a schema
+func checkBounds(schema map[string]any, lower, upper string, quantity float64, what string) error {
+	if bound, ok := schema[lower].(float64); ok && quantity < bound {
+		return fmt.Errorf("%s is below %s %v", what, lower, bound)
+	}
+	if bound, ok := schema[upper].(float64); ok && quantity > bound {
+		return fmt.Errorf("%s is above %s %v", what, upper, bound)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// GuestService defines the interface for anonymous guest sessions
+type GuestService interface {
+	// StartSession issues a signed token for a new guest session
+	StartSession(ctx context.Context) (*dtos.GuestSessionResponse, error)
+
+	// ClaimChats moves the chats of a guest session to a registered user
+	ClaimChats(ctx context.Context, guestID, userID string) (*dtos.ClaimGuestChatsResponse, error)
+
+	// PurgeExpired deletes the unclaimed chats of expired guest sessions and
+	// returns the number of chats deleted
+	PurgeExpired(ctx context.Context) (int, error)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// guestPurgeBatchSize bounds the chats deleted per purge run
+const guestPurgeBatchSize = 100
+
+// guestService implements the GuestService interface
+type guestService struct {
+	config      configs.Guest
+	secret      string
+	chatRepo    repositories.ChatRepository
+	chatService ChatService
+	events      EventPublisher
+}
+
+// NewGuestService creates a new guest service signing session tokens with secret
+func NewGuestService(
+	config configs.Guest,
+	secret string,
+	chatRepo repositories.ChatRepository,
+	chatService ChatService,
+	events EventPublisher,
+) GuestService {
+	return &guestService{
+		config:      config,
+		secret:      secret,
+		chatRepo:    chatRepo,
+		chatService: chatService,
+		events:      events,
+	}
+}
+
+// StartSession issues a signed token for a new guest session
+func (s *guestService) StartSession(ctx context.Context) (*dtos.GuestSessionResponse, error) {
+	log := logger.Context(ctx)
+
+	guestID := models.GuestUserPrefix + uuid.NewString()
+	now := time.Now()
+	expiresAt := now.Add(s.config.TTL)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":  guestID,
+		"role": models.RoleGuest,
+		"iat":  now.Unix(),
+		"exp":  expiresAt.Unix(),
+	})
+	signed, err := token.SignedString([]byte(s.secret))
+	if err != nil {
+		log.Errorw("Failed to sign guest token", "error", err)
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to start guest session")
+	}
+
+	log.Infow("Guest session started", "userID", guestID)
+	return &dtos.GuestSessionResponse{
+		Token:     signed,
+		UserID:    guestID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// ClaimChats moves the chats of a guest session to a registered user
+func (s *guestService) ClaimChats(ctx context.Context, guestID, userID string) (*dtos.ClaimGuestChatsResponse, error) {
+	log := logger.Context(ctx)
+
+	if !models.IsGuest(guestID) {
+		return nil, errors.New(errors.ErrInvalidRequest, "Token does not belong to a guest session")
+	}
+	if models.IsGuest(userID) {
+		return nil, errors.New(errors.ErrForbidden, "Guests cannot claim chats")
+	}
+
+	chats, err := s.chatRepo.TransferOwnership(ctx, guestID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chat := range chats {
+		event := newEvent(ctx, models.EventChatUpdated, dtos.ChatPayload{
+			ChatID: chat.ID,
+			UserID: chat.UserID,
+			Title:  chat.Title,
+		})
+		if err := s.events.PublishChatEvent(ctx, event); err != nil {
+			log.Errorw("Failed to publish chat updated event", "error", err, "chatID", chat.ID)
+		}
+	}
+
+	log.Infow("Guest chats claimed", "guestID", guestID, "userID", userID, "chats", len(chats))
+	return &dtos.ClaimGuestChatsResponse{Claimed: len(chats)}, nil
+}
+
+// PurgeExpired deletes the unclaimed chats of expired guest sessions
+func (s *guestService) PurgeExpired(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	// A chat outlives its session by at most the session TTL
+	ids, err := s.chatRepo.ListIDsCreatedBefore(ctx, models.GuestUserPrefix, time.Now().Add(-s.config.TTL), guestPurgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := s.chatService.DeleteChat(ctx, id); err != nil {
+			log.Errorw("Failed to delete guest chat", "error", err, "chatID", id)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// detachedWriteTimeout bounds writes performed after the request context is gone
+const detachedWriteTimeout = 5 * time.Second
+
+// llmContext derives the context for an LLM call. The client-requested timeout is
+// honored but bounded by llm.maxTimeout; without one the configured default applies.
+func llmContext(ctx context.Context, timeoutMs int) (context.Context, context.CancelFunc) {
+	cfg := configs.AppConfig.LLM
+
+	timeout := cfg.Timeout
+	if timeoutMs > 0 {
+		timeout = time.Duration(timeoutMs) * time.Millisecond
+		if cfg.MaxTimeout > 0 && timeout > cfg.MaxTimeout {
+			timeout = cfg.MaxTimeout
+		}
+	}
+
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// detachedContext returns a short-lived context that keeps the values of ctx but not its
+// cancellation, so results already produced are still persisted when the client disconnects
+func detachedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), detachedWriteTimeout)
+}
+
+// checkChatOwner rejects a user who does not own a chat. Chat and message routes are
+// authorized by the LoadChat and LoadMessage middlewares; this checks the chats whose ID comes
+// from elsewhere, such as a batch or the conversation of an integration.
+func checkChatOwner(owner, userID string) error {
+	if owner != userID {
+		return errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+	return nil
+}
+
+// checkUnlocked rejects changes to a locked chat and its messages, or to a chat pending deletion
+func checkUnlocked(chat *models.Chat) error {
+	if chat.Locked {
+		return errors.New(errors.ErrChatLocked, "The chat is locked and can only be read")
+	}
+	return checkNotPendingDeletion(chat)
+}
+
+// checkNotPendingDeletion rejects changes to a chat pending deletion, until it is restored
+func checkNotPendingDeletion(chat *models.Chat) error {
+	if chat.PendingDeletion() {
+		return errors.New(errors.ErrConflict, "The chat is pending deletion")
+	}
+	return nil
+}
+
+// checkChatBudget rejects the messages sent to a chat that has used its budget, unless the
+// chat only warns about it
+func checkChatBudget(chat *models.Chat) error {
+	if chat.BudgetExceeded() && chat.BudgetMode != models.ChatBudgetModeWarn {
+		return errors.New(errors.ErrQuotaExceeded, "The chat has used its budget")
+	}
+	return nil
+}
+
+// eventContent returns the content of a message to publish in events: a preview when the
+// content is kept in the storage, so large messages do not bloat events
+func eventContent(message *models.Message) string {
+	if message.ContentKey != "" {
+		return models.ContentPreview(message.Content)
+	}
+	return message.Content
+}
+
+// eventContentHash returns the hash of the content of a message whose events carry a preview
+func eventContentHash(message *models.Message) string {
+	if message.ContentKey == "" {
+		return ""
+	}
+	return contentHash(message.Content)
+}
+
+// contentHash returns the hex SHA-256 of content
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a UTF-8 character
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// fileExtension returns the lowercased extension of a filename, or an empty string when
+// it is not alphanumeric, so it is safe to use in storage keys
+func fileExtension(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, r := range strings.TrimPrefix(ext, ".") {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// isEmoji reports whether s can be an emoji: it has a symbol outside of ASCII and no
+// letters, spaces or control characters. Digits, # and * are allowed for keycaps.
+func isEmoji(s string) bool {
+	symbol := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+		if r < utf8.RuneSelf {
+			if (r < '0' || r > '9') && r != '#' && r != '*' {
+				return false
+			}
+			continue
+		}
+		symbol = true
+	}
+	return symbol
+}
+
+// AvailableModels returns the models replies can be requested from: the LLM model and the
+// models of comparisons
+func AvailableModels(config configs.LLM) []string {
+	models := []string{config.Model}
+	for _, model := range config.CompareModels {
+		if !slices.Contains(models, model) {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// CheckModel returns a model not found error when a model is set but not available
+func CheckModel(config configs.LLM, model string) error {
+	if model != "" && !slices.Contains(AvailableModels(config), model) {
+		return errors.New(errors.ErrModelNotFound, fmt.Sprintf("The model `%s` does not exist or you do not have access to it.", model))
+	}
+	return nil
+}
+
+// checkContent checks that content is within the maximum size of messages and valid for its
+// content type
+func checkContent(content, contentType string) error {
+	if maxSize := configs.AppConfig.MessageContent.MaxSize; maxSize > 0 && len(content) > maxSize {
+		return errors.New(errors.ErrPayloadTooLarge, fmt.Sprintf("Content exceeds the maximum size of %d bytes", maxSize))
+	}
+	if contentType == models.ContentTypeJSON && !json.Valid([]byte(content)) {
+		return errors.New(errors.ErrInvalidRequest, "Content is not valid JSON")
+	}
+	return nil
+}
+
+// replyContentType returns the content type of an assistant reply: JSON when the whole reply
+// is a JSON object or array, and Markdown otherwise
+func replyContentType(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return models.ContentTypeJSON
+	}
+	return models.ContentTypeMarkdown
+}
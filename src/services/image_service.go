@@ -0,0 +1,14 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// ImageService defines the interface for generating images in chats. The prompt is saved
+// as a user message and the images as attachments of the assistant message answering it.
+type ImageService interface {
+	// GenerateImages generates images of a prompt in a chat of the user
+	GenerateImages(ctx context.Context, chatID int64, userID string, req *dtos.ImageRequest) (*dtos.ImageExchangeResponse, error)
+}
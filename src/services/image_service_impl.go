@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// imageService implements the ImageService interface
+type imageService struct {
+	config         configs.Images
+	chatRepo       repositories.ChatRepository
+	messageRepo    repositories.MessageRepository
+	attachmentRepo repositories.AttachmentRepository
+	storage        adapters.StorageAdapter
+	images         adapters.ImageAdapter
+	attachments    AttachmentService
+	budgets        BudgetService
+	consent        ConsentService
+	events         EventPublisher
+}
+
+// NewImageService creates a new image generation service
+func NewImageService(
+	config configs.Images,
+	chatRepo repositories.ChatRepository,
+	messageRepo repositories.MessageRepository,
+	attachmentRepo repositories.AttachmentRepository,
+	storage adapters.StorageAdapter,
+	images adapters.ImageAdapter,
+	attachments AttachmentService,
+	budgets BudgetService,
+	consent ConsentService,
+	events EventPublisher,
+) ImageService {
+	if config.MaxImages <= 0 {
+		config.MaxImages = 1
+	}
+
+	return &imageService{
+		config:         config,
+		chatRepo:       chatRepo,
+		messageRepo:    messageRepo,
+		attachmentRepo: attachmentRepo,
+		storage:        storage,
+		images:         images,
+		attachments:    attachments,
+		budgets:        budgets,
+		consent:        consent,
+		events:         events,
+	}
+}
+
+// GenerateImages generates images of a prompt in a chat of the user. The images are
+// generated before any message is saved, so a failed generation leaves the chat unchanged.
+func (s *imageService) GenerateImages(ctx context.Context, chatID int64, userID string, req *dtos.ImageRequest) (*dtos.ImageExchangeResponse, error) {
+	log := logger.Context(ctx)
+
+	if !s.config.Enabled {
+		return nil, errors.New(errors.ErrInvalidRequest, "Image generation is not enabled")
+	}
+	if models.IsGuest(userID) {
+		return nil, errors.New(errors.ErrForbidden, "Image generation is not available to guests")
+	}
+	count := max(req.Count, 1)
+	if count > s.config.MaxImages {
+		return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("At most %d images can be generated at once", s.config.MaxImages))
+	}
+
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID != userID {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return nil, err
+	}
+	// Generated images are stored as attachments
+	if chat.Ephemeral {
+		return nil, errors.New(errors.ErrInvalidRequest, "Images cannot be generated in privacy mode chats")
+	}
+	if err := checkChatBudget(chat); err != nil {
+		return nil, err
+	}
+	if err := s.consent.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.budgets.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	images, err := s.images.Generate(ctx, req.Prompt, count)
+	if err != nil {
+		log.Errorw("Failed to generate images", "error", err, "chatID", chatID)
+		return nil, errors.Wrap(err, errors.ErrLLMService, "Failed to generate images")
+	}
+	if len(images) == 0 {
+		return nil, errors.New(errors.ErrLLMService, "No image was generated")
+	}
+
+	// Store the images first so the assistant message is never left without them
+	keys := make([]string, len(images))
+	for i, image := range images {
+		keys[i] = "attachments/" + uuid.NewString() + ".png"
+		if _, err := s.storage.Put(ctx, keys[i], bytes.NewReader(image.Data)); err != nil {
+			log.Errorw("Failed to store generated image", "error", err, "chatID", chatID)
+			s.deleteImages(ctx, keys[:i])
+			return nil, errors.Wrap(err, errors.ErrInternal, "Failed to store generated image")
+		}
+	}
+
+	// The assistant message describes the images with the prompt the provider used
+	content := req.Prompt
+	if images[0].RevisedPrompt != "" {
+		content = images[0].RevisedPrompt
+	}
+	userMessage := &models.Message{
+		ChatID:  chatID,
+		UserID:  &userID,
+		Role:    models.MessageRoleUser,
+		Content: req.Prompt,
+	}
+	assistantMessage := &models.Message{
+		ChatID:    chatID,
+		Role:      models.MessageRoleAssistant,
+		Content:   content,
+		Model:     s.config.Model,
+		LatencyMs: time.Since(startTime).Milliseconds(),
+	}
+	if err := s.messageRepo.CreateBatch(ctx, []*models.Message{userMessage, assistantMessage}); err != nil {
+		s.deleteImages(ctx, keys)
+		return nil, err
+	}
+
+	for i, image := range images {
+		attachment := &models.Attachment{
+			MessageID:   assistantMessage.ID,
+			Filename:    fmt.Sprintf("image-%d.png", i+1),
+			ContentType: image.ContentType,
+			Size:        int64(len(image.Data)),
+			StorageKey:  keys[i],
+		}
+		if err := s.attachmentRepo.Create(ctx, attachment); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.budgets.RecordImageUsage(ctx, userID, chatID, assistantMessage.ID, s.config.Model, len(images)); err != nil {
+		log.Errorw("Failed to record image usage", "error", err, "messageID", assistantMessage.ID)
+	}
+	if err := s.chatRepo.AddUsage(ctx, chatID, 0, s.budgets.EstimateImageCost(s.config.Model, len(images))); err != nil {
+		log.Errorw("Failed to add chat usage", "error", err, "messageID", assistantMessage.ID)
+	}
+	s.publishCreated(ctx, userMessage, assistantMessage)
+
+	attachments, err := s.attachments.ListAttachments(ctx, userID, assistantMessage.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infow("Images generated", "chatID", chatID, "messageID", assistantMessage.ID, "count", len(images))
+	return &dtos.ImageExchangeResponse{
+		UserMessage:      *toMessageResponse(userMessage),
+		AssistantMessage: *toMessageResponse(assistantMessage),
+		Images:           attachments.Attachments,
+	}, nil
+}
+
+// publishCreated publishes the message created events of an image exchange; failures are only logged
+func (s *imageService) publishCreated(ctx context.Context, messages ...*models.Message) {
+	for _, message := range messages {
+		event := newEvent(ctx, models.EventMessageCreated, dtos.MessagePayload{
+			MessageID:        message.ID,
+			SeqNo:            message.SeqNo,
+			ChatID:           message.ChatID,
+			UserID:           message.UserID,
+			Role:             message.Role,
+			Content:          eventContent(message),
+			ContentType:      message.ContentType,
+			ContentTruncated: message.ContentKey != "",
+			ContentHash:      eventContentHash(message),
+			Model:            message.Model,
+		})
+		if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+			logger.Context(ctx).Errorw("Failed to publish message event", "error", err, "messageID", message.ID)
+		}
+	}
+}
+
+// deleteImages deletes stored images no message refers to; failures are only logged
+func (s *imageService) deleteImages(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			logger.Context(ctx).Warnw("Failed to delete generated image", "error", err, "key", key)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// InstructionService defines the interface for the custom instructions layered into prompts
+type InstructionService interface {
+	// GetUserInstructions returns the custom instructions of a user
+	GetUserInstructions(ctx context.Context, userID string) (*dtos.UserInstructionsResponse, error)
+
+	// SetUserInstructions sets the custom instructions of a user
+	SetUserInstructions(ctx context.Context, userID string, req *dtos.UserInstructionsRequest) (*dtos.UserInstructionsResponse, error)
+
+	// GetChatInstructions returns the instruction layers of a chat, in the order they are sent
+	// to the model, for the owner of the chat or an admin
+	GetChatInstructions(ctx context.Context, chatID int64, userID string, admin bool) (*dtos.ChatInstructionsResponse, error)
+}
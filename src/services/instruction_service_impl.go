@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// instructionService implements the InstructionService interface
+type instructionService struct {
+	preferenceRepo repositories.UserPreferenceRepository
+	chatRepo       repositories.ChatRepository
+	promptBuilder  PromptBuilder
+}
+
+// NewInstructionService creates a new instruction service
+func NewInstructionService(preferenceRepo repositories.UserPreferenceRepository, chatRepo repositories.ChatRepository, promptBuilder PromptBuilder) InstructionService {
+	return &instructionService{
+		preferenceRepo: preferenceRepo,
+		chatRepo:       chatRepo,
+		promptBuilder:  promptBuilder,
+	}
+}
+
+// GetUserInstructions returns the custom instructions of a user, empty when they set none
+func (s *instructionService) GetUserInstructions(ctx context.Context, userID string) (*dtos.UserInstructionsResponse, error) {
+	preference, err := s.preferenceRepo.Get(ctx, userID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+			return &dtos.UserInstructionsResponse{}, nil
+		}
+		return nil, err
+	}
+
+	return &dtos.UserInstructionsResponse{Instructions: preference.Instructions, UpdatedAt: &preference.UpdatedAt}, nil
+}
+
+// SetUserInstructions sets the custom instructions of a user
+func (s *instructionService) SetUserInstructions(ctx context.Context, userID string, req *dtos.UserInstructionsRequest) (*dtos.UserInstructionsResponse, error) {
+	preference := &models.UserPreference{UserID: userID, Instructions: req.Instructions}
+	if err := s.preferenceRepo.Upsert(ctx, preference); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("User instructions updated", "userID", userID, "length", len(req.Instructions))
+	return &dtos.UserInstructionsResponse{Instructions: preference.Instructions, UpdatedAt: &preference.UpdatedAt}, nil
+}
+
+// GetChatInstructions returns the instruction layers of a chat, for the owner of the chat or
+// an admin
+func (s *instructionService) GetChatInstructions(ctx context.Context, chatID int64, userID string, admin bool) (*dtos.ChatInstructionsResponse, error) {
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID != userID && !admin {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+
+	layers, err := s.promptBuilder.Instructions(ctx, chat)
+	if err != nil {
+		return nil, err
+	}
+	if layers == nil {
+		layers = []dtos.InstructionLayer{}
+	}
+
+	return &dtos.ChatInstructionsResponse{ChatID: chat.ID, Layers: layers}, nil
+}
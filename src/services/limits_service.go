@@ -0,0 +1,14 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// LimitsService defines the interface for the limits in effect for users
+type LimitsService interface {
+	// GetLimits returns the limits in effect for a user, from the configuration, the workspace
+	// defaults, the guest allowance and the budgets
+	GetLimits(ctx context.Context, userID string) (*dtos.LimitsResponse, error)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// limitsService implements the LimitsService interface
+type limitsService struct {
+	config        configs.Config
+	messageRepo   repositories.MessageRepository
+	workspaceRepo repositories.WorkspaceRepository
+	budgets       BudgetService
+}
+
+// NewLimitsService creates a new limits service
+func NewLimitsService(config configs.Config, messageRepo repositories.MessageRepository, workspaceRepo repositories.WorkspaceRepository, budgets BudgetService) LimitsService {
+	return &limitsService{
+		config:        config,
+		messageRepo:   messageRepo,
+		workspaceRepo: workspaceRepo,
+		budgets:       budgets,
+	}
+}
+
+// GetLimits returns the limits in effect for a user, from the configuration, the workspace
+// defaults, the guest allowance and the budgets
+func (s *limitsService) GetLimits(ctx context.Context, userID string) (*dtos.LimitsResponse, error) {
+	settings, err := workspaceDefaults(ctx, s.workspaceRepo)
+	if err != nil {
+		return nil, err
+	}
+	budget, err := s.budgets.Quota(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	llm := s.config.LLM
+	limits := &dtos.LimitsResponse{
+		MaxMessageSize:    s.config.MessageContent.MaxSize,
+		Models:            s.allowedModels(settings),
+		HistoryMessages:   llm.Prompt.HistoryLimit,
+		MaxReplyTokens:    llm.MaxTokens,
+		MaxTimeoutMs:      llm.MaxTimeout.Milliseconds(),
+		MaxAttachmentSize: s.config.Attachments.MaxSize,
+		MaxPageSize:       s.config.API.MaxLimit,
+		Budget:            budget,
+	}
+	// Only these strategies bound the history by tokens
+	if llm.Prompt.Strategy == "token_budget" || llm.Prompt.Strategy == "summary" {
+		limits.ContextWindow = llm.Prompt.TokenBudget
+	}
+	if s.config.Transcription.Enabled {
+		limits.MaxAudioSize = &s.config.Transcription.MaxSize
+	}
+
+	if models.IsGuest(userID) {
+		guest := s.config.Guest
+		sent, err := s.messageRepo.CountByUser(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		remaining := max(0, guest.MaxMessages-int(sent))
+		limits.RequestsPerMinute = &guest.RequestsPerMinute
+		limits.MaxMessages = &guest.MaxMessages
+		limits.RemainingMessages = &remaining
+		if guest.MaxTokens > 0 {
+			limits.MaxReplyTokens = guest.MaxTokens
+		}
+	}
+
+	return limits, nil
+}
+
+// allowedModels returns the models replies can be generated with: the default model of the
+// workspace, or of the configuration, followed by the models messages can be compared with
+func (s *limitsService) allowedModels(settings *models.WorkspaceSettings) []string {
+	names := []string{s.config.LLM.Model}
+	if settings.DefaultModel != "" {
+		names[0] = settings.DefaultModel
+	}
+	for _, name := range s.config.LLM.CompareModels {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
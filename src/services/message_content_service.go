@@ -0,0 +1,13 @@
+package services
+
+import (
+	"context"
+)
+
+// MessageContentService defines the interface for the content shared by the messages
+// repeating it
+type MessageContentService interface {
+	// PurgeUnreferenced deletes the shared content no message references anymore and returns
+	// the number deleted
+	PurgeUnreferenced(ctx context.Context) (int64, error)
+}
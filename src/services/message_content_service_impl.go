@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// sharedContentGrace is how long shared content is kept after it was last used, so that the
+// messages taking it are written before it could be deleted
+const sharedContentGrace = time.Hour
+
+// messageContentService implements the MessageContentService interface
+type messageContentService struct {
+	contentRepo repositories.MessageContentRepository
+}
+
+// NewMessageContentService creates a new message content service
+func NewMessageContentService(contentRepo repositories.MessageContentRepository) MessageContentService {
+	return &messageContentService{contentRepo: contentRepo}
+}
+
+// PurgeUnreferenced deletes the shared content no message references anymore, once it was
+// not used for the grace period
+func (s *messageContentService) PurgeUnreferenced(ctx context.Context) (int64, error) {
+	return s.contentRepo.DeleteUnreferenced(ctx, time.Now().Add(-sharedContentGrace))
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"time"
 
 	"github.com/nvnamsss/chat/src/dtos"
 )
@@ -9,17 +10,94 @@ import (
 // MessageService defines the interface for message operations
 type MessageService interface {
 	// SendMessage sends a new user message to a chat and gets LLM response
-	SendMessage(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest) (*dtos.MessageResponse, error)
+	SendMessage(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest) (*dtos.MessageExchangeResponse, error)
+
+	// StreamMessage sends a new user message to a chat and gets LLM response, passing the
+	// reply to onChunk as it is generated
+	StreamMessage(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.MessageExchangeResponse, error)
+
+	// CompareMessage sends a new user message to a chat and gets the responses of several
+	// models to it, saved as separate assistant messages
+	CompareMessage(ctx context.Context, chatID int64, userID string, req *dtos.CompareRequest) (*dtos.CompareResponse, error)
+
+	// Complete answers the messages of an OpenAI chat completion request as they are, then
+	// saves the latest user message and the reply to a chat. onChunk, when set, receives the
+	// reply as it is generated.
+	Complete(ctx context.Context, chatID int64, userID string, req *dtos.ChatCompletionRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.CompletionResult, error)
+
+	// CompleteTranscription sets the transcript of a voice message as its content and
+	// gets the LLM response to it
+	CompleteTranscription(ctx context.Context, messageID int64, transcript string) (*dtos.MessageExchangeResponse, error)
+
+	// FailTranscription marks a voice message whose audio could not be transcribed
+	FailTranscription(ctx context.Context, messageID int64) error
+
+	// FailStalledReplies fails the replies stuck in generation, not saved for longer than the
+	// watchdog threshold, publishing an alert event for each, and returns how many it failed
+	FailStalledReplies(ctx context.Context) (int, error)
+
+	// RetryReply generates again a failed reply, which must be the latest message of its chat,
+	// replacing it. The user is the one asking for it, checked against their consent and budget.
+	RetryReply(ctx context.Context, id int64, userID string) (*dtos.MessageResponse, error)
+
+	// EstimateMessage estimates the prompt tokens and cost of a user sending a message to a chat
+	// with every available model, without sending it
+	EstimateMessage(ctx context.Context, userID string, req *dtos.EstimateRequest) (*dtos.EstimateResponse, error)
+
+	// SummarizeChat summarizes a conversation for a user with its key decisions and action
+	// items, saved as a message of the chat and optionally pinned
+	SummarizeChat(ctx context.Context, chatID int64, userID string, req *dtos.SummarizeChatRequest) (*dtos.ChatSummaryResponse, error)
+
+	// PreviewPrompt returns the LLM request a reply in a chat would be generated with, without
+	// calling the LLM
+	PreviewPrompt(ctx context.Context, chatID int64, req *dtos.PromptPreviewRequest) (*dtos.PromptPreviewResponse, error)
 
 	// GetMessage retrieves a message by ID
 	GetMessage(ctx context.Context, id int64) (*dtos.MessageResponse, error)
 
-	// ListMessages lists all messages for a chat
-	ListMessages(ctx context.Context, req *dtos.ListMessagesRequest) (*dtos.ListMessagesResponse, error)
+	// ResumeStream passes the chunks of the buffered stream of a reply to a user, from chunk
+	// number from onwards, to onChunk as they are generated, until the reply is complete
+	ResumeStream(ctx context.Context, messageID int64, userID, token string, from int, onChunk func(chunk *dtos.LLMChunk) error) error
+
+	// WaitForReply waits up to timeout for an assistant reply being generated to complete or
+	// fail, and returns it as it is then
+	WaitForReply(ctx context.Context, messageID int64, timeout time.Duration) (*dtos.MessageResponse, error)
+
+	// ListMessages lists all messages for a chat, with their reactions as seen by a user
+	ListMessages(ctx context.Context, userID string, req *dtos.ListMessagesRequest) (*dtos.ListMessagesResponse, error)
+
+	// LookupMessages gets the messages with the given IDs in the chats of a user, with their
+	// reactions as seen by the user
+	LookupMessages(ctx context.Context, userID string, ids []int64) (*dtos.LookupMessagesResponse, error)
 
 	// UpdateMessage updates a message
 	UpdateMessage(ctx context.Context, id int64, req *dtos.MessageRequest) (*dtos.MessageResponse, error)
 
+	// SetFeedback rates an assistant message
+	SetFeedback(ctx context.Context, id int64, req *dtos.FeedbackRequest) (*dtos.MessageResponse, error)
+
+	// React adds or takes back an emoji reaction of a user to a message
+	React(ctx context.Context, id int64, userID string, req *dtos.ReactionRequest) (*dtos.ReactionsResponse, error)
+
+	// ListRevisions lists the previous versions of a message, oldest first
+	ListRevisions(ctx context.Context, messageID int64) (*dtos.ListMessageRevisionsResponse, error)
+
+	// ListModelHistory lists the models that generated the replies of a chat, grouped in runs
+	// of consecutive replies of the same provider, model and model version
+	ListModelHistory(ctx context.Context, chatID int64) (*dtos.ChatModelHistoryResponse, error)
+
+	// ListArtifacts lists the code blocks extracted from a message, in their order in the message
+	ListArtifacts(ctx context.Context, messageID int64) (*dtos.ListArtifactsResponse, error)
+
+	// GetArtifact retrieves an artifact of a message
+	GetArtifact(ctx context.Context, messageID, artifactID int64) (*dtos.ArtifactResponse, error)
+
 	// DeleteMessage deletes a message
 	DeleteMessage(ctx context.Context, id int64) error
+
+	// DeleteMessages deletes the messages of a chat matching the filters of a request
+	DeleteMessages(ctx context.Context, chatID int64, req *dtos.DeleteMessagesRequest) (*dtos.DeleteMessagesResponse, error)
+
+	// BroadcastAnnouncement posts a system message into the selected chats
+	BroadcastAnnouncement(ctx context.Context, req *dtos.AnnouncementRequest) (*dtos.AnnouncementResponse, error)
 }
@@ -2,277 +2,2274 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
 	"github.com/nvnamsss/chat/src/dtos"
 	"github.com/nvnamsss/chat/src/errors"
 	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
 	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/dryrun"
+	"github.com/nvnamsss/chat/src/pkg/timing"
+	"github.com/nvnamsss/chat/src/pkg/tokenizer"
+	"github.com/nvnamsss/chat/src/plugins"
 	"github.com/nvnamsss/chat/src/repositories"
 )
 
+// Chat lock modes, applied to a message sent while another one of the chat is answered
+const (
+	ChatLockQueue  = "queue"
+	ChatLockReject = "reject"
+)
+
+// conversationSummaryInstructions is the system prompt used to summarize a conversation for its user
+const conversationSummaryInstructions = "You summarize a conversation between a user and an assistant for the user, who needs a recap of it. " +
+	"Reply in Markdown, in the language of the conversation, with three sections: " +
+	"\"## Summary\", a short overview of the conversation; \"## Key decisions\", the decisions made; and \"## Action items\", the tasks left to do. " +
+	"List the decisions and action items as bullet points, or write \"None\" when there are none."
+
 // messageService implements the MessageService interface
 type messageService struct {
-	messageRepo repositories.MessageRepository
-	chatRepo    repositories.ChatRepository
-	llmAdapter  adapters.LLMAdapter
-	kafka       KafkaProducer
+	messageRepo    repositories.MessageRepository
+	chatRepo       repositories.ChatRepository
+	artifactRepo   repositories.ArtifactRepository
+	personaRepo    repositories.PersonaRepository
+	experimentRepo repositories.ExperimentRepository
+	widgetRepo     repositories.WidgetRepository
+	workspaceRepo  repositories.WorkspaceRepository
+	llmAdapter     adapters.LLMAdapter
+	lock           adapters.LockAdapter
+	promptBuilder  PromptBuilder
+	tokens         *tokenizer.Tokenizer
+	postProcessors *PostProcessorChain
+	urlContext     URLContextEnricher // Nil when fetching linked pages is disabled
+	translator     Translator         // Nil when translation is disabled
+	suggester      Suggester          // Nil when follow-up suggestions are disabled
+	guardrails     Guardrails         // Nil when guardrails are disabled
+	budgets        BudgetService
+	consent        ConsentService
+	events         EventPublisher
+	hooks          *plugins.Hooks
+	streams        *StreamBuffer // Nil when streams are not resumable
+	guest          configs.Guest
+	dedup          configs.Dedup
+	chatLock       configs.ChatLock
+}
+
+// NewMessageService creates a new message service
+func NewMessageService(
+	messageRepo repositories.MessageRepository,
+	chatRepo repositories.ChatRepository,
+	artifactRepo repositories.ArtifactRepository,
+	personaRepo repositories.PersonaRepository,
+	experimentRepo repositories.ExperimentRepository,
+	widgetRepo repositories.WidgetRepository,
+	workspaceRepo repositories.WorkspaceRepository,
+	llmAdapter adapters.LLMAdapter,
+	lock adapters.LockAdapter,
+	promptBuilder PromptBuilder,
+	tokens *tokenizer.Tokenizer,
+	postProcessors *PostProcessorChain,
+	urlContext URLContextEnricher,
+	translator Translator,
+	suggester Suggester,
+	guardrails Guardrails,
+	budgets BudgetService,
+	consent ConsentService,
+	events EventPublisher,
+	hooks *plugins.Hooks,
+	streams *StreamBuffer,
+	guest configs.Guest,
+	dedup configs.Dedup,
+	chatLock configs.ChatLock,
+) MessageService {
+	return &messageService{
+		messageRepo:    messageRepo,
+		chatRepo:       chatRepo,
+		artifactRepo:   artifactRepo,
+		personaRepo:    personaRepo,
+		experimentRepo: experimentRepo,
+		widgetRepo:     widgetRepo,
+		workspaceRepo:  workspaceRepo,
+		llmAdapter:     llmAdapter,
+		lock:           lock,
+		promptBuilder:  promptBuilder,
+		tokens:         tokens,
+		postProcessors: postProcessors,
+		urlContext:     urlContext,
+		translator:     translator,
+		suggester:      suggester,
+		guardrails:     guardrails,
+		budgets:        budgets,
+		consent:        consent,
+		events:         events,
+		hooks:          hooks,
+		streams:        streams,
+		guest:          guest,
+		dedup:          dedup,
+		chatLock:       chatLock,
+	}
+}
+
+// SendMessage sends a new user message to a chat and gets LLM response
+func (s *messageService) SendMessage(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest) (*dtos.MessageExchangeResponse, error) {
+	return s.sendMessage(ctx, chatID, userID, req, nil)
+}
+
+// StreamMessage sends a new user message to a chat and gets LLM response, passing the reply
+// to onChunk as it is generated
+func (s *messageService) StreamMessage(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.MessageExchangeResponse, error) {
+	return s.sendMessage(ctx, chatID, userID, req, onChunk)
+}
+
+// sendMessage saves a user message and replies to it, streamed to onChunk when set
+func (s *messageService) sendMessage(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.MessageExchangeResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Processing new message", "chatID", chatID, "userID", userID, "stream", onChunk != nil)
+
+	ctx = timing.WithRecorder(ctx)
+	defer observeSendStages(ctx)
+
+	endRead := timing.Start(ctx, timing.StageDBRead)
+	chat, isGuest, err := s.checkSend(ctx, chatID, userID, req)
+	endRead()
+	if err != nil {
+		return nil, err
+	}
+	if dryrun.Enabled(ctx) {
+		return s.dryRunSend(ctx, chat, userID, req)
+	}
+
+	endLock := timing.Start(ctx, timing.StageLock)
+	unlock, err := s.lockChat(ctx, chatID)
+	endLock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if s.dedup.Enabled && !req.AllowDuplicate {
+		endRead := timing.Start(ctx, timing.StageDBRead)
+		exchange, err := s.findDuplicate(ctx, chatID, userID, req.Content)
+		endRead()
+		if err != nil {
+			return nil, err
+		}
+		if exchange != nil {
+			log.Infow("Duplicate message suppressed", "chatID", chatID, "messageID", exchange.UserMessage.ID)
+			return exchange, nil
+		}
+	}
+
+	userMessage, err := s.saveUserMessage(ctx, chat, userID, req.Content, req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	assistantMessage, err := s.reply(adapters.WithVendorOptions(ctx, req.Vendor), chat, isGuest, req.TimeoutMs, onChunk)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the user's message
+	return &dtos.MessageExchangeResponse{
+		UserMessage:      *toMessageResponse(userMessage),
+		AssistantMessage: toMessageResponse(assistantMessage),
+		BudgetExceeded:   chat.BudgetExceeded(),
+	}, nil
+}
+
+// dryRunSend returns the exchange a message that passed its checks would start, with the
+// user message unsaved, no reply and the estimated cost of the reply with the default model
+func (s *messageService) dryRunSend(ctx context.Context, chat *models.Chat, userID string, req *dtos.MessageRequest) (*dtos.MessageExchangeResponse, error) {
+	estimate, err := s.estimate(ctx, chat, userID, req.Content, []string{configs.AppConfig.LLM.Model})
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := req.ContentType
+	if contentType == "" {
+		contentType = models.ContentTypeMarkdown
+	}
+	return &dtos.MessageExchangeResponse{
+		UserMessage: dtos.MessageResponse{
+			ChatID:      chat.ID,
+			UserID:      &userID,
+			Role:        models.MessageRoleUser,
+			Content:     req.Content,
+			ContentType: contentType,
+		},
+		BudgetExceeded: chat.BudgetExceeded(),
+		DryRun:         true,
+		Estimate:       estimate,
+	}, nil
+}
+
+// observeSendStages records the time spent in each stage of sending a message in metrics
+func observeSendStages(ctx context.Context) {
+	for _, stage := range timing.From(ctx) {
+		metrics.SendMessageStageLatency.WithLabelValues(stage.Name).Observe(stage.Duration.Seconds())
+	}
+}
+
+// lockChat serializes the messages sent to a chat when chat locks are enabled, so each reply
+// is generated from the history including the previous exchange. It returns the function
+// releasing the lock.
+func (s *messageService) lockChat(ctx context.Context, chatID int64) (func(), error) {
+	if !s.chatLock.Enabled {
+		return func() {}, nil
+	}
+	log := logger.Context(ctx)
+	name := fmt.Sprintf("chat:%d", chatID)
+
+	if s.chatLock.Mode == ChatLockReject {
+		unlock, acquired, err := s.lock.TryLock(ctx, name)
+		if err != nil {
+			log.Errorw("Failed to lock chat", "error", err, "chatID", chatID)
+			return nil, errors.Wrap(err, errors.ErrInternal, "Failed to send message")
+		}
+		if !acquired {
+			return nil, errors.New(errors.ErrConflict, "A message is already being answered in this chat")
+		}
+		return unlock, nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, s.chatLock.QueueTimeout)
+	defer cancel()
+	unlock, err := s.lock.Lock(waitCtx, name)
+	if err != nil {
+		if ctx.Err() == nil && waitCtx.Err() != nil {
+			return nil, errors.New(errors.ErrConflict, "Timed out waiting for the previous message of this chat to be answered")
+		}
+		log.Errorw("Failed to lock chat", "error", err, "chatID", chatID)
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to send message")
+	}
+	return unlock, nil
+}
+
+// findDuplicate returns the exchange of the latest user message of a chat when the user sent
+// it with the same content within the dedup window, or nil. The reply is nil while it is still
+// being generated, or when it failed.
+func (s *messageService) findDuplicate(ctx context.Context, chatID int64, userID, content string) (*dtos.MessageExchangeResponse, error) {
+	recent, err := s.messageRepo.GetRecent(ctx, chatID, 0, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	// The latest user message, followed by its reply or by nothing
+	var message, reply *models.Message
+	switch {
+	case len(recent) > 0 && recent[len(recent)-1].Role == models.MessageRoleUser:
+		message = recent[len(recent)-1]
+	case len(recent) == 2 && recent[0].Role == models.MessageRoleUser && recent[1].Role == models.MessageRoleAssistant:
+		message, reply = recent[0], recent[1]
+	default:
+		return nil, nil
+	}
+
+	if message.UserID == nil || *message.UserID != userID || message.Content != content ||
+		time.Since(message.CreatedAt) > s.dedup.Window {
+		return nil, nil
+	}
+
+	exchange := &dtos.MessageExchangeResponse{
+		UserMessage: *toMessageResponse(message),
+		Duplicate:   true,
+	}
+	if reply != nil {
+		exchange.AssistantMessage = toMessageResponse(reply)
+	}
+	return exchange, nil
+}
+
+// checkSend verifies that a user may send a message to a chat: they own it, are within
+// their guest allowance and budget, plugins accept the message, its vendor options are
+// allowed, and its prompt fits the token budget of the chat for each of promptModels, or
+// for the LLM model when there are none
+func (s *messageService) checkSend(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest, promptModels ...string) (*models.Chat, bool, error) {
+	log := logger.Context(ctx)
+
+	// Verify chat exists
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return nil, false, err
+	}
+	if err := checkChatBudget(chat); err != nil {
+		return nil, false, err
+	}
+
+	// Guest sessions have a fixed message allowance
+	isGuest := models.IsGuest(userID)
+	if isGuest {
+		sent, err := s.messageRepo.CountByUser(ctx, userID)
+		if err != nil {
+			return nil, false, err
+		}
+		if sent >= int64(s.guest.MaxMessages) {
+			return nil, false, errors.New(errors.ErrRateLimited, "Guest message limit reached, sign in to continue")
+		}
+	}
+
+	if err := s.consent.Enforce(ctx, userID); err != nil {
+		return nil, false, err
+	}
+
+	if err := s.budgets.Enforce(ctx, userID); err != nil {
+		return nil, false, err
+	}
+
+	if err := s.hooks.BeforeSend(ctx, chatID, userID, req); err != nil {
+		log.Warnw("Message rejected by plugin", "error", err, "chatID", chatID)
+		return nil, false, errors.Wrap(err, errors.ErrInvalidRequest, "Message rejected")
+	}
+	if err := checkContent(req.Content, req.ContentType); err != nil {
+		return nil, false, err
+	}
+	if err := adapters.CheckVendorOptions(configs.AppConfig.LLM.Passthrough, req.Vendor); err != nil {
+		return nil, false, err
+	}
+	if err := s.checkPromptBudget(ctx, chat, req.Content, promptModels...); err != nil {
+		return nil, false, err
+	}
+
+	return chat, isGuest, nil
+}
+
+// checkPromptBudget rejects a message to a chat with a token budget when its prompt would take
+// more tokens than the chat has left, unless the chat only warns about its budget. The prompt
+// is counted for each of promptModels, as each of them is sent the prompt, or for the LLM
+// model when there are none.
+func (s *messageService) checkPromptBudget(ctx context.Context, chat *models.Chat, content string, promptModels ...string) error {
+	if chat.MaxTokens == 0 || chat.BudgetMode == models.ChatBudgetModeWarn {
+		return nil
+	}
+
+	request, err := s.promptBuilder.Preview(ctx, chat, content)
+	if err != nil {
+		return err
+	}
+	if len(promptModels) == 0 {
+		promptModels = []string{configs.AppConfig.LLM.Model}
+	}
+	var tokens int64
+	for _, model := range promptModels {
+		count := countPromptTokens(s.tokens, model, request)
+		logger.Context(ctx).Debugw("Counted prompt tokens against the chat budget", "chatID", chat.ID, "model", model, "tokens", count, "used", chat.UsedTokens, "max", chat.MaxTokens)
+		tokens += int64(count)
+	}
+	if chat.UsedTokens+tokens > chat.MaxTokens {
+		return errors.New(errors.ErrQuotaExceeded, "The message would exceed the token budget of the chat")
+	}
+	return nil
+}
+
+// CompareMessage sends a new user message to a chat and gets the responses of several
+// models to it, generated in parallel and saved as separate assistant messages
+func (s *messageService) CompareMessage(ctx context.Context, chatID int64, userID string, req *dtos.CompareRequest) (*dtos.CompareResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Processing compared message", "chatID", chatID, "userID", userID, "models", req.Models)
+
+	compareModels, err := selectCompareModels(configs.AppConfig.LLM.CompareModels, req.Models)
+	if err != nil {
+		return nil, err
+	}
+
+	// A comparison costs a reply per model, more than guest allowances are meant for
+	if models.IsGuest(userID) {
+		return nil, errors.New(errors.ErrForbidden, "Guest sessions cannot compare models, sign in to continue")
+	}
+
+	// The budget is checked once; the replies of the comparison may take it past its limit
+	messageReq := &dtos.MessageRequest{Content: req.Content, TimeoutMs: req.TimeoutMs}
+	chat, _, err := s.checkSend(ctx, chatID, userID, messageReq, compareModels...)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.lockChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	userMessage, err := s.saveUserMessage(ctx, chat, userID, messageReq.Content, models.ContentTypeMarkdown)
+	if err != nil {
+		return nil, err
+	}
+
+	// Experiments are left out: their variants would not use the compared models
+	draft, err := s.prepareReply(ctx, chat, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate in parallel, but save the replies in the order the models were requested
+	type generated struct {
+		response *dtos.LLMResponse
+		latency  time.Duration
+	}
+	drafts := make([]*replyDraft, len(compareModels))
+	calls := make([]adapters.FanOutCall[generated], len(compareModels))
+	for i, model := range compareModels {
+		modelRequest := *draft.request
+		modelRequest.Model = model
+		drafts[i] = &replyDraft{request: &modelRequest, personaID: draft.personaID, moderation: draft.moderation, guardrails: draft.guardrails, translate: draft.translate}
+		calls[i] = func(ctx context.Context) (generated, error) {
+			response, latency, err := s.generateReply(ctx, chat, drafts[i], req.TimeoutMs)
+			return generated{response: response, latency: latency}, err
+		}
+	}
+	// The replies of the other models queue behind the messages users wait for
+	results := adapters.FanOutAll(adapters.WithPriority(ctx, adapters.PriorityRegeneration), calls)
+
+	replies := make([]dtos.CompareReply, len(compareModels))
+	errs := make([]error, len(compareModels))
+	answered := 0
+	for i, model := range compareModels {
+		replies[i].Model = model
+		if errs[i] = results[i].Err; errs[i] == nil {
+			var message *models.Message
+			message, errs[i] = s.saveReply(ctx, chat, drafts[i], results[i].Value.response, results[i].Value.latency)
+			if errs[i] == nil {
+				replies[i].Message = toMessageResponse(message)
+				answered++
+				continue
+			}
+		}
+
+		replies[i].Error = errs[i].Error()
+		if appErr, ok := errs[i].(*errors.AppError); ok {
+			replies[i].Error = appErr.Message
+		}
+	}
+
+	// The comparison only fails when no model answered
+	if answered == 0 {
+		return nil, errs[0]
+	}
+
+	return &dtos.CompareResponse{
+		UserMessage: *toMessageResponse(userMessage),
+		Replies:     replies,
+	}, nil
+}
+
+// Complete answers the messages of an OpenAI chat completion request as they are, then
+// saves the latest user message and the reply to a chat. Into an empty chat, the earlier
+// messages of the request are imported first. onChunk, when set,
+// receives the reply as it is generated. The reply is not translated into the chat language.
+func (s *messageService) Complete(ctx context.Context, chatID int64, userID string, req *dtos.ChatCompletionRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.CompletionResult, error) {
+	log := logger.Context(ctx)
+	log.Infow("Processing chat completion", "chatID", chatID, "userID", userID, "model", req.Model, "stream", req.Stream)
+
+	last := len(req.Messages) - 1
+	if req.Messages[last].Role != models.MessageRoleUser {
+		return nil, errors.New(errors.ErrInvalidRequest, "The last message must be a user message")
+	}
+	// Only the models GET /v1/models lists can be requested
+	if err := CheckModel(configs.AppConfig.LLM, req.Model); err != nil {
+		return nil, err
+	}
+
+	messageReq := &dtos.MessageRequest{Content: string(req.Messages[last].Content), Vendor: req.Vendor}
+	chat, isGuest, err := s.checkSend(ctx, chatID, userID, messageReq)
+	if err != nil {
+		return nil, err
+	}
+	ctx = adapters.WithVendorOptions(ctx, req.Vendor)
+
+	// Completions appended to the same chat are serialized like messages, which keeps the
+	// history imported into an empty chat from interleaving with another exchange
+	unlock, err := s.lockChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	existing, err := s.messageRepo.ListByChatID(ctx, chatID, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		if err := s.importHistory(ctx, chatID, userID, req.Messages[:last]); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.saveUserMessage(ctx, chat, userID, messageReq.Content, models.ContentTypeMarkdown); err != nil {
+		return nil, err
+	}
+
+	// The client owns the prompt; only the latest message may have been changed by plugins
+	llmRequest := &dtos.LLMRequest{
+		Messages:  make([]dtos.LLMMessage, len(req.Messages)),
+		Model:     req.Model,
+		MaxTokens: req.MaxCompletionTokens,
+	}
+	if llmRequest.MaxTokens == 0 {
+		llmRequest.MaxTokens = req.MaxTokens
+	}
+	if isGuest && s.guest.MaxTokens > 0 && (llmRequest.MaxTokens == 0 || llmRequest.MaxTokens > s.guest.MaxTokens) {
+		llmRequest.MaxTokens = s.guest.MaxTokens
+	}
+	for i, message := range req.Messages {
+		role := message.Role
+		if role == "developer" {
+			role = models.MessageRoleSystem
+		}
+		llmRequest.Messages[i] = dtos.LLMMessage{Role: role, Content: string(message.Content), Name: message.Name, ToolCallID: message.ToolCallID}
+	}
+	llmRequest.Messages[last].Content = messageReq.Content
+
+	if err := s.hooks.BeforeGenerate(ctx, chat.ID, llmRequest); err != nil {
+		log.Warnw("LLM request rejected by plugin", "error", err, "chatID", chat.ID)
+		return nil, errors.Wrap(err, errors.ErrInvalidRequest, "Message rejected")
+	}
+
+	// The client owns the prompt, so only the moderation level and guardrails of the workspace apply
+	settings, err := workspaceDefaults(ctx, s.workspaceRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	draft := &replyDraft{request: llmRequest, moderation: settings.ModerationLevel, guardrails: guardrailRules(settings), onChunk: onChunk}
+	llmResponse, latency, err := s.generateReply(ctx, chat, draft, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	assistantMessage, err := s.saveReply(ctx, chat, draft, llmResponse, latency)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.CompletionResult{
+		ChatID:  chat.ID,
+		Message: toMessageResponse(assistantMessage),
+		Model:   assistantMessage.Model,
+		Usage:   llmResponse.Usage,
+	}, nil
+}
+
+// importHistory saves the messages of an OpenAI request to a chat; developer messages are
+// saved as system messages
+func (s *messageService) importHistory(ctx context.Context, chatID int64, userID string, history []dtos.ChatCompletionMessage) error {
+	for _, entry := range history {
+		message := &models.Message{
+			ChatID:     chatID,
+			Role:       entry.Role,
+			Content:    string(entry.Content),
+			Name:       entry.Name,
+			ToolCallID: entry.ToolCallID,
+		}
+		switch entry.Role {
+		case models.MessageRoleUser:
+			message.UserID = &userID
+		case "developer":
+			message.Role = models.MessageRoleSystem
+		}
+
+		if err := s.saveMessage(ctx, message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selectCompareModels returns the requested models, all configured ones when none are
+// requested. Only configured models can be compared, and at least two of them.
+func selectCompareModels(configured, requested []string) ([]string, error) {
+	if len(configured) < 2 {
+		return nil, errors.New(errors.ErrInvalidRequest, "Model comparison is not configured")
+	}
+	if len(requested) == 0 {
+		return configured, nil
+	}
+
+	selected := make([]string, 0, len(requested))
+	for _, model := range requested {
+		if !slices.Contains(configured, model) {
+			return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Model %s is not available for comparison", model))
+		}
+		if !slices.Contains(selected, model) {
+			selected = append(selected, model)
+		}
+	}
+	if len(selected) < 2 {
+		return nil, errors.New(errors.ErrInvalidRequest, "At least two models are needed for a comparison")
+	}
+	return selected, nil
+}
+
+// EstimateMessage estimates the prompt tokens and cost of sending a message to a chat of the
+// user with every available model: the default model and the models of comparisons. The
+// prompt is built from the chat context as for a send, without persona or experiment routing.
+func (s *messageService) EstimateMessage(ctx context.Context, userID string, req *dtos.EstimateRequest) (*dtos.EstimateResponse, error) {
+	available := AvailableModels(configs.AppConfig.LLM)
+	selected := available
+	if len(req.Models) > 0 {
+		selected = make([]string, 0, len(req.Models))
+		for _, model := range req.Models {
+			if !slices.Contains(available, model) {
+				return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Model %s is not available", model))
+			}
+			if !slices.Contains(selected, model) {
+				selected = append(selected, model)
+			}
+		}
+	}
+
+	chat, err := s.chatRepo.Get(ctx, req.ChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.estimate(ctx, chat, userID, req.Content, selected)
+}
+
+// estimate estimates the prompt tokens and cost of sending a message with content to a chat
+// with each of models
+func (s *messageService) estimate(ctx context.Context, chat *models.Chat, userID, content string, selected []string) (*dtos.EstimateResponse, error) {
+	llmConfig := configs.AppConfig.LLM
+
+	request, err := s.promptBuilder.Preview(ctx, chat, content)
+	if err != nil {
+		return nil, err
+	}
+
+	promptTokens := countPromptTokens(s.tokens, llmConfig.Model, request)
+	maxTokens := llmConfig.MaxTokens
+	if models.IsGuest(userID) && s.guest.MaxTokens > 0 {
+		maxTokens = s.guest.MaxTokens
+	}
+
+	estimates := make([]dtos.ModelEstimate, len(selected))
+	for i, model := range selected {
+		// Models of different encodings take different numbers of tokens
+		modelTokens := countPromptTokens(s.tokens, model, request)
+		estimates[i] = dtos.ModelEstimate{
+			Model:        model,
+			PromptTokens: modelTokens,
+			PromptCost:   s.budgets.EstimateCost(model, dtos.LLMUsage{PromptTokens: modelTokens}),
+			MaxCost:      s.budgets.EstimateCost(model, dtos.LLMUsage{PromptTokens: modelTokens, CompletionTokens: maxTokens}),
+		}
+	}
+
+	return &dtos.EstimateResponse{
+		PromptTokens:        promptTokens,
+		MaxCompletionTokens: maxTokens,
+		Estimates:           estimates,
+	}, nil
+}
+
+// PreviewPrompt returns the LLM request a reply in a chat would be generated with: the
+// history as the prompt strategy selects it, with the system prompt of the persona, widget,
+// experiment variant or workspace, linked pages and the changes of plugins. Linked pages are
+// fetched, but the LLM is not called and nothing is saved.
+func (s *messageService) PreviewPrompt(ctx context.Context, chatID int64, req *dtos.PromptPreviewRequest) (*dtos.PromptPreviewResponse, error) {
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	var request *dtos.LLMRequest
+	if req.Content != "" {
+		request, err = s.promptBuilder.Preview(ctx, chat, req.Content)
+	} else {
+		request, err = s.promptBuilder.Build(ctx, chat)
+	}
+	if err != nil {
+		return nil, err
+	}
+	draft, err := s.draftReply(ctx, chat, request, models.IsGuest(chat.UserID), true)
+	if err != nil {
+		return nil, err
+	}
+
+	// The LLM adapter sends requests without a model to the configured one
+	model := draft.request.Model
+	if model == "" {
+		model = configs.AppConfig.LLM.Model
+	}
+	return &dtos.PromptPreviewResponse{
+		ChatID:          chat.ID,
+		Model:           model,
+		MaxTokens:       draft.request.MaxTokens,
+		Messages:        draft.request.Messages,
+		PromptTokens:    countPromptTokens(s.tokens, model, draft.request),
+		PersonaID:       draft.personaID,
+		VariantID:       draft.variantID,
+		ModerationLevel: draft.moderation,
+	}, nil
+}
+
+// SummarizeChat summarizes a conversation for a user with its key decisions and action
+// items, from the rolling summary of the chat and its latest messages. The summary is saved
+// as an assistant message of the summary kind, excluded from prompts, and optionally pinned.
+func (s *messageService) SummarizeChat(ctx context.Context, chatID int64, userID string, req *dtos.SummarizeChatRequest) (*dtos.ChatSummaryResponse, error) {
+	log := logger.Context(ctx)
+	config := configs.AppConfig.Summaries
+
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return nil, err
+	}
+	if req.Pin && chat.Ephemeral {
+		return nil, errors.New(errors.ErrInvalidRequest, "Messages of privacy mode chats cannot be pinned")
+	}
+	if err := checkChatBudget(chat); err != nil {
+		return nil, err
+	}
+	if err := s.consent.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.budgets.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+	if chat.Ephemeral {
+		ctx = adapters.WithoutCapture(ctx)
+	}
+
+	messages, err := s.messageRepo.GetRecent(ctx, chatID, chat.SummaryMessageID, config.MaxMessages)
+	if err != nil {
+		return nil, err
+	}
+	history := toLLMMessages(messages)
+	if len(history) == 0 && chat.Summary == "" {
+		return nil, errors.New(errors.ErrInvalidRequest, "The chat has no messages to summarize")
+	}
+
+	var transcript strings.Builder
+	if chat.Summary != "" {
+		fmt.Fprintf(&transcript, "Summary of the earlier conversation:\n%s\n\nLatest messages:\n", chat.Summary)
+	}
+	for _, message := range history {
+		fmt.Fprintf(&transcript, "%s: %s\n", message.Role, message.Content)
+	}
+
+	start := time.Now()
+	response, err := s.llmAdapter.GenerateResponse(ctx, &dtos.LLMRequest{
+		Model: config.Model,
+		Messages: []dtos.LLMMessage{
+			{Role: models.MessageRoleSystem, Content: conversationSummaryInstructions},
+			{Role: models.MessageRoleUser, Content: transcript.String()},
+		},
+	})
+	if err != nil {
+		log.Errorw("Failed to summarize chat", "error", err, "chatID", chatID)
+		return nil, err
+	}
+
+	model := response.Model
+	if model == "" {
+		model = config.Model
+	}
+	if model == "" {
+		model = configs.AppConfig.LLM.Model
+	}
+	message := &models.Message{
+		ChatID:            chat.ID,
+		Role:              models.MessageRoleAssistant,
+		Kind:              models.MessageKindSummary,
+		Content:           response.Message.Content,
+		ContentType:       models.ContentTypeMarkdown,
+		ExcludeFromPrompt: true,
+		Model:             model,
+		Provider:          configs.AppConfig.LLM.Provider,
+		ModelVersion:      response.ModelVersion,
+		TotalTokens:       response.Usage.TotalTokens,
+		LatencyMs:         time.Since(start).Milliseconds(),
+	}
+	if err := s.messageRepo.Create(ctx, message); err != nil {
+		return nil, err
+	}
+
+	// The summary is already saved; a failure here only leaves it out of the budgets
+	if err := s.budgets.RecordUsage(ctx, chat.UserID, chat.ID, message.ID, model, response.Usage); err != nil {
+		log.Errorw("Failed to record usage", "error", err, "messageID", message.ID)
+	}
+	cost := s.budgets.EstimateCost(model, response.Usage)
+	if err := s.chatRepo.AddUsage(ctx, chat.ID, response.Usage.TotalTokens, cost); err != nil {
+		log.Errorw("Failed to add chat usage", "error", err, "messageID", message.ID)
+	}
+
+	event := newEvent(ctx, models.EventMessageCreated, dtos.MessagePayload{
+		MessageID:        message.ID,
+		SeqNo:            message.SeqNo,
+		ChatID:           message.ChatID,
+		Role:             message.Role,
+		Content:          eventContent(message),
+		ContentType:      message.ContentType,
+		Kind:             message.Kind,
+		ContentTruncated: message.ContentKey != "",
+		ContentHash:      eventContentHash(message),
+		Model:            message.Model,
+		TotalTokens:      message.TotalTokens,
+	})
+	if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+		log.Errorw("Failed to publish summary event", "error", err, "messageID", message.ID)
+		// Continue despite error
+	}
+
+	if req.Pin {
+		if err := s.chatRepo.Pin(ctx, chat.ID, message.ID); err != nil {
+			return nil, err
+		}
+	}
+	log.Infow("Chat summarized for its user", "chatID", chat.ID, "messageID", message.ID, "messages", len(history), "pinned", req.Pin)
+
+	return &dtos.ChatSummaryResponse{Message: *toMessageResponse(message), Pinned: req.Pin}, nil
+}
+
+// saveUserMessage saves a new user message of a chat, with its detected language, and publishes
+// it; an empty content type is Markdown
+func (s *messageService) saveUserMessage(ctx context.Context, chat *models.Chat, userID, content, contentType string) (*models.Message, error) {
+	// Create user message
+	userMessage := &models.Message{
+		ChatID:      chat.ID,
+		UserID:      &userID,
+		Role:        models.MessageRoleUser,
+		Content:     content,
+		ContentType: contentType,
+	}
+	if s.translator != nil {
+		s.translator.PrepareMessage(ctx, chat, userMessage)
+	}
+
+	if err := s.saveMessage(ctx, userMessage); err != nil {
+		return nil, err
+	}
+
+	return userMessage, nil
+}
+
+// saveMessage saves a new message and publishes it
+func (s *messageService) saveMessage(ctx context.Context, message *models.Message) error {
+	log := logger.Context(ctx)
+
+	// Save message to database
+	endWrite := timing.Start(ctx, timing.StageDBWrite)
+	err := s.messageRepo.Create(ctx, message)
+	endWrite()
+	if err != nil {
+		return err
+	}
+
+	// Publish message event
+	event := newEvent(ctx, models.EventMessageCreated, dtos.MessagePayload{
+		MessageID:        message.ID,
+		SeqNo:            message.SeqNo,
+		ChatID:           message.ChatID,
+		UserID:           message.UserID,
+		Role:             message.Role,
+		Content:          eventContent(message),
+		ContentType:      message.ContentType,
+		ContentTruncated: message.ContentKey != "",
+		ContentHash:      eventContentHash(message),
+	})
+
+	endPublish := timing.Start(ctx, timing.StagePublish)
+	if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+		log.Errorw("Failed to publish message event", "error", err, "messageID", message.ID)
+		// Continue despite error
+	}
+	endPublish()
+
+	return nil
+}
+
+// CompleteTranscription sets the transcript of a voice message as its content and
+// gets the LLM response to it, as if the message had been sent as text
+func (s *messageService) CompleteTranscription(ctx context.Context, messageID int64, transcript string) (*dtos.MessageExchangeResponse, error) {
+	// Transcribed in the background, the reply is still awaited, ahead of other background work
+	ctx = adapters.WithPriority(ctx, adapters.PriorityRegeneration)
+	log := logger.Context(ctx)
+
+	message, err := s.messageRepo.Get(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Status != models.MessageStatusTranscribing {
+		return nil, errors.New(errors.ErrInvalidRequest, "Message is not being transcribed")
+	}
+
+	chat, err := s.chatRepo.Get(ctx, message.ChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The reply is serialized with the messages sent to the chat meanwhile
+	unlock, err := s.lockChat(ctx, chat.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	message.Content = transcript
+	message.Status = ""
+	if s.translator != nil {
+		s.translator.PrepareMessage(ctx, chat, message)
+	}
+	if err := s.messageRepo.Update(ctx, message); err != nil {
+		return nil, err
+	}
+
+	// The message is only announced once it has content
+	event := newEvent(ctx, models.EventMessageCreated, dtos.MessagePayload{
+		MessageID:        message.ID,
+		SeqNo:            message.SeqNo,
+		ChatID:           message.ChatID,
+		UserID:           message.UserID,
+		Role:             message.Role,
+		Content:          eventContent(message),
+		ContentType:      message.ContentType,
+		ContentTruncated: message.ContentKey != "",
+		ContentHash:      eventContentHash(message),
+	})
+	if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+		log.Errorw("Failed to publish user message event", "error", err, "messageID", message.ID)
+		// Continue despite error
+	}
+
+	// The transcript is kept, but the reply is checked like any other send, as the chat or
+	// the user may have changed since the audio was uploaded
+	var userID string
+	if message.UserID != nil {
+		userID = *message.UserID
+	}
+	if err := checkChatOwner(chat.UserID, userID); err != nil {
+		return nil, err
+	}
+	messageReq := &dtos.MessageRequest{Content: message.Content, ContentType: message.ContentType}
+	chat, isGuest, err := s.checkSend(ctx, chat.ID, userID, messageReq)
+	if err != nil {
+		return nil, err
+	}
+
+	assistantMessage, err := s.reply(ctx, chat, isGuest, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.MessageExchangeResponse{
+		UserMessage:      *toMessageResponse(message),
+		AssistantMessage: toMessageResponse(assistantMessage),
+	}, nil
+}
+
+// FailTranscription marks a voice message whose audio could not be transcribed
+func (s *messageService) FailTranscription(ctx context.Context, messageID int64) error {
+	message, err := s.messageRepo.Get(ctx, messageID)
+	if err != nil {
+		return err
+	}
+
+	message.Status = models.MessageStatusTranscriptionFailed
+	return s.messageRepo.Update(ctx, message)
+}
+
+// FailStalledReplies fails the replies stuck in generation, not saved for longer than the
+// watchdog threshold, publishing an alert event for each, and returns how many it failed.
+// Replies saved meanwhile, by a generation that was only slow, are left alone.
+func (s *messageService) FailStalledReplies(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+	config := configs.AppConfig.Watchdog
+
+	before := time.Now().Add(-config.Threshold)
+	messages, err := s.messageRepo.ListStalled(ctx, models.MessageStatusPartial, before, config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for _, message := range messages {
+		changed, err := s.messageRepo.SetStalledStatus(ctx, message.ID, models.MessageStatusPartial, models.MessageStatusFailed, before)
+		if err != nil {
+			return failed, err
+		}
+		if !changed {
+			continue
+		}
+		failed++
+		metrics.LLMStalledGenerations.Inc()
+		log.Warnw("Failed reply stuck in generation", "messageID", message.ID, "chatID", message.ChatID, "updatedAt", message.UpdatedAt)
+
+		event := newEvent(ctx, models.EventMessageGenerationStalled, dtos.MessagePayload{
+			MessageID: message.ID,
+			SeqNo:     message.SeqNo,
+			ChatID:    message.ChatID,
+			Role:      message.Role,
+			PersonaID: message.PersonaID,
+			Model:     message.Model,
+		})
+		if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+			log.Errorw("Failed to publish stalled generation event", "error", err, "messageID", message.ID)
+		}
+	}
+
+	return failed, nil
+}
+
+// RetryReply generates again a failed reply of a user's chat, deleting it first. Only the
+// latest message of the chat is retried, since a reply answers the messages before it.
+func (s *messageService) RetryReply(ctx context.Context, id int64, userID string) (*dtos.MessageResponse, error) {
+	log := logger.Context(ctx)
+
+	message, err := s.messageRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if message.Role != models.MessageRoleAssistant || message.Status != models.MessageStatusFailed {
+		return nil, errors.New(errors.ErrInvalidRequest, "Only failed replies can be retried")
+	}
+
+	chat, err := s.chatRepo.Get(ctx, message.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return nil, err
+	}
+	if err := checkChatBudget(chat); err != nil {
+		return nil, err
+	}
+	if err := s.consent.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.budgets.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.lockChat(ctx, chat.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Checked under the lock, as another retry or message may have followed the reply
+	recent, err := s.messageRepo.GetRecent(ctx, chat.ID, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(recent) == 0 || recent[0].ID != message.ID || recent[0].Status != models.MessageStatusFailed {
+		return nil, errors.New(errors.ErrConflict, "Only the latest message of a chat can be retried")
+	}
+
+	if err := s.messageRepo.Delete(ctx, message.ID); err != nil {
+		return nil, err
+	}
+	event := newEvent(ctx, models.EventMessageDeleted, dtos.MessagePayload{
+		MessageID: message.ID,
+		SeqNo:     message.SeqNo,
+		ChatID:    message.ChatID,
+		Role:      message.Role,
+	})
+	if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+		log.Errorw("Failed to publish message deleted event", "error", err, "messageID", message.ID)
+	}
+
+	log.Infow("Retrying failed reply", "chatID", chat.ID, "messageID", message.ID)
+	reply, err := s.reply(ctx, chat, models.IsGuest(userID), 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return toMessageResponse(reply), nil
+}
+
+// replyDraft is an assistant reply about to be generated: the LLM request and the labels
+// of the message it becomes
+type replyDraft struct {
+	request   *dtos.LLMRequest
+	personaID *int64
+	variantID *int64
+	// moderation is the moderation level of the workspace, empty for the default
+	moderation string
+	// guardrails are the guardrail rules of the workspace, nil for every rule
+	guardrails []string
+	// onChunk, when set, receives the reply content as it is generated
+	onChunk func(chunk *dtos.LLMChunk) error
+	// translate translates the reply into the chat language before it is saved
+	translate bool
+	// message is the partial message saved while the reply is generated, if any
+	message *models.Message
+}
+
+// reply generates the assistant reply to the latest messages of a chat, then saves and
+// publishes it. Guest replies are capped to the guest token allowance. In group chats, the
+// first persona mentioned in the latest message replies; without mention the default
+// assistant does. onChunk, when set, receives the reply as it is generated.
+func (s *messageService) reply(ctx context.Context, chat *models.Chat, isGuest bool, timeoutMs int, onChunk func(chunk *dtos.LLMChunk) error) (*models.Message, error) {
+	draft, err := s.prepareReply(ctx, chat, isGuest, true)
+	if err != nil {
+		return nil, err
+	}
+	draft.onChunk = onChunk
+
+	llmResponse, latency, err := s.generateReply(ctx, chat, draft, timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.saveReply(ctx, chat, draft, llmResponse, latency)
+}
+
+// prepareReply builds the LLM request answering the latest messages of a chat, routed to
+// the persona mentioned, if any. Otherwise the assistant of the widget the chat was started
+// from applies, or, with withExperiment, the variant of the running experiment assigned to
+// the chat. The workspace defaults apply to what none of them sets.
+func (s *messageService) prepareReply(ctx context.Context, chat *models.Chat, isGuest, withExperiment bool) (*replyDraft, error) {
+	defer timing.Start(ctx, timing.StagePromptBuild)()
+
+	// Build the LLM request from the chat history, which now ends with the new message
+	llmRequest, err := s.promptBuilder.Build(ctx, chat)
+	if err != nil {
+		return nil, err
+	}
+	return s.draftReply(ctx, chat, llmRequest, isGuest, withExperiment)
+}
+
+// draftReply completes the LLM request built from the history of a chat as prepareReply
+// describes
+func (s *messageService) draftReply(ctx context.Context, chat *models.Chat, llmRequest *dtos.LLMRequest, isGuest, withExperiment bool) (*replyDraft, error) {
+	log := logger.Context(ctx)
+
+	if isGuest && s.guest.MaxTokens > 0 {
+		llmRequest.MaxTokens = s.guest.MaxTokens
+	}
+	settings, err := workspaceDefaults(ctx, s.workspaceRepo)
+	if err != nil {
+		return nil, err
+	}
+	draft := &replyDraft{request: llmRequest, moderation: settings.ModerationLevel, guardrails: guardrailRules(settings), translate: true}
+
+	// A persona, widget or variant with a system prompt prepends it
+	prompts := len(llmRequest.Messages)
+	persona, err := s.routePersona(ctx, chat.ID, llmRequest)
+	if err != nil {
+		return nil, err
+	}
+	widget, err := s.widgetOf(ctx, chat)
+	if err != nil {
+		return nil, err
+	}
+	if persona != nil {
+		log.Debugw("Routing reply to persona", "chatID", chat.ID, "personaID", persona.ID)
+		draft.personaID = &persona.ID
+		applyPersona(llmRequest, persona)
+	} else if widget != nil {
+		// Widget chats are answered by the assistant of their widget, out of experiments
+		applyWidget(llmRequest, widget)
+	} else if withExperiment {
+		variant, err := s.experimentVariant(ctx, chat.ID)
+		if err != nil {
+			return nil, err
+		}
+		if variant != nil {
+			draft.variantID = &variant.ID
+			applyVariant(llmRequest, variant)
+		}
+	}
+	applyWorkspaceDefaults(llmRequest, settings, len(llmRequest.Messages) != prompts)
+
+	if s.urlContext != nil && chat.URLContext {
+		s.urlContext.Enrich(ctx, llmRequest)
+	}
+
+	if err := s.hooks.BeforeGenerate(ctx, chat.ID, llmRequest); err != nil {
+		log.Warnw("LLM request rejected by plugin", "error", err, "chatID", chat.ID)
+		return nil, errors.Wrap(err, errors.ErrInvalidRequest, "Message rejected")
+	}
+
+	model := llmRequest.Model
+	if model == "" {
+		model = configs.AppConfig.LLM.Model
+	}
+	log.Debugw("Counted prompt tokens", "chatID", chat.ID, "model", model, "encoding", tokenizer.EncodingFor(model),
+		"exact", s.tokens.Exact(model), "tokens", countPromptTokens(s.tokens, model, llmRequest))
+
+	return draft, nil
+}
+
+// generateReply gets the LLM response to a draft within the client-requested deadline,
+// along with how long it took. Streamed replies are saved as partial assistant messages as
+// they are generated, and when generation fails midway, the partial content is saved.
+// Replies streamed to clients are buffered when streams are resumable, and then keep being
+// generated after the client disconnects.
+func (s *messageService) generateReply(ctx context.Context, chat *models.Chat, draft *replyDraft, timeoutMs int) (*dtos.LLMResponse, time.Duration, error) {
+	log := logger.Context(ctx)
+
+	start := time.Now()
+	onChunk := draft.onChunk
+	genCtx := ctx
+	stream := s.openStream(ctx, chat, draft)
+	if stream != nil {
+		onChunk = stream.onChunk
+		genCtx = context.WithoutCancel(ctx)
+	}
+	if saver := s.newPartialSaver(ctx, chat, draft, start, onChunk); saver != nil {
+		onChunk = saver.onChunk
+	}
+
+	if draft.moderation != "" {
+		genCtx = adapters.WithModerationLevel(genCtx, draft.moderation)
+	}
+	// Support captures would keep the conversations of privacy mode chats in the database
+	if chat.Ephemeral {
+		genCtx = adapters.WithoutCapture(genCtx)
+	}
+	llmCtx, cancel := llmContext(genCtx, timeoutMs)
+	defer cancel()
+
+	endLLM := timing.Start(ctx, timing.StageLLM)
+	llmResponse, partial, err := s.generate(llmCtx, draft.request, onChunk)
+	endLLM()
+	latency := time.Since(start)
+	if err == nil {
+		if stream != nil {
+			stream.close(nil)
+		}
+		return llmResponse, latency, nil
+	}
+
+	log.Errorw("LLM request failed", "error", err, "model", draft.request.Model, "partialLength", len(partial))
+	err = replyError(llmCtx, err)
+	if stream != nil {
+		stream.close(err)
+	}
+	if partial != "" {
+		s.savePartial(ctx, chat, draft, partial, latency, true)
+	} else if draft.message != nil {
+		// Nothing was generated into the message saved for the stream
+		s.deleteEmptyReply(ctx, draft.message)
+	}
+	return nil, latency, err
+}
+
+// replyError returns the error of a reply whose LLM request, sent with llmCtx, failed
+func replyError(llmCtx context.Context, err error) error {
+	if llmCtx.Err() == context.DeadlineExceeded {
+		return errors.Wrap(err, errors.ErrTimeout, "LLM service did not respond in time")
+	}
+	// Requests rejected by adapter middleware, e.g. moderation, are the client's error
+	if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrInvalidRequest {
+		return appErr
+	}
+	return errors.Wrap(err, errors.ErrLLMService, "Failed to get response from LLM service")
+}
+
+// partialSaver saves the content of a streamed reply every interval while it is generated,
+// so a crash or a disconnect mid-stream does not lose it
+type partialSaver struct {
+	s        *messageService
+	ctx      context.Context
+	chat     *models.Chat
+	draft    *replyDraft
+	next     func(chunk *dtos.LLMChunk) error
+	interval time.Duration
+	start    time.Time
+	savedAt  time.Time
+	content  strings.Builder
+}
+
+// newPartialSaver returns the saver of a draft passing chunks on to next, or nil when
+// replies are not streamed or not saved while they are generated
+func (s *messageService) newPartialSaver(ctx context.Context, chat *models.Chat, draft *replyDraft, start time.Time, next func(chunk *dtos.LLMChunk) error) *partialSaver {
+	interval := configs.AppConfig.LLM.PartialSaveInterval
+	if _, ok := s.llmAdapter.(adapters.LLMStreamer); !ok || interval <= 0 {
+		return nil
+	}
+	return &partialSaver{s: s, ctx: ctx, chat: chat, draft: draft, next: next, interval: interval, start: start, savedAt: start}
+}
+
+// onChunk collects a chunk of the reply, saves the content once the interval since the last
+// save has passed, and passes the chunk on
+func (p *partialSaver) onChunk(chunk *dtos.LLMChunk) error {
+	p.content.WriteString(chunk.Content)
+	// The complete reply is saved by saveReply
+	if !chunk.Done && time.Since(p.savedAt) >= p.interval {
+		p.s.savePartial(p.ctx, p.chat, p.draft, p.content.String(), time.Since(p.start), false)
+		p.savedAt = time.Now()
+	}
+
+	if p.next != nil {
+		return p.next(chunk)
+	}
+	return nil
+}
+
+// bufferedStream passes the chunks of a reply streamed to a client through the stream
+// buffer, numbered, and on to the client while it is connected
+type bufferedStream struct {
+	buffer    *StreamBuffer
+	ctx       context.Context
+	messageID int64
+	token     string
+	client    func(chunk *dtos.LLMChunk) error
+	// clientGone is set once the client could not be written to; the reply goes on
+	clientGone bool
+}
+
+// openStream starts buffering the reply of a draft streamed to a client, saving it first
+// to number the stream after its message. It returns nil when streams are not resumable.
+func (s *messageService) openStream(ctx context.Context, chat *models.Chat, draft *replyDraft) *bufferedStream {
+	if s.streams == nil || draft.onChunk == nil {
+		return nil
+	}
+
+	s.savePartial(ctx, chat, draft, "", 0, false)
+	if draft.message == nil {
+		return nil
+	}
+
+	return &bufferedStream{
+		buffer:    s.streams,
+		ctx:       ctx,
+		messageID: draft.message.ID,
+		token:     s.streams.open(draft.message.ID, chat.UserID),
+		client:    draft.onChunk,
+	}
+}
+
+// onChunk buffers a chunk with content and passes it on to the client
+func (b *bufferedStream) onChunk(chunk *dtos.LLMChunk) error {
+	if chunk.Content != "" {
+		chunk.Index = b.buffer.append(b.messageID, chunk.Content)
+	}
+	if b.clientGone {
+		return nil
+	}
+
+	chunk.MessageID, chunk.StreamToken = b.messageID, b.token
+	if err := b.client(chunk); err != nil {
+		logger.Context(b.ctx).Infow("Stream client disconnected, buffering the rest of the reply", "error", err, "messageID", b.messageID)
+		b.clientGone = true
+	}
+	return nil
+}
+
+// close ends the buffered stream, completed or failed with err
+func (b *bufferedStream) close(err error) {
+	b.buffer.close(b.messageID, err)
+}
+
+// deleteEmptyReply deletes the message saved for a reply that failed before any content
+func (s *messageService) deleteEmptyReply(ctx context.Context, message *models.Message) {
+	writeCtx, cancelWrite := detachedContext(ctx)
+	defer cancelWrite()
+
+	if err := s.messageRepo.Delete(writeCtx, message.ID); err != nil {
+		logger.Context(ctx).Errorw("Failed to delete empty assistant message", "error", err, "messageID", message.ID)
+	}
+}
+
+// savePartial saves the content generated so far of a reply as a partial assistant message,
+// created by the first save of the draft, and publishes it as updated. Empty content only
+// saves the message. Once the generation is cutOff, the reply fails, and its content is checked
+// against the guardrails and translated like complete replies. Failures are only logged, as the reply is still being generated or has
+// already failed.
+func (s *messageService) savePartial(ctx context.Context, chat *models.Chat, draft *replyDraft, content string, latency time.Duration, cutOff bool) {
+	log := logger.Context(ctx)
+
+	// The client may be gone by now; persist what was generated regardless
+	writeCtx, cancelWrite := detachedContext(ctx)
+	defer cancelWrite()
+
+	message := draft.message
+	if message == nil {
+		message = &models.Message{
+			ChatID:    chat.ID,
+			Role:      models.MessageRoleAssistant,
+			Status:    models.MessageStatusPartial,
+			Model:     draft.request.Model,
+			Provider:  configs.AppConfig.LLM.Provider,
+			PersonaID: draft.personaID,
+			VariantID: draft.variantID,
+		}
+	}
+	message.ContentType = replyContentType(content)
+	message.Content = s.postProcessors.Process(content, message.ContentType)
+	message.LatencyMs = latency.Milliseconds()
+	if cutOff {
+		message.Status = models.MessageStatusFailed
+	}
+	// Content cut off is the reply, checked like complete replies
+	var violations []GuardrailViolation
+	if cutOff && s.guardrails != nil {
+		message.Content, violations = s.guardrails.Check(writeCtx, message.Content, draft.guardrails)
+	}
+	if cutOff && s.translator != nil && draft.translate {
+		s.translator.LocalizeReply(writeCtx, chat, message)
+	}
+
+	if draft.message == nil {
+		if err := s.messageRepo.Create(writeCtx, message); err != nil {
+			log.Errorw("Failed to save partial assistant message", "error", err, "chatID", chat.ID)
+			return
+		}
+		draft.message = message
+	} else if err := s.messageRepo.UpdateReply(writeCtx, message); err != nil {
+		log.Errorw("Failed to save partial assistant message", "error", err, "messageID", message.ID)
+		return
+	}
+	if len(violations) > 0 {
+		s.guardrails.Record(writeCtx, chat, message, violations)
+	}
+	if content == "" {
+		return
+	}
+
+	event := newEvent(ctx, models.EventMessageUpdated, dtos.MessagePayload{
+		MessageID:        message.ID,
+		SeqNo:            message.SeqNo,
+		ChatID:           message.ChatID,
+		Role:             message.Role,
+		Content:          eventContent(message),
+		ContentType:      message.ContentType,
+		ContentTruncated: message.ContentKey != "",
+		ContentHash:      eventContentHash(message),
+		PersonaID:        message.PersonaID,
+		Model:            message.Model,
+		Partial:          true,
+	})
+	if err := s.events.PublishMessageEvent(writeCtx, event); err != nil {
+		log.Errorw("Failed to publish partial message event", "error", err, "messageID", message.ID)
+	}
+}
+
+// saveReply saves a generated response, post-processed, checked against the guardrails and
+// translated into the chat language, as an assistant message of a chat, with the model that
+// generated it, its token usage and latency, and publishes it. The partial message saved while streaming becomes the reply.
+func (s *messageService) saveReply(ctx context.Context, chat *models.Chat, draft *replyDraft, llmResponse *dtos.LLMResponse, latency time.Duration) (*models.Message, error) {
+	log := logger.Context(ctx)
+
+	// The client may be gone by now; persist what was generated regardless
+	writeCtx, cancelWrite := detachedContext(ctx)
+	defer cancelWrite()
+
+	s.hooks.AfterGenerate(writeCtx, chat.ID, llmResponse)
+
+	// Adapters report the model they used; requests only name it when overriding the default
+	model := llmResponse.Model
+	if model == "" {
+		model = draft.request.Model
+	}
+
+	// Complete the partial message saved while streaming, or create the assistant message
+	assistantMessage := draft.message
+	if assistantMessage == nil {
+		assistantMessage = &models.Message{
+			ChatID:    chat.ID,
+			Role:      models.MessageRoleAssistant,
+			PersonaID: draft.personaID,
+			VariantID: draft.variantID,
+		}
+	}
+	assistantMessage.ContentType = replyContentType(llmResponse.Message.Content)
+	assistantMessage.Content = s.postProcessors.Process(llmResponse.Message.Content, assistantMessage.ContentType)
+	assistantMessage.Status = ""
+	assistantMessage.Model = model
+	assistantMessage.Provider = configs.AppConfig.LLM.Provider
+	assistantMessage.ModelVersion = llmResponse.ModelVersion
+	assistantMessage.TotalTokens = llmResponse.Usage.TotalTokens
+	assistantMessage.LatencyMs = latency.Milliseconds()
+	var violations []GuardrailViolation
+	if s.guardrails != nil {
+		assistantMessage.Content, violations = s.guardrails.Check(writeCtx, assistantMessage.Content, draft.guardrails)
+	}
+	if s.translator != nil && draft.translate {
+		s.translator.LocalizeReply(writeCtx, chat, assistantMessage)
+	}
+	if s.suggester != nil && chat.Suggestions {
+		suggestions := s.suggester.Suggest(writeCtx, lastUserContent(draft.request), assistantMessage.Content)
+		assistantMessage.Suggestions = strings.Join(suggestions, "\n")
+	}
+
+	// Save assistant message to database
+	endWrite := timing.Start(ctx, timing.StageDBWrite)
+	if draft.message != nil {
+		if err := s.messageRepo.UpdateReply(writeCtx, assistantMessage); err != nil {
+			endWrite()
+			return nil, err
+		}
+	} else if err := s.messageRepo.Create(writeCtx, assistantMessage); err != nil {
+		endWrite()
+		return nil, err
+	}
+	if len(violations) > 0 {
+		s.guardrails.Record(writeCtx, chat, assistantMessage, violations)
+	}
+
+	// The reply is already saved; a failure here only leaves it without artifacts
+	if err := s.saveArtifacts(writeCtx, assistantMessage); err != nil {
+		log.Errorw("Failed to save message artifacts", "error", err, "messageID", assistantMessage.ID)
+	}
+
+	// Likewise, a failure here only leaves the reply out of the budgets of its user and chat
+	if err := s.budgets.RecordUsage(writeCtx, chat.UserID, chat.ID, assistantMessage.ID, model, llmResponse.Usage); err != nil {
+		log.Errorw("Failed to record usage", "error", err, "messageID", assistantMessage.ID)
+	}
+	cost := s.budgets.EstimateCost(model, llmResponse.Usage)
+	if err := s.chatRepo.AddUsage(writeCtx, chat.ID, llmResponse.Usage.TotalTokens, cost); err != nil {
+		log.Errorw("Failed to add chat usage", "error", err, "messageID", assistantMessage.ID)
+	}
+	endWrite()
+
+	// Publish assistant message event
+	assistantMsgEvent := newEvent(ctx, models.EventMessageCreated, dtos.MessagePayload{
+		MessageID:        assistantMessage.ID,
+		SeqNo:            assistantMessage.SeqNo,
+		ChatID:           assistantMessage.ChatID,
+		Role:             assistantMessage.Role,
+		Content:          eventContent(assistantMessage),
+		ContentType:      assistantMessage.ContentType,
+		ContentTruncated: assistantMessage.ContentKey != "",
+		ContentHash:      eventContentHash(assistantMessage),
+		PersonaID:        assistantMessage.PersonaID,
+		Model:            assistantMessage.Model,
+		TotalTokens:      llmResponse.Usage.TotalTokens,
+	})
+
+	endPublish := timing.Start(ctx, timing.StagePublish)
+	if err := s.events.PublishMessageEvent(writeCtx, assistantMsgEvent); err != nil {
+		log.Errorw("Failed to publish assistant message event", "error", err, "messageID", assistantMessage.ID)
+		// Continue despite error
+	}
+	endPublish()
+
+	return assistantMessage, nil
+}
+
+// routePersona returns the persona of the chat first mentioned in the latest message of
+// the request, or nil when none is
+func (s *messageService) routePersona(ctx context.Context, chatID int64, request *dtos.LLMRequest) (*models.Persona, error) {
+	last := len(request.Messages) - 1
+	if last < 0 || request.Messages[last].Role != models.MessageRoleUser {
+		return nil, nil
+	}
+
+	mentions := extractMentions(request.Messages[last].Content)
+	if len(mentions) == 0 {
+		return nil, nil
+	}
+
+	personas, err := s.personaRepo.ListByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	for _, handle := range mentions {
+		for _, persona := range personas {
+			if persona.Handle == handle {
+				return persona, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// widgetOf returns the widget a chat was started from, or nil for other chats and chats of
+// deleted widgets
+func (s *messageService) widgetOf(ctx context.Context, chat *models.Chat) (*models.Widget, error) {
+	widgetID, ok := models.WidgetOf(chat.UserID)
+	if !ok {
+		return nil, nil
+	}
+
+	widget, err := s.widgetRepo.Get(ctx, widgetID)
+	if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+		return nil, nil
+	}
+	return widget, err
+}
+
+// experimentVariant returns the variant of the running experiment assigned to a chat, or
+// nil when no experiment is running
+func (s *messageService) experimentVariant(ctx context.Context, chatID int64) (*models.ExperimentVariant, error) {
+	experiment, err := s.experimentRepo.GetRunning(ctx)
+	if err != nil || experiment == nil {
+		return nil, err
+	}
+	return assignVariant(experiment, chatID), nil
+}
+
+// applyPersona makes the request use the model and system prompt of a persona
+func applyPersona(request *dtos.LLMRequest, persona *models.Persona) {
+	if persona.Model != "" {
+		request.Model = persona.Model
+	}
+	if persona.SystemPrompt != "" {
+		prompt := dtos.LLMMessage{Role: "system", Content: persona.SystemPrompt}
+		request.Messages = append([]dtos.LLMMessage{prompt}, request.Messages...)
+	}
 }
 
-// NewMessageService creates a new message service
-func NewMessageService(
-	messageRepo repositories.MessageRepository,
-	chatRepo repositories.ChatRepository,
-	llmAdapter adapters.LLMAdapter,
-	kafka KafkaProducer,
-) MessageService {
-	return &messageService{
-		messageRepo: messageRepo,
-		chatRepo:    chatRepo,
-		llmAdapter:  llmAdapter,
-		kafka:       kafka,
+// generate calls the LLM adapter. Streaming adapters are consumed chunk by chunk so that
+// content produced before a timeout or disconnect is returned alongside the error. Chunks
+// are passed on to onChunk when set; the response of other adapters is passed on whole.
+func (s *messageService) generate(ctx context.Context, request *dtos.LLMRequest, onChunk func(chunk *dtos.LLMChunk) error) (*dtos.LLMResponse, string, error) {
+	streamer, ok := s.llmAdapter.(adapters.LLMStreamer)
+	if !ok {
+		response, err := s.llmAdapter.GenerateResponse(ctx, request)
+		if err != nil || onChunk == nil {
+			return response, "", err
+		}
+		if err := onChunk(&dtos.LLMChunk{Content: response.Message.Content, Done: true}); err != nil {
+			return nil, response.Message.Content, err
+		}
+		return response, "", nil
+	}
+
+	var partial strings.Builder
+	response, err := streamer.StreamResponse(ctx, request, func(chunk *dtos.LLMChunk) error {
+		partial.WriteString(chunk.Content)
+		if onChunk != nil {
+			return onChunk(chunk)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, partial.String(), err
 	}
+
+	return response, "", nil
 }
 
-// SendMessage sends a new user message to a chat and gets LLM response
-func (s *messageService) SendMessage(ctx context.Context, chatID int64, userID string, req *dtos.MessageRequest) (*dtos.MessageResponse, error) {
+// GetMessage retrieves a message by ID
+func (s *messageService) GetMessage(ctx context.Context, id int64) (*dtos.MessageResponse, error) {
 	log := logger.Context(ctx)
-	log.Infow("Processing new message", "chatID", chatID, "userID", userID)
+	log.Debugw("Getting message", "id", id)
 
-	// Verify chat exists
-	chat, err := s.chatRepo.Get(ctx, chatID)
+	message, err := s.messageRepo.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Verify the user owns the chat
-	if chat.UserID != userID {
-		return nil, errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	return toMessageResponse(message), nil
+}
+
+// ResumeStream passes the chunks of the buffered stream of a reply to a user, from chunk
+// number from onwards, to onChunk as they are generated, until the reply is complete
+func (s *messageService) ResumeStream(ctx context.Context, messageID int64, userID, token string, from int, onChunk func(chunk *dtos.LLMChunk) error) error {
+	logger.Context(ctx).Debugw("Resuming stream", "messageID", messageID, "from", from)
+
+	if s.streams == nil {
+		return errors.New(errors.ErrNotFound, "Stream not found or expired")
 	}
+	return s.streams.follow(ctx, messageID, userID, token, from, onChunk)
+}
 
-	// Create user message
-	userMessage := &models.Message{
-		ChatID:  chatID,
-		UserID:  &userID,
-		Role:    "user",
-		Content: req.Content,
+// replyPollInterval is how often a reply generated by another instance is read again while
+// waiting for it
+const replyPollInterval = 500 * time.Millisecond
+
+// WaitForReply waits up to timeout for an assistant reply being generated to complete or
+// fail. Replies streamed by this instance are waited for in their stream buffer; others are
+// read again every replyPollInterval. The reply is returned as it is when the wait ends, so
+// it is still partial when the timeout elapsed first.
+func (s *messageService) WaitForReply(ctx context.Context, messageID int64, timeout time.Duration) (*dtos.MessageResponse, error) {
+	logger.Context(ctx).Debugw("Waiting for reply", "messageID", messageID, "timeout", timeout)
+
+	// Reads use ctx, so the reply is still read once the wait ended
+	wait, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	// The stream may end before the reply is saved, which is then polled for
+	streamEnded := false
+	for {
+		message, err := s.messageRepo.Get(ctx, messageID)
+		if err != nil {
+			return nil, err
+		}
+		if message.Status != models.MessageStatusPartial || wait.Err() != nil {
+			return toMessageResponse(message), nil
+		}
+
+		if !streamEnded && s.streams != nil && s.streams.wait(wait, messageID) {
+			streamEnded = true
+		} else {
+			select {
+			case <-time.After(replyPollInterval):
+			case <-wait.Done():
+			}
+		}
+	}
+}
+
+// ListMessages lists all messages for a chat
+func (s *messageService) ListMessages(ctx context.Context, userID string, req *dtos.ListMessagesRequest) (*dtos.ListMessagesResponse, error) {
+	log := logger.Context(ctx)
+	log.Debugw("Listing messages", "chatID", req.ChatID, "limit", req.Limit, "offset", req.Offset, "count", req.Count)
+
+	var messages []*models.Message
+	var page pageInfo
+	if req.Count {
+		var total int64
+		var err error
+		messages, total, err = s.messageRepo.GetByChatID(ctx, req.ChatID, req.Limit, req.Offset)
+		if err != nil {
+			return nil, err
+		}
+		page = countedPage(total, req.Offset, len(messages))
+	} else {
+		// Fetch one extra message to tell whether there is a next page
+		var err error
+		messages, err = s.messageRepo.ListByChatID(ctx, req.ChatID, req.Limit+1, req.Offset)
+		if err != nil {
+			return nil, err
+		}
+		page = uncountedPage(req.Offset, len(messages), req.Limit)
+		messages = messages[:min(len(messages), req.Limit)]
 	}
 
-	// Save user message to database
-	if err := s.messageRepo.Create(ctx, userMessage); err != nil {
+	messageIDs := make([]int64, len(messages))
+	for i, message := range messages {
+		messageIDs[i] = message.ID
+	}
+	reactions, err := s.messageRepo.CountReactions(ctx, messageIDs, userID)
+	if err != nil {
 		return nil, err
 	}
 
-	// Publish message event
-	userMsgEvent := &dtos.KafkaMessage[dtos.MessagePayload]{
-		ID:        uuid.New().String(),
-		Event:     models.EventMessageCreated,
-		Timestamp: time.Now().Unix(),
-		Payload: dtos.MessagePayload{
-			MessageID: userMessage.ID,
-			ChatID:    userMessage.ChatID,
-			UserID:    userMessage.UserID,
-			Role:      userMessage.Role,
-			Content:   userMessage.Content,
-		},
+	// Convert to response DTOs
+	messageResponses := make([]dtos.MessageResponse, len(messages))
+	for i, message := range messages {
+		messageResponses[i] = *toMessageResponse(message)
+		messageResponses[i].Reactions = reactions[message.ID]
 	}
 
-	if err := s.kafka.PublishMessageEvent(ctx, userMsgEvent); err != nil {
-		log.Errorw("Failed to publish user message event", "error", err, "messageID", userMessage.ID)
-		// Continue despite error
+	return &dtos.ListMessagesResponse{
+		Messages:       messageResponses,
+		Total:          page.total,
+		HasMore:        page.hasMore,
+		TotalEstimated: page.estimated,
+	}, nil
+}
+
+// LookupMessages gets the messages with the given IDs in the chats of a user in a single
+// query, in the order of the IDs without duplicates
+func (s *messageService) LookupMessages(ctx context.Context, userID string, ids []int64) (*dtos.LookupMessagesResponse, error) {
+	log := logger.Context(ctx)
+	log.Debugw("Looking up messages", "count", len(ids))
+
+	messages, err := s.messageRepo.ListByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get chat history for context
-	messages, _, err := s.messageRepo.GetByChatID(ctx, chatID, 20, 0)
+	messageIDs := make([]int64, len(messages))
+	byID := make(map[int64]*models.Message, len(messages))
+	for i, message := range messages {
+		messageIDs[i] = message.ID
+		byID[message.ID] = message
+	}
+	reactions, err := s.messageRepo.CountReactions(ctx, messageIDs, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Prepare LLM request with context
-	llmMessages := make([]dtos.LLMMessage, 0, len(messages)+1)
+	response := &dtos.LookupMessagesResponse{
+		Messages: make([]dtos.MessageResponse, 0, len(messages)),
+		Missing:  []int64{},
+	}
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
 
-	// Add previous messages as context (limit to a reasonable number)
-	for _, msg := range messages {
-		llmMessages = append(llmMessages, dtos.LLMMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+		message, ok := byID[id]
+		if !ok {
+			response.Missing = append(response.Missing, id)
+			continue
+		}
+		messageResponse := *toMessageResponse(message)
+		messageResponse.Reactions = reactions[id]
+		response.Messages = append(response.Messages, messageResponse)
 	}
+	return response, nil
+}
 
-	// Add the new message
-	llmMessages = append(llmMessages, dtos.LLMMessage{
-		Role:    userMessage.Role,
-		Content: userMessage.Content,
-	})
+// UpdateMessage updates a message
+func (s *messageService) UpdateMessage(ctx context.Context, id int64, req *dtos.MessageRequest) (*dtos.MessageResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Updating message", "id", id)
 
-	// Create LLM request
-	llmRequest := &dtos.LLMRequest{
-		Messages: llmMessages,
+	// Get existing message
+	message, err := s.messageRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get LLM response
-	llmResponse, err := s.llmAdapter.GenerateResponse(ctx, llmRequest)
-	if err != nil {
-		log.Errorw("LLM request failed", "error", err)
-		return nil, errors.Wrap(err, errors.ErrLLMService, "Failed to get response from LLM service")
+	// Only allow updating user messages, not assistant messages
+	if message.Role != models.MessageRoleUser {
+		return nil, errors.New(errors.ErrForbidden, "Can only update user messages")
+	}
+	if message.Status == models.MessageStatusTranscribing {
+		return nil, errors.New(errors.ErrInvalidRequest, "Message is being transcribed")
 	}
 
-	// Create assistant message
-	assistantMessage := &models.Message{
-		ChatID:  chatID,
-		Role:    "assistant",
-		Content: llmResponse.Message.Content,
+	chat, err := s.chatRepo.Get(ctx, message.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return nil, err
 	}
 
-	// Save assistant message to database
-	if err := s.messageRepo.Create(ctx, assistantMessage); err != nil {
+	contentType := message.ContentType
+	if req.ContentType != "" {
+		contentType = req.ContentType
+	}
+	if err := checkContent(req.Content, contentType); err != nil {
 		return nil, err
 	}
 
-	// Publish assistant message event
-	assistantMsgEvent := &dtos.KafkaMessage[dtos.MessagePayload]{
-		ID:        uuid.New().String(),
-		Event:     models.EventMessageCreated,
-		Timestamp: time.Now().Unix(),
-		Payload: dtos.MessagePayload{
-			MessageID: assistantMessage.ID,
-			ChatID:    assistantMessage.ChatID,
-			Role:      assistantMessage.Role,
-			Content:   assistantMessage.Content,
-		},
+	// Unchanged content does not create a revision
+	if message.Content == req.Content && message.ContentType == contentType {
+		return toMessageResponse(message), nil
 	}
 
-	if err := s.kafka.PublishMessageEvent(ctx, assistantMsgEvent); err != nil {
-		log.Errorw("Failed to publish assistant message event", "error", err, "messageID", assistantMessage.ID)
-		// Continue despite error
+	// A dry run stops once the new content is validated
+	if dryrun.Enabled(ctx) {
+		message.Content = req.Content
+		message.ContentType = contentType
+		return toMessageResponse(message), nil
 	}
 
-	// Return the user's message
-	return &dtos.MessageResponse{
-		ID:        userMessage.ID,
-		ChatID:    userMessage.ChatID,
-		UserID:    userMessage.UserID,
-		Role:      userMessage.Role,
-		Content:   userMessage.Content,
-		CreatedAt: userMessage.CreatedAt,
-		UpdatedAt: userMessage.UpdatedAt,
-	}, nil
+	// Update message, keeping the previous content as a revision
+	previousContent := message.Content
+	message.Content = req.Content
+	message.ContentType = contentType
+	if s.translator != nil {
+		s.translator.PrepareMessage(ctx, chat, message)
+	} else {
+		// The translation of the previous content must not be sent to the LLM
+		message.Language = ""
+		message.Translation = ""
+	}
+
+	// Save to database
+	if err := s.messageRepo.Revise(ctx, message, previousContent); err != nil {
+		return nil, err
+	}
+
+	// Publish event
+	event := newEvent(ctx, models.EventMessageUpdated, dtos.MessagePayload{
+		MessageID:        message.ID,
+		SeqNo:            message.SeqNo,
+		ChatID:           message.ChatID,
+		UserID:           message.UserID,
+		Role:             message.Role,
+		Content:          eventContent(message),
+		ContentType:      message.ContentType,
+		ContentTruncated: message.ContentKey != "",
+		ContentHash:      eventContentHash(message),
+	})
+
+	if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+		log.Errorw("Failed to publish message updated event", "error", err, "messageID", message.ID)
+	}
+
+	return toMessageResponse(message), nil
 }
 
-// GetMessage retrieves a message by ID
-func (s *messageService) GetMessage(ctx context.Context, id int64) (*dtos.MessageResponse, error) {
+// DeleteMessage deletes a message
+func (s *messageService) DeleteMessage(ctx context.Context, id int64) error {
 	log := logger.Context(ctx)
-	log.Debugw("Getting message", "id", id)
+	log.Infow("Deleting message", "id", id)
 
+	// Get existing message for the event payload
 	message, err := s.messageRepo.Get(ctx, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &dtos.MessageResponse{
-		ID:        message.ID,
+	chat, err := s.chatRepo.Get(ctx, message.ChatID)
+	if err != nil {
+		return err
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return err
+	}
+
+	if err := s.messageRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	// Publish event; the content of a deleted message is not republished
+	event := newEvent(ctx, models.EventMessageDeleted, dtos.MessagePayload{
+		MessageID: message.ID,
+		SeqNo:     message.SeqNo,
 		ChatID:    message.ChatID,
 		UserID:    message.UserID,
 		Role:      message.Role,
-		Content:   message.Content,
-		CreatedAt: message.CreatedAt,
-		UpdatedAt: message.UpdatedAt,
-	}, nil
+	})
+
+	if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+		// Just log the error but don't fail the request
+		log.Errorw("Failed to publish message deleted event", "error", err, "messageID", message.ID)
+	}
+
+	return nil
 }
 
-// ListMessages lists all messages for a chat
-func (s *messageService) ListMessages(ctx context.Context, req *dtos.ListMessagesRequest) (*dtos.ListMessagesResponse, error) {
+// DeleteMessages deletes the messages of a chat of the user matching the filters of a
+// request, publishing a deleted event for each
+func (s *messageService) DeleteMessages(ctx context.Context, chatID int64, req *dtos.DeleteMessagesRequest) (*dtos.DeleteMessagesResponse, error) {
 	log := logger.Context(ctx)
-	log.Debugw("Listing messages", "chatID", req.ChatID, "limit", req.Limit, "offset", req.Offset)
+	log.Infow("Deleting chat messages", "chatID", chatID, "before", req.Before, "role", req.Role, "ids", len(req.IDs))
+
+	// Deleting every message of a chat takes an explicit filter
+	if req.Before == nil && req.Role == "" && len(req.IDs) == 0 {
+		return nil, errors.New(errors.ErrInvalidRequest, "At least one of before, role and ids is required")
+	}
 
-	if req.Limit <= 0 {
-		req.Limit = 50
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return nil, err
 	}
 
-	messages, total, err := s.messageRepo.GetByChatID(ctx, req.ChatID, req.Limit, req.Offset)
+	messages, err := s.messageRepo.DeleteByChatID(ctx, chatID, req)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to response DTOs
-	messageResponses := make([]dtos.MessageResponse, len(messages))
+	ids := make([]int64, len(messages))
 	for i, message := range messages {
-		messageResponses[i] = dtos.MessageResponse{
-			ID:        message.ID,
+		ids[i] = message.ID
+		// The content of deleted messages is not republished
+		event := newEvent(ctx, models.EventMessageDeleted, dtos.MessagePayload{
+			MessageID: message.ID,
+			SeqNo:     message.SeqNo,
 			ChatID:    message.ChatID,
 			UserID:    message.UserID,
 			Role:      message.Role,
-			Content:   message.Content,
-			CreatedAt: message.CreatedAt,
-			UpdatedAt: message.UpdatedAt,
+		})
+		if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+			// Just log the error but don't fail the request
+			log.Errorw("Failed to publish message deleted event", "error", err, "messageID", message.ID)
 		}
 	}
 
-	return &dtos.ListMessagesResponse{
-		Messages: messageResponses,
-		Total:    total,
-	}, nil
+	log.Infow("Chat messages deleted", "chatID", chatID, "count", len(ids))
+	return &dtos.DeleteMessagesResponse{Deleted: len(ids), MessageIDs: ids}, nil
 }
 
-// UpdateMessage updates a message
-func (s *messageService) UpdateMessage(ctx context.Context, id int64, req *dtos.MessageRequest) (*dtos.MessageResponse, error) {
+// BroadcastAnnouncement posts a system message into the selected chats
+func (s *messageService) BroadcastAnnouncement(ctx context.Context, req *dtos.AnnouncementRequest) (*dtos.AnnouncementResponse, error) {
 	log := logger.Context(ctx)
-	log.Infow("Updating message", "id", id)
+	log.Infow("Broadcasting announcement", "chatIDs", len(req.ChatIDs), "userIDs", len(req.UserIDs))
+
+	chatIDs, err := s.chatRepo.ListIDs(ctx, req.ChatIDs, req.UserIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*models.Message, len(chatIDs))
+	for i, chatID := range chatIDs {
+		messages[i] = &models.Message{
+			ChatID:            chatID,
+			Role:              models.MessageRoleSystem,
+			Content:           req.Content,
+			ExcludeFromPrompt: true,
+		}
+	}
+
+	if len(messages) > 0 {
+		if err := s.messageRepo.CreateBatch(ctx, messages); err != nil {
+			return nil, err
+		}
+	}
+
+	// Publish message events
+	for _, message := range messages {
+		event := newEvent(ctx, models.EventMessageCreated, dtos.MessagePayload{
+			MessageID:        message.ID,
+			SeqNo:            message.SeqNo,
+			ChatID:           message.ChatID,
+			Role:             message.Role,
+			Content:          eventContent(message),
+			ContentType:      message.ContentType,
+			ContentTruncated: message.ContentKey != "",
+			ContentHash:      eventContentHash(message),
+		})
+
+		if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+			log.Errorw("Failed to publish announcement event", "error", err, "messageID", message.ID)
+			// Continue despite error
+		}
+	}
+
+	return &dtos.AnnouncementResponse{Delivered: len(messages)}, nil
+}
+
+// SetFeedback rates an assistant message
+func (s *messageService) SetFeedback(ctx context.Context, id int64, req *dtos.FeedbackRequest) (*dtos.MessageResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Rating message", "id", id, "rating", req.Rating)
 
-	// Get existing message
 	message, err := s.messageRepo.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Only allow updating user messages, not assistant messages
-	if message.Role != "user" {
-		return nil, errors.New(errors.ErrForbidden, "Can only update user messages")
+	if message.Role != models.MessageRoleAssistant {
+		return nil, errors.New(errors.ErrInvalidRequest, "Can only rate assistant messages")
 	}
 
-	// Update message
-	message.Content = req.Content
+	if err := s.messageRepo.SetFeedback(ctx, id, req.Rating); err != nil {
+		return nil, err
+	}
+	message.Feedback = req.Rating
 
-	// Save to database
-	if err := s.messageRepo.Update(ctx, message); err != nil {
+	return toMessageResponse(message), nil
+}
+
+// React adds or takes back an emoji reaction of a user to a message. Only a change is
+// published, along with the reactions to the message once changed, so clients showing the
+// chat can update them live.
+func (s *messageService) React(ctx context.Context, id int64, userID string, req *dtos.ReactionRequest) (*dtos.ReactionsResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Reacting to message", "id", id, "emoji", req.Emoji, "reacted", *req.Reacted)
+
+	if !isEmoji(req.Emoji) {
+		return nil, errors.New(errors.ErrInvalidRequest, "Reactions must be emoji")
+	}
+
+	message, err := s.messageRepo.Get(ctx, id)
+	if err != nil {
 		return nil, err
 	}
 
-	// Publish event
-	event := &dtos.KafkaMessage[dtos.MessagePayload]{
-		ID:        uuid.New().String(),
-		Event:     models.EventMessageUpdated,
-		Timestamp: time.Now().Unix(),
-		Payload: dtos.MessagePayload{
+	var changed bool
+	eventType := models.EventMessageReactionAdded
+	if *req.Reacted {
+		changed, err = s.messageRepo.AddReaction(ctx, &models.MessageReaction{
+			MessageID: id,
+			UserID:    userID,
+			Emoji:     req.Emoji,
+		})
+	} else {
+		eventType = models.EventMessageReactionRemoved
+		changed, err = s.messageRepo.RemoveReaction(ctx, id, userID, req.Emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.messageRepo.CountReactions(ctx, []int64{id}, userID)
+	if err != nil {
+		return nil, err
+	}
+	reactions := counts[id]
+	if reactions == nil {
+		reactions = []dtos.ReactionCount{}
+	}
+
+	if changed {
+		// Whether the user reacted is theirs alone, so the event carries the counts only
+		eventReactions := make([]dtos.ReactionCount, len(reactions))
+		for i, reaction := range reactions {
+			eventReactions[i] = dtos.ReactionCount{Emoji: reaction.Emoji, Count: reaction.Count}
+		}
+		event := newEvent(ctx, eventType, dtos.MessagePayload{
 			MessageID: message.ID,
+			SeqNo:     message.SeqNo,
 			ChatID:    message.ChatID,
 			UserID:    message.UserID,
 			Role:      message.Role,
-			Content:   message.Content,
-		},
+			Reaction: &dtos.ReactionPayload{
+				UserID:    userID,
+				Emoji:     req.Emoji,
+				Reactions: eventReactions,
+			},
+		})
+		if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+			// Just log the error but don't fail the request
+			log.Errorw("Failed to publish message reaction event", "error", err, "messageID", message.ID)
+		}
 	}
 
-	if err := s.kafka.PublishMessageEvent(ctx, event); err != nil {
-		log.Errorw("Failed to publish message updated event", "error", err, "messageID", message.ID)
+	return &dtos.ReactionsResponse{
+		MessageID: id,
+		Changed:   changed,
+		Reactions: reactions,
+	}, nil
+}
+
+// ListRevisions lists the previous versions of a message, oldest first
+func (s *messageService) ListRevisions(ctx context.Context, messageID int64) (*dtos.ListMessageRevisionsResponse, error) {
+	log := logger.Context(ctx)
+	log.Debugw("Listing message revisions", "messageID", messageID)
+
+	revisions, err := s.messageRepo.ListRevisions(ctx, messageID)
+	if err != nil {
+		return nil, err
 	}
 
-	return &dtos.MessageResponse{
-		ID:        message.ID,
-		ChatID:    message.ChatID,
-		UserID:    message.UserID,
-		Role:      message.Role,
-		Content:   message.Content,
-		CreatedAt: message.CreatedAt,
-		UpdatedAt: message.UpdatedAt,
-	}, nil
+	// Convert to response DTOs
+	responses := make([]dtos.MessageRevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		responses[i] = dtos.MessageRevisionResponse{
+			ID:        revision.ID,
+			MessageID: revision.MessageID,
+			Content:   revision.Content,
+			CreatedAt: revision.CreatedAt,
+		}
+	}
+
+	return &dtos.ListMessageRevisionsResponse{Revisions: responses}, nil
 }
 
-// DeleteMessage deletes a message
-func (s *messageService) DeleteMessage(ctx context.Context, id int64) error {
+// ListModelHistory lists the models that generated the replies of a chat of the user,
+// grouped in runs of consecutive replies of the same provider, model and model version, so
+// that changes of behavior can be matched with the model changes, including upgrades made
+// by the provider under the same model name
+func (s *messageService) ListModelHistory(ctx context.Context, chatID int64) (*dtos.ChatModelHistoryResponse, error) {
 	log := logger.Context(ctx)
-	log.Infow("Deleting message", "id", id)
+	log.Debugw("Listing chat model history", "chatID", chatID)
+
+	messages, err := s.messageRepo.ListModels(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []dtos.ModelRunResponse{}
+	for _, message := range messages {
+		if n := len(runs); n > 0 {
+			last := &runs[n-1]
+			if last.Provider == message.Provider && last.Model == message.Model && last.ModelVersion == message.ModelVersion {
+				last.LastMessageID = message.ID
+				last.LastAt = message.CreatedAt
+				last.Messages++
+				continue
+			}
+		}
+		runs = append(runs, dtos.ModelRunResponse{
+			Provider:       message.Provider,
+			Model:          message.Model,
+			ModelVersion:   message.ModelVersion,
+			FirstMessageID: message.ID,
+			LastMessageID:  message.ID,
+			Messages:       1,
+			FirstAt:        message.CreatedAt,
+			LastAt:         message.CreatedAt,
+		})
+	}
+
+	return &dtos.ChatModelHistoryResponse{ChatID: chatID, Runs: runs}, nil
+}
 
-	return s.messageRepo.Delete(ctx, id)
+// ListArtifacts lists the code blocks extracted from a message, in their order in the message
+func (s *messageService) ListArtifacts(ctx context.Context, messageID int64) (*dtos.ListArtifactsResponse, error) {
+	log := logger.Context(ctx)
+	log.Debugw("Listing message artifacts", "messageID", messageID)
+
+	artifacts, err := s.artifactRepo.ListByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.ArtifactResponse, len(artifacts))
+	for i, artifact := range artifacts {
+		responses[i] = *toArtifactResponse(artifact)
+	}
+
+	return &dtos.ListArtifactsResponse{Artifacts: responses}, nil
+}
+
+// GetArtifact retrieves an artifact of a message
+func (s *messageService) GetArtifact(ctx context.Context, messageID, artifactID int64) (*dtos.ArtifactResponse, error) {
+	log := logger.Context(ctx)
+	log.Debugw("Getting message artifact", "messageID", messageID, "artifactID", artifactID)
+
+	artifact, err := s.artifactRepo.Get(ctx, artifactID)
+	if err != nil {
+		return nil, err
+	}
+	if artifact.MessageID != messageID {
+		return nil, errors.New(errors.ErrNotFound, "Artifact not found")
+	}
+
+	return toArtifactResponse(artifact), nil
+}
+
+// saveArtifacts stores the fenced code blocks of an assistant message as artifacts. The
+// messages of privacy mode chats have none, since they would be stored.
+func (s *messageService) saveArtifacts(ctx context.Context, message *models.Message) error {
+	if message.Ephemeral() {
+		return nil
+	}
+	blocks := extractCodeBlocks(message.Content)
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	artifacts := make([]*models.Artifact, len(blocks))
+	for i, block := range blocks {
+		artifacts[i] = &models.Artifact{
+			MessageID: message.ID,
+			Index:     i,
+			Language:  block.Language,
+			Content:   block.Content,
+		}
+	}
+	return s.artifactRepo.CreateBatch(ctx, artifacts)
+}
+
+// toArtifactResponse converts an artifact model to its response DTO
+func toArtifactResponse(artifact *models.Artifact) *dtos.ArtifactResponse {
+	return &dtos.ArtifactResponse{
+		ID:        artifact.ID,
+		MessageID: artifact.MessageID,
+		Index:     artifact.Index,
+		Language:  artifact.Language,
+		Filename:  artifactFilename(artifact),
+		Content:   artifact.Content,
+		CreatedAt: artifact.CreatedAt,
+	}
+}
+
+// toMessageResponse converts a message model to its response DTO
+func toMessageResponse(message *models.Message) *dtos.MessageResponse {
+	return &dtos.MessageResponse{
+		ID:                message.ID,
+		PublicID:          message.PublicID,
+		ChatID:            message.ChatID,
+		SeqNo:             message.SeqNo,
+		UserID:            message.UserID,
+		Role:              message.Role,
+		Content:           message.Content,
+		Name:              message.Name,
+		ToolCallID:        message.ToolCallID,
+		ContentType:       message.ContentType,
+		Kind:              message.Kind,
+		Status:            message.Status,
+		PersonaID:         message.PersonaID,
+		Model:             message.Model,
+		Provider:          message.Provider,
+		ModelVersion:      message.ModelVersion,
+		Feedback:          message.Feedback,
+		Suggestions:       message.SuggestionList(),
+		Language:          message.Language,
+		EditedAt:          message.EditedAt,
+		CreatedAt:         message.CreatedAt,
+		UpdatedAt:         message.UpdatedAt,
+		ExcludeFromPrompt: message.ExcludeFromPrompt,
+	}
 }
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// NotificationEvents returns the event bus subscriber notifying users of the events they
+// have a notification trigger for: budget alerts and activity of other users in their chats.
+// Notifications are sent in the background, so that publishers do not wait on them.
+func NotificationEvents(notifications NotificationService, chatRepo repositories.ChatRepository) EventPublisher {
+	return EventHandlers{
+		Budget: func(ctx context.Context, message *dtos.KafkaMessage[dtos.BudgetPayload]) error {
+			payload := message.Payload
+			// The service-wide budget has no user to notify
+			if payload.UserID == "" {
+				return nil
+			}
+
+			go notifyInBackground(ctx, func(ctx context.Context) error {
+				return notifications.Notify(ctx, payload.UserID, NotificationBudgetAlert, &dtos.BudgetAlertNotification{
+					Exceeded:     message.Event == models.EventBudgetExceeded,
+					Period:       payload.Period,
+					Spent:        payload.Spent,
+					MonthlyLimit: payload.MonthlyLimit,
+				})
+			})
+			return nil
+		},
+		Message: func(ctx context.Context, message *dtos.KafkaMessage[dtos.MessagePayload]) error {
+			payload := message.Payload
+			// Only messages posted by users are activity; replies are the owner's own doing
+			if message.Event != models.EventMessageCreated || payload.UserID == nil {
+				return nil
+			}
+
+			go notifyInBackground(ctx, func(ctx context.Context) error {
+				chat, err := chatRepo.Get(ctx, payload.ChatID)
+				if err != nil {
+					if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+						return nil
+					}
+					return err
+				}
+				if chat.UserID == *payload.UserID {
+					return nil
+				}
+				// Notifications may be stored for digests, so they leave out the content of
+				// the messages of privacy mode chats
+				content := truncateRunes(payload.Content, pushBodyLength)
+				if chat.Ephemeral {
+					content = ""
+				}
+
+				return notifications.Notify(ctx, chat.UserID, NotificationSharedChatActivity, &dtos.SharedChatActivityNotification{
+					ChatID:    chat.ID,
+					ChatTitle: chat.Title,
+					AuthorID:  *payload.UserID,
+					Content:   content,
+				})
+			})
+			return nil
+		},
+	}
+}
+
+// notifyInBackground runs a notification after the request of the event is done; failures
+// are only logged
+func notifyInBackground(ctx context.Context, notify func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	if err := notify(ctx); err != nil {
+		logger.Context(ctx).Warnw("Failed to send event notification", "error", err)
+	}
+}
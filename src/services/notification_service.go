@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// Notification triggers
+const (
+	// NotificationExportReady is sent when a chat export is completed
+	NotificationExportReady = "export_ready"
+	// NotificationScheduledPrompt is sent with the result of a scheduled prompt
+	NotificationScheduledPrompt = "scheduled_prompt"
+	// NotificationShareInvitation is sent when a chat is shared with a user
+	NotificationShareInvitation = "share_invitation"
+	// NotificationReplyDone is sent when the replies to a batch of prompts are done
+	NotificationReplyDone = "reply_done"
+	// NotificationSharedChatActivity is sent when another user posts in a chat of the user
+	NotificationSharedChatActivity = "shared_chat_activity"
+	// NotificationBudgetAlert is sent when the budget of the user reaches its warning
+	// threshold or is exceeded
+	NotificationBudgetAlert = "budget_alert"
+)
+
+// DigestUnsubscribePath is the route of the unsubscribe links of activity digests
+const DigestUnsubscribePath = "/notifications/digest/unsubscribe"
+
+// Notification channels
+const (
+	NotificationChannelEmail   = "email"
+	NotificationChannelPush    = "push"
+	NotificationChannelWebhook = "webhook"
+)
+
+// NotificationService defines the interface for notifying users by email, push and webhook
+type NotificationService interface {
+	// GetPreferences returns the email notification preferences of a user
+	GetPreferences(ctx context.Context, userID string) (*dtos.NotificationPreferencesResponse, error)
+
+	// SetPreferences sets the email notification preferences of a user
+	SetPreferences(ctx context.Context, userID string, req *dtos.NotificationPreferencesRequest) (*dtos.NotificationPreferencesResponse, error)
+
+	// GetSettings returns the notification settings of a user, for every channel
+	GetSettings(ctx context.Context, userID string) (*dtos.NotificationSettingsResponse, error)
+
+	// SetSettings sets the notification settings of a user, for every channel
+	SetSettings(ctx context.Context, userID string, req *dtos.NotificationSettingsRequest) (*dtos.NotificationSettingsResponse, error)
+
+	// Notify notifies a user about a trigger through the channels they enabled, rendering
+	// its template with data. Nothing is sent when the trigger is not configured or the
+	// user opted out of it.
+	Notify(ctx context.Context, userID string, trigger string, data interface{}) error
+
+	// SendDigests emails the activity digests due, and returns the number sent
+	SendDigests(ctx context.Context) (int, error)
+
+	// Unsubscribe turns off the activity digest of the user of a signed unsubscribe link
+	Unsubscribe(ctx context.Context, req *dtos.DigestUnsubscribeRequest) error
+}
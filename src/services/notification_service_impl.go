@@ -0,0 +1,591 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// notificationSubjects are the email subjects of the notification triggers, which
+// each have a template of the same name. They are also the titles of push notifications.
+var notificationSubjects = map[string]string{
+	NotificationExportReady:        "Your chat export is ready",
+	NotificationScheduledPrompt:    "Your scheduled prompt has been answered",
+	NotificationShareInvitation:    "A chat was shared with you",
+	NotificationReplyDone:          "Your replies are ready",
+	NotificationSharedChatActivity: "New activity in your chat",
+	NotificationBudgetAlert:        "Budget alert",
+}
+
+// digestTriggers are the triggers whose emails are replaced by the activity digest for the
+// users receiving one
+var digestTriggers = []string{
+	NotificationScheduledPrompt,
+	NotificationSharedChatActivity,
+	NotificationBudgetAlert,
+}
+
+// digestPeriods are the time between the digests of each frequency
+var digestPeriods = map[string]time.Duration{
+	models.DigestDaily:  24 * time.Hour,
+	models.DigestWeekly: 7 * 24 * time.Hour,
+}
+
+// digestOff is the digest frequency of users not receiving digests
+const digestOff = "off"
+
+// notificationSummary is implemented by notification data summarizing the notification in a
+// sentence, for push notifications and webhooks
+type notificationSummary interface {
+	Summary() string
+}
+
+// notificationService implements the NotificationService interface
+type notificationService struct {
+	config           configs.Notifications
+	notificationRepo repositories.NotificationRepository
+	email            adapters.EmailAdapter
+	push             PushService
+	webhook          adapters.WebhookAdapter
+	budgets          BudgetService
+	templates        map[string]*template.Template
+	digest           *template.Template
+	// channels are the channels notifications can be delivered through
+	channels []string
+}
+
+// NewNotificationService creates a new notification service. push is nil when push
+// notifications are disabled, and webhook when webhook notifications are. budgets reports
+// the spending of the users in their digests.
+func NewNotificationService(
+	config configs.Notifications,
+	notificationRepo repositories.NotificationRepository,
+	email adapters.EmailAdapter,
+	push PushService,
+	webhook adapters.WebhookAdapter,
+	budgets BudgetService,
+) (NotificationService, error) {
+	if config.Digest.Enabled && config.Digest.Secret == "" {
+		return nil, fmt.Errorf("digest secret is required to sign unsubscribe links")
+	}
+
+	templates := make(map[string]*template.Template, len(notificationSubjects))
+	for trigger := range notificationSubjects {
+		tmpl, err := template.ParseFS(templateFiles, "templates/layout.html", "templates/"+trigger+".html")
+		if err != nil {
+			return nil, err
+		}
+		templates[trigger] = tmpl
+	}
+	digest, err := template.ParseFS(templateFiles, "templates/layout.html", "templates/digest.html")
+	if err != nil {
+		return nil, err
+	}
+
+	channels := []string{NotificationChannelEmail}
+	if push != nil {
+		channels = append(channels, NotificationChannelPush)
+	}
+	if webhook != nil {
+		channels = append(channels, NotificationChannelWebhook)
+	}
+
+	return &notificationService{
+		config:           config,
+		notificationRepo: notificationRepo,
+		email:            email,
+		push:             push,
+		webhook:          webhook,
+		budgets:          budgets,
+		templates:        templates,
+		digest:           digest,
+		channels:         channels,
+	}, nil
+}
+
+// GetPreferences returns the email notification preferences of a user
+func (s *notificationService) GetPreferences(ctx context.Context, userID string) (*dtos.NotificationPreferencesResponse, error) {
+	preference, err := s.getPreference(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toPreferencesResponse(preference), nil
+}
+
+// SetPreferences sets the email notification preferences of a user. Triggers left out of
+// the request keep their current setting.
+func (s *notificationService) SetPreferences(ctx context.Context, userID string, req *dtos.NotificationPreferencesRequest) (*dtos.NotificationPreferencesResponse, error) {
+	preference, err := s.getPreference(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	disabled := splitList(preference.DisabledNotifications)
+	for trigger, enabled := range req.Triggers {
+		if _, ok := notificationSubjects[trigger]; !ok {
+			return nil, errors.New(errors.ErrInvalidRequest, "Unknown notification trigger: "+trigger)
+		}
+		disabled = setNotificationEnabled(disabled, trigger, NotificationChannelEmail, enabled)
+	}
+
+	preference.Email = req.Email
+	preference.DisabledNotifications = strings.Join(disabled, ",")
+	if err := s.notificationRepo.Upsert(ctx, preference); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Notification preferences updated", "userID", userID, "disabledNotifications", preference.DisabledNotifications)
+	return s.toPreferencesResponse(preference), nil
+}
+
+// GetSettings returns the notification settings of a user, for every channel
+func (s *notificationService) GetSettings(ctx context.Context, userID string) (*dtos.NotificationSettingsResponse, error) {
+	preference, err := s.getPreference(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toSettingsResponse(preference), nil
+}
+
+// SetSettings sets the notification settings of a user. Settings, triggers and channels
+// left out of the request keep their current value.
+func (s *notificationService) SetSettings(ctx context.Context, userID string, req *dtos.NotificationSettingsRequest) (*dtos.NotificationSettingsResponse, error) {
+	preference, err := s.getPreference(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Email != nil {
+		preference.Email = *req.Email
+	}
+	if req.WebhookURL != nil {
+		if *req.WebhookURL != "" {
+			if s.webhook == nil {
+				return nil, errors.New(errors.ErrInvalidRequest, "Webhook notifications are not enabled")
+			}
+			if err := s.webhook.CheckURL(*req.WebhookURL); err != nil {
+				return nil, errors.Wrap(err, errors.ErrInvalidRequest, "Webhook URL is not allowed")
+			}
+		}
+		preference.WebhookURL = *req.WebhookURL
+	}
+	if req.WebhookSecret != nil {
+		preference.WebhookSecret = *req.WebhookSecret
+	}
+	if req.Digest != nil {
+		frequency := *req.Digest
+		if frequency == digestOff {
+			frequency = ""
+		} else if !s.config.Digest.Enabled {
+			return nil, errors.New(errors.ErrInvalidRequest, "Digests are not enabled")
+		}
+		preference.DigestFrequency = frequency
+	}
+
+	disabled := splitList(preference.DisabledNotifications)
+	for trigger, channels := range req.Triggers {
+		if _, ok := notificationSubjects[trigger]; !ok {
+			return nil, errors.New(errors.ErrInvalidRequest, "Unknown notification trigger: "+trigger)
+		}
+		for channel, enabled := range channels {
+			if !slices.Contains(s.channels, channel) {
+				return nil, errors.New(errors.ErrInvalidRequest, "Unknown notification channel: "+channel)
+			}
+			disabled = setNotificationEnabled(disabled, trigger, channel, enabled)
+		}
+	}
+	preference.DisabledNotifications = strings.Join(disabled, ",")
+
+	if err := s.notificationRepo.Upsert(ctx, preference); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Notification settings updated", "userID", userID, "disabledNotifications", preference.DisabledNotifications)
+	return s.toSettingsResponse(preference), nil
+}
+
+// Notify notifies a user about a trigger through each channel they did not opt out of.
+// Emails of the triggers covered by the digest of the user are queued for it instead. A
+// failing channel does not keep the notification from the others.
+func (s *notificationService) Notify(ctx context.Context, userID string, trigger string, data interface{}) error {
+	log := logger.Context(ctx)
+
+	if !s.config.Enabled || !slices.Contains(s.config.Triggers, trigger) {
+		return nil
+	}
+
+	tmpl, ok := s.templates[trigger]
+	if !ok {
+		return errors.New(errors.ErrInternal, "Unknown notification trigger: "+trigger)
+	}
+
+	preference, err := s.getPreference(ctx, userID)
+	if err != nil {
+		return err
+	}
+	disabled := splitList(preference.DisabledNotifications)
+
+	var errs []error
+	for _, channel := range s.channels {
+		if slices.Contains(disabled, notificationKey(trigger, channel)) {
+			log.Debugw("Notification skipped by user preferences", "userID", userID, "trigger", trigger, "channel", channel)
+			continue
+		}
+
+		var err error
+		switch channel {
+		case NotificationChannelEmail:
+			if preference.Email == "" {
+				continue
+			}
+			if s.digested(preference, trigger) {
+				if err = s.notificationRepo.AddDigestItem(ctx, &models.NotificationDigestItem{
+					UserID:  userID,
+					Trigger: trigger,
+					Summary: summarize(data),
+				}); err == nil {
+					log.Infow("Notification queued for digest", "userID", userID, "trigger", trigger)
+					continue
+				}
+				break
+			}
+			err = s.sendEmail(ctx, preference.Email, tmpl, trigger, data)
+		case NotificationChannelPush:
+			err = s.push.NotifyUser(ctx, userID, &dtos.PushNotification{
+				Title: notificationSubjects[trigger],
+				Body:  truncateRunes(summarize(data), pushBodyLength),
+				Data:  map[string]string{"trigger": trigger},
+			})
+		case NotificationChannelWebhook:
+			if preference.WebhookURL == "" {
+				continue
+			}
+			err = s.postWebhook(ctx, preference, trigger, data)
+		}
+		if err != nil {
+			log.Errorw("Failed to send notification", "error", err, "userID", userID, "trigger", trigger, "channel", channel)
+			errs = append(errs, err)
+			continue
+		}
+		log.Infow("Notification sent", "userID", userID, "trigger", trigger, "channel", channel)
+	}
+
+	if len(errs) > 0 {
+		return errors.Wrap(stderrors.Join(errs...), errors.ErrInternal, "Failed to send notification")
+	}
+	return nil
+}
+
+// SendDigests emails the digests due of each frequency, up to BatchSize per run. Digests
+// with nothing to report are skipped, and the next one covers a new period. A digest that
+// fails is logged and retried on the next run.
+func (s *notificationService) SendDigests(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	if !s.config.Enabled || !s.config.Digest.Enabled {
+		return 0, nil
+	}
+
+	sent := 0
+	remaining := s.config.Digest.BatchSize
+	now := time.Now()
+	for _, frequency := range []string{models.DigestDaily, models.DigestWeekly} {
+		if remaining <= 0 {
+			break
+		}
+		preferences, err := s.notificationRepo.ListDigestsDue(ctx, frequency, now.Add(-digestPeriods[frequency]), remaining)
+		if err != nil {
+			return sent, err
+		}
+		remaining -= len(preferences)
+
+		for _, preference := range preferences {
+			if ctx.Err() != nil {
+				return sent, ctx.Err()
+			}
+			ok, err := s.sendDigest(ctx, preference, now)
+			if err != nil {
+				log.Errorw("Failed to send digest", "error", err, "userID", preference.UserID, "frequency", frequency)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+// sendDigest emails the digest of a user, listing the oldest MaxItems notifications queued
+// and the budget covering the user, and reports whether there was anything to send
+func (s *notificationService) sendDigest(ctx context.Context, preference *models.NotificationPreference, now time.Time) (bool, error) {
+	items, total, err := s.notificationRepo.ListDigestItems(ctx, preference.UserID, s.config.Digest.MaxItems)
+	if err != nil {
+		return false, err
+	}
+	budget, err := s.budgets.Quota(ctx, preference.UserID)
+	if err != nil {
+		return false, err
+	}
+	// The service-wide budget is not the user's to report
+	if budget != nil && budget.UserID != preference.UserID {
+		budget = nil
+	}
+
+	// A budget well within its limit is not worth an email on its own
+	if len(items) == 0 && (budget == nil || budget.Status == dtos.BudgetStatusOK) {
+		return false, s.notificationRepo.MarkDigestSent(ctx, preference.UserID, now)
+	}
+
+	data := &dtos.DigestNotification{
+		Frequency:      preference.DigestFrequency,
+		Items:          make([]dtos.DigestItem, len(items)),
+		More:           total - int64(len(items)),
+		UnsubscribeURL: s.unsubscribeURL(ctx, preference.UserID),
+	}
+	for i, item := range items {
+		data.Items[i] = dtos.DigestItem{
+			Subject:   notificationSubjects[item.Trigger],
+			Summary:   item.Summary,
+			CreatedAt: item.CreatedAt,
+		}
+	}
+	if budget != nil {
+		data.Budget = fmt.Sprintf("You spent $%.2f of your budget of $%.2f this month.", budget.Spent, *budget.MonthlyLimit)
+		if budget.Status == dtos.BudgetStatusExceeded {
+			data.Budget = fmt.Sprintf("You spent your budget of $%.2f this month.", *budget.MonthlyLimit)
+		}
+	}
+
+	subject := "Your " + preference.DigestFrequency + " digest"
+	var html bytes.Buffer
+	if err := s.digest.ExecuteTemplate(&html, "layout", map[string]interface{}{
+		"Subject": subject,
+		"Data":    data,
+	}); err != nil {
+		return false, err
+	}
+	if err := s.email.Send(ctx, &dtos.Email{To: preference.Email, Subject: subject, HTML: html.String(), ListUnsubscribe: data.UnsubscribeURL}); err != nil {
+		return false, err
+	}
+
+	if len(items) > 0 {
+		if err := s.notificationRepo.DeleteDigestItems(ctx, preference.UserID, items[len(items)-1].ID); err != nil {
+			return true, err
+		}
+	}
+	logger.Context(ctx).Infow("Digest sent", "userID", preference.UserID, "frequency", preference.DigestFrequency, "items", len(items))
+	return true, s.notificationRepo.MarkDigestSent(ctx, preference.UserID, now)
+}
+
+// Unsubscribe turns off the activity digest of the user of a signed unsubscribe link, and
+// drops the notifications queued for it
+func (s *notificationService) Unsubscribe(ctx context.Context, req *dtos.DigestUnsubscribeRequest) error {
+	if s.config.Digest.Secret == "" || !hmac.Equal([]byte(req.Signature), []byte(s.signUnsubscribe(req.Region, req.UserID))) {
+		return errors.New(errors.ErrForbidden, "Invalid unsubscribe link")
+	}
+	// The link was signed in the region of the user
+	if req.Region != "" {
+		ctx = adapters.WithRegion(ctx, req.Region)
+	}
+
+	preference, err := s.getPreference(ctx, req.UserID)
+	if err != nil {
+		return err
+	}
+	if preference.DigestFrequency != "" {
+		preference.DigestFrequency = ""
+		if err := s.notificationRepo.Upsert(ctx, preference); err != nil {
+			return err
+		}
+	}
+	if err := s.notificationRepo.DeleteDigestItems(ctx, req.UserID, math.MaxInt64); err != nil {
+		return err
+	}
+
+	logger.Context(ctx).Infow("Unsubscribed from digest", "userID", req.UserID)
+	return nil
+}
+
+// digested reports whether the email of a trigger is replaced by the digest of a user
+func (s *notificationService) digested(preference *models.NotificationPreference, trigger string) bool {
+	return s.config.Digest.Enabled && preference.DigestFrequency != "" && slices.Contains(digestTriggers, trigger)
+}
+
+// unsubscribeURL returns the signed link unsubscribing a user of the region of ctx from digests
+func (s *notificationService) unsubscribeURL(ctx context.Context, userID string) string {
+	region := adapters.RegionOf(ctx)
+	query := url.Values{}
+	query.Set("user", userID)
+	if region != "" {
+		query.Set("region", region)
+	}
+	query.Set("signature", s.signUnsubscribe(region, userID))
+
+	return strings.TrimSuffix(s.config.Digest.BaseURL, "/") + DigestUnsubscribePath + "?" + query.Encode()
+}
+
+// signUnsubscribe returns the HMAC of the unsubscribe link of a user of a region
+func (s *notificationService) signUnsubscribe(region, userID string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.Digest.Secret))
+	fmt.Fprintf(mac, "%s\n%s", region, userID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendEmail emails a notification, rendering the template of its trigger with data
+func (s *notificationService) sendEmail(ctx context.Context, to string, tmpl *template.Template, trigger string, data interface{}) error {
+	subject := notificationSubjects[trigger]
+	var html bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&html, "layout", map[string]interface{}{
+		"Subject": subject,
+		"Data":    data,
+	}); err != nil {
+		return err
+	}
+
+	return s.email.Send(ctx, &dtos.Email{
+		To:      to,
+		Subject: subject,
+		HTML:    html.String(),
+	})
+}
+
+// postWebhook posts a notification to the webhook of a user
+func (s *notificationService) postWebhook(ctx context.Context, preference *models.NotificationPreference, trigger string, data interface{}) error {
+	body, err := json.Marshal(&dtos.WebhookNotification{
+		ID:        uuid.NewString(),
+		Trigger:   trigger,
+		Subject:   notificationSubjects[trigger],
+		Summary:   summarize(data),
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.webhook.Post(ctx, preference.WebhookURL, preference.WebhookSecret, body)
+}
+
+// getPreference returns the notification preferences of a user, or empty preferences
+// when the user has none
+func (s *notificationService) getPreference(ctx context.Context, userID string) (*models.NotificationPreference, error) {
+	preference, err := s.notificationRepo.Get(ctx, userID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+			return &models.NotificationPreference{UserID: userID}, nil
+		}
+		return nil, err
+	}
+	return preference, nil
+}
+
+// toPreferencesResponse converts notification preferences to their email preferences
+// response DTO, listing every trigger the service is configured with
+func (s *notificationService) toPreferencesResponse(preference *models.NotificationPreference) *dtos.NotificationPreferencesResponse {
+	disabled := splitList(preference.DisabledNotifications)
+
+	triggers := make(map[string]bool, len(s.config.Triggers))
+	for _, trigger := range s.config.Triggers {
+		triggers[trigger] = !slices.Contains(disabled, notificationKey(trigger, NotificationChannelEmail))
+	}
+
+	return &dtos.NotificationPreferencesResponse{
+		Email:    preference.Email,
+		Triggers: triggers,
+	}
+}
+
+// toSettingsResponse converts notification preferences to their settings response DTO,
+// listing every trigger the service is configured with and every channel
+func (s *notificationService) toSettingsResponse(preference *models.NotificationPreference) *dtos.NotificationSettingsResponse {
+	disabled := splitList(preference.DisabledNotifications)
+
+	triggers := make(map[string]map[string]bool, len(s.config.Triggers))
+	for _, trigger := range s.config.Triggers {
+		channels := make(map[string]bool, len(s.channels))
+		for _, channel := range s.channels {
+			channels[channel] = !slices.Contains(disabled, notificationKey(trigger, channel))
+		}
+		triggers[trigger] = channels
+	}
+
+	return &dtos.NotificationSettingsResponse{
+		Email:         preference.Email,
+		WebhookURL:    preference.WebhookURL,
+		WebhookSigned: preference.WebhookSecret != "",
+		Digest:        digestFrequency(preference),
+		Channels:      s.channels,
+		Triggers:      triggers,
+	}
+}
+
+// digestFrequency returns the frequency of the digest of a user, or off
+func digestFrequency(preference *models.NotificationPreference) string {
+	if preference.DigestFrequency == "" {
+		return digestOff
+	}
+	return preference.DigestFrequency
+}
+
+// notificationKey identifies a channel of a trigger in the notifications a user opted out of
+func notificationKey(trigger, channel string) string {
+	return trigger + ":" + channel
+}
+
+// setNotificationEnabled enables or disables a channel of a trigger in the sorted list of
+// notifications a user opted out of
+func setNotificationEnabled(disabled []string, trigger, channel string, enabled bool) []string {
+	key := notificationKey(trigger, channel)
+	disabled = slices.DeleteFunc(disabled, func(k string) bool { return k == key })
+	if !enabled {
+		disabled = append(disabled, key)
+	}
+	sort.Strings(disabled)
+	return disabled
+}
+
+// summarize returns the summary of notification data, if it has one
+func summarize(data interface{}) string {
+	if summary, ok := data.(notificationSummary); ok {
+		return summary.Summary()
+	}
+	return ""
+}
+
+// splitList splits a comma-separated list
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
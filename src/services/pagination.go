@@ -0,0 +1,31 @@
+package services
+
+// pageInfo describes where a listed page stands in the full result
+type pageInfo struct {
+	total     int64
+	hasMore   bool
+	estimated bool
+}
+
+// countedPage returns the page info of a page of n items at offset out of an exact total
+func countedPage(total int64, offset, n int) pageInfo {
+	return pageInfo{
+		total:   total,
+		hasMore: int64(offset+n) < total,
+	}
+}
+
+// uncountedPage returns the page info of a page fetched with limit+1 rows instead of counting.
+// The total is then only a lower bound: the items up to this page, plus one when there are more.
+func uncountedPage(offset, fetched, limit int) pageInfo {
+	hasMore := fetched > limit
+	seen := offset + min(fetched, limit)
+	if hasMore {
+		seen++
+	}
+	return pageInfo{
+		total:     int64(seen),
+		hasMore:   hasMore,
+		estimated: true,
+	}
+}
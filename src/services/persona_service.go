@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// PersonaService defines the interface for managing the assistant personas of group chats
+type PersonaService interface {
+	// CreatePersona adds an assistant persona to a chat owned by the user
+	CreatePersona(ctx context.Context, userID string, chatID int64, req *dtos.PersonaRequest) (*dtos.PersonaResponse, error)
+
+	// ListPersonas lists the personas of a chat owned by the user
+	ListPersonas(ctx context.Context, userID string, chatID int64) (*dtos.ListPersonasResponse, error)
+
+	// UpdatePersona updates a persona of a chat owned by the user
+	UpdatePersona(ctx context.Context, userID string, chatID, personaID int64, req *dtos.UpdatePersonaRequest) (*dtos.PersonaResponse, error)
+
+	// DeletePersona removes a persona from a chat owned by the user; its messages are kept
+	DeletePersona(ctx context.Context, userID string, chatID, personaID int64) error
+}
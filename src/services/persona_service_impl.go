@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// maxPersonasPerChat bounds the personas of a chat
+const maxPersonasPerChat = 10
+
+// handlePattern matches valid persona handles
+var handlePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// mentionPattern matches the @handle mentions of a message, not preceded by a word
+// character so that email addresses are not taken as mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_-]{1,32})`)
+
+// personaService implements the PersonaService interface
+type personaService struct {
+	personaRepo repositories.PersonaRepository
+	chatRepo    repositories.ChatRepository
+}
+
+// NewPersonaService creates a new persona service
+func NewPersonaService(personaRepo repositories.PersonaRepository, chatRepo repositories.ChatRepository) PersonaService {
+	return &personaService{
+		personaRepo: personaRepo,
+		chatRepo:    chatRepo,
+	}
+}
+
+// CreatePersona adds an assistant persona to a chat owned by the user
+func (s *personaService) CreatePersona(ctx context.Context, userID string, chatID int64, req *dtos.PersonaRequest) (*dtos.PersonaResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Creating persona", "chatID", chatID, "handle", req.Handle)
+
+	if err := s.checkOwner(ctx, userID, chatID); err != nil {
+		return nil, err
+	}
+
+	handle := strings.ToLower(req.Handle)
+	if !handlePattern.MatchString(handle) {
+		return nil, errors.New(errors.ErrInvalidRequest, "Handle must be 1 to 32 letters, digits, '-' or '_'")
+	}
+
+	personas, err := s.personaRepo.ListByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(personas) >= maxPersonasPerChat {
+		return nil, errors.New(errors.ErrInvalidRequest, "Chat has too many personas")
+	}
+	for _, persona := range personas {
+		if persona.Handle == handle {
+			return nil, errors.New(errors.ErrInvalidRequest, "Handle is already used in this chat")
+		}
+	}
+
+	persona := &models.Persona{
+		ChatID:       chatID,
+		Handle:       handle,
+		Name:         req.Name,
+		Model:        req.Model,
+		SystemPrompt: req.SystemPrompt,
+	}
+	if err := s.personaRepo.Create(ctx, persona); err != nil {
+		return nil, err
+	}
+
+	return toPersonaResponse(persona), nil
+}
+
+// ListPersonas lists the personas of a chat owned by the user
+func (s *personaService) ListPersonas(ctx context.Context, userID string, chatID int64) (*dtos.ListPersonasResponse, error) {
+	if err := s.checkOwner(ctx, userID, chatID); err != nil {
+		return nil, err
+	}
+
+	personas, err := s.personaRepo.ListByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.PersonaResponse, len(personas))
+	for i, persona := range personas {
+		responses[i] = *toPersonaResponse(persona)
+	}
+
+	return &dtos.ListPersonasResponse{Personas: responses}, nil
+}
+
+// UpdatePersona updates a persona of a chat owned by the user
+func (s *personaService) UpdatePersona(ctx context.Context, userID string, chatID, personaID int64, req *dtos.UpdatePersonaRequest) (*dtos.PersonaResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Updating persona", "chatID", chatID, "personaID", personaID)
+
+	if err := s.checkOwner(ctx, userID, chatID); err != nil {
+		return nil, err
+	}
+
+	personas, err := s.personaRepo.ListByChatID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, persona := range personas {
+		if persona.ID != personaID {
+			continue
+		}
+
+		persona.Name = req.Name
+		persona.Model = req.Model
+		persona.SystemPrompt = req.SystemPrompt
+		if err := s.personaRepo.Update(ctx, persona); err != nil {
+			return nil, err
+		}
+		return toPersonaResponse(persona), nil
+	}
+
+	return nil, errors.New(errors.ErrNotFound, "Persona not found")
+}
+
+// DeletePersona removes a persona from a chat owned by the user
+func (s *personaService) DeletePersona(ctx context.Context, userID string, chatID, personaID int64) error {
+	log := logger.Context(ctx)
+	log.Infow("Deleting persona", "chatID", chatID, "personaID", personaID)
+
+	if err := s.checkOwner(ctx, userID, chatID); err != nil {
+		return err
+	}
+
+	return s.personaRepo.Delete(ctx, chatID, personaID)
+}
+
+// checkOwner verifies that the user owns the chat
+func (s *personaService) checkOwner(ctx context.Context, userID string, chatID int64) error {
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	if chat.UserID != userID {
+		return errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+	return nil
+}
+
+// extractMentions returns the lowercased handles mentioned in a message, in order
+func extractMentions(content string) []string {
+	var handles []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		handles = append(handles, strings.ToLower(match[1]))
+	}
+	return handles
+}
+
+// toPersonaResponse converts a persona model to its response DTO
+func toPersonaResponse(persona *models.Persona) *dtos.PersonaResponse {
+	return &dtos.PersonaResponse{
+		ID:           persona.ID,
+		ChatID:       persona.ChatID,
+		Handle:       persona.Handle,
+		Name:         persona.Name,
+		Model:        persona.Model,
+		SystemPrompt: persona.SystemPrompt,
+		CreatedAt:    persona.CreatedAt,
+		UpdatedAt:    persona.UpdatedAt,
+	}
+}
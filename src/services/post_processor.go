@@ -0,0 +1,213 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// Post-processor names
+const (
+	PostProcessorMarkdown       = "markdown"
+	PostProcessorCitations      = "citations"
+	PostProcessorTrimWhitespace = "trim_whitespace"
+	PostProcessorBannedPhrases  = "banned_phrases"
+)
+
+// PostProcessor transforms the content of an assistant reply before it is stored
+type PostProcessor func(content string) string
+
+// PostProcessorChain applies the configured post-processors in order. A nil chain leaves
+// content unchanged.
+type PostProcessorChain struct {
+	processors []PostProcessor
+	// markdown flags the processors relying on Markdown syntax, which other content is left to
+	markdown []bool
+}
+
+// NewPostProcessorChain creates the chain of the configured post-processors
+func NewPostProcessorChain(config configs.PostProcessing) (*PostProcessorChain, error) {
+	chain := &PostProcessorChain{}
+	for _, name := range config.Processors {
+		var processor PostProcessor
+		markdown := false
+		switch name {
+		case PostProcessorMarkdown:
+			processor, markdown = normalizeMarkdown, true
+		case PostProcessorCitations:
+			processor, markdown = formatCitations, true
+		case PostProcessorTrimWhitespace:
+			processor = trimWhitespace
+		case PostProcessorBannedPhrases:
+			var err error
+			if processor, err = replaceBannedPhrases(config.BannedPhrases); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown post-processor %q", name)
+		}
+		chain.processors = append(chain.processors, processor)
+		chain.markdown = append(chain.markdown, markdown)
+	}
+
+	return chain, nil
+}
+
+// Process applies the post-processors to the content of a reply of a content type. JSON
+// content is left unchanged, and Markdown post-processors only apply to Markdown.
+func (c *PostProcessorChain) Process(content, contentType string) string {
+	if c == nil || contentType == models.ContentTypeJSON {
+		return content
+	}
+
+	for i, process := range c.processors {
+		if c.markdown[i] && contentType != models.ContentTypeMarkdown {
+			continue
+		}
+		content = process(content)
+	}
+	return content
+}
+
+// mapProse applies fn to the lines of content outside fenced code blocks, with the index
+// of the line. Lines for which fn returns false are dropped.
+func mapProse(lines []string, fn func(i int, line string) (string, bool)) []string {
+	result := make([]string, 0, len(lines))
+	inFence := false
+	for i, line := range lines {
+		if isFence(line) {
+			inFence = !inFence
+			result = append(result, line)
+			continue
+		}
+		if inFence {
+			result = append(result, line)
+			continue
+		}
+		if line, keep := fn(i, line); keep {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// isFence reports whether a line opens or closes a fenced code block
+func isFence(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+var (
+	markdownBullet = regexp.MustCompile(`^(\s*)[*+](\s+)`)
+	markdownRule   = regexp.MustCompile(`^\s*([*_-])(\s*[*_-]){2,}\s*$`)
+)
+
+// normalizeMarkdown normalizes line endings, bullet markers and blank lines outside code
+// blocks, and closes a code block left open
+func normalizeMarkdown(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+
+	blank := 0
+	lines = mapProse(lines, func(_ int, line string) (string, bool) {
+		if strings.TrimSpace(line) == "" {
+			blank++
+			return line, blank <= 1
+		}
+		blank = 0
+		if markdownRule.MatchString(line) {
+			return line, true
+		}
+		return markdownBullet.ReplaceAllString(line, "$1-$2"), true
+	})
+
+	fences := 0
+	for _, line := range lines {
+		if isFence(line) {
+			fences++
+		}
+	}
+	if fences%2 == 1 {
+		lines = append(lines, "```")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+var (
+	// vendorCitation matches citation markers some models emit, such as 【4:0†source】
+	vendorCitation = regexp.MustCompile(`【[^】]*】`)
+	// citationSource matches a numbered source at the start of a line, such as [1] https://example.com
+	citationSource = regexp.MustCompile(`^\s*\[(\d+)\]:?\s+<?(https?://[^\s>]+)>?\s*$`)
+	// citationMarker matches an inline citation; those followed by ( or : are already links
+	citationMarker = regexp.MustCompile(`\[(\d+)\]([(:])?`)
+)
+
+// formatCitations removes vendor citation markers and links the numbered citations of a
+// reply to their sources listed at its end, formatting the list consistently
+func formatCitations(content string) string {
+	content = vendorCitation.ReplaceAllString(content, "")
+	lines := strings.Split(content, "\n")
+
+	sources := make(map[string]string)
+	isSource := make(map[int]bool)
+	mapProse(lines, func(i int, line string) (string, bool) {
+		if match := citationSource.FindStringSubmatch(line); match != nil {
+			sources[match[1]] = match[2]
+			isSource[i] = true
+		}
+		return line, true
+	})
+	if len(sources) == 0 {
+		return content
+	}
+
+	lines = mapProse(lines, func(i int, line string) (string, bool) {
+		if isSource[i] {
+			match := citationSource.FindStringSubmatch(line)
+			return fmt.Sprintf("- [%s] <%s>", match[1], match[2]), true
+		}
+		return citationMarker.ReplaceAllStringFunc(line, func(marker string) string {
+			match := citationMarker.FindStringSubmatch(marker)
+			url, ok := sources[match[1]]
+			if !ok || match[2] != "" {
+				return marker
+			}
+			return fmt.Sprintf("[[%s]](%s)", match[1], url)
+		}), true
+	})
+
+	return strings.Join(lines, "\n")
+}
+
+// trimWhitespace removes trailing whitespace from every line, and the blank lines
+// around the reply
+func trimWhitespace(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// replaceBannedPhrases returns a post-processor replacing the banned phrases, case-insensitively
+func replaceBannedPhrases(phrases []configs.BannedPhrase) (PostProcessor, error) {
+	patterns := make([]*regexp.Regexp, len(phrases))
+	for i, phrase := range phrases {
+		if phrase.Phrase == "" {
+			return nil, fmt.Errorf("banned phrase %d is empty", i)
+		}
+		patterns[i] = regexp.MustCompile(`(?i)` + regexp.QuoteMeta(phrase.Phrase))
+	}
+
+	return func(content string) string {
+		for i, pattern := range patterns {
+			content = pattern.ReplaceAllLiteralString(content, phrases[i].Replacement)
+		}
+		return content
+	}, nil
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/tokenizer"
+	"github.com/nvnamsss/chat/src/pkg/warnings"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// Prompt building strategies
+const (
+	// PromptStrategyLastN sends the latest HistoryLimit messages
+	PromptStrategyLastN = "last_n"
+	// PromptStrategyTokenBudget sends the latest messages that fit in TokenBudget
+	PromptStrategyTokenBudget = "token_budget"
+	// PromptStrategySummary sends the chat summary followed by the messages after it
+	PromptStrategySummary = "summary"
+)
+
+// Sources of the instruction layers, in the order they are sent to the model
+const (
+	InstructionSourceWorkspace = "workspace"
+	InstructionSourceUser      = "user"
+	InstructionSourceChat      = "chat"
+)
+
+// summaryPrefix introduces the chat summary in the prompt
+const summaryPrefix = "Summary of the earlier conversation:\n"
+
+// PromptBuilder builds the LLM request for a chat from its history
+type PromptBuilder interface {
+	// Build builds the LLM request from the latest messages of a chat,
+	// which must already include the message being answered
+	Build(ctx context.Context, chat *models.Chat) (*dtos.LLMRequest, error)
+
+	// Preview builds the LLM request Build would once a user message with content is sent
+	// to a chat, without saving it
+	Preview(ctx context.Context, chat *models.Chat, content string) (*dtos.LLMRequest, error)
+
+	// Instructions returns the instruction layers of a chat that are set, in the order they
+	// are sent to the model: the workspace default, the user preference, then the chat
+	// system prompt
+	Instructions(ctx context.Context, chat *models.Chat) ([]dtos.InstructionLayer, error)
+}
+
+// promptBuilder implements the PromptBuilder interface
+type promptBuilder struct {
+	config      configs.Prompt
+	model       string // Tokens are counted for the LLM model
+	messageRepo repositories.MessageRepository
+	// workspaceRepo and preferenceRepo hold the instructions of the workspace and the users
+	workspaceRepo  repositories.WorkspaceRepository
+	preferenceRepo repositories.UserPreferenceRepository
+	tokens         *tokenizer.Tokenizer
+}
+
+// NewPromptBuilder creates a new prompt builder using the configured strategy, counting the
+// tokens of prompts for model
+func NewPromptBuilder(
+	config configs.Prompt,
+	model string,
+	messageRepo repositories.MessageRepository,
+	workspaceRepo repositories.WorkspaceRepository,
+	preferenceRepo repositories.UserPreferenceRepository,
+	tokens *tokenizer.Tokenizer,
+) PromptBuilder {
+	if config.HistoryLimit <= 0 {
+		config.HistoryLimit = 20
+	}
+
+	return &promptBuilder{
+		config:         config,
+		model:          model,
+		messageRepo:    messageRepo,
+		workspaceRepo:  workspaceRepo,
+		preferenceRepo: preferenceRepo,
+		tokens:         tokens,
+	}
+}
+
+// Build builds the LLM request from the latest messages of a chat
+func (b *promptBuilder) Build(ctx context.Context, chat *models.Chat) (*dtos.LLMRequest, error) {
+	return b.build(ctx, chat, nil)
+}
+
+// Preview builds the LLM request Build would once a user message with content is sent
+func (b *promptBuilder) Preview(ctx context.Context, chat *models.Chat, content string) (*dtos.LLMRequest, error) {
+	return b.build(ctx, chat, &dtos.LLMMessage{Role: models.MessageRoleUser, Content: content})
+}
+
+// Instructions returns the instruction layers of a chat that are set, in the order they are
+// sent to the model
+func (b *promptBuilder) Instructions(ctx context.Context, chat *models.Chat) ([]dtos.InstructionLayer, error) {
+	settings, err := workspaceDefaults(ctx, b.workspaceRepo)
+	if err != nil {
+		return nil, err
+	}
+	userLayers, err := b.userInstructions(ctx, chat)
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []dtos.InstructionLayer
+	if settings.SystemPrompt != "" {
+		layers = append(layers, dtos.InstructionLayer{Source: InstructionSourceWorkspace, Content: settings.SystemPrompt})
+	}
+	return append(layers, userLayers...), nil
+}
+
+// userInstructions returns the layers of the instructions of the user and of the chat that
+// are set, in this order. The workspace layer is added once the reply is routed, since the
+// system prompt of a persona, widget or experiment variant takes its place.
+func (b *promptBuilder) userInstructions(ctx context.Context, chat *models.Chat) ([]dtos.InstructionLayer, error) {
+	var layers []dtos.InstructionLayer
+
+	preference, err := b.preferenceRepo.Get(ctx, chat.UserID)
+	if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+		preference, err = &models.UserPreference{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if preference.Instructions != "" {
+		layers = append(layers, dtos.InstructionLayer{Source: InstructionSourceUser, Content: preference.Instructions})
+	}
+	if chat.SystemPrompt != "" {
+		layers = append(layers, dtos.InstructionLayer{Source: InstructionSourceChat, Content: chat.SystemPrompt})
+	}
+	return layers, nil
+}
+
+// build builds the LLM request from the latest messages of a chat, followed by the pending
+// message when set
+func (b *promptBuilder) build(ctx context.Context, chat *models.Chat, pending *dtos.LLMMessage) (*dtos.LLMRequest, error) {
+	useSummary := b.config.Strategy == PromptStrategySummary && chat.Summary != ""
+
+	// With a summary, only the messages it does not cover are needed
+	afterID := int64(0)
+	if useSummary {
+		afterID = chat.SummaryMessageID
+	}
+
+	// The pending message takes the place of the oldest message of the history
+	limit := b.config.HistoryLimit
+	if pending != nil {
+		limit--
+	}
+
+	// One more message than the limit is read to tell whether older ones are left out
+	var messages []*models.Message
+	truncated := false
+	if limit > 0 {
+		var err error
+		messages, err = b.messageRepo.GetRecent(ctx, chat.ID, afterID, limit+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) > limit {
+			messages = messages[1:]
+			truncated = true
+		}
+	}
+
+	// The instructions of the user and the chat come first, then the system messages of the
+	// history whatever their place in it
+	layers, err := b.userInstructions(ctx, chat)
+	if err != nil {
+		return nil, err
+	}
+	instructions := make([]dtos.LLMMessage, 0, len(layers))
+	for _, layer := range layers {
+		instructions = append(instructions, dtos.LLMMessage{Role: models.MessageRoleSystem, Content: layer.Content})
+	}
+	history, llmMessages := splitInstructions(toLLMMessages(messages))
+	instructions = append(instructions, history...)
+	if pending != nil {
+		llmMessages = append(llmMessages, *pending)
+	}
+
+	var summary *dtos.LLMMessage
+	if useSummary {
+		summary = &dtos.LLMMessage{Role: "system", Content: summaryPrefix + chat.Summary}
+	}
+
+	if b.config.Strategy == PromptStrategyTokenBudget || b.config.Strategy == PromptStrategySummary {
+		count := func(message dtos.LLMMessage) int {
+			return b.tokens.CountMessage(b.model, tokenMessage(message))
+		}
+		budget := b.config.TokenBudget
+		for _, instruction := range instructions {
+			budget -= count(instruction)
+		}
+		if summary != nil {
+			budget -= count(*summary)
+		}
+		n := len(llmMessages)
+		llmMessages = trimToBudget(llmMessages, budget, count)
+		truncated = truncated || len(llmMessages) < n
+	}
+	llmMessages = dropOrphanResults(llmMessages)
+
+	if truncated {
+		warnings.Add(ctx, warnings.ContextTruncated, "Older messages of the chat were left out of the prompt")
+	}
+	if summary != nil {
+		warnings.Add(ctx, warnings.SummaryUsed, "Earlier messages of the chat were sent as a summary")
+		instructions = append(instructions, *summary)
+	}
+	llmMessages = append(instructions, llmMessages...)
+
+	return &dtos.LLMRequest{Messages: llmMessages}, nil
+}
+
+// toLLMMessages converts chat messages to LLM messages, in the model language when they
+// were translated, leaving out the messages excluded from prompts, such as announcements
+// which are meant for users only, and voice messages without a transcript
+func toLLMMessages(messages []*models.Message) []dtos.LLMMessage {
+	llmMessages := make([]dtos.LLMMessage, 0, len(messages))
+	for _, msg := range messages {
+		if !msg.InPrompt() {
+			continue
+		}
+		content := msg.Content
+		if msg.Translation != "" {
+			content = msg.Translation
+		}
+		llmMessages = append(llmMessages, dtos.LLMMessage{
+			Role:       msg.Role,
+			Content:    content,
+			Name:       msg.Name,
+			ToolCallID: msg.ToolCallID,
+		})
+	}
+	return llmMessages
+}
+
+// splitInstructions separates the system messages of a history from the rest, keeping the
+// order of both
+func splitInstructions(messages []dtos.LLMMessage) ([]dtos.LLMMessage, []dtos.LLMMessage) {
+	var instructions, rest []dtos.LLMMessage
+	for _, message := range messages {
+		if message.Role == models.MessageRoleSystem {
+			instructions = append(instructions, message)
+		} else {
+			rest = append(rest, message)
+		}
+	}
+	return instructions, rest
+}
+
+// dropOrphanResults drops the tool and function results starting a history, whose
+// assistant message making the calls was left out; providers reject results that do not
+// follow one
+func dropOrphanResults(messages []dtos.LLMMessage) []dtos.LLMMessage {
+	for i, message := range messages {
+		if !isResultRole(message.Role) {
+			return messages[i:]
+		}
+	}
+	return nil
+}
+
+// isResultRole reports whether a role marks the results of tool or function calls
+func isResultRole(role string) bool {
+	return role == models.MessageRoleTool || role == models.MessageRoleFunction
+}
+
+// trimToBudget drops the oldest messages until their tokens, counted with count, fit in
+// budget. The latest message is always kept.
+func trimToBudget(messages []dtos.LLMMessage, budget int, count func(dtos.LLMMessage) int) []dtos.LLMMessage {
+	total := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		total += count(messages[i])
+		if total > budget && i < len(messages)-1 {
+			return messages[i+1:]
+		}
+	}
+	return messages
+}
+
+// tokenMessage converts an LLM message to the message the tokenizer counts
+func tokenMessage(message dtos.LLMMessage) tokenizer.Message {
+	return tokenizer.Message{Role: message.Role, Content: message.Content, Name: message.Name}
+}
+
+// countPromptTokens returns the tokens of the prompt of an LLM request for a model
+func countPromptTokens(tokens *tokenizer.Tokenizer, model string, request *dtos.LLMRequest) int {
+	messages := make([]tokenizer.Message, len(request.Messages))
+	for i, message := range request.Messages {
+		messages[i] = tokenMessage(message)
+	}
+	return tokens.CountTokens(model, messages)
+}
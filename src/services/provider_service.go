@@ -0,0 +1,13 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// ProviderService defines the interface for reporting the health of the LLM providers
+type ProviderService interface {
+	// GetStatus reports the recent success rate, median latency and circuit breaker state of each provider
+	GetStatus(ctx context.Context) *dtos.ProviderStatusResponse
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// providerService implements the ProviderService interface
+type providerService struct {
+	providers []*adapters.LLMHealth
+}
+
+// NewProviderService creates a new provider service reporting the health of the given providers
+func NewProviderService(providers ...*adapters.LLMHealth) ProviderService {
+	return &providerService{providers: providers}
+}
+
+// GetStatus reports the health of each provider within its window
+func (s *providerService) GetStatus(ctx context.Context) *dtos.ProviderStatusResponse {
+	response := &dtos.ProviderStatusResponse{
+		Providers: make([]dtos.ProviderStatus, len(s.providers)),
+	}
+	for i, provider := range s.providers {
+		response.Providers[i] = provider.Status()
+		response.WindowSeconds = max(response.WindowSeconds, int64(provider.Window().Seconds()))
+	}
+
+	return response
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// PushService defines the interface for mobile push notifications
+type PushService interface {
+	// RegisterDevice registers a device of a user for push notifications
+	RegisterDevice(ctx context.Context, userID string, req *dtos.RegisterDeviceRequest) (*dtos.DeviceResponse, error)
+
+	// UnregisterDevice stops push notifications to a device of a user
+	UnregisterDevice(ctx context.Context, userID string, token string) error
+
+	// MuteChat silences the push notifications of a chat owned by the user
+	MuteChat(ctx context.Context, userID string, chatID int64, req *dtos.MuteChatRequest) (*dtos.ChatMuteResponse, error)
+
+	// UnmuteChat restores the push notifications of a chat owned by the user
+	UnmuteChat(ctx context.Context, userID string, chatID int64) (*dtos.ChatMuteResponse, error)
+
+	// NotifyMessage pushes a new chat message to the devices of the chat owner,
+	// unless they sent it or muted the chat
+	NotifyMessage(ctx context.Context, payload *dtos.MessagePayload) error
+
+	// NotifyUser pushes a notification to the devices of a user
+	NotifyUser(ctx context.Context, userID string, notification *dtos.PushNotification) error
+}
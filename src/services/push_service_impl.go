@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"strconv"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// pushBodyLength is the number of characters of a message shown in its notification
+const pushBodyLength = 200
+
+// pushService implements the PushService interface
+type pushService struct {
+	deviceRepo   repositories.DeviceRepository
+	chatRepo     repositories.ChatRepository
+	pushAdapters map[string]adapters.PushAdapter
+}
+
+// NewPushService creates a new push notification service sending through the
+// push adapter of each platform. pushAdapters is nil when the service only manages
+// devices and mutes, notifications being pushed by the consumer.
+func NewPushService(
+	deviceRepo repositories.DeviceRepository,
+	chatRepo repositories.ChatRepository,
+	pushAdapters map[string]adapters.PushAdapter,
+) PushService {
+	return &pushService{
+		deviceRepo:   deviceRepo,
+		chatRepo:     chatRepo,
+		pushAdapters: pushAdapters,
+	}
+}
+
+// RegisterDevice registers a device of a user for push notifications
+func (s *pushService) RegisterDevice(ctx context.Context, userID string, req *dtos.RegisterDeviceRequest) (*dtos.DeviceResponse, error) {
+	device := &models.Device{
+		UserID:   userID,
+		Platform: req.Platform,
+		Token:    req.Token,
+	}
+	if err := s.deviceRepo.Upsert(ctx, device); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Device registered", "userID", userID, "platform", device.Platform)
+	return &dtos.DeviceResponse{
+		Platform:  device.Platform,
+		Token:     device.Token,
+		CreatedAt: device.CreatedAt,
+	}, nil
+}
+
+// UnregisterDevice stops push notifications to a device of a user
+func (s *pushService) UnregisterDevice(ctx context.Context, userID string, token string) error {
+	if err := s.deviceRepo.Delete(ctx, userID, token); err != nil {
+		return err
+	}
+
+	logger.Context(ctx).Infow("Device unregistered", "userID", userID)
+	return nil
+}
+
+// MuteChat silences the push notifications of a chat owned by the user
+func (s *pushService) MuteChat(ctx context.Context, userID string, chatID int64, req *dtos.MuteChatRequest) (*dtos.ChatMuteResponse, error) {
+	if err := s.checkOwner(ctx, userID, chatID); err != nil {
+		return nil, err
+	}
+
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		return nil, errors.New(errors.ErrInvalidRequest, "Mute end must be in the future")
+	}
+
+	mute := &models.ChatMute{
+		ChatID: chatID,
+		UserID: userID,
+		Until:  req.Until,
+	}
+	if err := s.deviceRepo.SetMute(ctx, mute); err != nil {
+		return nil, err
+	}
+
+	return &dtos.ChatMuteResponse{ChatID: chatID, Muted: true, Until: mute.Until}, nil
+}
+
+// UnmuteChat restores the push notifications of a chat owned by the user
+func (s *pushService) UnmuteChat(ctx context.Context, userID string, chatID int64) (*dtos.ChatMuteResponse, error) {
+	if err := s.checkOwner(ctx, userID, chatID); err != nil {
+		return nil, err
+	}
+
+	if err := s.deviceRepo.DeleteMute(ctx, chatID, userID); err != nil {
+		return nil, err
+	}
+
+	return &dtos.ChatMuteResponse{ChatID: chatID, Muted: false}, nil
+}
+
+// NotifyMessage pushes a new chat message to the devices of the chat owner. Assistant
+// replies and messages posted by other users are pushed; announcements, instructions, the
+// results of tool calls and the summaries users asked for are not.
+func (s *pushService) NotifyMessage(ctx context.Context, payload *dtos.MessagePayload) error {
+	if payload.Role == models.MessageRoleSystem || isResultRole(payload.Role) || payload.Kind == models.MessageKindSummary {
+		return nil
+	}
+
+	chat, err := s.chatRepo.Get(ctx, payload.ChatID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+			// The chat was deleted since the message was sent
+			return nil
+		}
+		return err
+	}
+
+	recipient := chat.UserID
+	if payload.UserID != nil && *payload.UserID == recipient {
+		return nil
+	}
+
+	muted, err := s.deviceRepo.IsMuted(ctx, chat.ID, recipient)
+	if err != nil || muted {
+		return err
+	}
+
+	return s.NotifyUser(ctx, recipient, &dtos.PushNotification{
+		Title:    chat.Title,
+		Body:     truncateRunes(payload.Content, pushBodyLength),
+		ThreadID: "chat-" + strconv.FormatInt(chat.ID, 10),
+		Data: map[string]string{
+			"chatId":    strconv.FormatInt(chat.ID, 10),
+			"messageId": strconv.FormatInt(payload.MessageID, 10),
+		},
+	})
+}
+
+// NotifyUser pushes a notification to the devices of a user. Devices whose token was
+// rejected are unregistered.
+func (s *pushService) NotifyUser(ctx context.Context, recipient string, notification *dtos.PushNotification) error {
+	log := logger.Context(ctx)
+
+	devices, err := s.deviceRepo.ListByUserID(ctx, recipient)
+	if err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		adapter, ok := s.pushAdapters[device.Platform]
+		if !ok {
+			continue
+		}
+
+		err := adapter.Send(ctx, device.Token, notification)
+		if stderrors.Is(err, adapters.ErrInvalidDeviceToken) {
+			log.Infow("Unregistering rejected device", "userID", recipient, "platform", device.Platform)
+			if err := s.deviceRepo.DeleteByToken(ctx, device.Token); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			// A failing device does not hold back the others; the event is not retried
+			// so that devices already notified do not get the notification twice
+			log.Warnw("Failed to push notification", "error", err, "userID", recipient, "platform", device.Platform)
+		}
+	}
+
+	return nil
+}
+
+// checkOwner verifies that the user owns the chat
+func (s *pushService) checkOwner(ctx context.Context, userID string, chatID int64) error {
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	if chat.UserID != userID {
+		return errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+	return nil
+}
+
+// truncateRunes shortens s to at most n characters, marking the cut with an ellipsis
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+)
+
+// recentChatsKeyPrefix prefixes the keys of recent chats views in the cache
+const recentChatsKeyPrefix = "recent-chats:"
+
+// RecentChats keeps a view of each user's most recently updated chats in a cache: the IDs
+// of up to Size chats in the order of the chat list, with the number of chats of the user.
+// Views are rebuilt from the database on a miss and invalidated by the chat events of the
+// instance; views changed through other instances are seen once they expire.
+type RecentChats struct {
+	config configs.RecentChats
+	cache  adapters.CacheAdapter
+}
+
+// NewRecentChats creates the recent chats views, kept in cache
+func NewRecentChats(config configs.RecentChats, cache adapters.CacheAdapter) *RecentChats {
+	if config.Size < 1 {
+		config.Size = 1
+	}
+	return &RecentChats{config: config, cache: cache}
+}
+
+// Covers tells whether the page of the chat list at offset is within the view
+func (r *RecentChats) Covers(limit, offset int) bool {
+	return offset+limit <= r.config.Size
+}
+
+// Get returns the IDs of the most recently updated chats of a user, most recent first, and
+// the number of chats of the user. Errors of the cache are logged and reported as a miss.
+func (r *RecentChats) Get(ctx context.Context, userID string) ([]int64, int64, bool) {
+	value, ok, err := r.cache.Get(ctx, recentChatsKey(ctx, userID))
+	if err != nil {
+		logger.Context(ctx).Warnw("Failed to get recent chats from the cache", "userID", userID, "error", err)
+		return nil, 0, false
+	}
+	if !ok {
+		return nil, 0, false
+	}
+	ids, total, ok := decodeRecentChats(value)
+	if !ok {
+		logger.Context(ctx).Warnw("Ignoring unreadable recent chats", "userID", userID)
+	}
+	return ids, total, ok
+}
+
+// Set stores the view of a user, keeping the first Size IDs
+func (r *RecentChats) Set(ctx context.Context, userID string, ids []int64, total int64) {
+	ids = ids[:min(len(ids), r.config.Size)]
+	if err := r.cache.Set(ctx, recentChatsKey(ctx, userID), encodeRecentChats(ids, total), r.config.TTL); err != nil {
+		logger.Context(ctx).Warnw("Failed to cache recent chats", "userID", userID, "error", err)
+	}
+}
+
+// Invalidate forgets the view of a user
+func (r *RecentChats) Invalidate(ctx context.Context, userID string) {
+	if err := r.cache.Delete(ctx, recentChatsKey(ctx, userID)); err != nil {
+		logger.Context(ctx).Warnw("Failed to invalidate recent chats", "userID", userID, "error", err)
+	}
+}
+
+// Events returns the event bus subscriber invalidating the views of the users whose chats
+// are created, changed, transferred or deleted
+func (r *RecentChats) Events() EventPublisher {
+	return EventHandlers{
+		Chat: func(ctx context.Context, message *dtos.KafkaMessage[dtos.ChatPayload]) error {
+			r.Invalidate(ctx, message.Payload.UserID)
+			if message.Payload.PreviousUserID != "" {
+				r.Invalidate(ctx, message.Payload.PreviousUserID)
+			}
+			return nil
+		},
+	}
+}
+
+// recentChatsKey returns the cache key of the view of a user, scoped to the data residency
+// region of ctx since each region lists its own chats
+func recentChatsKey(ctx context.Context, userID string) string {
+	return recentChatsKeyPrefix + adapters.RegionOf(ctx) + ":" + userID
+}
+
+// encodeRecentChats encodes a view as the total, then the comma-separated IDs: "12:9,4,7"
+func encodeRecentChats(ids []int64, total int64) string {
+	var b strings.Builder
+	b.WriteString(strconv.FormatInt(total, 10))
+	b.WriteByte(':')
+	for i, id := range ids {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatInt(id, 10))
+	}
+	return b.String()
+}
+
+// decodeRecentChats decodes a view encoded by encodeRecentChats
+func decodeRecentChats(value string) ([]int64, int64, bool) {
+	totalText, idsText, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, 0, false
+	}
+	total, err := strconv.ParseInt(totalText, 10, 64)
+	if err != nil {
+		return nil, 0, false
+	}
+	if idsText == "" {
+		return []int64{}, total, true
+	}
+
+	fields := strings.Split(idsText, ",")
+	ids := make([]int64, 0, len(fields))
+	for _, field := range fields {
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, 0, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, total, true
+}
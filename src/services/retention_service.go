@@ -0,0 +1,19 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// RetentionService defines the interface for message retention
+type RetentionService interface {
+	// GetPolicy returns the retention applied to a user's messages
+	GetPolicy(ctx context.Context, userID string) (*dtos.RetentionPolicyResponse, error)
+
+	// SetPolicy sets the retention of a user's messages
+	SetPolicy(ctx context.Context, userID string, req *dtos.RetentionPolicyRequest) (*dtos.RetentionPolicyResponse, error)
+
+	// Purge deletes the messages past their retention, or only reports them on a dry run
+	Purge(ctx context.Context, dryRun bool) (*dtos.RetentionReport, error)
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// retentionService implements the RetentionService interface
+type retentionService struct {
+	config        configs.Retention
+	retentionRepo repositories.RetentionRepository
+	messageRepo   repositories.MessageRepository
+	workspaceRepo repositories.WorkspaceRepository
+	events        EventPublisher
+}
+
+// NewRetentionService creates a new retention service
+func NewRetentionService(
+	config configs.Retention,
+	retentionRepo repositories.RetentionRepository,
+	messageRepo repositories.MessageRepository,
+	workspaceRepo repositories.WorkspaceRepository,
+	events EventPublisher,
+) RetentionService {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+
+	return &retentionService{
+		config:        config,
+		retentionRepo: retentionRepo,
+		messageRepo:   messageRepo,
+		workspaceRepo: workspaceRepo,
+		events:        events,
+	}
+}
+
+// GetPolicy returns the retention applied to a user's messages
+func (s *retentionService) GetPolicy(ctx context.Context, userID string) (*dtos.RetentionPolicyResponse, error) {
+	policy, err := s.retentionRepo.Get(ctx, userID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+			days, err := s.defaultDays(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return &dtos.RetentionPolicyResponse{Days: days, Default: true}, nil
+		}
+		return nil, err
+	}
+
+	return &dtos.RetentionPolicyResponse{Days: policy.Days}, nil
+}
+
+// SetPolicy sets the retention of a user's messages
+func (s *retentionService) SetPolicy(ctx context.Context, userID string, req *dtos.RetentionPolicyRequest) (*dtos.RetentionPolicyResponse, error) {
+	policy := &models.RetentionPolicy{
+		UserID: userID,
+		Days:   *req.Days,
+	}
+	if err := s.retentionRepo.Upsert(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Retention policy updated", "userID", userID, "days", policy.Days)
+	return &dtos.RetentionPolicyResponse{Days: policy.Days}, nil
+}
+
+// Purge deletes the messages past their retention, or only reports them on a dry run.
+// User policies are applied first, then the default to the users without a policy.
+// The default of the workspace settings overrides the configured one.
+func (s *retentionService) Purge(ctx context.Context, dryRun bool) (*dtos.RetentionReport, error) {
+	log := logger.Context(ctx)
+
+	policies, err := s.retentionRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defaultDays, err := s.defaultDays(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dtos.RetentionReport{DryRun: dryRun, Policies: []dtos.RetentionPolicyReport{}}
+	now := time.Now()
+
+	policyUsers := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		policyUsers = append(policyUsers, policy.UserID)
+		if policy.Days <= 0 {
+			continue
+		}
+
+		count, err := s.purge(ctx, []string{policy.UserID}, nil, retentionCutoff(now, policy.Days), dryRun)
+		if err != nil {
+			return report, err
+		}
+		if count > 0 {
+			report.Policies = append(report.Policies, dtos.RetentionPolicyReport{UserID: policy.UserID, Days: policy.Days, Messages: count})
+			report.Messages += count
+		}
+	}
+
+	if defaultDays > 0 {
+		count, err := s.purge(ctx, nil, policyUsers, retentionCutoff(now, defaultDays), dryRun)
+		if err != nil {
+			return report, err
+		}
+		if count > 0 {
+			report.Policies = append(report.Policies, dtos.RetentionPolicyReport{Days: defaultDays, Messages: count})
+			report.Messages += count
+		}
+	}
+
+	log.Infow("Retention purge completed", "dryRun", dryRun, "messages", report.Messages, "policies", report.Policies)
+	return report, nil
+}
+
+// defaultDays returns the retention of the users without a policy, in days; 0 keeps their
+// messages forever
+func (s *retentionService) defaultDays(ctx context.Context) (int, error) {
+	settings, err := workspaceDefaults(ctx, s.workspaceRepo)
+	if err != nil {
+		return 0, err
+	}
+	if settings.RetentionDays != nil {
+		return *settings.RetentionDays, nil
+	}
+	return s.config.DefaultDays, nil
+}
+
+// purge deletes the expired messages of the selected users in batches, publishing a
+// message.deleted event for each, and returns the number of messages deleted.
+// On a dry run the messages are only counted.
+func (s *retentionService) purge(ctx context.Context, userIDs, excludeUserIDs []string, before time.Time, dryRun bool) (int64, error) {
+	log := logger.Context(ctx)
+
+	if dryRun {
+		return s.messageRepo.CountExpired(ctx, userIDs, excludeUserIDs, before)
+	}
+
+	var deleted int64
+	for {
+		messages, err := s.messageRepo.ListExpired(ctx, userIDs, excludeUserIDs, before, s.config.BatchSize)
+		if err != nil {
+			return deleted, err
+		}
+		if len(messages) == 0 {
+			return deleted, nil
+		}
+
+		ids := make([]int64, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+		}
+		if err := s.messageRepo.DeleteBatch(ctx, ids); err != nil {
+			return deleted, err
+		}
+		deleted += int64(len(messages))
+
+		// The content of deleted messages is not republished
+		for _, message := range messages {
+			event := newEvent(ctx, models.EventMessageDeleted, dtos.MessagePayload{
+				MessageID: message.ID,
+				SeqNo:     message.SeqNo,
+				ChatID:    message.ChatID,
+				UserID:    message.UserID,
+				Role:      message.Role,
+			})
+			if err := s.events.PublishMessageEvent(ctx, event); err != nil {
+				log.Errorw("Failed to publish message deleted event", "error", err, "messageID", message.ID)
+			}
+		}
+
+		if len(messages) < s.config.BatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// retentionCutoff returns the creation time before which messages kept for days are expired
+func retentionCutoff(now time.Time, days int) time.Time {
+	return now.AddDate(0, 0, -days)
+}
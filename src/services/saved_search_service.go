@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// SavedSearchService defines the interface for the saved chat searches of users, smart
+// folders whose chats are searched again each time they are opened
+type SavedSearchService interface {
+	// CreateSavedSearch saves a chat search of the user
+	CreateSavedSearch(ctx context.Context, userID string, req *dtos.SavedSearchRequest) (*dtos.SavedSearchResponse, error)
+
+	// ListSavedSearches lists the saved searches of the user
+	ListSavedSearches(ctx context.Context, userID string) (*dtos.ListSavedSearchesResponse, error)
+
+	// GetSavedSearch retrieves a saved search of the user
+	GetSavedSearch(ctx context.Context, userID string, id int64) (*dtos.SavedSearchResponse, error)
+
+	// UpdateSavedSearch replaces the name and filters of a saved search of the user
+	UpdateSavedSearch(ctx context.Context, userID string, id int64, req *dtos.SavedSearchRequest) (*dtos.SavedSearchResponse, error)
+
+	// DeleteSavedSearch deletes a saved search of the user
+	DeleteSavedSearch(ctx context.Context, userID string, id int64) error
+
+	// RunSavedSearch searches the chats of a saved search of the user, its period evaluated
+	// at the time of the call
+	RunSavedSearch(ctx context.Context, userID string, id int64, req *dtos.RunSavedSearchRequest) (*dtos.ListChatsResponse, error)
+}
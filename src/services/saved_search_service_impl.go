@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// savedSearchService implements the SavedSearchService interface
+type savedSearchService struct {
+	savedSearchRepo repositories.SavedSearchRepository
+	chatService     ChatService
+}
+
+// NewSavedSearchService creates a new saved search service
+func NewSavedSearchService(savedSearchRepo repositories.SavedSearchRepository, chatService ChatService) SavedSearchService {
+	return &savedSearchService{
+		savedSearchRepo: savedSearchRepo,
+		chatService:     chatService,
+	}
+}
+
+// CreateSavedSearch saves a chat search of the user
+func (s *savedSearchService) CreateSavedSearch(ctx context.Context, userID string, req *dtos.SavedSearchRequest) (*dtos.SavedSearchResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Creating saved search", "userID", userID)
+
+	if models.IsGuest(userID) {
+		return nil, errors.New(errors.ErrForbidden, "Saved searches are not available to guests")
+	}
+	if err := checkSearchFilters(&req.Filters); err != nil {
+		return nil, err
+	}
+
+	search := &models.SavedSearch{UserID: userID}
+	applySavedSearch(search, req)
+	if err := s.savedSearchRepo.Create(ctx, search); err != nil {
+		return nil, err
+	}
+
+	return toSavedSearchResponse(search), nil
+}
+
+// ListSavedSearches lists the saved searches of the user
+func (s *savedSearchService) ListSavedSearches(ctx context.Context, userID string) (*dtos.ListSavedSearchesResponse, error) {
+	searches, err := s.savedSearchRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dtos.SavedSearchResponse, len(searches))
+	for i, search := range searches {
+		responses[i] = *toSavedSearchResponse(search)
+	}
+	return &dtos.ListSavedSearchesResponse{SavedSearches: responses}, nil
+}
+
+// GetSavedSearch retrieves a saved search of the user
+func (s *savedSearchService) GetSavedSearch(ctx context.Context, userID string, id int64) (*dtos.SavedSearchResponse, error) {
+	search, err := s.ownedSavedSearch(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toSavedSearchResponse(search), nil
+}
+
+// UpdateSavedSearch replaces the name and filters of a saved search of the user
+func (s *savedSearchService) UpdateSavedSearch(ctx context.Context, userID string, id int64, req *dtos.SavedSearchRequest) (*dtos.SavedSearchResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Updating saved search", "savedSearchID", id)
+
+	if err := checkSearchFilters(&req.Filters); err != nil {
+		return nil, err
+	}
+	search, err := s.ownedSavedSearch(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	applySavedSearch(search, req)
+	if err := s.savedSearchRepo.Update(ctx, search); err != nil {
+		return nil, err
+	}
+
+	return toSavedSearchResponse(search), nil
+}
+
+// DeleteSavedSearch deletes a saved search of the user
+func (s *savedSearchService) DeleteSavedSearch(ctx context.Context, userID string, id int64) error {
+	log := logger.Context(ctx)
+	log.Infow("Deleting saved search", "savedSearchID", id)
+
+	if _, err := s.ownedSavedSearch(ctx, userID, id); err != nil {
+		return err
+	}
+
+	return s.savedSearchRepo.Delete(ctx, id)
+}
+
+// RunSavedSearch searches the chats of a saved search of the user, its period evaluated
+// at the time of the call
+func (s *savedSearchService) RunSavedSearch(ctx context.Context, userID string, id int64, req *dtos.RunSavedSearchRequest) (*dtos.ListChatsResponse, error) {
+	search, err := s.ownedSavedSearch(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	searchReq := &dtos.SearchChatsRequest{
+		Query:          search.Query,
+		From:           search.From,
+		To:             search.To,
+		Tag:            search.Tag,
+		Archived:       search.Archived,
+		HasAttachments: search.HasAttachments,
+		Model:          search.Model,
+		Facets:         req.Facets,
+		PageRequest:    req.PageRequest,
+	}
+	if search.Period != "" {
+		from, to := searchPeriod(search.Period, time.Now())
+		searchReq.From, searchReq.To = &from, &to
+	}
+
+	return s.chatService.SearchChats(ctx, userID, searchReq)
+}
+
+// ownedSavedSearch retrieves a saved search, verifying that the user owns it
+func (s *savedSearchService) ownedSavedSearch(ctx context.Context, userID string, id int64) (*models.SavedSearch, error) {
+	search, err := s.savedSearchRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if search.UserID != userID {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this saved search")
+	}
+	return search, nil
+}
+
+// checkSearchFilters validates the date range of saved search filters
+func checkSearchFilters(filters *dtos.SavedSearchFilters) error {
+	if filters.Period != "" && (filters.From != nil || filters.To != nil) {
+		return errors.New(errors.ErrInvalidRequest, "period cannot be combined with from and to")
+	}
+	if filters.From != nil && filters.To != nil && !filters.To.After(*filters.From) {
+		return errors.New(errors.ErrInvalidRequest, "to must be after from")
+	}
+	return nil
+}
+
+// applySavedSearch sets the name and filters of a request on a saved search
+func applySavedSearch(search *models.SavedSearch, req *dtos.SavedSearchRequest) {
+	filters := req.Filters
+	search.Name = req.Name
+	search.Query = filters.Query
+	search.Period = filters.Period
+	search.From = filters.From
+	search.To = filters.To
+	search.Tag = normalizeTag(filters.Tag)
+	search.Archived = filters.Archived
+	search.HasAttachments = filters.HasAttachments
+	search.Model = filters.Model
+}
+
+// searchPeriod returns the bounds of a saved search period at a time, in UTC
+func searchPeriod(period string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month, _ := budgetPeriod(now)
+
+	switch period {
+	case dtos.SearchPeriodToday:
+		return today, today.AddDate(0, 0, 1)
+	case dtos.SearchPeriodLast7Days:
+		return now.AddDate(0, 0, -7), now
+	case dtos.SearchPeriodLast30Days:
+		return now.AddDate(0, 0, -30), now
+	case dtos.SearchPeriodLastMonth:
+		return month.AddDate(0, -1, 0), month
+	default: // this month
+		return month, month.AddDate(0, 1, 0)
+	}
+}
+
+// toSavedSearchResponse converts a saved search model to its response DTO
+func toSavedSearchResponse(search *models.SavedSearch) *dtos.SavedSearchResponse {
+	return &dtos.SavedSearchResponse{
+		ID:   search.ID,
+		Name: search.Name,
+		Filters: dtos.SavedSearchFilters{
+			Query:          search.Query,
+			Period:         search.Period,
+			From:           search.From,
+			To:             search.To,
+			Tag:            search.Tag,
+			Archived:       search.Archived,
+			HasAttachments: search.HasAttachments,
+			Model:          search.Model,
+		},
+		CreatedAt: search.CreatedAt,
+		UpdatedAt: search.UpdatedAt,
+	}
+}
@@ -0,0 +1,13 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// SearchService defines the interface for searching messages in the search index
+type SearchService interface {
+	// SearchMessages searches the messages of the chats of a user, optionally within a chat
+	SearchMessages(ctx context.Context, userID string, req *dtos.SearchMessagesRequest) (*dtos.ListMessagesResponse, error)
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// searchService implements the SearchService interface
+type searchService struct {
+	search      adapters.SearchAdapter
+	messageRepo repositories.MessageRepository
+}
+
+// NewSearchService creates a new search service
+func NewSearchService(search adapters.SearchAdapter, messageRepo repositories.MessageRepository) SearchService {
+	return &searchService{
+		search:      search,
+		messageRepo: messageRepo,
+	}
+}
+
+// SearchMessages searches the messages of the chats of a user, best matches first. The index
+// trails the database, so messages deleted since they were indexed are left out of the page.
+func (s *searchService) SearchMessages(ctx context.Context, userID string, req *dtos.SearchMessagesRequest) (*dtos.ListMessagesResponse, error) {
+	log := logger.Context(ctx)
+	log.Debugw("Searching messages", "userID", userID, "query", req.Query, "chatID", req.ChatID, "limit", req.Limit, "offset", req.Offset)
+
+	ids, total, err := s.search.SearchMessages(ctx, userID, req.Query, req.ChatID, req.Limit, req.Offset)
+	if err != nil {
+		log.Errorw("Failed to search messages", "error", err, "userID", userID)
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to search messages")
+	}
+
+	messages, err := s.messageRepo.ListByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the ranking of the index
+	byID := make(map[int64]*models.Message, len(messages))
+	for _, message := range messages {
+		byID[message.ID] = message
+	}
+	responses := make([]dtos.MessageResponse, 0, len(messages))
+	for _, id := range ids {
+		if message, ok := byID[id]; ok {
+			responses = append(responses, *toMessageResponse(message))
+		}
+	}
+
+	page := countedPage(total, req.Offset, len(ids))
+	return &dtos.ListMessagesResponse{
+		Messages: responses,
+		Total:    page.total,
+		HasMore:  page.hasMore,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+)
+
+// StreamBuffer keeps the chunks of the replies being streamed, and for a while after they
+// end, so clients whose connection dropped can resume them. Streams are kept in the memory
+// of the instance generating them.
+type StreamBuffer struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	streams map[int64]*replyStream
+}
+
+// replyStream is the buffered stream of the reply of a message
+type replyStream struct {
+	token  string
+	userID string
+	chunks []string
+	done   bool
+	// err is why the reply failed, if it did
+	err error
+	// expiresAt is when the stream is dropped, set once it is done
+	expiresAt time.Time
+	// changed is closed when a chunk is added or the stream ends, then replaced
+	changed chan struct{}
+}
+
+// NewStreamBuffer creates a stream buffer keeping ended streams for the configured TTL
+func NewStreamBuffer(config configs.Streams) *StreamBuffer {
+	return &StreamBuffer{
+		ttl:     config.BufferTTL,
+		streams: make(map[int64]*replyStream),
+	}
+}
+
+// open starts buffering the stream of the reply of a message to a user and returns the
+// token resuming it
+func (b *StreamBuffer) open(messageID int64, userID string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Ended streams are dropped as new ones open
+	now := time.Now()
+	for id, stream := range b.streams {
+		if stream.done && now.After(stream.expiresAt) {
+			delete(b.streams, id)
+		}
+	}
+
+	token := uuid.NewString()
+	b.streams[messageID] = &replyStream{token: token, userID: userID, changed: make(chan struct{})}
+	return token
+}
+
+// append adds a chunk to the stream of a message and returns its number, counted from 0
+func (b *StreamBuffer) append(messageID int64, content string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream := b.streams[messageID]
+	stream.chunks = append(stream.chunks, content)
+	stream.notify()
+	return len(stream.chunks) - 1
+}
+
+// close ends the stream of a message, failed with err unless it is nil, which stays buffered
+// for the TTL
+func (b *StreamBuffer) close(messageID int64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream := b.streams[messageID]
+	stream.done, stream.err = true, err
+	stream.expiresAt = time.Now().Add(b.ttl)
+	stream.notify()
+}
+
+// follow passes the chunks of the stream of a message from number from onwards to onChunk,
+// waiting for those not generated yet, until the stream ends or ctx is done. It fails with
+// the error of the reply when the reply failed.
+func (b *StreamBuffer) follow(ctx context.Context, messageID int64, userID, token string, from int, onChunk func(chunk *dtos.LLMChunk) error) error {
+	for {
+		b.mu.Lock()
+		stream, ok := b.streams[messageID]
+		if !ok || stream.userID != userID || stream.token != token || (stream.done && time.Now().After(stream.expiresAt)) {
+			b.mu.Unlock()
+			return errors.New(errors.ErrNotFound, "Stream not found or expired")
+		}
+		// Chunks are only appended, so the slice can be read once unlocked
+		chunks := stream.chunks[min(from, len(stream.chunks)):]
+		done, failure, changed := stream.done, stream.err, stream.changed
+		b.mu.Unlock()
+
+		for _, content := range chunks {
+			if err := onChunk(&dtos.LLMChunk{Index: from, Content: content}); err != nil {
+				return err
+			}
+			from++
+		}
+
+		if done {
+			return failure
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// wait waits until the stream of a message ends or ctx is done. It returns false right away
+// when the stream is not buffered by this instance.
+func (b *StreamBuffer) wait(ctx context.Context, messageID int64) bool {
+	for {
+		b.mu.Lock()
+		stream, ok := b.streams[messageID]
+		if !ok {
+			b.mu.Unlock()
+			return false
+		}
+		done, changed := stream.done, stream.changed
+		b.mu.Unlock()
+
+		if done {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return true
+		}
+	}
+}
+
+// notify wakes the followers of the stream up
+func (s *replyStream) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
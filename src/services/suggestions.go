@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// suggestionInstructions is the system prompt used to suggest follow-up questions, with the
+// number of questions
+const suggestionInstructions = "You suggest follow-up questions a user may ask next in a conversation with an assistant. " +
+	"Given the last question and the reply to it, suggest up to %d short follow-up questions, in the language of the reply. " +
+	"Reply with one question per line and nothing else."
+
+// suggestionMarker matches the bullet or number of a listed question, such as - or 1.
+var suggestionMarker = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+
+// maxSuggestionLength is the most characters of a suggested follow-up question
+const maxSuggestionLength = 200
+
+// Suggester suggests follow-up questions after assistant replies, for clients to offer as quick
+// replies. Failures are logged and leave replies without suggestions.
+type Suggester interface {
+	// Suggest returns the follow-up questions suggested after a reply to a question
+	Suggest(ctx context.Context, question, reply string) []string
+}
+
+// suggester implements the Suggester interface
+type suggester struct {
+	config     configs.Suggestions
+	llmAdapter adapters.LLMAdapter
+}
+
+// NewSuggester creates a new suggester
+func NewSuggester(config configs.Suggestions, llmAdapter adapters.LLMAdapter) Suggester {
+	if config.Count <= 0 {
+		config.Count = 3
+	}
+
+	return &suggester{
+		config:     config,
+		llmAdapter: llmAdapter,
+	}
+}
+
+// Suggest asks the suggestion model for follow-up questions after a reply to a question
+func (s *suggester) Suggest(ctx context.Context, question, reply string) []string {
+	log := logger.Context(ctx)
+
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	response, err := s.llmAdapter.GenerateResponse(ctx, &dtos.LLMRequest{
+		Model: s.config.Model,
+		Messages: []dtos.LLMMessage{
+			{Role: "system", Content: fmt.Sprintf(suggestionInstructions, s.config.Count)},
+			{Role: models.MessageRoleUser, Content: fmt.Sprintf("Question:\n%s\n\nReply:\n%s", question, reply)},
+		},
+	})
+	if err != nil {
+		log.Warnw("Failed to suggest follow-up questions", "error", err)
+		return nil
+	}
+
+	return parseSuggestions(response.Message.Content, s.config.Count)
+}
+
+// parseSuggestions returns up to count questions of a reply listing one per line, without the
+// bullets or numbers models put before them
+func parseSuggestions(content string, count int) []string {
+	var suggestions []string
+	for _, line := range strings.Split(content, "\n") {
+		line = suggestionMarker.ReplaceAllString(strings.TrimSpace(line), "")
+		line = strings.TrimSpace(strings.Trim(line, `"`))
+		// Lines ending with a colon introduce the list
+		if line == "" || strings.HasSuffix(line, ":") || len([]rune(line)) > maxSuggestionLength {
+			continue
+		}
+		suggestions = append(suggestions, line)
+		if len(suggestions) == count {
+			break
+		}
+	}
+	return suggestions
+}
+
+// lastUserContent returns the content of the last user message of a request, the question the
+// reply answers
+func lastUserContent(request *dtos.LLMRequest) string {
+	for i := len(request.Messages) - 1; i >= 0; i-- {
+		if request.Messages[i].Role == models.MessageRoleUser {
+			return request.Messages[i].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,12 @@
+package services
+
+import (
+	"context"
+)
+
+// SummaryService defines the interface for maintaining rolling chat summaries
+type SummaryService interface {
+	// SummarizePending folds the older messages of long chats into their summaries
+	// and returns the number of chats summarized
+	SummarizePending(ctx context.Context) (int, error)
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// summaryInstructions is the system prompt used to update a chat summary
+const summaryInstructions = "You maintain a concise summary of a conversation between a user and an assistant. " +
+	"Update the existing summary with the new messages, keeping facts, decisions and open questions. " +
+	"Reply with the updated summary only."
+
+// summaryService implements the SummaryService interface
+type summaryService struct {
+	config      configs.Prompt
+	chatRepo    repositories.ChatRepository
+	messageRepo repositories.MessageRepository
+	llmAdapter  adapters.LLMAdapter
+}
+
+// NewSummaryService creates a new summary service
+func NewSummaryService(
+	config configs.Prompt,
+	chatRepo repositories.ChatRepository,
+	messageRepo repositories.MessageRepository,
+	llmAdapter adapters.LLMAdapter,
+) SummaryService {
+	return &summaryService{
+		config:      config,
+		chatRepo:    chatRepo,
+		messageRepo: messageRepo,
+		llmAdapter:  llmAdapter,
+	}
+}
+
+// SummarizePending folds the older messages of long chats into their summaries.
+// The latest HistoryLimit messages of a chat are left out so they are still sent verbatim.
+func (s *summaryService) SummarizePending(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	chats, err := s.chatRepo.ListPendingSummary(ctx, s.config.HistoryLimit+s.config.SummarizeAfter, s.config.SummaryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	summarized := 0
+	for _, chat := range chats {
+		if err := s.summarize(ctx, chat); err != nil {
+			log.Errorw("Failed to summarize chat", "error", err, "chatID", chat.ID)
+			continue
+		}
+		summarized++
+	}
+
+	return summarized, nil
+}
+
+// summarize folds the messages between the chat summary and its latest messages into the summary
+func (s *summaryService) summarize(ctx context.Context, chat *models.Chat) error {
+	log := logger.Context(ctx)
+
+	recent, err := s.messageRepo.GetRecent(ctx, chat.ID, chat.SummaryMessageID, s.config.HistoryLimit)
+	if err != nil {
+		return err
+	}
+	if len(recent) == 0 {
+		return nil
+	}
+
+	messages, err := s.messageRepo.GetRange(ctx, chat.ID, chat.SummaryMessageID, recent[0].ID-1)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var transcript strings.Builder
+	if chat.Summary != "" {
+		fmt.Fprintf(&transcript, "Existing summary:\n%s\n\nNew messages:\n", chat.Summary)
+	}
+	for _, message := range toLLMMessages(messages) {
+		fmt.Fprintf(&transcript, "%s: %s\n", message.Role, message.Content)
+	}
+
+	response, err := s.llmAdapter.GenerateResponse(ctx, &dtos.LLMRequest{
+		Messages: []dtos.LLMMessage{
+			{Role: "system", Content: summaryInstructions},
+			{Role: models.MessageRoleUser, Content: transcript.String()},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	lastID := messages[len(messages)-1].ID
+	if err := s.chatRepo.UpdateSummary(ctx, chat.ID, response.Message.Content, lastID); err != nil {
+		return err
+	}
+
+	log.Infow("Chat summarized", "chatID", chat.ID, "messages", len(messages), "summaryMessageID", lastID)
+	return nil
+}
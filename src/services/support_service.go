@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// SupportService defines the interface for the support bundles of captured requests
+type SupportService interface {
+	// Record saves an LLM call captured for support until it expires. Failures are logged,
+	// as captures never fail the requests they are made for.
+	Record(ctx context.Context, capture *adapters.LLMCapture)
+
+	// GetBundle retrieves the support bundle of a request: its LLM calls captured and not expired
+	GetBundle(ctx context.Context, requestID string) (*dtos.SupportBundleResponse, error)
+
+	// PurgeExpired deletes the expired captures, returning how many were deleted
+	PurgeExpired(ctx context.Context) (int64, error)
+}
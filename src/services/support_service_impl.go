@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// supportService implements the SupportService interface
+type supportService struct {
+	config      configs.Capture
+	captureRepo repositories.SupportCaptureRepository
+}
+
+// NewSupportService creates a new support service
+func NewSupportService(config configs.Capture, captureRepo repositories.SupportCaptureRepository) SupportService {
+	return &supportService{
+		config:      config,
+		captureRepo: captureRepo,
+	}
+}
+
+// Record saves an LLM call captured for support, keyed by the ID of its request
+func (s *supportService) Record(ctx context.Context, capture *adapters.LLMCapture) {
+	log := logger.Context(ctx)
+
+	request, err := json.Marshal(capture.Request)
+	if err != nil {
+		log.Errorw("Failed to encode captured LLM request", "error", err)
+		return
+	}
+	var response []byte
+	model := capture.Request.Model
+	if capture.Response != nil {
+		if response, err = json.Marshal(capture.Response); err != nil {
+			log.Errorw("Failed to encode captured LLM response", "error", err)
+			return
+		}
+		model = capture.Response.Model
+	}
+
+	entry := &models.SupportCapture{
+		RequestID:    logger.GetRequestID(ctx),
+		UserID:       capture.UserID,
+		Route:        capture.Route,
+		Model:        model,
+		Request:      string(request),
+		Response:     string(response),
+		Error:        capture.Error,
+		Streaming:    capture.Streaming,
+		Chunks:       capture.Chunks,
+		FirstChunkMs: capture.FirstChunk.Milliseconds(),
+		DurationMs:   capture.Duration.Milliseconds(),
+		StartedAt:    capture.StartedAt,
+		ExpiresAt:    capture.StartedAt.Add(s.config.TTL),
+	}
+	if err := s.captureRepo.Create(ctx, entry); err != nil {
+		return
+	}
+	log.Debugw("Captured LLM call", "captureID", entry.ID, "model", model, "durationMs", entry.DurationMs)
+}
+
+// GetBundle retrieves the support bundle of a request
+func (s *supportService) GetBundle(ctx context.Context, requestID string) (*dtos.SupportBundleResponse, error) {
+	captures, err := s.captureRepo.ListByRequestID(ctx, requestID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(captures) == 0 {
+		return nil, errors.New(errors.ErrNotFound, "No captures found for the request")
+	}
+
+	bundle := &dtos.SupportBundleResponse{
+		RequestID: requestID,
+		Captures:  make([]dtos.SupportCaptureResponse, len(captures)),
+	}
+	for i, capture := range captures {
+		response := json.RawMessage("null")
+		if capture.Response != "" {
+			response = json.RawMessage(capture.Response)
+		}
+		bundle.Captures[i] = dtos.SupportCaptureResponse{
+			ID:           capture.ID,
+			Model:        capture.Model,
+			Route:        capture.Route,
+			UserID:       capture.UserID,
+			Request:      json.RawMessage(capture.Request),
+			Response:     response,
+			Error:        capture.Error,
+			Streaming:    capture.Streaming,
+			Chunks:       capture.Chunks,
+			FirstChunkMs: capture.FirstChunkMs,
+			DurationMs:   capture.DurationMs,
+			StartedAt:    capture.StartedAt,
+			ExpiresAt:    capture.ExpiresAt,
+		}
+	}
+
+	return bundle, nil
+}
+
+// PurgeExpired deletes the expired captures
+func (s *supportService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.captureRepo.DeleteExpired(ctx, time.Now())
+}
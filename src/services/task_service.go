@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// TaskService defines the interface for managing the action items of chats as tasks
+type TaskService interface {
+	// ExtractTasks extracts the action items of the messages of a chat owned by the user since
+	// the previous extraction into tasks, and exports them to a tracker when one is named
+	ExtractTasks(ctx context.Context, userID string, chatID int64, req *dtos.ExtractTasksRequest) (*dtos.ListTasksResponse, error)
+
+	// ListTasks lists the tasks of a chat owned by the user, oldest first
+	ListTasks(ctx context.Context, userID string, chatID int64, req *dtos.ListTasksRequest) (*dtos.ListTasksResponse, error)
+
+	// UpdateTask changes the status of a task of a chat owned by the user
+	UpdateTask(ctx context.Context, userID string, chatID, taskID int64, req *dtos.UpdateTaskRequest) (*dtos.TaskResponse, error)
+
+	// ExportTasks exports tasks of a chat owned by the user to a tracker
+	ExportTasks(ctx context.Context, userID string, chatID int64, req *dtos.ExportTasksRequest) (*dtos.ListTasksResponse, error)
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/warnings"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// taskExtractionInstructions is the system prompt used to extract the action items of a conversation
+const taskExtractionInstructions = "You extract the action items of a conversation between a user and an assistant: the tasks someone agreed or was asked to do. " +
+	"Leave out the tasks already listed, and the tasks the assistant completed in the conversation. " +
+	"Reply with a JSON object only, such as {\"tasks\": [{\"title\": \"...\", \"description\": \"...\", \"assignee\": \"...\", \"dueDate\": \"2006-01-02\"}]}, " +
+	"in the language of the conversation. The title is a short imperative sentence; description, assignee and dueDate are empty when the conversation does not tell. " +
+	"Reply with {\"tasks\": []} when there are none."
+
+// maxExtractedTasks bounds the tasks of an extraction
+const maxExtractedTasks = 20
+
+// maxTaskTitleLength is the most characters of a task title
+const maxTaskTitleLength = 255
+
+// taskExportEvent is the event of the deliveries of tasks to trackers
+const taskExportEvent = "tasks.exported"
+
+// extractedTask is an action item in the reply of the extraction model
+type extractedTask struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Assignee    string `json:"assignee"`
+	DueDate     string `json:"dueDate"`
+}
+
+// taskService implements the TaskService interface
+type taskService struct {
+	config      configs.Tasks
+	taskRepo    repositories.TaskRepository
+	chatRepo    repositories.ChatRepository
+	messageRepo repositories.MessageRepository
+	llmAdapter  adapters.LLMAdapter
+	webhook     adapters.WebhookAdapter
+	budgets     BudgetService
+	consent     ConsentService
+}
+
+// NewTaskService creates a new task service, exporting tasks through webhook
+func NewTaskService(
+	config configs.Tasks,
+	taskRepo repositories.TaskRepository,
+	chatRepo repositories.ChatRepository,
+	messageRepo repositories.MessageRepository,
+	llmAdapter adapters.LLMAdapter,
+	webhook adapters.WebhookAdapter,
+	budgets BudgetService,
+	consent ConsentService,
+) TaskService {
+	return &taskService{
+		config:      config,
+		taskRepo:    taskRepo,
+		chatRepo:    chatRepo,
+		messageRepo: messageRepo,
+		llmAdapter:  llmAdapter,
+		webhook:     webhook,
+		budgets:     budgets,
+		consent:     consent,
+	}
+}
+
+// ExtractTasks extracts the action items of the messages of a chat owned by the user since
+// the previous extraction, up to MaxMessages of the latest ones, into open tasks. The open
+// tasks are given to the model so it does not list them again. When a tracker is named, the
+// new tasks are exported to it; a failed export is returned as a warning, and the tasks are
+// kept.
+func (s *taskService) ExtractTasks(ctx context.Context, userID string, chatID int64, req *dtos.ExtractTasksRequest) (*dtos.ListTasksResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Extracting tasks", "chatID", chatID, "tracker", req.Tracker)
+
+	chat, err := s.getOwned(ctx, userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	// Tasks keep what is extracted from the conversation in the database
+	if chat.Ephemeral {
+		return nil, errors.New(errors.ErrInvalidRequest, "Tasks cannot be extracted from privacy mode chats")
+	}
+	if req.Tracker != "" {
+		if _, ok := s.config.Trackers[req.Tracker]; !ok {
+			return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Unknown tracker %q", req.Tracker))
+		}
+	}
+	if err := checkChatBudget(chat); err != nil {
+		return nil, err
+	}
+	if err := s.consent.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.budgets.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	after, err := s.taskRepo.LastSourceMessageID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.messageRepo.GetRecent(ctx, chatID, after, s.config.MaxMessages)
+	if err != nil {
+		return nil, err
+	}
+	history := toLLMMessages(messages)
+	if len(history) == 0 {
+		return &dtos.ListTasksResponse{Tasks: []dtos.TaskResponse{}}, nil
+	}
+	open, err := s.taskRepo.ListByChatID(ctx, chatID, models.TaskStatusOpen)
+	if err != nil {
+		return nil, err
+	}
+
+	var transcript strings.Builder
+	if len(open) > 0 {
+		transcript.WriteString("Tasks already listed:\n")
+		for _, task := range open {
+			fmt.Fprintf(&transcript, "- %s\n", task.Title)
+		}
+		transcript.WriteString("\nConversation:\n")
+	}
+	for _, message := range history {
+		fmt.Fprintf(&transcript, "%s: %s\n", message.Role, message.Content)
+	}
+
+	response, err := s.llmAdapter.GenerateResponse(ctx, &dtos.LLMRequest{
+		Model: s.config.Model,
+		Messages: []dtos.LLMMessage{
+			{Role: models.MessageRoleSystem, Content: taskExtractionInstructions},
+			{Role: models.MessageRoleUser, Content: transcript.String()},
+		},
+	})
+	if err != nil {
+		log.Errorw("Failed to extract tasks", "error", err, "chatID", chatID)
+		return nil, err
+	}
+
+	sourceMessageID := messages[len(messages)-1].ID
+	model := response.Model
+	if model == "" {
+		model = s.config.Model
+	}
+	if model == "" {
+		model = configs.AppConfig.LLM.Model
+	}
+	// The extraction is already paid for; a failure here only leaves it out of the budgets
+	if err := s.budgets.RecordUsage(ctx, chat.UserID, chat.ID, sourceMessageID, model, response.Usage); err != nil {
+		log.Errorw("Failed to record usage", "error", err, "chatID", chatID)
+	}
+	cost := s.budgets.EstimateCost(model, response.Usage)
+	if err := s.chatRepo.AddUsage(ctx, chat.ID, response.Usage.TotalTokens, cost); err != nil {
+		log.Errorw("Failed to add chat usage", "error", err, "chatID", chatID)
+	}
+
+	extracted, err := parseTasks(response.Message.Content)
+	if err != nil {
+		log.Warnw("Failed to parse extracted tasks", "error", err, "chatID", chatID)
+		return nil, errors.New(errors.ErrLLMService, "The model did not reply with action items")
+	}
+
+	tasks := make([]*models.Task, len(extracted))
+	for i, item := range extracted {
+		tasks[i] = &models.Task{
+			ChatID:          chat.ID,
+			Title:           item.Title,
+			Description:     strings.TrimSpace(item.Description),
+			Assignee:        strings.TrimSpace(item.Assignee),
+			Status:          models.TaskStatusOpen,
+			SourceMessageID: sourceMessageID,
+		}
+		if dueDate, err := time.Parse(time.DateOnly, strings.TrimSpace(item.DueDate)); err == nil {
+			tasks[i].DueDate = &dueDate
+		}
+	}
+	if len(tasks) > 0 {
+		if err := s.taskRepo.CreateBatch(ctx, tasks); err != nil {
+			return nil, err
+		}
+	}
+	log.Infow("Tasks extracted", "chatID", chatID, "messages", len(history), "tasks", len(tasks))
+
+	exported := false
+	if req.Tracker != "" && len(tasks) > 0 {
+		if err := s.export(ctx, chat, req.Tracker, tasks); err != nil {
+			log.Errorw("Failed to export tasks", "error", err, "chatID", chatID, "tracker", req.Tracker)
+			warnings.Add(ctx, warnings.TaskExportFailed, fmt.Sprintf("The tasks could not be exported to %s", req.Tracker))
+		} else {
+			exported = true
+		}
+	}
+
+	return &dtos.ListTasksResponse{Tasks: toTaskResponses(tasks), Exported: exported}, nil
+}
+
+// ListTasks lists the tasks of a chat owned by the user, oldest first
+func (s *taskService) ListTasks(ctx context.Context, userID string, chatID int64, req *dtos.ListTasksRequest) (*dtos.ListTasksResponse, error) {
+	if _, err := s.getOwned(ctx, userID, chatID); err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.taskRepo.ListByChatID(ctx, chatID, req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.ListTasksResponse{Tasks: toTaskResponses(tasks)}, nil
+}
+
+// UpdateTask changes the status of a task of a chat owned by the user
+func (s *taskService) UpdateTask(ctx context.Context, userID string, chatID, taskID int64, req *dtos.UpdateTaskRequest) (*dtos.TaskResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Updating task", "chatID", chatID, "taskID", taskID, "status", req.Status)
+
+	if _, err := s.getOwned(ctx, userID, chatID); err != nil {
+		return nil, err
+	}
+
+	task, err := s.taskRepo.Get(ctx, chatID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	task.Status = req.Status
+	if err := s.taskRepo.UpdateStatus(ctx, task); err != nil {
+		return nil, err
+	}
+
+	return toTaskResponse(task), nil
+}
+
+// ExportTasks exports tasks of a chat owned by the user to a tracker: those selected by ID,
+// or the open tasks not exported yet
+func (s *taskService) ExportTasks(ctx context.Context, userID string, chatID int64, req *dtos.ExportTasksRequest) (*dtos.ListTasksResponse, error) {
+	log := logger.Context(ctx)
+	log.Infow("Exporting tasks", "chatID", chatID, "tracker", req.Tracker, "ids", len(req.IDs))
+
+	chat, err := s.getOwned(ctx, userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.config.Trackers[req.Tracker]; !ok {
+		return nil, errors.New(errors.ErrInvalidRequest, fmt.Sprintf("Unknown tracker %q", req.Tracker))
+	}
+
+	var tasks []*models.Task
+	if len(req.IDs) > 0 {
+		tasks, err = s.taskRepo.ListByIDs(ctx, chatID, req.IDs)
+	} else {
+		tasks, err = s.taskRepo.ListUnexported(ctx, chatID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return &dtos.ListTasksResponse{Tasks: []dtos.TaskResponse{}}, nil
+	}
+
+	if err := s.export(ctx, chat, req.Tracker, tasks); err != nil {
+		log.Errorw("Failed to export tasks", "error", err, "chatID", chatID, "tracker", req.Tracker)
+		return nil, errors.Wrap(err, errors.ErrInternal, fmt.Sprintf("Failed to export the tasks to %s", req.Tracker))
+	}
+
+	return &dtos.ListTasksResponse{Tasks: toTaskResponses(tasks), Exported: true}, nil
+}
+
+// export posts tasks of a chat to the webhook of a configured tracker, and records the export
+func (s *taskService) export(ctx context.Context, chat *models.Chat, tracker string, tasks []*models.Task) error {
+	config := s.config.Trackers[tracker]
+
+	body, err := json.Marshal(dtos.TaskExport{
+		Event:     taskExportEvent,
+		ChatID:    chat.ID,
+		ChatTitle: chat.Title,
+		UserID:    chat.UserID,
+		Tasks:     toTaskResponses(tasks),
+	})
+	if err != nil {
+		return err
+	}
+
+	postCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	if err := s.webhook.Post(postCtx, config.WebhookURL, config.Secret, body); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	ids := make([]int64, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+		task.Tracker, task.ExportedAt, task.UpdatedAt = tracker, &now, now
+	}
+	return s.taskRepo.MarkExported(ctx, ids, tracker, now)
+}
+
+// getOwned returns a chat, or a forbidden error when the user does not own it
+func (s *taskService) getOwned(ctx context.Context, userID string, chatID int64) (*models.Chat, error) {
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if chat.UserID != userID {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+	return chat, nil
+}
+
+// parseTasks returns the action items of the reply of the extraction model, up to
+// maxExtractedTasks, leaving out those without a title
+func parseTasks(content string) ([]extractedTask, error) {
+	content = unfenceJSON(content)
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("the reply holds no JSON object")
+	}
+	var reply struct {
+		Tasks []extractedTask `json:"tasks"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &reply); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]extractedTask, 0, len(reply.Tasks))
+	for _, task := range reply.Tasks {
+		task.Title = strings.TrimSpace(task.Title)
+		if task.Title == "" {
+			continue
+		}
+		if title := []rune(task.Title); len(title) > maxTaskTitleLength {
+			task.Title = string(title[:maxTaskTitleLength])
+		}
+		tasks = append(tasks, task)
+		if len(tasks) == maxExtractedTasks {
+			break
+		}
+	}
+	return tasks, nil
+}
+
+// toTaskResponses converts task models to their response DTOs
+func toTaskResponses(tasks []*models.Task) []dtos.TaskResponse {
+	responses := make([]dtos.TaskResponse, len(tasks))
+	for i, task := range tasks {
+		responses[i] = *toTaskResponse(task)
+	}
+	return responses
+}
+
+// toTaskResponse converts a task model to its response DTO
+func toTaskResponse(task *models.Task) *dtos.TaskResponse {
+	response := &dtos.TaskResponse{
+		ID:              task.ID,
+		ChatID:          task.ChatID,
+		Title:           task.Title,
+		Description:     task.Description,
+		Assignee:        task.Assignee,
+		Status:          task.Status,
+		SourceMessageID: task.SourceMessageID,
+		Tracker:         task.Tracker,
+		ExportedAt:      task.ExportedAt,
+		CreatedAt:       task.CreatedAt,
+		UpdatedAt:       task.UpdatedAt,
+	}
+	if task.DueDate != nil {
+		response.DueDate = task.DueDate.Format(time.DateOnly)
+	}
+	return response
+}
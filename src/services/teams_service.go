@@ -0,0 +1,14 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// TeamsService defines the interface for answering users in Microsoft Teams
+type TeamsService interface {
+	// HandleActivity handles an activity received from Teams. Messages are answered in the
+	// background, in the chat their conversation maps to.
+	HandleActivity(ctx context.Context, activity *dtos.TeamsActivity) error
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// teamsMention matches the mentions of the bot Teams includes in the text of messages
+// posted in channels and group chats
+var teamsMention = regexp.MustCompile(`<at>[^<]*</at>`)
+
+// teamsTitleLength is the most characters of the first message used as the title of a chat
+const teamsTitleLength = 50
+
+// teamsService implements the TeamsService interface
+type teamsService struct {
+	config         configs.Teams
+	teamsRepo      repositories.TeamsRepository
+	chatService    ChatService
+	messageService MessageService
+	teamsAdapter   adapters.TeamsAdapter
+}
+
+// NewTeamsService creates a new Teams service
+func NewTeamsService(
+	config configs.Teams,
+	teamsRepo repositories.TeamsRepository,
+	chatService ChatService,
+	messageService MessageService,
+	teamsAdapter adapters.TeamsAdapter,
+) TeamsService {
+	return &teamsService{
+		config:         config,
+		teamsRepo:      teamsRepo,
+		chatService:    chatService,
+		messageService: messageService,
+		teamsAdapter:   teamsAdapter,
+	}
+}
+
+// HandleActivity answers the messages of Teams users. Other activities, such as members
+// joining a conversation, are ignored.
+func (s *teamsService) HandleActivity(ctx context.Context, activity *dtos.TeamsActivity) error {
+	log := logger.Context(ctx)
+
+	if activity.Type != dtos.TeamsActivityMessage {
+		return nil
+	}
+	if activity.Conversation == nil || activity.Conversation.ID == "" || activity.From == nil {
+		return errors.New(errors.ErrInvalidRequest, "The activity has no conversation or sender")
+	}
+	// Bots and connectors have no Azure AD object ID
+	if activity.From.AADObjectID == "" {
+		return nil
+	}
+
+	tenantID := activity.Conversation.TenantID
+	if activity.ChannelData != nil && activity.ChannelData.Tenant != nil {
+		tenantID = activity.ChannelData.Tenant.ID
+	}
+	if len(s.config.AllowedTenants) > 0 && !slices.Contains(s.config.AllowedTenants, tenantID) {
+		log.Warnw("Teams activity from a tenant not allowed", "tenantID", tenantID)
+		return errors.New(errors.ErrForbidden, "The tenant is not allowed to use the bot")
+	}
+
+	text := strings.TrimSpace(teamsMention.ReplaceAllString(activity.Text, ""))
+	if text == "" {
+		return nil
+	}
+
+	userID := s.config.UserPrefix + activity.From.AADObjectID
+	// Bot Framework retries activities not acknowledged in time, so replies are generated
+	// after responding
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		if err := s.answer(ctx, activity, userID, tenantID, text); err != nil {
+			logger.Context(ctx).Errorw("Failed to answer Teams message", "error", err, "userID", userID)
+		}
+	}()
+	return nil
+}
+
+// answer sends a Teams message to the chat of its conversation and replies with the answer,
+// or with the error when there is none
+func (s *teamsService) answer(ctx context.Context, activity *dtos.TeamsActivity, userID, tenantID, text string) error {
+	conversationID := activity.Conversation.ID
+	if err := s.teamsAdapter.SendActivity(ctx, activity.ServiceURL, conversationID, "", &dtos.TeamsActivity{Type: dtos.TeamsActivityTyping}); err != nil {
+		logger.Context(ctx).Warnw("Failed to send Teams typing indicator", "error", err)
+	}
+
+	reply := "Sorry, something went wrong. Please try again."
+	chatID, err := s.chatOf(ctx, activity, userID, tenantID, text)
+	if err == nil {
+		var exchange *dtos.MessageExchangeResponse
+		exchange, err = s.messageService.SendMessage(ctx, chatID, userID, &dtos.MessageRequest{Content: text})
+		if err == nil && exchange.AssistantMessage != nil {
+			reply = exchange.AssistantMessage.Content
+		}
+	}
+	if appErr, ok := err.(*errors.AppError); ok {
+		reply = "Sorry, " + appErr.Message
+	}
+
+	if sendErr := s.teamsAdapter.SendActivity(ctx, activity.ServiceURL, conversationID, activity.ID, &dtos.TeamsActivity{
+		Type:       dtos.TeamsActivityMessage,
+		Text:       reply,
+		TextFormat: "markdown",
+		ReplyToID:  activity.ID,
+	}); sendErr != nil {
+		return sendErr
+	}
+	return err
+}
+
+// chatOf returns the chat of the Teams conversation of a user, creating it on their first message
+func (s *teamsService) chatOf(ctx context.Context, activity *dtos.TeamsActivity, userID, tenantID, text string) (int64, error) {
+	conversation, err := s.teamsRepo.GetConversation(ctx, activity.Conversation.ID, userID)
+	if err == nil {
+		// The chat may have been transferred since
+		owner, err := s.chatService.GetOwner(ctx, conversation.ChatID)
+		if err != nil {
+			return 0, err
+		}
+		return conversation.ChatID, checkChatOwner(owner.UserID, userID)
+	}
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		return 0, err
+	}
+
+	chat, err := s.chatService.CreateChat(ctx, userID, &dtos.ChatRequest{Title: truncateRunes(text, teamsTitleLength)})
+	if err != nil {
+		return 0, err
+	}
+	created, err := s.teamsRepo.CreateConversation(ctx, &models.TeamsConversation{
+		ConversationID: activity.Conversation.ID,
+		UserID:         userID,
+		ChatID:         chat.ID,
+		TenantID:       tenantID,
+		ServiceURL:     activity.ServiceURL,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !created {
+		// Another message of the user created the chat of the conversation at the same time
+		if err := s.chatService.DeleteChat(ctx, chat.ID); err != nil {
+			return 0, err
+		}
+		conversation, err := s.teamsRepo.GetConversation(ctx, activity.Conversation.ID, userID)
+		if err != nil {
+			return 0, err
+		}
+		return conversation.ChatID, nil
+	}
+
+	logger.Context(ctx).Infow("Teams conversation mapped to a chat", "userID", userID, "chatID", chat.ID, "tenantID", tenantID)
+	return chat.ID, nil
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// TrainingExportService defines the interface for the exports of conversations into
+// fine-tuning datasets
+type TrainingExportService interface {
+	// CreateExport queues an export of the conversations matching the filters of a request
+	CreateExport(ctx context.Context, adminID string, req *dtos.TrainingExportRequest) (*dtos.TrainingExportResponse, error)
+
+	// GetExport returns the status of a dataset export, with its download URL once completed
+	GetExport(ctx context.Context, id int64) (*dtos.TrainingExportResponse, error)
+
+	// ListExports lists the latest dataset exports
+	ListExports(ctx context.Context) (*dtos.ListTrainingExportsResponse, error)
+
+	// ProcessPending builds the pending dataset exports and returns the number processed
+	ProcessPending(ctx context.Context) (int, error)
+}
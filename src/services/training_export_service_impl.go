@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/redact"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// Bounds of the dataset exports
+const (
+	// trainingExportsListed is the number of latest dataset exports listed
+	trainingExportsListed = 50
+	// trainingExportsPerRun is the number of pending dataset exports built per run
+	trainingExportsPerRun = 2
+)
+
+// trainingExportService implements the TrainingExportService interface
+type trainingExportService struct {
+	config      configs.FineTuning
+	exportRepo  repositories.TrainingExportRepository
+	messageRepo repositories.MessageRepository
+	storage     adapters.StorageAdapter
+}
+
+// NewTrainingExportService creates a new fine-tuning dataset export service
+func NewTrainingExportService(config configs.FineTuning, exportRepo repositories.TrainingExportRepository, messageRepo repositories.MessageRepository, storage adapters.StorageAdapter) TrainingExportService {
+	return &trainingExportService{
+		config:      config,
+		exportRepo:  exportRepo,
+		messageRepo: messageRepo,
+		storage:     storage,
+	}
+}
+
+// CreateExport queues an export of the conversations matching the filters of a request. The
+// feedback score defaults to 1, so that only conversations rated up are exported.
+func (s *trainingExportService) CreateExport(ctx context.Context, adminID string, req *dtos.TrainingExportRequest) (*dtos.TrainingExportResponse, error) {
+	if req.From != nil && req.To != nil && !req.To.After(*req.From) {
+		return nil, errors.New(errors.ErrInvalidRequest, "The end of the period must be after its start")
+	}
+
+	export := &models.TrainingExport{
+		RequestedBy:  adminID,
+		Status:       models.ExportStatusPending,
+		From:         req.From,
+		To:           req.To,
+		Tags:         joinTags(req.Tags),
+		MinScore:     1,
+		SystemPrompt: strings.TrimSpace(req.SystemPrompt),
+	}
+	if req.MinScore != nil {
+		export.MinScore = *req.MinScore
+	}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Infow("Training export queued", "exportID", export.ID, "adminID", adminID,
+		"from", export.From, "to", export.To, "tags", export.Tags, "minScore", export.MinScore)
+	return s.toResponse(export)
+}
+
+// GetExport returns the status of a dataset export
+func (s *trainingExportService) GetExport(ctx context.Context, id int64) (*dtos.TrainingExportResponse, error) {
+	export, err := s.exportRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(export)
+}
+
+// ListExports lists the latest dataset exports, newest first
+func (s *trainingExportService) ListExports(ctx context.Context) (*dtos.ListTrainingExportsResponse, error) {
+	exports, err := s.exportRepo.List(ctx, trainingExportsListed)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dtos.ListTrainingExportsResponse{Exports: make([]dtos.TrainingExportResponse, 0, len(exports))}
+	for _, export := range exports {
+		item, err := s.toResponse(export)
+		if err != nil {
+			return nil, err
+		}
+		response.Exports = append(response.Exports, *item)
+	}
+	return response, nil
+}
+
+// ProcessPending builds the pending dataset exports and returns the number processed. A
+// failed export is marked as such and does not stop the others.
+func (s *trainingExportService) ProcessPending(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	exports, err := s.exportRepo.ListPending(ctx, trainingExportsPerRun)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, export := range exports {
+		export.Status = models.ExportStatusRunning
+		if err := s.exportRepo.Update(ctx, export); err != nil {
+			return 0, err
+		}
+
+		if err := s.build(ctx, export); err != nil {
+			log.Errorw("Failed to build training export", "error", err, "exportID", export.ID)
+			export.Status = models.ExportStatusFailed
+			export.Error = "Failed to build the dataset"
+		} else {
+			export.Status = models.ExportStatusCompleted
+			log.Infow("Training export completed", "exportID", export.ID, "chats", export.Chats, "size", export.Size)
+		}
+
+		now := time.Now()
+		export.CompletedAt = &now
+		if err := s.exportRepo.Update(ctx, export); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(exports), nil
+}
+
+// build writes the conversations of a dataset export as JSON lines, staged in a temporary
+// file, and stores them
+func (s *trainingExportService) build(ctx context.Context, export *models.TrainingExport) error {
+	tmp, err := os.CreateTemp("", "training-*.jsonl")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	chats, err := s.writeDataset(ctx, tmp, export)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("training-exports/%d/dataset-%d.jsonl", export.ID, export.ID)
+	size, err := s.storage.Put(ctx, key, tmp)
+	if err != nil {
+		return err
+	}
+
+	export.StorageKey = key
+	export.Size = size
+	export.Chats = chats
+	return nil
+}
+
+// writeDataset writes a line per matching conversation with a reply to train on, up to the
+// configured number of conversations, and returns the number written
+func (s *trainingExportService) writeDataset(ctx context.Context, w io.Writer, export *models.TrainingExport) (int, error) {
+	encoder := json.NewEncoder(w)
+	chats := 0
+	var afterID int64
+	for chats < s.config.MaxChats {
+		page, err := s.exportRepo.ListChats(ctx, export, afterID, exportPageSize)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, chat := range page {
+			afterID = chat.ID
+			example, err := s.example(ctx, export, chat)
+			if err != nil {
+				return 0, err
+			}
+			if example == nil {
+				continue
+			}
+			if err := encoder.Encode(example); err != nil {
+				return 0, err
+			}
+			if chats++; chats >= s.config.MaxChats {
+				break
+			}
+		}
+
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+	return chats, nil
+}
+
+// example converts the conversation of a chat into a training example, with personal
+// information redacted from its messages. Messages left out of prompts, summaries and tool
+// results are skipped, replies rated down are weighted 0, and the messages after the last
+// reply are dropped. It returns nil when no reply is left to train on.
+func (s *trainingExportService) example(ctx context.Context, export *models.TrainingExport, chat *models.Chat) (*dtos.TrainingExample, error) {
+	example := &dtos.TrainingExample{}
+	if export.SystemPrompt != "" {
+		example.Messages = append(example.Messages, dtos.TrainingMessage{Role: models.MessageRoleSystem, Content: export.SystemPrompt})
+	}
+
+	last := -1
+	for offset := 0; ; offset += exportPageSize {
+		messages, err := s.messageRepo.ListByChatID(ctx, chat.ID, exportPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, message := range messages {
+			if !message.InPrompt() || message.Kind != "" || strings.TrimSpace(message.Content) == "" {
+				continue
+			}
+			switch message.Role {
+			case models.MessageRoleSystem, models.MessageRoleUser:
+				example.Messages = append(example.Messages, dtos.TrainingMessage{Role: message.Role, Content: redact.PII(message.Content)})
+			case models.MessageRoleAssistant:
+				item := dtos.TrainingMessage{Role: message.Role, Content: redact.PII(message.Content)}
+				if message.Feedback < 0 {
+					weight := 0
+					item.Weight = &weight
+				} else {
+					last = len(example.Messages)
+				}
+				example.Messages = append(example.Messages, item)
+			}
+		}
+
+		if len(messages) < exportPageSize {
+			break
+		}
+	}
+
+	if last < 0 {
+		return nil, nil
+	}
+	example.Messages = example.Messages[:last+1]
+	return example, nil
+}
+
+// toResponse converts a dataset export to its response DTO, signing a download URL when it
+// is completed
+func (s *trainingExportService) toResponse(export *models.TrainingExport) (*dtos.TrainingExportResponse, error) {
+	response := &dtos.TrainingExportResponse{
+		ID:           export.ID,
+		Status:       export.Status,
+		RequestedBy:  export.RequestedBy,
+		From:         export.From,
+		To:           export.To,
+		Tags:         []string{},
+		MinScore:     export.MinScore,
+		SystemPrompt: export.SystemPrompt,
+		Chats:        export.Chats,
+		Size:         export.Size,
+		Error:        export.Error,
+		CreatedAt:    export.CreatedAt,
+		CompletedAt:  export.CompletedAt,
+	}
+	if export.Tags != "" {
+		response.Tags = strings.Split(export.Tags, ",")
+	}
+
+	if export.Status == models.ExportStatusCompleted {
+		url, err := s.storage.SignedURL(export.StorageKey, s.config.URLTTL)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrInternal, "Failed to sign download URL")
+		}
+		expiresAt := time.Now().Add(s.config.URLTTL)
+		response.DownloadURL = url
+		response.DownloadExpiresAt = &expiresAt
+	}
+
+	return response, nil
+}
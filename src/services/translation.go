@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+)
+
+// Translator detects the language of user messages and translates the messages of chats
+// that set a language other than the model's, so the model is prompted in its own language
+// and users read replies in theirs. Failures are logged and leave messages untranslated.
+type Translator interface {
+	// PrepareMessage detects the language of a user message and, when its chat sets a
+	// language and the message is not in the model language, sets its translation into it
+	PrepareMessage(ctx context.Context, chat *models.Chat, message *models.Message)
+	// LocalizeReply translates an assistant reply into the language its chat sets, keeping
+	// the reply in the model language as its translation
+	LocalizeReply(ctx context.Context, chat *models.Chat, message *models.Message)
+}
+
+// translator implements the Translator interface
+type translator struct {
+	adapter       adapters.TranslationAdapter
+	modelLanguage string
+}
+
+// NewTranslator creates a new translator
+func NewTranslator(config configs.Translation, adapter adapters.TranslationAdapter) Translator {
+	if config.ModelLanguage == "" {
+		config.ModelLanguage = "en"
+	}
+
+	return &translator{
+		adapter:       adapter,
+		modelLanguage: strings.ToLower(config.ModelLanguage),
+	}
+}
+
+// PrepareMessage detects the language of a user message and translates it into the model
+// language when its chat sets a language
+func (t *translator) PrepareMessage(ctx context.Context, chat *models.Chat, message *models.Message) {
+	log := logger.Context(ctx)
+
+	message.Language = ""
+	message.Translation = ""
+	if strings.TrimSpace(message.Content) == "" {
+		return
+	}
+
+	language, err := t.adapter.Detect(ctx, message.Content)
+	if err != nil {
+		log.Warnw("Failed to detect message language", "error", err, "chatID", chat.ID)
+	}
+	message.Language = strings.ToLower(language)
+
+	if chat.Language == "" {
+		return
+	}
+	// Users of a chat with a language set are expected to write in it
+	source := message.Language
+	if source == "" {
+		source = chat.Language
+	}
+	if source == t.modelLanguage {
+		return
+	}
+
+	translation, err := t.translate(ctx, message.Content, source, t.modelLanguage)
+	if err != nil {
+		log.Warnw("Failed to translate message, prompting with the original", "error", err, "chatID", chat.ID, "language", source)
+		return
+	}
+	message.Translation = translation
+}
+
+// LocalizeReply translates an assistant reply into the language its chat sets
+func (t *translator) LocalizeReply(ctx context.Context, chat *models.Chat, message *models.Message) {
+	message.Language = t.modelLanguage
+	if chat.Language == "" || chat.Language == t.modelLanguage || strings.TrimSpace(message.Content) == "" {
+		return
+	}
+
+	translation, err := t.translate(ctx, message.Content, t.modelLanguage, chat.Language)
+	if err != nil {
+		logger.Context(ctx).Warnw("Failed to translate reply, keeping the original", "error", err, "chatID", chat.ID, "language", chat.Language)
+		return
+	}
+
+	message.Translation = message.Content
+	message.Content = translation
+	message.Language = chat.Language
+}
+
+// translate translates Markdown content, leaving its fenced code blocks as they are
+func (t *translator) translate(ctx context.Context, content, source, target string) (string, error) {
+	var result, prose []string
+	flush := func() error {
+		if len(prose) == 0 {
+			return nil
+		}
+		text := strings.Join(prose, "\n")
+		prose = nil
+		if strings.TrimSpace(text) == "" {
+			result = append(result, text)
+			return nil
+		}
+
+		translated, err := t.adapter.Translate(ctx, text, source, target)
+		if err != nil {
+			return err
+		}
+		result = append(result, translated)
+		return nil
+	}
+
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if isFence(line) || inFence {
+			if err := flush(); err != nil {
+				return "", err
+			}
+			if isFence(line) {
+				inFence = !inFence
+			}
+			result = append(result, line)
+			continue
+		}
+		prose = append(prose, line)
+	}
+	if err := flush(); err != nil {
+		return "", err
+	}
+
+	return strings.Join(result, "\n"), nil
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// VoiceService defines the interface for voice messages, which are transcribed
+// asynchronously before the LLM responds to them
+type VoiceService interface {
+	// SendVoiceMessage stores the audio of a new voice message and queues its transcription
+	SendVoiceMessage(ctx context.Context, chatID int64, userID string, audio *dtos.AudioUpload) (*dtos.MessageResponse, error)
+
+	// ProcessPending transcribes the pending voice messages and returns the number processed
+	ProcessPending(ctx context.Context) (int, error)
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// uploadGracePeriod is how long a voice message may wait for its audio to be recorded
+// before it is considered failed
+const uploadGracePeriod = time.Minute
+
+// voiceService implements the VoiceService interface
+type voiceService struct {
+	config         configs.Transcription
+	chatRepo       repositories.ChatRepository
+	messageRepo    repositories.MessageRepository
+	attachmentRepo repositories.AttachmentRepository
+	storage        adapters.StorageAdapter
+	speechToText   adapters.SpeechToTextAdapter
+	messageService MessageService
+	budgets        BudgetService
+	consent        ConsentService
+}
+
+// NewVoiceService creates a new voice message service
+func NewVoiceService(
+	config configs.Transcription,
+	chatRepo repositories.ChatRepository,
+	messageRepo repositories.MessageRepository,
+	attachmentRepo repositories.AttachmentRepository,
+	storage adapters.StorageAdapter,
+	speechToText adapters.SpeechToTextAdapter,
+	messageService MessageService,
+	budgets BudgetService,
+	consent ConsentService,
+) VoiceService {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 5
+	}
+
+	return &voiceService{
+		config:         config,
+		chatRepo:       chatRepo,
+		messageRepo:    messageRepo,
+		attachmentRepo: attachmentRepo,
+		storage:        storage,
+		speechToText:   speechToText,
+		messageService: messageService,
+		budgets:        budgets,
+		consent:        consent,
+	}
+}
+
+// SendVoiceMessage stores the audio of a new voice message and queues its transcription.
+// The message has no content until it is transcribed.
+func (s *voiceService) SendVoiceMessage(ctx context.Context, chatID int64, userID string, audio *dtos.AudioUpload) (*dtos.MessageResponse, error) {
+	log := logger.Context(ctx)
+
+	if !s.config.Enabled {
+		return nil, errors.New(errors.ErrInvalidRequest, "Voice messages are not enabled")
+	}
+	if models.IsGuest(userID) {
+		return nil, errors.New(errors.ErrForbidden, "Voice messages are not available to guests")
+	}
+	if !strings.HasPrefix(audio.ContentType, "audio/") {
+		return nil, errors.New(errors.ErrInvalidRequest, "Voice messages must be audio files")
+	}
+	if s.config.MaxSize > 0 && audio.Size > s.config.MaxSize {
+		return nil, errors.New(errors.ErrPayloadTooLarge, "Audio file is too large")
+	}
+
+	chat, err := s.chatRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID != userID {
+		return nil, errors.New(errors.ErrForbidden, "User does not have access to this chat")
+	}
+	if err := checkUnlocked(chat); err != nil {
+		return nil, err
+	}
+	// The audio and its attachment would be stored, and privacy mode messages are not
+	// transcribed
+	if chat.Ephemeral {
+		return nil, errors.New(errors.ErrInvalidRequest, "Privacy mode chats cannot have voice messages")
+	}
+	if err := checkChatBudget(chat); err != nil {
+		return nil, err
+	}
+	if err := s.consent.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.budgets.Enforce(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// Store the audio first so a message is never left without it
+	key := "attachments/" + uuid.NewString() + fileExtension(audio.Filename)
+	size, err := s.storage.Put(ctx, key, audio.Reader)
+	if err != nil {
+		log.Errorw("Failed to store audio", "error", err, "chatID", chatID)
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to store audio")
+	}
+
+	// Transcripts are not Markdown
+	message := &models.Message{
+		ChatID:      chatID,
+		UserID:      &userID,
+		Role:        models.MessageRoleUser,
+		ContentType: models.ContentTypePlain,
+		Status:      models.MessageStatusTranscribing,
+	}
+	if err := s.messageRepo.Create(ctx, message); err != nil {
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		MessageID:   message.ID,
+		Filename:    filepath.Base(audio.Filename),
+		ContentType: audio.ContentType,
+		Size:        size,
+		StorageKey:  key,
+	}
+	if err := s.attachmentRepo.Create(ctx, attachment); err != nil {
+		return nil, err
+	}
+
+	log.Infow("Voice message queued for transcription", "chatID", chatID, "messageID", message.ID, "size", size)
+	return toMessageResponse(message), nil
+}
+
+// ProcessPending transcribes the pending voice messages and gets the LLM response to
+// each. A failed message is marked as such and does not stop the others.
+func (s *voiceService) ProcessPending(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	messages, err := s.messageRepo.ListByStatus(ctx, models.MessageStatusTranscribing, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, message := range messages {
+		transcript, err := s.transcribe(ctx, message)
+		if err != nil {
+			log.Errorw("Failed to transcribe voice message", "error", err, "messageID", message.ID)
+			if err := s.messageService.FailTranscription(ctx, message.ID); err != nil {
+				return processed, err
+			}
+			processed++
+			continue
+		}
+		if transcript == "" {
+			// The audio is still being recorded
+			continue
+		}
+
+		if _, err := s.messageService.CompleteTranscription(ctx, message.ID, transcript); err != nil {
+			// The transcript is kept; only the reply is missing, as when sending a text message fails
+			log.Errorw("Failed to reply to voice message", "error", err, "messageID", message.ID)
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// transcribe returns the transcript of the audio of a voice message. It returns an empty
+// transcript without error while the audio is not recorded yet.
+func (s *voiceService) transcribe(ctx context.Context, message *models.Message) (string, error) {
+	attachments, err := s.attachmentRepo.ListByMessageIDs(ctx, []int64{message.ID})
+	if err != nil {
+		return "", err
+	}
+	if len(attachments) == 0 {
+		if time.Since(message.CreatedAt) < uploadGracePeriod {
+			return "", nil
+		}
+		return "", errors.New(errors.ErrNotFound, "Voice message has no audio")
+	}
+
+	audio := attachments[0]
+	file, err := s.storage.Open(ctx, audio.StorageKey)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	transcript, err := s.speechToText.Transcribe(ctx, file, audio.Filename, audio.ContentType)
+	if err != nil {
+		return "", err
+	}
+	if transcript == "" {
+		return "", errors.New(errors.ErrInvalidRequest, "No speech detected")
+	}
+	return transcript, nil
+}
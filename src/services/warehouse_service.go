@@ -0,0 +1,13 @@
+package services
+
+import (
+	"context"
+)
+
+// WarehouseService defines the interface for the incremental export of chats, messages and
+// usage to a data warehouse
+type WarehouseService interface {
+	// Export exports the rows of each table changed since the previous export, and returns
+	// the number of rows exported
+	Export(ctx context.Context) (int, error)
+}
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/analytics"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// warehouseObjectType is the content type of the exported objects: gzipped JSON lines, which
+// BigQuery, Athena and Snowflake load as they are
+const warehouseObjectType = "application/x-ndjson"
+
+// warehouseTable is a table exported to the data warehouse
+type warehouseTable struct {
+	name    string
+	version int
+	// row is a zero row, describing the schema of the table
+	row interface{}
+	// fetch returns the rows changed after a cursor and before until, with the change time
+	// and ID of the last one
+	fetch func(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]interface{}, time.Time, int64, error)
+}
+
+// warehouseService implements the WarehouseService interface
+type warehouseService struct {
+	config        configs.Warehouse
+	warehouseRepo repositories.WarehouseRepository
+	workspaceRepo repositories.WorkspaceRepository
+	statRepo      repositories.WorkspaceStatRepository
+	sink          adapters.WarehouseSinkAdapter
+	policy        *analytics.Policy
+	tables        []warehouseTable
+}
+
+// NewWarehouseService creates a new warehouse service exporting to sink. The statistics of
+// workspaces are exported too with an analytics policy releasing them.
+func NewWarehouseService(config configs.Warehouse, warehouseRepo repositories.WarehouseRepository, workspaceRepo repositories.WorkspaceRepository, statRepo repositories.WorkspaceStatRepository, sink adapters.WarehouseSinkAdapter, policy *analytics.Policy) WarehouseService {
+	if config.BatchSize < 1 {
+		config.BatchSize = 1
+	}
+	s := &warehouseService{
+		config:        config,
+		warehouseRepo: warehouseRepo,
+		workspaceRepo: workspaceRepo,
+		statRepo:      statRepo,
+		sink:          sink,
+		policy:        policy,
+	}
+	s.tables = []warehouseTable{
+		{name: "chats", version: dtos.WarehouseChatsVersion, row: dtos.WarehouseChat{}, fetch: s.fetchChats},
+		{name: "messages", version: dtos.WarehouseMessagesVersion, row: dtos.WarehouseMessage{}, fetch: s.fetchMessages},
+		{name: "usage", version: dtos.WarehouseUsageVersion, row: dtos.WarehouseUsage{}, fetch: s.fetchUsage},
+	}
+	if policy != nil {
+		s.tables = append(s.tables, warehouseTable{name: "workspace_stats", version: dtos.WarehouseWorkspaceStatsVersion,
+			row: dtos.WarehouseWorkspaceStat{}, fetch: s.fetchWorkspaceStats})
+	}
+	return s
+}
+
+// Export exports the rows of each table changed since its cursor, up to the configured lag
+// before now, in at most MaxBatches objects per table. While the workspace is opted out
+// nothing is exported, and the cursors move past the rows changed meanwhile so that they
+// are never exported.
+func (s *warehouseService) Export(ctx context.Context) (int, error) {
+	log := logger.Context(ctx)
+
+	settings, err := workspaceDefaults(ctx, s.workspaceRepo)
+	if err != nil {
+		return 0, err
+	}
+
+	until := time.Now().Add(-s.config.Lag)
+	exported := 0
+	for _, table := range s.tables {
+		cursor, err := s.warehouseRepo.GetCursor(ctx, table.name)
+		if err != nil {
+			return exported, err
+		}
+		if cursor == nil {
+			cursor = &models.WarehouseCursor{Table: table.name}
+		}
+
+		if settings.WarehouseOptOut {
+			if cursor.SchemaVersion != table.version || cursor.ChangedAt.Before(until) {
+				cursor.SchemaVersion, cursor.ChangedAt, cursor.RowID = table.version, until, 0
+				if err := s.warehouseRepo.SaveCursor(ctx, cursor); err != nil {
+					return exported, err
+				}
+			}
+			continue
+		}
+
+		n, err := s.exportTable(ctx, table, cursor, until)
+		exported += n
+		if err != nil {
+			return exported, err
+		}
+	}
+
+	if settings.WarehouseOptOut {
+		log.Debugw("Workspace opted out of the warehouse export")
+	}
+	return exported, nil
+}
+
+// exportTable exports the rows of a table after its cursor in batches, saving the cursor
+// after each. A cursor of another schema version starts over.
+func (s *warehouseService) exportTable(ctx context.Context, table warehouseTable, cursor *models.WarehouseCursor, until time.Time) (int, error) {
+	log := logger.Context(ctx)
+
+	if cursor.SchemaVersion != table.version {
+		log.Infow("Exporting warehouse table from the start", "table", table.name, "version", table.version, "previousVersion", cursor.SchemaVersion)
+		cursor.SchemaVersion, cursor.ChangedAt, cursor.RowID, cursor.ExportedRows = table.version, time.Time{}, 0, 0
+	}
+
+	now := time.Now().UTC()
+	exported := 0
+	for batch := 0; batch < s.config.MaxBatches; batch++ {
+		rows, changedAt, rowID, err := table.fetch(ctx, cursor, until, s.config.BatchSize)
+		if err != nil {
+			return exported, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		if batch == 0 {
+			if err := s.writeSchema(ctx, table); err != nil {
+				return exported, err
+			}
+		}
+
+		data, err := encodeWarehouseRows(rows)
+		if err != nil {
+			return exported, errors.Wrap(err, errors.ErrInternal, "Failed to encode warehouse rows")
+		}
+		key := fmt.Sprintf("%s/region=%s/dt=%s/%s-%03d.jsonl.gz", s.tablePrefix(table), warehouseRegion(ctx),
+			now.Format("2006-01-02"), now.Format("20060102T150405Z"), batch)
+		if err := s.sink.Write(ctx, key, data, warehouseObjectType); err != nil {
+			log.Errorw("Failed to write warehouse object", "table", table.name, "key", key, "error", err)
+			return exported, errors.Wrap(err, errors.ErrInternal, "Failed to write warehouse object")
+		}
+
+		cursor.ChangedAt, cursor.RowID = changedAt, rowID
+		cursor.ExportedRows += int64(len(rows))
+		if err := s.warehouseRepo.SaveCursor(ctx, cursor); err != nil {
+			return exported, err
+		}
+		exported += len(rows)
+		log.Debugw("Exported warehouse rows", "table", table.name, "key", key, "count", len(rows))
+
+		if len(rows) < s.config.BatchSize {
+			break
+		}
+	}
+	return exported, nil
+}
+
+// writeSchema writes the schema of the version of a table next to its objects
+func (s *warehouseService) writeSchema(ctx context.Context, table warehouseTable) error {
+	data, err := json.MarshalIndent(warehouseSchema(table), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, errors.ErrInternal, "Failed to encode warehouse schema")
+	}
+	key := s.tablePrefix(table) + "/schema.json"
+	if err := s.sink.Write(ctx, key, data, "application/json"); err != nil {
+		logger.Context(ctx).Errorw("Failed to write warehouse schema", "table", table.name, "key", key, "error", err)
+		return errors.Wrap(err, errors.ErrInternal, "Failed to write warehouse schema")
+	}
+	return nil
+}
+
+// tablePrefix returns the prefix of the keys of the objects of the version of a table
+func (s *warehouseService) tablePrefix(table warehouseTable) string {
+	prefix := strings.Trim(s.config.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return fmt.Sprintf("%s%s/v%d", prefix, table.name, table.version)
+}
+
+// fetchChats returns the chats changed after a cursor as warehouse rows
+func (s *warehouseService) fetchChats(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]interface{}, time.Time, int64, error) {
+	chats, err := s.warehouseRepo.ListChats(ctx, after, until, limit)
+	if err != nil || len(chats) == 0 {
+		return nil, time.Time{}, 0, err
+	}
+
+	rows := make([]interface{}, 0, len(chats))
+	for _, chat := range chats {
+		row := dtos.WarehouseChat{
+			ID:          chat.ID,
+			PublicID:    chat.PublicID,
+			UserID:      chat.UserID,
+			Tags:        chat.Tags,
+			Language:    chat.Language,
+			Archived:    chat.Archived,
+			Locked:      chat.Locked,
+			URLContext:  chat.URLContext,
+			Suggestions: chat.Suggestions,
+			MaxTokens:   chat.MaxTokens,
+			MaxCost:     chat.MaxCost,
+			UsedTokens:  chat.UsedTokens,
+			UsedCost:    chat.UsedCost,
+			CreatedAt:   chat.CreatedAt,
+			UpdatedAt:   chat.UpdatedAt,
+			DeleteAt:    chat.DeleteAt,
+		}
+		if s.config.IncludeContent {
+			row.Title = &chat.Title
+		}
+		rows = append(rows, row)
+	}
+	last := chats[len(chats)-1]
+	return rows, last.UpdatedAt, last.ID, nil
+}
+
+// fetchMessages returns the messages changed after a cursor as warehouse rows
+func (s *warehouseService) fetchMessages(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]interface{}, time.Time, int64, error) {
+	messages, err := s.warehouseRepo.ListMessages(ctx, after, until, limit)
+	if err != nil || len(messages) == 0 {
+		return nil, time.Time{}, 0, err
+	}
+
+	rows := make([]interface{}, 0, len(messages))
+	for _, message := range messages {
+		row := dtos.WarehouseMessage{
+			ID:               message.ID,
+			PublicID:         message.PublicID,
+			ChatID:           message.ChatID,
+			SeqNo:            message.SeqNo,
+			UserID:           message.UserID,
+			Role:             message.Role,
+			Kind:             message.Kind,
+			ContentLength:    len(message.Content),
+			ContentInStorage: message.ContentKey != "",
+			ContentType:      message.ContentType,
+			Status:           message.Status,
+			Model:            message.Model,
+			Provider:         message.Provider,
+			ModelVersion:     message.ModelVersion,
+			PersonaID:        message.PersonaID,
+			VariantID:        message.VariantID,
+			TotalTokens:      message.TotalTokens,
+			LatencyMs:        message.LatencyMs,
+			Feedback:         message.Feedback,
+			Language:         message.Language,
+			EditedAt:         message.EditedAt,
+			CreatedAt:        message.CreatedAt,
+			UpdatedAt:        message.UpdatedAt,
+		}
+		if s.config.IncludeContent {
+			row.Content = &message.Content
+		}
+		rows = append(rows, row)
+	}
+	last := messages[len(messages)-1]
+	return rows, last.UpdatedAt, last.ID, nil
+}
+
+// fetchUsage returns the usage recorded after a cursor as warehouse rows
+func (s *warehouseService) fetchUsage(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]interface{}, time.Time, int64, error) {
+	usage, err := s.warehouseRepo.ListUsage(ctx, after, until, limit)
+	if err != nil || len(usage) == 0 {
+		return nil, time.Time{}, 0, err
+	}
+
+	rows := make([]interface{}, 0, len(usage))
+	for _, record := range usage {
+		rows = append(rows, dtos.WarehouseUsage{
+			ID:               record.ID,
+			UserID:           record.UserID,
+			ChatID:           record.ChatID,
+			MessageID:        record.MessageID,
+			Model:            record.Model,
+			PromptTokens:     record.PromptTokens,
+			CompletionTokens: record.CompletionTokens,
+			Images:           record.Images,
+			Cost:             record.Cost,
+			CreatedAt:        record.CreatedAt,
+		})
+	}
+	last := usage[len(usage)-1]
+	return rows, last.CreatedAt, last.ID, nil
+}
+
+// fetchWorkspaceStats returns the statistics of workspaces of the hours from that of a
+// cursor as warehouse rows, released by the analytics policy. Only the hours that ended an
+// hour before until are exported, once every instance flushed its counts. The cursor holds
+// the hour of the last row and the number of rows of that hour exported.
+func (s *warehouseService) fetchWorkspaceStats(ctx context.Context, after *models.WarehouseCursor, until time.Time, limit int) ([]interface{}, time.Time, int64, error) {
+	stats, err := s.warehouseRepo.ListWorkspaceStats(ctx, after, until.Add(-time.Hour).Truncate(time.Hour), limit)
+	if err != nil || len(stats) == 0 {
+		return nil, time.Time{}, 0, err
+	}
+	clipped, err := clipWorkspaceStats(ctx, s.policy, s.statRepo, stats)
+	if err != nil {
+		return nil, time.Time{}, 0, err
+	}
+
+	rows := make([]interface{}, 0, len(stats))
+	for _, stat := range clipped {
+		row := dtos.WarehouseWorkspaceStat{WorkspaceID: stat.WorkspaceID, Hour: stat.Hour.UTC(), Suppressed: true}
+		if released, ok := releaseWorkspaceStat(s.policy, stat); ok {
+			row.Suppressed = false
+			row.Users, row.Requests, row.Tokens = &released.Users, &released.Requests, &released.Tokens
+			row.ClientErrors, row.ServerErrors, row.Cost = &released.ClientErrors, &released.ServerErrors, &released.Cost
+		}
+		rows = append(rows, row)
+	}
+
+	last := stats[len(stats)-1].Hour
+	var exported int64
+	if last.Equal(after.ChangedAt) {
+		exported = after.RowID
+	}
+	for _, stat := range stats {
+		if stat.Hour.Equal(last) {
+			exported++
+		}
+	}
+	return rows, last, exported, nil
+}
+
+// encodeWarehouseRows encodes rows as gzipped JSON lines
+func encodeWarehouseRows(rows []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(writer)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// warehouseSchema describes the columns of a table from the JSON fields of its rows
+func warehouseSchema(table warehouseTable) dtos.WarehouseSchema {
+	schema := dtos.WarehouseSchema{Table: table.name, Version: table.version}
+	rowType := reflect.TypeOf(table.row)
+	for i := 0; i < rowType.NumField(); i++ {
+		field := rowType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		fieldType, mode := field.Type, "REQUIRED"
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType, mode = fieldType.Elem(), "NULLABLE"
+		}
+
+		kind := "STRING"
+		switch fieldType.Kind() {
+		case reflect.Bool:
+			kind = "BOOLEAN"
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			kind = "INTEGER"
+		case reflect.Float32, reflect.Float64:
+			kind = "FLOAT"
+		case reflect.Struct:
+			if fieldType == reflect.TypeOf(time.Time{}) {
+				kind = "TIMESTAMP"
+			}
+		}
+		schema.Fields = append(schema.Fields, dtos.WarehouseSchemaField{Name: name, Type: kind, Mode: mode})
+	}
+	return schema
+}
+
+// warehouseRegion returns the name of the data residency region of ctx in object keys
+func warehouseRegion(ctx context.Context) string {
+	if region := adapters.RegionOf(ctx); region != "" {
+		return region
+	}
+	return "main"
+}
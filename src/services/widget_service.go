@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// WidgetService defines the interface for embeddable chat widgets
+type WidgetService interface {
+	// CreateWidget creates a widget
+	CreateWidget(ctx context.Context, req *dtos.WidgetRequest) (*dtos.WidgetResponse, error)
+
+	// ListWidgets lists all widgets
+	ListWidgets(ctx context.Context) (*dtos.ListWidgetsResponse, error)
+
+	// GetWidget retrieves a widget by ID
+	GetWidget(ctx context.Context, id int64) (*dtos.WidgetResponse, error)
+
+	// UpdateWidget replaces the settings of a widget
+	UpdateWidget(ctx context.Context, id int64, req *dtos.WidgetRequest) (*dtos.WidgetResponse, error)
+
+	// DeleteWidget deletes a widget; the tokens of its sessions remain valid until they expire
+	DeleteWidget(ctx context.Context, id int64) error
+
+	// GetConfig returns the configuration an enabled widget is rendered with, requested from
+	// one of its allowed origins
+	GetConfig(ctx context.Context, id int64, origin string) (*dtos.WidgetConfigResponse, error)
+
+	// StartSession issues a short-lived guest token, bound to the origin it is requested from,
+	// for a new session of an enabled widget
+	StartSession(ctx context.Context, id int64, origin string) (*dtos.WidgetSessionResponse, error)
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/middlewares"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// widgetScopes are the scopes of widget session tokens: chatting, without settings or admin access
+var widgetScopes = []string{middlewares.ScopeChatsRead, middlewares.ScopeChatsWrite, middlewares.ScopeMessagesSend}
+
+// widgetService implements the WidgetService interface
+type widgetService struct {
+	config     configs.Widget
+	secret     string
+	widgetRepo repositories.WidgetRepository
+}
+
+// NewWidgetService creates a new widget service signing session tokens with secret
+func NewWidgetService(config configs.Widget, secret string, widgetRepo repositories.WidgetRepository) WidgetService {
+	return &widgetService{
+		config:     config,
+		secret:     secret,
+		widgetRepo: widgetRepo,
+	}
+}
+
+// CreateWidget creates a widget
+func (s *widgetService) CreateWidget(ctx context.Context, req *dtos.WidgetRequest) (*dtos.WidgetResponse, error) {
+	log := logger.Context(ctx)
+
+	widget := &models.Widget{Enabled: true}
+	if err := applyWidgetRequest(widget, req); err != nil {
+		return nil, err
+	}
+	if err := s.widgetRepo.Create(ctx, widget); err != nil {
+		return nil, err
+	}
+
+	log.Infow("Widget created", "widgetID", widget.ID, "name", widget.Name)
+	return toWidgetResponse(widget), nil
+}
+
+// ListWidgets lists all widgets
+func (s *widgetService) ListWidgets(ctx context.Context) (*dtos.ListWidgetsResponse, error) {
+	widgets, err := s.widgetRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dtos.ListWidgetsResponse{Widgets: make([]dtos.WidgetResponse, 0, len(widgets))}
+	for _, widget := range widgets {
+		response.Widgets = append(response.Widgets, *toWidgetResponse(widget))
+	}
+	return response, nil
+}
+
+// GetWidget retrieves a widget by ID
+func (s *widgetService) GetWidget(ctx context.Context, id int64) (*dtos.WidgetResponse, error) {
+	widget, err := s.widgetRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toWidgetResponse(widget), nil
+}
+
+// UpdateWidget replaces the settings of a widget; Enabled is kept when omitted
+func (s *widgetService) UpdateWidget(ctx context.Context, id int64, req *dtos.WidgetRequest) (*dtos.WidgetResponse, error) {
+	log := logger.Context(ctx)
+
+	widget, err := s.widgetRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWidgetRequest(widget, req); err != nil {
+		return nil, err
+	}
+	if err := s.widgetRepo.Update(ctx, widget); err != nil {
+		return nil, err
+	}
+
+	log.Infow("Widget updated", "widgetID", widget.ID, "enabled", widget.Enabled)
+	return toWidgetResponse(widget), nil
+}
+
+// DeleteWidget deletes a widget
+func (s *widgetService) DeleteWidget(ctx context.Context, id int64) error {
+	if err := s.widgetRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	logger.Context(ctx).Infow("Widget deleted", "widgetID", id)
+	return nil
+}
+
+// GetConfig returns the configuration of a widget requested from one of its allowed origins
+func (s *widgetService) GetConfig(ctx context.Context, id int64, origin string) (*dtos.WidgetConfigResponse, error) {
+	widget, err := s.embeddedWidget(ctx, id, origin)
+	if err != nil {
+		return nil, err
+	}
+	return toWidgetConfigResponse(widget), nil
+}
+
+// StartSession issues a token for a new session of a widget, as a guest bound to the origin
+func (s *widgetService) StartSession(ctx context.Context, id int64, origin string) (*dtos.WidgetSessionResponse, error) {
+	log := logger.Context(ctx)
+
+	widget, err := s.embeddedWidget(ctx, id, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := models.WidgetUserID(widget.ID, uuid.NewString())
+	now := time.Now()
+	expiresAt := now.Add(s.config.TokenTTL)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":    userID,
+		"role":   models.RoleGuest,
+		"scope":  strings.Join(widgetScopes, " "),
+		"origin": normalizeOrigin(origin),
+		"iat":    now.Unix(),
+		"exp":    expiresAt.Unix(),
+	})
+	signed, err := token.SignedString([]byte(s.secret))
+	if err != nil {
+		log.Errorw("Failed to sign widget token", "error", err)
+		return nil, errors.Wrap(err, errors.ErrInternal, "Failed to start widget session")
+	}
+
+	log.Infow("Widget session started", "widgetID", widget.ID, "userID", userID)
+	return &dtos.WidgetSessionResponse{
+		Token:     signed,
+		UserID:    userID,
+		Scopes:    widgetScopes,
+		ExpiresAt: expiresAt,
+		Config:    *toWidgetConfigResponse(widget),
+	}, nil
+}
+
+// embeddedWidget returns an enabled widget embedded on origin. Widgets that are disabled or
+// not embedded there are reported as not found, to not reveal them.
+func (s *widgetService) embeddedWidget(ctx context.Context, id int64, origin string) (*models.Widget, error) {
+	widget, err := s.widgetRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !widget.Enabled {
+		return nil, errors.New(errors.ErrNotFound, "Widget not found")
+	}
+
+	if origin == "" || !slices.Contains(splitList(widget.AllowedOrigins), normalizeOrigin(origin)) {
+		logger.Context(ctx).Warnw("Widget requested from an origin not allowed", "widgetID", id, "origin", origin)
+		return nil, errors.New(errors.ErrForbidden, "The widget is not allowed on this origin")
+	}
+	return widget, nil
+}
+
+// applyWidgetRequest sets the settings of a widget from a request
+func applyWidgetRequest(widget *models.Widget, req *dtos.WidgetRequest) error {
+	origins := make([]string, 0, len(req.AllowedOrigins))
+	for _, origin := range req.AllowedOrigins {
+		normalized, err := parseOrigin(origin)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(origins, normalized) {
+			origins = append(origins, normalized)
+		}
+	}
+
+	widget.Name = req.Name
+	widget.AllowedOrigins = strings.Join(origins, ",")
+	if req.Enabled != nil {
+		widget.Enabled = *req.Enabled
+	}
+	widget.Title = req.Branding.Title
+	widget.Greeting = req.Branding.Greeting
+	widget.PrimaryColor = req.Branding.PrimaryColor
+	widget.AvatarURL = req.Branding.AvatarURL
+	widget.AssistantName = req.Assistant.Name
+	widget.Model = req.Assistant.Model
+	widget.SystemPrompt = req.Assistant.SystemPrompt
+	return nil
+}
+
+// parseOrigin checks an origin is an HTTP(S) scheme and host, with an optional port, and
+// returns it normalized
+func parseOrigin(origin string) (string, error) {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", errors.New(errors.ErrInvalidRequest, "Invalid origin "+origin+", expected a scheme and host like https://example.com")
+	}
+	return normalizeOrigin(u.Scheme + "://" + u.Host), nil
+}
+
+// normalizeOrigin lowercases an origin and trims its trailing slash
+func normalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimSuffix(origin, "/"))
+}
+
+// applyWidget makes the request use the model and system prompt of the assistant of a widget
+func applyWidget(request *dtos.LLMRequest, widget *models.Widget) {
+	if widget.Model != "" {
+		request.Model = widget.Model
+	}
+	if widget.SystemPrompt != "" {
+		prompt := dtos.LLMMessage{Role: "system", Content: widget.SystemPrompt}
+		request.Messages = append([]dtos.LLMMessage{prompt}, request.Messages...)
+	}
+}
+
+// toWidgetResponse converts a widget to its API response
+func toWidgetResponse(widget *models.Widget) *dtos.WidgetResponse {
+	return &dtos.WidgetResponse{
+		ID:             widget.ID,
+		Name:           widget.Name,
+		AllowedOrigins: splitList(widget.AllowedOrigins),
+		Enabled:        widget.Enabled,
+		Branding:       toWidgetBranding(widget),
+		Assistant: dtos.WidgetAssistant{
+			Name:         widget.AssistantName,
+			Model:        widget.Model,
+			SystemPrompt: widget.SystemPrompt,
+		},
+		CreatedAt: widget.CreatedAt,
+		UpdatedAt: widget.UpdatedAt,
+	}
+}
+
+// toWidgetConfigResponse converts a widget to the configuration it is rendered with
+func toWidgetConfigResponse(widget *models.Widget) *dtos.WidgetConfigResponse {
+	return &dtos.WidgetConfigResponse{
+		WidgetID:      widget.ID,
+		Branding:      toWidgetBranding(widget),
+		AssistantName: widget.AssistantName,
+	}
+}
+
+// toWidgetBranding returns the branding of a widget
+func toWidgetBranding(widget *models.Widget) dtos.WidgetBranding {
+	return dtos.WidgetBranding{
+		Title:        widget.Title,
+		Greeting:     widget.Greeting,
+		PrimaryColor: widget.PrimaryColor,
+		AvatarURL:    widget.AvatarURL,
+	}
+}
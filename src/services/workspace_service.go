@@ -0,0 +1,16 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// WorkspaceService defines the interface for the workspace defaults admins manage
+type WorkspaceService interface {
+	// GetSettings returns the workspace defaults, along with the defaults applied
+	GetSettings(ctx context.Context) (*dtos.WorkspaceSettingsResponse, error)
+
+	// UpdateSettings replaces the workspace defaults
+	UpdateSettings(ctx context.Context, adminID string, req *dtos.WorkspaceSettingsRequest) (*dtos.WorkspaceSettingsResponse, error)
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/nvnamsss/chat/src/adapters"
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// workspaceService implements the WorkspaceService interface
+type workspaceService struct {
+	llm           configs.LLM
+	retention     configs.Retention
+	guardrails    configs.Guardrails
+	workspaceRepo repositories.WorkspaceRepository
+}
+
+// NewWorkspaceService creates a new workspace service, whose settings fall back to the LLM,
+// retention and guardrails configuration
+func NewWorkspaceService(llm configs.LLM, retention configs.Retention, guardrails configs.Guardrails, workspaceRepo repositories.WorkspaceRepository) WorkspaceService {
+	return &workspaceService{
+		llm:           llm,
+		retention:     retention,
+		guardrails:    guardrails,
+		workspaceRepo: workspaceRepo,
+	}
+}
+
+// GetSettings returns the workspace defaults
+func (s *workspaceService) GetSettings(ctx context.Context) (*dtos.WorkspaceSettingsResponse, error) {
+	settings, err := workspaceDefaults(ctx, s.workspaceRepo)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(settings), nil
+}
+
+// UpdateSettings replaces the workspace defaults
+func (s *workspaceService) UpdateSettings(ctx context.Context, adminID string, req *dtos.WorkspaceSettingsRequest) (*dtos.WorkspaceSettingsResponse, error) {
+	var guardrails *string
+	if req.Guardrails != nil {
+		for _, name := range req.Guardrails {
+			if !slices.ContainsFunc(s.guardrails.Rules, func(rule configs.GuardrailRule) bool { return rule.Name == name }) {
+				return nil, errors.New(errors.ErrInvalidRequest, "Unknown guardrail rule "+name)
+			}
+		}
+		list := strings.Join(req.Guardrails, ",")
+		guardrails = &list
+	}
+
+	settings := &models.WorkspaceSettings{
+		DefaultModel:    req.DefaultModel,
+		SystemPrompt:    req.SystemPrompt,
+		ModerationLevel: req.ModerationLevel,
+		RetentionDays:   req.RetentionDays,
+		Guardrails:      guardrails,
+		WarehouseOptOut: req.WarehouseOptOut,
+		UpdatedBy:       adminID,
+	}
+	if err := s.workspaceRepo.SaveSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	logger.Context(ctx).Warnw("Workspace settings updated", "adminID", adminID, "defaultModel", settings.DefaultModel,
+		"moderationLevel", settings.ModerationLevel, "retentionDays", settings.RetentionDays, "guardrails", req.Guardrails, "warehouseOptOut", settings.WarehouseOptOut)
+	return s.toResponse(settings), nil
+}
+
+// toResponse converts workspace settings to their API response, with the defaults applied
+func (s *workspaceService) toResponse(settings *models.WorkspaceSettings) *dtos.WorkspaceSettingsResponse {
+	response := &dtos.WorkspaceSettingsResponse{
+		DefaultModel:    settings.DefaultModel,
+		SystemPrompt:    settings.SystemPrompt,
+		ModerationLevel: settings.ModerationLevel,
+		RetentionDays:   settings.RetentionDays,
+		Guardrails:      guardrailRules(settings),
+		WarehouseOptOut: settings.WarehouseOptOut,
+		Effective: dtos.WorkspaceEffectiveSettings{
+			Model:           s.llm.Model,
+			ModerationLevel: adapters.ModerationOff,
+			RetentionDays:   s.retention.DefaultDays,
+			Guardrails:      []string{},
+		},
+		UpdatedBy: settings.UpdatedBy,
+	}
+	if !settings.UpdatedAt.IsZero() {
+		response.UpdatedAt = &settings.UpdatedAt
+	}
+
+	if settings.DefaultModel != "" {
+		response.Effective.Model = settings.DefaultModel
+	}
+	if s.llm.Middleware.Moderation.Enabled {
+		response.Effective.ModerationLevel = adapters.ModerationBlock
+		if settings.ModerationLevel != "" {
+			response.Effective.ModerationLevel = settings.ModerationLevel
+		}
+	}
+	if settings.RetentionDays != nil {
+		response.Effective.RetentionDays = *settings.RetentionDays
+	}
+	if s.guardrails.Enabled {
+		for _, rule := range s.guardrails.Rules {
+			if response.Guardrails == nil || slices.Contains(response.Guardrails, rule.Name) {
+				response.Effective.Guardrails = append(response.Effective.Guardrails, rule.Name)
+			}
+		}
+	}
+	return response
+}
+
+// workspaceDefaults returns the workspace settings, empty when none were saved
+func workspaceDefaults(ctx context.Context, workspaceRepo repositories.WorkspaceRepository) (*models.WorkspaceSettings, error) {
+	settings, err := workspaceRepo.GetSettings(ctx)
+	if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrNotFound {
+		return &models.WorkspaceSettings{}, nil
+	}
+	return settings, err
+}
+
+// guardrailRules returns the guardrail rules replies are checked against in a workspace, nil
+// for every rule
+func guardrailRules(settings *models.WorkspaceSettings) []string {
+	if settings.Guardrails == nil {
+		return nil
+	}
+	if *settings.Guardrails == "" {
+		return []string{}
+	}
+	return strings.Split(*settings.Guardrails, ",")
+}
+
+// applyWorkspaceDefaults makes a request use the default model of the workspace when it has
+// no model, and its system prompt when prompted is not set
+func applyWorkspaceDefaults(request *dtos.LLMRequest, settings *models.WorkspaceSettings, prompted bool) {
+	if request.Model == "" {
+		request.Model = settings.DefaultModel
+	}
+	if settings.SystemPrompt != "" && !prompted {
+		prompt := dtos.LLMMessage{Role: "system", Content: settings.SystemPrompt}
+		request.Messages = append([]dtos.LLMMessage{prompt}, request.Messages...)
+	}
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+
+	"github.com/nvnamsss/chat/src/dtos"
+)
+
+// WorkspaceStatsService defines the interface for counting the requests and usage of each
+// workspace, in metrics and in hourly statistics shared by the instances
+type WorkspaceStatsService interface {
+	// RecordRequest counts a request of a user of a workspace answered with a status
+	RecordRequest(ctx context.Context, workspaceID, userID string, status int)
+
+	// RecordUsage counts tokens and their cost of a user towards the workspace of ctx
+	RecordUsage(ctx context.Context, userID string, tokens int, cost float64)
+
+	// Run adds the counts of the instance to the statistics every flush interval, and
+	// deletes those past the retention, until ctx is done
+	Run(ctx context.Context)
+
+	// Flush adds the pending counts of the instance to the statistics, such as before it stops
+	Flush(ctx context.Context)
+
+	// GetStats returns the statistics of a workspace over the latest hours, as released by
+	// the analytics policy
+	GetStats(ctx context.Context, workspaceID string, req *dtos.WorkspaceStatsRequest) (*dtos.WorkspaceStatsResponse, error)
+}
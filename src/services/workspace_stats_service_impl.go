@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nvnamsss/chat/src/configs"
+	"github.com/nvnamsss/chat/src/dtos"
+	"github.com/nvnamsss/chat/src/errors"
+	"github.com/nvnamsss/chat/src/logger"
+	"github.com/nvnamsss/chat/src/metrics"
+	"github.com/nvnamsss/chat/src/models"
+	"github.com/nvnamsss/chat/src/pkg/analytics"
+	"github.com/nvnamsss/chat/src/pkg/workspace"
+	"github.com/nvnamsss/chat/src/repositories"
+)
+
+// otherWorkspacesLabel labels the workspaces over the metrics label limit
+const otherWorkspacesLabel = "other"
+
+// defaultWorkspaceStatsHours is the period of workspace statistics requests without hours
+const defaultWorkspaceStatsHours = 24
+
+// workspaceStatKey identifies the counts of a workspace in an hour
+type workspaceStatKey struct {
+	workspaceID string
+	hour        time.Time
+}
+
+// workspaceStatsService implements the WorkspaceStatsService interface. Counts are kept in
+// memory and added to the database every flush interval, so requests never wait for it.
+type workspaceStatsService struct {
+	config   configs.WorkspaceStats
+	policy   *analytics.Policy
+	statRepo repositories.WorkspaceStatRepository
+
+	mu      sync.Mutex
+	pending map[workspaceStatKey]*models.WorkspaceStat
+	users   map[workspaceStatKey]map[string]*models.WorkspaceStatUser // Pending counts of users by hash
+	labels  map[string]bool                                           // Workspaces labeled in metrics
+}
+
+// NewWorkspaceStatsService creates a new workspace statistics service. Nothing is counted
+// unless statistics are enabled, and the distinct users of each hour only with an analytics
+// policy, which then releases the statistics from the counts of each user.
+func NewWorkspaceStatsService(config configs.WorkspaceStats, policy *analytics.Policy, statRepo repositories.WorkspaceStatRepository) WorkspaceStatsService {
+	return &workspaceStatsService{
+		config:   config,
+		policy:   policy,
+		statRepo: statRepo,
+		pending:  map[workspaceStatKey]*models.WorkspaceStat{},
+		users:    map[workspaceStatKey]map[string]*models.WorkspaceStatUser{},
+		labels:   map[string]bool{},
+	}
+}
+
+// RecordRequest counts a request of a user of a workspace answered with a status
+func (s *workspaceStatsService) RecordRequest(ctx context.Context, workspaceID, userID string, status int) {
+	if !s.config.Enabled {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat := s.stat(workspaceID)
+	user := s.user(stat, userID)
+	stat.Requests++
+	user.Requests++
+	switch {
+	case status >= 500:
+		stat.ServerErrors++
+		user.ServerErrors++
+	case status >= 400:
+		stat.ClientErrors++
+		user.ClientErrors++
+	}
+	metrics.WorkspaceRequests.WithLabelValues(s.label(workspaceID), strconv.Itoa(status/100)+"xx").Inc()
+}
+
+// RecordUsage counts tokens and their cost of a user towards the workspace of ctx, or the
+// default workspace when ctx serves none, such as in background jobs
+func (s *workspaceStatsService) RecordUsage(ctx context.Context, userID string, tokens int, cost float64) {
+	if !s.config.Enabled {
+		return
+	}
+	workspaceID := workspace.Of(ctx)
+	if workspaceID == "" {
+		workspaceID = s.config.DefaultWorkspace
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat := s.stat(workspaceID)
+	user := s.user(stat, userID)
+	stat.Tokens += int64(tokens)
+	stat.Cost += cost
+	user.Tokens += int64(tokens)
+	user.Cost += cost
+	label := s.label(workspaceID)
+	metrics.WorkspaceTokens.WithLabelValues(label).Add(float64(tokens))
+	metrics.WorkspaceCost.WithLabelValues(label).Add(cost)
+}
+
+// stat returns the pending counts of a workspace in the current hour. s.mu must be held.
+func (s *workspaceStatsService) stat(workspaceID string) *models.WorkspaceStat {
+	key := workspaceStatKey{workspaceID: workspaceID, hour: time.Now().UTC().Truncate(time.Hour)}
+	stat, ok := s.pending[key]
+	if !ok {
+		stat = &models.WorkspaceStat{WorkspaceID: key.workspaceID, Hour: key.hour}
+		s.pending[key] = stat
+	}
+	return stat
+}
+
+// user returns the pending counts of a user in the workspace and hour of stat. Users are
+// only counted with an analytics policy; otherwise, and for requests without a user, the
+// counts returned are discarded. s.mu must be held.
+func (s *workspaceStatsService) user(stat *models.WorkspaceStat, userID string) *models.WorkspaceStatUser {
+	if s.policy == nil || userID == "" {
+		return &models.WorkspaceStatUser{}
+	}
+
+	key := workspaceStatKey{workspaceID: stat.WorkspaceID, hour: stat.Hour}
+	if s.users[key] == nil {
+		s.users[key] = map[string]*models.WorkspaceStatUser{}
+	}
+	hash := workspaceUserHash(s.policy.Secret, userID, stat.Hour)
+	user, ok := s.users[key][hash]
+	if !ok {
+		user = &models.WorkspaceStatUser{WorkspaceID: stat.WorkspaceID, Hour: stat.Hour, UserHash: hash}
+		s.users[key][hash] = user
+	}
+	return user
+}
+
+// label returns the metrics label of a workspace: its ID while fewer than MaxLabels
+// workspaces are labeled, or "other" once the limit is reached. s.mu must be held.
+func (s *workspaceStatsService) label(workspaceID string) string {
+	if s.labels[workspaceID] {
+		return workspaceID
+	}
+	if len(s.labels) >= s.config.MaxLabels {
+		return otherWorkspacesLabel
+	}
+	s.labels[workspaceID] = true
+	return workspaceID
+}
+
+// Run adds the counts of the instance to the statistics every flush interval, and deletes
+// those past the retention once an hour, until ctx is done
+func (s *workspaceStatsService) Run(ctx context.Context) {
+	if !s.config.Enabled {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+	var prunedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Flush(ctx)
+			if time.Since(prunedAt) >= time.Hour {
+				prunedAt = time.Now()
+				s.prune(ctx)
+			}
+		}
+	}
+}
+
+// Flush adds the pending counts to the statistics. Counts that could not be added are kept
+// for the next flush.
+func (s *workspaceStatsService) Flush(ctx context.Context) {
+	s.mu.Lock()
+	pending, users := s.pending, s.users
+	s.pending = map[workspaceStatKey]*models.WorkspaceStat{}
+	s.users = map[workspaceStatKey]map[string]*models.WorkspaceStatUser{}
+	s.mu.Unlock()
+
+	s.flushUsers(ctx, users)
+	if len(pending) == 0 {
+		return
+	}
+
+	stats := make([]*models.WorkspaceStat, 0, len(pending))
+	for _, stat := range pending {
+		stats = append(stats, stat)
+	}
+	if err := s.statRepo.Add(ctx, stats); err == nil {
+		return
+	}
+
+	logger.Context(ctx).Warnw("Failed to flush workspace statistics, retrying at the next flush", "count", len(stats))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, stat := range pending {
+		current, ok := s.pending[key]
+		if !ok {
+			s.pending[key] = stat
+			continue
+		}
+		current.Requests += stat.Requests
+		current.ClientErrors += stat.ClientErrors
+		current.ServerErrors += stat.ServerErrors
+		current.Tokens += stat.Tokens
+		current.Cost += stat.Cost
+	}
+}
+
+// flushUsers adds the pending counts of users to the statistics. Counts that could not be
+// added are kept for the next flush.
+func (s *workspaceStatsService) flushUsers(ctx context.Context, users map[workspaceStatKey]map[string]*models.WorkspaceStatUser) {
+	if len(users) == 0 {
+		return
+	}
+
+	var records []*models.WorkspaceStatUser
+	for _, hashes := range users {
+		for _, user := range hashes {
+			records = append(records, user)
+		}
+	}
+	if err := s.statRepo.AddUsers(ctx, records); err == nil {
+		return
+	}
+
+	logger.Context(ctx).Warnw("Failed to flush workspace statistics users, retrying at the next flush", "count", len(records))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, hashes := range users {
+		if s.users[key] == nil {
+			s.users[key] = map[string]*models.WorkspaceStatUser{}
+		}
+		for hash, user := range hashes {
+			current, ok := s.users[key][hash]
+			if !ok {
+				s.users[key][hash] = user
+				continue
+			}
+			current.Requests += user.Requests
+			current.ClientErrors += user.ClientErrors
+			current.ServerErrors += user.ServerErrors
+			current.Tokens += user.Tokens
+			current.Cost += user.Cost
+		}
+	}
+}
+
+// prune deletes the statistics past the retention
+func (s *workspaceStatsService) prune(ctx context.Context) {
+	deleted, err := s.statRepo.DeleteBefore(ctx, time.Now().Add(-s.config.Retention))
+	if err != nil {
+		return
+	}
+	if deleted > 0 {
+		logger.Context(ctx).Infow("Deleted expired workspace statistics", "count", deleted)
+	}
+}
+
+// GetStats returns the statistics of a workspace over the latest hours, including the
+// current one. The counts of the instances not flushed yet are left out. With an analytics
+// policy, hours are counted from the contributions of their users, clipped by the policy,
+// and the hours it suppresses are left out of the totals too, so that they cannot be told
+// from the difference.
+func (s *workspaceStatsService) GetStats(ctx context.Context, workspaceID string, req *dtos.WorkspaceStatsRequest) (*dtos.WorkspaceStatsResponse, error) {
+	if !s.config.Enabled {
+		return nil, errors.New(errors.ErrInvalidRequest, "Workspace statistics are not enabled")
+	}
+
+	hours := req.Hours
+	if hours == 0 {
+		hours = defaultWorkspaceStatsHours
+	}
+	now := time.Now().UTC()
+	since := now.Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour)
+
+	stats, err := s.statRepo.ListByWorkspace(ctx, workspaceID, since)
+	if err != nil {
+		return nil, err
+	}
+	if stats, err = clipWorkspaceStats(ctx, s.policy, s.statRepo, stats); err != nil {
+		return nil, err
+	}
+
+	response := &dtos.WorkspaceStatsResponse{
+		WorkspaceID: workspaceID,
+		Since:       since,
+		Hours:       make([]dtos.WorkspaceHourStats, 0, len(stats)),
+	}
+	for _, stat := range stats {
+		stat, ok := releaseWorkspaceStat(s.policy, stat)
+		if !ok {
+			response.SuppressedHours++
+			continue
+		}
+		response.Requests += stat.Requests
+		response.ClientErrors += stat.ClientErrors
+		response.ServerErrors += stat.ServerErrors
+		response.Tokens += stat.Tokens
+		response.Cost += stat.Cost
+		response.Hours = append(response.Hours, dtos.WorkspaceHourStats{
+			Hour:         stat.Hour.UTC(),
+			Users:        stat.Users,
+			Requests:     stat.Requests,
+			ClientErrors: stat.ClientErrors,
+			ServerErrors: stat.ServerErrors,
+			Tokens:       stat.Tokens,
+			Cost:         stat.Cost,
+		})
+	}
+	if response.Requests > 0 {
+		response.ErrorRate = float64(response.ServerErrors) / float64(response.Requests)
+		response.ClientErrorRate = float64(response.ClientErrors) / float64(response.Requests)
+	}
+	response.RequestsPerMinute = float64(response.Requests) / now.Sub(since).Minutes()
+
+	return response, nil
+}